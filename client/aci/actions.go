@@ -0,0 +1,76 @@
+package aci
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/virtual-kubelet/azure-aci/client/api"
+)
+
+// StopContainerGroup stops all containers in an Azure Container Instance in the provided
+// resource group with the given container group name. Compute resources are deallocated
+// but the container group definition is preserved.
+// From: https://docs.microsoft.com/en-us/rest/api/container-instances/containergroups/stop
+func (c *Client) StopContainerGroup(ctx context.Context, resourceGroup, containerGroupName string) error {
+	return c.containerGroupAction(ctx, resourceGroup, containerGroupName, containerGroupStopURLPath, "stop")
+}
+
+// StartContainerGroup starts all containers of a stopped Azure Container Instance in the provided
+// resource group with the given container group name.
+// From: https://docs.microsoft.com/en-us/rest/api/container-instances/containergroups/start
+func (c *Client) StartContainerGroup(ctx context.Context, resourceGroup, containerGroupName string) error {
+	return c.containerGroupAction(ctx, resourceGroup, containerGroupName, containerGroupStartURLPath, "start")
+}
+
+// RestartContainerGroup restarts all containers in an Azure Container Instance in place,
+// in the provided resource group with the given container group name.
+// From: https://docs.microsoft.com/en-us/rest/api/container-instances/containergroups/restart
+func (c *Client) RestartContainerGroup(ctx context.Context, resourceGroup, containerGroupName string) error {
+	return c.containerGroupAction(ctx, resourceGroup, containerGroupName, containerGroupRestartURLPath, "restart")
+}
+
+// containerGroupAction posts the action at the given path for the container group.
+func (c *Client) containerGroupAction(ctx context.Context, resourceGroup, containerGroupName, path, action string) error {
+	urlParams := url.Values{
+		"api-version": []string{c.apiVersion(APIOperationAction)},
+	}
+
+	// Create the url.
+	uri := api.ResolveRelative(c.auth.ResourceManagerEndpoint, path)
+	uri += "?" + url.Values(urlParams).Encode()
+
+	// Create the request.
+	req, err := http.NewRequest("POST", uri, nil)
+	if err != nil {
+		return fmt.Errorf("Creating %s container group uri request failed: %v", action, err)
+	}
+	req = req.WithContext(ctx)
+
+	// Add the parameters to the url.
+	if err := api.ExpandURL(req.URL, map[string]string{
+		"subscriptionId":     c.auth.SubscriptionID,
+		"resourceGroup":      resourceGroup,
+		"containerGroupName": containerGroupName,
+	}); err != nil {
+		return fmt.Errorf("Expanding URL with parameters failed: %v", err)
+	}
+
+	// The container group changes, do not serve it from the ETag cache anymore.
+	c.etags.remove(etagCacheKey(resourceGroup, containerGroupName))
+
+	// Send the request.
+	resp, err := c.hc.Do(req)
+	if err != nil {
+		return fmt.Errorf("Sending %s container group request failed: %v", action, err)
+	}
+	defer resp.Body.Close()
+
+	// 200 (OK), 202 (Accepted) and 204 (No Content) are successful responses.
+	if err := api.CheckResponse(resp); err != nil {
+		return err
+	}
+
+	return nil
+}
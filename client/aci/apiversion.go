@@ -0,0 +1,136 @@
+package aci
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// APIOperation is an operation on container groups whose api version can be overridden.
+type APIOperation string
+
+const (
+	APIOperationCreate APIOperation = "create"
+	APIOperationGet    APIOperation = "get"
+	APIOperationList   APIOperation = "list"
+	APIOperationDelete APIOperation = "delete"
+	APIOperationAction APIOperation = "action"
+	APIOperationLogs   APIOperation = "logs"
+	APIOperationExec   APIOperation = "exec"
+)
+
+// APIOperations are the operations whose api version can be overridden.
+var APIOperations = []APIOperation{
+	APIOperationCreate,
+	APIOperationGet,
+	APIOperationList,
+	APIOperationDelete,
+	APIOperationAction,
+	APIOperationLogs,
+	APIOperationExec,
+}
+
+var apiVersionRegexp = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}(-preview)?$`)
+
+// ValidateAPIVersion returns an error if the version is not an api version of ARM.
+func ValidateAPIVersion(version string) error {
+	if !apiVersionRegexp.MatchString(version) {
+		return fmt.Errorf("invalid api version %q, expected YYYY-MM-DD or YYYY-MM-DD-preview", version)
+	}
+	return nil
+}
+
+// SetAPIVersions overrides the api versions of the operations. The api version of the creations
+// overrides the one picked from the properties of the container groups.
+// It must be called before the client is used.
+func (c *Client) SetAPIVersions(versions map[APIOperation]string) {
+	c.apiVersions = versions
+}
+
+// apiVersion returns the api version of the operation.
+func (c *Client) apiVersion(op APIOperation) string {
+	if v, ok := c.apiVersions[op]; ok {
+		return v
+	}
+	return apiVersion
+}
+
+// DetectAPIVersions gets the api versions of container groups supported by the cloud, so the creations
+// of container groups requiring an unsupported api version fail with an explicit error.
+// It must be called before the client is used.
+func (c *Client) DetectAPIVersions(ctx context.Context) error {
+	manifest, err := c.getResourceProviderManifest(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, rt := range manifest.ResourceTypes {
+		if !strings.EqualFold(rt.ResourceType, "containerGroups") {
+			continue
+		}
+		c.supportedAPIVersions = make(map[string]bool, len(rt.APIVersions))
+		for _, v := range rt.APIVersions {
+			c.supportedAPIVersions[v] = true
+		}
+	}
+	return nil
+}
+
+// SupportsAPIVersion returns whether the cloud supports the api version for container groups.
+// All the versions are supported until detected.
+func (c *Client) SupportsAPIVersion(version string) bool {
+	return c.supportedAPIVersions == nil || c.supportedAPIVersions[version]
+}
+
+// CreateAPIVersion returns the api version the container group is created with.
+func (c *Client) CreateAPIVersion(containerGroup ContainerGroup) (string, error) {
+	return c.createAPIVersion(containerGroup)
+}
+
+// createAPIVersion returns the api version of the creation of the container group: the overridden one,
+// or else the oldest api version supporting all its properties.
+func (c *Client) createAPIVersion(containerGroup ContainerGroup) (string, error) {
+	if v, ok := c.apiVersions[APIOperationCreate]; ok {
+		return v, nil
+	}
+
+	version, feature := requiredAPIVersion(containerGroup)
+	if !c.SupportsAPIVersion(version) {
+		return "", fmt.Errorf("Container group %s requires api version %s, which the cloud does not support", feature, version)
+	}
+	return version, nil
+}
+
+// requiredAPIVersion returns the oldest api version supporting all the properties of the container group,
+// and the feature requiring it.
+func requiredAPIVersion(containerGroup ContainerGroup) (string, string) {
+	if containerGroup.ContainerGroupProfile != nil || containerGroup.StandbyPoolProfile != nil {
+		return standbyPoolAPIVersion, "profiles and standby pools"
+	}
+
+	if containerGroup.Sku != "" {
+		return securityContextAPIVersion, "SKUs"
+	}
+	if containerGroup.Priority != "" {
+		return spotAPIVersion, "priorities"
+	}
+	if containerGroup.IPAddress != nil && containerGroup.IPAddress.AutoGeneratedDomainNameLabelScope != "" {
+		return dnsNameLabelScopeAPIVersion, "DNS name label scopes"
+	}
+	for _, c := range containerGroup.Containers {
+		if c.SecurityContext != nil {
+			return securityContextAPIVersion, "security contexts"
+		}
+	}
+
+	if len(containerGroup.Zones) > 0 {
+		return zonesAPIVersion, "availability zones"
+	}
+
+	if len(containerGroup.InitContainers) > 0 {
+		return initContainersAPIVersion, "init containers"
+	}
+
+	return apiVersion, ""
+}
@@ -0,0 +1,66 @@
+package aci
+
+import (
+	"testing"
+)
+
+func TestCreateAPIVersion(t *testing.T) {
+	c := &Client{}
+	plain := ContainerGroup{}
+	withSku := ContainerGroup{ContainerGroupProperties: ContainerGroupProperties{Sku: ContainerGroupSkuConfidential}}
+
+	if v, err := c.createAPIVersion(plain); err != nil || v != apiVersion {
+		t.Fatalf("expected the default api version, got %q, %v", v, err)
+	}
+	if v, err := c.createAPIVersion(withSku); err != nil || v != securityContextAPIVersion {
+		t.Fatalf("expected the security context api version for a SKU, got %q, %v", v, err)
+	}
+	withScope := ContainerGroup{ContainerGroupProperties: ContainerGroupProperties{IPAddress: &IPAddress{DNSNameLabel: "app", AutoGeneratedDomainNameLabelScope: "TenantReuse"}}}
+	if v, err := c.createAPIVersion(withScope); err != nil || v != dnsNameLabelScopeAPIVersion {
+		t.Fatalf("expected the DNS name label scope api version, got %q, %v", v, err)
+	}
+	withPriority := ContainerGroup{ContainerGroupProperties: ContainerGroupProperties{Priority: ContainerGroupPrioritySpot}}
+	if v, err := c.createAPIVersion(withPriority); err != nil || v != spotAPIVersion {
+		t.Fatalf("expected the Spot api version, got %q, %v", v, err)
+	}
+	withZones := ContainerGroup{Zones: []string{"1"}}
+	if v, err := c.createAPIVersion(withZones); err != nil || v != zonesAPIVersion {
+		t.Fatalf("expected the availability zones api version, got %q, %v", v, err)
+	}
+	withInit := ContainerGroup{ContainerGroupProperties: ContainerGroupProperties{InitContainers: []InitContainerDefinition{{Name: "init"}}}}
+	if v, err := c.createAPIVersion(withInit); err != nil || v != initContainersAPIVersion {
+		t.Fatalf("expected the init containers api version, got %q, %v", v, err)
+	}
+
+	c.supportedAPIVersions = map[string]bool{apiVersion: true}
+	if _, err := c.createAPIVersion(withSku); err == nil {
+		t.Fatal("expected an error for an api version the cloud does not support")
+	}
+	if v, err := c.createAPIVersion(plain); err != nil || v != apiVersion {
+		t.Fatalf("expected the default api version to stay supported, got %q, %v", v, err)
+	}
+
+	c.SetAPIVersions(map[APIOperation]string{APIOperationCreate: "2021-10-01", APIOperationGet: "2021-09-01"})
+	if v, err := c.createAPIVersion(withSku); err != nil || v != "2021-10-01" {
+		t.Fatalf("expected the overridden api version, got %q, %v", v, err)
+	}
+	if v := c.apiVersion(APIOperationGet); v != "2021-09-01" {
+		t.Fatalf("expected the overridden get api version, got %q", v)
+	}
+	if v := c.apiVersion(APIOperationDelete); v != apiVersion {
+		t.Fatalf("expected the default delete api version, got %q", v)
+	}
+}
+
+func TestValidateAPIVersion(t *testing.T) {
+	for _, v := range []string{"2018-10-01", "2024-05-01-preview"} {
+		if err := ValidateAPIVersion(v); err != nil {
+			t.Errorf("expected %q to be valid: %v", v, err)
+		}
+	}
+	for _, v := range []string{"", "latest", "2018-10"} {
+		if err := ValidateAPIVersion(v); err == nil {
+			t.Errorf("expected %q to be invalid", v)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package aci
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OperationClass groups the requests to ARM sharing a circuit breaker.
+type OperationClass string
+
+const (
+	// OperationRead are the reads of container groups and their logs.
+	OperationRead OperationClass = "read"
+	// OperationWrite are the creations, deletions and actions on container groups.
+	OperationWrite OperationClass = "write"
+	// OperationMetrics are the reads of container group metrics.
+	OperationMetrics OperationClass = "metrics"
+)
+
+// circuitBreaker sheds the requests of an operation class once threshold requests in a row failed
+// with a 5xx or 429 response or no response at all. After cooldown a single probe request is let
+// through, its success closes the circuit and its failure opens it again.
+type circuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openedAt  time.Time
+	probing   bool
+}
+
+func (cb *circuitBreaker) open() bool {
+	return cb.failures >= cb.threshold
+}
+
+// allow reports whether a request can be sent at now.
+func (cb *circuitBreaker) allow(now time.Time) bool {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if !cb.open() {
+		return true
+	}
+	if cb.probing || now.Sub(cb.openedAt) < cb.cooldown {
+		return false
+	}
+	cb.probing = true
+	return true
+}
+
+// record records the outcome of a request allowed at now.
+func (cb *circuitBreaker) record(now time.Time, failed bool) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cb.probing = false
+	if !failed {
+		cb.failures = 0
+		return
+	}
+
+	cb.failures++
+	if cb.open() {
+		cb.openedAt = now
+	}
+}
+
+// EnableCircuitBreakers enables a circuit breaker per operation class, opening after threshold
+// requests in a row failed and probing for recovery every cooldown.
+// It must be called before the client is used.
+func (c *Client) EnableCircuitBreakers(threshold int, cooldown time.Duration) {
+	c.breakers = map[OperationClass]*circuitBreaker{
+		OperationRead:    {threshold: threshold, cooldown: cooldown},
+		OperationWrite:   {threshold: threshold, cooldown: cooldown},
+		OperationMetrics: {threshold: threshold, cooldown: cooldown},
+	}
+}
+
+// OpenCircuits returns the operation classes whose requests are currently shed.
+func (c *Client) OpenCircuits() []OperationClass {
+	var classes []OperationClass
+	for class, cb := range c.breakers {
+		cb.mu.Lock()
+		if cb.open() {
+			classes = append(classes, class)
+		}
+		cb.mu.Unlock()
+	}
+	sort.Slice(classes, func(i, j int) bool { return classes[i] < classes[j] })
+
+	return classes
+}
+
+func operationClass(req *http.Request) OperationClass {
+	if strings.HasSuffix(strings.ToLower(req.URL.Path), "/providers/microsoft.insights/metrics") {
+		return OperationMetrics
+	}
+	if req.Method == http.MethodGet {
+		return OperationRead
+	}
+	return OperationWrite
+}
+
+// breakerTransport applies the circuit breakers of the client to its requests.
+type breakerTransport struct {
+	base   http.RoundTripper
+	client *Client
+}
+
+func (t *breakerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	class := operationClass(req)
+	cb := t.client.breakers[class]
+	if cb == nil {
+		return t.base.RoundTrip(req)
+	}
+
+	if !cb.allow(time.Now()) {
+		return nil, fmt.Errorf("circuit breaker for ARM %s operations is open, request to %s shed", class, req.URL.Path)
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	failed := (err != nil && req.Context().Err() != context.Canceled) ||
+		(resp != nil && (resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests))
+	cb.record(time.Now(), failed)
+
+	return resp, err
+}
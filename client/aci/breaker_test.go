@@ -0,0 +1,41 @@
+package aci
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	cb := circuitBreaker{threshold: 2, cooldown: time.Minute}
+	now := time.Now()
+
+	cb.record(now, true)
+	if !cb.allow(now) {
+		t.Fatal("expected the circuit to be closed after a single failure")
+	}
+	cb.record(now, true)
+	if cb.allow(now) {
+		t.Fatal("expected the circuit to be open after two failures in a row")
+	}
+
+	now = now.Add(time.Minute)
+	if !cb.allow(now) {
+		t.Fatal("expected a probe to be allowed after the cooldown")
+	}
+	if cb.allow(now) {
+		t.Fatal("expected a single probe at a time")
+	}
+	cb.record(now, true)
+	if cb.allow(now.Add(time.Second)) {
+		t.Fatal("expected a failed probe to open the circuit again")
+	}
+
+	now = now.Add(time.Minute)
+	if !cb.allow(now) {
+		t.Fatal("expected a probe to be allowed after the cooldown")
+	}
+	cb.record(now, false)
+	if !cb.allow(now) || !cb.allow(now) {
+		t.Fatal("expected a successful probe to close the circuit")
+	}
+}
@@ -9,18 +9,35 @@ import (
 	"go.opencensus.io/plugin/ochttp"
 
 	azure "github.com/virtual-kubelet/azure-aci/client"
+	"github.com/virtual-kubelet/azure-aci/client/api"
 )
 
 const (
 	defaultUserAgent = "virtual-kubelet/azure-arm-aci/2018-10-01"
 	apiVersion       = "2018-10-01"
 
+	// standbyPoolAPIVersion is the api version supporting container group profiles and standby pools.
+	standbyPoolAPIVersion = "2024-05-01-preview"
+	// securityContextAPIVersion is the api version supporting container security contexts and SKUs.
+	securityContextAPIVersion = "2023-05-01"
+	// dnsNameLabelScopeAPIVersion is the api version supporting the reuse scopes of DNS name labels.
+	dnsNameLabelScopeAPIVersion = "2023-05-01"
+	// spotAPIVersion is the api version supporting the priority of container groups, and so Spot container groups.
+	spotAPIVersion = "2023-05-01"
+	// zonesAPIVersion is the api version supporting the availability zones of container groups.
+	zonesAPIVersion = "2021-03-01"
+	// initContainersAPIVersion is the api version supporting init containers.
+	initContainersAPIVersion = "2019-12-01"
+
 	containerGroupURLPath                    = "subscriptions/{{.subscriptionId}}/resourceGroups/{{.resourceGroup}}/providers/Microsoft.ContainerInstance/containerGroups/{{.containerGroupName}}"
 	containerGroupListURLPath                = "subscriptions/{{.subscriptionId}}/providers/Microsoft.ContainerInstance/containerGroups"
 	containerGroupListByResourceGroupURLPath = "subscriptions/{{.subscriptionId}}/resourceGroups/{{.resourceGroup}}/providers/Microsoft.ContainerInstance/containerGroups"
 	containerLogsURLPath                     = containerGroupURLPath + "/containers/{{.containerName}}/logs"
 	containerExecURLPath                     = containerGroupURLPath + "/containers/{{.containerName}}/exec"
 	containerGroupMetricsURLPath             = containerGroupURLPath + "/providers/microsoft.Insights/metrics"
+	containerGroupStopURLPath                = containerGroupURLPath + "/stop"
+	containerGroupStartURLPath               = containerGroupURLPath + "/start"
+	containerGroupRestartURLPath             = containerGroupURLPath + "/restart"
 )
 
 // Client is a client for interacting with Azure Container Instances.
@@ -28,12 +45,33 @@ const (
 // Clients should be reused instead of created as needed.
 // The methods of Client are safe for concurrent use by multiple goroutines.
 type Client struct {
+	// inFlight is first for its 64-bit alignment, used atomically.
+	inFlight int64
+
 	hc   *http.Client
 	auth *azure.Authentication
+
+	etags    etagCache
+	breakers map[OperationClass]*circuitBreaker
+	limiter  *adaptiveLimiter
+	health   armHealth
+	faults   *faultInjector
+
+	observeConnection func(ConnectionTrace)
+	record            func(containerGroupName string) func(Exchange)
+
+	apiVersions          map[APIOperation]string
+	supportedAPIVersions map[string]bool
 }
 
 // NewClient creates a new Azure Container Instances client with extra user agent.
 func NewClient(auth *azure.Authentication, extraUserAgent string) (*Client, error) {
+	return NewClientWithTransport(auth, extraUserAgent, azure.TransportOptions{})
+}
+
+// NewClientWithTransport creates a new Azure Container Instances client like NewClient,
+// with the connections of its transport tuned by opts.
+func NewClientWithTransport(auth *azure.Authentication, extraUserAgent string, opts azure.TransportOptions) (*Client, error) {
 	if auth == nil {
 		return nil, fmt.Errorf("Authentication is not supplied for the Azure client")
 	}
@@ -43,16 +81,43 @@ func NewClient(auth *azure.Authentication, extraUserAgent string) (*Client, erro
 		userAgent = append(userAgent, extraUserAgent)
 	}
 
-	client, err := azure.NewClient(auth, userAgent)
+	client, err := azure.NewClientWithTransport(auth, userAgent, opts)
 	if err != nil {
 		return nil, fmt.Errorf("Creating Azure client failed: %v", err)
 	}
+	c := &Client{hc: client.HTTPClient, auth: auth}
 	hc := client.HTTPClient
+	var base http.RoundTripper = &connectionTraceTransport{base: hc.Transport, client: c}
+	if !opts.DisableCompression {
+		base = &compressionTransport{base: base}
+	}
+	base = &faultTransport{base: base, client: c}
+	base = &inFlightTransport{base: base, client: c}
+	base = &recordTransport{base: base, client: c}
 	hc.Transport = &ochttp.Transport{
-		Base:           hc.Transport,
+		Base:           &requestIDTransport{base: &healthTransport{base: &breakerTransport{base: &limiterTransport{base: base, client: c}, client: c}, client: c}},
 		Propagation:    &b3.HTTPFormat{},
 		NewClientTrace: ochttp.NewSpanAnnotatingClientTrace,
 	}
 
-	return &Client{hc: client.HTTPClient, auth: auth}, nil
+	return c, nil
+}
+
+// updateNotSupportedCode is the error code of the updates ACI can't apply to an existing container group,
+// like a change of the resources of its containers, which must be deleted and created again.
+const updateNotSupportedCode = "InvalidContainerGroupUpdate"
+
+// IsUpdateNotSupported determines if the passed in error is returned by the API
+// because the requested update can not be applied to the existing container group.
+// Other validation errors and conflicts are not, the container group would not be
+// created again either.
+func IsUpdateNotSupported(err error) bool {
+	switch e := err.(type) {
+	case nil:
+		return false
+	case *api.Error:
+		return e.StatusCode == http.StatusBadRequest && e.Code == updateNotSupportedCode
+	default:
+		return false
+	}
 }
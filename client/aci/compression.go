@@ -0,0 +1,100 @@
+package aci
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// acceptEncoding are the encodings of the responses the client decompresses.
+const acceptEncoding = "gzip, deflate"
+
+// compressionTransport asks ARM to compress the responses, the lists and metrics of thousands of container
+// groups shrinking several times, and decompresses them. Setting Accept-Encoding disables the transparent
+// gzip decompression of the HTTP transport, which doesn't handle deflate either.
+type compressionTransport struct {
+	base http.RoundTripper
+}
+
+func (t *compressionTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("Accept-Encoding") != "" || req.Method == http.MethodHead {
+		return t.base.RoundTrip(req)
+	}
+
+	compressed := req.Clone(req.Context())
+	compressed.Header.Set("Accept-Encoding", acceptEncoding)
+	resp, err := t.base.RoundTrip(compressed)
+	if err != nil || resp.Body == nil || resp.Body == http.NoBody {
+		return resp, err
+	}
+
+	var body io.ReadCloser
+	switch strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding"))) {
+	case "gzip":
+		body = &gzipBody{raw: resp.Body}
+	case "deflate":
+		body = &deflateBody{raw: resp.Body}
+	default:
+		return resp, nil
+	}
+
+	resp.Body = body
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return resp, nil
+}
+
+// gzipBody decompresses a gzip body on the first read, so an empty body is not an error until read.
+type gzipBody struct {
+	raw  io.ReadCloser
+	zr   *gzip.Reader
+	zerr error
+}
+
+func (b *gzipBody) Read(p []byte) (int, error) {
+	if b.zr == nil && b.zerr == nil {
+		b.zr, b.zerr = gzip.NewReader(b.raw)
+	}
+	if b.zerr != nil {
+		return 0, b.zerr
+	}
+	return b.zr.Read(p)
+}
+
+func (b *gzipBody) Close() error {
+	return b.raw.Close()
+}
+
+// deflateBody decompresses a deflate body, zlib wrapped as the HTTP specification says, or raw as some servers send it.
+type deflateBody struct {
+	raw io.ReadCloser
+	r   io.Reader
+	err error
+}
+
+func (b *deflateBody) Read(p []byte) (int, error) {
+	if b.r == nil && b.err == nil {
+		br := bufio.NewReader(b.raw)
+		header, _ := br.Peek(2)
+		// A zlib header is a deflate compression method byte, whose first 2 bytes are a multiple of 31.
+		if len(header) == 2 && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+			b.r, b.err = zlib.NewReader(br)
+		} else {
+			b.r = flate.NewReader(br)
+		}
+	}
+	if b.err != nil {
+		return 0, b.err
+	}
+	return b.r.Read(p)
+}
+
+func (b *deflateBody) Close() error {
+	return b.raw.Close()
+}
@@ -0,0 +1,66 @@
+package aci
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCompressionTransport(t *testing.T) {
+	const payload = `{"value":[{"name":"cg-1"},{"name":"cg-2"}]}`
+	compress := map[string]func(io.Writer) io.WriteCloser{
+		"gzip":        func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) },
+		"deflate":     func(w io.Writer) io.WriteCloser { return zlib.NewWriter(w) },
+		"deflate-raw": func(w io.Writer) io.WriteCloser { fw, _ := flate.NewWriter(w, flate.DefaultCompression); return fw },
+		"identity":    nil,
+	}
+
+	var acceptEncodings []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		acceptEncodings = append(acceptEncodings, r.Header.Get("Accept-Encoding"))
+		encoding := r.URL.Query().Get("encoding")
+		newWriter := compress[encoding]
+		if newWriter == nil {
+			w.Write([]byte(payload))
+			return
+		}
+		if encoding == "deflate-raw" {
+			encoding = "deflate"
+		}
+		w.Header().Set("Content-Encoding", encoding)
+		zw := newWriter(w)
+		zw.Write([]byte(payload))
+		zw.Close()
+	}))
+	defer server.Close()
+
+	hc := &http.Client{Transport: &compressionTransport{base: http.DefaultTransport}}
+	for encoding := range compress {
+		resp, err := hc.Get(server.URL + "?encoding=" + encoding)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("%s: %v", encoding, err)
+		}
+		if !bytes.Equal(body, []byte(payload)) {
+			t.Fatalf("%s: expected the decompressed payload, got %q", encoding, body)
+		}
+		if resp.Header.Get("Content-Encoding") != "" {
+			t.Fatalf("%s: expected the Content-Encoding to be removed", encoding)
+		}
+	}
+	for _, ae := range acceptEncodings {
+		if ae != acceptEncoding {
+			t.Fatalf("expected the compressed responses to be requested, got %q", ae)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package aci
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+// Priority orders the requests waiting for a slot of the adaptive concurrency limit, the requests
+// of a higher priority are always sent first.
+type Priority int
+
+const (
+	// PriorityLow is the default priority of reads, such as the background status refreshes.
+	PriorityLow Priority = iota
+	// PriorityNormal is the default priority of creations and actions.
+	PriorityNormal
+	// PriorityHigh is the default priority of deletions, and the priority of user facing operations.
+	PriorityHigh
+
+	numPriorities = int(PriorityHigh) + 1
+)
+
+type priorityKey struct{}
+
+// WithPriority returns a context whose requests wait for a slot of the adaptive concurrency limit
+// with the priority p, instead of the default priority of their method.
+func WithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+func requestPriority(req *http.Request) Priority {
+	if p, ok := req.Context().Value(priorityKey{}).(Priority); ok && p >= PriorityLow && p <= PriorityHigh {
+		return p
+	}
+
+	switch req.Method {
+	case http.MethodDelete:
+		return PriorityHigh
+	case http.MethodGet:
+		return PriorityLow
+	default:
+		return PriorityNormal
+	}
+}
+
+// adaptiveLimiter limits the number of requests in flight with an AIMD algorithm: the limit grows by
+// one every limit successful requests, and is halved by a throttled request, staying within [min, max].
+type adaptiveLimiter struct {
+	mu       sync.Mutex
+	limit    float64
+	min      float64
+	max      float64
+	inflight int
+	waiters  [numPriorities][]chan struct{}
+}
+
+// acquire waits for a request slot, or for the context to be done.
+func (l *adaptiveLimiter) acquire(ctx context.Context, p Priority) error {
+	l.mu.Lock()
+	if !l.waiting() && l.inflight < int(l.limit) {
+		l.inflight++
+		l.mu.Unlock()
+		return nil
+	}
+	ch := make(chan struct{})
+	l.waiters[p] = append(l.waiters[p], ch)
+	l.mu.Unlock()
+
+	select {
+	case <-ch:
+		return nil
+	case <-ctx.Done():
+		l.cancel(ch, p)
+		return ctx.Err()
+	}
+}
+
+func (l *adaptiveLimiter) waiting() bool {
+	for _, w := range l.waiters {
+		if len(w) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// queued returns the number of requests waiting for a slot.
+func (l *adaptiveLimiter) queued() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	n := 0
+	for _, w := range l.waiters {
+		n += len(w)
+	}
+	return n
+}
+
+// cancel gives up waiting on ch, releasing the slot if it was granted in the meantime.
+func (l *adaptiveLimiter) cancel(ch chan struct{}, p Priority) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for i, w := range l.waiters[p] {
+		if w == ch {
+			l.waiters[p] = append(l.waiters[p][:i], l.waiters[p][i+1:]...)
+			return
+		}
+	}
+	l.inflight--
+	l.grant()
+}
+
+// release releases a request slot and adapts the limit to whether the request was throttled.
+func (l *adaptiveLimiter) release(throttled bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.inflight--
+	if throttled {
+		l.limit /= 2
+	} else {
+		l.limit += 1 / l.limit
+	}
+	if l.limit < l.min {
+		l.limit = l.min
+	}
+	if l.limit > l.max {
+		l.limit = l.max
+	}
+	l.grant()
+}
+
+// grant grants the free slots to the waiters, by priority and then in order of arrival.
+func (l *adaptiveLimiter) grant() {
+	for p := numPriorities - 1; p >= 0; p-- {
+		for len(l.waiters[p]) > 0 && l.inflight < int(l.limit) {
+			ch := l.waiters[p][0]
+			l.waiters[p] = l.waiters[p][1:]
+			l.inflight++
+			close(ch)
+		}
+	}
+}
+
+// EnableAdaptiveConcurrency limits the requests to container groups in flight, starting at maxConcurrency
+// and halving the limit every time ARM throttles a request, down to minConcurrency. The limit grows back
+// as requests succeed. The requests waiting for a slot are sent by priority, see WithPriority.
+// It must be called before the client is used.
+func (c *Client) EnableAdaptiveConcurrency(minConcurrency, maxConcurrency int) {
+	c.limiter = &adaptiveLimiter{
+		limit: float64(maxConcurrency),
+		min:   float64(minConcurrency),
+		max:   float64(maxConcurrency),
+	}
+}
+
+// limiterTransport applies the adaptive concurrency limit of the client to its container group requests.
+type limiterTransport struct {
+	base   http.RoundTripper
+	client *Client
+}
+
+func (t *limiterTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	l := t.client.limiter
+	if l == nil || operationClass(req) == OperationMetrics {
+		return t.base.RoundTrip(req)
+	}
+
+	if err := l.acquire(req.Context(), requestPriority(req)); err != nil {
+		return nil, err
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	l.release(resp != nil && resp.StatusCode == http.StatusTooManyRequests)
+
+	return resp, err
+}
@@ -0,0 +1,87 @@
+package aci
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestAdaptiveLimiter(t *testing.T) {
+	l := adaptiveLimiter{limit: 2, min: 1, max: 2}
+	ctx := context.Background()
+
+	if err := l.acquire(ctx, PriorityNormal); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.acquire(ctx, PriorityNormal); err != nil {
+		t.Fatal(err)
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err := l.acquire(timeoutCtx, PriorityNormal); err == nil {
+		t.Fatal("expected the limiter to be full")
+	}
+
+	l.release(true)
+	if l.limit != 1 {
+		t.Fatalf("limit is %v after a throttled request, expected 1", l.limit)
+	}
+
+	granted := make(chan struct{})
+	go func() {
+		if err := l.acquire(ctx, PriorityNormal); err == nil {
+			close(granted)
+		}
+	}()
+	select {
+	case <-granted:
+		t.Fatal("expected the limiter to be full at its lowered limit")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	l.release(false)
+	select {
+	case <-granted:
+	case <-time.After(time.Second):
+		t.Fatal("expected a slot to be granted on release")
+	}
+	if l.limit != 2 {
+		t.Fatalf("limit is %v after a successful request, expected 2", l.limit)
+	}
+}
+
+func TestAdaptiveLimiterPriorities(t *testing.T) {
+	l := adaptiveLimiter{limit: 1, min: 1, max: 1}
+	ctx := context.Background()
+
+	if err := l.acquire(ctx, PriorityHigh); err != nil {
+		t.Fatal(err)
+	}
+
+	granted := make(chan Priority, numPriorities)
+	for _, p := range []Priority{PriorityLow, PriorityNormal, PriorityHigh} {
+		p := p
+		go func() {
+			if err := l.acquire(ctx, p); err == nil {
+				granted <- p
+			}
+		}()
+		for {
+			l.mu.Lock()
+			queued := len(l.waiters[p]) > 0
+			l.mu.Unlock()
+			if queued {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	for _, want := range []Priority{PriorityHigh, PriorityNormal, PriorityLow} {
+		l.release(false)
+		if p := <-granted; p != want {
+			t.Fatalf("granted a slot to priority %d, expected priority %d", p, want)
+		}
+	}
+}
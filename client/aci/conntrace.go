@@ -0,0 +1,111 @@
+package aci
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// ConnectionTrace is how a request to ARM used the network: the time to resolve the name of ARM, connect to
+// it and handshake TLS, zero when the connection was reused, and the time ARM took to answer once the request
+// was written, which the network doesn't account for.
+type ConnectionTrace struct {
+	Operation    OperationClass
+	DNS          time.Duration
+	Connect      time.Duration
+	TLSHandshake time.Duration
+	// Reused is whether the request was sent on an idle connection instead of a new one.
+	Reused bool
+	// ServerTime is the time from the request written to the first byte of the response.
+	ServerTime time.Duration
+}
+
+// EnableConnectionTracing traces the connections of the requests to ARM, passing the trace of every
+// request which got a response to observe. It must be called before the client is used.
+func (c *Client) EnableConnectionTracing(observe func(ConnectionTrace)) {
+	c.observeConnection = observe
+}
+
+// connectionTraceTransport traces the connections of the requests of the client.
+type connectionTraceTransport struct {
+	base   http.RoundTripper
+	client *Client
+}
+
+func (t *connectionTraceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	observe := t.client.observeConnection
+	if observe == nil {
+		return t.base.RoundTrip(req)
+	}
+
+	var (
+		mu                               sync.Mutex
+		trace                            = ConnectionTrace{Operation: operationClass(req)}
+		dnsStart, connectStart, tlsStart time.Time
+		wroteRequest, firstResponseByte  time.Time
+	)
+	ct := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			mu.Lock()
+			defer mu.Unlock()
+			trace.Reused = info.Reused
+		},
+		DNSStart: func(httptrace.DNSStartInfo) {
+			mu.Lock()
+			defer mu.Unlock()
+			dnsStart = time.Now()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			mu.Lock()
+			defer mu.Unlock()
+			trace.DNS = time.Since(dnsStart)
+		},
+		ConnectStart: func(string, string) {
+			mu.Lock()
+			defer mu.Unlock()
+			connectStart = time.Now()
+		},
+		ConnectDone: func(string, string, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			trace.Connect = time.Since(connectStart)
+		},
+		TLSHandshakeStart: func() {
+			mu.Lock()
+			defer mu.Unlock()
+			tlsStart = time.Now()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			trace.TLSHandshake = time.Since(tlsStart)
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			mu.Lock()
+			defer mu.Unlock()
+			wroteRequest = time.Now()
+		},
+		GotFirstResponseByte: func() {
+			mu.Lock()
+			defer mu.Unlock()
+			firstResponseByte = time.Now()
+		},
+	}
+
+	resp, err := t.base.RoundTrip(req.WithContext(httptrace.WithClientTrace(req.Context(), ct)))
+	if err != nil {
+		return resp, err
+	}
+
+	mu.Lock()
+	if !wroteRequest.IsZero() && firstResponseByte.After(wroteRequest) {
+		trace.ServerTime = firstResponseByte.Sub(wroteRequest)
+	}
+	result := trace
+	mu.Unlock()
+	observe(result)
+
+	return resp, err
+}
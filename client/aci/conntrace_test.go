@@ -0,0 +1,44 @@
+package aci
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestConnectionTraceTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(10 * time.Millisecond)
+		w.Write([]byte("{}"))
+	}))
+	defer server.Close()
+
+	var traces []ConnectionTrace
+	c := &Client{}
+	c.EnableConnectionTracing(func(trace ConnectionTrace) { traces = append(traces, trace) })
+	hc := &http.Client{Transport: &connectionTraceTransport{base: &http.Transport{}, client: c}}
+
+	for i := 0; i < 2; i++ {
+		resp, err := hc.Get(server.URL + "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.ContainerInstance/containerGroups/cg")
+		if err != nil {
+			t.Fatal(err)
+		}
+		ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+	}
+
+	if len(traces) != 2 {
+		t.Fatalf("expected a trace per request, got %d", len(traces))
+	}
+	if traces[0].Reused || traces[0].Connect <= 0 {
+		t.Fatalf("expected the first request to open a connection, got %+v", traces[0])
+	}
+	if !traces[1].Reused || traces[1].Connect != 0 {
+		t.Fatalf("expected the second request to reuse the connection, got %+v", traces[1])
+	}
+	if traces[1].Operation != OperationRead || traces[1].ServerTime < 10*time.Millisecond {
+		t.Fatalf("expected the server time of a read, got %+v", traces[1])
+	}
+}
@@ -16,8 +16,12 @@ import (
 // provided properties.
 // From: https://docs.microsoft.com/en-us/rest/api/container-instances/containergroups/createorupdate
 func (c *Client) CreateContainerGroup(ctx context.Context, resourceGroup, containerGroupName string, containerGroup ContainerGroup) (*ContainerGroup, error) {
+	version, err := c.createAPIVersion(containerGroup)
+	if err != nil {
+		return nil, err
+	}
 	urlParams := url.Values{
-		"api-version": []string{apiVersion},
+		"api-version": []string{version},
 	}
 
 	// Create the url.
@@ -29,6 +33,13 @@ func (c *Client) CreateContainerGroup(ctx context.Context, resourceGroup, contai
 	if err := json.NewEncoder(b).Encode(containerGroup); err != nil {
 		return nil, fmt.Errorf("Encoding create container group body request failed: %v", err)
 	}
+	if len(containerGroup.MergePatch) > 0 {
+		patched, err := api.MergePatch(b.Bytes(), containerGroup.MergePatch)
+		if err != nil {
+			return nil, fmt.Errorf("Patching create container group body request failed: %v", err)
+		}
+		b = bytes.NewBuffer(patched)
+	}
 
 	// Create the request.
 	req, err := http.NewRequest("PUT", uri, b)
@@ -46,8 +57,11 @@ func (c *Client) CreateContainerGroup(ctx context.Context, resourceGroup, contai
 		return nil, fmt.Errorf("Expanding URL with parameters failed: %v", err)
 	}
 
+	// The container group changes, do not serve it from the ETag cache anymore.
+	c.etags.remove(etagCacheKey(resourceGroup, containerGroupName))
+
 	// Send the request.
-	resp, err := c.hc.Do(req)
+	resp, resent, err := c.doIdempotent(req)
 	if err != nil {
 		return nil, fmt.Errorf("Sending create container group request failed: %v", err)
 	}
@@ -55,6 +69,13 @@ func (c *Client) CreateContainerGroup(ctx context.Context, resourceGroup, contai
 
 	// 200 (OK) and 201 (Created) are a successful responses.
 	if err := api.CheckResponse(resp); err != nil {
+		// The first request reached ARM, whose operation is still in progress: it's the container group we're creating.
+		if resent && resp.StatusCode == http.StatusConflict {
+			cg, _, getErr := c.GetContainerGroup(ctx, resourceGroup, containerGroupName)
+			if getErr == nil && cg.ProvisioningState != "Succeeded" && cg.ProvisioningState != "Failed" {
+				return cg, nil
+			}
+		}
 		return nil, err
 	}
 
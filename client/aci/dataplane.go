@@ -0,0 +1,112 @@
+package aci
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	defaultDataPlaneHandshakeTimeout = 30 * time.Second
+	defaultDataPlaneRetryWait        = 500 * time.Millisecond
+)
+
+// DataPlaneOptions tunes the connections of a DataPlaneClient.
+type DataPlaneOptions struct {
+	// HandshakeTimeout bounds the websocket handshake with a container group, 30s if zero.
+	HandshakeTimeout time.Duration
+	// Retries is the number of retries of a failed websocket handshake.
+	Retries int
+	// RetryWait is the wait before the first retry, doubling with every retry, 500ms if zero.
+	RetryWait time.Duration
+	// Proxy returns the proxy of a connection, which is direct if Proxy is nil.
+	Proxy func(*http.Request) (*url.URL, error)
+	// AllowHost reports whether the client may connect to a host, any host if nil.
+	AllowHost func(host string) bool
+	// Observe is passed every dial with its duration, attempts and error, if set.
+	Observe func(d time.Duration, attempts int, err error)
+}
+
+// DataPlaneClient connects to the regional data plane endpoints of the container groups, like their exec
+// websockets, whose latency and authentication differ from ARM: the endpoints are authenticated by the one
+// time password ARM returns rather than the token of the client, and they are reached directly rather than
+// through the transport of the ARM client.
+type DataPlaneClient struct {
+	dialer  websocket.Dialer
+	options DataPlaneOptions
+}
+
+// NewDataPlaneClient creates a client for the data plane endpoints of the container groups.
+func NewDataPlaneClient(opts DataPlaneOptions) *DataPlaneClient {
+	if opts.HandshakeTimeout <= 0 {
+		opts.HandshakeTimeout = defaultDataPlaneHandshakeTimeout
+	}
+	if opts.RetryWait <= 0 {
+		opts.RetryWait = defaultDataPlaneRetryWait
+	}
+	return &DataPlaneClient{
+		dialer: websocket.Dialer{
+			Proxy:            opts.Proxy,
+			HandshakeTimeout: opts.HandshakeTimeout,
+		},
+		options: opts,
+	}
+}
+
+// DialExec connects to the exec websocket returned by LaunchExec and authenticates with its password,
+// retrying the failed handshakes.
+func (c *DataPlaneClient) DialExec(ctx context.Context, exec ExecResponse) (*websocket.Conn, error) {
+	u, err := url.Parse(exec.WebSocketURI)
+	if err != nil {
+		return nil, fmt.Errorf("Parsing the exec websocket uri failed: %v", err)
+	}
+	if c.options.AllowHost != nil && !c.options.AllowHost(u.Hostname()) {
+		return nil, fmt.Errorf("The exec endpoint %s is not an allowed data plane host", u.Hostname())
+	}
+
+	start := time.Now()
+	conn, attempts, err := c.dial(ctx, exec.WebSocketURI)
+	if c.options.Observe != nil {
+		c.options.Observe(time.Since(start), attempts, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// The password must be sent before the terminal is active.
+	if err := conn.WriteMessage(websocket.TextMessage, []byte(exec.Password)); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("Authenticating to the exec websocket failed: %v", err)
+	}
+	return conn, nil
+}
+
+func (c *DataPlaneClient) dial(ctx context.Context, uri string) (*websocket.Conn, int, error) {
+	wait := c.options.RetryWait
+	for attempt := 1; ; attempt++ {
+		conn, resp, err := c.dialer.DialContext(ctx, uri, nil)
+		if err == nil {
+			return conn, attempt, nil
+		}
+		// The endpoint answered, another handshake won't change its mind.
+		if resp != nil && resp.StatusCode >= 400 && resp.StatusCode < 500 {
+			return nil, attempt, fmt.Errorf("Connecting to the exec websocket failed with status %d: %v", resp.StatusCode, err)
+		}
+		if attempt > c.options.Retries || ctx.Err() != nil {
+			return nil, attempt, fmt.Errorf("Connecting to the exec websocket failed after %d attempts: %v", attempt, err)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, attempt, ctx.Err()
+		case <-timer.C:
+		}
+		wait *= 2
+	}
+}
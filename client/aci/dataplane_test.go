@@ -0,0 +1,64 @@
+package aci
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestDataPlaneDialExec(t *testing.T) {
+	var upgrader websocket.Upgrader
+	failures := 1
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, password, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		conn.WriteMessage(websocket.TextMessage, password)
+	}))
+	defer srv.Close()
+	exec := ExecResponse{WebSocketURI: "ws" + strings.TrimPrefix(srv.URL, "http"), Password: "secret"}
+
+	var attempts int
+	c := NewDataPlaneClient(DataPlaneOptions{
+		Retries:   1,
+		RetryWait: time.Millisecond,
+		Observe:   func(d time.Duration, n int, err error) { attempts = n },
+	})
+	conn, err := c.DialExec(context.Background(), exec)
+	if err != nil {
+		t.Fatalf("expected the retried handshake to succeed: %v", err)
+	}
+	defer conn.Close()
+	if attempts != 2 {
+		t.Fatalf("expected 2 attempts, got %d", attempts)
+	}
+	if _, msg, err := conn.ReadMessage(); err != nil || string(msg) != "secret" {
+		t.Fatalf("expected the password to be sent first, got %q, %v", msg, err)
+	}
+
+	failures = 2
+	if _, err := c.DialExec(context.Background(), exec); err == nil {
+		t.Fatal("expected an error once the retries are exhausted")
+	}
+
+	c = NewDataPlaneClient(DataPlaneOptions{AllowHost: func(string) bool { return false }})
+	if _, err := c.DialExec(context.Background(), exec); err == nil {
+		t.Fatal("expected an error for a host that is not allowed")
+	}
+}
@@ -14,7 +14,7 @@ import (
 // From: https://docs.microsoft.com/en-us/rest/api/container-instances/containergroups/delete
 func (c *Client) DeleteContainerGroup(ctx context.Context, resourceGroup, containerGroupName string) error {
 	urlParams := url.Values{
-		"api-version": []string{apiVersion},
+		"api-version": []string{c.apiVersion(APIOperationDelete)},
 	}
 
 	// Create the url.
@@ -37,8 +37,11 @@ func (c *Client) DeleteContainerGroup(ctx context.Context, resourceGroup, contai
 		return fmt.Errorf("Expanding URL with parameters failed: %v", err)
 	}
 
+	// The container group changes, do not serve it from the ETag cache anymore.
+	c.etags.remove(etagCacheKey(resourceGroup, containerGroupName))
+
 	// Send the request.
-	resp, err := c.hc.Do(req)
+	resp, _, err := c.doIdempotent(req)
 	if err != nil {
 		return fmt.Errorf("Sending delete container group request failed: %v", err)
 	}
@@ -0,0 +1,74 @@
+package aci
+
+import (
+	"sync"
+	"time"
+)
+
+// etagCache keeps the body of the last container group read along with its ETag, so the next read of the
+// same container group can be a conditional GET answered with 304 (Not Modified) when unchanged.
+// Entries older than maxAge are not used, so a change not reflected in the ETag is eventually seen.
+// The body is decoded again on every hit, the callers never share a container group they could modify.
+type etagCache struct {
+	mu      sync.Mutex
+	maxAge  time.Duration
+	entries map[string]etagEntry
+}
+
+type etagEntry struct {
+	etag      string
+	body      []byte
+	fetchedAt time.Time
+}
+
+// EnableETagCache enables conditional GETs of container groups, reusing a cached container group
+// for at most maxAge. It must be called before the client is used.
+func (c *Client) EnableETagCache(maxAge time.Duration) {
+	c.etags.maxAge = maxAge
+	c.etags.entries = make(map[string]etagEntry)
+}
+
+func etagCacheKey(resourceGroup, containerGroupName string) string {
+	return resourceGroup + "/" + containerGroupName
+}
+
+func (ec *etagCache) get(key string) (etagEntry, bool) {
+	if ec.maxAge <= 0 {
+		return etagEntry{}, false
+	}
+
+	ec.mu.Lock()
+	defer ec.mu.Unlock()
+
+	e, ok := ec.entries[key]
+	if !ok || time.Since(e.fetchedAt) > ec.maxAge {
+		return etagEntry{}, false
+	}
+	return e, true
+}
+
+func (ec *etagCache) put(key, etag string, body []byte) {
+	if ec.maxAge <= 0 {
+		return
+	}
+
+	ec.mu.Lock()
+	defer ec.mu.Unlock()
+
+	if etag == "" {
+		delete(ec.entries, key)
+		return
+	}
+	ec.entries[key] = etagEntry{etag: etag, body: body, fetchedAt: time.Now()}
+}
+
+func (ec *etagCache) remove(key string) {
+	if ec.maxAge <= 0 {
+		return
+	}
+
+	ec.mu.Lock()
+	defer ec.mu.Unlock()
+
+	delete(ec.entries, key)
+}
@@ -0,0 +1,72 @@
+package aci
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	azure "github.com/virtual-kubelet/azure-aci/client"
+)
+
+func TestETagCache(t *testing.T) {
+	var ec etagCache
+	ec.put("rg/cg", "etag-1", []byte(`{"name": "cg"}`))
+	if _, ok := ec.get("rg/cg"); ok {
+		t.Fatal("expected a disabled cache to be empty")
+	}
+
+	ec = etagCache{maxAge: time.Minute, entries: make(map[string]etagEntry)}
+	ec.put("rg/cg", "etag-1", []byte(`{"name": "cg"}`))
+	e, ok := ec.get("rg/cg")
+	if !ok || e.etag != "etag-1" || string(e.body) != `{"name": "cg"}` {
+		t.Fatalf("unexpected cache entry %v", e)
+	}
+
+	ec.put("rg/cg", "", []byte(`{"name": "cg"}`))
+	if _, ok := ec.get("rg/cg"); ok {
+		t.Fatal("expected a response without ETag to clear the entry")
+	}
+
+	ec.entries["rg/old"] = etagEntry{etag: "etag-2", fetchedAt: time.Now().Add(-2 * time.Minute)}
+	if _, ok := ec.get("rg/old"); ok {
+		t.Fatal("expected entries older than the max age to be ignored")
+	}
+}
+
+func TestGetContainerGroupNotModified(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == "etag-1" {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", "etag-1")
+		w.Write([]byte(`{"name": "cg", "tags": {"NodeName": "vk"}, "properties": {"containers": [{"name": "app"}]}}`))
+	}))
+	defer server.Close()
+
+	c := &Client{hc: server.Client(), auth: &azure.Authentication{ResourceManagerEndpoint: server.URL + "/", SubscriptionID: "sub"}}
+	c.EnableETagCache(time.Minute)
+
+	cg, status, err := c.GetContainerGroup(context.Background(), "rg", "cg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *status != http.StatusOK {
+		t.Fatalf("expected the first read to be a 200, got %d", *status)
+	}
+	cg.Tags["NodeName"] = "modified"
+	cg.Containers[0].Name = "modified"
+
+	cg, status, err = c.GetContainerGroup(context.Background(), "rg", "cg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *status != http.StatusNotModified {
+		t.Fatalf("expected the second read to be a 304, got %d", *status)
+	}
+	if cg.Tags["NodeName"] != "vk" || cg.Containers[0].Name != "app" {
+		t.Fatalf("expected the cached container group to be unchanged by the callers, got %+v", cg)
+	}
+}
@@ -21,7 +21,7 @@ type TerminalSizeRequest struct {
 // From: https://docs.microsoft.com/en-us/rest/api/container-instances/startcontainer/launchexec
 func (c *Client) LaunchExec(resourceGroup, containerGroupName, containerName, command string, terminalSize TerminalSizeRequest) (ExecResponse, error) {
 	urlParams := url.Values{
-		"api-version": []string{apiVersion},
+		"api-version": []string{c.apiVersion(APIOperationExec)},
 	}
 
 	// Create the url to call Azure REST API
@@ -0,0 +1,54 @@
+package aci
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// FaultConfig are the faults injected in the requests of a client, to test how its callers handle a degraded ARM.
+// The faults are only injected by the builds with the faultinjection tag.
+type FaultConfig struct {
+	// Seed seeds the random choice of the requests the faults are injected in, so a run can be replayed.
+	Seed int64
+	// Latency is added to every request, plus a random part of LatencyJitter.
+	Latency       time.Duration
+	LatencyJitter time.Duration
+	// ThrottleRate is the fraction of the requests answered 429 Too Many Requests, without being sent to ARM,
+	// with a Retry-After of RetryAfter.
+	ThrottleRate float64
+	RetryAfter   time.Duration
+	// DropCreateRate is the fraction of the creations of container groups answered 201 Created without being sent
+	// to ARM, like asynchronous creations ARM accepts and then loses.
+	DropCreateRate float64
+	// PartialListRate is the fraction of the lists of container groups truncated to a random part of their
+	// container groups, without their next link.
+	PartialListRate float64
+}
+
+// Validate checks the rates are fractions and the durations are not negative.
+func (cfg FaultConfig) Validate() error {
+	for name, rate := range map[string]float64{"throttle": cfg.ThrottleRate, "drop create": cfg.DropCreateRate, "partial list": cfg.PartialListRate} {
+		if rate < 0 || rate > 1 {
+			return fmt.Errorf("invalid %s rate %v, expected a fraction between 0 and 1", name, rate)
+		}
+	}
+	for name, d := range map[string]time.Duration{"latency": cfg.Latency, "latency jitter": cfg.LatencyJitter, "retry after": cfg.RetryAfter} {
+		if d < 0 {
+			return fmt.Errorf("invalid %s %v, expected a positive duration", name, d)
+		}
+	}
+	return nil
+}
+
+// isContainerGroupCreate reports whether a request creates or updates a container group.
+func isContainerGroupCreate(req *http.Request) bool {
+	return req.Method == http.MethodPut && strings.Contains(strings.ToLower(req.URL.Path), "/providers/microsoft.containerinstance/containergroups/")
+}
+
+// isContainerGroupList reports whether a request lists container groups.
+func isContainerGroupList(req *http.Request) bool {
+	path := strings.ToLower(req.URL.Path)
+	return req.Method == http.MethodGet && (strings.HasSuffix(path, "/providers/microsoft.containerinstance/containergroups") || strings.HasSuffix(path, "/resources"))
+}
@@ -0,0 +1,27 @@
+//go:build !faultinjection
+// +build !faultinjection
+
+package aci
+
+import (
+	"errors"
+	"net/http"
+)
+
+// faultInjector is not built without the faultinjection tag.
+type faultInjector struct{}
+
+// EnableFaultInjection fails without the faultinjection build tag, the production builds never inject faults.
+func (c *Client) EnableFaultInjection(cfg FaultConfig) error {
+	return errors.New("Fault injection is not available in this build, build with the faultinjection tag")
+}
+
+// faultTransport sends the requests as is without the faultinjection build tag.
+type faultTransport struct {
+	base   http.RoundTripper
+	client *Client
+}
+
+func (t *faultTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.base.RoundTrip(req)
+}
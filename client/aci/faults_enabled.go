@@ -0,0 +1,153 @@
+//go:build faultinjection
+// +build faultinjection
+
+package aci
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// faultInjector chooses the requests the faults of its config are injected in.
+type faultInjector struct {
+	cfg FaultConfig
+
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+// EnableFaultInjection injects the faults of cfg in the requests of the client, below its circuit breakers, health
+// and concurrency limits, so they react to the faults as to the ones of ARM. It must be called before the client is
+// used.
+func (c *Client) EnableFaultInjection(cfg FaultConfig) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	c.faults = &faultInjector{cfg: cfg, rng: rand.New(rand.NewSource(cfg.Seed))}
+	return nil
+}
+
+// chance reports whether a fault of rate is injected.
+func (f *faultInjector) chance(rate float64) bool {
+	if rate <= 0 {
+		return false
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.rng.Float64() < rate
+}
+
+// intn returns a random number in [0, n).
+func (f *faultInjector) intn(n int) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.rng.Intn(n)
+}
+
+// latency returns the latency added to a request.
+func (f *faultInjector) latency() time.Duration {
+	d := f.cfg.Latency
+	if f.cfg.LatencyJitter > 0 {
+		f.mu.Lock()
+		d += time.Duration(f.rng.Int63n(int64(f.cfg.LatencyJitter)))
+		f.mu.Unlock()
+	}
+	return d
+}
+
+// faultTransport injects the faults of the client in its requests.
+type faultTransport struct {
+	base   http.RoundTripper
+	client *Client
+}
+
+func (t *faultTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	f := t.client.faults
+	if f == nil {
+		return t.base.RoundTrip(req)
+	}
+
+	if d := f.latency(); d > 0 {
+		timer := time.NewTimer(d)
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		}
+	}
+
+	if f.chance(f.cfg.ThrottleRate) {
+		resp := faultResponse(req, http.StatusTooManyRequests, []byte(`{"error":{"code":"TooManyRequests","message":"Injected throttling fault."}}`))
+		if f.cfg.RetryAfter > 0 {
+			resp.Header.Set("Retry-After", strconv.Itoa(int((f.cfg.RetryAfter+time.Second-1)/time.Second)))
+		}
+		return resp, nil
+	}
+
+	if isContainerGroupCreate(req) && f.chance(f.cfg.DropCreateRate) {
+		// The creation is accepted with the container group sent, which is then never created.
+		var body []byte
+		if req.Body != nil {
+			var err error
+			if body, err = ioutil.ReadAll(req.Body); err != nil {
+				return nil, err
+			}
+			req.Body.Close()
+		}
+		return faultResponse(req, http.StatusCreated, body), nil
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusOK || !isContainerGroupList(req) || !f.chance(f.cfg.PartialListRate) {
+		return resp, err
+	}
+	return truncateList(resp, f)
+}
+
+// truncateList keeps a random part of the values of a list response, without its next link.
+func truncateList(resp *http.Response, f *faultInjector) (*http.Response, error) {
+	b, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	var list struct {
+		Value []json.RawMessage `json:"value"`
+	}
+	if err := json.Unmarshal(b, &list); err != nil {
+		return nil, fmt.Errorf("Injecting a partial list fault failed: %v", err)
+	}
+	if len(list.Value) > 0 {
+		list.Value = list.Value[:f.intn(len(list.Value))]
+	}
+	if b, err = json.Marshal(list); err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(b))
+	resp.ContentLength = int64(len(b))
+	resp.Header.Del("Content-Length")
+	return resp, nil
+}
+
+// faultResponse is a response of the client itself to a request.
+func faultResponse(req *http.Request, status int, body []byte) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
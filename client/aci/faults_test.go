@@ -0,0 +1,75 @@
+//go:build faultinjection
+// +build faultinjection
+
+package aci
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestFaultTransport(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`{"value":[{"name":"cg-1"},{"name":"cg-2"},{"name":"cg-3"}],"nextLink":"next"}`))
+	}))
+	defer server.Close()
+
+	c := &Client{}
+	hc := &http.Client{Transport: &faultTransport{base: http.DefaultTransport, client: c}}
+	listURL := server.URL + "/subscriptions/s/resourceGroups/rg/providers/Microsoft.ContainerInstance/containerGroups"
+	createURL := listURL + "/cg-1"
+
+	if err := c.EnableFaultInjection(FaultConfig{ThrottleRate: 1, RetryAfter: 1500 * time.Millisecond}); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := hc.Get(listURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTooManyRequests || resp.Header.Get("Retry-After") != "2" || requests != 0 {
+		t.Fatalf("expected an injected 429 with a Retry-After of 2s, got %s with %q after %d requests", resp.Status, resp.Header.Get("Retry-After"), requests)
+	}
+
+	if err := c.EnableFaultInjection(FaultConfig{DropCreateRate: 1}); err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest(http.MethodPut, createURL, bytes.NewReader([]byte(`{"name":"cg-1"}`)))
+	resp, err = hc.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated || string(body) != `{"name":"cg-1"}` || requests != 0 {
+		t.Fatalf("expected a dropped creation, got %s %q after %d requests", resp.Status, body, requests)
+	}
+
+	if err := c.EnableFaultInjection(FaultConfig{PartialListRate: 1, Seed: 1}); err != nil {
+		t.Fatal(err)
+	}
+	resp, err = hc.Get(listURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var list ContainerGroupListResult
+	err = json.NewDecoder(resp.Body).Decode(&list)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Value) >= 3 || list.NextLink != "" || requests != 1 {
+		t.Fatalf("expected a partial list without its next link, got %d container groups and %q", len(list.Value), list.NextLink)
+	}
+
+	if err := c.EnableFaultInjection(FaultConfig{ThrottleRate: 2}); err == nil {
+		t.Fatal("expected a rate above 1 to be rejected")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/url"
 
@@ -16,7 +17,7 @@ import (
 // From: https://docs.microsoft.com/en-us/rest/api/container-instances/containergroups/get
 func (c *Client) GetContainerGroup(ctx context.Context, resourceGroup, containerGroupName string) (*ContainerGroup, *int, error) {
 	urlParams := url.Values{
-		"api-version": []string{apiVersion},
+		"api-version": []string{c.apiVersion(APIOperationGet)},
 	}
 
 	// Create the url.
@@ -39,6 +40,12 @@ func (c *Client) GetContainerGroup(ctx context.Context, resourceGroup, container
 		return nil, nil, fmt.Errorf("Expanding URL with parameters failed: %v", err)
 	}
 
+	key := etagCacheKey(resourceGroup, containerGroupName)
+	cached, ok := c.etags.get(key)
+	if ok {
+		req.Header.Set("If-None-Match", cached.etag)
+	}
+
 	// Send the request.
 	resp, err := c.hc.Do(req)
 	if err != nil {
@@ -46,8 +53,19 @@ func (c *Client) GetContainerGroup(ctx context.Context, resourceGroup, container
 	}
 	defer resp.Body.Close()
 
+	// 304 (Not Modified) is the answer to a conditional GET of an unchanged container group.
+	if ok && resp.StatusCode == http.StatusNotModified {
+		var cg ContainerGroup
+		if err := json.Unmarshal(cached.body, &cg); err != nil {
+			c.etags.remove(key)
+			return nil, &resp.StatusCode, fmt.Errorf("Decoding cached container group failed: %v", err)
+		}
+		return &cg, &resp.StatusCode, nil
+	}
+
 	// 200 (OK) is a success response.
 	if err := api.CheckResponse(resp); err != nil {
+		c.etags.remove(key)
 		return nil, &resp.StatusCode, err
 	}
 
@@ -55,10 +73,15 @@ func (c *Client) GetContainerGroup(ctx context.Context, resourceGroup, container
 	if resp.Body == nil {
 		return nil, &resp.StatusCode, errors.New("Get container group returned an empty body in the response")
 	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, &resp.StatusCode, fmt.Errorf("Reading get container group response body failed: %v", err)
+	}
 	var cg ContainerGroup
-	if err := json.NewDecoder(resp.Body).Decode(&cg); err != nil {
+	if err := json.Unmarshal(body, &cg); err != nil {
 		return nil, &resp.StatusCode, fmt.Errorf("Decoding get container group response body failed: %v", err)
 	}
+	c.etags.put(key, resp.Header.Get("ETag"), body)
 
 	return &cg, &resp.StatusCode, nil
 }
@@ -0,0 +1,70 @@
+package aci
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ARMHealth is the outcome of the last requests of the client to ARM.
+type ARMHealth struct {
+	// LastSuccess is when the last request got a response which was neither a server error,
+	// a throttling nor an authentication or authorization failure.
+	LastSuccess time.Time
+	// LastFailure is when the last request failed.
+	LastFailure time.Time
+	// LastError describes the last failure.
+	LastError string
+}
+
+type armHealth struct {
+	mu     sync.Mutex
+	health ARMHealth
+}
+
+func (h *armHealth) record(now time.Time, failure string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if failure == "" {
+		h.health.LastSuccess = now
+		return
+	}
+	h.health.LastFailure = now
+	h.health.LastError = failure
+}
+
+// ARMHealth returns the outcome of the last requests of the client to ARM.
+func (c *Client) ARMHealth() ARMHealth {
+	c.health.mu.Lock()
+	defer c.health.mu.Unlock()
+
+	return c.health.health
+}
+
+// healthTransport records the outcome of the requests of the client.
+type healthTransport struct {
+	base   http.RoundTripper
+	client *Client
+}
+
+func (t *healthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+
+	var failure string
+	switch {
+	case err != nil:
+		if req.Context().Err() == context.Canceled {
+			return resp, err
+		}
+		failure = err.Error()
+	case resp.StatusCode >= 500, resp.StatusCode == http.StatusTooManyRequests,
+		resp.StatusCode == http.StatusUnauthorized, resp.StatusCode == http.StatusForbidden:
+		failure = fmt.Sprintf("%s %s: %s", req.Method, req.URL.Path, resp.Status)
+	}
+	t.client.health.record(time.Now(), failure)
+
+	return resp, err
+}
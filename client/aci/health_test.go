@@ -0,0 +1,46 @@
+package aci
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+type statusTransport struct {
+	status int
+	err    error
+}
+
+func (t *statusTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.err != nil {
+		return nil, t.err
+	}
+	return &http.Response{StatusCode: t.status, Status: http.StatusText(t.status)}, nil
+}
+
+func TestHealthTransport(t *testing.T) {
+	c := &Client{}
+	base := &statusTransport{}
+	transport := &healthTransport{base: base, client: c}
+	req, _ := http.NewRequest(http.MethodGet, "https://management.azure.com/subscriptions/sub", nil)
+
+	base.status = http.StatusNotFound
+	transport.RoundTrip(req)
+	h := c.ARMHealth()
+	if h.LastSuccess.IsZero() || !h.LastFailure.IsZero() {
+		t.Fatalf("expected a not found response to be a success, got %+v", h)
+	}
+
+	base.status = http.StatusUnauthorized
+	transport.RoundTrip(req)
+	h = c.ARMHealth()
+	if h.LastFailure.Before(h.LastSuccess) || h.LastError == "" {
+		t.Fatalf("expected an unauthorized response to be a failure, got %+v", h)
+	}
+
+	base.err = errors.New("token refresh failed")
+	transport.RoundTrip(req)
+	if h = c.ARMHealth(); h.LastError != "token refresh failed" {
+		t.Fatalf("expected the last error to be reported, got %q", h.LastError)
+	}
+}
@@ -0,0 +1,36 @@
+package aci
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/virtual-kubelet/azure-aci/client/api"
+)
+
+// doIdempotent sends a PUT or DELETE request, sending it once more when the connection breaks before the response,
+// since the request may or may not have reached ARM. Both attempts carry the same client request ID, so they can be
+// told apart from another operation. It reports whether the request was sent again.
+func (c *Client) doIdempotent(req *http.Request) (*http.Response, bool, error) {
+	if req.Header.Get(api.ClientRequestIDHeader) == "" {
+		req.Header.Set(api.ClientRequestIDHeader, uuid.New().String())
+	}
+
+	resp, err := c.hc.Do(req)
+	if err == nil || req.Context().Err() != nil {
+		return resp, false, err
+	}
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return resp, false, err
+	}
+
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, bodyErr := req.GetBody()
+		if bodyErr != nil {
+			return resp, false, err
+		}
+		retry.Body = body
+	}
+	resp, err = c.hc.Do(retry)
+	return resp, true, err
+}
@@ -0,0 +1,48 @@
+package aci
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/virtual-kubelet/azure-aci/client/api"
+)
+
+// brokenTransport fails the first request after reading its body, as if the connection broke before the response.
+type brokenTransport struct {
+	bodies     []string
+	requestIDs []string
+}
+
+func (t *brokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, _ := ioutil.ReadAll(req.Body)
+	t.bodies = append(t.bodies, string(body))
+	t.requestIDs = append(t.requestIDs, req.Header.Get(api.ClientRequestIDHeader))
+	if len(t.bodies) == 1 {
+		return nil, errors.New("connection reset by peer")
+	}
+	return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(bytes.NewReader(nil))}, nil
+}
+
+func TestDoIdempotent(t *testing.T) {
+	transport := &brokenTransport{}
+	c := &Client{hc: &http.Client{Transport: transport}}
+
+	req, _ := http.NewRequest("PUT", "https://management.azure.com/cg", bytes.NewBufferString(`{"location":"westus"}`))
+	resp, resent, err := c.doIdempotent(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if !resent || len(transport.bodies) != 2 {
+		t.Fatalf("expected the request to be sent again after the broken connection, got %d requests", len(transport.bodies))
+	}
+	if transport.bodies[1] != transport.bodies[0] {
+		t.Fatalf("expected the same body on the second attempt, got %q", transport.bodies[1])
+	}
+	if transport.requestIDs[0] == "" || transport.requestIDs[1] != transport.requestIDs[0] {
+		t.Fatalf("expected the same client request ID on both attempts, got %q", transport.requestIDs)
+	}
+}
@@ -5,12 +5,21 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
+	"strings"
 
 	"github.com/virtual-kubelet/azure-aci/client/api"
 )
 
+const (
+	resourcesByResourceGroupURLPath = "subscriptions/{{.subscriptionId}}/resourceGroups/{{.resourceGroup}}/resources"
+	resourcesAPIVersion             = "2019-10-01"
+
+	containerGroupResourceType = "Microsoft.ContainerInstance/containerGroups"
+)
+
 // ListContainerGroups lists an Azure Container Instance Groups, if a resource
 // group is given it will list by resource group.
 // It optionally accepts a resource group name and will filter based off of it
@@ -18,8 +27,76 @@ import (
 // From: https://docs.microsoft.com/en-us/rest/api/container-instances/containergroups/list
 // From: https://docs.microsoft.com/en-us/rest/api/container-instances/containergroups/listbyresourcegroup
 func (c *Client) ListContainerGroups(ctx context.Context, resourceGroup string) (*ContainerGroupListResult, error) {
+	var list ContainerGroupListResult
+	nextLink, err := c.listContainerGroupsPage(ctx, c.containerGroupListURI(resourceGroup), resourceGroup, func(cg *ContainerGroup) error {
+		list.Value = append(list.Value, *cg)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	list.NextLink = nextLink
+
+	return &list, nil
+}
+
+// VisitContainerGroups lists the Azure Container Instance Groups like ListContainerGroups, following
+// the next links, and calls visit for each container group as it is decoded from the response.
+// The container groups are never all held in memory, and visit must not keep the pointer it is given
+// past its return if it needs the container group to stay unchanged. An error returned by visit stops
+// the listing and is returned.
+func (c *Client) VisitContainerGroups(ctx context.Context, resourceGroup string, visit func(*ContainerGroup) error) error {
+	uri := c.containerGroupListURI(resourceGroup)
+	for uri != "" {
+		nextLink, err := c.listContainerGroupsPage(ctx, uri, resourceGroup, visit)
+		if err != nil {
+			return err
+		}
+		uri = nextLink
+	}
+
+	return nil
+}
+
+// VisitContainerGroupsByTag lists the resources of the resource group having the tag with the value,
+// following the next links, and calls visit for each container group among them. Only the resource
+// group is filtered by ARM, so the other container groups of a shared resource group are not listed,
+// but the container groups visited only have their ID, name, type, location and tags: the properties,
+// and so the instance view, are not returned.
+// From: https://docs.microsoft.com/en-us/rest/api/resources/resources/listbyresourcegroup
+func (c *Client) VisitContainerGroupsByTag(ctx context.Context, resourceGroup, tagName, tagValue string, visit func(*ContainerGroup) error) error {
 	urlParams := url.Values{
-		"api-version": []string{apiVersion},
+		"api-version": []string{resourcesAPIVersion},
+		"$filter":     []string{fmt.Sprintf("tagName eq '%s' and tagValue eq '%s'", odataQuote(tagName), odataQuote(tagValue))},
+	}
+	uri := api.ResolveRelative(c.auth.ResourceManagerEndpoint, resourcesByResourceGroupURLPath) + "?" + urlParams.Encode()
+
+	// ARM does not combine the tag and resource type filters, the other resources are skipped here.
+	visitContainerGroups := func(cg *ContainerGroup) error {
+		if !strings.EqualFold(cg.Type, containerGroupResourceType) {
+			return nil
+		}
+		return visit(cg)
+	}
+	for uri != "" {
+		nextLink, err := c.listContainerGroupsPage(ctx, uri, resourceGroup, visitContainerGroups)
+		if err != nil {
+			return err
+		}
+		uri = nextLink
+	}
+
+	return nil
+}
+
+// odataQuote escapes the single quotes of a string literal of an OData filter.
+func odataQuote(s string) string {
+	return strings.Replace(s, "'", "''", -1)
+}
+
+func (c *Client) containerGroupListURI(resourceGroup string) string {
+	urlParams := url.Values{
+		"api-version": []string{c.apiVersion(APIOperationList)},
 	}
 
 	// Create the url.
@@ -29,12 +106,15 @@ func (c *Client) ListContainerGroups(ctx context.Context, resourceGroup string)
 		uri = api.ResolveRelative(c.auth.ResourceManagerEndpoint, containerGroupListByResourceGroupURLPath)
 
 	}
-	uri += "?" + url.Values(urlParams).Encode()
+	return uri + "?" + url.Values(urlParams).Encode()
+}
 
+// listContainerGroupsPage gets a page of the container group list and returns its next link.
+func (c *Client) listContainerGroupsPage(ctx context.Context, uri, resourceGroup string, visit func(*ContainerGroup) error) (string, error) {
 	// Create the request.
 	req, err := http.NewRequest("GET", uri, nil)
 	if err != nil {
-		return nil, fmt.Errorf("Creating get container group list uri request failed: %v", err)
+		return "", fmt.Errorf("Creating get container group list uri request failed: %v", err)
 	}
 	req = req.WithContext(ctx)
 
@@ -43,29 +123,101 @@ func (c *Client) ListContainerGroups(ctx context.Context, resourceGroup string)
 		"subscriptionId": c.auth.SubscriptionID,
 		"resourceGroup":  resourceGroup,
 	}); err != nil {
-		return nil, fmt.Errorf("Expanding URL with parameters failed: %v", err)
+		return "", fmt.Errorf("Expanding URL with parameters failed: %v", err)
 	}
 
 	// Send the request.
 	resp, err := c.hc.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("Sending get container group list request failed: %v", err)
+		return "", fmt.Errorf("Sending get container group list request failed: %v", err)
 	}
 	defer resp.Body.Close()
 
 	// 200 (OK) is a success response.
 	if err := api.CheckResponse(resp); err != nil {
-		return nil, err
+		return "", err
 	}
 
 	// Decode the body from the response.
 	if resp.Body == nil {
-		return nil, errors.New("Create container group list returned an empty body in the response")
+		return "", errors.New("Create container group list returned an empty body in the response")
 	}
-	var list ContainerGroupListResult
-	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
-		return nil, fmt.Errorf("Decoding get container group response body failed: %v", err)
+	nextLink, err := decodeContainerGroupList(resp.Body, visit)
+	if err != nil {
+		return "", fmt.Errorf("Decoding get container group response body failed: %v", err)
 	}
 
-	return &list, nil
+	return nextLink, nil
+}
+
+// decodeContainerGroupList decodes a container group list response one container group at a time,
+// instead of decoding the whole list at once, and returns its next link.
+func decodeContainerGroupList(r io.Reader, visit func(*ContainerGroup) error) (string, error) {
+	dec := json.NewDecoder(r)
+	if err := expectDelim(dec, '{'); err != nil {
+		return "", err
+	}
+
+	var nextLink string
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return "", err
+		}
+
+		switch t {
+		case "value":
+			if err := decodeContainerGroups(dec, visit); err != nil {
+				return "", err
+			}
+		case "nextLink":
+			if err := dec.Decode(&nextLink); err != nil {
+				return "", err
+			}
+		default:
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	return nextLink, expectDelim(dec, '}')
+}
+
+func decodeContainerGroups(dec *json.Decoder, visit func(*ContainerGroup) error) error {
+	t, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if t == nil {
+		return nil
+	}
+	if t != json.Delim('[') {
+		return fmt.Errorf("unexpected %v in container group list", t)
+	}
+
+	for dec.More() {
+		var cg ContainerGroup
+		if err := dec.Decode(&cg); err != nil {
+			return err
+		}
+		if err := visit(&cg); err != nil {
+			return err
+		}
+	}
+
+	return expectDelim(dec, ']')
+}
+
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	t, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if t != delim {
+		return fmt.Errorf("unexpected %v in container group list, expected %v", t, delim)
+	}
+
+	return nil
 }
@@ -0,0 +1,32 @@
+package aci
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDecodeContainerGroupList(t *testing.T) {
+	body := `{"value": [{"name": "cg-1", "tags": {"NodeName": "vk"}}, {"name": "cg-2"}], "other": {"a": [1]}, "nextLink": "https://next"}`
+
+	var names []string
+	nextLink, err := decodeContainerGroupList(strings.NewReader(body), func(cg *ContainerGroup) error {
+		names = append(names, cg.Name)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nextLink != "https://next" {
+		t.Fatalf("next link is %s, expected https://next", nextLink)
+	}
+	if strings.Join(names, ",") != "cg-1,cg-2" {
+		t.Fatalf("container groups are %v, expected [cg-1 cg-2]", names)
+	}
+
+	if _, err := decodeContainerGroupList(strings.NewReader(`{"value": null}`), nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := decodeContainerGroupList(strings.NewReader(`{"value": [{"name": "cg-1"}`), func(*ContainerGroup) error { return nil }); err == nil {
+		t.Fatal("expected an error decoding a truncated list")
+	}
+}
@@ -16,7 +16,7 @@ import (
 // From: https://docs.microsoft.com/en-us/rest/api/container-instances/containers/listlogs
 func (c *Client) GetContainerLogs(ctx context.Context, resourceGroup, containerGroupName, containerName string, tail int) (*Logs, error) {
 	urlParams := url.Values{
-		"api-version": []string{apiVersion},
+		"api-version": []string{c.apiVersion(APIOperationLogs)},
 	}
 	// by default, kubectl does not provide a tail number so the value is 0, but actually it expects to show all logs.
 	if tail != 0 {
@@ -3,6 +3,7 @@ package aci
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/url"
 	"path"
@@ -41,7 +42,7 @@ func (c *Client) GetContainerGroupMetrics(ctx context.Context, resourceGroup, co
 		"api-version": []string{"2018-01-01"},
 		"aggregation": []string{ag},
 		"metricnames": []string{metricNames},
-		"interval":    []string{"PT1M"}, // TODO: make configurable?
+		"interval":    []string{metricsInterval(options.Interval)},
 	}
 
 	if options.Dimension != "" {
@@ -95,3 +96,15 @@ func (c *Client) GetContainerGroupMetrics(ctx context.Context, resourceGroup, co
 
 	return &metrics, nil
 }
+
+// metricsInterval formats the granularity of the metrics as an ISO 8601 duration, such as PT5M.
+func metricsInterval(d time.Duration) string {
+	switch {
+	case d <= 0:
+		return "PT1M"
+	case d%time.Hour == 0:
+		return fmt.Sprintf("PT%dH", d/time.Hour)
+	default:
+		return fmt.Sprintf("PT%dM", d/time.Minute)
+	}
+}
@@ -0,0 +1,237 @@
+package aci
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// maxRecordedBody is the size of the largest body recorded, the larger ones are replaced by their size.
+const maxRecordedBody = 1 << 20
+
+// redacted replaces the secrets in the recorded exchanges.
+const redacted = "REDACTED"
+
+// Exchange is a request to ARM and its response, sanitized of the credentials and secrets: only the headers
+// without secrets are kept, the secret values of the query and of the JSON bodies are redacted, and the bodies
+// which are not JSON, or too large to be sanitized, are replaced by their size.
+type Exchange struct {
+	Time            time.Time       `json:"time"`
+	Duration        string          `json:"duration"`
+	Method          string          `json:"method"`
+	URL             string          `json:"url"`
+	RequestHeaders  http.Header     `json:"requestHeaders,omitempty"`
+	RequestBody     json.RawMessage `json:"requestBody,omitempty"`
+	StatusCode      int             `json:"statusCode,omitempty"`
+	ResponseHeaders http.Header     `json:"responseHeaders,omitempty"`
+	ResponseBody    json.RawMessage `json:"responseBody,omitempty"`
+	Error           string          `json:"error,omitempty"`
+}
+
+// EnableRecording records the requests to the container groups and their responses, including the ones of
+// their logs and metrics. For every request, record is called with the name of its container group and returns
+// the func the exchange is passed to once answered, or nil not to record it. Every attempt of a request is
+// recorded, as sent to ARM. It must be called before the client is used.
+func (c *Client) EnableRecording(record func(containerGroupName string) func(Exchange)) {
+	c.record = record
+}
+
+// recordTransport records the requests of the client to the container groups record asks for.
+type recordTransport struct {
+	base   http.RoundTripper
+	client *Client
+}
+
+var containerGroupPath = regexp.MustCompile(`(?i)/containerGroups/([^/]+)`)
+
+func (t *recordTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	record := t.client.record
+	if record == nil {
+		return t.base.RoundTrip(req)
+	}
+	m := containerGroupPath.FindStringSubmatch(req.URL.Path)
+	if m == nil {
+		return t.base.RoundTrip(req)
+	}
+	observe := record(m[1])
+	if observe == nil {
+		return t.base.RoundTrip(req)
+	}
+
+	e := Exchange{
+		Time:           time.Now(),
+		Method:         req.Method,
+		URL:            sanitizeURL(req.URL),
+		RequestHeaders: sanitizeHeaders(req.Header),
+	}
+	if req.Body != nil && req.Body != http.NoBody {
+		b, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req = req.Clone(req.Context())
+		req.Body = ioutil.NopCloser(bytes.NewReader(b))
+		req.GetBody = func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(bytes.NewReader(b)), nil
+		}
+		e.RequestBody = sanitizeBody(b)
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	e.Duration = time.Since(e.Time).String()
+	if err != nil {
+		e.Error = err.Error()
+		observe(e)
+		return resp, err
+	}
+
+	e.StatusCode = resp.StatusCode
+	e.ResponseHeaders = sanitizeHeaders(resp.Header)
+	if resp.Body != nil && resp.Body != http.NoBody {
+		b, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			e.Error = err.Error()
+			observe(e)
+			return nil, err
+		}
+		resp.Body = ioutil.NopCloser(bytes.NewReader(b))
+		e.ResponseBody = sanitizeBody(b)
+	}
+	observe(e)
+	return resp, nil
+}
+
+// sanitizeHeaders keeps the headers which don't carry secrets.
+func sanitizeHeaders(h http.Header) http.Header {
+	kept := make(http.Header)
+	for k, v := range h {
+		k = http.CanonicalHeaderKey(k)
+		switch {
+		case strings.Contains(k, "Authorization"), strings.Contains(k, "Cookie"):
+		case strings.HasPrefix(k, "X-Ms-"),
+			k == "Content-Type", k == "Etag", k == "If-Match", k == "If-None-Match",
+			k == "Retry-After", k == "Location", k == "Azure-Asyncoperation", k == "User-Agent":
+			kept[k] = v
+		}
+	}
+	if len(kept) == 0 {
+		return nil
+	}
+	return kept
+}
+
+// secretQueryParameters are the query parameters redacted, the signatures of the SAS URLs and the tokens.
+var secretQueryParameters = map[string]bool{
+	"sig":          true,
+	"code":         true,
+	"token":        true,
+	"access_token": true,
+}
+
+func sanitizeURL(u *url.URL) string {
+	q := u.Query()
+	for k := range q {
+		if secretQueryParameters[strings.ToLower(k)] {
+			q.Set(k, redacted)
+		}
+	}
+	sanitized := *u
+	sanitized.User = nil
+	sanitized.RawQuery = q.Encode()
+	return sanitized.String()
+}
+
+// secretProperties are the properties of the JSON bodies redacted, lower case: the secure environment variables,
+// the passwords of the registries and of the exec sessions, the secret volumes, the protected settings of the
+// extensions such as the kubeconfig of kube-proxy, the keys of the storage accounts and Log Analytics workspaces,
+// and the tokens.
+var secretProperties = map[string]bool{
+	"securevalue":       true,
+	"password":          true,
+	"secret":            true,
+	"protectedsettings": true,
+	"storageaccountkey": true,
+	"workspacekey":      true,
+	"primarykey":        true,
+	"secondarykey":      true,
+	"clientsecret":      true,
+	"token":             true,
+	"accesstoken":       true,
+	"refreshtoken":      true,
+}
+
+// sanitizeBody returns the JSON body b with its secrets redacted, or its size when it is not JSON or too large.
+func sanitizeBody(b []byte) json.RawMessage {
+	if len(b) == 0 {
+		return nil
+	}
+	notRecorded, _ := marshalUnescaped(fmt.Sprintf("<%d bytes not recorded>", len(b)))
+	if len(b) > maxRecordedBody {
+		return notRecorded
+	}
+
+	var v interface{}
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	if err := d.Decode(&v); err != nil {
+		return notRecorded
+	}
+	sanitized, err := marshalUnescaped(redact(v))
+	if err != nil {
+		return notRecorded
+	}
+	return sanitized
+}
+
+// Sanitize returns the JSON encoding of v with its secrets redacted, like the bodies of the recorded exchanges.
+func Sanitize(v interface{}) (json.RawMessage, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var decoded interface{}
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	if err := d.Decode(&decoded); err != nil {
+		return nil, err
+	}
+	return marshalUnescaped(redact(decoded))
+}
+
+// marshalUnescaped marshals v without escaping the HTML characters, which the bodies of ARM don't escape either.
+func marshalUnescaped(v interface{}) (json.RawMessage, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+func redact(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, value := range v {
+			if secretProperties[strings.ToLower(k)] {
+				v[k] = redacted
+				continue
+			}
+			v[k] = redact(value)
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = redact(v[i])
+		}
+	}
+	return v
+}
@@ -0,0 +1,140 @@
+package aci
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRecordTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Ms-Request-Id", "request-1")
+		w.Header().Set("Set-Cookie", "session=secret")
+		w.Write([]byte(`{"name":"cg-1","properties":{"containers":[{"properties":{"environmentVariables":[{"name":"A","value":"a"},{"name":"B","secureValue":"b"}]}}],"imageRegistryCredentials":[{"server":"r.io","username":"u","password":"p"}],"volumes":[{"name":"v","secret":{"key":"c2VjcmV0"}}]}}`))
+	}))
+	defer server.Close()
+
+	var recorded []Exchange
+	c := &Client{}
+	c.EnableRecording(func(name string) func(Exchange) {
+		if name != "cg-1" {
+			return nil
+		}
+		return func(e Exchange) {
+			recorded = append(recorded, e)
+		}
+	})
+	hc := &http.Client{Transport: &recordTransport{base: http.DefaultTransport, client: c}}
+	prefix := server.URL + "/subscriptions/s/resourceGroups/rg/providers/Microsoft.ContainerInstance/containerGroups/"
+
+	req, _ := http.NewRequest(http.MethodPut, prefix+"cg-1?api-version=2021-10-01&sig=abc", bytes.NewReader([]byte(`{"properties":{"imageRegistryCredentials":[{"password":"p"}]}}`)))
+	req.Header.Set("Authorization", "Bearer token")
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := hc.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(body), `"secureValue":"b"`) {
+		t.Fatalf("expected the response to be passed on as is, got %s", body)
+	}
+
+	resp, err = hc.Get(prefix + "cg-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if len(recorded) != 1 {
+		t.Fatalf("expected only the requests to cg-1 to be recorded, got %d", len(recorded))
+	}
+	e := recorded[0]
+	b, _ := json.Marshal(e)
+	for _, secret := range []string{"Bearer", "session", `"p"`, `"b"`, "c2VjcmV0", "abc"} {
+		if strings.Contains(string(b), secret) {
+			t.Errorf("expected %s to be sanitized from the exchange, got %s", secret, b)
+		}
+	}
+	if e.StatusCode != http.StatusOK || e.ResponseHeaders.Get("X-Ms-Request-Id") != "request-1" || !strings.Contains(string(e.ResponseBody), `"value":"a"`) {
+		t.Errorf("expected the exchange to keep what is not secret, got %s", b)
+	}
+	if !strings.Contains(e.URL, "api-version=2021-10-01") || !strings.Contains(e.URL, "sig=REDACTED") {
+		t.Errorf("expected the signature of the URL to be redacted, got %s", e.URL)
+	}
+}
+
+func TestSanitizeBody(t *testing.T) {
+	if got := string(sanitizeBody([]byte("plain text logs"))); got != `"<15 bytes not recorded>"` {
+		t.Errorf("expected a body which is not JSON to be replaced by its size, got %s", got)
+	}
+	if got := string(sanitizeBody([]byte(`{"count":12345678901234567890}`))); got != `{"count":12345678901234567890}` {
+		t.Errorf("expected the numbers to be kept as is, got %s", got)
+	}
+
+	sanitized, err := Sanitize(ContainerGroup{Name: "cg-1", ContainerGroupProperties: ContainerGroupProperties{
+		ImageRegistryCredentials: []ImageRegistryCredential{{Server: "r.io", Username: "u", Password: "p"}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(sanitized), `"p"`) || !strings.Contains(string(sanitized), `"r.io"`) {
+		t.Errorf("expected the password of the registry to be redacted, got %s", sanitized)
+	}
+}
+
+func TestRecordTransportProtectedSettings(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		b, _ := ioutil.ReadAll(r.Body)
+		w.Write(b)
+	}))
+	defer server.Close()
+
+	var recorded []Exchange
+	c := &Client{}
+	c.EnableRecording(func(name string) func(Exchange) {
+		return func(e Exchange) {
+			recorded = append(recorded, e)
+		}
+	})
+	hc := &http.Client{Transport: &recordTransport{base: http.DefaultTransport, client: c}}
+
+	body, err := json.Marshal(ContainerGroup{Name: "cg-1", ContainerGroupProperties: ContainerGroupProperties{
+		Extensions: []*Extension{{
+			Name: "kube-proxy",
+			Properties: &ExtensionProperties{
+				Type:              ExtensionTypeKubeProxy,
+				Version:           ExtensionVersion1_0,
+				Settings:          map[string]string{KubeProxyExtensionSettingClusterCIDR: "10.240.0.0/16"},
+				ProtectedSettings: map[string]string{KubeProxyExtensionSettingKubeConfig: "a3ViZWNvbmZpZw=="},
+			},
+		}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest(http.MethodPut, server.URL+"/subscriptions/s/resourceGroups/rg/providers/Microsoft.ContainerInstance/containerGroups/cg-1", bytes.NewReader(body))
+	resp, err := hc.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if len(recorded) != 1 {
+		t.Fatalf("expected the creation to be recorded, got %d exchanges", len(recorded))
+	}
+	b, _ := json.Marshal(recorded[0])
+	if strings.Contains(string(b), "a3ViZWNvbmZpZw==") {
+		t.Errorf("expected the kubeconfig of kube-proxy to be redacted, got %s", b)
+	}
+	if !strings.Contains(string(b), `"protectedSettings":"REDACTED"`) || !strings.Contains(string(b), "10.240.0.0/16") {
+		t.Errorf("expected only the protected settings of the extension to be redacted, got %s", b)
+	}
+}
@@ -0,0 +1,41 @@
+package aci
+
+import (
+	"net/http"
+
+	"github.com/virtual-kubelet/azure-aci/client/api"
+	"github.com/virtual-kubelet/virtual-kubelet/log"
+	"go.opencensus.io/trace"
+)
+
+// requestIDTransport adds the IDs ARM gives to the requests to their spans and logs.
+type requestIDTransport struct {
+	base http.RoundTripper
+}
+
+func (t *requestIDTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if resp == nil {
+		return resp, err
+	}
+
+	requestID := resp.Header.Get(api.RequestIDHeader)
+	correlationID := resp.Header.Get(api.CorrelationIDHeader)
+	if requestID == "" && correlationID == "" {
+		return resp, err
+	}
+
+	if span := trace.FromContext(req.Context()); span != nil {
+		span.AddAttributes(
+			trace.StringAttribute("azure.requestID", requestID),
+			trace.StringAttribute("azure.correlationID", correlationID),
+		)
+	}
+	log.G(req.Context()).WithFields(log.Fields{
+		"azure.requestID":     requestID,
+		"azure.correlationID": correlationID,
+		"status":              resp.StatusCode,
+	}).Debugf("%s %s", req.Method, req.URL.Path)
+
+	return resp, err
+}
@@ -0,0 +1,41 @@
+package aci
+
+import (
+	"net/http"
+	"sync/atomic"
+)
+
+// Stats are the sizes of the state the client keeps between requests, to detect its leaks in long-running
+// deployments.
+type Stats struct {
+	// InFlight is the number of requests sent to ARM and not answered yet.
+	InFlight int
+	// Waiting is the number of requests waiting for a slot of the adaptive concurrency limit.
+	Waiting int
+	// ETagCacheEntries is the number of container groups in the ETag cache.
+	ETagCacheEntries int
+}
+
+// Stats returns the sizes of the state of the client.
+func (c *Client) Stats() Stats {
+	s := Stats{InFlight: int(atomic.LoadInt64(&c.inFlight))}
+	if c.limiter != nil {
+		s.Waiting = c.limiter.queued()
+	}
+	c.etags.mu.Lock()
+	s.ETagCacheEntries = len(c.etags.entries)
+	c.etags.mu.Unlock()
+	return s
+}
+
+// inFlightTransport counts the requests of the client in flight.
+type inFlightTransport struct {
+	base   http.RoundTripper
+	client *Client
+}
+
+func (t *inFlightTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	atomic.AddInt64(&t.client.inFlight, 1)
+	defer atomic.AddInt64(&t.client.inFlight, -1)
+	return t.base.RoundTrip(req)
+}
@@ -0,0 +1,48 @@
+package aci
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+
+	c := &Client{limiter: &adaptiveLimiter{limit: 1, min: 1, max: 1}}
+	c.EnableETagCache(time.Minute)
+	hc := &http.Client{Transport: &inFlightTransport{base: http.DefaultTransport, client: c}}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if resp, err := hc.Get(server.URL); err == nil {
+			resp.Body.Close()
+		}
+	}()
+	if err := c.limiter.acquire(context.Background(), PriorityNormal); err != nil {
+		t.Fatal(err)
+	}
+	go c.limiter.acquire(context.Background(), PriorityNormal)
+	c.etags.put("cg-1", "etag", []byte("{}"))
+
+	deadline := time.Now().Add(time.Second)
+	for c.Stats() != (Stats{InFlight: 1, Waiting: 1, ETagCacheEntries: 1}) {
+		if time.Now().After(deadline) {
+			t.Fatalf("unexpected stats %+v", c.Stats())
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	close(release)
+	<-done
+	if s := c.Stats(); s.InFlight != 0 {
+		t.Fatalf("expected no request in flight once answered, got %d", s.InFlight)
+	}
+}
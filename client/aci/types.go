@@ -1,6 +1,7 @@
 package aci
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/virtual-kubelet/azure-aci/client/api"
@@ -48,6 +49,29 @@ const (
 	Windows OperatingSystemTypes = "Windows"
 )
 
+// ContainerGroupSku enumerates the values for container group SKU.
+type ContainerGroupSku string
+
+const (
+	// ContainerGroupSkuStandard specifies the standard SKU for container group.
+	ContainerGroupSkuStandard ContainerGroupSku = "Standard"
+	// ContainerGroupSkuDedicated specifies the dedicated SKU for container group.
+	ContainerGroupSkuDedicated ContainerGroupSku = "Dedicated"
+	// ContainerGroupSkuConfidential specifies the confidential SKU for container group.
+	ContainerGroupSkuConfidential ContainerGroupSku = "Confidential"
+)
+
+// ContainerGroupPriority enumerates the values for the priority of a container group.
+type ContainerGroupPriority string
+
+const (
+	// ContainerGroupPriorityRegular specifies a regular container group.
+	ContainerGroupPriorityRegular ContainerGroupPriority = "Regular"
+	// ContainerGroupPrioritySpot specifies a Spot container group, run on spare capacity at a discount and
+	// evicted when Azure needs the capacity back.
+	ContainerGroupPrioritySpot ContainerGroupPriority = "Spot"
+)
+
 // OperationsOrigin enumerates the values for operations origin.
 type OperationsOrigin string
 
@@ -80,23 +104,66 @@ type ContainerGroup struct {
 	Type                     string            `json:"type,omitempty"`
 	Location                 string            `json:"location,omitempty"`
 	Tags                     map[string]string `json:"tags,omitempty"`
+	Zones                    []string          `json:"zones,omitempty"`
 	ContainerGroupProperties `json:"properties,omitempty"`
+	// MergePatch is a JSON merge patch applied to the body of the creation of the container group, to set the
+	// properties the types of the package do not model.
+	MergePatch json.RawMessage `json:"-"`
 }
 
 // ContainerGroupProperties is
 type ContainerGroupProperties struct {
-	ProvisioningState        string                               `json:"provisioningState,omitempty"`
-	Containers               []Container                          `json:"containers,omitempty"`
-	ImageRegistryCredentials []ImageRegistryCredential            `json:"imageRegistryCredentials,omitempty"`
-	RestartPolicy            ContainerGroupRestartPolicy          `json:"restartPolicy,omitempty"`
-	IPAddress                *IPAddress                           `json:"ipAddress,omitempty"`
-	OsType                   OperatingSystemTypes                 `json:"osType,omitempty"`
-	Volumes                  []Volume                             `json:"volumes,omitempty"`
-	InstanceView             ContainerGroupPropertiesInstanceView `json:"instanceView,omitempty"`
-	Diagnostics              *ContainerGroupDiagnostics           `json:"diagnostics,omitempty"`
-	NetworkProfile           *NetworkProfileDefinition            `json:"networkProfile,omitempty"`
-	Extensions               []*Extension                         `json:"extensions,omitempty"`
-	DNSConfig                *DNSConfig                           `json:"dnsConfig,omitempty"`
+	ProvisioningState             string                               `json:"provisioningState,omitempty"`
+	Containers                    []Container                          `json:"containers,omitempty"`
+	InitContainers                []InitContainerDefinition            `json:"initContainers,omitempty"`
+	ImageRegistryCredentials      []ImageRegistryCredential            `json:"imageRegistryCredentials,omitempty"`
+	RestartPolicy                 ContainerGroupRestartPolicy          `json:"restartPolicy,omitempty"`
+	IPAddress                     *IPAddress                           `json:"ipAddress,omitempty"`
+	OsType                        OperatingSystemTypes                 `json:"osType,omitempty"`
+	Volumes                       []Volume                             `json:"volumes,omitempty"`
+	InstanceView                  ContainerGroupPropertiesInstanceView `json:"instanceView,omitempty"`
+	Diagnostics                   *ContainerGroupDiagnostics           `json:"diagnostics,omitempty"`
+	NetworkProfile                *NetworkProfileDefinition            `json:"networkProfile,omitempty"`
+	Extensions                    []*Extension                         `json:"extensions,omitempty"`
+	DNSConfig                     *DNSConfig                           `json:"dnsConfig,omitempty"`
+	Sku                           ContainerGroupSku                    `json:"sku,omitempty"`
+	ContainerGroupProfile         *ContainerGroupProfileReference      `json:"containerGroupProfile,omitempty"`
+	StandbyPoolProfile            *StandbyPoolProfileDefinition        `json:"standbyPoolProfile,omitempty"`
+	ConfidentialComputeProperties *ConfidentialComputeProperties       `json:"confidentialComputeProperties,omitempty"`
+	Priority                      ContainerGroupPriority               `json:"priority,omitempty"`
+}
+
+// ConfidentialComputeProperties are the properties of a container group of the Confidential SKU.
+type ConfidentialComputeProperties struct {
+	// CcePolicy is the base64 encoded confidential computing enforcement policy of the container group.
+	CcePolicy string `json:"ccePolicy,omitempty"`
+}
+
+// ContainerGroupProfileReference is the reference to the container group profile the container group is created from.
+type ContainerGroupProfileReference struct {
+	ID       string `json:"id,omitempty"`
+	Revision *int64 `json:"revision,omitempty"`
+}
+
+// StandbyPoolProfileDefinition is the standby pool the container group is drawn from.
+type StandbyPoolProfileDefinition struct {
+	ID                                     string `json:"id,omitempty"`
+	FailContainerGroupCreateOnReuseFailure bool   `json:"failContainerGroupCreateOnReuseFailure,omitempty"`
+}
+
+// InitContainerDefinition is a container run to completion before the containers of the container group start.
+type InitContainerDefinition struct {
+	Name       string                            `json:"name,omitempty"`
+	Properties InitContainerPropertiesDefinition `json:"properties,omitempty"`
+}
+
+// InitContainerPropertiesDefinition is the properties of an init container.
+type InitContainerPropertiesDefinition struct {
+	Image                string                           `json:"image,omitempty"`
+	Command              []string                         `json:"command,omitempty"`
+	EnvironmentVariables []EnvironmentVariable            `json:"environmentVariables,omitempty"`
+	VolumeMounts         []VolumeMount                    `json:"volumeMounts,omitempty"`
+	InstanceView         *ContainerPropertiesInstanceView `json:"instanceView,omitempty"`
 }
 
 // ContainerGroupPropertiesInstanceView is the instance view of the container group. Only valid in response.
@@ -135,6 +202,23 @@ type ContainerProperties struct {
 	VolumeMounts         []VolumeMount                   `json:"volumeMounts,omitempty"`
 	LivenessProbe        *ContainerProbe                 `json:"livenessProbe,omitempty"`
 	ReadinessProbe       *ContainerProbe                 `json:"readinessProbe,omitempty"`
+	SecurityContext      *SecurityContextDefinition      `json:"securityContext,omitempty"`
+}
+
+// SecurityContextDefinition is the security context of a container instance.
+type SecurityContextDefinition struct {
+	Privileged               *bool                                  `json:"privileged,omitempty"`
+	AllowPrivilegeEscalation *bool                                  `json:"allowPrivilegeEscalation,omitempty"`
+	Capabilities             *SecurityContextCapabilitiesDefinition `json:"capabilities,omitempty"`
+	RunAsGroup               *int64                                 `json:"runAsGroup,omitempty"`
+	RunAsUser                *int64                                 `json:"runAsUser,omitempty"`
+	SeccompProfile           string                                 `json:"seccompProfile,omitempty"`
+}
+
+// SecurityContextCapabilitiesDefinition is the Linux capabilities to add or drop from a container instance.
+type SecurityContextCapabilitiesDefinition struct {
+	Add  []string `json:"add,omitempty"`
+	Drop []string `json:"drop,omitempty"`
 }
 
 // ContainerPropertiesInstanceView is the instance view of the container instance. Only valid in response.
@@ -191,6 +275,8 @@ type IPAddress struct {
 	Type         string `json:"type,omitempty"`
 	IP           string `json:"ip,omitempty"`
 	DNSNameLabel string `json:"dnsNameLabel,omitempty"`
+	// AutoGeneratedDomainNameLabelScope is the scope in which the DNS name label is reused, like TenantReuse.
+	AutoGeneratedDomainNameLabelScope string `json:"autoGeneratedDomainNameLabelScope,omitempty"`
 }
 
 // Logs is the logs.
@@ -404,6 +490,7 @@ type ValueDescriptor struct {
 type TimeSeriesEntry struct {
 	Timestamp time.Time `json:"timestamp"`
 	Average   float64   `json:"average"`
+	Maximum   float64   `json:"maximum"`
 	Total     float64   `json:"total"`
 	Count     float64   `json:"count"`
 }
@@ -414,6 +501,8 @@ type MetricsRequest struct {
 	End          time.Time
 	Types        []MetricType
 	Aggregations []AggregationType
+	// Interval is the granularity of the time series, 1 minute if zero.
+	Interval time.Duration
 
 	// Note that a dimension may not be available for certain metrics.
 	// In such cases, you will need to make separate requests.
@@ -438,6 +527,7 @@ type AggregationType string
 const (
 	AggregationTypeCount   AggregationType = "count"
 	AggregationTypeAverage AggregationType = "average"
+	AggregationTypeMaximum AggregationType = "maximum"
 	AggregationTypeTotal   AggregationType = "total"
 )
 
@@ -516,5 +606,12 @@ type ResourceProviderMetadata struct {
 
 // ResourceProviderManifest is the ACI resource provider manifest
 type ResourceProviderManifest struct {
-	Metadata *ResourceProviderMetadata `json:"metadata"`
+	Metadata      *ResourceProviderMetadata      `json:"metadata"`
+	ResourceTypes []ResourceProviderResourceType `json:"resourceTypes,omitempty"`
+}
+
+// ResourceProviderResourceType is a resource type of the ACI resource provider with its api versions.
+type ResourceProviderResourceType struct {
+	ResourceType string   `json:"resourceType"`
+	APIVersions  []string `json:"apiVersions"`
 }
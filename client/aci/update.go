@@ -1,6 +1,15 @@
 package aci
 
-import "context"
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/virtual-kubelet/azure-aci/client/api"
+)
 
 // UpdateContainerGroup updates an Azure Container Instance with the
 // provided properties.
@@ -8,3 +17,53 @@ import "context"
 func (c *Client) UpdateContainerGroup(ctx context.Context, resourceGroup, containerGroupName string, containerGroup ContainerGroup) (*ContainerGroup, error) {
 	return c.CreateContainerGroup(ctx, resourceGroup, containerGroupName, containerGroup)
 }
+
+// UpdateContainerGroupTags replaces the tags of an Azure Container Instance, without
+// restarting its containers.
+// From: https://docs.microsoft.com/en-us/rest/api/container-instances/containergroups/update
+func (c *Client) UpdateContainerGroupTags(ctx context.Context, resourceGroup, containerGroupName string, tags map[string]string) error {
+	urlParams := url.Values{
+		"api-version": []string{c.apiVersion(APIOperationCreate)},
+	}
+
+	// Create the url.
+	uri := api.ResolveRelative(c.auth.ResourceManagerEndpoint, containerGroupURLPath)
+	uri += "?" + url.Values(urlParams).Encode()
+
+	// Create the body for the request.
+	b := new(bytes.Buffer)
+	if err := json.NewEncoder(b).Encode(struct {
+		Tags map[string]string `json:"tags"`
+	}{tags}); err != nil {
+		return fmt.Errorf("Encoding update container group tags body request failed: %v", err)
+	}
+
+	// Create the request.
+	req, err := http.NewRequest("PATCH", uri, b)
+	if err != nil {
+		return fmt.Errorf("Creating update container group tags uri request failed: %v", err)
+	}
+	req = req.WithContext(ctx)
+
+	// Add the parameters to the url.
+	if err := api.ExpandURL(req.URL, map[string]string{
+		"subscriptionId":     c.auth.SubscriptionID,
+		"resourceGroup":      resourceGroup,
+		"containerGroupName": containerGroupName,
+	}); err != nil {
+		return fmt.Errorf("Expanding URL with parameters failed: %v", err)
+	}
+
+	// The container group changes, do not serve it from the ETag cache anymore.
+	c.etags.remove(etagCacheKey(resourceGroup, containerGroupName))
+
+	// Send the request.
+	resp, err := c.hc.Do(req)
+	if err != nil {
+		return fmt.Errorf("Sending update container group tags request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	// 200 (OK) is a success response.
+	return api.CheckResponse(resp)
+}
@@ -0,0 +1,105 @@
+package aci
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/virtual-kubelet/azure-aci/client/api"
+)
+
+const (
+	usageURLPath    = "subscriptions/{{.subscriptionId}}/providers/Microsoft.ContainerInstance/locations/{{.location}}/usages"
+	usageAPIVersion = "2019-12-01"
+)
+
+// ListUsages gets the usages of the ACI quota dimensions in a location.
+// From: https://docs.microsoft.com/en-us/rest/api/container-instances/location/listusage
+func (c *Client) ListUsages(ctx context.Context, location string) (*UsageListResult, error) {
+	urlParams := url.Values{
+		"api-version": []string{usageAPIVersion},
+	}
+
+	// Create the url.
+	uri := api.ResolveRelative(c.auth.ResourceManagerEndpoint, usageURLPath)
+	uri += "?" + url.Values(urlParams).Encode()
+
+	// Create the request.
+	req, err := http.NewRequest("GET", uri, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Creating list usages uri request failed: %v", err)
+	}
+	req = req.WithContext(ctx)
+
+	// Add the parameters to the url.
+	if err := api.ExpandURL(req.URL, map[string]string{
+		"subscriptionId": c.auth.SubscriptionID,
+		"location":       location,
+	}); err != nil {
+		return nil, fmt.Errorf("Expanding URL with parameters failed: %v", err)
+	}
+
+	// Send the request.
+	resp, err := c.hc.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Sending list usages request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	// 200 (OK) is a success response.
+	if err := api.CheckResponse(resp); err != nil {
+		return nil, err
+	}
+
+	// Decode the body from the response.
+	if resp.Body == nil {
+		return nil, errors.New("List usages returned an empty body in the response")
+	}
+	var usages UsageListResult
+	if err := json.NewDecoder(resp.Body).Decode(&usages); err != nil {
+		return nil, fmt.Errorf("Decoding list usages response body failed: %v", err)
+	}
+
+	return &usages, nil
+}
+
+var quotaDimensionRegexps = []*regexp.Regexp{
+	regexp.MustCompile(`quota limit '([^']+)'`),
+	regexp.MustCompile(`(?i)exceeds? the (\w+) quota`),
+	regexp.MustCompile(`(?i)quota (?:for|of) '?(\w+)'?`),
+}
+
+// IsQuotaExceeded determines if the passed in error is returned by the API because
+// a quota of the subscription is reached.
+func IsQuotaExceeded(err error) bool {
+	e, ok := err.(*api.Error)
+	if !ok {
+		return false
+	}
+
+	if strings.Contains(strings.ToLower(e.Code), "quota") {
+		return true
+	}
+	return e.Code == "OperationNotAllowed" && strings.Contains(strings.ToLower(e.Message), "quota")
+}
+
+// QuotaDimension returns the quota dimension reached from a quota exceeded error,
+// or its error code if the message does not mention it.
+func QuotaDimension(err error) string {
+	e, ok := err.(*api.Error)
+	if !ok {
+		return ""
+	}
+
+	for _, re := range quotaDimensionRegexps {
+		if m := re.FindStringSubmatch(e.Message); m != nil {
+			return m[1]
+		}
+	}
+	return e.Code
+}
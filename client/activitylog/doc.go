@@ -0,0 +1,3 @@
+// Package activitylog provides tools for interacting with the
+// Azure Monitor Activity Log API.
+package activitylog
@@ -0,0 +1,102 @@
+package activitylog
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/virtual-kubelet/azure-aci/client/api"
+)
+
+// ListEvents lists the events of the Activity Log of the subscription matching the filter.
+// From: https://docs.microsoft.com/en-us/rest/api/monitor/activity-logs/list
+func (c *Client) ListEvents(ctx context.Context, filter string) ([]Event, error) {
+	urlParams := url.Values{
+		"api-version": []string{apiVersion},
+		"$filter":     []string{filter},
+	}
+
+	// Create the url.
+	uri := api.ResolveRelative(c.auth.ResourceManagerEndpoint, eventsURLPath)
+	uri += "?" + url.Values(urlParams).Encode()
+
+	var events []Event
+	for uri != "" {
+		// Create the request.
+		req, err := http.NewRequest("GET", uri, nil)
+		if err != nil {
+			return nil, fmt.Errorf("Creating list activity log events uri request failed: %v", err)
+		}
+		req = req.WithContext(ctx)
+
+		// Add the parameters to the url.
+		if err := api.ExpandURL(req.URL, map[string]string{
+			"subscriptionId": c.auth.SubscriptionID,
+		}); err != nil {
+			return nil, fmt.Errorf("Expanding URL with parameters failed: %v", err)
+		}
+
+		list, err := c.listEvents(req)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, list.Value...)
+		uri = list.NextLink
+	}
+
+	return events, nil
+}
+
+func (c *Client) listEvents(req *http.Request) (*EventList, error) {
+	// Send the request.
+	resp, err := c.hc.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Sending list activity log events request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	// 200 (OK) is a success response.
+	if err := api.CheckResponse(resp); err != nil {
+		return nil, err
+	}
+
+	// Decode the body from the response.
+	if resp.Body == nil {
+		return nil, errors.New("List activity log events returned an empty body in the response")
+	}
+	var list EventList
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("Decoding list activity log events response body failed: %v", err)
+	}
+
+	return &list, nil
+}
+
+// ListContainerGroupFailures lists the failed operations on a container group since a time, the ones of the
+// operation with the correlation ID if it is not empty, newest first.
+func (c *Client) ListContainerGroupFailures(ctx context.Context, resourceGroup, containerGroupName, correlationID string, since time.Time) ([]Event, error) {
+	// The Activity Log filters the events by a correlation ID or by a resource, not both.
+	filter := fmt.Sprintf("eventTimestamp ge '%s'", since.UTC().Format(time.RFC3339))
+	if correlationID != "" {
+		filter += fmt.Sprintf(" and correlationId eq '%s'", correlationID)
+	} else {
+		filter += fmt.Sprintf(" and resourceUri eq '/subscriptions/%s/resourceGroups/%s/providers/Microsoft.ContainerInstance/containerGroups/%s'", c.auth.SubscriptionID, resourceGroup, containerGroupName)
+	}
+
+	events, err := c.ListEvents(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var failures []Event
+	for _, e := range events {
+		if e.Failed() {
+			failures = append(failures, e)
+		}
+	}
+	return failures, nil
+}
@@ -0,0 +1,72 @@
+package activitylog
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// EventList is a page of the events of the Activity Log.
+type EventList struct {
+	Value    []Event `json:"value,omitempty"`
+	NextLink string  `json:"nextLink,omitempty"`
+}
+
+// Event is an event of the Activity Log, like the start or the outcome of an operation on a resource.
+type Event struct {
+	ID             string            `json:"id,omitempty"`
+	CorrelationID  string            `json:"correlationId,omitempty"`
+	ResourceID     string            `json:"resourceId,omitempty"`
+	EventTimestamp time.Time         `json:"eventTimestamp,omitempty"`
+	OperationName  LocalizableString `json:"operationName,omitempty"`
+	Status         LocalizableString `json:"status,omitempty"`
+	SubStatus      LocalizableString `json:"subStatus,omitempty"`
+	Properties     map[string]string `json:"properties,omitempty"`
+}
+
+// LocalizableString is a value of an event along with its localized display value.
+type LocalizableString struct {
+	Value          string `json:"value,omitempty"`
+	LocalizedValue string `json:"localizedValue,omitempty"`
+}
+
+// Failed returns whether the event is the failure of an operation.
+func (e *Event) Failed() bool {
+	return e.Status.Value == "Failed"
+}
+
+// eventError is the error of a failed operation in the status message of its event.
+type eventError struct {
+	Code    string       `json:"code"`
+	Message string       `json:"message"`
+	Details []eventError `json:"details"`
+}
+
+// FailureDetail returns the cause of the failure of an operation, from the error in the status message of
+// its event, like a policy denial, an exceeded quota or an error of the resource provider. The innermost
+// error code is kept, as the outer ones are often generic, like DeploymentFailed.
+func (e *Event) FailureDetail() string {
+	message := e.Properties["statusMessage"]
+	if message == "" {
+		if e.SubStatus.LocalizedValue != "" {
+			return e.SubStatus.LocalizedValue
+		}
+		return e.Properties["statusCode"]
+	}
+
+	var status struct {
+		Error *eventError `json:"error"`
+	}
+	if err := json.Unmarshal([]byte(message), &status); err != nil || status.Error == nil {
+		return strings.TrimSpace(message)
+	}
+	err := *status.Error
+	for len(err.Details) > 0 {
+		err = err.Details[0]
+	}
+	if err.Code == "" {
+		return err.Message
+	}
+	return fmt.Sprintf("%s: %s", err.Code, err.Message)
+}
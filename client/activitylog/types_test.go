@@ -0,0 +1,36 @@
+package activitylog
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestFailureDetail(t *testing.T) {
+	var list EventList
+	body := `{"value": [
+		{"status": {"value": "Failed"}, "properties": {"statusCode": "Forbidden", "statusMessage": "{\"error\":{\"code\":\"RequestDisallowedByPolicy\",\"message\":\"Resource 'ns-web' was disallowed by policy.\"}}"}},
+		{"status": {"value": "Failed"}, "properties": {"statusMessage": "{\"status\":\"Failed\",\"error\":{\"code\":\"DeploymentFailed\",\"message\":\"At least one resource deployment operation failed.\",\"details\":[{\"code\":\"ContainerGroupQuotaReached\",\"message\":\"Resource type 'Microsoft.ContainerInstance/containerGroups' container group quota exceeded.\"}]}}"}},
+		{"status": {"value": "Failed"}, "subStatus": {"value": "InternalServerError", "localizedValue": "Internal Server Error (HTTP Status Code: 500)"}},
+		{"status": {"value": "Succeeded"}}
+	]}`
+	if err := json.Unmarshal([]byte(body), &list); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"RequestDisallowedByPolicy: Resource 'ns-web' was disallowed by policy.",
+		"ContainerGroupQuotaReached: Resource type 'Microsoft.ContainerInstance/containerGroups' container group quota exceeded.",
+		"Internal Server Error (HTTP Status Code: 500)",
+	}
+	for i, detail := range expected {
+		if !list.Value[i].Failed() {
+			t.Fatalf("expected event %d to be failed", i)
+		}
+		if d := list.Value[i].FailureDetail(); d != detail {
+			t.Fatalf("expected detail %q, got %q", detail, d)
+		}
+	}
+	if list.Value[3].Failed() {
+		t.Fatalf("expected a succeeded event not to be failed")
+	}
+}
@@ -8,6 +8,15 @@ import (
 	"net/http"
 )
 
+const (
+	// RequestIDHeader is the header of the ID ARM gives to a request.
+	RequestIDHeader = "x-ms-request-id"
+	// CorrelationIDHeader is the header of the ID correlating the requests of an operation to ARM.
+	CorrelationIDHeader = "x-ms-correlation-request-id"
+	// ClientRequestIDHeader is the header of the ID the client gives to a request, the same for all its retries.
+	ClientRequestIDHeader = "x-ms-client-request-id"
+)
+
 // Error contains an error response from the server.
 type Error struct {
 	// StatusCode is the HTTP response status code and will always be populated.
@@ -24,16 +33,31 @@ type Error struct {
 	Header http.Header
 	// URL is the URL of the original HTTP request and will always be populated.
 	URL string
+	// RequestID and CorrelationID are the IDs of the request given by ARM, to look it up in Azure support tickets.
+	RequestID     string
+	CorrelationID string
 }
 
 // Error converts the Error type to a readable string.
 func (e *Error) Error() string {
 	// If the message is empty return early.
 	if e.Message == "" {
-		return fmt.Sprintf("api call to %s: got HTTP response status code %d error code %q with body: %v", e.URL, e.StatusCode, e.Code, e.Body)
+		return fmt.Sprintf("api call to %s: got HTTP response status code %d error code %q with body: %v%s", e.URL, e.StatusCode, e.Code, e.Body, e.requestIDs())
 	}
 
-	return fmt.Sprintf("api call to %s: got HTTP response status code %d error code %q: %s", e.URL, e.StatusCode, e.Code, e.Message)
+	return fmt.Sprintf("api call to %s: got HTTP response status code %d error code %q: %s%s", e.URL, e.StatusCode, e.Code, e.Message, e.requestIDs())
+}
+
+func (e *Error) requestIDs() string {
+	switch {
+	case e.RequestID != "" && e.CorrelationID != "":
+		return fmt.Sprintf(" (request ID %s, correlation ID %s)", e.RequestID, e.CorrelationID)
+	case e.RequestID != "":
+		return fmt.Sprintf(" (request ID %s)", e.RequestID)
+	case e.CorrelationID != "":
+		return fmt.Sprintf(" (correlation ID %s)", e.CorrelationID)
+	}
+	return ""
 }
 
 type errorReply struct {
@@ -57,13 +81,18 @@ func CheckResponse(res *http.Response) error {
 			}
 			jerr.Error.Body = string(slurp)
 			jerr.Error.URL = res.Request.URL.String()
+			jerr.Error.Header = res.Header
+			jerr.Error.RequestID = res.Header.Get(RequestIDHeader)
+			jerr.Error.CorrelationID = res.Header.Get(CorrelationIDHeader)
 			return jerr.Error
 		}
 	}
 
 	return &Error{
-		StatusCode: res.StatusCode,
-		Body:       res.Status,
-		Header:     res.Header,
+		StatusCode:    res.StatusCode,
+		Body:          res.Status,
+		Header:        res.Header,
+		RequestID:     res.Header.Get(RequestIDHeader),
+		CorrelationID: res.Header.Get(CorrelationIDHeader),
 	}
 }
@@ -0,0 +1,35 @@
+package api
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestCheckResponseRequestIDs(t *testing.T) {
+	u, _ := url.Parse("https://management.azure.com/subscriptions/sub")
+	res := &http.Response{
+		StatusCode: http.StatusConflict,
+		Status:     "409 Conflict",
+		Header: http.Header{
+			"X-Ms-Request-Id":             []string{"request-id"},
+			"X-Ms-Correlation-Request-Id": []string{"correlation-id"},
+		},
+		Body:    ioutil.NopCloser(strings.NewReader(`{"error":{"code":"Conflict","message":"busy"}}`)),
+		Request: &http.Request{URL: u},
+	}
+
+	err := CheckResponse(res)
+	e, ok := err.(*Error)
+	if !ok {
+		t.Fatalf("expected an *Error, got %T", err)
+	}
+	if e.RequestID != "request-id" || e.CorrelationID != "correlation-id" {
+		t.Fatalf("expected the request IDs of the response, got %q and %q", e.RequestID, e.CorrelationID)
+	}
+	if !strings.Contains(e.Error(), "(request ID request-id, correlation ID correlation-id)") {
+		t.Fatalf("expected the error to mention the request IDs, got %q", e.Error())
+	}
+}
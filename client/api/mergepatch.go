@@ -0,0 +1,38 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// MergePatch applies a JSON merge patch (RFC 7386) to a JSON document: the values of the patch replace the ones
+// of the document, objects are merged recursively, and null values remove their key.
+func MergePatch(doc, patch []byte) ([]byte, error) {
+	var d, p interface{}
+	if err := json.Unmarshal(doc, &d); err != nil {
+		return nil, fmt.Errorf("Decoding merge patch document failed: %v", err)
+	}
+	if err := json.Unmarshal(patch, &p); err != nil {
+		return nil, fmt.Errorf("Decoding merge patch failed: %v", err)
+	}
+	return json.Marshal(mergePatch(d, p))
+}
+
+func mergePatch(doc, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	d, ok := doc.(map[string]interface{})
+	if !ok {
+		d = make(map[string]interface{}, len(p))
+	}
+	for key, value := range p {
+		if value == nil {
+			delete(d, key)
+			continue
+		}
+		d[key] = mergePatch(d[key], value)
+	}
+	return d
+}
@@ -0,0 +1,23 @@
+package api
+
+import (
+	"testing"
+)
+
+func TestMergePatch(t *testing.T) {
+	doc := `{"location":"eastus","tags":{"a":"1","b":"2"},"properties":{"osType":"Linux","containers":[{"name":"web"}]}}`
+	patch := `{"tags":{"b":null,"c":"3"},"properties":{"priority":"Spot","containers":[{"name":"app"}]}}`
+
+	b, err := MergePatch([]byte(doc), []byte(patch))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"location":"eastus","properties":{"containers":[{"name":"app"}],"osType":"Linux","priority":"Spot"},"tags":{"a":"1","c":"3"}}`
+	if string(b) != want {
+		t.Fatalf("expected %s, got %s", want, b)
+	}
+
+	if _, err := MergePatch([]byte(doc), []byte(`{`)); err == nil {
+		t.Fatal("expected an error for an invalid patch")
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"net/url"
 	"unicode/utf16"
 
 	"github.com/dimchansky/utfbom"
@@ -25,6 +26,10 @@ type Authentication struct {
 	ManagementEndpoint      string `json:"managementEndpointUrl,omitempty"`
 	UseUserIdentity         bool   `json:"useUserIdentity,omitempty"`
 	UserIdentityClientId    string `json:"userIdentityClientId,omitempty"`
+
+	// ControlPlaneProxy is the HTTP proxy the requests to Azure Resource Manager and Active Directory
+	// go through, they go direct if nil.
+	ControlPlaneProxy *url.URL `json:"-"`
 }
 
 // NewAuthentication returns an authentication struct from user provided
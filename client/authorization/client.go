@@ -0,0 +1,47 @@
+package authorization
+
+import (
+	"fmt"
+	"net/http"
+
+	azure "github.com/virtual-kubelet/azure-aci/client"
+)
+
+const (
+	defaultUserAgent = "virtual-kubelet/azure-arm-authorization/2022-04-01"
+	apiVersion       = "2022-04-01"
+
+	resourceGroupURLPath                = "subscriptions/{{.subscriptionId}}/resourceGroups/{{.resourceGroup}}"
+	subnetURLPath                       = resourceGroupURLPath + "/providers/Microsoft.Network/virtualNetworks/{{.virtualNetworkName}}/subnets/{{.subnetName}}"
+	resourceGroupPermissionsURLPath     = resourceGroupURLPath + "/providers/Microsoft.Authorization/permissions"
+	subnetPermissionsURLPath            = subnetURLPath + "/providers/Microsoft.Authorization/permissions"
+	resourceGroupDenyAssignmentsURLPath = resourceGroupURLPath + "/providers/Microsoft.Authorization/denyAssignments"
+)
+
+// Client is a client for interacting with Azure role-based access control.
+//
+// Clients should be reused instead of created as needed.
+// The methods of Client are safe for concurrent use by multiple goroutines.
+type Client struct {
+	hc   *http.Client
+	auth *azure.Authentication
+}
+
+// NewClient creates a new Azure role-based access control client.
+func NewClient(auth *azure.Authentication, extraUserAgent string) (*Client, error) {
+	if auth == nil {
+		return nil, fmt.Errorf("Authentication is not supplied for the Azure client")
+	}
+
+	userAgent := []string{defaultUserAgent}
+	if extraUserAgent != "" {
+		userAgent = append(userAgent, extraUserAgent)
+	}
+
+	client, err := azure.NewClient(auth, userAgent)
+	if err != nil {
+		return nil, fmt.Errorf("Creating Azure client failed: %v", err)
+	}
+
+	return &Client{hc: client.HTTPClient, auth: auth}, nil
+}
@@ -0,0 +1,3 @@
+// Package authorization provides tools for interacting with the
+// Azure role-based access control API.
+package authorization
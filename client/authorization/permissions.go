@@ -0,0 +1,131 @@
+package authorization
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/virtual-kubelet/azure-aci/client/api"
+)
+
+// ListResourceGroupPermissions lists the permissions of the caller on a resource group.
+// From: https://docs.microsoft.com/en-us/rest/api/authorization/permissions/list-for-resource-group
+func (c *Client) ListResourceGroupPermissions(ctx context.Context, resourceGroup string) ([]Permission, error) {
+	var permissions []Permission
+	err := c.list(ctx, resourceGroupPermissionsURLPath, map[string]string{
+		"subscriptionId": c.auth.SubscriptionID,
+		"resourceGroup":  resourceGroup,
+	}, "permissions", func(b []byte) (string, error) {
+		var list PermissionList
+		if err := json.Unmarshal(b, &list); err != nil {
+			return "", err
+		}
+		permissions = append(permissions, list.Value...)
+		return list.NextLink, nil
+	})
+	return permissions, err
+}
+
+// ListSubnetPermissions lists the permissions of the caller on a subnet.
+// From: https://docs.microsoft.com/en-us/rest/api/authorization/permissions/list-for-resource
+func (c *Client) ListSubnetPermissions(ctx context.Context, resourceGroup, virtualNetworkName, subnetName string) ([]Permission, error) {
+	var permissions []Permission
+	err := c.list(ctx, subnetPermissionsURLPath, map[string]string{
+		"subscriptionId":     c.auth.SubscriptionID,
+		"resourceGroup":      resourceGroup,
+		"virtualNetworkName": virtualNetworkName,
+		"subnetName":         subnetName,
+	}, "permissions", func(b []byte) (string, error) {
+		var list PermissionList
+		if err := json.Unmarshal(b, &list); err != nil {
+			return "", err
+		}
+		permissions = append(permissions, list.Value...)
+		return list.NextLink, nil
+	})
+	return permissions, err
+}
+
+// ListResourceGroupDenyAssignments lists the deny assignments applying to a resource group.
+// From: https://docs.microsoft.com/en-us/rest/api/authorization/deny-assignments/list-for-resource-group
+func (c *Client) ListResourceGroupDenyAssignments(ctx context.Context, resourceGroup string) ([]DenyAssignment, error) {
+	var assignments []DenyAssignment
+	err := c.list(ctx, resourceGroupDenyAssignmentsURLPath, map[string]string{
+		"subscriptionId": c.auth.SubscriptionID,
+		"resourceGroup":  resourceGroup,
+	}, "deny assignments", func(b []byte) (string, error) {
+		var list DenyAssignmentList
+		if err := json.Unmarshal(b, &list); err != nil {
+			return "", err
+		}
+		assignments = append(assignments, list.Value...)
+		return list.NextLink, nil
+	})
+	return assignments, err
+}
+
+// list gets the pages of a list, decoding each with decode, which returns the link to the next page.
+func (c *Client) list(ctx context.Context, path string, params map[string]string, what string, decode func([]byte) (string, error)) error {
+	urlParams := url.Values{
+		"api-version": []string{apiVersion},
+	}
+
+	// Create the url.
+	uri := api.ResolveRelative(c.auth.ResourceManagerEndpoint, path)
+	uri += "?" + url.Values(urlParams).Encode()
+
+	for uri != "" {
+		// Create the request.
+		req, err := http.NewRequest("GET", uri, nil)
+		if err != nil {
+			return fmt.Errorf("Creating list %s uri request failed: %v", what, err)
+		}
+		req = req.WithContext(ctx)
+
+		// Add the parameters to the url, ExpandURL escapes them in place.
+		expansions := make(map[string]string, len(params))
+		for k, v := range params {
+			expansions[k] = v
+		}
+		if err := api.ExpandURL(req.URL, expansions); err != nil {
+			return fmt.Errorf("Expanding URL with parameters failed: %v", err)
+		}
+
+		uri, err = c.get(req, what, decode)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Client) get(req *http.Request, what string, decode func([]byte) (string, error)) (string, error) {
+	// Send the request.
+	resp, err := c.hc.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("Sending list %s request failed: %v", what, err)
+	}
+	defer resp.Body.Close()
+
+	// 200 (OK) is a success response.
+	if err := api.CheckResponse(resp); err != nil {
+		return "", err
+	}
+
+	// Decode the body from the response.
+	if resp.Body == nil {
+		return "", errors.New("List " + what + " returned an empty body in the response")
+	}
+	var body json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("Decoding list %s response body failed: %v", what, err)
+	}
+	next, err := decode(body)
+	if err != nil {
+		return "", fmt.Errorf("Decoding list %s response body failed: %v", what, err)
+	}
+	return next, nil
+}
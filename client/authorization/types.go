@@ -0,0 +1,101 @@
+package authorization
+
+import (
+	"regexp"
+	"strings"
+)
+
+// everyonePrincipalID is the principal of the deny assignments applying to all the users and identities.
+const everyonePrincipalID = "00000000-0000-0000-0000-000000000000"
+
+// PermissionList is the list of the permissions of the caller at a scope.
+type PermissionList struct {
+	Value    []Permission `json:"value,omitempty"`
+	NextLink string       `json:"nextLink,omitempty"`
+}
+
+// Permission is a set of actions allowed or denied, short of the not actions.
+type Permission struct {
+	Actions        []string `json:"actions,omitempty"`
+	NotActions     []string `json:"notActions,omitempty"`
+	DataActions    []string `json:"dataActions,omitempty"`
+	NotDataActions []string `json:"notDataActions,omitempty"`
+}
+
+// DenyAssignmentList is the list of the deny assignments at a scope.
+type DenyAssignmentList struct {
+	Value    []DenyAssignment `json:"value,omitempty"`
+	NextLink string           `json:"nextLink,omitempty"`
+}
+
+// DenyAssignment denies actions to principals at a scope, even if a role assignment allows them.
+type DenyAssignment struct {
+	ID         string                   `json:"id,omitempty"`
+	Name       string                   `json:"name,omitempty"`
+	Properties DenyAssignmentProperties `json:"properties"`
+}
+
+// DenyAssignmentProperties are the properties of a deny assignment.
+type DenyAssignmentProperties struct {
+	DenyAssignmentName string       `json:"denyAssignmentName,omitempty"`
+	Permissions        []Permission `json:"permissions,omitempty"`
+	Principals         []Principal  `json:"principals,omitempty"`
+	ExcludePrincipals  []Principal  `json:"excludePrincipals,omitempty"`
+}
+
+// Principal is a user, a group or an identity.
+type Principal struct {
+	ID   string `json:"id,omitempty"`
+	Type string `json:"type,omitempty"`
+}
+
+// Allows returns whether the permission allows the action.
+func (p *Permission) Allows(action string) bool {
+	return matchesAny(p.Actions, action) && !matchesAny(p.NotActions, action)
+}
+
+// Allowed returns whether any of the permissions allows the action.
+func Allowed(permissions []Permission, action string) bool {
+	for i := range permissions {
+		if permissions[i].Allows(action) {
+			return true
+		}
+	}
+	return false
+}
+
+// Denies returns whether the deny assignment denies the action to everyone, with no principal excluded. The
+// principals of a deny assignment are not resolved, an assignment to other principals or excluding some is
+// assumed not to apply to the caller.
+func (d *DenyAssignment) Denies(action string) bool {
+	if len(d.Properties.ExcludePrincipals) > 0 {
+		return false
+	}
+	everyone := false
+	for _, p := range d.Properties.Principals {
+		if p.ID == everyonePrincipalID {
+			everyone = true
+		}
+	}
+	return everyone && Allowed(d.Properties.Permissions, action)
+}
+
+func matchesAny(patterns []string, action string) bool {
+	for _, p := range patterns {
+		if actionMatches(p, action) {
+			return true
+		}
+	}
+	return false
+}
+
+// actionMatches returns whether the action matches the pattern, in which * matches any characters,
+// ignoring the case like Azure does.
+func actionMatches(pattern, action string) bool {
+	if !strings.Contains(pattern, "*") {
+		return strings.EqualFold(pattern, action)
+	}
+	expr := "(?i)^" + strings.Replace(regexp.QuoteMeta(pattern), `\*`, ".*", -1) + "$"
+	matched, err := regexp.MatchString(expr, action)
+	return err == nil && matched
+}
@@ -0,0 +1,48 @@
+package authorization
+
+import (
+	"testing"
+)
+
+func TestAllowed(t *testing.T) {
+	permissions := []Permission{
+		{Actions: []string{"Microsoft.ContainerInstance/*"}, NotActions: []string{"Microsoft.ContainerInstance/containerGroups/delete"}},
+		{Actions: []string{"*/read"}},
+	}
+
+	for _, action := range []string{
+		"Microsoft.ContainerInstance/containerGroups/write",
+		"microsoft.containerinstance/containergroups/read",
+		"Microsoft.Insights/metrics/read",
+	} {
+		if !Allowed(permissions, action) {
+			t.Errorf("expected %s to be allowed", action)
+		}
+	}
+	for _, action := range []string{
+		"Microsoft.ContainerInstance/containerGroups/delete",
+		"Microsoft.Network/virtualNetworks/subnets/join/action",
+	} {
+		if Allowed(permissions, action) {
+			t.Errorf("expected %s not to be allowed", action)
+		}
+	}
+}
+
+func TestDenies(t *testing.T) {
+	deny := DenyAssignment{Properties: DenyAssignmentProperties{
+		Permissions: []Permission{{Actions: []string{"*/delete"}}},
+		Principals:  []Principal{{ID: everyonePrincipalID, Type: "SystemDefined"}},
+	}}
+	if !deny.Denies("Microsoft.ContainerInstance/containerGroups/delete") {
+		t.Fatal("expected the deny assignment to deny the deletions")
+	}
+	if deny.Denies("Microsoft.ContainerInstance/containerGroups/write") {
+		t.Fatal("expected the deny assignment not to deny the writes")
+	}
+
+	deny.Properties.ExcludePrincipals = []Principal{{ID: "publisher", Type: "ServicePrincipal"}}
+	if deny.Denies("Microsoft.ContainerInstance/containerGroups/delete") {
+		t.Fatal("expected a deny assignment excluding principals not to apply")
+	}
+}
@@ -0,0 +1,67 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"time"
+
+	"github.com/virtual-kubelet/azure-aci/client/api"
+)
+
+const (
+	storageAPIVersion = "2019-12-12"
+	defaultTimeout    = time.Minute
+)
+
+// Client is a client uploading blobs to a single storage container.
+type Client struct {
+	hc           *http.Client
+	containerURL *url.URL
+}
+
+// NewClient creates a new client for the storage container URL, including its SAS token,
+// e.g. https://account.blob.core.windows.net/container?sv=...&sig=...
+func NewClient(containerURL string) (*Client, error) {
+	u, err := url.Parse(containerURL)
+	if err != nil {
+		return nil, fmt.Errorf("Parsing storage container URL failed: %v", err)
+	}
+	if u.Scheme != "https" || u.Host == "" || u.Path == "" {
+		return nil, fmt.Errorf("Storage container URL must be an https URL including the container name")
+	}
+
+	return &Client{
+		hc:           &http.Client{Timeout: defaultTimeout},
+		containerURL: u,
+	}, nil
+}
+
+// PutBlockBlob creates or replaces a block blob with the given content.
+// From: https://docs.microsoft.com/en-us/rest/api/storageservices/put-blob
+func (c *Client) PutBlockBlob(ctx context.Context, name, contentType string, content []byte) error {
+	u := *c.containerURL
+	u.Path = path.Join(u.Path, name)
+
+	req, err := http.NewRequest("PUT", u.String(), bytes.NewReader(content))
+	if err != nil {
+		return fmt.Errorf("Creating put blob request failed: %v", err)
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("x-ms-version", storageAPIVersion)
+	req.Header.Set("x-ms-blob-type", "BlockBlob")
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := c.hc.Do(req)
+	if err != nil {
+		// Do not leak the SAS token of the URL in the error.
+		return fmt.Errorf("Sending put blob request for %s failed", name)
+	}
+	defer resp.Body.Close()
+
+	// 201 (Created) is a success response.
+	return api.CheckResponse(resp)
+}
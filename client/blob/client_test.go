@@ -0,0 +1,51 @@
+package blob
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPutBlockBlob(t *testing.T) {
+	var gotPath, gotQuery, gotType string
+	var gotBody []byte
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotQuery = r.URL.RawQuery
+		gotType = r.Header.Get("x-ms-blob-type")
+		gotBody, _ = ioutil.ReadAll(r.Body)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	c, err := NewClient(server.URL + "/logs?sv=2019-12-12&sig=secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.hc = server.Client()
+
+	if err := c.PutBlockBlob(context.Background(), "ns/pod/uid/nginx.log", "text/plain", []byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+
+	if gotPath != "/logs/ns/pod/uid/nginx.log" {
+		t.Fatalf("unexpected blob path %s", gotPath)
+	}
+	if gotQuery != "sv=2019-12-12&sig=secret" {
+		t.Fatalf("unexpected query %s", gotQuery)
+	}
+	if gotType != "BlockBlob" {
+		t.Fatalf("unexpected blob type %s", gotType)
+	}
+	if string(gotBody) != "hello" {
+		t.Fatalf("unexpected blob content %s", gotBody)
+	}
+}
+
+func TestNewClientRequiresHTTPS(t *testing.T) {
+	if _, err := NewClient("http://account.blob.core.windows.net/logs"); err == nil {
+		t.Fatal("expected an error for a plain http URL")
+	}
+}
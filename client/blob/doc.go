@@ -0,0 +1,3 @@
+// Package blob provides a minimal client for uploading blobs to an Azure Storage
+// container through a shared access signature (SAS) URL.
+package blob
@@ -1,12 +1,18 @@
 package azure
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"math/rand"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/Azure/go-autorest/autorest/adal"
+	"github.com/google/uuid"
+	"github.com/virtual-kubelet/azure-aci/client/api"
 )
 
 // Client represents authentication details and cloud specific parameters for
@@ -28,15 +34,72 @@ type userAgentTransport struct {
 	userAgent []string
 	base      http.RoundTripper
 	client    *Client
+
+	retryWaitMin time.Duration
+	retryWaitMax time.Duration
+	retryMax     int
 }
 
 var (
 	concurrentConnections          = 200
 	throttlingAdditionalRetryCount = 3
+	defaultRetryWaitMin            = 500 * time.Millisecond
+	defaultRetryWaitMax            = 10 * time.Second
 )
 
+// TransportOptions tunes the connections of the HTTP transport of a client.
+// The zero value disables keep-alives, so every request opens a new connection.
+type TransportOptions struct {
+	// KeepAlives reuses the connections across requests.
+	KeepAlives bool
+	// MaxIdleConnsPerHost is the number of idle connections kept per host, 200 if zero.
+	MaxIdleConnsPerHost int
+	// IdleConnTimeout is how long an idle connection is kept before being closed, forever if zero.
+	IdleConnTimeout time.Duration
+	// HTTP2 attempts to use HTTP/2 for the connections.
+	HTTP2 bool
+	// RetryWaitMin and RetryWaitMax bound the wait before retrying a throttled request, 500ms and 10s if zero.
+	// The wait doubles with every retry and is randomized, so the requests throttled together are not retried together.
+	// A longer Retry-After of the response is waited for anyway.
+	RetryWaitMin time.Duration
+	RetryWaitMax time.Duration
+	// RetryMax is the number of retries of a throttled request, 3 if nil.
+	RetryMax *int
+	// DisableCompression doesn't ask for gzip or deflate compressed responses.
+	DisableCompression bool
+}
+
+type correlationIDKey struct{}
+
+// WithCorrelationID returns a context whose requests to ARM carry the correlation ID, so all the requests
+// of an operation can be looked up together in the ARM logs.
+func WithCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationIDKey{}, id)
+}
+
+// CorrelationID returns the correlation ID of the requests sent with the context, if any.
+func CorrelationID(ctx context.Context) string {
+	id, _ := ctx.Value(correlationIDKey{}).(string)
+	return id
+}
+
 // NewClient creates a new Azure API client from an Authentication struct and BaseURI.
 func NewClient(auth *Authentication, userAgent []string) (*Client, error) {
+	return NewClientWithTransport(auth, userAgent, TransportOptions{})
+}
+
+// NewClientWithTransport creates a new Azure API client like NewClient, with a transport tuned by opts.
+func NewClientWithTransport(auth *Authentication, userAgent []string, opts TransportOptions) (*Client, error) {
+	return newClient(auth, userAgent, opts, "")
+}
+
+// NewClientForResource creates a new Azure API client like NewClient, authorized for another resource
+// than Azure Resource Manager, such as the Key Vault data plane.
+func NewClientForResource(auth *Authentication, userAgent []string, resource string) (*Client, error) {
+	return newClient(auth, userAgent, TransportOptions{}, resource)
+}
+
+func newClient(auth *Authentication, userAgent []string, opts TransportOptions, resource string) (*Client, error) {
 	client := &Client{
 		Authentication: auth,
 		BaseURI:        auth.ResourceManagerEndpoint,
@@ -48,19 +111,30 @@ func NewClient(auth *Authentication, userAgent []string) (*Client, error) {
 			return nil, fmt.Errorf("Creating new OAuth config for active directory failed: %v", err)
 		}
 
-		client.spToken, err = adal.NewServicePrincipalToken(*config, auth.ClientID, auth.ClientSecret, auth.ResourceManagerEndpoint)
+		spResource := auth.ResourceManagerEndpoint
+		if resource != "" {
+			spResource = resource
+		}
+		client.spToken, err = adal.NewServicePrincipalToken(*config, auth.ClientID, auth.ClientSecret, spResource)
 		if err != nil {
 			return nil, fmt.Errorf("Creating new service principal token failed: %v", err)
 		}
+		if auth.ControlPlaneProxy != nil {
+			client.spToken.SetSender(&http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(auth.ControlPlaneProxy)}})
+		}
 	} else {
 		endpoint, err := adal.GetMSIVMEndpoint()
 		if err != nil {
 			return nil, fmt.Errorf("Unable to retrieve managed identity endpoint: %v", err)
 		}
 
+		msiResource := auth.ManagementEndpoint
+		if resource != "" {
+			msiResource = resource
+		}
 		client.spToken, err = adal.NewServicePrincipalTokenFromMSIWithUserAssignedID(
 			endpoint,
-			auth.ManagementEndpoint,
+			msiResource,
 			auth.UserIdentityClientId)
 		if err != nil {
 			return nil, fmt.Errorf("Unable to create token provider with managed identity: %v", err)
@@ -77,14 +151,39 @@ func NewClient(auth *Authentication, userAgent []string) (*Client, error) {
 	}
 
 	// As go transport doesn't support a away to force close (not reuse) a specific connection in a selective way
-	// after rountrip completes, we'll disable keepalives.
+	// after rountrip completes, we'll disable keepalives unless asked to keep them.
+	maxIdleConnsPerHost := concurrentConnections
+	if opts.MaxIdleConnsPerHost > 0 {
+		maxIdleConnsPerHost = opts.MaxIdleConnsPerHost
+	}
+	transport := &http.Transport{
+		DisableKeepAlives:   !opts.KeepAlives,
+		MaxIdleConnsPerHost: maxIdleConnsPerHost,
+		IdleConnTimeout:     opts.IdleConnTimeout,
+		ForceAttemptHTTP2:   opts.HTTP2,
+	}
+	if auth.ControlPlaneProxy != nil {
+		transport.Proxy = http.ProxyURL(auth.ControlPlaneProxy)
+	}
 	uat := userAgentTransport{
-		base: &http.Transport{
-			DisableKeepAlives:   true,
-			MaxIdleConnsPerHost: concurrentConnections,
-		},
-		userAgent: nonEmptyUserAgent,
-		client:    client,
+		base:         transport,
+		userAgent:    nonEmptyUserAgent,
+		client:       client,
+		retryWaitMin: defaultRetryWaitMin,
+		retryWaitMax: defaultRetryWaitMax,
+		retryMax:     throttlingAdditionalRetryCount,
+	}
+	if opts.RetryWaitMin > 0 {
+		uat.retryWaitMin = opts.RetryWaitMin
+	}
+	if opts.RetryWaitMax > 0 {
+		uat.retryWaitMax = opts.RetryWaitMax
+	}
+	if uat.retryWaitMax < uat.retryWaitMin {
+		uat.retryWaitMax = uat.retryWaitMin
+	}
+	if opts.RetryMax != nil {
+		uat.retryMax = *opts.RetryMax
 	}
 
 	client.HTTPClient = &http.Client{
@@ -115,6 +214,17 @@ func (t userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error)
 	// Add the content-type header.
 	newReq.Header["Content-Type"] = []string{"application/json"}
 
+	// Add the correlation ID header.
+	if id := CorrelationID(req.Context()); id != "" {
+		newReq.Header.Set(api.CorrelationIDHeader, id)
+	}
+
+	// Identify the request, and all its retries, with the same client request ID.
+	if newReq.Header.Get(api.ClientRequestIDHeader) == "" {
+		newReq.Header.Set(api.ClientRequestIDHeader, uuid.New().String())
+	}
+	newReq.Header.Set("x-ms-return-client-request-id", "true")
+
 	// Refresh the token if necessary
 	// TODO: don't refresh the token everytime
 	refresher, ok := t.client.BearerAuthorizer.tokenProvider.(adal.Refresher)
@@ -127,16 +237,66 @@ func (t userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error)
 	// Add the authorization header.
 	newReq.Header["Authorization"] = []string{fmt.Sprintf("Bearer %s", t.client.BearerAuthorizer.tokenProvider.OAuthToken())}
 
-	var retries int
-	for retries = 0; retries < throttlingAdditionalRetryCount; retries++ {
+	for retries := 0; ; retries++ {
 		response, err := t.base.RoundTrip(&newReq)
-		if err == nil && response.StatusCode == 429 {
-			// We hit throttling, retry to hopefully hit another ARM instance.
-			continue
+		if err != nil || response.StatusCode != http.StatusTooManyRequests || retries >= t.retryMax {
+			return response, err
+		}
+		// The body was sent, only retry the requests whose body can be sent again.
+		hasBody := req.Body != nil && req.Body != http.NoBody
+		if hasBody && req.GetBody == nil {
+			return response, err
+		}
+
+		// We hit throttling, retry after a while to hopefully hit another ARM instance.
+		wait := retryWait(t.retryWaitMin, t.retryWaitMax, retries, response.Header.Get("Retry-After"), time.Now())
+		// Don't wait for a retry the request would have no time for, the caller gets the throttling instead.
+		if deadline, ok := req.Context().Deadline(); ok && time.Now().Add(wait).After(deadline) {
+			return response, err
+		}
+		response.Body.Close()
+		timer := time.NewTimer(wait)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
 		}
 
-		return response, err
+		if hasBody {
+			if newReq.Body, err = req.GetBody(); err != nil {
+				return nil, fmt.Errorf("Failed to rewind the body of request to %s: %v", newReq.URL, err)
+			}
+		}
+	}
+}
+
+// retryWait returns the wait before a retry: an exponential backoff from min, randomized between half and all
+// of it and bounded by max, or the Retry-After of the response if longer, in seconds or as an HTTP date. ARM
+// keeps throttling the requests retried before the Retry-After, so it is honored even when longer than max.
+func retryWait(min, max time.Duration, retries int, retryAfter string, now time.Time) time.Duration {
+	backoff := min
+	for i := 0; i < retries && backoff < max; i++ {
+		backoff *= 2
+	}
+	if backoff > max {
+		backoff = max
 	}
+	wait := backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
 
-	return t.base.RoundTrip(&newReq)
+	if after := parseRetryAfter(retryAfter, now); after > wait {
+		wait = after
+	}
+	return wait
+}
+
+// parseRetryAfter returns the wait of a Retry-After header, or 0 if it is not set or invalid.
+func parseRetryAfter(retryAfter string, now time.Time) time.Duration {
+	if seconds, err := strconv.Atoi(retryAfter); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(retryAfter); err == nil {
+		return t.Sub(now)
+	}
+	return 0
 }
@@ -0,0 +1,160 @@
+package azure
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/virtual-kubelet/azure-aci/client/api"
+)
+
+type staticToken string
+
+func (t staticToken) OAuthToken() string {
+	return string(t)
+}
+
+func TestRetryWait(t *testing.T) {
+	min, max := 100*time.Millisecond, time.Second
+	for retries, bounds := range [][2]time.Duration{
+		{50 * time.Millisecond, 100 * time.Millisecond},
+		{100 * time.Millisecond, 200 * time.Millisecond},
+		{200 * time.Millisecond, 400 * time.Millisecond},
+		{400 * time.Millisecond, 800 * time.Millisecond},
+		{500 * time.Millisecond, time.Second},
+	} {
+		for i := 0; i < 100; i++ {
+			if wait := retryWait(min, max, retries, "", time.Now()); wait < bounds[0] || wait > bounds[1] {
+				t.Fatalf("expected the wait of retry %d between %s and %s, got %s", retries, bounds[0], bounds[1], wait)
+			}
+		}
+	}
+
+	now := time.Now()
+	if wait := retryWait(min, max, 0, "1", now); wait != time.Second {
+		t.Fatalf("expected the wait of the Retry-After header, got %s", wait)
+	}
+	if wait := retryWait(min, max, 0, "120", now); wait != 2*time.Minute {
+		t.Fatalf("expected the Retry-After header honored beyond the maximum wait, got %s", wait)
+	}
+	date := now.Add(30 * time.Second).UTC().Format(http.TimeFormat)
+	if wait := retryWait(min, max, 0, date, now); wait < 29*time.Second || wait > 30*time.Second {
+		t.Fatalf("expected the wait until the Retry-After date, got %s", wait)
+	}
+	if wait := retryWait(min, max, 0, "soon", now); wait > 100*time.Millisecond {
+		t.Fatalf("expected an invalid Retry-After header to be ignored, got %s", wait)
+	}
+}
+
+func TestRoundTripRetryAfterBeyondDeadline(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	transport := userAgentTransport{
+		base:         http.DefaultTransport,
+		client:       &Client{BearerAuthorizer: &BearerAuthorizer{tokenProvider: staticToken("token")}},
+		retryWaitMin: time.Millisecond,
+		retryWaitMax: time.Millisecond,
+		retryMax:     3,
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	req, _ := http.NewRequest("GET", server.URL, nil)
+	start := time.Now()
+	resp, err := transport.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTooManyRequests || requests != 1 {
+		t.Fatalf("expected the throttled response without retry, got %d after %d", resp.StatusCode, requests)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("expected no wait for a retry beyond the deadline, waited %s", elapsed)
+	}
+}
+
+func TestRoundTripRetriesThrottledRequests(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests < 3 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	transport := userAgentTransport{
+		base:         http.DefaultTransport,
+		client:       &Client{BearerAuthorizer: &BearerAuthorizer{tokenProvider: staticToken("token")}},
+		retryWaitMin: time.Millisecond,
+		retryWaitMax: time.Millisecond,
+		retryMax:     3,
+	}
+	req, _ := http.NewRequest("GET", server.URL, nil)
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || requests != 3 {
+		t.Fatalf("expected the throttled request to succeed on the third attempt, got %d after %d", resp.StatusCode, requests)
+	}
+
+	requests = 0
+	transport.retryMax = 1
+	resp, err = transport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTooManyRequests || requests != 2 {
+		t.Fatalf("expected the throttled response after 1 retry, got %d after %d", resp.StatusCode, requests)
+	}
+}
+
+func TestRoundTripRewindsBodies(t *testing.T) {
+	var bodies, requestIDs []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		requestIDs = append(requestIDs, r.Header.Get(api.ClientRequestIDHeader))
+		if len(bodies) < 2 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	transport := userAgentTransport{
+		base:         http.DefaultTransport,
+		client:       &Client{BearerAuthorizer: &BearerAuthorizer{tokenProvider: staticToken("token")}},
+		retryWaitMin: time.Millisecond,
+		retryWaitMax: time.Millisecond,
+		retryMax:     3,
+	}
+	req, _ := http.NewRequest("PUT", server.URL, bytes.NewBufferString(`{"location":"westus"}`))
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if len(bodies) != 2 || bodies[1] != `{"location":"westus"}` {
+		t.Fatalf("expected the body sent again on the retry, got %q", bodies)
+	}
+	if requestIDs[0] == "" || requestIDs[1] != requestIDs[0] {
+		t.Fatalf("expected the same client request ID on the retry, got %q", requestIDs)
+	}
+}
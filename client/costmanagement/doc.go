@@ -0,0 +1,3 @@
+// Package costmanagement provides tools for interacting with the
+// Azure Cost Management query API.
+package costmanagement
@@ -0,0 +1,100 @@
+package costmanagement
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/virtual-kubelet/azure-aci/client/api"
+)
+
+const (
+	// CostColumn is the column of the actual cost before taxes in the results of QueryMonthToDateCostsByTag.
+	CostColumn = "PreTaxCost"
+
+	containerGroupResourceType = "microsoft.containerinstance/containergroups"
+)
+
+// QueryResourceGroup queries the costs of the resources of a resource group.
+// From: https://docs.microsoft.com/en-us/rest/api/cost-management/query/usage
+func (c *Client) QueryResourceGroup(ctx context.Context, resourceGroup string, query Query) (*QueryResult, error) {
+	urlParams := url.Values{
+		"api-version": []string{apiVersion},
+	}
+
+	// Create the url.
+	uri := api.ResolveRelative(c.auth.ResourceManagerEndpoint, resourceGroupQueryURLPath)
+	uri += "?" + url.Values(urlParams).Encode()
+
+	b := new(bytes.Buffer)
+	if err := json.NewEncoder(b).Encode(query); err != nil {
+		return nil, fmt.Errorf("Encoding cost query body failed: %v", err)
+	}
+
+	// Create the request.
+	req, err := http.NewRequest("POST", uri, b)
+	if err != nil {
+		return nil, fmt.Errorf("Creating cost query uri request failed: %v", err)
+	}
+	req = req.WithContext(ctx)
+
+	// Add the parameters to the url.
+	if err := api.ExpandURL(req.URL, map[string]string{
+		"subscriptionId": c.auth.SubscriptionID,
+		"resourceGroup":  resourceGroup,
+	}); err != nil {
+		return nil, fmt.Errorf("Expanding URL with parameters failed: %v", err)
+	}
+
+	// Send the request.
+	resp, err := c.hc.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Sending cost query request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	// 200 (OK) is a success response.
+	if err := api.CheckResponse(resp); err != nil {
+		return nil, err
+	}
+
+	// Decode the body from the response.
+	if resp.Body == nil {
+		return nil, errors.New("Cost query returned an empty body in the response")
+	}
+	var result QueryResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("Decoding cost query response body failed: %v", err)
+	}
+
+	return &result, nil
+}
+
+// QueryMonthToDateCostsByTag returns the actual costs of the month of the container groups of the resource
+// group tagged with filterTag=filterValue, grouped by the values of the groupTag tag.
+func (c *Client) QueryMonthToDateCostsByTag(ctx context.Context, resourceGroup, filterTag, filterValue, groupTag string) ([]TagCost, error) {
+	result, err := c.QueryResourceGroup(ctx, resourceGroup, Query{
+		Type:      "ActualCost",
+		Timeframe: "MonthToDate",
+		Dataset: &QueryDataset{
+			Granularity: "None",
+			Aggregation: map[string]QueryAggregation{
+				"totalCost": {Name: CostColumn, Function: "Sum"},
+			},
+			Grouping: []QueryGrouping{{Type: "TagKey", Name: groupTag}},
+			Filter: &QueryFilter{And: []QueryFilter{
+				{Dimensions: &QueryComparisonFilter{Name: "ResourceType", Operator: "In", Values: []string{containerGroupResourceType}}},
+				{Tags: &QueryComparisonFilter{Name: filterTag, Operator: "In", Values: []string{filterValue}}},
+			}},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result.TagCosts(CostColumn)
+}
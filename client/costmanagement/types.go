@@ -0,0 +1,114 @@
+package costmanagement
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Query is the definition of a cost query.
+type Query struct {
+	Type       string        `json:"type"`
+	Timeframe  string        `json:"timeframe"`
+	TimePeriod *QueryPeriod  `json:"timePeriod,omitempty"`
+	Dataset    *QueryDataset `json:"dataset,omitempty"`
+}
+
+// QueryPeriod is the custom time period of a query.
+type QueryPeriod struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// QueryDataset is the data of a query, aggregated and grouped.
+type QueryDataset struct {
+	Granularity string                      `json:"granularity,omitempty"`
+	Aggregation map[string]QueryAggregation `json:"aggregation,omitempty"`
+	Grouping    []QueryGrouping             `json:"grouping,omitempty"`
+	Filter      *QueryFilter                `json:"filter,omitempty"`
+}
+
+// QueryAggregation is an aggregation of a column.
+type QueryAggregation struct {
+	Name     string `json:"name"`
+	Function string `json:"function"`
+}
+
+// QueryGrouping groups the rows of a query by a dimension or a tag.
+type QueryGrouping struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+}
+
+// QueryFilter filters the rows of a query by dimensions or tags.
+type QueryFilter struct {
+	And        []QueryFilter          `json:"and,omitempty"`
+	Dimensions *QueryComparisonFilter `json:"dimensions,omitempty"`
+	Tags       *QueryComparisonFilter `json:"tags,omitempty"`
+}
+
+// QueryComparisonFilter compares a dimension or a tag with values.
+type QueryComparisonFilter struct {
+	Name     string   `json:"name"`
+	Operator string   `json:"operator"`
+	Values   []string `json:"values"`
+}
+
+// QueryResult is the result of a cost query.
+type QueryResult struct {
+	Properties QueryResultProperties `json:"properties"`
+}
+
+// QueryResultProperties are the columns and rows of the result of a query.
+type QueryResultProperties struct {
+	NextLink string          `json:"nextLink,omitempty"`
+	Columns  []QueryColumn   `json:"columns,omitempty"`
+	Rows     [][]interface{} `json:"rows,omitempty"`
+}
+
+// QueryColumn is a column of the result of a query.
+type QueryColumn struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// TagCost is the cost of the resources with a value of a tag.
+type TagCost struct {
+	TagValue string
+	Cost     float64
+	Currency string
+}
+
+func (r *QueryResult) column(name string) int {
+	for i, c := range r.Properties.Columns {
+		if strings.EqualFold(c.Name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// TagCosts returns the costs of the rows of a query grouped by tag and aggregated in the cost column.
+func (r *QueryResult) TagCosts(costColumn string) ([]TagCost, error) {
+	cost, value, currency := r.column(costColumn), r.column("TagValue"), r.column("Currency")
+	if cost < 0 || value < 0 {
+		return nil, fmt.Errorf("Query result has no %s or TagValue column", costColumn)
+	}
+
+	costs := make([]TagCost, 0, len(r.Properties.Rows))
+	for _, row := range r.Properties.Rows {
+		if len(row) != len(r.Properties.Columns) {
+			return nil, fmt.Errorf("Query result row has %d values for %d columns", len(row), len(r.Properties.Columns))
+		}
+		c, ok := row[cost].(float64)
+		if !ok {
+			return nil, fmt.Errorf("Query result cost %v is not a number", row[cost])
+		}
+		tc := TagCost{Cost: c}
+		tc.TagValue, _ = row[value].(string)
+		if currency >= 0 {
+			tc.Currency, _ = row[currency].(string)
+		}
+		costs = append(costs, tc)
+	}
+	return costs, nil
+}
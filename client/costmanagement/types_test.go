@@ -0,0 +1,36 @@
+package costmanagement
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestTagCosts(t *testing.T) {
+	var result QueryResult
+	body := `{"properties": {
+		"columns": [{"name": "PreTaxCost", "type": "Number"}, {"name": "TagKey", "type": "String"}, {"name": "TagValue", "type": "String"}, {"name": "Currency", "type": "String"}],
+		"rows": [[12.5, "namespace", "team-a", "USD"], [0.25, "namespace", "", "USD"]]
+	}}`
+	if err := json.Unmarshal([]byte(body), &result); err != nil {
+		t.Fatal(err)
+	}
+
+	costs, err := result.TagCosts(CostColumn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(costs) != 2 {
+		t.Fatalf("expected 2 costs, got %d", len(costs))
+	}
+	if costs[0] != (TagCost{TagValue: "team-a", Cost: 12.5, Currency: "USD"}) {
+		t.Fatalf("unexpected cost %+v", costs[0])
+	}
+	if costs[1].TagValue != "" || costs[1].Cost != 0.25 {
+		t.Fatalf("unexpected untagged cost %+v", costs[1])
+	}
+
+	result.Properties.Rows = [][]interface{}{{"12.5", "namespace", "team-a", "USD"}}
+	if _, err := result.TagCosts(CostColumn); err == nil {
+		t.Fatal("expected an error for a cost which is not a number")
+	}
+}
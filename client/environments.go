@@ -1,5 +1,7 @@
 package azure
 
+import "strings"
+
 const (
 	// EnvironmentFilepathName defines the name of the environment variable
 	// containing the path to the file to be used to populate the Azure Environment.
@@ -112,3 +114,14 @@ var (
 		ContainerRegistryDNSSuffix:   "azurecr.io",
 	}
 )
+
+// EnvironmentForResourceManager returns the cloud environment of a Resource Manager endpoint,
+// defaulting to the public cloud.
+func EnvironmentForResourceManager(endpoint string) Environment {
+	for _, env := range []Environment{PublicCloud, USGovernmentCloud, ChinaCloud, GermanCloud} {
+		if strings.TrimSuffix(env.ResourceManagerEndpoint, "/") == strings.TrimSuffix(endpoint, "/") {
+			return env
+		}
+	}
+	return PublicCloud
+}
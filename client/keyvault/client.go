@@ -0,0 +1,58 @@
+package keyvault
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	azure "github.com/virtual-kubelet/azure-aci/client"
+)
+
+const (
+	defaultUserAgent = "virtual-kubelet/azure-keyvault/7.0"
+	apiVersion       = "7.0"
+
+	secretURLPath      = "secrets/{{.name}}/{{.version}}"
+	certificateURLPath = "certificates/{{.name}}/{{.version}}"
+	unwrapKeyURLPath   = "keys/{{.name}}/{{.version}}/unwrapkey"
+)
+
+var vaultNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9-]{3,24}$`)
+
+// Client is a client for reading Azure Key Vaults and unwrapping keys with them, in the cloud of its authentication.
+//
+// Clients should be reused instead of created as needed.
+// The methods of Client are safe for concurrent use by multiple goroutines.
+type Client struct {
+	hc        *http.Client
+	dnsSuffix string
+}
+
+// NewClient creates a new Azure Key Vault client.
+func NewClient(auth *azure.Authentication, extraUserAgent string) (*Client, error) {
+	if auth == nil {
+		return nil, fmt.Errorf("Authentication is not supplied for the Azure client")
+	}
+
+	userAgent := []string{defaultUserAgent}
+	if extraUserAgent != "" {
+		userAgent = append(userAgent, extraUserAgent)
+	}
+
+	env := azure.EnvironmentForResourceManager(auth.ResourceManagerEndpoint)
+	client, err := azure.NewClientForResource(auth, userAgent, strings.TrimSuffix(env.KeyVaultEndpoint, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("Creating Azure client failed: %v", err)
+	}
+
+	return &Client{hc: client.HTTPClient, dnsSuffix: env.KeyVaultDNSSuffix}, nil
+}
+
+// vaultURL returns the URL of a Key Vault.
+func (c *Client) vaultURL(vaultName string) (string, error) {
+	if !vaultNameRegexp.MatchString(vaultName) {
+		return "", fmt.Errorf("Invalid Key Vault name %q", vaultName)
+	}
+	return "https://" + vaultName + "." + c.dnsSuffix + "/", nil
+}
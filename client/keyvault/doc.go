@@ -0,0 +1,3 @@
+// Package keyvault provides tools for reading the secrets and certificates
+// of Azure Key Vaults.
+package keyvault
@@ -0,0 +1,84 @@
+package keyvault
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/virtual-kubelet/azure-aci/client/api"
+)
+
+// GetSecret gets a version of a secret of a Key Vault, the latest one if version is empty.
+// From: https://docs.microsoft.com/en-us/rest/api/keyvault/getsecret/getsecret
+func (c *Client) GetSecret(ctx context.Context, vaultName, name, version string) (*SecretBundle, error) {
+	var secret SecretBundle
+	if err := c.get(ctx, vaultName, secretURLPath, name, version, "secret", &secret); err != nil {
+		return nil, err
+	}
+	return &secret, nil
+}
+
+// GetCertificate gets a version of a certificate of a Key Vault, the latest one if version is empty.
+// From: https://docs.microsoft.com/en-us/rest/api/keyvault/getcertificate/getcertificate
+func (c *Client) GetCertificate(ctx context.Context, vaultName, name, version string) (*CertificateBundle, error) {
+	var certificate CertificateBundle
+	if err := c.get(ctx, vaultName, certificateURLPath, name, version, "certificate", &certificate); err != nil {
+		return nil, err
+	}
+	return &certificate, nil
+}
+
+func (c *Client) get(ctx context.Context, vaultName, urlPath, name, version, kind string, v interface{}) error {
+	urlParams := url.Values{
+		"api-version": []string{apiVersion},
+	}
+
+	base, err := c.vaultURL(vaultName)
+	if err != nil {
+		return err
+	}
+
+	// Create the url.
+	uri := api.ResolveRelative(base, urlPath)
+	uri += "?" + url.Values(urlParams).Encode()
+
+	// Create the request.
+	req, err := http.NewRequest("GET", uri, nil)
+	if err != nil {
+		return fmt.Errorf("Creating get %s uri request failed: %v", kind, err)
+	}
+	req = req.WithContext(ctx)
+
+	// Add the parameters to the url.
+	if err := api.ExpandURL(req.URL, map[string]string{
+		"name":    name,
+		"version": version,
+	}); err != nil {
+		return fmt.Errorf("Expanding URL with parameters failed: %v", err)
+	}
+
+	// Send the request.
+	resp, err := c.hc.Do(req)
+	if err != nil {
+		return fmt.Errorf("Sending get %s request failed: %v", kind, err)
+	}
+	defer resp.Body.Close()
+
+	// 200 (OK) is a success response.
+	if err := api.CheckResponse(resp); err != nil {
+		return err
+	}
+
+	// Decode the body from the response.
+	if resp.Body == nil {
+		return errors.New("Get " + kind + " returned an empty body in the response")
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("Decoding get %s response body failed: %v", kind, err)
+	}
+
+	return nil
+}
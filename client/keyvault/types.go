@@ -0,0 +1,29 @@
+package keyvault
+
+// SecretBundle is a secret of a Key Vault.
+type SecretBundle struct {
+	ID          string `json:"id,omitempty"`
+	Value       string `json:"value,omitempty"`
+	ContentType string `json:"contentType,omitempty"`
+}
+
+// CertificateBundle is a certificate of a Key Vault.
+type CertificateBundle struct {
+	ID string `json:"id,omitempty"`
+	// Cer is the public certificate, DER encoded.
+	Cer []byte `json:"cer,omitempty"`
+}
+
+// KeyOperationsParameters are the parameters of a cryptographic operation with a key of a Key Vault.
+type KeyOperationsParameters struct {
+	Algorithm string `json:"alg"`
+	// Value is base64url encoded, without padding.
+	Value string `json:"value"`
+}
+
+// KeyOperationResult is the result of a cryptographic operation with a key of a Key Vault.
+type KeyOperationResult struct {
+	KeyID string `json:"kid,omitempty"`
+	// Value is base64url encoded, without padding.
+	Value string `json:"value,omitempty"`
+}
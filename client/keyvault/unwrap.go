@@ -0,0 +1,84 @@
+package keyvault
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/virtual-kubelet/azure-aci/client/api"
+)
+
+// UnwrapKeyAlgorithm is the algorithm keys are wrapped with by the RSA keys of a Key Vault.
+const UnwrapKeyAlgorithm = "RSA-OAEP-256"
+
+// UnwrapKey unwraps a key wrapped with a version of a key of a Key Vault, the latest one if version is empty.
+// From: https://docs.microsoft.com/en-us/rest/api/keyvault/unwrapkey/unwrapkey
+func (c *Client) UnwrapKey(ctx context.Context, vaultName, name, version string, wrapped []byte) ([]byte, error) {
+	urlParams := url.Values{
+		"api-version": []string{apiVersion},
+	}
+
+	base, err := c.vaultURL(vaultName)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create the url.
+	uri := api.ResolveRelative(base, unwrapKeyURLPath)
+	uri += "?" + url.Values(urlParams).Encode()
+
+	b := new(bytes.Buffer)
+	if err := json.NewEncoder(b).Encode(KeyOperationsParameters{
+		Algorithm: UnwrapKeyAlgorithm,
+		Value:     base64.RawURLEncoding.EncodeToString(wrapped),
+	}); err != nil {
+		return nil, fmt.Errorf("Encoding unwrap key body failed: %v", err)
+	}
+
+	// Create the request.
+	req, err := http.NewRequest("POST", uri, b)
+	if err != nil {
+		return nil, fmt.Errorf("Creating unwrap key uri request failed: %v", err)
+	}
+	req = req.WithContext(ctx)
+
+	// Add the parameters to the url.
+	if err := api.ExpandURL(req.URL, map[string]string{
+		"name":    name,
+		"version": version,
+	}); err != nil {
+		return nil, fmt.Errorf("Expanding URL with parameters failed: %v", err)
+	}
+
+	// Send the request.
+	resp, err := c.hc.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Sending unwrap key request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	// 200 (OK) is a success response.
+	if err := api.CheckResponse(resp); err != nil {
+		return nil, err
+	}
+
+	// Decode the body from the response.
+	if resp.Body == nil {
+		return nil, errors.New("Unwrap key returned an empty body in the response")
+	}
+	var result KeyOperationResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("Decoding unwrap key response body failed: %v", err)
+	}
+
+	key, err := base64.RawURLEncoding.DecodeString(result.Value)
+	if err != nil {
+		return nil, fmt.Errorf("Decoding unwrapped key failed: %v", err)
+	}
+	return key, nil
+}
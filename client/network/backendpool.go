@@ -0,0 +1,257 @@
+package network
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/virtual-kubelet/azure-aci/client/api"
+)
+
+const (
+	// backendPoolAPIVersion is the api version supporting the IP based backend pools of load balancers.
+	backendPoolAPIVersion = "2020-06-01"
+
+	loadBalancerPoolPath           = "subscriptions/{{.subscriptionId}}/resourceGroups/{{.resourceGroupName}}/providers/Microsoft.Network/loadBalancers/{{.name}}/backendAddressPools/{{.poolName}}"
+	applicationGatewayPath         = "subscriptions/{{.subscriptionId}}/resourceGroups/{{.resourceGroupName}}/providers/Microsoft.Network/applicationGateways/{{.name}}"
+	loadBalancerResourceType       = "loadBalancers"
+	applicationGatewayResourceType = "applicationGateways"
+)
+
+// BackendAddress is an IP address of the backend pool of a load balancer or an application gateway.
+type BackendAddress struct {
+	// Name is the name of the address, only kept by load balancers.
+	Name      string
+	IPAddress string
+	// VirtualNetworkID is the virtual network of the address, required by load balancers.
+	VirtualNetworkID string
+}
+
+// BackendPool is the backend address pool of an Azure load balancer or application gateway.
+type BackendPool struct {
+	ResourceGroup string
+	// ResourceType is loadBalancers or applicationGateways.
+	ResourceType string
+	Name         string
+	PoolName     string
+}
+
+// ParseBackendPoolID parses the resource ID of the backend address pool of a load balancer or an application
+// gateway of the subscription, like /subscriptions/<id>/resourceGroups/<group>/providers/Microsoft.Network/loadBalancers/<name>/backendAddressPools/<pool>.
+func ParseBackendPoolID(subscriptionID, id string) (*BackendPool, error) {
+	parts := strings.Split(strings.Trim(id, "/"), "/")
+	if len(parts) != 10 ||
+		!strings.EqualFold(parts[0], "subscriptions") ||
+		!strings.EqualFold(parts[2], "resourceGroups") ||
+		!strings.EqualFold(parts[4], "providers") ||
+		!strings.EqualFold(parts[5], "Microsoft.Network") ||
+		!strings.EqualFold(parts[8], "backendAddressPools") {
+		return nil, fmt.Errorf("%q is not the resource ID of a backend address pool", id)
+	}
+	if !strings.EqualFold(parts[1], subscriptionID) {
+		return nil, fmt.Errorf("backend address pool %q is not in subscription %s", id, subscriptionID)
+	}
+
+	pool := &BackendPool{ResourceGroup: parts[3], Name: parts[7], PoolName: parts[9]}
+	switch {
+	case strings.EqualFold(parts[6], loadBalancerResourceType):
+		pool.ResourceType = loadBalancerResourceType
+	case strings.EqualFold(parts[6], applicationGatewayResourceType):
+		pool.ResourceType = applicationGatewayResourceType
+	default:
+		return nil, fmt.Errorf("backend address pool %q is not a pool of a load balancer or an application gateway", id)
+	}
+	return pool, nil
+}
+
+// UpdateBackendPoolAddresses replaces the addresses of a backend pool by the addresses returned by update,
+// called with the current addresses. The pool is read and written back as is, so the properties it has
+// beyond its addresses are kept, and the write fails if the resource changed since it was read.
+// From: https://docs.microsoft.com/en-us/rest/api/load-balancer/load-balancer-backend-address-pools/create-or-update
+// and https://docs.microsoft.com/en-us/rest/api/application-gateway/application-gateways/create-or-update
+func (c *Client) UpdateBackendPoolAddresses(ctx context.Context, pool *BackendPool, update func([]BackendAddress) []BackendAddress) error {
+	path := loadBalancerPoolPath
+	if pool.ResourceType == applicationGatewayResourceType {
+		path = applicationGatewayPath
+	}
+	params := map[string]string{
+		"subscriptionId":    c.auth.SubscriptionID,
+		"resourceGroupName": pool.ResourceGroup,
+		"name":              pool.Name,
+		"poolName":          pool.PoolName,
+	}
+
+	resource, etag, err := c.getRawResource(ctx, path, params)
+	if err != nil {
+		return err
+	}
+
+	if pool.ResourceType == applicationGatewayResourceType {
+		err = updateApplicationGatewayPool(resource, pool.PoolName, update)
+	} else {
+		err = updateLoadBalancerPool(resource, update)
+	}
+	if err != nil {
+		return err
+	}
+
+	return c.putRawResource(ctx, path, params, resource, etag)
+}
+
+// updateLoadBalancerPool updates the loadBalancerBackendAddresses of a load balancer backend pool.
+func updateLoadBalancerPool(pool map[string]interface{}, update func([]BackendAddress) []BackendAddress) error {
+	props := objectField(pool, "properties")
+	var current []BackendAddress
+	for _, a := range arrayField(props, "loadBalancerBackendAddresses") {
+		address, _ := a.(map[string]interface{})
+		addressProps := objectField(address, "properties")
+		current = append(current, BackendAddress{
+			Name:             stringField(address, "name"),
+			IPAddress:        stringField(addressProps, "ipAddress"),
+			VirtualNetworkID: stringField(objectField(addressProps, "virtualNetwork"), "id"),
+		})
+	}
+
+	addresses := []interface{}{}
+	for _, a := range update(current) {
+		addressProps := map[string]interface{}{"ipAddress": a.IPAddress}
+		if a.VirtualNetworkID != "" {
+			addressProps["virtualNetwork"] = map[string]interface{}{"id": a.VirtualNetworkID}
+		}
+		addresses = append(addresses, map[string]interface{}{"name": a.Name, "properties": addressProps})
+	}
+	props["loadBalancerBackendAddresses"] = addresses
+	pool["properties"] = props
+	return nil
+}
+
+// updateApplicationGatewayPool updates the backendAddresses of a backend pool of an application gateway.
+func updateApplicationGatewayPool(gateway map[string]interface{}, poolName string, update func([]BackendAddress) []BackendAddress) error {
+	for _, p := range arrayField(objectField(gateway, "properties"), "backendAddressPools") {
+		pool, _ := p.(map[string]interface{})
+		if !strings.EqualFold(stringField(pool, "name"), poolName) {
+			continue
+		}
+
+		props := objectField(pool, "properties")
+		var current []BackendAddress
+		var fqdns []interface{}
+		for _, a := range arrayField(props, "backendAddresses") {
+			address, _ := a.(map[string]interface{})
+			if ip := stringField(address, "ipAddress"); ip != "" {
+				current = append(current, BackendAddress{IPAddress: ip})
+				continue
+			}
+			// The addresses by FQDN are not IP addresses of pods, they are kept as is.
+			fqdns = append(fqdns, a)
+		}
+
+		addresses := append([]interface{}{}, fqdns...)
+		for _, a := range update(current) {
+			addresses = append(addresses, map[string]interface{}{"ipAddress": a.IPAddress})
+		}
+		props["backendAddresses"] = addresses
+		pool["properties"] = props
+		return nil
+	}
+	return fmt.Errorf("application gateway %s has no backend address pool %s", stringField(gateway, "name"), poolName)
+}
+
+func objectField(o map[string]interface{}, name string) map[string]interface{} {
+	if v, ok := o[name].(map[string]interface{}); ok {
+		return v
+	}
+	return map[string]interface{}{}
+}
+
+func arrayField(o map[string]interface{}, name string) []interface{} {
+	v, _ := o[name].([]interface{})
+	return v
+}
+
+func stringField(o map[string]interface{}, name string) string {
+	v, _ := o[name].(string)
+	return v
+}
+
+func (c *Client) getRawResource(ctx context.Context, path string, params map[string]string) (map[string]interface{}, string, error) {
+	urlParams := url.Values{
+		"api-version": []string{backendPoolAPIVersion},
+	}
+
+	// Create the url.
+	uri := api.ResolveRelative(c.auth.ResourceManagerEndpoint, path)
+	uri += "?" + url.Values(urlParams).Encode()
+
+	req, err := http.NewRequest("GET", uri, nil)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "creating backend pool get uri request failed")
+	}
+	req = req.WithContext(ctx)
+
+	if err := api.ExpandURL(req.URL, params); err != nil {
+		return nil, "", errors.Wrap(err, "expanding URL with parameters failed")
+	}
+
+	// Send the request.
+	resp, err := c.hc.Do(req)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "sending backend pool get request failed")
+	}
+	defer resp.Body.Close()
+
+	// 200 (OK) is a success response.
+	if err := api.CheckResponse(resp); err != nil {
+		return nil, "", err
+	}
+
+	var resource map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&resource); err != nil {
+		return nil, "", errors.Wrap(err, "decoding backend pool get response body failed")
+	}
+	return resource, stringField(resource, "etag"), nil
+}
+
+func (c *Client) putRawResource(ctx context.Context, path string, params map[string]string, resource map[string]interface{}, etag string) error {
+	urlParams := url.Values{
+		"api-version": []string{backendPoolAPIVersion},
+	}
+
+	// Create the url.
+	uri := api.ResolveRelative(c.auth.ResourceManagerEndpoint, path)
+	uri += "?" + url.Values(urlParams).Encode()
+
+	// Create the request.
+	b, err := json.Marshal(resource)
+	if err != nil {
+		return errors.Wrap(err, "marshalling backend pool failed")
+	}
+
+	req, err := http.NewRequest("PUT", uri, bytes.NewReader(b))
+	if err != nil {
+		return errors.Wrap(err, "creating backend pool update uri request failed")
+	}
+	req = req.WithContext(ctx)
+	if etag != "" {
+		req.Header.Set("If-Match", etag)
+	}
+
+	if err := api.ExpandURL(req.URL, params); err != nil {
+		return errors.Wrap(err, "expanding URL with parameters failed")
+	}
+
+	// Send the request.
+	resp, err := c.hc.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "sending backend pool update request failed")
+	}
+	defer resp.Body.Close()
+
+	// 200 (OK) and 201 (Created) are successful responses.
+	return api.CheckResponse(resp)
+}
@@ -54,6 +54,9 @@ func NewClient(azAuth *azure.Authentication, extraUserAgent string) (*Client, er
 
 	sc := network.NewSubnetsClientWithBaseURI(azAuth.ResourceManagerEndpoint, azAuth.SubscriptionID)
 	sc.Authorizer = networkAuth
+	if azAuth.ControlPlaneProxy != nil {
+		sc.Sender = &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(azAuth.ControlPlaneProxy)}}
+	}
 
 	return &Client{
 		sc:   sc,
@@ -74,6 +77,19 @@ func IsNotFound(err error) bool {
 	}
 }
 
+// IsForbidden determines if the passed in error is returned by the API because the identity
+// of the client is not authorized to perform the operation.
+func IsForbidden(err error) bool {
+	switch e := err.(type) {
+	case nil:
+		return false
+	case *api.Error:
+		return e.StatusCode == http.StatusForbidden
+	default:
+		return false
+	}
+}
+
 func NewClientConfigByCloud(azAuth *azure.Authentication) auth.ClientCredentialsConfig {
 	return auth.ClientCredentialsConfig{
 		ClientID:     azAuth.ClientID,
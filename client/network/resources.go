@@ -0,0 +1,58 @@
+package network
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	"github.com/Azure/azure-sdk-for-go/services/network/mgmt/2018-08-01/network"
+	"github.com/pkg/errors"
+	"github.com/virtual-kubelet/azure-aci/client/api"
+)
+
+// GetSecurityGroup gets a network security group by its resource ID, such as the security group of a subnet.
+func (c *Client) GetSecurityGroup(id string) (*network.SecurityGroup, error) {
+	var nsg network.SecurityGroup
+	if err := c.getByID(id, &nsg); err != nil {
+		return nil, errors.Wrap(err, "getting network security group failed")
+	}
+	return &nsg, nil
+}
+
+// GetRouteTable gets a route table by its resource ID, such as the route table of a subnet.
+func (c *Client) GetRouteTable(id string) (*network.RouteTable, error) {
+	var rt network.RouteTable
+	if err := c.getByID(id, &rt); err != nil {
+		return nil, errors.Wrap(err, "getting route table failed")
+	}
+	return &rt, nil
+}
+
+func (c *Client) getByID(id string, v interface{}) error {
+	urlParams := url.Values{
+		"api-version": []string{apiVersion},
+	}
+
+	// Create the url.
+	uri := api.ResolveRelative(c.sc.BaseClient.BaseURI, id)
+	uri += "?" + url.Values(urlParams).Encode()
+
+	req, err := http.NewRequest("GET", uri, nil)
+	if err != nil {
+		return errors.Wrap(err, "creating get uri request failed")
+	}
+
+	// Send the request.
+	resp, err := c.hc.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "sending get request failed")
+	}
+	defer resp.Body.Close()
+
+	// 200 (OK) is a success response.
+	if err := api.CheckResponse(resp); err != nil {
+		return err
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,75 @@
+package network
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	"github.com/Azure/azure-sdk-for-go/services/network/mgmt/2018-08-01/network"
+	"github.com/pkg/errors"
+	"github.com/virtual-kubelet/azure-aci/client/api"
+)
+
+// CreateOrUpdateSecurityRule creates or updates a rule of the network security group with the resource ID.
+// From: https://docs.microsoft.com/en-us/rest/api/virtualnetwork/securityrules/createorupdate
+func (c *Client) CreateOrUpdateSecurityRule(securityGroupID string, rule *network.SecurityRule) error {
+	urlParams := url.Values{
+		"api-version": []string{apiVersion},
+	}
+
+	// Create the url.
+	uri := api.ResolveRelative(c.sc.BaseClient.BaseURI, securityGroupID+"/securityRules/"+url.PathEscape(*rule.Name))
+	uri += "?" + url.Values(urlParams).Encode()
+
+	// Create the request.
+	b, err := json.Marshal(rule)
+	if err != nil {
+		return errors.Wrap(err, "marshalling security rule failed")
+	}
+
+	req, err := http.NewRequest("PUT", uri, bytes.NewReader(b))
+	if err != nil {
+		return errors.Wrap(err, "creating security rule create uri request failed")
+	}
+
+	// Send the request.
+	resp, err := c.hc.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "sending create security rule request failed")
+	}
+	defer resp.Body.Close()
+
+	// 200 (OK) and 201 (Created) are successful responses.
+	return api.CheckResponse(resp)
+}
+
+// DeleteSecurityRule deletes a rule of the network security group with the resource ID.
+// From: https://docs.microsoft.com/en-us/rest/api/virtualnetwork/securityrules/delete
+func (c *Client) DeleteSecurityRule(securityGroupID, name string) error {
+	urlParams := url.Values{
+		"api-version": []string{apiVersion},
+	}
+
+	// Create the url.
+	uri := api.ResolveRelative(c.sc.BaseClient.BaseURI, securityGroupID+"/securityRules/"+url.PathEscape(name))
+	uri += "?" + url.Values(urlParams).Encode()
+
+	req, err := http.NewRequest("DELETE", uri, nil)
+	if err != nil {
+		return errors.Wrap(err, "creating security rule delete uri request failed")
+	}
+
+	// Send the request.
+	resp, err := c.hc.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "sending delete security rule request failed")
+	}
+	defer resp.Body.Close()
+
+	// 200 (OK), 202 (Accepted) and 204 (No Content) are successful responses.
+	if err := api.CheckResponse(resp); err != nil && !IsNotFound(err) {
+		return err
+	}
+	return nil
+}
@@ -28,13 +28,7 @@ func NewSubnetWithContainerInstanceDelegation(name, addressPrefix string) *netwo
 		SubnetPropertiesFormat: &network.SubnetPropertiesFormat{
 			AddressPrefix: &addressPrefix,
 			Delegations: &[]network.Delegation{
-				network.Delegation{
-					Name: &delegationName,
-					ServiceDelegationPropertiesFormat: &network.ServiceDelegationPropertiesFormat{
-						ServiceName: &serviceName,
-						Actions:     &[]string{subnetAction},
-					},
-				},
+				newContainerInstanceDelegation(),
 			},
 		},
 	}
@@ -42,6 +36,30 @@ func NewSubnetWithContainerInstanceDelegation(name, addressPrefix string) *netwo
 	return &subnet
 }
 
+// AddContainerInstanceDelegation adds the ACI delegation to an existing subnet, keeping its other properties
+// such as its security group and route table, so the subnet can be updated in place.
+func AddContainerInstanceDelegation(subnet *network.Subnet) {
+	if subnet.SubnetPropertiesFormat == nil {
+		subnet.SubnetPropertiesFormat = &network.SubnetPropertiesFormat{}
+	}
+	var delegations []network.Delegation
+	if subnet.SubnetPropertiesFormat.Delegations != nil {
+		delegations = *subnet.SubnetPropertiesFormat.Delegations
+	}
+	delegations = append(delegations, newContainerInstanceDelegation())
+	subnet.SubnetPropertiesFormat.Delegations = &delegations
+}
+
+func newContainerInstanceDelegation() network.Delegation {
+	return network.Delegation{
+		Name: &delegationName,
+		ServiceDelegationPropertiesFormat: &network.ServiceDelegationPropertiesFormat{
+			ServiceName: &serviceName,
+			Actions:     &[]string{subnetAction},
+		},
+	}
+}
+
 // GetSubnet gets the subnet from the specified resourcegroup/vnet
 func (c *Client) GetSubnet(resourceGroup, vnet, name string) (*network.Subnet, error) {
 	urlParams := url.Values{
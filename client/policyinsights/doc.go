@@ -0,0 +1,3 @@
+// Package policyinsights provides tools for interacting with the
+// Azure Policy Insights API.
+package policyinsights
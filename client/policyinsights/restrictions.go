@@ -0,0 +1,68 @@
+package policyinsights
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/virtual-kubelet/azure-aci/client/api"
+)
+
+// CheckPolicyRestrictions evaluates the policies assigned to a resource group against a resource before it is created.
+// From: https://docs.microsoft.com/en-us/rest/api/policy/policy-restrictions/check-at-resource-group-scope
+func (c *Client) CheckPolicyRestrictions(ctx context.Context, resourceGroup string, request CheckRestrictionsRequest) (*CheckRestrictionsResult, error) {
+	urlParams := url.Values{
+		"api-version": []string{apiVersion},
+	}
+
+	// Create the url.
+	uri := api.ResolveRelative(c.auth.ResourceManagerEndpoint, resourceGroupCheckRestrictionsURLPath)
+	uri += "?" + url.Values(urlParams).Encode()
+
+	b := new(bytes.Buffer)
+	if err := json.NewEncoder(b).Encode(request); err != nil {
+		return nil, fmt.Errorf("Encoding check policy restrictions body failed: %v", err)
+	}
+
+	// Create the request.
+	req, err := http.NewRequest("POST", uri, b)
+	if err != nil {
+		return nil, fmt.Errorf("Creating check policy restrictions uri request failed: %v", err)
+	}
+	req = req.WithContext(ctx)
+
+	// Add the parameters to the url.
+	if err := api.ExpandURL(req.URL, map[string]string{
+		"subscriptionId": c.auth.SubscriptionID,
+		"resourceGroup":  resourceGroup,
+	}); err != nil {
+		return nil, fmt.Errorf("Expanding URL with parameters failed: %v", err)
+	}
+
+	// Send the request.
+	resp, err := c.hc.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Sending check policy restrictions request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	// 200 (OK) is a success response.
+	if err := api.CheckResponse(resp); err != nil {
+		return nil, err
+	}
+
+	// Decode the body from the response.
+	if resp.Body == nil {
+		return nil, errors.New("Check policy restrictions returned an empty body in the response")
+	}
+	var result CheckRestrictionsResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("Decoding check policy restrictions response body failed: %v", err)
+	}
+
+	return &result, nil
+}
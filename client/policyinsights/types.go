@@ -0,0 +1,73 @@
+package policyinsights
+
+import (
+	"strings"
+)
+
+// CheckRestrictionsRequest is the resource to check against the policies assigned to its scope.
+type CheckRestrictionsRequest struct {
+	ResourceDetails CheckRestrictionsResourceDetails `json:"resourceDetails"`
+}
+
+// CheckRestrictionsResourceDetails is the content of the resource to check, as sent to create it.
+type CheckRestrictionsResourceDetails struct {
+	ResourceContent interface{} `json:"resourceContent"`
+	APIVersion      string      `json:"apiVersion,omitempty"`
+	Scope           string      `json:"scope,omitempty"`
+}
+
+// CheckRestrictionsResult is the evaluation of the policies against the resource.
+type CheckRestrictionsResult struct {
+	ContentEvaluationResult ContentEvaluationResult `json:"contentEvaluationResult"`
+}
+
+// ContentEvaluationResult is the evaluation of the policies against the content of the resource.
+type ContentEvaluationResult struct {
+	PolicyEvaluations []PolicyEvaluationResult `json:"policyEvaluations,omitempty"`
+}
+
+// PolicyEvaluationResult is the evaluation of a policy against the resource.
+type PolicyEvaluationResult struct {
+	PolicyInfo       PolicyReference `json:"policyInfo"`
+	EvaluationResult string          `json:"evaluationResult,omitempty"`
+	EffectDetails    *EffectDetails  `json:"effectDetails,omitempty"`
+}
+
+// PolicyReference identifies the policy, and the assignment applying it.
+type PolicyReference struct {
+	PolicyDefinitionID          string `json:"policyDefinitionId,omitempty"`
+	PolicySetDefinitionID       string `json:"policySetDefinitionId,omitempty"`
+	PolicyDefinitionReferenceID string `json:"policyDefinitionReferenceId,omitempty"`
+	PolicyAssignmentID          string `json:"policyAssignmentId,omitempty"`
+}
+
+// EffectDetails is the effect of a policy on the resource.
+type EffectDetails struct {
+	PolicyEffect string `json:"policyEffect,omitempty"`
+}
+
+// Denials returns the evaluations of the policies which deny the resource.
+func (r *CheckRestrictionsResult) Denials() []PolicyEvaluationResult {
+	var denials []PolicyEvaluationResult
+	for _, e := range r.ContentEvaluationResult.PolicyEvaluations {
+		if e.EvaluationResult != "NonCompliant" || e.EffectDetails == nil || !strings.EqualFold(e.EffectDetails.PolicyEffect, "Deny") {
+			continue
+		}
+		denials = append(denials, e)
+	}
+	return denials
+}
+
+// PolicyName returns the name of the policy definition, the last segment of its ID.
+func (p PolicyReference) PolicyName() string {
+	return resourceName(p.PolicyDefinitionID)
+}
+
+// AssignmentName returns the name of the policy assignment, the last segment of its ID.
+func (p PolicyReference) AssignmentName() string {
+	return resourceName(p.PolicyAssignmentID)
+}
+
+func resourceName(id string) string {
+	return id[strings.LastIndex(id, "/")+1:]
+}
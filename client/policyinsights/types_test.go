@@ -0,0 +1,32 @@
+package policyinsights
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDenials(t *testing.T) {
+	var result CheckRestrictionsResult
+	body := `{"fieldRestrictions": [], "contentEvaluationResult": {"policyEvaluations": [
+		{"policyInfo": {"policyDefinitionId": "/providers/Microsoft.Authorization/policyDefinitions/no-public-ip", "policyAssignmentId": "/subscriptions/sub/providers/Microsoft.Authorization/policyAssignments/network"},
+			"evaluationResult": "NonCompliant", "effectDetails": {"policyEffect": "Deny"}},
+		{"policyInfo": {"policyDefinitionId": "/providers/Microsoft.Authorization/policyDefinitions/audit-tags"},
+			"evaluationResult": "NonCompliant", "effectDetails": {"policyEffect": "Audit"}},
+		{"policyInfo": {"policyDefinitionId": "/providers/Microsoft.Authorization/policyDefinitions/allowed-locations"},
+			"evaluationResult": "Compliant", "effectDetails": {"policyEffect": "Deny"}}
+	]}}`
+	if err := json.Unmarshal([]byte(body), &result); err != nil {
+		t.Fatal(err)
+	}
+
+	denials := result.Denials()
+	if len(denials) != 1 {
+		t.Fatalf("expected 1 denial, got %d", len(denials))
+	}
+	if name := denials[0].PolicyInfo.PolicyName(); name != "no-public-ip" {
+		t.Fatalf("unexpected policy name %q", name)
+	}
+	if name := denials[0].PolicyInfo.AssignmentName(); name != "network" {
+		t.Fatalf("unexpected assignment name %q", name)
+	}
+}
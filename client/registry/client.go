@@ -0,0 +1,96 @@
+package registry
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	defaultUserAgent = "virtual-kubelet/registry"
+	defaultTimeout   = 30 * time.Second
+
+	// dockerHub is the registry of the images without a registry host, served by dockerHubHost.
+	dockerHub     = "docker.io"
+	dockerHubHost = "registry-1.docker.io"
+)
+
+// Client is a client resolving the digests of image tags with the registry API, authenticating with the basic
+// credentials of the registries or the bearer tokens they hand out for them.
+//
+// Clients should be reused instead of created as needed.
+// The methods of Client are safe for concurrent use by multiple goroutines.
+type Client struct {
+	hc        *http.Client
+	userAgent string
+}
+
+// NewClient creates a new registry client.
+func NewClient(extraUserAgent string) *Client {
+	userAgent := []string{defaultUserAgent}
+	if extraUserAgent != "" {
+		userAgent = append(userAgent, extraUserAgent)
+	}
+
+	return &Client{
+		hc:        &http.Client{Timeout: defaultTimeout},
+		userAgent: strings.Join(userAgent, " "),
+	}
+}
+
+// Reference is an image reference split into its registry, its repository and its tag or digest.
+type Reference struct {
+	Registry   string
+	Repository string
+	Tag        string
+	Digest     string
+}
+
+// ParseReference splits an image reference, with the rules of docker: the first component of the name is the
+// registry when it has a dot or a port or is localhost, the images without one are of Docker Hub, and the tag
+// defaults to latest.
+func ParseReference(image string) Reference {
+	var ref Reference
+	name := image
+	if i := strings.Index(name, "@"); i >= 0 {
+		name, ref.Digest = name[:i], name[i+1:]
+	}
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name, ref.Tag = name[:i], name[i+1:]
+	}
+	if ref.Tag == "" && ref.Digest == "" {
+		ref.Tag = "latest"
+	}
+
+	if i := strings.Index(name, "/"); i >= 0 && (strings.ContainsAny(name[:i], ".:") || name[:i] == "localhost") {
+		ref.Registry, ref.Repository = name[:i], name[i+1:]
+	} else {
+		ref.Registry, ref.Repository = dockerHub, name
+	}
+	if ref.Registry == dockerHub && !strings.Contains(ref.Repository, "/") {
+		ref.Repository = "library/" + ref.Repository
+	}
+	return ref
+}
+
+// NormalizeServer returns the registry of the server of credentials, which can be a URL, and the Docker Hub for
+// its aliases.
+func NormalizeServer(server string) string {
+	server = strings.TrimPrefix(strings.TrimPrefix(server, "https://"), "http://")
+	if i := strings.Index(server, "/"); i >= 0 {
+		server = server[:i]
+	}
+	switch server {
+	case "index.docker.io", dockerHubHost:
+		return dockerHub
+	}
+	return server
+}
+
+// host returns the host serving the registry API of a registry.
+func host(registry string) string {
+	if registry == dockerHub {
+		return dockerHubHost
+	}
+	return registry
+}
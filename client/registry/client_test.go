@@ -0,0 +1,87 @@
+package registry
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseReference(t *testing.T) {
+	for _, c := range []struct {
+		image string
+		want  Reference
+	}{
+		{"nginx", Reference{Registry: "docker.io", Repository: "library/nginx", Tag: "latest"}},
+		{"bitnami/redis:7", Reference{Registry: "docker.io", Repository: "bitnami/redis", Tag: "7"}},
+		{"myregistry.azurecr.io/team/app:v1", Reference{Registry: "myregistry.azurecr.io", Repository: "team/app", Tag: "v1"}},
+		{"localhost:5000/app", Reference{Registry: "localhost:5000", Repository: "app", Tag: "latest"}},
+		{"mcr.microsoft.com/oss/nginx@sha256:abc", Reference{Registry: "mcr.microsoft.com", Repository: "oss/nginx", Digest: "sha256:abc"}},
+	} {
+		if got := ParseReference(c.image); got != c.want {
+			t.Errorf("expected %s to be parsed as %+v, got %+v", c.image, c.want, got)
+		}
+	}
+
+	for server, want := range map[string]string{
+		"https://index.docker.io/v1/": "docker.io",
+		"myregistry.azurecr.io":       "myregistry.azurecr.io",
+	} {
+		if got := NormalizeServer(server); got != want {
+			t.Errorf("expected server %s to be normalized to %s, got %s", server, want, got)
+		}
+	}
+}
+
+func TestDigest(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			if u, p, ok := r.BasicAuth(); !ok || u != "user" || p != "password" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			if r.URL.Query().Get("scope") != "repository:team/app:pull" {
+				t.Errorf("unexpected token scope %q", r.URL.Query().Get("scope"))
+			}
+			w.Write([]byte(`{"token": "registry-token"}`))
+		case "/v2/team/app/manifests/v1":
+			if r.Header.Get("Authorization") != "Bearer registry-token" {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="`+server.URL+`/token",service="registry",scope="repository:team/app:pull"`)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			if r.Method != http.MethodHead || !strings.Contains(r.Header.Get("Accept"), "manifest.list.v2+json") {
+				t.Errorf("unexpected manifest request %s %s", r.Method, r.Header.Get("Accept"))
+			}
+			w.Header().Set("Docker-Content-Digest", "sha256:0123")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	c := NewClient("")
+	c.hc = server.Client()
+	registry := strings.TrimPrefix(server.URL, "https://")
+
+	digest, err := c.Digest(context.Background(), registry+"/team/app:v1", "user", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if digest != "sha256:0123" {
+		t.Errorf("expected the digest of the tag, got %s", digest)
+	}
+
+	if _, err := c.Digest(context.Background(), registry+"/team/app:v1", "user", "wrong"); err == nil {
+		t.Error("expected invalid credentials to fail")
+	}
+	if _, err := c.Digest(context.Background(), registry+"/team/app:missing", "", ""); err == nil {
+		t.Error("expected a missing tag to fail")
+	}
+	if digest, err := c.Digest(context.Background(), registry+"/team/app@sha256:4567", "", ""); err != nil || digest != "sha256:4567" {
+		t.Errorf("expected the digest of a pinned image to be returned as is, got %s, %v", digest, err)
+	}
+}
@@ -0,0 +1,175 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// manifestMediaTypes are the manifests accepted, so the digest is the one of the index or manifest list of the
+// multi-platform images, like the one docker pulls.
+var manifestMediaTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// Digest returns the digest of the manifest an image tag points to, authenticating with the username and password
+// if not empty. The digest of an image pinned to one is returned as is.
+// From: https://github.com/opencontainers/distribution-spec/blob/main/spec.md#checking-if-content-exists-in-the-registry
+func (c *Client) Digest(ctx context.Context, image, username, password string) (string, error) {
+	ref := ParseReference(image)
+	if ref.Digest != "" {
+		return ref.Digest, nil
+	}
+
+	uri := "https://" + host(ref.Registry) + "/v2/" + ref.Repository + "/manifests/" + ref.Tag
+	resp, err := c.headManifest(ctx, uri, "")
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		authorization, err := c.authorization(ctx, resp.Header.Get("WWW-Authenticate"), username, password)
+		if err != nil {
+			return "", err
+		}
+		if resp, err = c.headManifest(ctx, uri, authorization); err != nil {
+			return "", err
+		}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("Getting the manifest of image %s returned %s", image, resp.Status)
+	}
+	digest := resp.Header.Get("Docker-Content-Digest")
+	if digest == "" {
+		return "", fmt.Errorf("Getting the manifest of image %s returned no digest", image)
+	}
+	return digest, nil
+}
+
+func (c *Client) headManifest(ctx context.Context, uri, authorization string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodHead, uri, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Creating head manifest request failed: %v", err)
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", strings.Join(manifestMediaTypes, ", "))
+	req.Header.Set("User-Agent", c.userAgent)
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+
+	resp, err := c.hc.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Sending head manifest request failed: %v", err)
+	}
+	resp.Body.Close()
+	return resp, nil
+}
+
+// authorization returns the Authorization header answering the challenge of a registry: the basic credentials,
+// or a bearer token of the realm of the registry, requested with the credentials if any.
+// From: https://distribution.github.io/distribution/spec/auth/token/
+func (c *Client) authorization(ctx context.Context, challenge, username, password string) (string, error) {
+	scheme, params := parseChallenge(challenge)
+	switch {
+	case strings.EqualFold(scheme, "Basic"):
+		if username == "" {
+			return "", fmt.Errorf("The registry requires credentials")
+		}
+		req := &http.Request{Header: make(http.Header)}
+		req.SetBasicAuth(username, password)
+		return req.Header.Get("Authorization"), nil
+	case !strings.EqualFold(scheme, "Bearer") || params["realm"] == "":
+		return "", fmt.Errorf("Unsupported registry authentication challenge %q", challenge)
+	}
+
+	realm, err := url.Parse(params["realm"])
+	if err != nil {
+		return "", fmt.Errorf("Invalid registry token realm %q: %v", params["realm"], err)
+	}
+	q := realm.Query()
+	for _, k := range []string{"service", "scope"} {
+		if params[k] != "" {
+			q.Set(k, params[k])
+		}
+	}
+	realm.RawQuery = q.Encode()
+
+	req, err := http.NewRequest(http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return "", fmt.Errorf("Creating get token request failed: %v", err)
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("User-Agent", c.userAgent)
+	if username != "" {
+		req.SetBasicAuth(username, password)
+	}
+
+	resp, err := c.hc.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("Sending get token request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("Getting a registry token returned %s", resp.Status)
+	}
+
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("Decoding get token response body failed: %v", err)
+	}
+	if token.Token == "" {
+		token.Token = token.AccessToken
+	}
+	if token.Token == "" {
+		return "", fmt.Errorf("Getting a registry token returned no token")
+	}
+	return "Bearer " + token.Token, nil
+}
+
+// parseChallenge parses the scheme and the parameters of a WWW-Authenticate header, like
+// Bearer realm="https://auth.docker.io/token",service="registry.docker.io".
+func parseChallenge(challenge string) (string, map[string]string) {
+	challenge = strings.TrimSpace(challenge)
+	scheme := challenge
+	rest := ""
+	if i := strings.Index(challenge, " "); i >= 0 {
+		scheme, rest = challenge[:i], challenge[i+1:]
+	}
+
+	params := make(map[string]string)
+	for rest != "" {
+		rest = strings.TrimLeft(rest, " ,")
+		eq := strings.Index(rest, "=")
+		if eq < 0 {
+			break
+		}
+		key := strings.ToLower(strings.TrimSpace(rest[:eq]))
+		rest = rest[eq+1:]
+
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end < 0 {
+				value, rest = rest[1:], ""
+			} else {
+				value, rest = rest[1:end+1], rest[end+2:]
+			}
+		} else if comma := strings.Index(rest, ","); comma >= 0 {
+			value, rest = rest[:comma], rest[comma+1:]
+		} else {
+			value, rest = rest, ""
+		}
+		params[key] = value
+	}
+	return scheme, params
+}
@@ -0,0 +1,3 @@
+// Package registry provides tools for resolving the digests of the image
+// tags of container registries.
+package registry
@@ -0,0 +1,131 @@
+package resourcehealth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/virtual-kubelet/azure-aci/client/api"
+)
+
+// GetContainerGroupAvailabilityStatus gets the current availability of a container group.
+// From: https://docs.microsoft.com/en-us/rest/api/resourcehealth/availability-statuses/get-by-resource
+func (c *Client) GetContainerGroupAvailabilityStatus(ctx context.Context, resourceGroup, containerGroupName string) (*AvailabilityStatus, error) {
+	urlParams := url.Values{
+		"api-version": []string{apiVersion},
+	}
+
+	// Create the url.
+	uri := api.ResolveRelative(c.auth.ResourceManagerEndpoint, containerGroupAvailabilityURLPath)
+	uri += "?" + url.Values(urlParams).Encode()
+
+	// Create the request.
+	req, err := http.NewRequest("GET", uri, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Creating get availability status uri request failed: %v", err)
+	}
+	req = req.WithContext(ctx)
+
+	// Add the parameters to the url.
+	if err := api.ExpandURL(req.URL, map[string]string{
+		"subscriptionId":     c.auth.SubscriptionID,
+		"resourceGroup":      resourceGroup,
+		"containerGroupName": containerGroupName,
+	}); err != nil {
+		return nil, fmt.Errorf("Expanding URL with parameters failed: %v", err)
+	}
+
+	// Send the request.
+	resp, err := c.hc.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Sending get availability status request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	// 200 (OK) is a success response.
+	if err := api.CheckResponse(resp); err != nil {
+		return nil, err
+	}
+
+	// Decode the body from the response.
+	if resp.Body == nil {
+		return nil, errors.New("Get availability status returned an empty body in the response")
+	}
+	var status AvailabilityStatus
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return nil, fmt.Errorf("Decoding get availability status response body failed: %v", err)
+	}
+
+	return &status, nil
+}
+
+// ListActiveServiceIssues lists the active service issues of the subscription impacting the service in the region.
+// From: https://docs.microsoft.com/en-us/rest/api/resourcehealth/events/list-by-subscription-id
+func (c *Client) ListActiveServiceIssues(ctx context.Context, service, region string) ([]Event, error) {
+	urlParams := url.Values{
+		"api-version": []string{apiVersion},
+		"$filter":     []string{"properties/eventType eq 'ServiceIssue' and properties/status eq 'Active'"},
+	}
+
+	// Create the url.
+	uri := api.ResolveRelative(c.auth.ResourceManagerEndpoint, eventsURLPath)
+	uri += "?" + url.Values(urlParams).Encode()
+
+	var issues []Event
+	for uri != "" {
+		// Create the request.
+		req, err := http.NewRequest("GET", uri, nil)
+		if err != nil {
+			return nil, fmt.Errorf("Creating list events uri request failed: %v", err)
+		}
+		req = req.WithContext(ctx)
+
+		// Add the parameters to the url.
+		if err := api.ExpandURL(req.URL, map[string]string{
+			"subscriptionId": c.auth.SubscriptionID,
+		}); err != nil {
+			return nil, fmt.Errorf("Expanding URL with parameters failed: %v", err)
+		}
+
+		list, err := c.listEvents(req)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range list.Value {
+			if e.Active() && e.Impacts(service, region) {
+				issues = append(issues, e)
+			}
+		}
+		uri = list.NextLink
+	}
+
+	return issues, nil
+}
+
+func (c *Client) listEvents(req *http.Request) (*EventList, error) {
+	// Send the request.
+	resp, err := c.hc.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Sending list events request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	// 200 (OK) is a success response.
+	if err := api.CheckResponse(resp); err != nil {
+		return nil, err
+	}
+
+	// Decode the body from the response.
+	if resp.Body == nil {
+		return nil, errors.New("List events returned an empty body in the response")
+	}
+	var list EventList
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("Decoding list events response body failed: %v", err)
+	}
+
+	return &list, nil
+}
@@ -0,0 +1,3 @@
+// Package resourcehealth provides tools for interacting with the
+// Azure Resource Health API.
+package resourcehealth
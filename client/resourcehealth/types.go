@@ -0,0 +1,104 @@
+package resourcehealth
+
+import (
+	"strings"
+	"time"
+)
+
+// AvailabilityState is the availability of a resource reported by Resource Health.
+type AvailabilityState string
+
+const (
+	// AvailabilityStateAvailable is the state of a resource with no platform event affecting it.
+	AvailabilityStateAvailable AvailabilityState = "Available"
+	// AvailabilityStateDegraded is the state of a resource whose performance is affected.
+	AvailabilityStateDegraded AvailabilityState = "Degraded"
+	// AvailabilityStateUnavailable is the state of a resource affected by a platform or user event.
+	AvailabilityStateUnavailable AvailabilityState = "Unavailable"
+	// AvailabilityStateUnknown is the state of a resource Resource Health has no information about.
+	AvailabilityStateUnknown AvailabilityState = "Unknown"
+)
+
+// ContainerInstancesService is the name of the Azure Container Instances service in the service health events.
+const ContainerInstancesService = "Container Instances"
+
+// AvailabilityStatus is the current availability of a resource.
+type AvailabilityStatus struct {
+	ID         string                       `json:"id,omitempty"`
+	Name       string                       `json:"name,omitempty"`
+	Properties AvailabilityStatusProperties `json:"properties"`
+}
+
+// AvailabilityStatusProperties are the properties of the availability of a resource.
+type AvailabilityStatusProperties struct {
+	AvailabilityState AvailabilityState `json:"availabilityState,omitempty"`
+	Title             string            `json:"title,omitempty"`
+	Summary           string            `json:"summary,omitempty"`
+	ReasonType        string            `json:"reasonType,omitempty"`
+	ReasonChronicity  string            `json:"reasonChronicity,omitempty"`
+	OccuredTime       *time.Time        `json:"occuredTime,omitempty"`
+}
+
+// EventList is the list of the service health events of a subscription.
+type EventList struct {
+	Value    []Event `json:"value,omitempty"`
+	NextLink string  `json:"nextLink,omitempty"`
+}
+
+// Event is a service health event, like a service issue or a planned maintenance.
+type Event struct {
+	ID         string          `json:"id,omitempty"`
+	Name       string          `json:"name,omitempty"`
+	Properties EventProperties `json:"properties"`
+}
+
+// EventProperties are the properties of a service health event.
+type EventProperties struct {
+	EventType       string     `json:"eventType,omitempty"`
+	Status          string     `json:"status,omitempty"`
+	Title           string     `json:"title,omitempty"`
+	Summary         string     `json:"summary,omitempty"`
+	Impact          []Impact   `json:"impact,omitempty"`
+	ImpactStartTime *time.Time `json:"impactStartTime,omitempty"`
+	LastUpdateTime  *time.Time `json:"lastUpdateTime,omitempty"`
+}
+
+// Impact is a service impacted by an event, in some regions.
+type Impact struct {
+	ImpactedService string           `json:"impactedService,omitempty"`
+	ImpactedRegions []ImpactedRegion `json:"impactedRegions,omitempty"`
+}
+
+// ImpactedRegion is a region impacted by an event.
+type ImpactedRegion struct {
+	ImpactedRegion string `json:"impactedRegion,omitempty"`
+	Status         string `json:"status,omitempty"`
+}
+
+// Active returns whether the event is an active service issue.
+func (e *Event) Active() bool {
+	return e.Properties.EventType == "ServiceIssue" && e.Properties.Status == "Active"
+}
+
+// Impacts returns whether the event impacts the service in the region. The regions of the events are
+// display names, like "East US", and are compared with the names of the regions, like "eastus".
+func (e *Event) Impacts(service, region string) bool {
+	for _, impact := range e.Properties.Impact {
+		if !strings.EqualFold(impact.ImpactedService, service) {
+			continue
+		}
+		for _, r := range impact.ImpactedRegions {
+			if r.Status == "Resolved" {
+				continue
+			}
+			if regionName(r.ImpactedRegion) == regionName(region) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func regionName(region string) string {
+	return strings.ToLower(strings.Replace(region, " ", "", -1))
+}
@@ -0,0 +1,35 @@
+package resourcehealth
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestEventImpacts(t *testing.T) {
+	var list EventList
+	body := `{"value": [
+		{"name": "issue", "properties": {"eventType": "ServiceIssue", "status": "Active", "title": "Container Instances - East US",
+			"impact": [{"impactedService": "Container Instances", "impactedRegions": [{"impactedRegion": "East US", "status": "Active"}, {"impactedRegion": "West Europe", "status": "Resolved"}]}]}},
+		{"name": "resolved", "properties": {"eventType": "ServiceIssue", "status": "Resolved",
+			"impact": [{"impactedService": "Container Instances", "impactedRegions": [{"impactedRegion": "East US"}]}]}},
+		{"name": "maintenance", "properties": {"eventType": "PlannedMaintenance", "status": "Active",
+			"impact": [{"impactedService": "Container Instances", "impactedRegions": [{"impactedRegion": "East US"}]}]}}
+	]}`
+	if err := json.Unmarshal([]byte(body), &list); err != nil {
+		t.Fatal(err)
+	}
+
+	issue := list.Value[0]
+	if !issue.Active() || !issue.Impacts(ContainerInstancesService, "eastus") {
+		t.Fatalf("expected the issue to be active in eastus")
+	}
+	if issue.Impacts(ContainerInstancesService, "westeurope") {
+		t.Fatalf("expected the issue to be resolved in westeurope")
+	}
+	if issue.Impacts("Virtual Machines", "eastus") {
+		t.Fatalf("expected the issue not to impact another service")
+	}
+	if list.Value[1].Active() || list.Value[2].Active() {
+		t.Fatalf("expected resolved issues and maintenances not to be active")
+	}
+}
@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/virtual-kubelet/azure-aci/client/api"
+)
+
+// ListAccounts lists the storage accounts of the subscription, following the next links.
+// From: https://docs.microsoft.com/en-us/rest/api/storagerp/storageaccounts/list
+func (c *Client) ListAccounts(ctx context.Context) ([]Account, error) {
+	urlParams := url.Values{
+		"api-version": []string{apiVersion},
+	}
+
+	// Create the url.
+	uri := api.ResolveRelative(c.auth.ResourceManagerEndpoint, storageAccountListURLPath)
+	uri += "?" + url.Values(urlParams).Encode()
+
+	var accounts []Account
+	for uri != "" {
+		page, err := c.listAccountsPage(ctx, uri)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, page.Value...)
+		uri = page.NextLink
+	}
+
+	return accounts, nil
+}
+
+func (c *Client) listAccountsPage(ctx context.Context, uri string) (*AccountListResult, error) {
+	// Create the request.
+	req, err := http.NewRequest("GET", uri, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Creating list storage accounts uri request failed: %v", err)
+	}
+	req = req.WithContext(ctx)
+
+	// Add the parameters to the url.
+	if err := api.ExpandURL(req.URL, map[string]string{
+		"subscriptionId": c.auth.SubscriptionID,
+	}); err != nil {
+		return nil, fmt.Errorf("Expanding URL with parameters failed: %v", err)
+	}
+
+	// Send the request.
+	resp, err := c.hc.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Sending list storage accounts request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	// 200 (OK) is a success response.
+	if err := api.CheckResponse(resp); err != nil {
+		return nil, err
+	}
+
+	// Decode the body from the response.
+	if resp.Body == nil {
+		return nil, errors.New("List storage accounts returned an empty body in the response")
+	}
+	var page AccountListResult
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		return nil, fmt.Errorf("Decoding list storage accounts response body failed: %v", err)
+	}
+
+	return &page, nil
+}
@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"fmt"
+	"net/http"
+
+	azure "github.com/virtual-kubelet/azure-aci/client"
+	"github.com/virtual-kubelet/azure-aci/client/api"
+)
+
+const (
+	defaultUserAgent = "virtual-kubelet/azure-arm-storage/2019-06-01"
+	apiVersion       = "2019-06-01"
+
+	storageAccountListURLPath = "subscriptions/{{.subscriptionId}}/providers/Microsoft.Storage/storageAccounts"
+	storageAccountURLPath     = "subscriptions/{{.subscriptionId}}/resourceGroups/{{.resourceGroup}}/providers/Microsoft.Storage/storageAccounts/{{.accountName}}"
+	listKeysURLPath           = storageAccountURLPath + "/listKeys"
+	fileShareURLPath          = storageAccountURLPath + "/fileServices/default/shares/{{.shareName}}"
+)
+
+// Client is a client for interacting with Azure storage accounts.
+//
+// Clients should be reused instead of created as needed.
+// The methods of Client are safe for concurrent use by multiple goroutines.
+type Client struct {
+	hc   *http.Client
+	auth *azure.Authentication
+}
+
+// NewClient creates a new Azure storage client.
+func NewClient(auth *azure.Authentication, extraUserAgent string) (*Client, error) {
+	if auth == nil {
+		return nil, fmt.Errorf("Authentication is not supplied for the Azure client")
+	}
+
+	userAgent := []string{defaultUserAgent}
+	if extraUserAgent != "" {
+		userAgent = append(userAgent, extraUserAgent)
+	}
+
+	client, err := azure.NewClient(auth, userAgent)
+	if err != nil {
+		return nil, fmt.Errorf("Creating Azure client failed: %v", err)
+	}
+
+	return &Client{hc: client.HTTPClient, auth: auth}, nil
+}
+
+// IsNotFound determines if the passed in error is a not found error from the API.
+func IsNotFound(err error) bool {
+	switch e := err.(type) {
+	case nil:
+		return false
+	case *api.Error:
+		return e.StatusCode == http.StatusNotFound
+	default:
+		return false
+	}
+}
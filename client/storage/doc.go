@@ -0,0 +1,3 @@
+// Package storage provides tools for interacting with the
+// Azure Resource Manager storage accounts and file shares APIs.
+package storage
@@ -0,0 +1,163 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/virtual-kubelet/azure-aci/client/api"
+)
+
+// CreateFileShare creates or updates a file share of a storage account.
+// From: https://docs.microsoft.com/en-us/rest/api/storagerp/fileshares/create
+func (c *Client) CreateFileShare(ctx context.Context, resourceGroup, accountName, shareName string, share FileShare) (*FileShare, error) {
+	urlParams := url.Values{
+		"api-version": []string{apiVersion},
+	}
+
+	// Create the url.
+	uri := api.ResolveRelative(c.auth.ResourceManagerEndpoint, fileShareURLPath)
+	uri += "?" + url.Values(urlParams).Encode()
+
+	// Create the body for the request.
+	b := new(bytes.Buffer)
+	if err := json.NewEncoder(b).Encode(share); err != nil {
+		return nil, fmt.Errorf("Encoding create file share body request failed: %v", err)
+	}
+
+	// Create the request.
+	req, err := http.NewRequest("PUT", uri, b)
+	if err != nil {
+		return nil, fmt.Errorf("Creating create file share uri request failed: %v", err)
+	}
+	req = req.WithContext(ctx)
+
+	// Add the parameters to the url.
+	if err := api.ExpandURL(req.URL, map[string]string{
+		"subscriptionId": c.auth.SubscriptionID,
+		"resourceGroup":  resourceGroup,
+		"accountName":    accountName,
+		"shareName":      shareName,
+	}); err != nil {
+		return nil, fmt.Errorf("Expanding URL with parameters failed: %v", err)
+	}
+
+	// Send the request.
+	resp, err := c.hc.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Sending create file share request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	// 200 (OK) and 201 (Created) are successful responses.
+	if err := api.CheckResponse(resp); err != nil {
+		return nil, err
+	}
+
+	// Decode the body from the response.
+	if resp.Body == nil {
+		return nil, errors.New("Create file share returned an empty body in the response")
+	}
+	var s FileShare
+	if err := json.NewDecoder(resp.Body).Decode(&s); err != nil {
+		return nil, fmt.Errorf("Decoding create file share response body failed: %v", err)
+	}
+
+	return &s, nil
+}
+
+// GetFileShare gets a file share of a storage account, with its usage stats.
+// From: https://docs.microsoft.com/en-us/rest/api/storagerp/fileshares/get
+func (c *Client) GetFileShare(ctx context.Context, resourceGroup, accountName, shareName string) (*FileShare, error) {
+	urlParams := url.Values{
+		"api-version": []string{apiVersion},
+		"$expand":     []string{"stats"},
+	}
+
+	// Create the url.
+	uri := api.ResolveRelative(c.auth.ResourceManagerEndpoint, fileShareURLPath)
+	uri += "?" + url.Values(urlParams).Encode()
+
+	// Create the request.
+	req, err := http.NewRequest("GET", uri, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Creating get file share uri request failed: %v", err)
+	}
+	req = req.WithContext(ctx)
+
+	// Add the parameters to the url.
+	if err := api.ExpandURL(req.URL, map[string]string{
+		"subscriptionId": c.auth.SubscriptionID,
+		"resourceGroup":  resourceGroup,
+		"accountName":    accountName,
+		"shareName":      shareName,
+	}); err != nil {
+		return nil, fmt.Errorf("Expanding URL with parameters failed: %v", err)
+	}
+
+	// Send the request.
+	resp, err := c.hc.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Sending get file share request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	// 200 (OK) is a success response.
+	if err := api.CheckResponse(resp); err != nil {
+		return nil, err
+	}
+
+	// Decode the body from the response.
+	if resp.Body == nil {
+		return nil, errors.New("Get file share returned an empty body in the response")
+	}
+	var s FileShare
+	if err := json.NewDecoder(resp.Body).Decode(&s); err != nil {
+		return nil, fmt.Errorf("Decoding get file share response body failed: %v", err)
+	}
+
+	return &s, nil
+}
+
+// DeleteFileShare deletes a file share of a storage account.
+// From: https://docs.microsoft.com/en-us/rest/api/storagerp/fileshares/delete
+func (c *Client) DeleteFileShare(ctx context.Context, resourceGroup, accountName, shareName string) error {
+	urlParams := url.Values{
+		"api-version": []string{apiVersion},
+	}
+
+	// Create the url.
+	uri := api.ResolveRelative(c.auth.ResourceManagerEndpoint, fileShareURLPath)
+	uri += "?" + url.Values(urlParams).Encode()
+
+	// Create the request.
+	req, err := http.NewRequest("DELETE", uri, nil)
+	if err != nil {
+		return fmt.Errorf("Creating delete file share uri request failed: %v", err)
+	}
+	req = req.WithContext(ctx)
+
+	// Add the parameters to the url.
+	if err := api.ExpandURL(req.URL, map[string]string{
+		"subscriptionId": c.auth.SubscriptionID,
+		"resourceGroup":  resourceGroup,
+		"accountName":    accountName,
+		"shareName":      shareName,
+	}); err != nil {
+		return fmt.Errorf("Expanding URL with parameters failed: %v", err)
+	}
+
+	// Send the request.
+	resp, err := c.hc.Do(req)
+	if err != nil {
+		return fmt.Errorf("Sending delete file share request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	// 200 (OK) and 204 (No Content) are successful responses.
+	return api.CheckResponse(resp)
+}
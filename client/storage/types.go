@@ -0,0 +1,61 @@
+package storage
+
+import (
+	"strings"
+
+	"github.com/virtual-kubelet/azure-aci/client/api"
+)
+
+// FileShare is an Azure Files share of a storage account.
+type FileShare struct {
+	api.ResponseMetadata `json:"-"`
+	ID                   string               `json:"id,omitempty"`
+	Name                 string               `json:"name,omitempty"`
+	Properties           *FileShareProperties `json:"properties,omitempty"`
+}
+
+// FileShareProperties are the properties of a file share.
+type FileShareProperties struct {
+	// ShareQuota is the maximum size of the share, in GiB.
+	ShareQuota int32             `json:"shareQuota,omitempty"`
+	Metadata   map[string]string `json:"metadata,omitempty"`
+	// ShareUsageBytes is the approximate size of the data stored on the share, only returned
+	// when the share is read with its stats. It is updated by Azure at most once an hour.
+	ShareUsageBytes *int64 `json:"shareUsageBytes,omitempty"`
+}
+
+// Account is a storage account.
+type Account struct {
+	ID       string `json:"id,omitempty"`
+	Name     string `json:"name,omitempty"`
+	Location string `json:"location,omitempty"`
+}
+
+// ResourceGroup returns the resource group of the storage account, parsed from its ID.
+func (a Account) ResourceGroup() string {
+	parts := strings.Split(a.ID, "/")
+	for i := 0; i+1 < len(parts); i++ {
+		if strings.EqualFold(parts[i], "resourceGroups") {
+			return parts[i+1]
+		}
+	}
+	return ""
+}
+
+// AccountListResult is a page of the list of the storage accounts.
+type AccountListResult struct {
+	Value    []Account `json:"value,omitempty"`
+	NextLink string    `json:"nextLink,omitempty"`
+}
+
+// AccountKey is an access key of a storage account.
+type AccountKey struct {
+	KeyName     string `json:"keyName,omitempty"`
+	Value       string `json:"value,omitempty"`
+	Permissions string `json:"permissions,omitempty"`
+}
+
+// AccountListKeysResult is the list of the access keys of a storage account.
+type AccountListKeysResult struct {
+	Keys []AccountKey `json:"keys,omitempty"`
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/pflag"
+)
+
+// logFormatters are the formats the logs can be written in.
+var logFormatters = map[string]func() logrus.Formatter{
+	"text": func() logrus.Formatter { return &logrus.TextFormatter{} },
+	"json": func() logrus.Formatter {
+		return &logrus.JSONFormatter{
+			FieldMap: logrus.FieldMap{
+				logrus.FieldKeyTime:  "time",
+				logrus.FieldKeyLevel: "level",
+				logrus.FieldKeyMsg:   "msg",
+			},
+		}
+	},
+}
+
+// logFormatConfig is the format of the logs, from the --log-format flag or the LOG_FORMAT environment variable.
+type logFormatConfig struct {
+	Format string
+}
+
+func (c *logFormatConfig) FlagSet() *pflag.FlagSet {
+	format := os.Getenv("LOG_FORMAT")
+	if format == "" {
+		format = "text"
+	}
+
+	flags := pflag.NewFlagSet("log-format", pflag.ContinueOnError)
+	flags.StringVar(&c.Format, "log-format", format, fmt.Sprintf("format of the logs: %s", strings.Join(logFormats(), ", ")))
+	return flags
+}
+
+// configure sets the formatter of the logger.
+func (c *logFormatConfig) configure(logger *logrus.Logger) error {
+	newFormatter, ok := logFormatters[c.Format]
+	if !ok {
+		return fmt.Errorf("invalid log format %q, expected one of %s", c.Format, strings.Join(logFormats(), ", "))
+	}
+	logger.SetFormatter(newFormatter())
+	return nil
+}
+
+func logFormats() []string {
+	formats := make([]string, 0, len(logFormatters))
+	for format := range logFormatters {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+	return formats
+}
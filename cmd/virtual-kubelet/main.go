@@ -16,6 +16,7 @@ package main
 
 import (
 	"context"
+	"os"
 	"strings"
 
 	"github.com/sirupsen/logrus"
@@ -34,6 +35,7 @@ import (
 var (
 	buildVersion    = "N/A"
 	buildTime       = "N/A"
+	gitCommit       = "N/A"
 	k8sVersion      = "v1.18.4" // This should follow the version of k8s.io/kubernetes we are importing
 	numberOfWorkers = 50
 )
@@ -44,6 +46,7 @@ func main() {
 	logger := logrus.StandardLogger()
 	log.L = logruslogger.FromLogrus(logrus.NewEntry(logger))
 	logConfig := &logruscli.Config{LogLevel: "info"}
+	logFormat := &logFormatConfig{}
 
 	trace.T = opencensus.Adapter{}
 	traceConfig := opencensuscli.Config{
@@ -59,17 +62,39 @@ func main() {
 	o.Provider = "azure"
 	o.Version = strings.Join([]string{k8sVersion, "vk-azure-aci", buildVersion}, "-")
 	o.PodSyncWorkers = numberOfWorkers
+	azprovider.Version = buildVersion
+	azprovider.GitCommit = gitCommit
+
+	// The provider serves the kubelet API itself, with the authentication of its clients and the reload of its
+	// serving certificate, so node-cli must not find the certificate to serve it.
+	servingCertFile, servingKeyFile := os.Getenv("APISERVER_CERT_LOCATION"), os.Getenv("APISERVER_KEY_LOCATION")
+	os.Unsetenv("APISERVER_CERT_LOCATION")
+	os.Unsetenv("APISERVER_KEY_LOCATION")
 
 	node, err := cli.New(ctx,
 		cli.WithBaseOpts(o),
 		cli.WithCLIVersion(buildVersion, buildTime),
 		cli.WithProvider("azure", func(cfg provider.InitConfig) (provider.Provider, error) {
-			return azprovider.NewACIProvider(cfg.ConfigPath, cfg.ResourceManager, cfg.NodeName, cfg.OperatingSystem, cfg.InternalIP, cfg.DaemonPort, cfg.KubeClusterDomain)
+			return azprovider.NewProvider(ctx, azprovider.Options{
+				ConfigPath:      cfg.ConfigPath,
+				ResourceManager: cfg.ResourceManager,
+				NodeName:        cfg.NodeName,
+				OperatingSystem: cfg.OperatingSystem,
+				InternalIP:      cfg.InternalIP,
+				DaemonPort:      cfg.DaemonPort,
+				ClusterDomain:   cfg.KubeClusterDomain,
+				ServingCertFile: servingCertFile,
+				ServingKeyFile:  servingKeyFile,
+			})
 		}),
 		cli.WithPersistentFlags(logConfig.FlagSet()),
 		cli.WithPersistentPreRunCallback(func() error {
 			return logruscli.Configure(logConfig, logger)
 		}),
+		cli.WithPersistentFlags(logFormat.FlagSet()),
+		cli.WithPersistentPreRunCallback(func() error {
+			return logFormat.configure(logger)
+		}),
 		cli.WithPersistentFlags(traceConfig.FlagSet()),
 		cli.WithPersistentPreRunCallback(func() error {
 			return opencensuscli.Configure(ctx, &traceConfig, o)
@@ -15,7 +15,9 @@ require (
 	github.com/gorilla/mux v1.7.3
 	github.com/gorilla/websocket v1.4.0
 	github.com/pkg/errors v0.8.1
+	github.com/prometheus/client_golang v1.0.0
 	github.com/sirupsen/logrus v1.4.2
+	github.com/spf13/pflag v1.0.5
 	github.com/virtual-kubelet/node-cli v0.5.1
 	github.com/virtual-kubelet/virtual-kubelet v1.3.0
 	go.opencensus.io v0.21.0
@@ -23,8 +25,10 @@ require (
 	gotest.tools v2.2.0+incompatible
 	k8s.io/api v0.18.4
 	k8s.io/apimachinery v0.18.4
+	k8s.io/apiserver v0.18.4
 	k8s.io/client-go v0.18.4
 	k8s.io/kubernetes v1.18.4
+	sigs.k8s.io/yaml v1.1.0
 )
 
 replace k8s.io/legacy-cloud-providers => k8s.io/legacy-cloud-providers v0.18.4
@@ -19,10 +19,11 @@ import (
 	"sync"
 	"time"
 
-	"github.com/gorilla/websocket"
+	"github.com/google/uuid"
 	client "github.com/virtual-kubelet/azure-aci/client"
 	"github.com/virtual-kubelet/azure-aci/client/aci"
 	"github.com/virtual-kubelet/azure-aci/client/network"
+	"github.com/virtual-kubelet/azure-aci/client/registry"
 	"github.com/virtual-kubelet/node-cli/manager"
 	"github.com/virtual-kubelet/virtual-kubelet/errdefs"
 	"github.com/virtual-kubelet/virtual-kubelet/log"
@@ -34,9 +35,13 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes"
+	nodelisters "k8s.io/client-go/listers/node/v1beta1"
+	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/clientcmd"
 	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 	clientcmdapiv1 "k8s.io/client-go/tools/clientcmd/api/v1"
+	"k8s.io/client-go/tools/record"
 	credconfig "k8s.io/kubernetes/pkg/credentialprovider"
 	stats "k8s.io/kubernetes/pkg/kubelet/apis/stats/v1alpha1"
 )
@@ -95,9 +100,166 @@ type ACIProvider struct {
 	kubeDNSIP          string
 	extraUserAgent     string
 
+	podOverheadCPU       string
+	podOverheadMemory    string
+	podOverhead          v1.ResourceList
+	runtimeClassProfiles map[string]runtimeClassProfile
+	runtimeClasses       nodelisters.RuntimeClassLister
+	runtimeClassesSynced cache.InformerSynced
+	kubeClient           kubernetes.Interface
+	eventRecorder        record.EventRecorder
+	registryMirrors      map[string]string
+	architecture         string
+	logSink              logSink
+	logArchiveInterval   time.Duration
+	namespaceDiagnostics map[string]*aci.ContainerGroupDiagnostics
+	statusSyncInterval   string
+	statusSyncJitter     *float64
+	transportOptions     client.TransportOptions
+	httpIdleTimeout      string
+	httpRetryWaitMin     string
+	httpRetryWaitMax     string
+	updatesInterval      time.Duration
+	updatesJitter        float64
+	terminalStatuses     terminalStatusCache
+	instanceViews        instanceViewCache
+	createLatencies      createLatencies
+	provisioning         provisioningTracker
+	provisionTimeout     string
+	stuckPodTimeout      time.Duration
+	stuckPodPolicy       string
+	deleteStuckGroups    bool
+	repairs              repairCounter
+	maxRepairs           int
+	quotaWaits           quotaQueue
+	nodeLabels           map[string]string
+	nodeTaints           []v1.Taint
+	zone                 string
+	zones                []string
+	zonePlacements       zonePlacements
+	spotPreemption       bool
+	preemptions          preemptions
+	networkClient        *network.Client
+	subnetPods           subnetCapacity
+	readiness            armReadiness
+	armUnhealthyAfter    string
+	armHealthyAfter      string
+	statusInterval       string
+	heartbeat            string
+	nodeStatusInterval   time.Duration
+	heartbeatInterval    time.Duration
+	leaseRenew           string
+	leaseDuration        string
+	leaseRenewInterval   time.Duration
+	nodeLeaseDuration    time.Duration
+	externalAddress      string
+	hostname             string
+	execAudit            *execAuditLog
+	stateKeyFile         string
+	stateKeyVaultKey     string
+	stateCipher          *stateCipher
+	seccompProfileDir    string
+	extensions           []containerGroupExtension
+	podConfigsEnabled    bool
+	podConfigs           podConfigSource
+	hookURL              string
+	hookCAFile           string
+	hookTimeout          string
+	hookFailurePolicy    string
+	templateHook         *templateHook
+	faults               string
+	recordingDir         string
+	recordings           *armRecordings
+	execAuditEvents      bool
+	authorizeRequests    bool
+	accessReviews        accessReviewCache
+	requireAuthn         bool
+	tokenReviews         tokenReviewCache
+	kubeletAPICert       *kubeletAPICert
+	apiVersions          map[string]string
+	userAgentClusterID   string
+	userAgentSuffix      string
+	listByNodeTag        bool
+	allowedNamespaces    []string
+	deniedNamespaces     []string
+	budgets              map[string]namespaceBudget
+	budgetLimits         map[string]budgetUsage
+	budgetLocks          budgetLocks
+	budgetReservations   budgetReservations
+	costSource           costSource
+	costReportInterval   time.Duration
+	resourceHealth       resourceHealthSource
+	resourceHealthPoll   time.Duration
+	serviceHealth        serviceHealth
+	activityLogConfig    *bool
+	activityLog          activityLogSource
+	createFailures       createFailures
+	armErrors            armErrors
+	translations         translations
+	policyCheck          bool
+	policies             policySource
+	permissionCheck      string
+	missingPermissions   []string
+	credentials          map[string]resourceGroupCredential
+	resourceGroupAuth    map[string]*client.Authentication
+	emptyDirMaxSize      string
+	emptyDirLimit        resource.Quantity
+	scratchAccount       string
+	scratchAccountRG     string
+	scratchStorage       scratchStorage
+	volumeInitImage      string
+	gitRepoImage         string
+	csiSecretsStore      bool
+	csiDrivers           map[string]csiDriver
+	unsupportedFields    string
+	portExposure         string
+	subscriptionID       string
+	backendPoolsEnabled  bool
+	backendPools         backendPoolUpdater
+	backendPoolMembers   backendPoolMembers
+	networkCheck         networkCheck
+	manageSubnet         bool
+	manageSubnetConfig   *bool
+	manageNSG            bool
+	nsgID                string
+	securityRules        securityRuleManager
+	controlPlaneProxy    string
+	dataPlaneHosts       []string
+	egress               egressEndpoints
+	dnsLabelScope        string
+	deleteConcurrency    *int
+	deletions            *containerGroupDeletions
+	httpTrace            bool
+	execTimeout          string
+	execDialRetries      *int
+	dataPlane            *aci.DataPlaneClient
+	metricsSource        metricsSource
+	metricsAggregation   string
+	metricsWindowConfig  string
+	aggregation          aci.AggregationType
+	metricsWindow        time.Duration
+	onlyCPUAndMemory     bool
+	metricsFallback      bool
+	metricsUnavailable   sync.Once
+	noMetricsNamespaces  []string
+	crashLoopPolicy      string
+	crashLoopRestarts    *int
+	crashLoopMaxRestarts int
+	imageDigests         imageDigestResolver
+	volumeStatsEnabled   bool
+	volumeStats          fileShareStats
+	accountGroups        storageAccountGroups
+
+	// nodeMu guards the node kept by ConfigureNode, read by the node status loop.
+	nodeMu sync.Mutex
+	node   *v1.Node
+
 	metricsSync     sync.Mutex
 	metricsSyncTime time.Time
 	lastMetric      *stats.Summary
+	lastPodStats    map[string]lastPodStats
+	cpuUsage        map[string]*cpuUsage
+	metricsCaches   metricsCacheSizes
 	tracker         *PodsTracker
 }
 
@@ -156,16 +318,31 @@ func isValidACIRegion(region string) bool {
 	return false
 }
 
-// NewACIProvider creates a new ACIProvider.
+// NewACIProvider creates a new ACIProvider, with the Azure authentication read from the environment and a client of
+// the API server from KUBECONFIG or the in-cluster configuration.
 func NewACIProvider(config string, rm *manager.ResourceManager, nodeName, operatingSystem string, internalIP string, daemonEndpointPort int32, clusterDomain string) (*ACIProvider, error) {
+	return NewProvider(context.TODO(), Options{
+		ConfigPath:      config,
+		ResourceManager: rm,
+		NodeName:        nodeName,
+		OperatingSystem: operatingSystem,
+		InternalIP:      internalIP,
+		DaemonPort:      daemonEndpointPort,
+		ClusterDomain:   clusterDomain,
+	})
+}
+
+// NewProvider creates a new ACIProvider from opts. The settings of the provider config file and of the environment
+// variables are applied as with NewACIProvider.
+func NewProvider(ctx context.Context, opts Options) (*ACIProvider, error) {
 	var p ACIProvider
 	var err error
 
-	p.resourceManager = rm
-	p.clusterDomain = clusterDomain
+	p.resourceManager = opts.ResourceManager
+	p.clusterDomain = opts.ClusterDomain
 
-	if config != "" {
-		f, err := os.Open(config)
+	if opts.ConfigPath != "" {
+		f, err := os.Open(opts.ConfigPath)
 		if err != nil {
 			return nil, err
 		}
@@ -176,90 +353,195 @@ func NewACIProvider(config string, rm *manager.ResourceManager, nodeName, operat
 		}
 	}
 
-	var azAuth *client.Authentication
-
-	if authFilepath := os.Getenv("AZURE_AUTH_LOCATION"); authFilepath != "" {
-		auth, err := client.NewAuthenticationFromFile(authFilepath)
-		if err != nil {
+	azAuth := opts.Authentication
+	if azAuth == nil {
+		if azAuth, err = p.authenticationFromEnv(ctx); err != nil {
 			return nil, err
 		}
+	}
 
-		azAuth = auth
+	if vnetName := os.Getenv("ACI_VNET_NAME"); vnetName != "" {
+		p.vnetName = vnetName
+	}
+	if vnetResourceGroup := os.Getenv("ACI_VNET_RESOURCE_GROUP"); vnetResourceGroup != "" {
+		p.vnetResourceGroup = vnetResourceGroup
 	}
 
-	if acsFilepath := os.Getenv("ACS_CREDENTIAL_LOCATION"); acsFilepath != "" {
-		acsCredential, err := NewAcsCredential(acsFilepath)
-		if err != nil {
-			return nil, err
-		}
+	if err := p.setupUserAgent(); err != nil {
+		return nil, err
+	}
 
-		if acsCredential != nil {
-			var clientId string
-			if !strings.EqualFold(acsCredential.ClientID, "msi") {
-				clientId = acsCredential.ClientID
-			}
+	if err := p.setupRegistryMirrors(); err != nil {
+		return nil, err
+	}
 
-			azAuth = client.NewAuthentication(
-				acsCredential.Cloud,
-				clientId,
-				acsCredential.ClientSecret,
-				acsCredential.SubscriptionID,
-				acsCredential.TenantID,
-				acsCredential.UserAssignedIdentityID)
+	if err := p.setupArchitecture(); err != nil {
+		return nil, err
+	}
 
-			p.resourceGroup = acsCredential.ResourceGroup
-			p.region = acsCredential.Region
+	if err := p.setupLogArchive(); err != nil {
+		return nil, err
+	}
 
-			p.vnetName = acsCredential.VNetName
-			p.vnetResourceGroup = acsCredential.VNetResourceGroup
-			if p.vnetResourceGroup == "" {
-				p.vnetResourceGroup = p.resourceGroup
-			}
-		}
+	if err := p.setupStatusSync(); err != nil {
+		return nil, err
 	}
 
-	if vnetName := os.Getenv("ACI_VNET_NAME"); vnetName != "" {
-		p.vnetName = vnetName
+	if err := p.setupNamespaces(); err != nil {
+		return nil, err
 	}
-	if vnetResourceGroup := os.Getenv("ACI_VNET_RESOURCE_GROUP"); vnetResourceGroup != "" {
-		p.vnetResourceGroup = vnetResourceGroup
+
+	if err := p.setupNamespaceBudgets(); err != nil {
+		return nil, err
 	}
 
-	if clientID := os.Getenv("AZURE_CLIENT_ID"); clientID != "" {
-		azAuth.ClientID = clientID
+	if err := p.setupSpotPreemption(); err != nil {
+		return nil, err
 	}
 
-	if clientSecret := os.Getenv("AZURE_CLIENT_SECRET"); clientSecret != "" {
-		azAuth.ClientSecret = clientSecret
+	p.setupVolumeInit()
+
+	p.setupSeccompProfiles()
+
+	if err := p.setupExtensions(); err != nil {
+		return nil, err
 	}
 
-	if userIdentityClientId := os.Getenv("VIRTUALNODE_USER_IDENTITY_CLIENTID"); userIdentityClientId != "" {
-		azAuth.UserIdentityClientId = userIdentityClientId
+	if err := p.setupUnsupportedFields(); err != nil {
+		return nil, err
 	}
-	azAuth.UseUserIdentity = (len(azAuth.ClientID) == 0)
 
-	if azAuth.UseUserIdentity {
-		if len(azAuth.UserIdentityClientId) == 0 {
-			return nil, fmt.Errorf("Neither AZURE_CLIENT_ID or VIRTUALNODE_USER_IDENTITY_CLIENTID is being set")
-		}
+	if err := p.setupPortExposure(); err != nil {
+		return nil, err
+	}
 
-		log.G(context.TODO()).Info("Using user identity for authentication")
+	if err := p.setupStateEncryption(ctx, azAuth); err != nil {
+		return nil, err
 	}
 
-	if tenantID := os.Getenv("AZURE_TENANT_ID"); tenantID != "" {
-		azAuth.TenantID = tenantID
+	if err := p.setupExecAudit(); err != nil {
+		return nil, err
 	}
 
-	if subscriptionID := os.Getenv("AZURE_SUBSCRIPTION_ID"); subscriptionID != "" {
-		azAuth.SubscriptionID = subscriptionID
+	if err := p.setupReadiness(); err != nil {
+		return nil, err
+	}
+
+	if err := p.setupProvisioningTimeout(); err != nil {
+		return nil, err
 	}
 
-	p.extraUserAgent = os.Getenv("ACI_EXTRA_USER_AGENT")
+	if err := p.setupCrashLoops(); err != nil {
+		return nil, err
+	}
+
+	if err := p.setupRepairs(); err != nil {
+		return nil, err
+	}
+
+	if err := p.setupTransport(); err != nil {
+		return nil, err
+	}
+
+	if err := p.setupEgress(azAuth); err != nil {
+		return nil, err
+	}
+
+	if err := p.setupCredentials(azAuth); err != nil {
+		return nil, err
+	}
+
+	if err := p.setupDataPlane(); err != nil {
+		return nil, err
+	}
 
-	p.aciClient, err = aci.NewClient(azAuth, p.extraUserAgent)
+	p.aciClient, err = aci.NewClientWithTransport(azAuth, p.extraUserAgent, p.transportOptions)
 	if err != nil {
 		return nil, err
 	}
+	p.metricsSource = p.aciClient
+	p.imageDigests = registry.NewClient(p.extraUserAgent)
+
+	// Conditional GETs of container groups are opt-in, they rely on the ETag changing with the instance view.
+	if maxAge := os.Getenv("ACI_ETAG_CACHE_MAX_AGE"); maxAge != "" {
+		d, err := time.ParseDuration(maxAge)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid ACI_ETAG_CACHE_MAX_AGE %q, expected a positive duration", maxAge)
+		}
+		p.aciClient.EnableETagCache(d)
+	}
+
+	if err := p.setupFaultInjection(); err != nil {
+		return nil, err
+	}
+
+	if err := p.setupRecording(); err != nil {
+		return nil, err
+	}
+
+	if err := p.setupCircuitBreakers(); err != nil {
+		return nil, err
+	}
+
+	if err := p.setupConnectionTracing(); err != nil {
+		return nil, err
+	}
+
+	if err := p.setupConcurrency(); err != nil {
+		return nil, err
+	}
+
+	if err := p.setupDeletions(); err != nil {
+		return nil, err
+	}
+
+	if err := p.setupMetrics(); err != nil {
+		return nil, err
+	}
+
+	if err := p.setupAPIVersions(ctx); err != nil {
+		return nil, err
+	}
+
+	if err := p.setupListByNodeTag(); err != nil {
+		return nil, err
+	}
+
+	if err := p.setupZones(); err != nil {
+		return nil, err
+	}
+
+	if err := p.setupCostReport(azAuth); err != nil {
+		return nil, err
+	}
+
+	if err := p.setupPermissionCheck(ctx, azAuth); err != nil {
+		return nil, err
+	}
+
+	if err := p.setupResourceHealth(azAuth); err != nil {
+		return nil, err
+	}
+
+	if err := p.setupActivityLog(azAuth); err != nil {
+		return nil, err
+	}
+
+	if err := p.setupPolicyCheck(azAuth); err != nil {
+		return nil, err
+	}
+
+	if err := p.setupEmptyDirs(azAuth); err != nil {
+		return nil, err
+	}
+
+	if err := p.setupVolumeStats(azAuth); err != nil {
+		return nil, err
+	}
+
+	if err := p.setupBackendPools(azAuth); err != nil {
+		return nil, err
+	}
 
 	// If the log analytics file has been specified, load workspace credentials from the file
 	if logAnalyticsAuthFile := os.Getenv("LOG_ANALYTICS_AUTH_LOCATION"); logAnalyticsAuthFile != "" {
@@ -285,7 +567,7 @@ func NewACIProvider(config string, rm *manager.ResourceManager, nodeName, operat
 			p.diagnostics.LogAnalytics.LogType = aci.LogAnlyticsLogTypeContainerInsights
 			p.diagnostics.LogAnalytics.Metadata = map[string]string{
 				aci.LogAnalyticsMetadataKeyClusterResourceID: clusterResourceID,
-				aci.LogAnalyticsMetadataKeyNodeName:          nodeName,
+				aci.LogAnalyticsMetadataKeyNodeName:          opts.NodeName,
 			}
 		}
 	}
@@ -310,14 +592,52 @@ func NewACIProvider(config string, rm *manager.ResourceManager, nodeName, operat
 		return nil, errors.New(unsupportedRegionMessage)
 	}
 
-	if err := p.setupCapacity(context.TODO()); err != nil {
+	if err := p.setupCapacity(ctx); err != nil {
+		return nil, err
+	}
+
+	p.operatingSystem = opts.OperatingSystem
+	p.nodeName = opts.NodeName
+	if p.internalIP == "" {
+		p.internalIP = opts.InternalIP
+	}
+	if err := p.setupNodeAddresses(); err != nil {
+		return nil, err
+	}
+	p.daemonEndpointPort = opts.DaemonPort
+	p.kubeClient = opts.KubeClient
+	if p.kubeClient == nil {
+		p.kubeClient = newKubeClient(ctx)
+	}
+	p.eventRecorder = opts.EventRecorder
+	if p.eventRecorder == nil {
+		p.eventRecorder = newEventRecorder(p.kubeClient, p.nodeName)
+	}
+	if err := p.setupServingCert(ctx, opts); err != nil {
+		return nil, err
+	}
+	if err := p.setupAuthorization(); err != nil {
+		return nil, err
+	}
+	if err := p.setupCSIDrivers(azAuth); err != nil {
 		return nil, err
 	}
 
-	p.operatingSystem = operatingSystem
-	p.nodeName = nodeName
-	p.internalIP = internalIP
-	p.daemonEndpointPort = daemonEndpointPort
+	if err := p.setupPodConfigs(ctx); err != nil {
+		return nil, err
+	}
+
+	if err := p.setupRuntimeClasses(ctx); err != nil {
+		return nil, err
+	}
+
+	if err := p.setupTemplateHook(); err != nil {
+		return nil, err
+	}
+	p.setupPrometheus(ctx)
+	if err := p.setupKubeletAPI(ctx, opts); err != nil {
+		return nil, err
+	}
 
 	if subnetName := os.Getenv("ACI_SUBNET_NAME"); p.vnetName != "" && subnetName != "" {
 		p.subnetName = subnetName
@@ -336,6 +656,9 @@ func NewACIProvider(config string, rm *manager.ResourceManager, nodeName, operat
 	}
 
 	if p.subnetName != "" {
+		if err := p.setupSubnetManagement(); err != nil {
+			return nil, err
+		}
 		if err := p.setupNetworkProfile(azAuth); err != nil {
 			return nil, fmt.Errorf("error setting up network profile: %v", err)
 		}
@@ -361,9 +684,95 @@ func NewACIProvider(config string, rm *manager.ResourceManager, nodeName, operat
 		}
 	}
 
+	if err := p.setupSecurityRules(); err != nil {
+		return nil, err
+	}
+
 	return &p, err
 }
 
+// authenticationFromEnv returns the Azure authentication of the AZURE_AUTH_LOCATION file or of the
+// ACS_CREDENTIAL_LOCATION credential, overridden by the AZURE_* environment variables. The resource group, region and
+// virtual network of the ACS credential are set on the provider.
+func (p *ACIProvider) authenticationFromEnv(ctx context.Context) (*client.Authentication, error) {
+	var azAuth *client.Authentication
+
+	if authFilepath := os.Getenv("AZURE_AUTH_LOCATION"); authFilepath != "" {
+		auth, err := client.NewAuthenticationFromFile(authFilepath)
+		if err != nil {
+			return nil, err
+		}
+
+		azAuth = auth
+	}
+
+	if acsFilepath := os.Getenv("ACS_CREDENTIAL_LOCATION"); acsFilepath != "" {
+		acsCredential, err := NewAcsCredential(acsFilepath)
+		if err != nil {
+			return nil, err
+		}
+
+		if acsCredential != nil {
+			var clientId string
+			if !strings.EqualFold(acsCredential.ClientID, "msi") {
+				clientId = acsCredential.ClientID
+			}
+
+			azAuth = client.NewAuthentication(
+				acsCredential.Cloud,
+				clientId,
+				acsCredential.ClientSecret,
+				acsCredential.SubscriptionID,
+				acsCredential.TenantID,
+				acsCredential.UserAssignedIdentityID)
+
+			p.resourceGroup = acsCredential.ResourceGroup
+			p.region = acsCredential.Region
+
+			p.vnetName = acsCredential.VNetName
+			p.vnetResourceGroup = acsCredential.VNetResourceGroup
+			if p.vnetResourceGroup == "" {
+				p.vnetResourceGroup = p.resourceGroup
+			}
+		}
+	}
+
+	if azAuth == nil {
+		return nil, errors.New("No Azure authentication found, please set AZURE_AUTH_LOCATION or ACS_CREDENTIAL_LOCATION")
+	}
+
+	if clientID := os.Getenv("AZURE_CLIENT_ID"); clientID != "" {
+		azAuth.ClientID = clientID
+	}
+
+	if clientSecret := os.Getenv("AZURE_CLIENT_SECRET"); clientSecret != "" {
+		azAuth.ClientSecret = clientSecret
+	}
+
+	if userIdentityClientId := os.Getenv("VIRTUALNODE_USER_IDENTITY_CLIENTID"); userIdentityClientId != "" {
+		azAuth.UserIdentityClientId = userIdentityClientId
+	}
+	azAuth.UseUserIdentity = (len(azAuth.ClientID) == 0)
+
+	if azAuth.UseUserIdentity {
+		if len(azAuth.UserIdentityClientId) == 0 {
+			return nil, fmt.Errorf("Neither AZURE_CLIENT_ID or VIRTUALNODE_USER_IDENTITY_CLIENTID is being set")
+		}
+
+		log.G(ctx).Info("Using user identity for authentication")
+	}
+
+	if tenantID := os.Getenv("AZURE_TENANT_ID"); tenantID != "" {
+		azAuth.TenantID = tenantID
+	}
+
+	if subscriptionID := os.Getenv("AZURE_SUBSCRIPTION_ID"); subscriptionID != "" {
+		azAuth.SubscriptionID = subscriptionID
+	}
+
+	return azAuth, nil
+}
+
 func (p *ACIProvider) setupCapacity(ctx context.Context) error {
 	ctx, span := trace.StartSpan(ctx, "setupCapacity")
 	defer span.End()
@@ -386,6 +795,10 @@ func (p *ACIProvider) setupCapacity(ctx context.Context) error {
 		p.pods = podsQuota
 	}
 
+	if err := p.setupPodOverhead(); err != nil {
+		return err
+	}
+
 	metadata, err := p.aciClient.GetResourceProviderMetadata(ctx)
 
 	if err != nil {
@@ -413,12 +826,23 @@ func (p *ACIProvider) setupCapacity(ctx context.Context) error {
 }
 
 func (p *ACIProvider) setupNetworkProfile(auth *client.Authentication) error {
-	c, err := network.NewClient(auth, p.extraUserAgent)
+	c, err := network.NewClient(p.authFor(p.vnetResourceGroup, auth), p.extraUserAgent)
 	if err != nil {
 		return fmt.Errorf("error creating azure networking client: %v", err)
 	}
+	p.networkClient = c
+
+	// The network profile is in the resource group of the container groups, whose identity may not be
+	// the one of the virtual network.
+	profiles := c
+	if profileAuth := p.authFor(p.resourceGroup, auth); profileAuth != p.authFor(p.vnetResourceGroup, auth) {
+		if profiles, err = network.NewClient(profileAuth, p.extraUserAgent); err != nil {
+			return fmt.Errorf("error creating azure networking client: %v", err)
+		}
+	}
 
 	createSubnet := true
+	delegateSubnet := false
 	subnet, err := c.GetSubnet(p.vnetResourceGroup, p.vnetName, p.subnetName)
 	if err != nil && !network.IsNotFound(err) {
 		return fmt.Errorf("error while looking up subnet: %v", err)
@@ -433,9 +857,6 @@ func (p *ACIProvider) setupNetworkProfile(auth *client.Authentication) error {
 		if p.subnetCIDR != *subnet.SubnetPropertiesFormat.AddressPrefix {
 			return fmt.Errorf("found subnet '%s' using different CIDR: '%s'. desired: '%s'", p.subnetName, *subnet.SubnetPropertiesFormat.AddressPrefix, p.subnetCIDR)
 		}
-		if subnet.SubnetPropertiesFormat.RouteTable != nil {
-			return fmt.Errorf("unable to delegate subnet '%s' to Azure Container Instance since it references the route table '%s'.", p.subnetName, *subnet.SubnetPropertiesFormat.RouteTable.ID)
-		}
 		if subnet.SubnetPropertiesFormat.ServiceAssociationLinks != nil {
 			for _, l := range *subnet.SubnetPropertiesFormat.ServiceAssociationLinks {
 				if l.ServiceAssociationLinkPropertiesFormat != nil {
@@ -448,27 +869,40 @@ func (p *ACIProvider) setupNetworkProfile(auth *client.Authentication) error {
 				}
 			}
 		} else {
-			for _, d := range *subnet.SubnetPropertiesFormat.Delegations {
-				if d.ServiceDelegationPropertiesFormat != nil && *d.ServiceDelegationPropertiesFormat.ServiceName == subnetDelegationService {
-					createSubnet = false
-					break
-				}
+			delegated, err := subnetDelegatedToACI(subnet)
+			if err != nil {
+				return err
 			}
+			// The existing subnet only lacks the delegation, it is repaired instead of replaced.
+			createSubnet = false
+			delegateSubnet = !delegated
 		}
 	}
 
+	if (createSubnet || delegateSubnet) && !p.manageSubnet {
+		return fmt.Errorf("subnet '%s' of vnet '%s' is not delegated to Azure Container Instance and ACI_MANAGE_SUBNET is false, delegate it with: az network vnet subnet update --resource-group %s --vnet-name %s --name %s --delegations %s",
+			p.subnetName, p.vnetName, p.vnetResourceGroup, p.vnetName, p.subnetName, subnetDelegationService)
+	}
 	if createSubnet {
 		subnet = network.NewSubnetWithContainerInstanceDelegation(p.subnetName, p.subnetCIDR)
 		subnet, err = c.CreateOrUpdateSubnet(p.vnetResourceGroup, p.vnetName, subnet)
 		if err != nil {
-			return fmt.Errorf("error creating subnet: %v", err)
+			return subnetWriteError("creating", p.subnetName, err)
+		}
+	}
+	if delegateSubnet {
+		log.G(context.TODO()).Infof("delegating subnet %s of vnet %s to Azure Container Instance", p.subnetName, p.vnetName)
+		network.AddContainerInstanceDelegation(subnet)
+		subnet, err = c.CreateOrUpdateSubnet(p.vnetResourceGroup, p.vnetName, subnet)
+		if err != nil {
+			return subnetWriteError("delegating", p.subnetName, err)
 		}
 	}
 	if p.networkProfileName == "" {
 		p.networkProfileName = getNetworkProfileName(*subnet.ID)
 	}
 
-	profile, err := c.GetProfile(p.resourceGroup, p.networkProfileName)
+	profile, err := profiles.GetProfile(p.resourceGroup, p.networkProfileName)
 	if err != nil && !network.IsNotFound(err) {
 		return fmt.Errorf("error while looking up network profile: %v", err)
 	}
@@ -485,8 +919,11 @@ func (p *ACIProvider) setupNetworkProfile(auth *client.Authentication) error {
 
 	// at this point, profile should be nil
 	profile = network.NewNetworkProfile(p.networkProfileName, p.region, *subnet.ID)
-	profile, err = c.CreateOrUpdateProfile(p.resourceGroup, profile)
+	profile, err = profiles.CreateOrUpdateProfile(p.resourceGroup, profile)
 	if err != nil {
+		if network.IsForbidden(err) {
+			return fmt.Errorf("error creating network profile '%s', the identity of the virtual kubelet needs the Microsoft.Network/networkProfiles/write permission on resource group '%s': %v", p.networkProfileName, p.resourceGroup, err)
+		}
 		return err
 	}
 
@@ -627,12 +1064,106 @@ func addAzureAttributes(ctx context.Context, span trace.Span, p *ACIProvider) co
 	})
 }
 
+// addPodAttributes adds the operation, the pod and its container group to the span and the logs, along with
+// the correlation ID sent with the requests to ARM of the operation.
+func addPodAttributes(ctx context.Context, span trace.Span, operation, namespace, name string, uid types.UID) context.Context {
+	id := client.CorrelationID(ctx)
+	if id == "" {
+		id = uuid.New().String()
+		ctx = client.WithCorrelationID(ctx, id)
+	}
+
+	fields := log.Fields{
+		"operation":            operation,
+		"pod.namespace":        namespace,
+		"pod.name":             name,
+		"azure.containerGroup": containerGroupName(namespace, name),
+		"azure.correlationID":  id,
+	}
+	if uid != "" {
+		fields["pod.uid"] = string(uid)
+	}
+	return span.WithFields(ctx, fields)
+}
+
 // CreatePod accepts a Pod definition and creates
 // an ACI deployment
 func (p *ACIProvider) CreatePod(ctx context.Context, pod *v1.Pod) error {
 	ctx, span := trace.StartSpan(ctx, "aci.CreatePod")
 	defer span.End()
 	ctx = addAzureAttributes(ctx, span, p)
+	ctx = addPodAttributes(ctx, span, "CreatePod", pod.Namespace, pod.Name, pod.UID)
+	start := time.Now()
+	p.trackRecording(pod)
+
+	if err := p.checkPodNamespace(pod); err != nil {
+		return err
+	}
+
+	if p.deletions != nil {
+		if err := p.deletions.wait(ctx, pod); err != nil {
+			return err
+		}
+	}
+
+	if err := p.checkPodFitsCapacity(ctx, pod); err != nil {
+		return err
+	}
+
+	if err := p.checkPodArchitecture(pod); err != nil {
+		return err
+	}
+
+	if err := p.checkUnsupportedFields(pod); err != nil {
+		return err
+	}
+
+	containerGroup, err := p.getContainerGroupFromPod(ctx, pod)
+	if err != nil {
+		return err
+	}
+
+	if err := p.checkPolicyRestrictions(ctx, pod, containerGroup); err != nil {
+		return err
+	}
+
+	cancelBudget, err := p.reserveNamespaceBudget(ctx, pod, containerGroup)
+	if err != nil {
+		return err
+	}
+
+	if err := p.placeInZone(pod, containerGroup); err != nil {
+		cancelBudget()
+		return err
+	}
+
+	translated := time.Now()
+	p.translations.add(pod.Namespace, pod.Name, containerGroup)
+
+	log.G(ctx).Infof("start creating pod %v", pod.Name)
+	p.recordImagePullPolicyEvents(ctx, pod)
+	// TODO: Run in a go routine to not block workers, and use taracker.UpdatePodStatus() based on result.
+	if err := p.createContainerGroup(ctx, pod, containerGroup); err != nil {
+		if aci.IsQuotaExceeded(err) {
+			// The pod keeps its budget while it waits for quota.
+			p.waitForQuota(ctx, pod, containerGroup, err)
+			return nil
+		}
+		cancelBudget()
+		p.checkNetworkOnFailure(ctx, err)
+		return err
+	}
+
+	p.createLatencies.created(pod, start, translated, time.Now())
+	return nil
+}
+
+// getContainerGroupFromPod translates the pod spec into the ACI container group to deploy.
+func (p *ACIProvider) getContainerGroupFromPod(ctx context.Context, pod *v1.Pod) (*aci.ContainerGroup, error) {
+	pod, settings, err := p.applyPodConfig(ctx, pod)
+	if err != nil {
+		return nil, err
+	}
 
 	var containerGroup aci.ContainerGroup
 	containerGroup.Location = p.region
@@ -642,42 +1173,62 @@ func (p *ACIProvider) CreatePod(ctx context.Context, pod *v1.Pod) error {
 	// get containers
 	containers, err := p.getContainers(pod)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	// get registry creds
 	creds, err := p.getImagePullSecrets(pod)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	// get volumes
-	volumes, err := p.getVolumes(pod)
+	volumes, initContainers, err := p.getVolumes(ctx, pod)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if len(initContainers) > 0 && strings.EqualFold(p.operatingSystem, "Windows") {
+		return nil, errdefs.InvalidInputf("pod %s requires init containers to populate its volumes, which ACI only supports on Linux", pod.Name)
 	}
 	// assign all the things
 	containerGroup.ContainerGroupProperties.Containers = containers
+	containerGroup.ContainerGroupProperties.InitContainers = initContainers
 	containerGroup.ContainerGroupProperties.Volumes = volumes
 	containerGroup.ContainerGroupProperties.ImageRegistryCredentials = creds
 	containerGroup.ContainerGroupProperties.Diagnostics = p.getDiagnostics(pod)
+	if settings != nil && settings.Diagnostics != nil {
+		containerGroup.ContainerGroupProperties.Diagnostics = p.podDiagnostics(pod, settings.Diagnostics)
+	}
+
+	if err := p.applyRuntimeClassProfile(pod, &containerGroup); err != nil {
+		return nil, err
+	}
+	if settings != nil && settings.SKU != "" && containerGroup.Sku == "" {
+		containerGroup.Sku = (runtimeClassProfile{SKU: settings.SKU}).containerGroupSku()
+	}
+	if err := validateSecurityContexts(pod, &containerGroup); err != nil {
+		return nil, err
+	}
+	if err := validateAppArmorProfiles(pod); err != nil {
+		return nil, err
+	}
+	if err := p.applyConfidentialPolicy(pod, &containerGroup); err != nil {
+		return nil, err
+	}
+	applySpotPriority(pod, &containerGroup)
 
-	filterServiceAccountSecretVolume(p.operatingSystem, &containerGroup)
+	filterServiceAccountSecretVolume(string(containerGroup.ContainerGroupProperties.OsType), &containerGroup)
 
 	// create ipaddress if containerPort is used
-	count := 0
-	for _, container := range containers {
-		count = count + len(container.Ports)
+	ports, err := getContainerGroupPorts(pod)
+	if err != nil {
+		return nil, err
 	}
-	ports := make([]aci.Port, 0, count)
-	for _, container := range containers {
-		for _, containerPort := range container.Ports {
-
-			ports = append(ports, aci.Port{
-				Port:     containerPort.Port,
-				Protocol: aci.ContainerGroupNetworkProtocol("TCP"),
-			})
+	public := false
+	if len(ports) > 0 && p.subnetName == "" {
+		if public, err = p.exposePublicly(pod); err != nil {
+			return nil, err
 		}
 	}
-	if len(ports) > 0 && p.subnetName == "" {
+	if public {
 		containerGroup.ContainerGroupProperties.IPAddress = &aci.IPAddress{
 			Ports: ports,
 			Type:  "Public",
@@ -685,6 +1236,11 @@ func (p *ACIProvider) CreatePod(ctx context.Context, pod *v1.Pod) error {
 
 		if dnsNameLabel := pod.Annotations[virtualKubeletDNSNameLabel]; dnsNameLabel != "" {
 			containerGroup.ContainerGroupProperties.IPAddress.DNSNameLabel = dnsNameLabel
+			scope, err := p.dnsNameLabelScope(pod)
+			if err != nil {
+				return nil, err
+			}
+			containerGroup.ContainerGroupProperties.IPAddress.AutoGeneratedDomainNameLabelScope = scope
 		}
 	}
 
@@ -698,18 +1254,42 @@ func (p *ACIProvider) CreatePod(ctx context.Context, pod *v1.Pod) error {
 		"UID":               podUID,
 		"CreationTimestamp": podCreationTimestamp,
 	}
+	if fields := unsupportedPodFields(pod); len(fields) > 0 {
+		containerGroup.Tags[unsupportedFieldsTag] = unsupportedFieldsTagValue(fields)
+	}
+	if settings != nil {
+		applyTags(&containerGroup, settings.Tags)
+	}
 
 	p.amendVnetResources(&containerGroup, pod)
 
-	log.G(ctx).Infof("start creating pod %v", pod.Name)
-	// TODO: Run in a go routine to not block workers, and use taracker.UpdatePodStatus() based on result.
-	return p.createContainerGroup(ctx, pod.Namespace, pod.Name, &containerGroup)
+	if err := p.applyExtensions(pod, &containerGroup); err != nil {
+		return nil, err
+	}
+
+	if err := p.amendStandbyPoolProfile(&containerGroup, pod); err != nil {
+		return nil, err
+	}
+
+	if err := p.applyTemplateHook(ctx, pod, &containerGroup); err != nil {
+		return nil, err
+	}
+
+	return &containerGroup, nil
 }
 
-func (p *ACIProvider) createContainerGroup(ctx context.Context, podNS, podName string, cg *aci.ContainerGroup) error {
+func (p *ACIProvider) createContainerGroup(ctx context.Context, pod *v1.Pod, cg *aci.ContainerGroup) error {
 	ctx, span := trace.StartSpan(ctx, "aci.createContainerGroup")
 	defer span.End()
 	ctx = addAzureAttributes(ctx, span, p)
+	podNS, podName := pod.Namespace, pod.Name
+
+	if err := p.provisionScratchShares(ctx, pod, cg); err != nil {
+		return err
+	}
+
+	p.terminalStatuses.remove(podNS, podName)
+	p.instanceViews.remove(podNS, podName)
 
 	cgName := containerGroupName(podNS, podName)
 	_, err := p.aciClient.CreateContainerGroup(
@@ -721,9 +1301,13 @@ func (p *ACIProvider) createContainerGroup(ctx context.Context, podNS, podName s
 
 	if err != nil {
 		log.G(ctx).WithError(err).Errorf("failed to create container group %v", cgName)
+		p.armErrors.add(ctx, podNS, podName, "create", err)
+		return err
 	}
+	p.provisioning.created(podNS, podName, time.Now())
+	p.createFailures.created(podNS, podName, client.CorrelationID(ctx))
 
-	return err
+	return nil
 }
 
 func (p *ACIProvider) amendVnetResources(containerGroup *aci.ContainerGroup, pod *v1.Pod) {
@@ -848,21 +1432,129 @@ func formDNSSearchFitsLimits(searches []string) string {
 }
 
 func (p *ACIProvider) getDiagnostics(pod *v1.Pod) *aci.ContainerGroupDiagnostics {
-	if p.diagnostics != nil && p.diagnostics.LogAnalytics != nil && p.diagnostics.LogAnalytics.LogType == aci.LogAnlyticsLogTypeContainerInsights {
-		d := *p.diagnostics
-		d.LogAnalytics.Metadata[aci.LogAnalyticsMetadataKeyPodUUID] = string(pod.ObjectMeta.UID)
-		return &d
+	diagnostics := p.diagnostics
+	if d, ok := p.namespaceDiagnostics[pod.Namespace]; ok {
+		diagnostics = d
 	}
-	return p.diagnostics
+	return p.podDiagnostics(pod, diagnostics)
+}
+
+// podDiagnostics returns the diagnostics of a pod from the ones of its namespace or of the node, with the metadata
+// of the pod for the Container Insights logs.
+func (p *ACIProvider) podDiagnostics(pod *v1.Pod, diagnostics *aci.ContainerGroupDiagnostics) *aci.ContainerGroupDiagnostics {
+	if diagnostics != nil && diagnostics.LogAnalytics != nil && diagnostics.LogAnalytics.LogType == aci.LogAnlyticsLogTypeContainerInsights {
+		// Copy the workspace and its metadata, they are shared by all the pods.
+		la := *diagnostics.LogAnalytics
+		la.Metadata = make(map[string]string, len(diagnostics.LogAnalytics.Metadata)+2)
+		for k, v := range diagnostics.LogAnalytics.Metadata {
+			la.Metadata[k] = v
+		}
+		if _, ok := la.Metadata[aci.LogAnalyticsMetadataKeyNodeName]; !ok && p.nodeName != "" {
+			la.Metadata[aci.LogAnalyticsMetadataKeyNodeName] = p.nodeName
+		}
+		la.Metadata[aci.LogAnalyticsMetadataKeyPodUUID] = string(pod.ObjectMeta.UID)
+		return &aci.ContainerGroupDiagnostics{LogAnalytics: &la}
+	}
+	return diagnostics
 }
 
 func containerGroupName(podNS, podName string) string {
 	return fmt.Sprintf("%s-%s", podNS, podName)
 }
 
-// UpdatePod is a noop, ACI currently does not support live updates of a pod.
+// UpdatePod applies resource changes of a pod to its container group.
+// ACI does not support live updates of a pod other than resources, so any other change is a noop.
+// The container group is updated in place where ACI allows it, otherwise it is recreated.
+// Pods annotated for suspension get their container group stopped, and started again on resume.
+// Pods annotated with a restart request get their container group restarted in place.
 func (p *ACIProvider) UpdatePod(ctx context.Context, pod *v1.Pod) error {
-	return nil
+	ctx, span := trace.StartSpan(ctx, "aci.UpdatePod")
+	defer span.End()
+	ctx = addAzureAttributes(ctx, span, p)
+	ctx = addPodAttributes(ctx, span, "UpdatePod", pod.Namespace, pod.Name, pod.UID)
+	p.trackRecording(pod)
+
+	if err := p.rejectEphemeralContainers(pod); err != nil {
+		return err
+	}
+
+	// A restart or resume brings a terminated container group back to life.
+	p.terminalStatuses.remove(pod.Namespace, pod.Name)
+	p.instanceViews.remove(pod.Namespace, pod.Name)
+
+	cg, err := p.getContainerGroup(ctx, pod.Namespace, pod.Name)
+	if err != nil {
+		if errdefs.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	if handled, err := p.reconcileSuspend(ctx, pod, cg); handled || err != nil {
+		return err
+	}
+
+	if restarted, err := p.reconcileRestart(ctx, pod, cg); restarted || err != nil {
+		return err
+	}
+
+	desired, err := p.getContainerGroupFromPod(ctx, pod)
+	if err != nil {
+		return err
+	}
+
+	if !containerResourcesChanged(cg.Containers, desired.Containers) {
+		return nil
+	}
+
+	// Updating the container group resets the restart counts of its containers.
+	desired.Tags = withRestartBaseline(desired.Tags, cg, time.Now())
+
+	cancelBudget, err := p.reserveNamespaceBudget(ctx, pod, desired)
+	if err != nil {
+		return err
+	}
+
+	log.G(ctx).Infof("start resizing pod %v", pod.Name)
+	_, err = p.aciClient.UpdateContainerGroup(ctx, p.resourceGroup, cg.Name, *desired)
+	if err == nil {
+		return nil
+	}
+	if !aci.IsUpdateNotSupported(err) {
+		log.G(ctx).WithError(err).Errorf("failed to resize container group %v", cg.Name)
+		cancelBudget()
+		return err
+	}
+
+	log.G(ctx).WithError(err).Warnf("container group %v can not be resized in place, recreating it", cg.Name)
+	if err := p.aciClient.DeleteContainerGroup(ctx, p.resourceGroup, cg.Name); err != nil {
+		log.G(ctx).WithError(err).Errorf("failed to delete container group %v for resize", cg.Name)
+		cancelBudget()
+		return err
+	}
+
+	return p.createContainerGroup(ctx, pod, desired)
+}
+
+// containerResourcesChanged reports whether only the resources of the containers differ.
+// Any other difference (containers added, removed or using another image) is not resizable.
+func containerResourcesChanged(current, desired []aci.Container) bool {
+	if len(current) != len(desired) {
+		return false
+	}
+
+	changed := false
+	for i := range desired {
+		if current[i].Name != desired[i].Name || current[i].Image != desired[i].Image {
+			return false
+		}
+
+		if !reflect.DeepEqual(current[i].Resources, desired[i].Resources) {
+			changed = true
+		}
+	}
+
+	return changed
 }
 
 // DeletePod deletes the specified pod out of ACI.
@@ -870,10 +1562,35 @@ func (p *ACIProvider) DeletePod(ctx context.Context, pod *v1.Pod) error {
 	ctx, span := trace.StartSpan(ctx, "aci.DeletePod")
 	defer span.End()
 	ctx = addAzureAttributes(ctx, span, p)
+	ctx = addPodAttributes(ctx, span, "DeletePod", pod.Namespace, pod.Name, pod.UID)
 
 	log.G(ctx).Infof("start deleting pod %v", pod.Name)
-	// TODO: Run in a go routine to not block workers.
-	return p.deleteContainerGroup(ctx, pod.Namespace, pod.Name)
+	p.quotaWaits.remove(pod.Namespace, pod.Name)
+	p.deregisterBackendPools(ctx, pod)
+	p.deletePodSecurityRule(ctx, pod)
+	if p.deletions != nil {
+		p.deletions.enqueue(ctx, pod)
+		return nil
+	}
+	return p.deletePodResources(ctx, pod)
+}
+
+// deletePodResources deletes the container group of the pod and its scratch shares. The container group
+// of a pod recreated since under the same name, which has the same container group name, is kept.
+func (p *ACIProvider) deletePodResources(ctx context.Context, pod *v1.Pod) error {
+	if pod.UID != "" {
+		cg, err := p.getContainerGroup(ctx, pod.Namespace, pod.Name)
+		if err == nil && cg.Tags["UID"] != "" && cg.Tags["UID"] != string(pod.UID) {
+			log.G(ctx).Infof("keeping container group %s of pod %s recreated as %s", containerGroupName(pod.Namespace, pod.Name), pod.UID, cg.Tags["UID"])
+			p.deleteScratchShares(ctx, pod)
+			return nil
+		}
+	}
+	if err := p.deleteContainerGroup(ctx, pod.Namespace, pod.Name); err != nil {
+		return err
+	}
+	p.deleteScratchShares(ctx, pod)
+	return nil
 }
 
 func (p *ACIProvider) deleteContainerGroup(ctx context.Context, podNS, podName string) error {
@@ -881,12 +1598,28 @@ func (p *ACIProvider) deleteContainerGroup(ctx context.Context, podNS, podName s
 	defer span.End()
 	ctx = addAzureAttributes(ctx, span, p)
 
+	// Keep the logs of the containers before they are gone with the container group.
+	p.archivePodLogs(ctx, podNS, podName)
+
 	cgName := containerGroupName(podNS, podName)
 	err := p.aciClient.DeleteContainerGroup(ctx, p.resourceGroup, cgName)
 	if err != nil {
 		log.G(ctx).WithError(err).Errorf("failed to delete container group %v", cgName)
+		p.armErrors.add(ctx, podNS, podName, "delete", err)
 		return err
 	}
+	p.terminalStatuses.remove(podNS, podName)
+	p.instanceViews.remove(podNS, podName)
+	p.createLatencies.remove(podNS, podName)
+	p.provisioning.remove(podNS, podName)
+	p.repairs.remove(podNS, podName)
+	p.createFailures.remove(podNS, podName)
+	p.recordings.remove(podNS, podName)
+	p.armErrors.remove(podNS, podName)
+	p.translations.remove(podNS, podName)
+	p.budgetReservations.remove(podNS, podName)
+	p.zonePlacements.remove(podNS, podName)
+	p.preemptions.remove(podNS, podName)
 
 	if p.tracker != nil {
 		// Delete is not an sync API on ACI yet, but will assume with current implementation that termination is completed. Also, till gracePeriod is supported.
@@ -928,6 +1661,7 @@ func (p *ACIProvider) GetPod(ctx context.Context, namespace, name string) (*v1.P
 	ctx, span := trace.StartSpan(ctx, "aci.GetPod")
 	defer span.End()
 	ctx = addAzureAttributes(ctx, span, p)
+	ctx = addPodAttributes(ctx, span, "GetPod", namespace, name, "")
 
 	cg, err := p.getContainerGroup(ctx, namespace, name)
 	if err != nil {
@@ -942,6 +1676,12 @@ func (p *ACIProvider) GetContainerLogs(ctx context.Context, namespace, podName,
 	ctx, span := trace.StartSpan(ctx, "aci.GetContainerLogs")
 	defer span.End()
 	ctx = addAzureAttributes(ctx, span, p)
+	ctx = addPodAttributes(ctx, span, "GetContainerLogs", namespace, podName, "")
+	if err := p.authorize(ctx, "get", "proxy"); err != nil {
+		return nil, err
+	}
+	// kubectl logs is waiting, send its requests before the background status refreshes.
+	ctx = aci.WithPriority(ctx, aci.PriorityHigh)
 
 	cg, err := p.getContainerGroup(ctx, namespace, podName)
 	if err != nil {
@@ -972,12 +1712,26 @@ func (p *ACIProvider) GetPodFullName(namespace string, pod string) string {
 
 // RunInContainer executes a command in a container in the pod, copying data
 // between in/out/err and the container's stdin/stdout/stderr.
+// The start and end of every session are audited.
 func (p *ACIProvider) RunInContainer(ctx context.Context, namespace, name, container string, cmd []string, attach api.AttachIO) error {
+	session := p.startExecSession(ctx, namespace, name, container, cmd, attach.TTY())
+	err := p.runInContainer(ctx, namespace, name, container, cmd, attach)
+	session.end(err)
+	return err
+}
+
+func (p *ACIProvider) runInContainer(ctx context.Context, namespace, name, container string, cmd []string, attach api.AttachIO) error {
 	out := attach.Stdout()
 	if out != nil {
 		defer out.Close()
 	}
 
+	if err := p.authorize(ctx, "create", "proxy"); err != nil {
+		return err
+	}
+
+	// kubectl exec is waiting, send its requests before the background status refreshes.
+	ctx = aci.WithPriority(ctx, aci.PriorityHigh)
 	cg, err := p.getContainerGroup(ctx, namespace, name)
 	if err != nil {
 		return err
@@ -999,83 +1753,54 @@ func (p *ACIProvider) RunInContainer(ctx context.Context, namespace, name, conta
 	}
 
 	ts := aci.TerminalSizeRequest{Height: int(size.Height), Width: int(size.Width)}
-	xcrsp, err := p.aciClient.LaunchExec(p.resourceGroup, cg.Name, container, strings.Join(cmd, " "), ts)
+	xcrsp, err := p.aciClient.LaunchExec(p.resourceGroup, cg.Name, container, execCommand(cmd, attach.TTY()), ts)
 	if err != nil {
 		return err
 	}
 
-	wsURI := xcrsp.WebSocketURI
-	password := xcrsp.Password
-
-	c, _, _ := websocket.DefaultDialer.Dial(wsURI, nil)
-	if err := c.WriteMessage(websocket.TextMessage, []byte(password)); err != nil { // Websocket password needs to be sent before WS terminal is active
-		panic(err)
+	c, err := p.dataPlane.DialExec(ctx, xcrsp)
+	if err != nil {
+		return fmt.Errorf("failed to connect to the exec websocket of container %s: %v", container, err)
 	}
-
 	// Cleanup on exit
 	defer c.Close()
 
 	in := attach.Stdin()
 	if in != nil {
-		go func() {
-			for {
-				select {
-				case <-ctx.Done():
-					return
-				default:
-				}
-
-				var msg = make([]byte, 512)
-				n, err := in.Read(msg)
-				if err != nil {
-					// Handle errors
-					return
-				}
-				if n > 0 { // Only call WriteMessage if there is data to send
-					if err := c.WriteMessage(websocket.BinaryMessage, msg[:n]); err != nil {
-						panic(err)
-					}
-				}
-			}
-		}()
+		goSubsystem("exec", func() { copyToExecWebsocket(ctx, c, in) })
 	}
 
-	if out != nil {
-		for {
-			select {
-			case <-ctx.Done():
-				break
-			default:
-			}
-
-			_, cr, err := c.NextReader()
-			if err != nil {
-				// Handle errors
-				break
-			}
-			if _, err := io.Copy(out, cr); err != nil {
-				panic(err)
-			}
-		}
+	if out == nil {
+		// Drain the websocket until the command exits.
+		return copyFromExecWebsocket(ctx, c, ioutil.Discard)
 	}
 
-	return ctx.Err()
+	return copyFromExecWebsocket(ctx, c, out)
 }
 
 // ConfigureNode enables a provider to configure the node object that
 // will be used for Kubernetes.
 func (p *ACIProvider) ConfigureNode(ctx context.Context, node *v1.Node) {
 	node.Status.Capacity = p.capacity()
-	node.Status.Allocatable = p.capacity()
+	node.Status.Allocatable = p.allocatable()
 	node.Status.Conditions = p.nodeConditions()
 	node.Status.Addresses = p.nodeAddresses()
 	node.Status.DaemonEndpoints = p.nodeDaemonEndpoints()
 	node.Status.NodeInfo.OperatingSystem = p.operatingSystem
+	node.Status.NodeInfo.Architecture = p.architecture
+	node.ObjectMeta.Labels[archLabel] = p.architecture
+	node.ObjectMeta.Labels[betaArchLabel] = p.architecture
 	node.ObjectMeta.Labels["alpha.service-controller.kubernetes.io/exclude-balancer"] = "true"
 	node.ObjectMeta.Labels["node.kubernetes.io/exclude-from-external-load-balancers"] = "true"
 
 	// Virtual node would be skipped for cloud provider operations (e.g. CP should not add route).
 	node.ObjectMeta.Labels["kubernetes.azure.com/managed"] = "false"
+	p.applyNodeConfig(node)
+
+	// Keep the node to notify the node controller of its status changes.
+	p.nodeMu.Lock()
+	p.node = node.DeepCopy()
+	p.nodeMu.Unlock()
 }
 
 // GetPodStatus returns the status of a pod by name that is running inside ACI
@@ -1084,13 +1809,24 @@ func (p *ACIProvider) GetPodStatus(ctx context.Context, namespace, name string)
 	ctx, span := trace.StartSpan(ctx, "aci.GetPodStatus")
 	defer span.End()
 	ctx = addAzureAttributes(ctx, span, p)
+	ctx = addPodAttributes(ctx, span, "GetPodStatus", namespace, name, "")
+
+	if status := p.terminalStatuses.get(namespace, name); status != nil {
+		return status, nil
+	}
 
 	cg, err := p.getContainerGroup(ctx, namespace, name)
 	if err != nil {
 		return nil, err
 	}
 
-	return podStatusFromContainerGroup(cg), nil
+	status := p.instanceViews.status(namespace, name, cg, podStatusFromContainerGroup)
+	p.checkProvisioningTimeout(ctx, namespace, name, cg, status)
+	p.checkCrashLoop(ctx, namespace, name, cg, status)
+	p.annotateAttestation(ctx, namespace, name, cg)
+	p.terminalStatuses.put(namespace, name, status)
+	p.observeCreateLatency(namespace, name, status)
+	return status, nil
 }
 
 // GetPods returns a list of all pods known to be running within ACI.
@@ -1099,28 +1835,24 @@ func (p *ACIProvider) GetPods(ctx context.Context) ([]*v1.Pod, error) {
 	defer span.End()
 	ctx = addAzureAttributes(ctx, span, p)
 
-	cgs, err := p.aciClient.ListContainerGroups(ctx, p.resourceGroup)
-	if err != nil {
-		return nil, err
-	}
-	pods := make([]*v1.Pod, 0, len(cgs.Value))
-
-	for _, cg := range cgs.Value {
-		c := cg
-		if cg.Tags["NodeName"] != p.nodeName {
-			continue
-		}
-
-		p, err := containerGroupToPod(&c)
+	pods := make([]*v1.Pod, 0)
+	// The container groups are converted as they are decoded, so the whole list is never held in memory.
+	err := p.visitNodeContainerGroups(ctx, func(cg *aci.ContainerGroup) error {
+		pod, err := containerGroupToPod(cg)
 		if err != nil {
 			log.G(ctx).WithFields(log.Fields{
-				"name": c.Name,
-				"id":   c.ID,
+				"name": cg.Name,
+				"id":   cg.ID,
 			}).WithError(err).Error("error converting container group to pod")
 
-			continue
+			return nil
 		}
-		pods = append(pods, p)
+		p.zonePlacements.observe(pod.Namespace, pod.Name, cg.Zones)
+		pods = append(pods, pod)
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return pods, nil
@@ -1139,26 +1871,57 @@ func (p *ACIProvider) NotifyPods(ctx context.Context, notifierCb func(*v1.Pod))
 		rm:       p.resourceManager,
 		updateCb: notifierCb,
 		handler:  p,
+		recorder: p.eventRecorder,
+
+		updatesInterval: p.updatesInterval,
+		updatesJitter:   p.updatesJitter,
+	}
+
+	goSubsystem("pods_tracker", func() { p.tracker.StartTracking(ctx) })
+
+	if p.logSink != nil {
+		goSubsystem("log_archive", func() { p.archiveLogsLoop(ctx) })
+	}
+
+	goSubsystem("quota_retry", func() { p.retryQuotaLoop(ctx) })
+	goSubsystem("image_pull_always", func() { p.imagePullAlwaysLoop(ctx) })
+
+	if p.networkClient != nil && p.subnetName != "" {
+		goSubsystem("network_check", func() { p.checkNetwork(ctx, p.networkClient) })
+	}
+
+	if p.costSource != nil {
+		goSubsystem("cost_report", func() { p.costReportLoop(ctx) })
+	}
+
+	if p.resourceHealth != nil {
+		goSubsystem("resource_health", func() { p.resourceHealthLoop(ctx) })
+	}
+
+	if p.backendPools != nil {
+		goSubsystem("backend_pools", func() { p.backendPoolsLoop(ctx) })
 	}
 
-	go p.tracker.StartTracking(ctx)
+	if p.securityRules != nil {
+		goSubsystem("security_rules", func() { p.securityRulesLoop(ctx) })
+	}
 }
 
 // PodsTrackerHandler interface impl.
 func (p *ACIProvider) ListActivePods(ctx context.Context) ([]PodIdentifier, error) {
-	providerPods, err := p.GetPods(ctx)
-	if err != nil {
-		return nil, err
-	}
-
-	podsIdentifiers := make([]PodIdentifier, 0, len(providerPods))
-	for _, pod := range providerPods {
+	var podsIdentifiers []PodIdentifier
+	// Only the tags are needed, the container groups are not converted to pods.
+	err := p.visitNodeContainerGroupTags(ctx, func(cg *aci.ContainerGroup) error {
 		podsIdentifiers = append(
 			podsIdentifiers,
 			PodIdentifier{
-				namespace: pod.Namespace,
-				name:      pod.Name,
+				namespace: cg.Tags["Namespace"],
+				name:      cg.Tags["PodName"],
 			})
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return podsIdentifiers, nil
@@ -1175,7 +1938,10 @@ func (p *ACIProvider) CleanupPod(ctx context.Context, ns, name string) error {
 // implement NodeProvider
 
 // Ping checks if the node is still active/ready.
+// It probes ARM when no request was sent for a while, but does not fail when ARM does: the node controller
+// keeps renewing the lease of the node, whose readiness is reported by its Ready condition instead.
 func (p *ACIProvider) Ping(ctx context.Context) error {
+	p.probeARM(ctx)
 	return nil
 }
 
@@ -1184,12 +1950,16 @@ func (p *ACIProvider) getContainerGroup(ctx context.Context, namespace, name str
 	cg, status, err := p.aciClient.GetContainerGroup(ctx, p.resourceGroup, fmt.Sprintf("%s-%s", namespace, name))
 	if err != nil {
 		if status != nil && *status == http.StatusNotFound {
+			// The container group was deleted behind the pod, its cached terminal status is gone with it.
+			p.terminalStatuses.remove(namespace, name)
 			return nil, errdefs.NotFound("cg not found")
 		}
+		p.armErrors.add(ctx, namespace, name, "get", err)
 		return nil, err
 	}
 
 	if cg.Tags["NodeName"] != p.nodeName {
+		p.terminalStatuses.remove(namespace, name)
 		return nil, errdefs.NotFound("cg found with mismatching node")
 	}
 
@@ -1201,7 +1971,7 @@ func (p *ACIProvider) capacity() v1.ResourceList {
 	resourceList := v1.ResourceList{
 		v1.ResourceCPU:    resource.MustParse(p.cpu),
 		v1.ResourceMemory: resource.MustParse(p.memory),
-		v1.ResourcePods:   resource.MustParse(p.pods),
+		v1.ResourcePods:   resource.MustParse(p.podsCapacity()),
 	}
 
 	if p.gpu != "" {
@@ -1215,15 +1985,8 @@ func (p *ACIProvider) capacity() v1.ResourceList {
 // within Kubernetes.
 func (p *ACIProvider) nodeConditions() []v1.NodeCondition {
 	// TODO: Make these dynamic and augment with custom ACI specific conditions of interest
-	return []v1.NodeCondition{
-		{
-			Type:               "Ready",
-			Status:             v1.ConditionTrue,
-			LastHeartbeatTime:  metav1.Now(),
-			LastTransitionTime: metav1.Now(),
-			Reason:             "KubeletReady",
-			Message:            "kubelet is ready.",
-		},
+	conditions := []v1.NodeCondition{
+		p.readyCondition(),
 		{
 			Type:               "OutOfDisk",
 			Status:             v1.ConditionFalse,
@@ -1256,19 +2019,15 @@ func (p *ACIProvider) nodeConditions() []v1.NodeCondition {
 			Reason:             "RouteCreated",
 			Message:            "RouteController created a route",
 		},
+		p.armCondition(),
 	}
-}
-
-// nodeAddresses returns a list of addresses for the node status
-// within Kubernetes.
-func (p *ACIProvider) nodeAddresses() []v1.NodeAddress {
-	// TODO: Make these dynamic and augment with custom ACI specific conditions of interest
-	return []v1.NodeAddress{
-		{
-			Type:    "InternalIP",
-			Address: p.internalIP,
-		},
+	if p.resourceHealth != nil {
+		conditions = append(conditions, p.serviceHealthCondition())
+	}
+	if p.permissionCheck != "" {
+		conditions = append(conditions, p.permissionsCondition())
 	}
+	return conditions
 }
 
 // nodeDaemonEndpoints returns NodeDaemonEndpoints for the node status
@@ -1423,12 +2182,21 @@ func (p *ACIProvider) getContainers(pod *v1.Pod) ([]aci.Container, error) {
 		c := aci.Container{
 			Name: container.Name,
 			ContainerProperties: aci.ContainerProperties{
-				Image:   container.Image,
+				Image:   p.mirrorImage(container.Image),
 				Command: append(container.Command, container.Args...),
 				Ports:   make([]aci.ContainerPort, 0, len(container.Ports)),
 			},
 		}
 
+		securityContext, err := getContainerSecurityContext(pod, &container)
+		if err != nil {
+			return nil, err
+		}
+		c.SecurityContext = securityContext
+		if err := p.applySeccompProfile(pod, &c); err != nil {
+			return nil, err
+		}
+
 		for _, p := range container.Ports {
 			c.Ports = append(c.Ports, aci.ContainerPort{
 				Port:     p.ContainerPort,
@@ -1455,20 +2223,19 @@ func (p *ACIProvider) getContainers(pod *v1.Pod) ([]aci.Container, error) {
 
 		// NOTE(robbiezhang): ACI CPU request must be times of 10m
 		cpuRequest := 1.00
-		if _, ok := container.Resources.Requests[v1.ResourceCPU]; ok {
-			cpuRequest = float64(container.Resources.Requests.Cpu().MilliValue()/10.00) / 100.00
-			if cpuRequest < 0.01 {
-				cpuRequest = 0.01
-			}
+		if cpu, ok := container.Resources.Requests[v1.ResourceCPU]; ok {
+			cpuRequest = aciCPURequest(cpu)
+		} else if cpu, ok := container.Resources.Limits[v1.ResourceCPU]; ok {
+			// Kubernetes defaults the request to the limit when only the limit is set.
+			cpuRequest = aciCPURequest(cpu)
 		}
 
 		// NOTE(robbiezhang): ACI memory request must be times of 0.1 GB
 		memoryRequest := 1.50
-		if _, ok := container.Resources.Requests[v1.ResourceMemory]; ok {
-			memoryRequest = float64(container.Resources.Requests.Memory().Value()/100000000.00) / 10.00
-			if memoryRequest < 0.10 {
-				memoryRequest = 0.10
-			}
+		if memory, ok := container.Resources.Requests[v1.ResourceMemory]; ok {
+			memoryRequest = aciMemoryRequest(memory)
+		} else if memory, ok := container.Resources.Limits[v1.ResourceMemory]; ok {
+			memoryRequest = aciMemoryRequest(memory)
 		}
 
 		c.Resources = aci.ResourceRequirements{
@@ -1480,15 +2247,25 @@ func (p *ACIProvider) getContainers(pod *v1.Pod) ([]aci.Container, error) {
 
 		if container.Resources.Limits != nil {
 			cpuLimit := cpuRequest
-			if _, ok := container.Resources.Limits[v1.ResourceCPU]; ok {
-				cpuLimit = float64(container.Resources.Limits.Cpu().MilliValue()) / 1000.00
+			if cpu, ok := container.Resources.Limits[v1.ResourceCPU]; ok {
+				cpuLimit = float64(cpu.MilliValue()) / 1000.00
 			}
 
 			// NOTE(jahstreet): ACI memory limit must be times of 0.1 GB
 			memoryLimit := memoryRequest
-			if _, ok := container.Resources.Limits[v1.ResourceMemory]; ok {
-				memoryLimit = float64(container.Resources.Limits.Memory().Value()/100000000.00) / 10.00
+			if memory, ok := container.Resources.Limits[v1.ResourceMemory]; ok {
+				memoryLimit = float64(memory.Value()/100000000.00) / 10.00
 			}
+
+			// Rounding the request up to the ACI minimum may push it above a very small limit,
+			// ACI rejects limits lower than requests so keep the limit at least as large.
+			if cpuLimit < cpuRequest {
+				cpuLimit = cpuRequest
+			}
+			if memoryLimit < memoryRequest {
+				memoryLimit = memoryRequest
+			}
+
 			c.Resources.Limits = &aci.ComputeResources{
 				CPU:        cpuLimit,
 				MemoryInGB: memoryLimit,
@@ -1535,6 +2312,26 @@ func (p *ACIProvider) getContainers(pod *v1.Pod) ([]aci.Container, error) {
 	return containers, nil
 }
 
+// aciCPURequest converts a CPU quantity into the ACI CPU request.
+// ACI CPU request must be times of 10m, with a minimum of 10m.
+func aciCPURequest(q resource.Quantity) float64 {
+	cpu := float64(q.MilliValue()/10.00) / 100.00
+	if cpu < 0.01 {
+		cpu = 0.01
+	}
+	return cpu
+}
+
+// aciMemoryRequest converts a memory quantity into the ACI memory request in GB.
+// ACI memory request must be times of 0.1 GB, with a minimum of 0.1 GB.
+func aciMemoryRequest(q resource.Quantity) float64 {
+	memory := float64(q.Value()/100000000.00) / 10.00
+	if memory < 0.10 {
+		memory = 0.10
+	}
+	return memory
+}
+
 func (p *ACIProvider) getGPUSKU(pod *v1.Pod) (aci.GPUSKU, error) {
 	if len(p.gpuSKUs) == 0 {
 		return "", fmt.Errorf("The pod requires GPU resource, but ACI doesn't provide GPU enabled container group in region %s", p.region)
@@ -1550,6 +2347,16 @@ func (p *ACIProvider) getGPUSKU(pod *v1.Pod) (aci.GPUSKU, error) {
 		return "", fmt.Errorf("The pod requires GPU SKU %s, but ACI only supports SKUs %v in region %s", desiredSKU, p.region, p.gpuSKUs)
 	}
 
+	if profile, err := p.getRuntimeClassProfile(pod); err == nil && profile != nil && profile.GPUSKU != "" {
+		for _, supportedSKU := range p.gpuSKUs {
+			if strings.EqualFold(profile.GPUSKU, string(supportedSKU)) {
+				return supportedSKU, nil
+			}
+		}
+
+		return "", fmt.Errorf("The runtime class %s requires GPU SKU %s, but ACI only supports SKUs %v in region %s", *pod.Spec.RuntimeClassName, profile.GPUSKU, p.gpuSKUs, p.region)
+	}
+
 	return p.gpuSKUs[0], nil
 }
 
@@ -1611,18 +2418,19 @@ func getProbe(probe *v1.Probe, ports []v1.ContainerPort) (*aci.ContainerProbe, e
 	}, nil
 }
 
-func (p *ACIProvider) getVolumes(pod *v1.Pod) ([]aci.Volume, error) {
+func (p *ACIProvider) getVolumes(ctx context.Context, pod *v1.Pod) ([]aci.Volume, []aci.InitContainerDefinition, error) {
 	volumes := make([]aci.Volume, 0, len(pod.Spec.Volumes))
+	var initContainers []aci.InitContainerDefinition
 	for _, v := range pod.Spec.Volumes {
 		// Handle the case for the AzureFile volume.
 		if v.AzureFile != nil {
 			secret, err := p.resourceManager.GetSecret(v.AzureFile.SecretName, pod.Namespace)
 			if err != nil {
-				return volumes, err
+				return nil, nil, err
 			}
 
 			if secret == nil {
-				return nil, fmt.Errorf("Getting secret for AzureFile volume returned an empty secret")
+				return nil, nil, fmt.Errorf("Getting secret for AzureFile volume returned an empty secret")
 			}
 
 			volumes = append(volumes, aci.Volume{
@@ -1639,15 +2447,21 @@ func (p *ACIProvider) getVolumes(pod *v1.Pod) ([]aci.Volume, error) {
 
 		// Handle the case for the EmptyDir.
 		if v.EmptyDir != nil {
-			volumes = append(volumes, aci.Volume{
-				Name:     v.Name,
-				EmptyDir: map[string]interface{}{},
-			})
+			volume, err := p.getEmptyDirVolume(pod, v)
+			if err != nil {
+				return nil, nil, err
+			}
+			volumes = append(volumes, volume)
 			continue
 		}
 
 		// Handle the case for GitRepo volume.
 		if v.GitRepo != nil {
+			if p.gitRepoImage != "" {
+				volumes = append(volumes, aci.Volume{Name: v.Name, EmptyDir: map[string]interface{}{}})
+				initContainers = append(initContainers, p.gitRepoInitContainer(v.Name, v.GitRepo))
+				continue
+			}
 			volumes = append(volumes, aci.Volume{
 				Name: v.Name,
 				GitRepo: &aci.GitRepoVolume{
@@ -1661,60 +2475,97 @@ func (p *ACIProvider) getVolumes(pod *v1.Pod) ([]aci.Volume, error) {
 
 		// Handle the case for Secret volume.
 		if v.Secret != nil {
-			paths := make(map[string]string)
+			optional := v.Secret.Optional != nil && *v.Secret.Optional
 			secret, err := p.resourceManager.GetSecret(v.Secret.SecretName, pod.Namespace)
-			if v.Secret.Optional != nil && !*v.Secret.Optional && k8serr.IsNotFound(err) {
-				return nil, fmt.Errorf("Secret %s is required by Pod %s and does not exist", v.Secret.SecretName, pod.Name)
+			if k8serr.IsNotFound(err) && !optional {
+				return nil, nil, fmt.Errorf("Secret %s is required by Pod %s and does not exist", v.Secret.SecretName, pod.Name)
 			}
-			if secret == nil {
-				continue
+			if err != nil && !k8serr.IsNotFound(err) {
+				return nil, nil, err
 			}
 
-			for k, v := range secret.Data {
-				paths[k] = base64.StdEncoding.EncodeToString(v)
+			var data map[string][]byte
+			if secret != nil {
+				data = secret.Data
 			}
-
-			if len(paths) != 0 {
-				volumes = append(volumes, aci.Volume{
-					Name:   v.Name,
-					Secret: paths,
-				})
+			projected, initContainer, err := p.getProjectedVolumes(pod, v.Name, "Secret "+v.Secret.SecretName, data, v.Secret.Items, v.Secret.DefaultMode, optional)
+			if err != nil {
+				return nil, nil, err
+			}
+			volumes = append(volumes, projected...)
+			if initContainer != nil {
+				initContainers = append(initContainers, *initContainer)
 			}
 			continue
 		}
 
 		// Handle the case for ConfigMap volume.
 		if v.ConfigMap != nil {
-			paths := make(map[string]string)
+			optional := v.ConfigMap.Optional != nil && *v.ConfigMap.Optional
 			configMap, err := p.resourceManager.GetConfigMap(v.ConfigMap.Name, pod.Namespace)
-			if v.ConfigMap.Optional != nil && !*v.ConfigMap.Optional && k8serr.IsNotFound(err) {
-				return nil, fmt.Errorf("ConfigMap %s is required by Pod %s and does not exist", v.ConfigMap.Name, pod.Name)
+			if k8serr.IsNotFound(err) && !optional {
+				return nil, nil, fmt.Errorf("ConfigMap %s is required by Pod %s and does not exist", v.ConfigMap.Name, pod.Name)
 			}
-			if configMap == nil {
-				continue
+			if err != nil && !k8serr.IsNotFound(err) {
+				return nil, nil, err
 			}
 
-			for k, v := range configMap.Data {
-				paths[k] = base64.StdEncoding.EncodeToString([]byte(v))
+			data := make(map[string][]byte)
+			if configMap != nil {
+				for k, v := range configMap.Data {
+					data[k] = []byte(v)
+				}
+				for k, v := range configMap.BinaryData {
+					data[k] = v
+				}
+			}
+			projected, initContainer, err := p.getProjectedVolumes(pod, v.Name, "ConfigMap "+v.ConfigMap.Name, data, v.ConfigMap.Items, v.ConfigMap.DefaultMode, optional)
+			if err != nil {
+				return nil, nil, err
+			}
+			volumes = append(volumes, projected...)
+			if initContainer != nil {
+				initContainers = append(initContainers, *initContainer)
 			}
-			for k, v := range configMap.BinaryData {
-				paths[k] = base64.StdEncoding.EncodeToString(v)
+			continue
+		}
+
+		// Handle the case for the claims of Azure Files persistent volumes.
+		if v.PersistentVolumeClaim != nil {
+			volume, err := p.getPVCVolume(ctx, pod, v)
+			if err != nil {
+				return nil, nil, err
 			}
+			volumes = append(volumes, volume)
+			continue
+		}
 
-			if len(paths) != 0 {
-				volumes = append(volumes, aci.Volume{
-					Name:   v.Name,
-					Secret: paths,
-				})
+		// Handle the case for CSI inline volumes of the drivers resolved by the provider.
+		if v.CSI != nil {
+			driver, ok := p.csiDrivers[v.CSI.Driver]
+			if !ok {
+				return nil, nil, fmt.Errorf("Pod %s requires CSI volume %s of driver %s, which is not supported", pod.Name, v.Name, v.CSI.Driver)
+			}
+			data, err := driver.volumeFiles(ctx, pod, v.CSI)
+			if err != nil {
+				return nil, nil, err
+			}
+			projected, initContainer, err := p.getProjectedVolumes(pod, v.Name, "CSI volume "+v.Name, data, nil, nil, false)
+			if err != nil {
+				return nil, nil, err
+			}
+			volumes = append(volumes, projected...)
+			if initContainer != nil {
+				initContainers = append(initContainers, *initContainer)
 			}
 			continue
 		}
 
 		// If we've made it this far we have found a volume type that isn't supported
-		return nil, fmt.Errorf("Pod %s requires volume %s which is of an unsupported type", pod.Name, v.Name)
+		return nil, nil, fmt.Errorf("Pod %s requires volume %s which is of an unsupported type", pod.Name, v.Name)
 	}
 
-	return volumes, nil
+	return volumes, initContainers, nil
 }
 
 func getProtocol(pro v1.Protocol) aci.ContainerNetworkProtocol {
@@ -1754,7 +2605,7 @@ func containerGroupToPod(cg *aci.ContainerGroup) (*v1.Pod, error) {
 			}
 
 			if c.Resources.Limits.GPU != nil {
-				container.Resources.Limits[gpuResourceName] = resource.MustParse(fmt.Sprintf("%d", c.Resources.Requests.GPU.Count))
+				container.Resources.Limits[gpuResourceName] = resource.MustParse(fmt.Sprintf("%d", c.Resources.Limits.GPU.Count))
 			}
 		}
 
@@ -1810,6 +2661,7 @@ func podStatusFromContainerGroup(cg *aci.ContainerGroup) *v1.PodStatus {
 	firstContainerStartTime := metav1.NewTime(time.Time(cg.Containers[0].ContainerProperties.InstanceView.CurrentState.StartTime))
 	lastUpdateTime := firstContainerStartTime
 	allReady := true
+	restarts := parseRestartBaseline(cg.Tags[restartCountsTag])
 	for _, c := range cg.Containers {
 		containerStartTime := metav1.NewTime(time.Time(c.ContainerProperties.InstanceView.CurrentState.StartTime))
 		containerStatus := v1.ContainerStatus{
@@ -1817,16 +2669,35 @@ func podStatusFromContainerGroup(cg *aci.ContainerGroup) *v1.PodStatus {
 			State:                aciContainerStateToContainerState(c.InstanceView.CurrentState),
 			LastTerminationState: aciContainerStateToContainerState(c.InstanceView.PreviousState),
 			Ready:                aciStateToPodPhase(c.InstanceView.CurrentState.State) == v1.PodRunning,
-			RestartCount:         c.InstanceView.RestartCount,
+			RestartCount:         restarts.restartCount(c),
 			Image:                c.Image,
-			ImageID:              "",
+			ImageID:              getImageID(c),
 			ContainerID:          getContainerID(cg.ID, c.Name),
 		}
 
+		// A container waiting on its image reports the pull progress or failure instead of the plain ACI state.
+		if containerStatus.State.Waiting != nil {
+			if waiting := imagePullWaitingState(c.InstanceView.Events); waiting != nil {
+				containerStatus.State.Waiting = waiting
+			}
+		}
+		// A container ACI keeps re-launching after it fails waits in a crash loop back-off.
+		if waiting := crashLoopWaiting(c); waiting != nil {
+			containerStatus.State = v1.ContainerState{Waiting: waiting}
+		}
+
 		if aciStateToPodPhase(c.InstanceView.CurrentState.State) != v1.PodRunning &&
 			aciStateToPodPhase(c.InstanceView.CurrentState.State) != v1.PodSucceeded {
 			allReady = false
 		}
+		// A container failing its liveness probe is being restarted by ACI.
+		if failedAt, ok := livenessFailure(c); ok {
+			containerStatus.Ready = false
+			allReady = false
+			if failedAt.After(lastUpdateTime.Time) {
+				lastUpdateTime = metav1.NewTime(failedAt)
+			}
+		}
 		if containerStartTime.Time.After(lastUpdateTime.Time) {
 			lastUpdateTime = containerStartTime
 		}
@@ -1840,11 +2711,30 @@ func podStatusFromContainerGroup(cg *aci.ContainerGroup) *v1.PodStatus {
 		ip = cg.IPAddress.IP
 	}
 
+	reason := ""
+	if aciState == aciStateStopped {
+		reason = podStatusReasonSuspended
+	}
+	if cg.ContainerGroupProperties.ProvisioningState == "Failed" {
+		reason = podStatusReasonProvisioningFailed
+	}
+
+	conditions := aciStateToPodConditions(aciState, creationTime, lastUpdateTime, allReady)
+	if condition, ok := routableCondition(aciState, ip, creationTime, lastUpdateTime, allReady); ok {
+		conditions = append(conditions, condition)
+	}
+	if condition, ok := unsupportedFieldsCondition(cg.Tags, creationTime); ok {
+		conditions = append(conditions, condition)
+	}
+	if condition, ok := disruptionCondition(cg); ok {
+		conditions = append(conditions, condition)
+	}
+
 	return &v1.PodStatus{
 		Phase:             aciStateToPodPhase(aciState),
-		Conditions:        aciStateToPodConditions(aciState, creationTime, lastUpdateTime, allReady),
+		Conditions:        conditions,
 		Message:           "",
-		Reason:            "",
+		Reason:            reason,
 		HostIP:            "",
 		PodIP:             ip,
 		StartTime:         &firstContainerStartTime,
@@ -1885,6 +2775,8 @@ func aciStateToPodPhase(state string) v1.PodPhase {
 		return v1.PodPending
 	case "Accepted":
 		return v1.PodPending
+	case aciStateStopped:
+		return v1.PodPending
 	}
 
 	return v1.PodUnknown
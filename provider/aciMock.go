@@ -17,12 +17,22 @@ type ACIMock struct {
 	OnGetContainerGroups func(string, string) (int, interface{})
 	OnGetContainerGroup  func(string, string, string) (int, interface{})
 	OnGetRPManifest      func() (int, interface{})
+	OnAction             func(string, string, string, string) (int, interface{})
+	OnListResources      func(string, string, string) (int, interface{})
+	OnUpdateTags         func(string, string, string, map[string]string) (int, interface{})
+	OnDelete             func(string, string, string) (int, interface{})
+	OnGetLogs            func(string, string, string, string) (int, interface{})
+	OnGetMetrics         func(string, string, string, string) (int, interface{})
 }
 
 const (
-	containerGroupsRoute  = "/subscriptions/{subscriptionId}/resourceGroups/{resourceGroup}/providers/Microsoft.ContainerInstance/containerGroups"
-	containerGroupRoute   = containerGroupsRoute + "/{containerGroup}"
-	resourceProviderRoute = "/providers/Microsoft.ContainerInstance"
+	containerGroupsRoute       = "/subscriptions/{subscriptionId}/resourceGroups/{resourceGroup}/providers/Microsoft.ContainerInstance/containerGroups"
+	containerGroupRoute        = containerGroupsRoute + "/{containerGroup}"
+	containerGroupActionRoute  = containerGroupRoute + "/{action}"
+	containerLogsRoute         = containerGroupRoute + "/containers/{containerName}/logs"
+	containerGroupMetricsRoute = containerGroupRoute + "/providers/microsoft.Insights/metrics"
+	resourceProviderRoute      = "/providers/Microsoft.ContainerInstance"
+	resourcesRoute             = "/subscriptions/{subscriptionId}/resourceGroups/{resourceGroup}/resources"
 )
 
 // NewACIMock creates a new Azure Container Instance mock server.
@@ -68,6 +78,36 @@ func (mock *ACIMock) start() {
 			w.WriteHeader(http.StatusNotImplemented)
 		}).Methods("PUT")
 
+	router.HandleFunc(
+		containerGroupRoute,
+		func(w http.ResponseWriter, r *http.Request) {
+			subscription := mux.Vars(r)["subscriptionId"]
+			resourceGroup := mux.Vars(r)["resourceGroup"]
+			containerGroup := mux.Vars(r)["containerGroup"]
+
+			var body struct {
+				Tags map[string]string `json:"tags"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				panic(err)
+			}
+
+			if mock.OnUpdateTags != nil {
+				statusCode, response := mock.OnUpdateTags(subscription, resourceGroup, containerGroup, body.Tags)
+				w.WriteHeader(statusCode)
+				b := new(bytes.Buffer)
+				if err := json.NewEncoder(b).Encode(response); err != nil {
+					panic(err)
+				}
+				if _, err := w.Write(b.Bytes()); err != nil {
+					panic(err)
+				}
+				return
+			}
+
+			w.WriteHeader(http.StatusNotImplemented)
+		}).Methods("PATCH")
+
 	router.HandleFunc(
 		containerGroupRoute,
 		func(w http.ResponseWriter, r *http.Request) {
@@ -92,6 +132,106 @@ func (mock *ACIMock) start() {
 			w.WriteHeader(http.StatusNotImplemented)
 		}).Methods("GET")
 
+	router.HandleFunc(
+		containerGroupRoute,
+		func(w http.ResponseWriter, r *http.Request) {
+			subscription := mux.Vars(r)["subscriptionId"]
+			resourceGroup := mux.Vars(r)["resourceGroup"]
+			containerGroup := mux.Vars(r)["containerGroup"]
+
+			if mock.OnDelete != nil {
+				statusCode, response := mock.OnDelete(subscription, resourceGroup, containerGroup)
+				w.WriteHeader(statusCode)
+				if response == nil {
+					return
+				}
+				b := new(bytes.Buffer)
+				if err := json.NewEncoder(b).Encode(response); err != nil {
+					panic(err)
+				}
+				if _, err := w.Write(b.Bytes()); err != nil {
+					panic(err)
+				}
+				return
+			}
+
+			w.WriteHeader(http.StatusNotImplemented)
+		}).Methods("DELETE")
+
+	router.HandleFunc(
+		containerLogsRoute,
+		func(w http.ResponseWriter, r *http.Request) {
+			subscription := mux.Vars(r)["subscriptionId"]
+			resourceGroup := mux.Vars(r)["resourceGroup"]
+			containerGroup := mux.Vars(r)["containerGroup"]
+			containerName := mux.Vars(r)["containerName"]
+
+			if mock.OnGetLogs != nil {
+				statusCode, response := mock.OnGetLogs(subscription, resourceGroup, containerGroup, containerName)
+				w.WriteHeader(statusCode)
+				b := new(bytes.Buffer)
+				if err := json.NewEncoder(b).Encode(response); err != nil {
+					panic(err)
+				}
+				if _, err := w.Write(b.Bytes()); err != nil {
+					panic(err)
+				}
+				return
+			}
+
+			w.WriteHeader(http.StatusNotImplemented)
+		}).Methods("GET")
+
+	router.HandleFunc(
+		containerGroupMetricsRoute,
+		func(w http.ResponseWriter, r *http.Request) {
+			subscription := mux.Vars(r)["subscriptionId"]
+			resourceGroup := mux.Vars(r)["resourceGroup"]
+			containerGroup := mux.Vars(r)["containerGroup"]
+
+			if mock.OnGetMetrics != nil {
+				statusCode, response := mock.OnGetMetrics(subscription, resourceGroup, containerGroup, r.URL.Query().Get("metricnames"))
+				w.WriteHeader(statusCode)
+				b := new(bytes.Buffer)
+				if err := json.NewEncoder(b).Encode(response); err != nil {
+					panic(err)
+				}
+				if _, err := w.Write(b.Bytes()); err != nil {
+					panic(err)
+				}
+				return
+			}
+
+			w.WriteHeader(http.StatusNotImplemented)
+		}).Methods("GET")
+
+	router.HandleFunc(
+		containerGroupActionRoute,
+		func(w http.ResponseWriter, r *http.Request) {
+			subscription := mux.Vars(r)["subscriptionId"]
+			resourceGroup := mux.Vars(r)["resourceGroup"]
+			containerGroup := mux.Vars(r)["containerGroup"]
+			action := mux.Vars(r)["action"]
+
+			if mock.OnAction != nil {
+				statusCode, response := mock.OnAction(subscription, resourceGroup, containerGroup, action)
+				w.WriteHeader(statusCode)
+				if response == nil {
+					return
+				}
+				b := new(bytes.Buffer)
+				if err := json.NewEncoder(b).Encode(response); err != nil {
+					panic(err)
+				}
+				if _, err := w.Write(b.Bytes()); err != nil {
+					panic(err)
+				}
+				return
+			}
+
+			w.WriteHeader(http.StatusNotImplemented)
+		}).Methods("POST")
+
 	router.HandleFunc(
 		containerGroupsRoute,
 		func(w http.ResponseWriter, r *http.Request) {
@@ -115,6 +255,29 @@ func (mock *ACIMock) start() {
 			w.WriteHeader(http.StatusNotImplemented)
 		}).Methods("GET")
 
+	router.HandleFunc(
+		resourcesRoute,
+		func(w http.ResponseWriter, r *http.Request) {
+			subscription := mux.Vars(r)["subscriptionId"]
+			resourceGroup := mux.Vars(r)["resourceGroup"]
+
+			if mock.OnListResources != nil {
+				statusCode, response := mock.OnListResources(subscription, resourceGroup, r.URL.Query().Get("$filter"))
+				w.WriteHeader(statusCode)
+				b := new(bytes.Buffer)
+				if err := json.NewEncoder(b).Encode(response); err != nil {
+					panic(err)
+				}
+				if _, err := w.Write(b.Bytes()); err != nil {
+					panic(err)
+				}
+
+				return
+			}
+
+			w.WriteHeader(http.StatusNotImplemented)
+		}).Methods("GET")
+
 	router.HandleFunc(
 		resourceProviderRoute,
 		func(w http.ResponseWriter, r *http.Request) {
@@ -418,6 +418,54 @@ func TestCreatePodWithResourceRequestAndLimit(t *testing.T) {
 	}
 }
 
+// Tests create pod with resource limit only, the request is expected to default to the limit.
+func TestCreatePodWithResourceLimitOnly(t *testing.T) {
+	_, aciServerMocker, provider, err := prepareMocks()
+
+	if err != nil {
+		t.Fatal("Unable to prepare the mocks", err)
+	}
+
+	podName := "pod-" + uuid.New().String()
+	podNamespace := "ns-" + uuid.New().String()
+
+	aciServerMocker.OnCreate = func(subscription, resourceGroup, containerGroup string, cg *aci.ContainerGroup) (int, interface{}) {
+		assert.Check(t, is.Equal(1, len(cg.ContainerGroupProperties.Containers)), "1 Container is expected")
+		assert.Check(t, cg.ContainerGroupProperties.Containers[0].Resources.Requests != nil, "Container resource requests should not be nil")
+		assert.Check(t, is.Equal(0.5, cg.ContainerGroupProperties.Containers[0].Resources.Requests.CPU), "Request CPU is not expected")
+		assert.Check(t, is.Equal(0.8, cg.ContainerGroupProperties.Containers[0].Resources.Requests.MemoryInGB), "Request Memory is not expected")
+		assert.Check(t, cg.ContainerGroupProperties.Containers[0].Resources.Limits != nil, "Container resource limits should not be nil")
+		assert.Check(t, is.Equal(0.5, cg.ContainerGroupProperties.Containers[0].Resources.Limits.CPU), "Limit CPU is not expected")
+		assert.Check(t, is.Equal(0.8, cg.ContainerGroupProperties.Containers[0].Resources.Limits.MemoryInGB), "Limit Memory is not expected")
+
+		return http.StatusOK, cg
+	}
+
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      podName,
+			Namespace: podNamespace,
+		},
+		Spec: v1.PodSpec{
+			Containers: []v1.Container{
+				v1.Container{
+					Name: "nginx",
+					Resources: v1.ResourceRequirements{
+						Limits: v1.ResourceList{
+							"cpu":    resource.MustParse("500m"),
+							"memory": resource.MustParse("800M"),
+						},
+					},
+				},
+			},
+		},
+	}
+
+	if err := provider.CreatePod(context.Background(), pod); err != nil {
+		t.Fatal("Failed to create pod", err)
+	}
+}
+
 // Tests get pods with empty list.
 func TestGetPodsWithEmptyList(t *testing.T) {
 	_, aciServerMocker, provider, err := prepareMocks()
@@ -879,6 +927,8 @@ func TestConfigureNode(t *testing.T) {
 	assert.Equal(t, "true", node.ObjectMeta.Labels["alpha.service-controller.kubernetes.io/exclude-balancer"], "exclude-balancer label doesn't match")
 	assert.Equal(t, "true", node.ObjectMeta.Labels["node.kubernetes.io/exclude-from-external-load-balancers"], "exclude-from-external-load-balancers label doesn't match")
 	assert.Equal(t, "false", node.ObjectMeta.Labels["kubernetes.azure.com/managed"], "kubernetes.azure.com/managed label doesn't match")
+	assert.Equal(t, "amd64", node.ObjectMeta.Labels["kubernetes.io/arch"], "kubernetes.io/arch label doesn't match")
+	assert.Equal(t, "amd64", node.Status.NodeInfo.Architecture, "architecture doesn't match")
 }
 
 func TestCreatePodWithNamedLivenessProbe(t *testing.T) {
@@ -1062,3 +1112,195 @@ func TestCreatePodWithReadinessProbe(t *testing.T) {
 		t.Fatal("Failed to create pod", err)
 	}
 }
+
+func TestContainerResourcesChanged(t *testing.T) {
+	container := func(name, image string, cpu float64) aci.Container {
+		return aci.Container{
+			Name: name,
+			ContainerProperties: aci.ContainerProperties{
+				Image: image,
+				Resources: aci.ResourceRequirements{
+					Requests: &aci.ComputeResources{
+						CPU:        cpu,
+						MemoryInGB: 1.5,
+					},
+				},
+			},
+		}
+	}
+
+	tt := []struct {
+		name     string
+		current  []aci.Container
+		desired  []aci.Container
+		expected bool
+	}{
+		{"Unchanged", []aci.Container{container("c1", "nginx", 1)}, []aci.Container{container("c1", "nginx", 1)}, false},
+		{"CPU changed", []aci.Container{container("c1", "nginx", 1)}, []aci.Container{container("c1", "nginx", 2)}, true},
+		{"Image changed", []aci.Container{container("c1", "nginx", 1)}, []aci.Container{container("c1", "busybox", 2)}, false},
+		{"Container added", []aci.Container{container("c1", "nginx", 1)}, []aci.Container{container("c1", "nginx", 2), container("c2", "nginx", 1)}, false},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Check(t, is.Equal(tc.expected, containerResourcesChanged(tc.current, tc.desired)))
+		})
+	}
+}
+
+func TestUpdatePodResize(t *testing.T) {
+	_, aciServerMocker, provider, err := prepareMocks()
+	if err != nil {
+		t.Fatal("Unable to prepare the mocks", err)
+	}
+
+	podName := "pod-" + uuid.New().String()
+	podNamespace := "ns-" + uuid.New().String()
+
+	aciServerMocker.OnGetContainerGroup = func(subscription, resourceGroup, containerGroup string) (int, interface{}) {
+		return http.StatusOK, aci.ContainerGroup{
+			Name: containerGroup,
+			Tags: map[string]string{
+				"NodeName": fakeNodeName,
+			},
+			ContainerGroupProperties: aci.ContainerGroupProperties{
+				ProvisioningState: "Succeeded",
+				InstanceView: aci.ContainerGroupPropertiesInstanceView{
+					State: "Running",
+				},
+				Containers: []aci.Container{
+					aci.Container{
+						Name: "nginx",
+						ContainerProperties: aci.ContainerProperties{
+							Image: "nginx",
+							Resources: aci.ResourceRequirements{
+								Requests: &aci.ComputeResources{
+									CPU:        1,
+									MemoryInGB: 1.5,
+								},
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	var updateErr map[string]interface{}
+	puts := 0
+	aciServerMocker.OnCreate = func(subscription, resourceGroup, containerGroup string, cg *aci.ContainerGroup) (int, interface{}) {
+		puts++
+		if puts == 1 && updateErr != nil {
+			return http.StatusBadRequest, updateErr
+		}
+		return http.StatusOK, cg
+	}
+	deletes := 0
+	aciServerMocker.OnDelete = func(subscription, resourceGroup, containerGroup string) (int, interface{}) {
+		deletes++
+		return http.StatusOK, nil
+	}
+
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      podName,
+			Namespace: podNamespace,
+		},
+		Spec: v1.PodSpec{
+			Containers: []v1.Container{
+				v1.Container{
+					Name:  "nginx",
+					Image: "nginx",
+					Resources: v1.ResourceRequirements{
+						Requests: v1.ResourceList{
+							"cpu":    resource.MustParse("2"),
+							"memory": resource.MustParse("1.5G"),
+						},
+					},
+				},
+			},
+		},
+	}
+
+	// A validation error of the update is returned, the container group is kept.
+	updateErr = map[string]interface{}{"error": map[string]string{"code": "InvalidResourceRequests", "message": "The CPU request is invalid."}}
+	assert.Check(t, provider.UpdatePod(context.Background(), pod) != nil, "The validation error should be returned")
+	assert.Check(t, is.Equal(1, puts))
+	assert.Check(t, is.Equal(0, deletes), "The container group should not be deleted")
+
+	// An update ACI can't apply in place recreates the container group.
+	puts = 0
+	updateErr = map[string]interface{}{"error": map[string]string{"code": "InvalidContainerGroupUpdate", "message": "The updates on container group are invalid, you must delete it first."}}
+	assert.NilError(t, provider.UpdatePod(context.Background(), pod))
+	assert.Check(t, is.Equal(2, puts), "The container group should be created again")
+	assert.Check(t, is.Equal(1, deletes))
+
+	// A resize applied in place.
+	puts, deletes = 0, 0
+	updateErr = nil
+	assert.NilError(t, provider.UpdatePod(context.Background(), pod))
+	assert.Check(t, is.Equal(1, puts))
+	assert.Check(t, is.Equal(0, deletes))
+}
+
+// Tests listing the pods of the node by the NodeName tag of their container groups.
+func TestListActivePodsByNodeTag(t *testing.T) {
+	_, aciServerMocker, provider, err := prepareMocks()
+	if err != nil {
+		t.Fatal("Unable to prepare the mocks", err)
+	}
+	provider.listByNodeTag = true
+
+	aciServerMocker.OnGetContainerGroups = func(subscription, resourceGroup string) (int, interface{}) {
+		t.Error("Container groups of the resource group should not be listed")
+		return http.StatusInternalServerError, nil
+	}
+	aciServerMocker.OnListResources = func(subscription, resourceGroup, filter string) (int, interface{}) {
+		assert.Check(t, is.Equal(fakeResourceGroup, resourceGroup), "Resource group doesn't match")
+		assert.Check(t, is.Equal("tagName eq 'NodeName' and tagValue eq '"+fakeNodeName+"'", filter), "Filter doesn't match")
+
+		tags := func(name string) map[string]string {
+			return map[string]string{"NodeName": fakeNodeName, "Namespace": "default", "PodName": name}
+		}
+		return http.StatusOK, map[string]interface{}{
+			"value": []map[string]interface{}{
+				{"name": "default-nginx", "type": "Microsoft.ContainerInstance/containerGroups", "tags": tags("nginx")},
+				{"name": "default-disk", "type": "Microsoft.Compute/disks", "tags": tags("disk")},
+				{"name": "default-deleted", "type": "Microsoft.ContainerInstance/containerGroups", "tags": tags("deleted")},
+			},
+		}
+	}
+	gets := 0
+	aciServerMocker.OnGetContainerGroup = func(subscription, resourceGroup, containerGroup string) (int, interface{}) {
+		gets++
+		assert.Check(t, containerGroup != "default-disk", "Other resources should not be fetched")
+		if containerGroup == "default-deleted" {
+			return http.StatusNotFound, nil
+		}
+
+		return http.StatusOK, aci.ContainerGroup{
+			Name: containerGroup,
+			Tags: map[string]string{
+				"NodeName":  fakeNodeName,
+				"Namespace": "default",
+				"PodName":   "nginx",
+			},
+			ContainerGroupProperties: aci.ContainerGroupProperties{
+				Containers: []aci.Container{{Name: "nginx"}},
+			},
+		}
+	}
+
+	// The tags of the list are enough to track the pods.
+	ids, err := provider.ListActivePods(context.Background())
+	assert.NilError(t, err)
+	assert.Check(t, is.Len(ids, 2), "Expected the pods of the container groups listed")
+	assert.Check(t, ids[0] == PodIdentifier{namespace: "default", name: "nginx"}, "Pod identifier doesn't match")
+	assert.Check(t, is.Equal(0, gets), "The container groups should not be fetched for their tags")
+
+	// The pods need the properties of their container groups.
+	pods, err := provider.GetPods(context.Background())
+	assert.NilError(t, err)
+	assert.Check(t, is.Len(pods, 1), "Expected only the pod of the existing container group")
+	assert.Check(t, is.Equal(2, gets), "Each container group should be fetched for its properties")
+}
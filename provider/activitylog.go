@@ -0,0 +1,137 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	client "github.com/virtual-kubelet/azure-aci/client"
+	"github.com/virtual-kubelet/azure-aci/client/activitylog"
+	"github.com/virtual-kubelet/virtual-kubelet/log"
+	v1 "k8s.io/api/core/v1"
+)
+
+// activityLogLookback is how far back the Activity Log is queried for the failure of a container group whose
+// creation was not tracked, like one created before the virtual kubelet restarted.
+const activityLogLookback = time.Hour
+
+// activityLogSource reports the failed operations on the container groups.
+type activityLogSource interface {
+	ListContainerGroupFailures(ctx context.Context, resourceGroup, containerGroupName, correlationID string, since time.Time) ([]activitylog.Event, error)
+}
+
+// createFailures keeps the correlation IDs of the creations of the container groups of the pods, and whether the
+// failure of their provisioning was already reported.
+type createFailures struct {
+	mu           sync.Mutex
+	correlations map[string]string
+	reported     map[string]bool
+}
+
+// created tracks the creation of the container group of a pod with the correlation ID of its requests to ARM.
+func (cf *createFailures) created(namespace, name, correlationID string) {
+	cf.mu.Lock()
+	defer cf.mu.Unlock()
+
+	if cf.correlations == nil {
+		cf.correlations = make(map[string]string)
+		cf.reported = make(map[string]bool)
+	}
+	key := provisioningKey(namespace, name)
+	cf.correlations[key] = correlationID
+	delete(cf.reported, key)
+}
+
+// report returns the correlation ID of the creation of the container group of the pod, if tracked, and whether its
+// failure was not reported yet, and marks it reported.
+func (cf *createFailures) report(namespace, name string) (string, bool) {
+	cf.mu.Lock()
+	defer cf.mu.Unlock()
+
+	if cf.reported == nil {
+		cf.reported = make(map[string]bool)
+	}
+	key := provisioningKey(namespace, name)
+	if cf.reported[key] {
+		return "", false
+	}
+	cf.reported[key] = true
+	return cf.correlations[key], true
+}
+
+// remove stops tracking the pod, once its container group is deleted.
+func (cf *createFailures) remove(namespace, name string) {
+	cf.mu.Lock()
+	defer cf.mu.Unlock()
+
+	key := provisioningKey(namespace, name)
+	delete(cf.correlations, key)
+	delete(cf.reported, key)
+}
+
+// setupActivityLog enables looking up the failures of the container groups in the Activity Log, unless disabled
+// by ActivityLog in the config file or the ACI_ACTIVITY_LOG environment variable.
+func (p *ACIProvider) setupActivityLog(azAuth *client.Authentication) error {
+	enabled := p.activityLogConfig == nil || *p.activityLogConfig
+	if v := os.Getenv("ACI_ACTIVITY_LOG"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid ACI_ACTIVITY_LOG %q: %v", v, err)
+		}
+		enabled = b
+	}
+	if !enabled {
+		return nil
+	}
+
+	var err error
+	p.activityLog, err = activitylog.NewClient(azAuth, p.extraUserAgent)
+	if err != nil {
+		return fmt.Errorf("error setting up the activity log: %v", err)
+	}
+	return nil
+}
+
+// recordProvisioningFailure records an event on a pod whose container group failed to provision, with the cause
+// of the failure from the Activity Log. ARM accepts the creation of a container group before it provisions, so
+// the cause of a policy denial, an exceeded quota or an error of the resource provider is not in the response to
+// the creation. The failure is only looked up once per creation.
+func (p *ACIProvider) recordProvisioningFailure(ctx context.Context, pod *v1.Pod) {
+	if p.activityLog == nil {
+		return
+	}
+	correlationID, ok := p.createFailures.report(pod.Namespace, pod.Name)
+	if !ok {
+		return
+	}
+
+	cgName := containerGroupName(pod.Namespace, pod.Name)
+	// Leave some slack for the clock skew with the Activity Log.
+	since := p.provisioning.createdAt(pod.Namespace, pod.Name, time.Now().Add(-activityLogLookback)).Add(-time.Minute)
+	failures, err := p.activityLog.ListContainerGroupFailures(ctx, p.resourceGroup, cgName, correlationID, since)
+	if err != nil {
+		log.G(ctx).WithError(err).Warnf("failed to look up the failure of container group %v in the activity log", cgName)
+		return
+	}
+
+	var latest *activitylog.Event
+	for i := range failures {
+		if failures[i].FailureDetail() == "" {
+			continue
+		}
+		if latest == nil || failures[i].EventTimestamp.After(latest.EventTimestamp) {
+			latest = &failures[i]
+		}
+	}
+	if latest == nil {
+		log.G(ctx).Debugf("no failure of container group %v in the activity log", cgName)
+		return
+	}
+
+	detail := latest.FailureDetail()
+	log.G(ctx).WithField("azure.correlationID", latest.CorrelationID).Warnf("provisioning of container group %v failed: %s", cgName, detail)
+	p.recordEvent(pod, v1.EventTypeWarning, podStatusReasonProvisioningFailed, "Provisioning of container group %s failed: %s", cgName, detail)
+}
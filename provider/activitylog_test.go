@@ -0,0 +1,51 @@
+package provider
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/virtual-kubelet/azure-aci/client/activitylog"
+	"gotest.tools/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+type fakeActivityLog struct {
+	correlationID string
+	queries       int
+}
+
+func (f *fakeActivityLog) ListContainerGroupFailures(ctx context.Context, resourceGroup, containerGroupName, correlationID string, since time.Time) ([]activitylog.Event, error) {
+	f.queries++
+	f.correlationID = correlationID
+	now := time.Now()
+	return []activitylog.Event{
+		{EventTimestamp: now.Add(-time.Minute), Status: activitylog.LocalizableString{Value: "Failed"}, Properties: map[string]string{"statusCode": "Conflict"}},
+		{EventTimestamp: now, Status: activitylog.LocalizableString{Value: "Failed"}, Properties: map[string]string{
+			"statusMessage": `{"error":{"code":"RequestDisallowedByPolicy","message":"Resource 'ns-web' was disallowed by policy."}}`,
+		}},
+	}, nil
+}
+
+func TestRecordProvisioningFailure(t *testing.T) {
+	activityLog := &fakeActivityLog{}
+	recorder := record.NewFakeRecorder(2)
+	p := ACIProvider{activityLog: activityLog, eventRecorder: recorder}
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "ns"}}
+	ctx := context.Background()
+
+	p.createFailures.created("ns", "web", "correlation")
+	p.recordProvisioningFailure(ctx, pod)
+	assert.Equal(t, activityLog.correlationID, "correlation", "The operation of the creation should be looked up")
+	assert.Equal(t, <-recorder.Events, "Warning ProvisioningFailed Provisioning of container group ns-web failed: RequestDisallowedByPolicy: Resource 'ns-web' was disallowed by policy.")
+
+	p.recordProvisioningFailure(ctx, pod)
+	assert.Equal(t, activityLog.queries, 1, "A failure should only be looked up once per creation")
+
+	p.createFailures.created("ns", "web", "")
+	p.recordProvisioningFailure(ctx, pod)
+	assert.Equal(t, activityLog.queries, 2, "The failure of a re-created container group should be looked up")
+	<-recorder.Events
+}
@@ -0,0 +1,60 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/virtual-kubelet/azure-aci/client/aci"
+	"github.com/virtual-kubelet/virtual-kubelet/log"
+)
+
+// setupAPIVersions overrides the api versions of the operations of the ACI client, from the config file
+// or the ACI_API_VERSIONS environment variable, a list of operation=version pairs separated by commas,
+// and detects the api versions supported by the cloud.
+func (p *ACIProvider) setupAPIVersions(ctx context.Context) error {
+	if env := os.Getenv("ACI_API_VERSIONS"); env != "" {
+		p.apiVersions = make(map[string]string)
+		for _, pair := range strings.Split(env, ",") {
+			kv := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+			if len(kv) != 2 {
+				return fmt.Errorf("invalid ACI_API_VERSIONS %q, expected operation=version pairs separated by commas", env)
+			}
+			p.apiVersions[kv[0]] = kv[1]
+		}
+	}
+
+	versions, err := apiVersionOverrides(p.apiVersions)
+	if err != nil {
+		return err
+	}
+	p.aciClient.SetAPIVersions(versions)
+
+	if err := p.aciClient.DetectAPIVersions(ctx); err != nil {
+		log.G(ctx).WithError(err).Warn("failed to detect the api versions supported by ACI, assuming all of them are")
+	}
+	return nil
+}
+
+// apiVersionOverrides validates the api versions by operation.
+func apiVersionOverrides(overrides map[string]string) (map[aci.APIOperation]string, error) {
+	known := make(map[aci.APIOperation]bool, len(aci.APIOperations))
+	names := make([]string, 0, len(aci.APIOperations))
+	for _, op := range aci.APIOperations {
+		known[op] = true
+		names = append(names, string(op))
+	}
+
+	versions := make(map[aci.APIOperation]string, len(overrides))
+	for op, version := range overrides {
+		if !known[aci.APIOperation(op)] {
+			return nil, fmt.Errorf("invalid api version operation %q, expected one of %s", op, strings.Join(names, ", "))
+		}
+		if err := aci.ValidateAPIVersion(version); err != nil {
+			return nil, fmt.Errorf("invalid api version for %s: %v", op, err)
+		}
+		versions[aci.APIOperation(op)] = version
+	}
+	return versions, nil
+}
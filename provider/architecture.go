@@ -0,0 +1,51 @@
+package provider
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/virtual-kubelet/virtual-kubelet/errdefs"
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	archAMD64 = "amd64"
+	archARM64 = "arm64"
+
+	archLabel     = "kubernetes.io/arch"
+	betaArchLabel = "beta.kubernetes.io/arch"
+)
+
+// setupArchitecture validates the CPU architecture of the container groups created by this node,
+// read from ACI_ARCHITECTURE or the config file, and defaulting to amd64. ACI has no property to
+// request another architecture for a container group, so arm64 is rejected rather than advertised
+// on a node which would run its pods on amd64.
+func (p *ACIProvider) setupArchitecture() error {
+	if arch := os.Getenv("ACI_ARCHITECTURE"); arch != "" {
+		p.architecture = arch
+	}
+	if p.architecture == "" {
+		p.architecture = archAMD64
+	}
+
+	switch p.architecture {
+	case archAMD64:
+		return nil
+	case archARM64:
+		return fmt.Errorf("the %s architecture is not supported, ACI only runs %s container groups", archARM64, archAMD64)
+	}
+
+	return fmt.Errorf("%q is not a valid architecture, try the following instead: %s", p.architecture, archAMD64)
+}
+
+// checkPodArchitecture rejects pods selecting another architecture than the one of this node.
+// The scheduler already filters on the node label, this catches pods bound to the node directly.
+func (p *ACIProvider) checkPodArchitecture(pod *v1.Pod) error {
+	for _, label := range []string{archLabel, betaArchLabel} {
+		if arch, ok := pod.Spec.NodeSelector[label]; ok && arch != p.architecture {
+			return errdefs.InvalidInputf("pod %s requires the %s architecture, this node creates %s container groups", pod.Name, arch, p.architecture)
+		}
+	}
+
+	return nil
+}
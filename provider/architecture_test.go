@@ -0,0 +1,37 @@
+package provider
+
+import (
+	"os"
+	"testing"
+
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSetupArchitecture(t *testing.T) {
+	p := ACIProvider{}
+	assert.NilError(t, p.setupArchitecture())
+	assert.Check(t, is.Equal(archAMD64, p.architecture))
+
+	p = ACIProvider{architecture: archARM64}
+	assert.ErrorContains(t, p.setupArchitecture(), "not supported")
+
+	os.Setenv("ACI_ARCHITECTURE", "s390x")
+	defer os.Unsetenv("ACI_ARCHITECTURE")
+	p = ACIProvider{}
+	assert.ErrorContains(t, p.setupArchitecture(), "not a valid architecture")
+}
+
+func TestCheckPodArchitecture(t *testing.T) {
+	p := ACIProvider{architecture: archAMD64}
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod"}}
+	assert.NilError(t, p.checkPodArchitecture(pod))
+
+	pod.Spec.NodeSelector = map[string]string{archLabel: archAMD64}
+	assert.NilError(t, p.checkPodArchitecture(pod))
+
+	pod.Spec.NodeSelector = map[string]string{betaArchLabel: archARM64}
+	assert.ErrorContains(t, p.checkPodArchitecture(pod), "requires the arm64 architecture")
+}
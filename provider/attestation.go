@@ -0,0 +1,57 @@
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/virtual-kubelet/azure-aci/client/aci"
+	"github.com/virtual-kubelet/virtual-kubelet/log"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// ccePolicyHashAnnotation is the hex encoded SHA-256 of the confidential computing enforcement policy the container
+// group of a pod of the Confidential SKU runs under, the host data of its attestation reports.
+const ccePolicyHashAnnotation = "virtual-kubelet.io/cce-policy-hash"
+
+// ccePolicyHash returns the hash of the confidential computing enforcement policy of a container group, empty when
+// the container group is not of the Confidential SKU or runs with the allow all policy.
+func ccePolicyHash(cg *aci.ContainerGroup) string {
+	if cg.Sku != aci.ContainerGroupSkuConfidential || cg.ConfidentialComputeProperties == nil || cg.ConfidentialComputeProperties.CcePolicy == "" {
+		return ""
+	}
+	policy, err := base64.StdEncoding.DecodeString(cg.ConfidentialComputeProperties.CcePolicy)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(policy)
+	return hex.EncodeToString(sum[:])
+}
+
+// annotateAttestation annotates a pod with the hash of the policy its container group runs under, as reported by
+// ACI, for verifiers to compare with the host data of the attestation reports of the pod. The reports themselves are
+// only available from within the container group.
+func (p *ACIProvider) annotateAttestation(ctx context.Context, namespace, name string, cg *aci.ContainerGroup) {
+	hash := ccePolicyHash(cg)
+	if hash == "" || p.kubeClient == nil {
+		return
+	}
+	if pod := p.managedPod(namespace, name); pod == nil || pod.Annotations[ccePolicyHashAnnotation] == hash {
+		return
+	}
+
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{ccePolicyHashAnnotation: hash},
+		},
+	})
+	if err != nil {
+		return
+	}
+	if _, err := p.kubeClient.CoreV1().Pods(namespace).Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		log.G(ctx).WithError(err).Warnf("failed to annotate pod %s/%s with its confidential policy hash", namespace, name)
+	}
+}
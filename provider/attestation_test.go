@@ -0,0 +1,47 @@
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"testing"
+
+	"github.com/virtual-kubelet/azure-aci/client/aci"
+	"github.com/virtual-kubelet/node-cli/manager"
+	"gotest.tools/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestAnnotateAttestation(t *testing.T) {
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "ns"}}
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	assert.NilError(t, indexer.Add(pod))
+	rm, err := manager.NewResourceManager(corev1listers.NewPodLister(indexer), nil, nil, nil)
+	assert.NilError(t, err)
+	kubeClient := fake.NewSimpleClientset(pod.DeepCopy())
+	p := ACIProvider{resourceManager: rm, kubeClient: kubeClient}
+	ctx := context.Background()
+
+	cg := &aci.ContainerGroup{}
+	cg.Sku = aci.ContainerGroupSkuConfidential
+	p.annotateAttestation(ctx, "ns", "web", cg)
+	assert.Equal(t, len(kubeClient.Actions()), 0, "The allow all policy has no hash")
+
+	policy := []byte("package policy")
+	cg.ConfidentialComputeProperties = &aci.ConfidentialComputeProperties{CcePolicy: base64.StdEncoding.EncodeToString(policy)}
+	p.annotateAttestation(ctx, "ns", "web", cg)
+	annotated, err := kubeClient.CoreV1().Pods("ns").Get(ctx, "web", metav1.GetOptions{})
+	assert.NilError(t, err)
+	sum := sha256.Sum256(policy)
+	assert.Equal(t, annotated.Annotations[ccePolicyHashAnnotation], hex.EncodeToString(sum[:]))
+
+	assert.NilError(t, indexer.Update(annotated))
+	actions := len(kubeClient.Actions())
+	p.annotateAttestation(ctx, "ns", "web", cg)
+	assert.Equal(t, len(kubeClient.Actions()), actions, "An annotated pod should not be patched again")
+}
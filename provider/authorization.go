@@ -0,0 +1,140 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/virtual-kubelet/virtual-kubelet/log"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/endpoints/request"
+)
+
+const (
+	// The TTLs of the authorization decisions are the ones of the kubelet webhook authorizer.
+	authorizedTTL   = 5 * time.Minute
+	unauthorizedTTL = 30 * time.Second
+)
+
+// accessDecision is a cached outcome of a subject access review.
+type accessDecision struct {
+	allowed bool
+	reason  string
+	expires time.Time
+}
+
+// accessReviewCache caches the outcome of the subject access reviews by user and attributes.
+type accessReviewCache struct {
+	mu        sync.Mutex
+	decisions map[string]accessDecision
+}
+
+func (c *accessReviewCache) get(key string, now time.Time) (accessDecision, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	d, ok := c.decisions[key]
+	if !ok || now.After(d.expires) {
+		delete(c.decisions, key)
+		return accessDecision{}, false
+	}
+	return d, true
+}
+
+func (c *accessReviewCache) put(key string, d accessDecision) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.decisions == nil {
+		c.decisions = make(map[string]accessDecision)
+	}
+	c.decisions[key] = d
+}
+
+// setupAuthorization enables the authorization of the logs, exec and stats requests with subject access
+// reviews when ACI_AUTHORIZATION_WEBHOOK is true.
+func (p *ACIProvider) setupAuthorization() error {
+	v := os.Getenv("ACI_AUTHORIZATION_WEBHOOK")
+	if v == "" {
+		return nil
+	}
+	enabled, err := strconv.ParseBool(v)
+	if err != nil {
+		return fmt.Errorf("invalid ACI_AUTHORIZATION_WEBHOOK %q: %v", v, err)
+	}
+	if enabled && p.kubeClient == nil {
+		return fmt.Errorf("the authorization webhook requires a kubernetes client")
+	}
+	p.authorizeRequests = enabled
+	return nil
+}
+
+// authorize checks the user authenticated for the request can verb the subresource of the node, like
+// the kubelet does: get nodes/proxy for logs, create nodes/proxy for exec and get nodes/stats for stats.
+// Requests without an authenticated user are denied.
+func (p *ACIProvider) authorize(ctx context.Context, verb, subresource string) error {
+	if !p.authorizeRequests {
+		return nil
+	}
+
+	u, ok := request.UserFrom(ctx)
+	if !ok || u.GetName() == "" {
+		return fmt.Errorf("Forbidden: no authenticated user to %s nodes/%s", verb, subresource)
+	}
+
+	key := strings.Join([]string{u.GetName(), u.GetUID(), strings.Join(u.GetGroups(), ","), verb, subresource}, "/")
+	now := time.Now()
+	d, ok := p.accessReviews.get(key, now)
+	if !ok {
+		var err error
+		d, err = p.reviewAccess(ctx, u, verb, subresource)
+		if err != nil {
+			return fmt.Errorf("error authorizing %s to %s nodes/%s: %v", u.GetName(), verb, subresource, err)
+		}
+		d.expires = now.Add(unauthorizedTTL)
+		if d.allowed {
+			d.expires = now.Add(authorizedTTL)
+		}
+		p.accessReviews.put(key, d)
+	}
+
+	if !d.allowed {
+		log.G(ctx).WithField("user", u.GetName()).Warnf("denied %s nodes/%s: %s", verb, subresource, d.reason)
+		return fmt.Errorf("Forbidden: user %s cannot %s nodes/%s of node %s", u.GetName(), verb, subresource, p.nodeName)
+	}
+	return nil
+}
+
+// reviewAccess asks the API server whether the user can verb the subresource of the node.
+func (p *ACIProvider) reviewAccess(ctx context.Context, u user.Info, verb, subresource string) (accessDecision, error) {
+	extra := make(map[string]authorizationv1.ExtraValue, len(u.GetExtra()))
+	for k, v := range u.GetExtra() {
+		extra[k] = authorizationv1.ExtraValue(v)
+	}
+
+	review, err := p.kubeClient.AuthorizationV1().SubjectAccessReviews().Create(ctx, &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:   u.GetName(),
+			UID:    u.GetUID(),
+			Groups: u.GetGroups(),
+			Extra:  extra,
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Verb:        verb,
+				Resource:    "nodes",
+				Subresource: subresource,
+				Name:        p.nodeName,
+			},
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return accessDecision{}, err
+	}
+
+	return accessDecision{allowed: review.Status.Allowed, reason: review.Status.Reason}, nil
+}
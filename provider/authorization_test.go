@@ -0,0 +1,42 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"gotest.tools/assert"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestAuthorize(t *testing.T) {
+	reviews := 0
+	kubeClient := fake.NewSimpleClientset()
+	kubeClient.PrependReactor("create", "subjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		reviews++
+		review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview)
+		attrs := review.Spec.ResourceAttributes
+		review.Status.Allowed = review.Spec.User == "alice" && attrs.Resource == "nodes" && attrs.Name == "vk"
+		return true, review, nil
+	})
+	p := ACIProvider{nodeName: "vk", kubeClient: kubeClient, authorizeRequests: true}
+
+	alice := request.WithUser(context.Background(), &user.DefaultInfo{Name: "alice"})
+	assert.NilError(t, p.authorize(alice, "get", "proxy"))
+	assert.NilError(t, p.authorize(alice, "get", "proxy"))
+	assert.Equal(t, 1, reviews, "Authorization decisions should be cached")
+
+	bob := request.WithUser(context.Background(), &user.DefaultInfo{Name: "bob"})
+	assert.ErrorContains(t, p.authorize(bob, "create", "proxy"), "Forbidden")
+
+	assert.ErrorContains(t, p.authorize(context.Background(), "get", "stats"), "no authenticated user")
+}
+
+func TestAuthorizeDisabled(t *testing.T) {
+	var p ACIProvider
+	assert.NilError(t, p.authorize(context.Background(), "create", "proxy"))
+}
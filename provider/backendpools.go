@@ -0,0 +1,217 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	client "github.com/virtual-kubelet/azure-aci/client"
+	"github.com/virtual-kubelet/azure-aci/client/network"
+	"github.com/virtual-kubelet/virtual-kubelet/log"
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	// backendPoolAnnotation lists the resource IDs of the load balancer or application gateway backend
+	// pools the IP of the pod is registered in once it is ready, comma separated.
+	backendPoolAnnotation = "virtual-kubelet.io/backend-pools"
+
+	backendPoolsSyncInterval = 30 * time.Second
+)
+
+// backendPoolUpdater updates the addresses of the backend pools of load balancers and application gateways.
+type backendPoolUpdater interface {
+	UpdateBackendPoolAddresses(ctx context.Context, pool *network.BackendPool, update func([]network.BackendAddress) []network.BackendAddress) error
+}
+
+// backendPoolMembers are the addresses registered in each backend pool by the node, by pool resource ID
+// and address name.
+type backendPoolMembers struct {
+	mu         sync.Mutex
+	registered map[string]map[string]string
+}
+
+// setupBackendPools enables the registration of the annotated pods into backend pools with
+// ACI_BACKEND_POOLS or the config file.
+func (p *ACIProvider) setupBackendPools(azAuth *client.Authentication) error {
+	if v := os.Getenv("ACI_BACKEND_POOLS"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid ACI_BACKEND_POOLS %q: %v", v, err)
+		}
+		p.backendPoolsEnabled = b
+	}
+	if !p.backendPoolsEnabled {
+		return nil
+	}
+
+	c, err := network.NewClient(azAuth, p.extraUserAgent)
+	if err != nil {
+		return fmt.Errorf("error setting up backend pools: %v", err)
+	}
+	p.backendPools = c
+	p.subscriptionID = azAuth.SubscriptionID
+	return nil
+}
+
+// backendAddressName is the name of the address of the pod in the backend pools of load balancers.
+func backendAddressName(pod *v1.Pod) string {
+	return aciName("vk-", pod.Namespace+"-"+pod.Name)
+}
+
+// podBackendPools returns the resource IDs of the backend pools of the pod.
+func podBackendPools(pod *v1.Pod) []string {
+	var pools []string
+	for _, id := range strings.Split(pod.Annotations[backendPoolAnnotation], ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			pools = append(pools, id)
+		}
+	}
+	return pools
+}
+
+// podReady reports whether the pod is ready to take traffic, from its status.
+func podReady(pod *v1.Pod) bool {
+	if pod.DeletionTimestamp != nil || pod.Status.Phase != v1.PodRunning || pod.Status.PodIP == "" {
+		return false
+	}
+	for _, c := range pod.Status.Conditions {
+		if c.Type == v1.PodReady {
+			return c.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// virtualNetworkID returns the resource ID of the virtual network of the pods, if they run in one.
+func (p *ACIProvider) virtualNetworkID() string {
+	if p.vnetName == "" || p.subnetName == "" {
+		return ""
+	}
+	return fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Network/virtualNetworks/%s", p.subscriptionID, p.vnetResourceGroup, p.vnetName)
+}
+
+// backendPoolsLoop keeps the backend pools in sync with the ready pods until the context is done.
+func (p *ACIProvider) backendPoolsLoop(ctx context.Context) {
+	ticker := time.NewTicker(backendPoolsSyncInterval)
+	defer ticker.Stop()
+
+	for {
+		p.syncBackendPools(ctx, nil)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// syncBackendPools registers the IP of the ready annotated pods in their backend pools, and removes the
+// addresses of the pods which are not ready anymore, failed or were deleted. The deleted pod is left
+// out even if it is still known.
+func (p *ACIProvider) syncBackendPools(ctx context.Context, deleted *v1.Pod) {
+	desired := make(map[string]map[string]string)
+	for _, pod := range p.resourceManager.GetPods() {
+		if (deleted != nil && pod.UID == deleted.UID) || !podReady(pod) {
+			continue
+		}
+		for _, id := range podBackendPools(pod) {
+			if desired[id] == nil {
+				desired[id] = make(map[string]string)
+			}
+			desired[id][backendAddressName(pod)] = pod.Status.PodIP
+		}
+	}
+
+	m := &p.backendPoolMembers
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.registered == nil {
+		m.registered = make(map[string]map[string]string)
+	}
+	for id := range m.registered {
+		if _, ok := desired[id]; !ok {
+			desired[id] = map[string]string{}
+		}
+	}
+
+	for id, want := range desired {
+		have := m.registered[id]
+		if sameMembers(have, want) {
+			continue
+		}
+		logger := log.G(ctx).WithField("backendPool", id)
+
+		pool, err := network.ParseBackendPoolID(p.subscriptionID, id)
+		if err != nil {
+			logger.WithError(err).Warn("Invalid backend pool")
+			continue
+		}
+		err = p.backendPools.UpdateBackendPoolAddresses(ctx, pool, func(current []network.BackendAddress) []network.BackendAddress {
+			return p.backendPoolAddresses(current, have, want)
+		})
+		if err != nil {
+			logger.WithError(err).Warn("Unable to update the backend pool")
+			continue
+		}
+
+		logger.Infof("Registered %d pods in the backend pool", len(want))
+		if len(want) == 0 {
+			delete(m.registered, id)
+		} else {
+			m.registered[id] = want
+		}
+	}
+}
+
+// backendPoolAddresses returns the addresses of a backend pool, with the addresses the node registered
+// replaced by the wanted ones. The addresses of other nodes or registered by other means are kept.
+func (p *ACIProvider) backendPoolAddresses(current []network.BackendAddress, have, want map[string]string) []network.BackendAddress {
+	owned := make(map[string]bool, len(have)+len(want))
+	for name, ip := range have {
+		owned[name] = true
+		owned[ip] = true
+	}
+	for name, ip := range want {
+		owned[name] = true
+		owned[ip] = true
+	}
+
+	var addresses []network.BackendAddress
+	for _, a := range current {
+		if (a.Name != "" && owned[a.Name]) || owned[a.IPAddress] {
+			continue
+		}
+		addresses = append(addresses, a)
+	}
+	for name, ip := range want {
+		addresses = append(addresses, network.BackendAddress{Name: name, IPAddress: ip, VirtualNetworkID: p.virtualNetworkID()})
+	}
+	return addresses
+}
+
+func sameMembers(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name, ip := range a {
+		if b[name] != ip {
+			return false
+		}
+	}
+	return true
+}
+
+// deregisterBackendPools removes a deleted pod from its backend pools before its container group is deleted.
+func (p *ACIProvider) deregisterBackendPools(ctx context.Context, pod *v1.Pod) {
+	if p.backendPools == nil || len(podBackendPools(pod)) == 0 {
+		return
+	}
+	p.syncBackendPools(ctx, pod)
+}
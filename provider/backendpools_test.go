@@ -0,0 +1,102 @@
+package provider
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/virtual-kubelet/azure-aci/client/network"
+	"github.com/virtual-kubelet/node-cli/manager"
+	"gotest.tools/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+const testBackendPool = "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/loadBalancers/lb/backendAddressPools/pool"
+
+type fakeBackendPools struct {
+	addresses map[string][]network.BackendAddress
+	updates   int
+}
+
+func (f *fakeBackendPools) UpdateBackendPoolAddresses(ctx context.Context, pool *network.BackendPool, update func([]network.BackendAddress) []network.BackendAddress) error {
+	f.updates++
+	f.addresses[pool.PoolName] = update(f.addresses[pool.PoolName])
+	return nil
+}
+
+func (f *fakeBackendPools) ips(pool string) []string {
+	var ips []string
+	for _, a := range f.addresses[pool] {
+		ips = append(ips, a.IPAddress)
+	}
+	sort.Strings(ips)
+	return ips
+}
+
+func backendPoolPod(name, ip string, ready bool) *v1.Pod {
+	status := v1.ConditionFalse
+	if ready {
+		status = v1.ConditionTrue
+	}
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   "ns",
+			UID:         types.UID(name),
+			Annotations: map[string]string{backendPoolAnnotation: testBackendPool},
+		},
+		Status: v1.PodStatus{
+			Phase:      v1.PodRunning,
+			PodIP:      ip,
+			Conditions: []v1.PodCondition{{Type: v1.PodReady, Status: status}},
+		},
+	}
+}
+
+func TestSyncBackendPools(t *testing.T) {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	rm, err := manager.NewResourceManager(corev1listers.NewPodLister(indexer), nil, nil, nil)
+	assert.NilError(t, err)
+
+	pools := &fakeBackendPools{addresses: map[string][]network.BackendAddress{
+		"pool": {{Name: "vm-1", IPAddress: "10.0.0.4"}},
+	}}
+	p := ACIProvider{
+		resourceManager:   rm,
+		backendPools:      pools,
+		subscriptionID:    "sub",
+		vnetName:          "vnet",
+		vnetResourceGroup: "vnet-rg",
+		subnetName:        "aci",
+	}
+	ctx := context.Background()
+
+	web1 := backendPoolPod("web-1", "10.1.0.4", true)
+	assert.NilError(t, indexer.Add(web1))
+	assert.NilError(t, indexer.Add(backendPoolPod("web-2", "10.1.0.5", false)))
+
+	p.syncBackendPools(ctx, nil)
+	assert.DeepEqual(t, pools.ips("pool"), []string{"10.0.0.4", "10.1.0.4"})
+	added := pools.addresses["pool"][1]
+	assert.Equal(t, added.Name, "vk-ns-web-1")
+	assert.Equal(t, added.VirtualNetworkID, "/subscriptions/sub/resourceGroups/vnet-rg/providers/Microsoft.Network/virtualNetworks/vnet")
+
+	p.syncBackendPools(ctx, nil)
+	assert.Equal(t, pools.updates, 1, "An unchanged pool should not be updated")
+
+	assert.NilError(t, indexer.Update(backendPoolPod("web-2", "10.1.0.5", true)))
+	p.syncBackendPools(ctx, nil)
+	assert.DeepEqual(t, pools.ips("pool"), []string{"10.0.0.4", "10.1.0.4", "10.1.0.5"})
+
+	p.deregisterBackendPools(ctx, web1)
+	assert.DeepEqual(t, pools.ips("pool"), []string{"10.0.0.4", "10.1.0.5"})
+
+	assert.NilError(t, indexer.Delete(web1))
+	assert.NilError(t, indexer.Update(backendPoolPod("web-2", "10.1.0.5", false)))
+	p.syncBackendPools(ctx, nil)
+	assert.DeepEqual(t, pools.ips("pool"), []string{"10.0.0.4"}, "Only the addresses of the node should be removed")
+}
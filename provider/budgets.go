@@ -0,0 +1,267 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/virtual-kubelet/azure-aci/client/aci"
+	"github.com/virtual-kubelet/virtual-kubelet/errdefs"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+const eventReasonNamespaceBudgetExceeded = "NamespaceBudgetExceeded"
+
+// namespaceBudget is the maximum ACI consumption of the pods of a namespace on this node, as set
+// in the provider config file. An empty or zero limit is not enforced.
+type namespaceBudget struct {
+	// CPU is the total CPU of the container groups of the namespace, like "16".
+	CPU string
+	// Memory is the total memory of the container groups of the namespace, like "64Gi".
+	Memory string
+	// Pods is the number of container groups of the namespace.
+	Pods int
+}
+
+// budgetUsage is the consumption of container groups, in the units of ACI.
+type budgetUsage struct {
+	cpu        float64
+	memoryInGB float64
+	pods       int
+}
+
+func (u *budgetUsage) add(o budgetUsage) {
+	u.cpu += o.cpu
+	u.memoryInGB += o.memoryInGB
+	u.pods += o.pods
+}
+
+func (b namespaceBudget) limits() (budgetUsage, error) {
+	limits := budgetUsage{pods: b.Pods}
+	if b.Pods < 0 {
+		return limits, fmt.Errorf("invalid pods %d, expected a positive number", b.Pods)
+	}
+	if b.CPU != "" {
+		q, err := resource.ParseQuantity(b.CPU)
+		if err != nil {
+			return limits, fmt.Errorf("invalid CPU %q: %v", b.CPU, err)
+		}
+		limits.cpu = float64(q.MilliValue()) / 1000
+	}
+	if b.Memory != "" {
+		q, err := resource.ParseQuantity(b.Memory)
+		if err != nil {
+			return limits, fmt.Errorf("invalid memory %q: %v", b.Memory, err)
+		}
+		limits.memoryInGB = float64(q.Value()) / 1000000000
+	}
+	return limits, nil
+}
+
+// exceeds returns the dimension of the limits the usage exceeds, if any.
+func (u budgetUsage) exceeds(limits budgetUsage) (string, bool) {
+	switch {
+	case limits.cpu > 0 && u.cpu > limits.cpu:
+		return fmt.Sprintf("CPU usage to %g, which exceeds its budget of %g", u.cpu, limits.cpu), true
+	case limits.memoryInGB > 0 && u.memoryInGB > limits.memoryInGB:
+		return fmt.Sprintf("memory usage to %gGB, which exceeds its budget of %gGB", u.memoryInGB, limits.memoryInGB), true
+	case limits.pods > 0 && u.pods > limits.pods:
+		return fmt.Sprintf("pods to %d, which exceeds its budget of %d", u.pods, limits.pods), true
+	}
+	return "", false
+}
+
+// containerGroupUsage returns the resources requested by the containers of the container group.
+func containerGroupUsage(cg *aci.ContainerGroup) budgetUsage {
+	usage := budgetUsage{pods: 1}
+	for _, c := range cg.Containers {
+		if r := c.Resources.Requests; r != nil {
+			usage.cpu += r.CPU
+			usage.memoryInGB += r.MemoryInGB
+		}
+	}
+	return usage
+}
+
+// budgetLocks serializes the reservations of the budget of a namespace, so concurrent creations
+// can't both fit in the remaining budget.
+type budgetLocks struct {
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+func (l *budgetLocks) get(namespace string) *sync.Mutex {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.locks == nil {
+		l.locks = make(map[string]*sync.Mutex)
+	}
+	if _, ok := l.locks[namespace]; !ok {
+		l.locks[namespace] = &sync.Mutex{}
+	}
+	return l.locks[namespace]
+}
+
+// budgetReservations are the consumptions reserved in the budget of their namespace by the pods whose container
+// group is being created, waits for quota or is being repaired, until it is deleted. They count even while the
+// container group is missing from the list of ARM.
+type budgetReservations struct {
+	mu   sync.Mutex
+	pods map[string]budgetUsage
+}
+
+// set reserves the consumption of the pod, and returns the one it replaces, if any.
+func (r *budgetReservations) set(namespace, name string, usage budgetUsage) (budgetUsage, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.pods == nil {
+		r.pods = make(map[string]budgetUsage)
+	}
+	key := namespace + "/" + name
+	previous, ok := r.pods[key]
+	r.pods[key] = usage
+	return previous, ok
+}
+
+// namespace returns the consumptions reserved by the pods of the namespace, by pod.
+func (r *budgetReservations) namespace(namespace string) map[string]budgetUsage {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	reserved := make(map[string]budgetUsage)
+	for key, usage := range r.pods {
+		if strings.HasPrefix(key, namespace+"/") {
+			reserved[key] = usage
+		}
+	}
+	return reserved
+}
+
+func (r *budgetReservations) remove(namespace, name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.pods, namespace+"/"+name)
+}
+
+// setupNamespaceBudgets validates the budgets of the namespaces from the config file.
+func (p *ACIProvider) setupNamespaceBudgets() error {
+	if len(p.budgets) == 0 {
+		return nil
+	}
+
+	p.budgetLimits = make(map[string]budgetUsage, len(p.budgets))
+	for namespace, b := range p.budgets {
+		limits, err := b.limits()
+		if err != nil {
+			return fmt.Errorf("invalid budget for namespace %q: %v", namespace, err)
+		}
+		p.budgetLimits[namespace] = limits
+	}
+	return nil
+}
+
+// namespaceUsageByPod returns the consumption of the running container groups of the namespace by pod,
+// except the one of the pod itself, and the pods whose container group is not running anymore.
+func (p *ACIProvider) namespaceUsageByPod(ctx context.Context, pod *v1.Pod) (map[string]budgetUsage, map[string]bool, error) {
+	byPod := make(map[string]budgetUsage)
+	terminated := make(map[string]bool)
+	err := p.visitNodeContainerGroups(ctx, func(cg *aci.ContainerGroup) error {
+		if cg.Tags["Namespace"] != pod.Namespace || cg.Tags["PodName"] == pod.Name {
+			return nil
+		}
+		key := pod.Namespace + "/" + cg.Tags["PodName"]
+		switch state, _ := aciResourceMetaFromContainerGroup(cg); state {
+		case aciStateStopped, "Succeeded", "Failed":
+			terminated[key] = true
+			return nil
+		}
+		usage := byPod[key]
+		usage.add(containerGroupUsage(cg))
+		byPod[key] = usage
+		return nil
+	})
+	return byPod, terminated, err
+}
+
+// reserveNamespaceBudget rejects the container group of the pod with an event if it doesn't fit in
+// the budget of its namespace, unless preempting Spot pods of the namespace of a lower priority makes
+// room for it. Otherwise its consumption stays reserved until its container group is deleted, or cancel
+// is called when the container group is not created, which restores the previous reservation of the pod.
+// Every creation of a container group, retries and repairs included, reserves its consumption first.
+// The container groups are listed before the budget is locked, the reservations of the pods cover the
+// container groups created since.
+func (p *ACIProvider) reserveNamespaceBudget(ctx context.Context, pod *v1.Pod, cg *aci.ContainerGroup) (cancel func(), err error) {
+	limits, ok := p.budgetLimits[pod.Namespace]
+	if !ok {
+		return func() {}, nil
+	}
+
+	for preempted := false; ; preempted = true {
+		byPod, terminated, err := p.namespaceUsageByPod(ctx, pod)
+		if err != nil {
+			return nil, fmt.Errorf("unable to check the budget of namespace %s: %v", pod.Namespace, err)
+		}
+		usageWithout := func(evicted map[string]bool) budgetUsage {
+			consumers := make(map[string]budgetUsage, len(byPod))
+			for key, u := range byPod {
+				consumers[key] = u
+			}
+			for key, u := range p.budgetReservations.namespace(pod.Namespace) {
+				if key != podKey(pod) && !terminated[key] {
+					consumers[key] = u
+				}
+			}
+			usage := containerGroupUsage(cg)
+			for key, u := range consumers {
+				if !evicted[key] {
+					usage.add(u)
+				}
+			}
+			return usage
+		}
+
+		mu := p.budgetLocks.get(pod.Namespace)
+		mu.Lock()
+		exceeded, exceeds := usageWithout(nil).exceeds(limits)
+		if !exceeds {
+			previous, reserved := p.budgetReservations.set(pod.Namespace, pod.Name, containerGroupUsage(cg))
+			mu.Unlock()
+			return func() {
+				if reserved {
+					p.budgetReservations.set(pod.Namespace, pod.Name, previous)
+				} else {
+					p.budgetReservations.remove(pod.Namespace, pod.Name)
+				}
+			}, nil
+		}
+		mu.Unlock()
+
+		// Once the pods preempted are gone the budget is checked again, with the pods created meanwhile.
+		if !preempted {
+			var candidates []*v1.Pod
+			for _, other := range p.resourceManager.GetPods() {
+				if other.Namespace == pod.Namespace {
+					candidates = append(candidates, other)
+				}
+			}
+			fits, err := p.preemptSpotPods(ctx, pod, candidates, func(evicted map[string]bool) bool {
+				_, exceeds := usageWithout(evicted).exceeds(limits)
+				return !exceeds
+			})
+			if err != nil {
+				return nil, err
+			}
+			if fits {
+				continue
+			}
+		}
+
+		message := fmt.Sprintf("Pod %s would bring the %s of namespace %s", pod.Name, exceeded, pod.Namespace)
+		p.recordEvent(pod, v1.EventTypeWarning, eventReasonNamespaceBudgetExceeded, "%s", message)
+		return nil, errdefs.InvalidInput(message)
+	}
+}
@@ -0,0 +1,129 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/virtual-kubelet/azure-aci/client/aci"
+	"github.com/virtual-kubelet/virtual-kubelet/errdefs"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func budgetContainerGroup(namespace, name, state string, cpu, memoryInGB float64) aci.ContainerGroup {
+	return aci.ContainerGroup{
+		Name: namespace + "-" + name,
+		Tags: map[string]string{
+			"NodeName":  fakeNodeName,
+			"Namespace": namespace,
+			"PodName":   name,
+		},
+		ContainerGroupProperties: aci.ContainerGroupProperties{
+			ProvisioningState: "Succeeded",
+			InstanceView:      aci.ContainerGroupPropertiesInstanceView{State: state},
+			Containers: []aci.Container{{
+				Name: "app",
+				ContainerProperties: aci.ContainerProperties{
+					Resources: aci.ResourceRequirements{
+						Requests: &aci.ComputeResources{CPU: cpu, MemoryInGB: memoryInGB},
+					},
+				},
+			}},
+		},
+	}
+}
+
+func TestNamespaceBudgetLimits(t *testing.T) {
+	limits, err := namespaceBudget{CPU: "1500m", Memory: "4G", Pods: 3}.limits()
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(budgetUsage{cpu: 1.5, memoryInGB: 4, pods: 3}, limits))
+
+	_, err = namespaceBudget{CPU: "lots"}.limits()
+	assert.Check(t, err != nil, "Invalid CPU should be rejected")
+	_, err = namespaceBudget{Pods: -1}.limits()
+	assert.Check(t, err != nil, "Negative pods should be rejected")
+
+	_, ok := budgetUsage{cpu: 8, memoryInGB: 100, pods: 100}.exceeds(budgetUsage{})
+	assert.Check(t, !ok, "Zero limits should not be enforced")
+	exceeded, ok := budgetUsage{cpu: 1, memoryInGB: 5, pods: 1}.exceeds(limits)
+	assert.Check(t, ok)
+	assert.Check(t, is.Equal("memory usage to 5GB, which exceeds its budget of 4GB", exceeded))
+}
+
+func TestReserveNamespaceBudget(t *testing.T) {
+	_, aciServerMocker, provider, err := prepareMocks()
+	if err != nil {
+		t.Fatal("Unable to prepare the mocks", err)
+	}
+	provider.budgets = map[string]namespaceBudget{"team": {CPU: "2"}}
+	assert.NilError(t, provider.setupNamespaceBudgets())
+
+	aciServerMocker.OnGetContainerGroups = func(subscription, resourceGroup string) (int, interface{}) {
+		return http.StatusOK, aci.ContainerGroupListResult{
+			Value: []aci.ContainerGroup{
+				budgetContainerGroup("team", "web", "Running", 1, 1.5),
+				budgetContainerGroup("team", "job", "Succeeded", 4, 1.5),
+				budgetContainerGroup("other", "web", "Running", 4, 1.5),
+			},
+		}
+	}
+
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "team"}}
+	fits := budgetContainerGroup("team", "api", "", 1, 1.5)
+	cancel, err := provider.reserveNamespaceBudget(context.Background(), pod, &fits)
+	assert.NilError(t, err)
+	cancel()
+	assert.Check(t, is.Len(provider.budgetReservations.namespace("team"), 0), "The reservation should be canceled")
+
+	exceeds := budgetContainerGroup("team", "api", "", 1.5, 1.5)
+	_, err = provider.reserveNamespaceBudget(context.Background(), pod, &exceeds)
+	assert.Check(t, errdefs.IsInvalidInput(err), "Expected an invalid input error, got %v", err)
+
+	pod.Namespace = "unbudgeted"
+	cancel, err = provider.reserveNamespaceBudget(context.Background(), pod, &exceeds)
+	assert.NilError(t, err)
+	cancel()
+}
+
+func TestNamespaceBudgetReservations(t *testing.T) {
+	_, aciServerMocker, provider, err := prepareMocks()
+	if err != nil {
+		t.Fatal("Unable to prepare the mocks", err)
+	}
+	provider.budgets = map[string]namespaceBudget{"team": {CPU: "2"}}
+	assert.NilError(t, provider.setupNamespaceBudgets())
+
+	listed := []aci.ContainerGroup{budgetContainerGroup("team", "web", "Running", 1, 1.5)}
+	aciServerMocker.OnGetContainerGroups = func(subscription, resourceGroup string) (int, interface{}) {
+		return http.StatusOK, aci.ContainerGroupListResult{Value: listed}
+	}
+
+	// The pod waiting for quota, or being repaired, has no container group listed but keeps its budget.
+	waiting := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "waiting", Namespace: "team"}}
+	cg := budgetContainerGroup("team", "waiting", "", 1, 1.5)
+	_, err = provider.reserveNamespaceBudget(context.Background(), waiting, &cg)
+	assert.NilError(t, err)
+
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "team"}}
+	small := budgetContainerGroup("team", "api", "", 0.5, 1)
+	_, err = provider.reserveNamespaceBudget(context.Background(), pod, &small)
+	assert.Check(t, errdefs.IsInvalidInput(err), "The reservation of the pod waiting should count, got %v", err)
+
+	// The reservation of the pod is replaced by its new consumption, and canceling restores it.
+	smaller := budgetContainerGroup("team", "waiting", "", 0.5, 1)
+	cancel, err := provider.reserveNamespaceBudget(context.Background(), waiting, &smaller)
+	assert.NilError(t, err)
+	_, err = provider.reserveNamespaceBudget(context.Background(), pod, &small)
+	assert.NilError(t, err)
+	provider.budgetReservations.remove("team", "api")
+	cancel()
+	assert.Check(t, is.Equal(budgetUsage{cpu: 1, memoryInGB: 1.5, pods: 1}, provider.budgetReservations.namespace("team")["team/waiting"]))
+
+	// Once its container group stopped, the pod doesn't count anymore.
+	listed = append(listed, budgetContainerGroup("team", "waiting", aciStateStopped, 1, 1.5))
+	_, err = provider.reserveNamespaceBudget(context.Background(), pod, &small)
+	assert.NilError(t, err)
+}
@@ -0,0 +1,142 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	defaultCircuitBreakerThreshold = 10
+	defaultCircuitBreakerCooldown  = 30 * time.Second
+
+	// armDegradedCondition is true on the node while the requests of an operation class to ARM are shed.
+	armDegradedCondition v1.NodeConditionType = "ARMDegraded"
+)
+
+// setupCircuitBreakers enables the circuit breakers of the ACI client, configured by the ACI_CIRCUIT_BREAKER_THRESHOLD
+// and ACI_CIRCUIT_BREAKER_COOLDOWN environment variables. A threshold of 0 disables them.
+func (p *ACIProvider) setupCircuitBreakers() error {
+	threshold := defaultCircuitBreakerThreshold
+	if v := os.Getenv("ACI_CIRCUIT_BREAKER_THRESHOLD"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid ACI_CIRCUIT_BREAKER_THRESHOLD %q, expected a positive number of failures", v)
+		}
+		threshold = n
+	}
+
+	cooldown := defaultCircuitBreakerCooldown
+	if v := os.Getenv("ACI_CIRCUIT_BREAKER_COOLDOWN"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("invalid ACI_CIRCUIT_BREAKER_COOLDOWN %q, expected a positive duration", v)
+		}
+		cooldown = d
+	}
+
+	if threshold > 0 {
+		p.aciClient.EnableCircuitBreakers(threshold, cooldown)
+	}
+	return nil
+}
+
+// armCondition returns the condition of the node reporting the operation classes whose requests to ARM are shed.
+func (p *ACIProvider) armCondition() v1.NodeCondition {
+	condition := v1.NodeCondition{
+		Type:               armDegradedCondition,
+		Status:             v1.ConditionFalse,
+		LastHeartbeatTime:  metav1.Now(),
+		LastTransitionTime: metav1.Now(),
+		Reason:             "ARMAvailable",
+		Message:            "requests to Azure Resource Manager are sent",
+	}
+
+	if open := p.openCircuits(); len(open) > 0 {
+		condition.Status = v1.ConditionTrue
+		condition.Reason = "CircuitBreakerOpen"
+		condition.Message = fmt.Sprintf("requests to Azure Resource Manager are shed for %s operations", strings.Join(open, ", "))
+	}
+
+	return condition
+}
+
+func (p *ACIProvider) openCircuits() []string {
+	if p.aciClient == nil {
+		return nil
+	}
+
+	var open []string
+	for _, class := range p.aciClient.OpenCircuits() {
+		open = append(open, string(class))
+	}
+	return open
+}
+
+// nodeStatusKey summarizes the parts of the node status which change, the readiness, the open circuits,
+// the pods capacity, the allocatable resources and the ACI service issues.
+func (p *ACIProvider) nodeStatusKey() string {
+	allocatable := p.allocatable()
+	cpu, memory := allocatable[v1.ResourceCPU], allocatable[v1.ResourceMemory]
+	return string(p.readyCondition().Status) + "/" + strings.Join(p.openCircuits(), ",") + "/" + p.podsCapacity() + "/" +
+		cpu.String() + "," + memory.String() + "/" + p.serviceHealth.key()
+}
+
+// NotifyNodeStatus is called by the node controller, the passed in function is called with the
+// node when its status changes, such as when a circuit breaker of the ACI client opens or closes,
+// the pods capacity changes with the IP addresses left in the subnet, the allocatable resources change with
+// the overhead of the pods, or the node turns ready or not ready with the outcome of the requests to ARM. With a heartbeat interval, the status is also sent unchanged at
+// least that often.
+// It also keeps the labels and taints from the provider config on the node, and renews the node lease with a
+// lease renew interval.
+func (p *ACIProvider) NotifyNodeStatus(ctx context.Context, cb func(*v1.Node)) {
+	goSubsystem("node_config", func() { p.reconcileNodeConfigLoop(ctx) })
+	goSubsystem("subnet_capacity", func() { p.subnetCapacityLoop(ctx) })
+	if p.leaseRenewInterval > 0 && p.kubeClient != nil {
+		goSubsystem("node_lease", func() { p.renewNodeLeaseLoop(ctx) })
+	}
+
+	goSubsystem("node_status", func() {
+		interval := p.nodeStatusInterval
+		if interval <= 0 {
+			interval = defaultNodeStatusInterval
+		}
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		last := p.nodeStatusKey()
+		lastSent := time.Now()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			key := p.nodeStatusKey()
+			heartbeat := p.heartbeatInterval > 0 && time.Since(lastSent) >= p.heartbeatInterval
+			if key == last && !heartbeat {
+				continue
+			}
+			p.nodeMu.Lock()
+			node := p.node.DeepCopy()
+			p.nodeMu.Unlock()
+			if node == nil {
+				continue
+			}
+			last = key
+			lastSent = time.Now()
+
+			node.Status.Capacity = p.capacity()
+			node.Status.Allocatable = p.allocatable()
+			node.Status.Conditions = p.nodeConditions()
+			cb(node)
+		}
+	})
+}
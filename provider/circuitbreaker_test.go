@@ -0,0 +1,45 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestCircuitBreakerDegradesNode(t *testing.T) {
+	_, aciServerMocker, provider, err := prepareMocks()
+	if err != nil {
+		t.Fatal("Unable to prepare the mocks", err)
+	}
+
+	podName := "pod-" + uuid.New().String()
+	podNamespace := "ns-" + uuid.New().String()
+
+	requests := 0
+	aciServerMocker.OnGetContainerGroup = func(subscription, resourceGroup, containerGroup string) (int, interface{}) {
+		requests++
+		return http.StatusInternalServerError, nil
+	}
+
+	assert.Check(t, is.Equal(v1.ConditionFalse, provider.armCondition().Status))
+
+	for i := 0; i < defaultCircuitBreakerThreshold; i++ {
+		_, err := provider.GetPodStatus(context.Background(), podNamespace, podName)
+		assert.Check(t, err != nil, "Expected an error from a failing ARM")
+	}
+
+	_, err = provider.GetPodStatus(context.Background(), podNamespace, podName)
+	assert.Check(t, err != nil && strings.Contains(err.Error(), "circuit breaker"), "Expected the request to be shed, got %v", err)
+	assert.Check(t, is.Equal(defaultCircuitBreakerThreshold, requests))
+
+	condition := provider.armCondition()
+	assert.Check(t, is.Equal(v1.ConditionTrue, condition.Status))
+	assert.Check(t, is.Equal("CircuitBreakerOpen", condition.Reason))
+	assert.Check(t, strings.Contains(condition.Message, "read"))
+}
@@ -0,0 +1,48 @@
+package provider
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+const (
+	defaultMinConcurrency = 1
+	defaultMaxConcurrency = 32
+)
+
+// setupConcurrency enables the adaptive concurrency of the container group requests of the ACI client,
+// bounded by the ACI_MIN_CONCURRENCY and ACI_MAX_CONCURRENCY environment variables. A maximum of 0 disables it.
+func (p *ACIProvider) setupConcurrency() error {
+	minConcurrency, err := concurrencyFromEnv("ACI_MIN_CONCURRENCY", defaultMinConcurrency)
+	if err != nil {
+		return err
+	}
+	maxConcurrency, err := concurrencyFromEnv("ACI_MAX_CONCURRENCY", defaultMaxConcurrency)
+	if err != nil {
+		return err
+	}
+
+	if maxConcurrency == 0 {
+		return nil
+	}
+	if minConcurrency < 1 || minConcurrency > maxConcurrency {
+		return fmt.Errorf("invalid ACI_MIN_CONCURRENCY %d, expected a number between 1 and %d", minConcurrency, maxConcurrency)
+	}
+
+	p.aciClient.EnableAdaptiveConcurrency(minConcurrency, maxConcurrency)
+	return nil
+}
+
+func concurrencyFromEnv(name string, defaultValue int) (int, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return defaultValue, nil
+	}
+
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid %s %q, expected a positive number of requests", name, v)
+	}
+	return n, nil
+}
@@ -7,7 +7,10 @@ import (
 	"strings"
 
 	"github.com/BurntSushi/toml"
+	client "github.com/virtual-kubelet/azure-aci/client"
+	"github.com/virtual-kubelet/azure-aci/client/aci"
 	"github.com/virtual-kubelet/node-cli/provider"
+	"k8s.io/apimachinery/pkg/util/validation"
 )
 
 type providerConfig struct {
@@ -20,6 +23,88 @@ type providerConfig struct {
 	SubnetName         string
 	SubnetCIDR         string
 	NetworkProfileName string
+	PodOverheadCPU     string
+	PodOverheadMemory  string
+	RuntimeClasses     map[string]runtimeClassProfile
+	RegistryMirrors    map[string]string
+	Architecture       string
+	Diagnostics        map[string]namespaceDiagnostics
+	StatusSyncInterval string
+	StatusSyncJitter   *float64
+	HTTPKeepAlives     bool
+	HTTPMaxIdleConns   int
+	HTTPIdleTimeout    string
+	HTTP2              bool
+	HTTPRetryWaitMin   string
+	HTTPRetryWaitMax   string
+	HTTPRetryMax       *int
+	HTTPCompression    *bool
+	HTTPTrace          bool
+	ExecDialTimeout    string
+	ExecDialRetries    *int
+	ProvisionTimeout   string
+	StuckPodPolicy     string
+	DeleteStuckGroups  bool
+	NodeLabels         map[string]string
+	NodeTaints         []nodeTaint
+	Zone               string
+	Zones              []string
+	ARMUnhealthyAfter  string
+	ARMHealthyAfter    string
+	NodeStatusInterval string
+	HeartbeatInterval  string
+	LeaseRenewInterval string
+	LeaseDuration      string
+	InternalIP         string
+	ExternalAddress    string
+	Hostname           string
+	APIVersions        map[string]string
+	UserAgentClusterID string
+	UserAgentSuffix    string
+	ListByNodeTag      bool
+	AllowedNamespaces  []string
+	DeniedNamespaces   []string
+	NamespaceBudgets   map[string]namespaceBudget
+	EmptyDirMaxSize    string
+	ScratchAccount     string
+	ScratchAccountRG   string
+	VolumeInitImage    string
+	GitRepoImage       string
+	CSISecretsStore    bool
+	VolumeStats        bool
+	UnsupportedFields  string
+	PortExposure       string
+	BackendPools       bool
+	ManageSubnet       *bool
+	ManageNSG          bool
+	NSGID              string
+	ControlPlaneProxy  string
+	DataPlaneHosts     []string
+	DNSNameLabelScope  string
+	DeleteConcurrency  *int
+	MetricsAggregation string
+	MetricsWindow      string
+	OnlyCPUAndMemory   bool
+	MetricsFallback    bool
+	ExcludeFromMetrics []string
+	CrashLoopPolicy    string
+	CrashLoopRestarts  *int
+	ActivityLog        *bool
+	PolicyCheck        bool
+	PermissionCheck    string
+	Credentials        map[string]resourceGroupCredential
+	StateKeyFile       string
+	StateKeyVaultKey   string
+	SeccompProfileDir  string
+	Extensions         []containerGroupExtension
+	PodConfigs         bool
+	HookURL            string
+	HookCAFile         string
+	HookTimeout        string
+	HookFailurePolicy  string
+	Faults             string
+	RecordingDir       string
+	SpotPreemption     bool
 }
 
 func (p *ACIProvider) loadConfig(r io.Reader) error {
@@ -73,6 +158,120 @@ func (p *ACIProvider) loadConfig(r io.Reader) error {
 	if config.NetworkProfileName != "" {
 		p.networkProfileName = config.NetworkProfileName
 	}
+
+	p.podOverheadCPU = config.PodOverheadCPU
+	p.podOverheadMemory = config.PodOverheadMemory
+
+	for name, profile := range config.RuntimeClasses {
+		if err := profile.validate(); err != nil {
+			return fmt.Errorf("invalid profile for runtime class handler %q: %v", name, err)
+		}
+	}
+	p.runtimeClassProfiles = config.RuntimeClasses
+	p.registryMirrors = config.RegistryMirrors
+	p.architecture = config.Architecture
+	p.statusSyncInterval = config.StatusSyncInterval
+	p.statusSyncJitter = config.StatusSyncJitter
+	p.transportOptions = client.TransportOptions{
+		KeepAlives:          config.HTTPKeepAlives,
+		MaxIdleConnsPerHost: config.HTTPMaxIdleConns,
+		HTTP2:               config.HTTP2,
+		RetryMax:            config.HTTPRetryMax,
+		DisableCompression:  config.HTTPCompression != nil && !*config.HTTPCompression,
+	}
+	p.httpIdleTimeout = config.HTTPIdleTimeout
+	p.httpRetryWaitMin = config.HTTPRetryWaitMin
+	p.httpRetryWaitMax = config.HTTPRetryWaitMax
+	p.httpTrace = config.HTTPTrace
+	p.execTimeout = config.ExecDialTimeout
+	p.execDialRetries = config.ExecDialRetries
+	p.provisionTimeout = config.ProvisionTimeout
+	p.stuckPodPolicy = config.StuckPodPolicy
+	p.deleteStuckGroups = config.DeleteStuckGroups
+
+	if err := validateNodeLabels(config.NodeLabels); err != nil {
+		return err
+	}
+	p.nodeLabels = config.NodeLabels
+	for _, t := range config.NodeTaints {
+		taint, err := t.taint()
+		if err != nil {
+			return err
+		}
+		p.nodeTaints = append(p.nodeTaints, taint)
+	}
+	if errs := validation.IsValidLabelValue(config.Zone); len(errs) > 0 {
+		return fmt.Errorf("invalid zone %q: %s", config.Zone, strings.Join(errs, ", "))
+	}
+	p.zone = config.Zone
+	p.zones = config.Zones
+	p.armUnhealthyAfter = config.ARMUnhealthyAfter
+	p.armHealthyAfter = config.ARMHealthyAfter
+	p.statusInterval = config.NodeStatusInterval
+	p.heartbeat = config.HeartbeatInterval
+	p.leaseRenew = config.LeaseRenewInterval
+	p.leaseDuration = config.LeaseDuration
+	p.internalIP = config.InternalIP
+	p.externalAddress = config.ExternalAddress
+	p.hostname = config.Hostname
+	p.apiVersions = config.APIVersions
+	p.userAgentClusterID = config.UserAgentClusterID
+	p.userAgentSuffix = config.UserAgentSuffix
+	p.listByNodeTag = config.ListByNodeTag
+	p.allowedNamespaces = config.AllowedNamespaces
+	p.deniedNamespaces = config.DeniedNamespaces
+	p.budgets = config.NamespaceBudgets
+	p.emptyDirMaxSize = config.EmptyDirMaxSize
+	p.scratchAccount = config.ScratchAccount
+	p.scratchAccountRG = config.ScratchAccountRG
+	p.volumeInitImage = config.VolumeInitImage
+	p.gitRepoImage = config.GitRepoImage
+	p.csiSecretsStore = config.CSISecretsStore
+	p.volumeStatsEnabled = config.VolumeStats
+	p.unsupportedFields = config.UnsupportedFields
+	p.portExposure = config.PortExposure
+	p.backendPoolsEnabled = config.BackendPools
+	p.manageSubnetConfig = config.ManageSubnet
+	p.manageNSG = config.ManageNSG
+	p.nsgID = config.NSGID
+	p.controlPlaneProxy = config.ControlPlaneProxy
+	p.dataPlaneHosts = config.DataPlaneHosts
+	p.dnsLabelScope = config.DNSNameLabelScope
+	p.deleteConcurrency = config.DeleteConcurrency
+	p.metricsAggregation = config.MetricsAggregation
+	p.metricsWindowConfig = config.MetricsWindow
+	p.onlyCPUAndMemory = config.OnlyCPUAndMemory
+	p.metricsFallback = config.MetricsFallback
+	p.noMetricsNamespaces = config.ExcludeFromMetrics
+	p.crashLoopPolicy = config.CrashLoopPolicy
+	p.crashLoopRestarts = config.CrashLoopRestarts
+	p.activityLogConfig = config.ActivityLog
+	p.policyCheck = config.PolicyCheck
+	p.permissionCheck = config.PermissionCheck
+	p.credentials = config.Credentials
+	p.stateKeyFile = config.StateKeyFile
+	p.stateKeyVaultKey = config.StateKeyVaultKey
+	p.seccompProfileDir = config.SeccompProfileDir
+	p.extensions = config.Extensions
+	p.podConfigsEnabled = config.PodConfigs
+	p.hookURL = config.HookURL
+	p.hookCAFile = config.HookCAFile
+	p.hookTimeout = config.HookTimeout
+	p.hookFailurePolicy = config.HookFailurePolicy
+	p.faults = config.Faults
+	p.recordingDir = config.RecordingDir
+	p.spotPreemption = config.SpotPreemption
+
+	if len(config.Diagnostics) > 0 {
+		p.namespaceDiagnostics = make(map[string]*aci.ContainerGroupDiagnostics, len(config.Diagnostics))
+	}
+	for namespace, d := range config.Diagnostics {
+		diagnostics, err := d.containerGroupDiagnostics()
+		if err != nil {
+			return fmt.Errorf("invalid diagnostics for namespace %q: %v", namespace, err)
+		}
+		p.namespaceDiagnostics[namespace] = diagnostics
+	}
 	p.operatingSystem = config.OperatingSystem
 	return nil
 }
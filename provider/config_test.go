@@ -4,6 +4,9 @@ import (
 	"bytes"
 	"strings"
 	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
 )
 
 const cfg = `
@@ -92,3 +95,284 @@ func TestDefaultedConfig(t *testing.T) {
 		t.Errorf("Wanted default %s, got %s.", wanted, p.pods)
 	}
 }
+
+const overheadCfg = `
+Region = "westus"
+ResourceGroup = "virtual-kubeletrg"
+PodOverheadCPU = "250m"
+PodOverheadMemory = "256Mi"`
+
+func TestPodOverheadConfig(t *testing.T) {
+	br := bytes.NewReader([]byte(overheadCfg))
+	var p ACIProvider
+	if err := p.loadConfig(br); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.setupPodOverhead(); err != nil {
+		t.Fatal(err)
+	}
+
+	cpu := p.podOverhead[v1.ResourceCPU]
+	if cpu.String() != "250m" {
+		t.Errorf("Wanted pod overhead CPU 250m, got %s.", cpu.String())
+	}
+
+	memory := p.podOverhead[v1.ResourceMemory]
+	if memory.String() != "256Mi" {
+		t.Errorf("Wanted pod overhead memory 256Mi, got %s.", memory.String())
+	}
+}
+
+const runtimeClassCfg = `
+Region = "westus"
+ResourceGroup = "virtual-kubeletrg"
+
+[RuntimeClasses.confidential]
+SKU = "Confidential"
+
+[RuntimeClasses.windows]
+OperatingSystem = "Windows"`
+
+func TestRuntimeClassConfig(t *testing.T) {
+	br := bytes.NewReader([]byte(runtimeClassCfg))
+	var p ACIProvider
+	if err := p.loadConfig(br); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(p.runtimeClassProfiles) != 2 {
+		t.Fatalf("Wanted 2 runtime class profiles, got %d.", len(p.runtimeClassProfiles))
+	}
+
+	if sku := p.runtimeClassProfiles["confidential"].containerGroupSku(); sku != "Confidential" {
+		t.Errorf("Wanted SKU Confidential, got %s.", sku)
+	}
+
+	if os := p.runtimeClassProfiles["windows"].OperatingSystem; os != "Windows" {
+		t.Errorf("Wanted operating system Windows, got %s.", os)
+	}
+}
+
+const runtimeClassCfgBad = `
+Region = "westus"
+ResourceGroup = "virtual-kubeletrg"
+
+[RuntimeClasses.confidential]
+SKU = "Premium"`
+
+func TestBadRuntimeClassConfig(t *testing.T) {
+	br := bytes.NewReader([]byte(runtimeClassCfgBad))
+	var p ACIProvider
+	err := p.loadConfig(br)
+	if err == nil {
+		t.Fatal("expected loadConfig to fail with bad runtime class SKU")
+	}
+
+	if !strings.Contains(err.Error(), "is not a valid container group SKU") {
+		t.Fatalf("expected loadConfig to fail with 'is not a valid container group SKU' but got: %v", err)
+	}
+}
+
+const registryMirrorsCfg = `
+Region = "westus"
+ResourceGroup = "virtual-kubeletrg"
+
+[RegistryMirrors]
+"docker.io" = "mirror.azurecr.io/dockerhub"`
+
+func TestRegistryMirrorsConfig(t *testing.T) {
+	br := bytes.NewReader([]byte(registryMirrorsCfg))
+	var p ACIProvider
+	if err := p.loadConfig(br); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.setupRegistryMirrors(); err != nil {
+		t.Fatal(err)
+	}
+
+	wanted := "mirror.azurecr.io/dockerhub"
+	if mirror := p.registryMirrors["docker.io"]; mirror != wanted {
+		t.Errorf("Wanted mirror %s, got %s.", wanted, mirror)
+	}
+}
+
+const diagnosticsCfg = `
+Region = "westus"
+ResourceGroup = "virtual-kubeletrg"
+
+[Diagnostics.team-a]
+WorkspaceID = "workspace-a"
+WorkspaceKey = "key-a"
+LogType = "ContainerInsights"
+
+[Diagnostics.team-a.Metadata]
+team = "a"
+
+[Diagnostics.team-b]
+Disabled = true`
+
+func TestNamespaceDiagnosticsConfig(t *testing.T) {
+	br := bytes.NewReader([]byte(diagnosticsCfg))
+	var p ACIProvider
+	if err := p.loadConfig(br); err != nil {
+		t.Fatal(err)
+	}
+
+	pod := &v1.Pod{}
+	pod.Namespace = "team-a"
+	pod.UID = "uid-a"
+	d := p.getDiagnostics(pod)
+	if d == nil || d.LogAnalytics == nil {
+		t.Fatal("Wanted diagnostics for namespace team-a.")
+	}
+	if d.LogAnalytics.WorkspaceID != "workspace-a" {
+		t.Errorf("Wanted workspace workspace-a, got %s.", d.LogAnalytics.WorkspaceID)
+	}
+	if d.LogAnalytics.Metadata["team"] != "a" || d.LogAnalytics.Metadata["pod-uuid"] != "uid-a" {
+		t.Errorf("Unexpected metadata %v.", d.LogAnalytics.Metadata)
+	}
+	if _, ok := p.namespaceDiagnostics["team-a"].LogAnalytics.Metadata["pod-uuid"]; ok {
+		t.Error("The namespace diagnostics must not be modified.")
+	}
+
+	pod.Namespace = "team-b"
+	if d := p.getDiagnostics(pod); d != nil {
+		t.Errorf("Wanted no diagnostics for namespace team-b, got %v.", d)
+	}
+}
+
+const diagnosticsCfgBad = `
+Region = "westus"
+ResourceGroup = "virtual-kubeletrg"
+
+[Diagnostics.team-a]
+WorkspaceID = "workspace-a"`
+
+func TestBadNamespaceDiagnosticsConfig(t *testing.T) {
+	br := bytes.NewReader([]byte(diagnosticsCfgBad))
+	var p ACIProvider
+	err := p.loadConfig(br)
+	if err == nil {
+		t.Fatal("expected loadConfig to fail with a missing workspace key")
+	}
+
+	if !strings.Contains(err.Error(), "requires both the workspace ID and Key") {
+		t.Fatalf("expected loadConfig to fail with 'requires both the workspace ID and Key' but got: %v", err)
+	}
+}
+
+const statusSyncCfg = `
+Region = "westus"
+ResourceGroup = "virtual-kubeletrg"
+StatusSyncInterval = "30s"
+StatusSyncJitter = 0.5`
+
+func TestStatusSyncConfig(t *testing.T) {
+	br := bytes.NewReader([]byte(statusSyncCfg))
+	var p ACIProvider
+	if err := p.loadConfig(br); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.setupStatusSync(); err != nil {
+		t.Fatal(err)
+	}
+
+	if p.updatesInterval != 30*time.Second {
+		t.Errorf("Wanted status sync interval 30s, got %v.", p.updatesInterval)
+	}
+	if p.updatesJitter != 0.5 {
+		t.Errorf("Wanted status sync jitter 0.5, got %v.", p.updatesJitter)
+	}
+}
+
+const transportCfg = `
+Region = "westus"
+ResourceGroup = "virtual-kubeletrg"
+HTTPKeepAlives = true
+HTTPMaxIdleConns = 50
+HTTPIdleTimeout = "90s"
+HTTP2 = true`
+
+func TestTransportConfig(t *testing.T) {
+	br := bytes.NewReader([]byte(transportCfg))
+	var p ACIProvider
+	if err := p.loadConfig(br); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.setupTransport(); err != nil {
+		t.Fatal(err)
+	}
+
+	if !p.transportOptions.KeepAlives {
+		t.Error("Wanted keep-alives enabled.")
+	}
+	if p.transportOptions.MaxIdleConnsPerHost != 50 {
+		t.Errorf("Wanted 50 idle connections per host, got %d.", p.transportOptions.MaxIdleConnsPerHost)
+	}
+	if p.transportOptions.IdleConnTimeout != 90*time.Second {
+		t.Errorf("Wanted idle connection timeout 90s, got %v.", p.transportOptions.IdleConnTimeout)
+	}
+	if !p.transportOptions.HTTP2 {
+		t.Error("Wanted HTTP/2 enabled.")
+	}
+}
+
+const nodeConfigCfg = `
+Region = "westus"
+ResourceGroup = "virtual-kubeletrg"
+Zone = "westus-1"
+
+[NodeLabels]
+team = "a"
+cost-center = "1234"
+
+[[NodeTaints]]
+Key = "team"
+Value = "a"
+Effect = "NoSchedule"`
+
+func TestNodeConfig(t *testing.T) {
+	br := bytes.NewReader([]byte(nodeConfigCfg))
+	var p ACIProvider
+	if err := p.loadConfig(br); err != nil {
+		t.Fatal(err)
+	}
+
+	node := &v1.Node{}
+	if !p.applyNodeConfig(node) {
+		t.Fatal("Wanted the node to change.")
+	}
+	if node.Labels["team"] != "a" || node.Labels["cost-center"] != "1234" {
+		t.Errorf("Wanted the configured labels, got %v.", node.Labels)
+	}
+	if node.Labels[regionLabel] != "westus" || node.Labels[zoneLabel] != "westus-1" {
+		t.Errorf("Wanted the topology labels, got %v.", node.Labels)
+	}
+	if len(node.Spec.Taints) != 1 || node.Spec.Taints[0].Effect != v1.TaintEffectNoSchedule {
+		t.Errorf("Wanted the configured taint, got %v.", node.Spec.Taints)
+	}
+	if p.applyNodeConfig(node) {
+		t.Error("Wanted the node to be unchanged once configured.")
+	}
+}
+
+const nodeConfigCfgBad = `
+Region = "westus"
+ResourceGroup = "virtual-kubeletrg"
+
+[[NodeTaints]]
+Key = "team"
+Effect = "Evict"`
+
+func TestBadNodeConfig(t *testing.T) {
+	br := bytes.NewReader([]byte(nodeConfigCfgBad))
+	var p ACIProvider
+	err := p.loadConfig(br)
+	if err == nil {
+		t.Fatal("expected loadConfig to fail with an invalid taint effect")
+	}
+
+	if !strings.Contains(err.Error(), "invalid effect") {
+		t.Fatalf("expected loadConfig to fail with 'invalid effect' but got: %v", err)
+	}
+}
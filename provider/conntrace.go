@@ -0,0 +1,84 @@
+package provider
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/virtual-kubelet/azure-aci/client/aci"
+)
+
+var connectionBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5}
+
+var (
+	armDNSDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "aci",
+		Name:      "arm_dns_duration_seconds",
+		Help:      "Duration of the name resolutions of ARM for new connections.",
+		Buckets:   connectionBuckets,
+	}, []string{"operation"})
+	armConnectDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "aci",
+		Name:      "arm_connect_duration_seconds",
+		Help:      "Duration of the TCP connections to ARM.",
+		Buckets:   connectionBuckets,
+	}, []string{"operation"})
+	armTLSHandshakeDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "aci",
+		Name:      "arm_tls_handshake_duration_seconds",
+		Help:      "Duration of the TLS handshakes with ARM.",
+		Buckets:   connectionBuckets,
+	}, []string{"operation"})
+	armServerDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "aci",
+		Name:      "arm_server_duration_seconds",
+		Help:      "Duration from a request written to ARM to the first byte of its response.",
+		Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"operation"})
+	armConnections = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "aci",
+		Name:      "arm_connections_total",
+		Help:      "Requests to ARM, by whether they reused an idle connection.",
+	}, []string{"operation", "reused"})
+)
+
+func init() {
+	prometheus.MustRegister(armDNSDuration, armConnectDuration, armTLSHandshakeDuration, armServerDuration, armConnections)
+}
+
+// setupConnectionTracing traces the connections of the requests of the ACI client to ARM when HTTPTrace is set
+// in the config file or ACI_HTTP_TRACE, and exposes their DNS, connection, TLS handshake and server times and the
+// reuse of the connections as Prometheus metrics, to tell a slow network from a slow ACI.
+func (p *ACIProvider) setupConnectionTracing() error {
+	if v := os.Getenv("ACI_HTTP_TRACE"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid ACI_HTTP_TRACE %q: %v", v, err)
+		}
+		p.httpTrace = b
+	}
+	if p.httpTrace {
+		p.aciClient.EnableConnectionTracing(observeConnectionTrace)
+	}
+	return nil
+}
+
+func observeConnectionTrace(trace aci.ConnectionTrace) {
+	operation := string(trace.Operation)
+	armConnections.WithLabelValues(operation, strconv.FormatBool(trace.Reused)).Inc()
+	if !trace.Reused {
+		if trace.DNS > 0 {
+			armDNSDuration.WithLabelValues(operation).Observe(trace.DNS.Seconds())
+		}
+		if trace.Connect > 0 {
+			armConnectDuration.WithLabelValues(operation).Observe(trace.Connect.Seconds())
+		}
+		if trace.TLSHandshake > 0 {
+			armTLSHandshakeDuration.WithLabelValues(operation).Observe(trace.TLSHandshake.Seconds())
+		}
+	}
+	if trace.ServerTime > 0 {
+		armServerDuration.WithLabelValues(operation).Observe(trace.ServerTime.Seconds())
+	}
+}
@@ -0,0 +1,123 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	client "github.com/virtual-kubelet/azure-aci/client"
+	"github.com/virtual-kubelet/azure-aci/client/aci"
+	"github.com/virtual-kubelet/azure-aci/client/costmanagement"
+	"github.com/virtual-kubelet/virtual-kubelet/log"
+	"github.com/virtual-kubelet/virtual-kubelet/trace"
+)
+
+// namespaceCost is the actual cost of the month of the container groups of a namespace, as reported
+// by Azure Cost Management from the Namespace tag of the container groups.
+var namespaceCost = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: "aci",
+	Name:      "namespace_cost_month_to_date",
+	Help:      "Actual cost of the month of the container groups of the namespace, reported by Azure Cost Management.",
+}, []string{"namespace", "currency"})
+
+// namespaceContainerGroups is the number of running container groups of a namespace, reported along
+// with the costs to correlate them.
+var namespaceContainerGroups = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: "aci",
+	Name:      "namespace_container_groups",
+	Help:      "Number of running container groups of the namespace.",
+}, []string{"namespace"})
+
+func init() {
+	prometheus.MustRegister(namespaceCost, namespaceContainerGroups)
+}
+
+// costSource reports the costs of the container groups of the node.
+type costSource interface {
+	QueryMonthToDateCostsByTag(ctx context.Context, resourceGroup, filterTag, filterValue, groupTag string) ([]costmanagement.TagCost, error)
+}
+
+// setupCostReport enables reporting the costs of the namespaces every ACI_COST_REPORT_INTERVAL.
+func (p *ACIProvider) setupCostReport(azAuth *client.Authentication) error {
+	interval := os.Getenv("ACI_COST_REPORT_INTERVAL")
+	if interval == "" {
+		return nil
+	}
+
+	d, err := time.ParseDuration(interval)
+	if err != nil || d <= 0 {
+		return fmt.Errorf("invalid ACI_COST_REPORT_INTERVAL %q, expected a positive duration", interval)
+	}
+	p.costReportInterval = d
+
+	p.costSource, err = costmanagement.NewClient(azAuth, p.extraUserAgent)
+	if err != nil {
+		return fmt.Errorf("error setting up cost report: %v", err)
+	}
+	return nil
+}
+
+// costReportLoop periodically reports the costs of the namespaces until the context is done.
+func (p *ACIProvider) costReportLoop(ctx context.Context) {
+	ticker := time.NewTicker(p.costReportInterval)
+	defer ticker.Stop()
+
+	for {
+		p.reportCosts(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// reportCosts updates the costs and the running container groups of the namespaces. The costs of
+// Azure Cost Management lag by several hours, so a namespace with running container groups and no
+// cost yet is only logged.
+func (p *ACIProvider) reportCosts(ctx context.Context) {
+	ctx, span := trace.StartSpan(ctx, "aci.reportCosts")
+	defer span.End()
+	ctx = addAzureAttributes(ctx, span, p)
+
+	running := make(map[string]int)
+	err := p.visitNodeContainerGroups(ctx, func(cg *aci.ContainerGroup) error {
+		switch state, _ := aciResourceMetaFromContainerGroup(cg); state {
+		case aciStateStopped, "Succeeded", "Failed":
+			return nil
+		}
+		running[cg.Tags["Namespace"]]++
+		return nil
+	})
+	if err != nil {
+		log.G(ctx).WithError(err).Warn("failed to list the container groups to report the costs")
+		return
+	}
+
+	costs, err := p.costSource.QueryMonthToDateCostsByTag(ctx, p.resourceGroup, "NodeName", p.nodeName, "Namespace")
+	if err != nil {
+		log.G(ctx).WithError(err).Warn("failed to query the costs of the container groups")
+		return
+	}
+
+	namespaceContainerGroups.Reset()
+	for namespace, n := range running {
+		namespaceContainerGroups.WithLabelValues(namespace).Set(float64(n))
+	}
+
+	namespaceCost.Reset()
+	for _, c := range costs {
+		if c.TagValue == "" {
+			log.G(ctx).Debugf("%g %s of container groups without a namespace tag", c.Cost, c.Currency)
+			continue
+		}
+		namespaceCost.WithLabelValues(c.TagValue, c.Currency).Set(c.Cost)
+		delete(running, c.TagValue)
+	}
+	for namespace := range running {
+		log.G(ctx).Debugf("no cost reported yet for the running container groups of namespace %s", namespace)
+	}
+}
@@ -0,0 +1,50 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/virtual-kubelet/azure-aci/client/aci"
+	"github.com/virtual-kubelet/azure-aci/client/costmanagement"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+type fakeCostSource struct {
+	costs []costmanagement.TagCost
+}
+
+func (s *fakeCostSource) QueryMonthToDateCostsByTag(ctx context.Context, resourceGroup, filterTag, filterValue, groupTag string) ([]costmanagement.TagCost, error) {
+	if filterTag != "NodeName" || filterValue != fakeNodeName || groupTag != "Namespace" {
+		return nil, nil
+	}
+	return s.costs, nil
+}
+
+func TestReportCosts(t *testing.T) {
+	_, aciServerMocker, provider, err := prepareMocks()
+	if err != nil {
+		t.Fatal("Unable to prepare the mocks", err)
+	}
+	provider.costSource = &fakeCostSource{costs: []costmanagement.TagCost{
+		{TagValue: "team-a", Cost: 12.5, Currency: "USD"},
+		{TagValue: "", Cost: 0.5, Currency: "USD"},
+	}}
+
+	aciServerMocker.OnGetContainerGroups = func(subscription, resourceGroup string) (int, interface{}) {
+		return http.StatusOK, aci.ContainerGroupListResult{
+			Value: []aci.ContainerGroup{
+				budgetContainerGroup("team-a", "web", "Running", 1, 1.5),
+				budgetContainerGroup("team-a", "job", "Succeeded", 1, 1.5),
+				budgetContainerGroup("team-b", "web", "Running", 1, 1.5),
+			},
+		}
+	}
+
+	provider.reportCosts(context.Background())
+	assert.Check(t, is.Equal(12.5, testutil.ToFloat64(namespaceCost.WithLabelValues("team-a", "USD"))))
+	assert.Check(t, is.Equal(float64(1), testutil.ToFloat64(namespaceContainerGroups.WithLabelValues("team-a"))))
+	assert.Check(t, is.Equal(float64(1), testutil.ToFloat64(namespaceContainerGroups.WithLabelValues("team-b"))))
+}
@@ -0,0 +1,153 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/virtual-kubelet/azure-aci/client/aci"
+	"github.com/virtual-kubelet/virtual-kubelet/log"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// crashLoopPolicyReport only reports the crash looping containers.
+	crashLoopPolicyReport = "Report"
+	// crashLoopPolicyStop stops the container groups whose containers keep crashing, and fails their pods.
+	crashLoopPolicyStop = "Stop"
+
+	defaultCrashLoopRestarts = 10
+
+	containerWaitingReasonCrashLoopBackOff = "CrashLoopBackOff"
+	eventReasonCrashLoopStopped            = "CrashLoopStopped"
+
+	// crashLoopStoppedTag marks the container groups stopped because of a crash loop.
+	crashLoopStoppedTag = "CrashLoopStopped"
+)
+
+// setupCrashLoops validates the crash loop policy and its restarts threshold, from CrashLoopPolicy and
+// CrashLoopRestarts in the config file or the ACI_CRASH_LOOP_POLICY and ACI_CRASH_LOOP_RESTARTS environment
+// variables.
+func (p *ACIProvider) setupCrashLoops() error {
+	if v := os.Getenv("ACI_CRASH_LOOP_POLICY"); v != "" {
+		p.crashLoopPolicy = v
+	}
+	switch {
+	case p.crashLoopPolicy == "":
+		p.crashLoopPolicy = crashLoopPolicyReport
+	case strings.EqualFold(p.crashLoopPolicy, crashLoopPolicyReport):
+		p.crashLoopPolicy = crashLoopPolicyReport
+	case strings.EqualFold(p.crashLoopPolicy, crashLoopPolicyStop):
+		p.crashLoopPolicy = crashLoopPolicyStop
+	default:
+		return fmt.Errorf("invalid crash loop policy %q, expected %s or %s", p.crashLoopPolicy, crashLoopPolicyReport, crashLoopPolicyStop)
+	}
+
+	p.crashLoopMaxRestarts = defaultCrashLoopRestarts
+	if p.crashLoopRestarts != nil {
+		p.crashLoopMaxRestarts = *p.crashLoopRestarts
+	}
+	if v := os.Getenv("ACI_CRASH_LOOP_RESTARTS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("invalid ACI_CRASH_LOOP_RESTARTS %q: %v", v, err)
+		}
+		p.crashLoopMaxRestarts = n
+	}
+	if p.crashLoopMaxRestarts <= 0 {
+		return fmt.Errorf("invalid crash loop restarts %d, expected a positive number", p.crashLoopMaxRestarts)
+	}
+	return nil
+}
+
+// crashLoopWaiting returns the CrashLoopBackOff waiting state of a container which ACI keeps re-launching after
+// it fails, like the kubelet reports it, or nil if the container is not crash looping.
+func crashLoopWaiting(c aci.Container) *v1.ContainerStateWaiting {
+	iv := c.InstanceView
+	if iv.RestartCount == 0 {
+		return nil
+	}
+	switch iv.CurrentState.State {
+	case "Running", "Succeeded":
+		return nil
+	}
+
+	if strings.HasPrefix(iv.CurrentState.DetailStatus, containerWaitingReasonCrashLoopBackOff) {
+		return &v1.ContainerStateWaiting{Reason: containerWaitingReasonCrashLoopBackOff, Message: iv.CurrentState.DetailStatus}
+	}
+	exitCode := iv.CurrentState.ExitCode
+	if exitCode == 0 {
+		exitCode = iv.PreviousState.ExitCode
+	}
+	if exitCode == 0 && !hasCrashBackOffEvent(iv.Events) {
+		return nil
+	}
+	return &v1.ContainerStateWaiting{
+		Reason:  containerWaitingReasonCrashLoopBackOff,
+		Message: fmt.Sprintf("back-off restarting failed container %s, exit code %d", c.Name, exitCode),
+	}
+}
+
+// hasCrashBackOffEvent reports whether ACI backs off re-launching a failed container, rather than pulling its image.
+func hasCrashBackOffEvent(events []aci.Event) bool {
+	for i := range events {
+		if events[i].Name == aciEventBackOff && !isImagePullEvent(&events[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkCrashLoop stops the container group of a pod whose containers crash looped more than the crash loop restarts
+// with the Stop policy, and fails the pod. The container group is not started again, the pod must be replaced.
+func (p *ACIProvider) checkCrashLoop(ctx context.Context, namespace, name string, cg *aci.ContainerGroup, status *v1.PodStatus) {
+	if p.crashLoopPolicy != crashLoopPolicyStop {
+		return
+	}
+
+	if cg.Tags[crashLoopStoppedTag] != "" {
+		status.Phase = v1.PodFailed
+		status.Reason = containerWaitingReasonCrashLoopBackOff
+		status.Message = cg.Tags[crashLoopStoppedTag]
+		return
+	}
+
+	var crashing *v1.ContainerStatus
+	for i := range status.ContainerStatuses {
+		cs := &status.ContainerStatuses[i]
+		if cs.State.Waiting != nil && cs.State.Waiting.Reason == containerWaitingReasonCrashLoopBackOff && int(cs.RestartCount) >= p.crashLoopMaxRestarts {
+			crashing = cs
+			break
+		}
+	}
+	if crashing == nil {
+		return
+	}
+
+	message := fmt.Sprintf("Container %s restarted %d times, stopped container group %s at %s", crashing.Name, crashing.RestartCount, cg.Name, time.Now().UTC().Format(time.RFC3339))
+	tags := make(map[string]string, len(cg.Tags)+1)
+	for k, v := range cg.Tags {
+		tags[k] = v
+	}
+	tags[crashLoopStoppedTag] = message
+	if err := p.aciClient.UpdateContainerGroupTags(ctx, p.resourceGroup, cg.Name, tags); err != nil {
+		log.G(ctx).WithError(err).Errorf("failed to mark crash looping container group %v", cg.Name)
+		return
+	}
+	if err := p.aciClient.StopContainerGroup(ctx, p.resourceGroup, cg.Name); err != nil {
+		log.G(ctx).WithError(err).Errorf("failed to stop crash looping container group %v", cg.Name)
+	}
+
+	log.G(ctx).WithField("pod", name).WithField("namespace", namespace).Warn(message)
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, UID: types.UID(cg.Tags["UID"])}}
+	p.recordEvent(pod, v1.EventTypeWarning, eventReasonCrashLoopStopped, "%s", message)
+
+	status.Phase = v1.PodFailed
+	status.Reason = containerWaitingReasonCrashLoopBackOff
+	status.Message = message
+}
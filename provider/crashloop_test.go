@@ -0,0 +1,79 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/virtual-kubelet/azure-aci/client/aci"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestCrashLoopWaiting(t *testing.T) {
+	c := aci.Container{Name: "web"}
+	c.InstanceView.CurrentState = aci.ContainerState{State: "Terminated", ExitCode: 1}
+	assert.Check(t, crashLoopWaiting(c) == nil, "A container which never restarted is not crash looping")
+
+	c.InstanceView.RestartCount = 3
+	waiting := crashLoopWaiting(c)
+	assert.Assert(t, waiting != nil)
+	assert.Check(t, is.Equal(waiting.Reason, containerWaitingReasonCrashLoopBackOff))
+
+	c.InstanceView.CurrentState = aci.ContainerState{State: "Waiting"}
+	c.InstanceView.PreviousState = aci.ContainerState{State: "Terminated", ExitCode: 0}
+	assert.Check(t, crashLoopWaiting(c) == nil, "A container which exited successfully is not crash looping")
+
+	c.InstanceView.Events = []aci.Event{{Name: aciEventBackOff, Message: "Back-off restarting failed container"}}
+	assert.Check(t, crashLoopWaiting(c) != nil)
+
+	c.InstanceView.CurrentState = aci.ContainerState{State: "Running"}
+	assert.Check(t, crashLoopWaiting(c) == nil, "A running container is not waiting")
+}
+
+func TestCheckCrashLoop(t *testing.T) {
+	os.Setenv("ACI_CRASH_LOOP_POLICY", "stop")
+	defer os.Unsetenv("ACI_CRASH_LOOP_POLICY")
+	_, aciServerMocker, provider, err := prepareMocks()
+	if err != nil {
+		t.Fatal("Unable to prepare the mocks", err)
+	}
+	assert.Check(t, is.Equal(provider.crashLoopPolicy, crashLoopPolicyStop))
+
+	var tags map[string]string
+	aciServerMocker.OnUpdateTags = func(subscription, resourceGroup, containerGroup string, t map[string]string) (int, interface{}) {
+		tags = t
+		return http.StatusOK, nil
+	}
+	stops := 0
+	aciServerMocker.OnAction = func(subscription, resourceGroup, containerGroup, action string) (int, interface{}) {
+		assert.Check(t, is.Equal("stop", action))
+		stops++
+		return http.StatusNoContent, nil
+	}
+
+	cg := &aci.ContainerGroup{Name: "ns-pod", Tags: map[string]string{"UID": "uid"}}
+	waiting := &v1.ContainerStateWaiting{Reason: containerWaitingReasonCrashLoopBackOff}
+	status := &v1.PodStatus{Phase: v1.PodRunning, ContainerStatuses: []v1.ContainerStatus{{Name: "web", RestartCount: 3, State: v1.ContainerState{Waiting: waiting}}}}
+	provider.checkCrashLoop(context.Background(), "ns", "pod", cg, status)
+	assert.Check(t, is.Equal(stops, 0), "The container group should only be stopped after the crash loop restarts")
+
+	status.ContainerStatuses[0].RestartCount = defaultCrashLoopRestarts
+	provider.checkCrashLoop(context.Background(), "ns", "pod", cg, status)
+	assert.Check(t, is.Equal(stops, 1))
+	assert.Check(t, tags[crashLoopStoppedTag] != "")
+	assert.Check(t, is.Equal(status.Phase, v1.PodFailed))
+
+	// The stopped container group keeps failing its pod.
+	cg.Tags = tags
+	status = &v1.PodStatus{Phase: v1.PodPending}
+	provider.checkCrashLoop(context.Background(), "ns", "pod", cg, status)
+	assert.Check(t, is.Equal(stops, 1))
+	assert.Check(t, is.Equal(status.Reason, containerWaitingReasonCrashLoopBackOff))
+
+	os.Setenv("ACI_CRASH_LOOP_RESTARTS", "0")
+	defer os.Unsetenv("ACI_CRASH_LOOP_RESTARTS")
+	assert.Check(t, provider.setupCrashLoops() != nil, "expected an error for no restarts")
+}
@@ -0,0 +1,130 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/virtual-kubelet/virtual-kubelet/log"
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	createPhaseTranslation  = "translation"
+	createPhaseARMAccept    = "arm_accept"
+	createPhaseProvisioning = "provisioning"
+	createPhaseTotal        = "total"
+
+	eventReasonProvisioned = "Provisioned"
+)
+
+// podCreateDuration is the duration of the phases of the creation of a pod: the translation of the pod
+// to a container group, ARM accepting the container group, its provisioning until the pod runs, and
+// the total from CreatePod to the pod running.
+var podCreateDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: "aci",
+	Name:      "pod_create_duration_seconds",
+	Help:      "Duration of the phases of the creation of a pod, from CreatePod to the pod running.",
+	Buckets:   []float64{0.1, 0.5, 1, 2.5, 5, 10, 20, 30, 45, 60, 90, 120, 180, 300, 600},
+}, []string{"phase"})
+
+func init() {
+	prometheus.MustRegister(podCreateDuration)
+}
+
+// setupPrometheus serves the Prometheus metrics of the provider on /metrics at the ACI_PROMETHEUS_ADDR address, if set.
+func (p *ACIProvider) setupPrometheus(ctx context.Context) {
+	addr := os.Getenv("ACI_PROMETHEUS_ADDR")
+	if addr == "" {
+		return
+	}
+	p.registerSoakCollector(ctx)
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	go func() {
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			log.G(ctx).WithError(err).Errorf("failed to serve the Prometheus metrics on %s", addr)
+		}
+	}()
+}
+
+// createTiming are the times a pod was created at, translated at, and accepted by ARM at.
+type createTiming struct {
+	pod        *v1.Pod
+	start      time.Time
+	translated time.Time
+	accepted   time.Time
+}
+
+// createLatencies tracks the pods created until they run, to measure their creation phases.
+type createLatencies struct {
+	mu   sync.Mutex
+	pods map[string]createTiming
+}
+
+func createLatencyKey(namespace, name string) string {
+	return namespace + "/" + name
+}
+
+// created records the translation and ARM accept phases of a pod, and tracks it until it runs.
+func (c *createLatencies) created(pod *v1.Pod, start, translated, accepted time.Time) {
+	podCreateDuration.WithLabelValues(createPhaseTranslation).Observe(translated.Sub(start).Seconds())
+	podCreateDuration.WithLabelValues(createPhaseARMAccept).Observe(accepted.Sub(translated).Seconds())
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.pods == nil {
+		c.pods = make(map[string]createTiming)
+	}
+	c.pods[createLatencyKey(pod.Namespace, pod.Name)] = createTiming{pod: pod, start: start, translated: translated, accepted: accepted}
+}
+
+// started returns the timing of a tracked pod whose containers started with the status, and stops tracking it.
+// Pods which failed before starting are no longer tracked either.
+func (c *createLatencies) started(namespace, name string, status *v1.PodStatus) (createTiming, bool) {
+	if status.Phase == v1.PodPending || status.Phase == "" {
+		return createTiming{}, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := createLatencyKey(namespace, name)
+	timing, ok := c.pods[key]
+	delete(c.pods, key)
+
+	return timing, ok && status.Phase != v1.PodFailed
+}
+
+// remove stops tracking a pod, when it is deleted before it runs.
+func (c *createLatencies) remove(namespace, name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.pods, createLatencyKey(namespace, name))
+}
+
+// observeCreateLatency records the provisioning phase of a pod once it runs, with an event breaking down its creation.
+func (p *ACIProvider) observeCreateLatency(namespace, name string, status *v1.PodStatus) {
+	timing, ok := p.createLatencies.started(namespace, name, status)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	provisioning := now.Sub(timing.accepted)
+	total := now.Sub(timing.start)
+	podCreateDuration.WithLabelValues(createPhaseProvisioning).Observe(provisioning.Seconds())
+	podCreateDuration.WithLabelValues(createPhaseTotal).Observe(total.Seconds())
+
+	p.recordEvent(timing.pod, v1.EventTypeNormal, eventReasonProvisioned,
+		"Container group running after %s: translation %s, ARM accept %s, provisioning %s",
+		total.Round(time.Millisecond), timing.translated.Sub(timing.start).Round(time.Millisecond),
+		timing.accepted.Sub(timing.translated).Round(time.Millisecond), provisioning.Round(time.Millisecond))
+}
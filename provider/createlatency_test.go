@@ -0,0 +1,33 @@
+package provider
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCreateLatencies(t *testing.T) {
+	var c createLatencies
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "pod"}}
+	start := time.Now()
+	c.created(pod, start, start.Add(time.Second), start.Add(3*time.Second))
+
+	_, ok := c.started("ns", "pod", &v1.PodStatus{Phase: v1.PodPending})
+	assert.Check(t, !ok, "A pending pod should not be started")
+
+	timing, ok := c.started("ns", "pod", &v1.PodStatus{Phase: v1.PodRunning})
+	assert.Check(t, ok, "A running pod should be started")
+	assert.Check(t, is.Equal(2*time.Second, timing.accepted.Sub(timing.translated)))
+
+	_, ok = c.started("ns", "pod", &v1.PodStatus{Phase: v1.PodRunning})
+	assert.Check(t, !ok, "A pod should only be started once")
+
+	c.created(pod, start, start, start)
+	_, ok = c.started("ns", "pod", &v1.PodStatus{Phase: v1.PodFailed})
+	assert.Check(t, !ok, "A failed pod should not be started")
+	assert.Check(t, is.Len(c.pods, 0))
+}
@@ -0,0 +1,75 @@
+package provider
+
+import (
+	"fmt"
+	"strings"
+
+	client "github.com/virtual-kubelet/azure-aci/client"
+)
+
+// resourceGroupCredential is the identity of the virtual kubelet for the resources of a resource group other than
+// the one of the container groups, like the virtual network or the storage account of the scratch shares, so each
+// identity only needs roles on its own resource group.
+type resourceGroupCredential struct {
+	// AuthFile is an Azure SDK auth file with the service principal of the resource group.
+	AuthFile string
+	// UserIdentityClientID is the client ID of the user-assigned managed identity of the resource group.
+	UserIdentityClientID string
+}
+
+// authentication returns the authentication of the credential, in the cloud and the subscription of the default one.
+func (c resourceGroupCredential) authentication(defaultAuth *client.Authentication) (*client.Authentication, error) {
+	switch {
+	case c.AuthFile != "" && c.UserIdentityClientID != "":
+		return nil, fmt.Errorf("only one of AuthFile and UserIdentityClientID can be set")
+	case c.AuthFile != "":
+		auth, err := client.NewAuthenticationFromFile(c.AuthFile)
+		if err != nil {
+			return nil, err
+		}
+		if auth.SubscriptionID != "" && !strings.EqualFold(auth.SubscriptionID, defaultAuth.SubscriptionID) {
+			return nil, fmt.Errorf("the subscription %s of auth file %q is not the subscription %s of the node", auth.SubscriptionID, c.AuthFile, defaultAuth.SubscriptionID)
+		}
+		auth.SubscriptionID = defaultAuth.SubscriptionID
+		auth.ControlPlaneProxy = defaultAuth.ControlPlaneProxy
+		return auth, nil
+	case c.UserIdentityClientID != "":
+		auth := *defaultAuth
+		auth.ClientID = ""
+		auth.ClientSecret = ""
+		auth.UseUserIdentity = true
+		auth.UserIdentityClientId = c.UserIdentityClientID
+		return &auth, nil
+	default:
+		return nil, fmt.Errorf("one of AuthFile and UserIdentityClientID must be set")
+	}
+}
+
+// setupCredentials loads the identities of the resource groups from Credentials in the config file. The resource
+// group of the container groups keeps the identity of the node.
+func (p *ACIProvider) setupCredentials(azAuth *client.Authentication) error {
+	if len(p.credentials) == 0 {
+		return nil
+	}
+
+	p.resourceGroupAuth = make(map[string]*client.Authentication, len(p.credentials))
+	for rg, c := range p.credentials {
+		if strings.EqualFold(rg, p.resourceGroup) {
+			return fmt.Errorf("invalid credential for resource group %q: the container groups use the identity of the node", rg)
+		}
+		auth, err := c.authentication(azAuth)
+		if err != nil {
+			return fmt.Errorf("invalid credential for resource group %q: %v", rg, err)
+		}
+		p.resourceGroupAuth[strings.ToLower(rg)] = auth
+	}
+	return nil
+}
+
+// authFor returns the authentication for the resources of a resource group, its own credential if it has one.
+func (p *ACIProvider) authFor(resourceGroup string, azAuth *client.Authentication) *client.Authentication {
+	if auth, ok := p.resourceGroupAuth[strings.ToLower(resourceGroup)]; ok {
+		return auth
+	}
+	return azAuth
+}
@@ -0,0 +1,47 @@
+package provider
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	client "github.com/virtual-kubelet/azure-aci/client"
+	"gotest.tools/assert"
+)
+
+func TestSetupCredentials(t *testing.T) {
+	dir, err := ioutil.TempDir("", "credentials")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+	authFile := filepath.Join(dir, "vnet.json")
+	assert.NilError(t, ioutil.WriteFile(authFile, []byte(`{"clientId": "vnet-sp", "clientSecret": "secret", "subscriptionId": "sub", "tenantId": "tenant"}`), 0600))
+
+	azAuth := client.NewAuthentication("AzurePublicCloud", "node-sp", "secret", "sub", "tenant", "")
+	p := ACIProvider{
+		resourceGroup: "aci-rg",
+		credentials: map[string]resourceGroupCredential{
+			"vnet-rg":    {AuthFile: authFile},
+			"storage-rg": {UserIdentityClientID: "storage-identity"},
+		},
+	}
+	assert.NilError(t, p.setupCredentials(azAuth))
+
+	assert.Equal(t, p.authFor("VNET-RG", azAuth).ClientID, "vnet-sp")
+	storage := p.authFor("storage-rg", azAuth)
+	assert.Assert(t, storage.UseUserIdentity)
+	assert.Equal(t, storage.UserIdentityClientId, "storage-identity")
+	assert.Equal(t, storage.ResourceManagerEndpoint, azAuth.ResourceManagerEndpoint)
+	assert.Equal(t, azAuth.ClientID, "node-sp", "The identity of the node should not be changed")
+	assert.Equal(t, p.authFor("other-rg", azAuth), azAuth)
+
+	p.credentials = map[string]resourceGroupCredential{"aci-rg": {UserIdentityClientID: "identity"}}
+	assert.ErrorContains(t, p.setupCredentials(azAuth), "identity of the node")
+
+	p.credentials = map[string]resourceGroupCredential{"vnet-rg": {}}
+	assert.ErrorContains(t, p.setupCredentials(azAuth), "must be set")
+
+	assert.NilError(t, ioutil.WriteFile(authFile, []byte(`{"clientId": "vnet-sp", "subscriptionId": "other-sub"}`), 0600))
+	p.credentials = map[string]resourceGroupCredential{"vnet-rg": {AuthFile: authFile}}
+	assert.ErrorContains(t, p.setupCredentials(azAuth), "not the subscription")
+}
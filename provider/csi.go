@@ -0,0 +1,189 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	client "github.com/virtual-kubelet/azure-aci/client"
+	"github.com/virtual-kubelet/azure-aci/client/keyvault"
+	"github.com/virtual-kubelet/virtual-kubelet/errdefs"
+	v1 "k8s.io/api/core/v1"
+	k8serr "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	secretsStoreDriverName  = "secrets-store.csi.k8s.io"
+	secretsStoreGroup       = "secrets-store.csi.x-k8s.io"
+	secretsStoreAzure       = "azure"
+	secretProviderClassAttr = "secretProviderClass"
+)
+
+// csiDriver resolves the CSI inline ephemeral volumes of a driver into the files of the volume, for
+// the drivers whose content the provider can fetch itself, since ACI can't run CSI drivers.
+type csiDriver interface {
+	volumeFiles(ctx context.Context, pod *v1.Pod, v *v1.CSIVolumeSource) (map[string][]byte, error)
+}
+
+// setupCSIDrivers registers the CSI drivers resolved by the provider. The secrets store driver is opt-in
+// with ACI_CSI_SECRETS_STORE or the config file, as the Key Vault objects are read with the identity of
+// the virtual kubelet.
+func (p *ACIProvider) setupCSIDrivers(azAuth *client.Authentication) error {
+	if v := os.Getenv("ACI_CSI_SECRETS_STORE"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid ACI_CSI_SECRETS_STORE %q: %v", v, err)
+		}
+		p.csiSecretsStore = b
+	}
+	if !p.csiSecretsStore {
+		return nil
+	}
+	if p.kubeClient == nil {
+		return fmt.Errorf("the secrets store CSI driver requires a kubernetes client to read the SecretProviderClasses")
+	}
+
+	vaults, err := keyvault.NewClient(azAuth, p.extraUserAgent)
+	if err != nil {
+		return fmt.Errorf("error setting up the secrets store CSI driver: %v", err)
+	}
+	store := &secretsStore{vaults: vaults}
+	store.getClass = kubeSecretProviderClassGetter(p.kubeClient)
+	p.csiDrivers = map[string]csiDriver{secretsStoreDriverName: store}
+	return nil
+}
+
+// keyVaultReader reads the objects of Key Vaults.
+type keyVaultReader interface {
+	GetSecret(ctx context.Context, vaultName, name, version string) (*keyvault.SecretBundle, error)
+	GetCertificate(ctx context.Context, vaultName, name, version string) (*keyvault.CertificateBundle, error)
+}
+
+// secretProviderClass is the part of a SecretProviderClass of the secrets store CSI driver read by the provider.
+type secretProviderClass struct {
+	Spec struct {
+		Provider   string            `json:"provider"`
+		Parameters map[string]string `json:"parameters"`
+	} `json:"spec"`
+}
+
+// keyVaultObject is an object of the objects parameter of an Azure SecretProviderClass.
+type keyVaultObject struct {
+	ObjectName    string `json:"objectName"`
+	ObjectType    string `json:"objectType"`
+	ObjectVersion string `json:"objectVersion"`
+	ObjectAlias   string `json:"objectAlias"`
+}
+
+// secretsStore resolves the volumes of the secrets store CSI driver with the Azure provider, by reading
+// the Key Vault objects of their SecretProviderClass.
+type secretsStore struct {
+	vaults   keyVaultReader
+	getClass func(ctx context.Context, namespace, name string) (*secretProviderClass, error)
+}
+
+// kubeSecretProviderClassGetter reads the SecretProviderClasses from the API server, with the v1 or
+// the older v1alpha1 version of their group.
+func kubeSecretProviderClassGetter(kubeClient kubernetes.Interface) func(ctx context.Context, namespace, name string) (*secretProviderClass, error) {
+	return func(ctx context.Context, namespace, name string) (*secretProviderClass, error) {
+		var err error
+		for _, version := range []string{"v1", "v1alpha1"} {
+			var raw []byte
+			raw, err = kubeClient.Discovery().RESTClient().Get().
+				AbsPath("/apis", secretsStoreGroup, version, "namespaces", namespace, "secretproviderclasses", name).
+				DoRaw(ctx)
+			if k8serr.IsNotFound(err) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			var class secretProviderClass
+			if err := json.Unmarshal(raw, &class); err != nil {
+				return nil, fmt.Errorf("invalid SecretProviderClass %s: %v", name, err)
+			}
+			return &class, nil
+		}
+		return nil, err
+	}
+}
+
+// parseKeyVaultObjects parses the objects parameter of an Azure SecretProviderClass, an array of YAML objects.
+func parseKeyVaultObjects(objects string) ([]keyVaultObject, error) {
+	var array struct {
+		Array []string `json:"array"`
+	}
+	if err := yaml.Unmarshal([]byte(objects), &array); err != nil {
+		return nil, err
+	}
+
+	parsed := make([]keyVaultObject, 0, len(array.Array))
+	for _, o := range array.Array {
+		var object keyVaultObject
+		if err := yaml.Unmarshal([]byte(o), &object); err != nil {
+			return nil, err
+		}
+		if object.ObjectName == "" {
+			return nil, fmt.Errorf("object without objectName")
+		}
+		parsed = append(parsed, object)
+	}
+	return parsed, nil
+}
+
+func (s *secretsStore) volumeFiles(ctx context.Context, pod *v1.Pod, v *v1.CSIVolumeSource) (map[string][]byte, error) {
+	className := v.VolumeAttributes[secretProviderClassAttr]
+	if className == "" {
+		return nil, errdefs.InvalidInputf("pod %s requires a secrets store CSI volume without the %s attribute", pod.Name, secretProviderClassAttr)
+	}
+
+	class, err := s.getClass(ctx, pod.Namespace, className)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get SecretProviderClass %s: %v", className, err)
+	}
+	if class.Spec.Provider != secretsStoreAzure {
+		return nil, errdefs.InvalidInputf("SecretProviderClass %s uses the %s provider, only the %s provider is supported", className, class.Spec.Provider, secretsStoreAzure)
+	}
+
+	vault := class.Spec.Parameters["keyvaultName"]
+	objects, err := parseKeyVaultObjects(class.Spec.Parameters["objects"])
+	if err != nil {
+		return nil, errdefs.InvalidInputf("invalid objects of SecretProviderClass %s: %v", className, err)
+	}
+
+	files := make(map[string][]byte, len(objects))
+	for _, o := range objects {
+		name := o.ObjectName
+		if o.ObjectAlias != "" {
+			name = o.ObjectAlias
+		}
+		if strings.Contains(name, "/") {
+			return nil, errdefs.InvalidInputf("invalid file name %q in SecretProviderClass %s", name, className)
+		}
+
+		switch o.ObjectType {
+		case "secret":
+			secret, err := s.vaults.GetSecret(ctx, vault, o.ObjectName, o.ObjectVersion)
+			if err != nil {
+				return nil, fmt.Errorf("unable to get secret %s of Key Vault %s: %v", o.ObjectName, vault, err)
+			}
+			files[name] = []byte(secret.Value)
+		case "cert":
+			cert, err := s.vaults.GetCertificate(ctx, vault, o.ObjectName, o.ObjectVersion)
+			if err != nil {
+				return nil, fmt.Errorf("unable to get certificate %s of Key Vault %s: %v", o.ObjectName, vault, err)
+			}
+			files[name] = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Cer})
+		default:
+			return nil, errdefs.InvalidInputf("object %s of SecretProviderClass %s has the %q type, only secret and cert objects are supported", o.ObjectName, className, o.ObjectType)
+		}
+	}
+	return files, nil
+}
@@ -0,0 +1,75 @@
+package provider
+
+import (
+	"context"
+	"encoding/pem"
+	"fmt"
+	"testing"
+
+	"github.com/virtual-kubelet/azure-aci/client/keyvault"
+	"github.com/virtual-kubelet/virtual-kubelet/errdefs"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type fakeKeyVault struct{}
+
+func (fakeKeyVault) GetSecret(ctx context.Context, vaultName, name, version string) (*keyvault.SecretBundle, error) {
+	if vaultName != "kv" {
+		return nil, fmt.Errorf("unknown vault %s", vaultName)
+	}
+	return &keyvault.SecretBundle{Value: name + "@" + version}, nil
+}
+
+func (fakeKeyVault) GetCertificate(ctx context.Context, vaultName, name, version string) (*keyvault.CertificateBundle, error) {
+	return &keyvault.CertificateBundle{Cer: []byte{0x30, 0x82}}, nil
+}
+
+const fakeKeyVaultObjects = `array:
+  - |
+    objectName: db-password
+    objectType: secret
+    objectVersion: "1"
+  - |
+    objectName: tls
+    objectType: cert
+    objectAlias: tls.crt
+`
+
+func TestSecretsStoreVolumeFiles(t *testing.T) {
+	store := &secretsStore{
+		vaults: fakeKeyVault{},
+		getClass: func(ctx context.Context, namespace, name string) (*secretProviderClass, error) {
+			class := &secretProviderClass{}
+			class.Spec.Provider = "azure"
+			class.Spec.Parameters = map[string]string{"keyvaultName": "kv", "objects": fakeKeyVaultObjects}
+			return class, nil
+		},
+	}
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "nginx", Namespace: "default"}}
+
+	files, err := store.volumeFiles(context.Background(), pod, &v1.CSIVolumeSource{
+		Driver:           secretsStoreDriverName,
+		VolumeAttributes: map[string]string{secretProviderClassAttr: "app"},
+	})
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal("db-password@1", string(files["db-password"])))
+	block, _ := pem.Decode(files["tls.crt"])
+	assert.Assert(t, block != nil, "Certificate should be PEM encoded")
+	assert.Check(t, is.DeepEqual([]byte{0x30, 0x82}, block.Bytes))
+
+	_, err = store.volumeFiles(context.Background(), pod, &v1.CSIVolumeSource{Driver: secretsStoreDriverName})
+	assert.Check(t, errdefs.IsInvalidInput(err), "Volume without SecretProviderClass should be rejected, got %v", err)
+}
+
+func TestParseKeyVaultObjects(t *testing.T) {
+	objects, err := parseKeyVaultObjects(fakeKeyVaultObjects)
+	assert.NilError(t, err)
+	assert.Assert(t, is.Len(objects, 2))
+	assert.Check(t, is.Equal(keyVaultObject{ObjectName: "tls", ObjectType: "cert", ObjectAlias: "tls.crt"}, objects[1]))
+
+	_, err = parseKeyVaultObjects("array:\n  - |\n    objectType: secret\n")
+	assert.Check(t, err != nil, "Object without name should be rejected")
+}
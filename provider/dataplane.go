@@ -0,0 +1,80 @@
+package provider
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/virtual-kubelet/azure-aci/client/aci"
+)
+
+const defaultExecDialRetries = 2
+
+var execDialDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: "aci",
+	Name:      "exec_dial_duration_seconds",
+	Help:      "Duration of the connections to the exec websockets of the container groups, retries included.",
+	Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+}, []string{"result"})
+
+var execDialAttempts = prometheus.NewCounter(prometheus.CounterOpts{
+	Namespace: "aci",
+	Name:      "exec_dial_attempts_total",
+	Help:      "Attempts to connect to the exec websockets of the container groups.",
+})
+
+func init() {
+	prometheus.MustRegister(execDialDuration, execDialAttempts)
+}
+
+// setupDataPlane creates the client of the data plane endpoints of the container groups, separate from the
+// ARM client: its websocket handshakes time out after ExecDialTimeout or ACI_EXEC_DIAL_TIMEOUT, 30s by default,
+// and are retried ExecDialRetries or ACI_EXEC_DIAL_RETRIES times, 2 by default. In the egress lockdown mode
+// the connections are direct and only to the allowed data plane hosts, otherwise they follow HTTPS_PROXY.
+func (p *ACIProvider) setupDataPlane() error {
+	if v := os.Getenv("ACI_EXEC_DIAL_TIMEOUT"); v != "" {
+		p.execTimeout = v
+	}
+	opts := aci.DataPlaneOptions{Retries: defaultExecDialRetries, Observe: observeExecDial}
+	if p.execTimeout != "" {
+		d, err := time.ParseDuration(p.execTimeout)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("invalid exec dial timeout %q, expected a positive duration", p.execTimeout)
+		}
+		opts.HandshakeTimeout = d
+	}
+
+	if p.execDialRetries != nil {
+		opts.Retries = *p.execDialRetries
+	}
+	if v := os.Getenv("ACI_EXEC_DIAL_RETRIES"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("invalid ACI_EXEC_DIAL_RETRIES %q: %v", v, err)
+		}
+		opts.Retries = n
+	}
+	if opts.Retries < 0 {
+		return fmt.Errorf("invalid exec dial retries %d, expected a positive number", opts.Retries)
+	}
+
+	if len(p.dataPlaneHosts) > 0 {
+		opts.AllowHost = p.dataPlaneAllowed
+	} else {
+		opts.Proxy = http.ProxyFromEnvironment
+	}
+	p.dataPlane = aci.NewDataPlaneClient(opts)
+	return nil
+}
+
+func observeExecDial(d time.Duration, attempts int, err error) {
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	execDialDuration.WithLabelValues(result).Observe(d.Seconds())
+	execDialAttempts.Add(float64(attempts))
+}
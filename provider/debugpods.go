@@ -0,0 +1,272 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	client "github.com/virtual-kubelet/azure-aci/client"
+	"github.com/virtual-kubelet/azure-aci/client/aci"
+	"github.com/virtual-kubelet/virtual-kubelet/errdefs"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	stats "k8s.io/kubernetes/pkg/kubelet/apis/stats/v1alpha1"
+)
+
+const (
+	// maxARMErrors is the number of the last errors of ARM kept per pod.
+	maxARMErrors = 5
+	// maxDebugEvents is the number of the last events of a pod in its diagnosis.
+	maxDebugEvents = 20
+)
+
+// armError is an error of ARM for the container group of a pod.
+type armError struct {
+	Time          time.Time `json:"time"`
+	Operation     string    `json:"operation"`
+	Error         string    `json:"error"`
+	CorrelationID string    `json:"correlationID,omitempty"`
+}
+
+// armErrors are the last errors of ARM of the pods, until their container group is deleted.
+type armErrors struct {
+	mu   sync.Mutex
+	pods map[string][]armError
+}
+
+func (e *armErrors) add(ctx context.Context, podNS, podName, operation string, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.pods == nil {
+		e.pods = make(map[string][]armError)
+	}
+	key := podNS + "/" + podName
+	errs := append(e.pods[key], armError{Time: time.Now(), Operation: operation, Error: err.Error(), CorrelationID: client.CorrelationID(ctx)})
+	if len(errs) > maxARMErrors {
+		errs = errs[len(errs)-maxARMErrors:]
+	}
+	e.pods[key] = errs
+}
+
+func (e *armErrors) get(podNS, podName string) []armError {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]armError(nil), e.pods[podNS+"/"+podName]...)
+}
+
+func (e *armErrors) remove(podNS, podName string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	delete(e.pods, podNS+"/"+podName)
+}
+
+func (e *armErrors) len() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return len(e.pods)
+}
+
+// translation is the container group a pod was translated to on its creation, sanitized of its secrets.
+type translation struct {
+	group json.RawMessage
+	err   string
+}
+
+// translations are the last translations of the pods created, until their container group is deleted.
+type translations struct {
+	mu   sync.Mutex
+	pods map[string]translation
+}
+
+func (t *translations) add(podNS, podName string, cg *aci.ContainerGroup) {
+	var tr translation
+	group, err := aci.Sanitize(cg)
+	if err != nil {
+		tr.err = err.Error()
+	} else {
+		tr.group = group
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.pods == nil {
+		t.pods = make(map[string]translation)
+	}
+	t.pods[podNS+"/"+podName] = tr
+}
+
+func (t *translations) get(podNS, podName string) (translation, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	tr, ok := t.pods[podNS+"/"+podName]
+	return tr, ok
+}
+
+func (t *translations) remove(podNS, podName string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.pods, podNS+"/"+podName)
+}
+
+// podDiagnosis is everything the provider knows of a pod, to debug it in one request: its translation to a
+// container group on its creation, sanitized of its secrets, the last errors of ARM, the instance view and a metrics sample of its
+// container group, and its last events. The parts which can't be had have an error instead.
+type podDiagnosis struct {
+	Pod               string                      `json:"pod"`
+	UID               string                      `json:"uid"`
+	Phase             v1.PodPhase                 `json:"phase"`
+	Reason            string                      `json:"reason,omitempty"`
+	Translated        json.RawMessage             `json:"translatedContainerGroup,omitempty"`
+	TranslationError  string                      `json:"translationError,omitempty"`
+	ARMErrors         []armError                  `json:"armErrors,omitempty"`
+	ProvisioningState string                      `json:"provisioningState,omitempty"`
+	InstanceView      *containerGroupInstanceView `json:"instanceView,omitempty"`
+	InstanceViewError string                      `json:"instanceViewError,omitempty"`
+	Events            []podEvent                  `json:"events,omitempty"`
+	EventsError       string                      `json:"eventsError,omitempty"`
+	Metrics           *stats.PodStats             `json:"metrics,omitempty"`
+	MetricsError      string                      `json:"metricsError,omitempty"`
+}
+
+// containerGroupInstanceView is the instance view of a container group and of its containers, by name.
+type containerGroupInstanceView struct {
+	State      string                                         `json:"state,omitempty"`
+	Events     []aci.Event                                    `json:"events,omitempty"`
+	Containers map[string]aci.ContainerPropertiesInstanceView `json:"containers,omitempty"`
+}
+
+// podEvent is an event of a pod.
+type podEvent struct {
+	LastTimestamp metav1.Time `json:"lastTimestamp"`
+	Type          string      `json:"type"`
+	Reason        string      `json:"reason"`
+	Message       string      `json:"message"`
+	Count         int32       `json:"count,omitempty"`
+}
+
+// serveDebugPod serves the diagnosis of the pod of /debug/pods/<namespace>/<name> as JSON. It is forbidden when
+// the kubelet API doesn't require the authentication of the requests, as anyone reaching the port could read it.
+func (p *ACIProvider) serveDebugPod(w http.ResponseWriter, r *http.Request) {
+	if !p.requireAuthn {
+		http.Error(w, "the pod diagnosis requires ACI_CLIENT_CA_FILE or ACI_AUTHORIZATION_WEBHOOK", http.StatusForbidden)
+		return
+	}
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/debug/pods/"), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		http.Error(w, "expected /debug/pods/<namespace>/<name>", http.StatusBadRequest)
+		return
+	}
+
+	d, err := p.diagnosePod(r.Context(), parts[0], parts[1])
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errdefs.IsNotFound(err) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(d)
+}
+
+// diagnosePod diagnoses a pod of the node.
+func (p *ACIProvider) diagnosePod(ctx context.Context, namespace, name string) (*podDiagnosis, error) {
+	var pod *v1.Pod
+	for _, candidate := range p.resourceManager.GetPods() {
+		if candidate.Namespace == namespace && candidate.Name == name {
+			pod = candidate
+			break
+		}
+	}
+	if pod == nil {
+		return nil, errdefs.NotFoundf("pod %s/%s is not on the node", namespace, name)
+	}
+
+	d := &podDiagnosis{
+		Pod:       namespace + "/" + name,
+		UID:       string(pod.UID),
+		Phase:     pod.Status.Phase,
+		Reason:    pod.Status.Reason,
+		ARMErrors: p.armErrors.get(namespace, name),
+	}
+
+	// The pod is not translated again, which would read its secrets and config maps as they are now rather than as
+	// they were sent to ARM.
+	if tr, ok := p.translations.get(namespace, name); !ok {
+		d.TranslationError = "no translation recorded, the pod was not created by this virtual kubelet since it started"
+	} else {
+		d.Translated, d.TranslationError = tr.group, tr.err
+	}
+
+	if cg, err := p.getContainerGroup(ctx, namespace, name); err != nil {
+		d.InstanceViewError = err.Error()
+	} else {
+		d.ProvisioningState = cg.ProvisioningState
+		d.InstanceView = &containerGroupInstanceView{
+			State:      cg.InstanceView.State,
+			Events:     cg.InstanceView.Events,
+			Containers: make(map[string]aci.ContainerPropertiesInstanceView, len(cg.Containers)),
+		}
+		for _, c := range cg.Containers {
+			d.InstanceView.Containers[c.Name] = c.InstanceView
+		}
+	}
+
+	if events, err := p.podEvents(ctx, pod); err != nil {
+		d.EventsError = err.Error()
+	} else {
+		d.Events = events
+	}
+
+	if pod.Status.Phase == v1.PodRunning && p.collectsMetrics(pod) {
+		end := time.Now()
+		system, net, err := p.getContainerGroupMetrics(ctx, containerGroupName(namespace, name), p.isWindowsPod(pod), end.Add(-p.metricsWindow), end)
+		if err != nil {
+			d.MetricsError = err.Error()
+		} else {
+			stat := collectMetrics(pod, system, net, p.aggregation)
+			d.Metrics = &stat
+		}
+	}
+
+	return d, nil
+}
+
+// podEvents returns the last events of a pod, the latest first.
+func (p *ACIProvider) podEvents(ctx context.Context, pod *v1.Pod) ([]podEvent, error) {
+	selector := fields.Set{
+		"involvedObject.kind": "Pod",
+		"involvedObject.name": pod.Name,
+		"involvedObject.uid":  string(pod.UID),
+	}.AsSelector().String()
+	list, err := p.kubeClient.CoreV1().Events(pod.Namespace).List(ctx, metav1.ListOptions{FieldSelector: selector})
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]podEvent, 0, len(list.Items))
+	for _, e := range list.Items {
+		last := e.LastTimestamp
+		if last.IsZero() {
+			last = metav1.NewTime(e.EventTime.Time)
+		}
+		events = append(events, podEvent{LastTimestamp: last, Type: e.Type, Reason: e.Reason, Message: e.Message, Count: e.Count})
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[j].LastTimestamp.Before(&events[i].LastTimestamp)
+	})
+	if len(events) > maxDebugEvents {
+		events = events[:maxDebugEvents]
+	}
+	return events, nil
+}
@@ -0,0 +1,79 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/virtual-kubelet/azure-aci/client/aci"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func TestARMErrors(t *testing.T) {
+	var e armErrors
+	for i := 0; i < maxARMErrors+2; i++ {
+		e.add(context.Background(), "ns", "pod", "get", fmt.Errorf("error %d", i))
+	}
+	e.add(context.Background(), "ns", "other", "create", errors.New("quota exceeded"))
+
+	errs := e.get("ns", "pod")
+	assert.Assert(t, is.Len(errs, maxARMErrors))
+	assert.Check(t, is.Equal("error 2", errs[0].Error))
+	assert.Check(t, is.Equal(fmt.Sprintf("error %d", maxARMErrors+1), errs[maxARMErrors-1].Error))
+	assert.Check(t, is.Equal(2, e.len()))
+
+	e.remove("ns", "pod")
+	assert.Check(t, is.Len(e.get("ns", "pod"), 0))
+	assert.Check(t, is.Equal("create", e.get("ns", "other")[0].Operation))
+}
+
+func TestServeDebugPodPath(t *testing.T) {
+	p := &ACIProvider{}
+	w := httptest.NewRecorder()
+	p.serveDebugPod(w, httptest.NewRequest(http.MethodGet, "/debug/pods/ns/pod", nil))
+	assert.Check(t, is.Equal(http.StatusForbidden, w.Code), "the diagnosis should require the authentication")
+
+	p.requireAuthn = true
+	for _, path := range []string{"/debug/pods/", "/debug/pods/ns", "/debug/pods/ns/", "/debug/pods/ns/pod/extra"} {
+		w := httptest.NewRecorder()
+		p.serveDebugPod(w, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Check(t, is.Equal(http.StatusBadRequest, w.Code), path)
+	}
+}
+
+func TestTranslations(t *testing.T) {
+	var tr translations
+	cg := &aci.ContainerGroup{Name: "ns-pod"}
+	cg.Containers = []aci.Container{{
+		Name: "app",
+		ContainerProperties: aci.ContainerProperties{
+			EnvironmentVariables: []aci.EnvironmentVariable{{Name: "PASSWORD", SecureValue: "hunter2"}},
+		},
+	}}
+	cg.Extensions = []*aci.Extension{{
+		Name: "kube-proxy",
+		Properties: &aci.ExtensionProperties{
+			Type:              aci.ExtensionTypeKubeProxy,
+			ProtectedSettings: map[string]string{aci.KubeProxyExtensionSettingKubeConfig: "a3ViZWNvbmZpZw=="},
+		},
+	}}
+	tr.add("ns", "pod", cg)
+
+	// The translation is kept as it was on creation.
+	cg.Name = "changed"
+	got, ok := tr.get("ns", "pod")
+	assert.Assert(t, ok)
+	assert.Check(t, is.Equal("", got.err))
+	assert.Check(t, strings.Contains(string(got.group), `"ns-pod"`), string(got.group))
+	assert.Check(t, !strings.Contains(string(got.group), "hunter2"), "the secrets should be redacted")
+	assert.Check(t, !strings.Contains(string(got.group), "a3ViZWNvbmZpZw=="), "the kubeconfig of kube-proxy should be redacted")
+
+	tr.remove("ns", "pod")
+	_, ok = tr.get("ns", "pod")
+	assert.Check(t, !ok)
+}
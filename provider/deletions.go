@@ -0,0 +1,192 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/virtual-kubelet/virtual-kubelet/log"
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	defaultDeleteConcurrency = 16
+	deleteAttempts           = 5
+	deleteRetryDelay         = 2 * time.Second
+	deleteProgressInterval   = 30 * time.Second
+	// deleteBatchEventSize is the number of deletions from which the progress of a batch is reported on the node.
+	deleteBatchEventSize = 10
+
+	eventReasonDeletingContainerGroups = "DeletingContainerGroups"
+	eventReasonContainerGroupsDeleted  = "ContainerGroupsDeleted"
+	eventReasonDeleteFailed            = "DeleteFailed"
+)
+
+// setupDeletions reads the number of container groups deleted in parallel from ACI_DELETE_CONCURRENCY
+// or the config file, 16 by default. 0 deletes the container groups synchronously in DeletePod.
+func (p *ACIProvider) setupDeletions() error {
+	concurrency := defaultDeleteConcurrency
+	if p.deleteConcurrency != nil {
+		concurrency = *p.deleteConcurrency
+	}
+	if v := os.Getenv("ACI_DELETE_CONCURRENCY"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("invalid ACI_DELETE_CONCURRENCY %q: %v", v, err)
+		}
+		concurrency = n
+	}
+	if concurrency < 0 {
+		return fmt.Errorf("invalid delete concurrency %d, expected a positive number", concurrency)
+	}
+	if concurrency > 0 {
+		p.deletions = newContainerGroupDeletions(concurrency, p.deletePodResources, p.recordEvent, p.recordNodeEvent)
+	}
+	return nil
+}
+
+// containerGroupDeletions deletes the container groups of the deleted pods in the background, with a bounded
+// concurrency, so deleting a namespace of hundreds of pods doesn't serialize their deletions in the workers of
+// the pod controller. Failed deletions are retried, and the progress of large batches is reported on the node.
+type containerGroupDeletions struct {
+	delete          func(ctx context.Context, pod *v1.Pod) error
+	recordEvent     func(pod *v1.Pod, eventType, reason, messageFmt string, args ...interface{})
+	recordNodeEvent func(eventType, reason, messageFmt string, args ...interface{})
+	slots           chan struct{}
+	retryDelay      time.Duration
+
+	mu      sync.Mutex
+	pending map[string]*pendingDeletion
+	batch   deleteBatch
+}
+
+// pendingDeletion is the deletion in progress of the container group of a pod.
+type pendingDeletion struct {
+	pod  string
+	done chan struct{}
+}
+
+// deleteBatch counts the deletions since the queue was last empty.
+type deleteBatch struct {
+	started  time.Time
+	total    int
+	deleted  int
+	failed   int
+	reported time.Time
+}
+
+func newContainerGroupDeletions(concurrency int, delete func(context.Context, *v1.Pod) error,
+	recordEvent func(*v1.Pod, string, string, string, ...interface{}), recordNodeEvent func(string, string, string, ...interface{})) *containerGroupDeletions {
+	return &containerGroupDeletions{
+		delete:          delete,
+		recordEvent:     recordEvent,
+		recordNodeEvent: recordNodeEvent,
+		slots:           make(chan struct{}, concurrency),
+		retryDelay:      deleteRetryDelay,
+		pending:         make(map[string]*pendingDeletion),
+	}
+}
+
+// deletionKey identifies the deletions by pod UID, the pods recreated under the same name are deleted apart.
+func deletionKey(pod *v1.Pod) string {
+	return pod.Namespace + "/" + pod.Name + "/" + string(pod.UID)
+}
+
+// enqueue deletes the container group of the pod in the background. A pod already being deleted is ignored.
+func (d *containerGroupDeletions) enqueue(ctx context.Context, pod *v1.Pod) {
+	key := deletionKey(pod)
+	d.mu.Lock()
+	if d.pending[key] != nil {
+		d.mu.Unlock()
+		return
+	}
+	d.pending[key] = &pendingDeletion{pod: pod.Namespace + "/" + pod.Name, done: make(chan struct{})}
+	if len(d.pending) == 1 && d.batch.total == 0 {
+		d.batch = deleteBatch{started: time.Now(), reported: time.Now()}
+	}
+	d.batch.total++
+	d.mu.Unlock()
+
+	// The deletion outlives the request of the pod controller.
+	ctx = log.WithLogger(context.Background(), log.G(ctx))
+	pod = pod.DeepCopy()
+	goSubsystem("deletions", func() { d.run(ctx, key, pod) })
+}
+
+func (d *containerGroupDeletions) run(ctx context.Context, key string, pod *v1.Pod) {
+	d.slots <- struct{}{}
+	var err error
+	for attempt := 1; attempt <= deleteAttempts; attempt++ {
+		if err = d.delete(ctx, pod); err == nil {
+			break
+		}
+		log.G(ctx).WithError(err).WithField("attempt", attempt).Warnf("failed to delete the container group of pod %s", key)
+		if attempt < deleteAttempts {
+			time.Sleep(d.retryDelay * time.Duration(attempt))
+		}
+	}
+	<-d.slots
+
+	if err != nil {
+		d.recordEvent(pod, v1.EventTypeWarning, eventReasonDeleteFailed, "Failed to delete the container group after %d attempts, it is deleted as a dangling container group later: %v", deleteAttempts, err)
+	}
+	d.done(key, err == nil)
+}
+
+// wait waits for the deletion in progress of the container group of a previous pod of the same name as pod,
+// which has the same container group name, so a pod recreated under its name isn't created before the
+// container group of the previous pod is deleted.
+func (d *containerGroupDeletions) wait(ctx context.Context, pod *v1.Pod) error {
+	name, key := pod.Namespace+"/"+pod.Name, deletionKey(pod)
+	var previous []chan struct{}
+	d.mu.Lock()
+	for k, pending := range d.pending {
+		if pending.pod == name && k != key {
+			previous = append(previous, pending.done)
+		}
+	}
+	d.mu.Unlock()
+	if len(previous) == 0 {
+		return nil
+	}
+
+	log.G(ctx).Infof("waiting for the container group of the previous pod %s to be deleted", pod.Name)
+	for _, done := range previous {
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return fmt.Errorf("the container group of the previous pod %s is still being deleted: %v", pod.Name, ctx.Err())
+		}
+	}
+	return nil
+}
+
+// done records the end of a deletion, and reports the progress of the batch.
+func (d *containerGroupDeletions) done(key string, deleted bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	close(d.pending[key].done)
+	delete(d.pending, key)
+	if deleted {
+		d.batch.deleted++
+	} else {
+		d.batch.failed++
+	}
+
+	batch := d.batch
+	if len(d.pending) == 0 {
+		d.batch = deleteBatch{}
+		if batch.total >= deleteBatchEventSize {
+			d.recordNodeEvent(v1.EventTypeNormal, eventReasonContainerGroupsDeleted, "Deleted %d container groups in %s, %d failed", batch.deleted, time.Since(batch.started).Round(time.Second), batch.failed)
+		}
+		return
+	}
+	if batch.total >= deleteBatchEventSize && time.Since(batch.reported) >= deleteProgressInterval {
+		d.batch.reported = time.Now()
+		d.recordNodeEvent(v1.EventTypeNormal, eventReasonDeletingContainerGroups, "Deleted %d of %d container groups, %d failed, %d in progress", batch.deleted, batch.total, batch.failed, len(d.pending))
+	}
+}
@@ -0,0 +1,154 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/virtual-kubelet/azure-aci/client/aci"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type fakeDeleter struct {
+	mu       sync.Mutex
+	wg       sync.WaitGroup
+	running  int
+	peak     int
+	failures map[string]int
+	deleted  []string
+	events   []string
+	release  chan struct{}
+}
+
+func (f *fakeDeleter) delete(ctx context.Context, pod *v1.Pod) error {
+	f.mu.Lock()
+	f.running++
+	if f.running > f.peak {
+		f.peak = f.running
+	}
+	f.mu.Unlock()
+
+	<-f.release
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.running--
+	if f.failures[pod.Name] > 0 {
+		f.failures[pod.Name]--
+		return errors.New("throttled")
+	}
+	f.deleted = append(f.deleted, pod.Name)
+	return nil
+}
+
+func (f *fakeDeleter) recordEvent(pod *v1.Pod, eventType, reason, messageFmt string, args ...interface{}) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.events = append(f.events, reason+" "+pod.Name)
+}
+
+func (f *fakeDeleter) recordNodeEvent(eventType, reason, messageFmt string, args ...interface{}) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.events = append(f.events, reason)
+	if reason == eventReasonContainerGroupsDeleted {
+		f.wg.Done()
+	}
+}
+
+func TestContainerGroupDeletions(t *testing.T) {
+	f := &fakeDeleter{failures: map[string]int{"pod-3": 2, "pod-7": deleteAttempts}, release: make(chan struct{})}
+	d := newContainerGroupDeletions(4, f.delete, f.recordEvent, f.recordNodeEvent)
+	d.retryDelay = 0
+
+	f.wg.Add(1)
+	ctx := context.Background()
+	for i := 0; i < 20; i++ {
+		pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: fmt.Sprintf("pod-%d", i)}}
+		d.enqueue(ctx, pod)
+		// A pod deleted again while its deletion is in progress is ignored.
+		d.enqueue(ctx, pod)
+	}
+	close(f.release)
+	f.wg.Wait()
+
+	assert.Check(t, f.peak <= 4, "expected at most 4 deletions in parallel, got %d", f.peak)
+	assert.Check(t, is.Len(f.deleted, 19))
+	assert.Check(t, is.Contains(f.events, eventReasonDeleteFailed+" pod-7"))
+	assert.Check(t, is.Contains(f.events, eventReasonContainerGroupsDeleted))
+}
+
+func TestContainerGroupDeletionsRecreate(t *testing.T) {
+	f := &fakeDeleter{release: make(chan struct{})}
+	d := newContainerGroupDeletions(4, f.delete, f.recordEvent, f.recordNodeEvent)
+
+	previous := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "web-0", UID: "previous"}}
+	recreated := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "web-0", UID: "recreated"}}
+	d.enqueue(context.Background(), previous)
+	// The deletion of the recreated pod is not mistaken for the one of the previous pod.
+	d.enqueue(context.Background(), recreated)
+	assert.Check(t, is.Len(d.pending, 2))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Check(t, d.wait(ctx, recreated) != nil, "expected the recreated pod to wait for the deletion of the previous pod")
+
+	waited := make(chan error)
+	go func() { waited <- d.wait(context.Background(), recreated) }()
+	close(f.release)
+	assert.NilError(t, <-waited)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	assert.Check(t, is.Contains(f.deleted, "web-0"))
+}
+
+func TestDeletePodResourcesKeepsRecreatedContainerGroup(t *testing.T) {
+	_, aciServerMocker, provider, err := prepareMocks()
+	if err != nil {
+		t.Fatal("Unable to prepare the mocks", err)
+	}
+
+	deletes := 0
+	aciServerMocker.OnGetContainerGroup = func(subscription, resourceGroup, containerGroup string) (int, interface{}) {
+		return http.StatusOK, aci.ContainerGroup{
+			Name: containerGroup,
+			Tags: map[string]string{"NodeName": fakeNodeName, "Namespace": "ns", "PodName": "web-0", "UID": "recreated"},
+		}
+	}
+	aciServerMocker.OnDelete = func(subscription, resourceGroup, containerGroup string) (int, interface{}) {
+		deletes++
+		return http.StatusOK, nil
+	}
+
+	previous := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "web-0", UID: "previous"}}
+	assert.NilError(t, provider.deletePodResources(context.Background(), previous))
+	assert.Check(t, is.Equal(0, deletes), "The container group of the recreated pod should be kept")
+
+	recreated := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "web-0", UID: "recreated"}}
+	assert.NilError(t, provider.deletePodResources(context.Background(), recreated))
+	assert.Check(t, is.Equal(1, deletes))
+}
+
+func TestSetupDeletions(t *testing.T) {
+	p := ACIProvider{}
+	assert.NilError(t, p.setupDeletions())
+	assert.Check(t, p.deletions != nil, "expected the deletions in the background by default")
+	assert.Check(t, is.Equal(cap(p.deletions.slots), defaultDeleteConcurrency))
+
+	os.Setenv("ACI_DELETE_CONCURRENCY", "0")
+	defer os.Unsetenv("ACI_DELETE_CONCURRENCY")
+	p = ACIProvider{}
+	assert.NilError(t, p.setupDeletions())
+	assert.Check(t, p.deletions == nil, "expected synchronous deletions")
+
+	os.Setenv("ACI_DELETE_CONCURRENCY", "-1")
+	assert.Check(t, p.setupDeletions() != nil)
+}
@@ -0,0 +1,50 @@
+package provider
+
+import (
+	"fmt"
+
+	"github.com/virtual-kubelet/azure-aci/client/aci"
+)
+
+// namespaceDiagnostics is the log forwarding profile applied to all the container groups of a namespace,
+// overriding the one of the node. The workspace credentials are either inline, or read from a file using
+// the format of LOG_ANALYTICS_AUTH_LOCATION.
+type namespaceDiagnostics struct {
+	Disabled     bool
+	WorkspaceID  string
+	WorkspaceKey string
+	AuthFile     string
+	LogType      string
+	Metadata     map[string]string
+}
+
+func (d namespaceDiagnostics) containerGroupDiagnostics() (*aci.ContainerGroupDiagnostics, error) {
+	if d.Disabled {
+		return nil, nil
+	}
+
+	var diagnostics *aci.ContainerGroupDiagnostics
+	var err error
+	if d.AuthFile != "" {
+		diagnostics, err = aci.NewContainerGroupDiagnosticsFromFile(d.AuthFile)
+	} else {
+		diagnostics, err = aci.NewContainerGroupDiagnostics(d.WorkspaceID, d.WorkspaceKey)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch logType := aci.LogAnalyticsLogType(d.LogType); logType {
+	case "":
+	case aci.LogAnlyticsLogTypeContainerInsights, aci.LogAnlyticsLogTypeContainerInstance:
+		diagnostics.LogAnalytics.LogType = logType
+	default:
+		return nil, fmt.Errorf("%q is not a valid log type, try one of the following instead: %s | %s", d.LogType, aci.LogAnlyticsLogTypeContainerInsights, aci.LogAnlyticsLogTypeContainerInstance)
+	}
+
+	if len(d.Metadata) > 0 {
+		diagnostics.LogAnalytics.Metadata = d.Metadata
+	}
+
+	return diagnostics, nil
+}
@@ -0,0 +1,83 @@
+package provider
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/virtual-kubelet/azure-aci/client/aci"
+	"github.com/virtual-kubelet/virtual-kubelet/log"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// podConditionDisruptionTarget is the condition of the pods about to be disrupted, like the one Kubernetes
+	// sets on the pods it is about to evict, so controllers drain them gracefully.
+	podConditionDisruptionTarget v1.PodConditionType = "DisruptionTarget"
+
+	podConditionReasonPlannedMaintenance = "PlannedMaintenance"
+	podConditionReasonEviction           = "Evicted"
+)
+
+// disruptionCondition returns the disruption condition of a container group whose instance view announces a
+// planned maintenance or the eviction of the container group, like the eviction of Spot container groups. ACI has
+// no scheduled events endpoint for the container groups, the platform reports them as container group events.
+func disruptionCondition(cg *aci.ContainerGroup) (v1.PodCondition, bool) {
+	var latest *aci.Event
+	var reason string
+	for i := range cg.InstanceView.Events {
+		e := &cg.InstanceView.Events[i]
+		r := disruptionReason(e)
+		if r == "" {
+			continue
+		}
+		if latest == nil || time.Time(e.LastTimestamp).After(time.Time(latest.LastTimestamp)) {
+			latest = e
+			reason = r
+		}
+	}
+	if latest == nil {
+		return v1.PodCondition{}, false
+	}
+
+	return v1.PodCondition{
+		Type:               podConditionDisruptionTarget,
+		Status:             v1.ConditionTrue,
+		Reason:             reason,
+		Message:            latest.Message,
+		LastTransitionTime: metav1.NewTime(time.Time(latest.FirstTimestamp)),
+	}, true
+}
+
+// disruptionReason returns the reason of the disruption an ACI event announces, if any.
+func disruptionReason(e *aci.Event) string {
+	s := strings.ToLower(e.Name + " " + e.Message)
+	switch {
+	case strings.Contains(s, "evict"), strings.Contains(s, "preempt"):
+		return podConditionReasonEviction
+	case strings.Contains(s, "maintenance"):
+		return podConditionReasonPlannedMaintenance
+	}
+	return ""
+}
+
+// recordDisruptionEvents records a warning event on the pod when its container group is about to be disrupted,
+// comparing the last known status of the pod with the one from the provider.
+func (pt *PodsTracker) recordDisruptionEvents(ctx context.Context, pod *v1.Pod, status *v1.PodStatus) {
+	for _, c := range status.Conditions {
+		if c.Type != podConditionDisruptionTarget || c.Status != v1.ConditionTrue {
+			continue
+		}
+		for _, previous := range pod.Status.Conditions {
+			if previous.Type == c.Type && previous.Status == c.Status && previous.Reason == c.Reason {
+				return
+			}
+		}
+
+		log.G(ctx).WithField("pod", pod.Name).Warnf("%s: %s", c.Reason, c.Message)
+		if pt.recorder != nil {
+			pt.recorder.Event(pod, v1.EventTypeWarning, c.Reason, c.Message)
+		}
+	}
+}
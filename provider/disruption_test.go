@@ -0,0 +1,44 @@
+package provider
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/virtual-kubelet/azure-aci/client/aci"
+	"github.com/virtual-kubelet/azure-aci/client/api"
+	"gotest.tools/assert"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestDisruptionCondition(t *testing.T) {
+	now := time.Now()
+	cg := &aci.ContainerGroup{}
+	cg.InstanceView.Events = []aci.Event{
+		{Name: "Started", Message: "Started container", LastTimestamp: api.JSONTime(now.Add(-time.Hour))},
+	}
+	_, ok := disruptionCondition(cg)
+	assert.Check(t, !ok, "A running container group should not be disrupted")
+
+	cg.InstanceView.Events = append(cg.InstanceView.Events,
+		aci.Event{Name: "PlannedMaintenance", Message: "The host is scheduled for maintenance", FirstTimestamp: api.JSONTime(now.Add(-time.Minute)), LastTimestamp: api.JSONTime(now.Add(-time.Minute))},
+		aci.Event{Name: "Evicting", Message: "The Spot container group is being evicted", FirstTimestamp: api.JSONTime(now), LastTimestamp: api.JSONTime(now)},
+	)
+	condition, ok := disruptionCondition(cg)
+	assert.Assert(t, ok)
+	assert.Equal(t, condition.Type, podConditionDisruptionTarget)
+	assert.Equal(t, condition.Status, v1.ConditionTrue)
+	assert.Equal(t, condition.Reason, podConditionReasonEviction, "The latest disruption should be reported")
+
+	recorder := record.NewFakeRecorder(2)
+	pt := &PodsTracker{recorder: recorder}
+	pod := &v1.Pod{}
+	status := &v1.PodStatus{Conditions: []v1.PodCondition{condition}}
+	pt.recordDisruptionEvents(context.Background(), pod, status)
+	assert.Equal(t, <-recorder.Events, "Warning Evicted The Spot container group is being evicted")
+
+	pod.Status = *status
+	pt.recordDisruptionEvents(context.Background(), pod, status)
+	assert.Equal(t, len(recorder.Events), 0, "The disruption should only be reported once")
+}
@@ -0,0 +1,20 @@
+// Package provider implements a virtual kubelet provider running the pods of a node in Azure Container Instances.
+//
+// The provider can be embedded in another program, such as a custom virtual kubelet or a test harness, with
+// NewProvider:
+//
+//	p, err := provider.NewProvider(ctx, provider.Options{
+//		NodeName:        "virtual-node-aci-linux",
+//		OperatingSystem: "Linux",
+//		ResourceManager: rm,
+//		Authentication:  auth,
+//		KubeClient:      kubeClient,
+//	})
+//
+// ContainerGroupFromPod and PodFromContainerGroup expose the translation between the pods and the container groups,
+// and the client packages under github.com/virtual-kubelet/azure-aci/client the ARM API.
+//
+// Options, NewProvider, NewACIProvider, the exported methods of ACIProvider and the exported functions and types of
+// this package are kept compatible. The settings of the provider config file and of the environment variables are
+// documented in the README.
+package provider
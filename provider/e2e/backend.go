@@ -0,0 +1,358 @@
+package e2e
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	azure "github.com/virtual-kubelet/azure-aci/client"
+	"github.com/virtual-kubelet/azure-aci/client/aci"
+	"github.com/virtual-kubelet/azure-aci/client/api"
+	"github.com/virtual-kubelet/azure-aci/provider"
+)
+
+const (
+	// SubscriptionID is the subscription of the simulated backend.
+	SubscriptionID = "00000000-0000-0000-0000-000000000000"
+	// ResourceGroup is the resource group of the container groups of the simulated backend.
+	ResourceGroup = "vk-e2e"
+	// Region is the region of the simulated backend.
+	Region = "eastus"
+
+	// containerGroupIP is the IP of the container groups of the simulated backend.
+	containerGroupIP = "10.240.0.4"
+)
+
+// Backend simulates ACI on the ARM and AAD mock servers of the provider package. The container groups created by
+// the provider run as soon as they are created, until they are stopped or their containers are terminated with
+// TerminateContainer.
+type Backend struct {
+	ACI *provider.ACIMock
+	AAD *provider.AADMock
+
+	mu     sync.Mutex
+	groups map[string]*aci.ContainerGroup
+	logs   map[string]string
+	// cpuUsage and memoryUsage are the usage reported for each running container, in millicores and bytes.
+	cpuUsage    float64
+	memoryUsage float64
+}
+
+// NewBackend starts a simulated ACI backend, stopped with Close.
+func NewBackend() *Backend {
+	b := &Backend{
+		ACI:         provider.NewACIMock(),
+		AAD:         provider.NewAADMock(),
+		groups:      map[string]*aci.ContainerGroup{},
+		logs:        map[string]string{},
+		cpuUsage:    100,
+		memoryUsage: 64 * 1024 * 1024,
+	}
+
+	b.ACI.OnGetRPManifest = b.getRPManifest
+	b.ACI.OnCreate = b.create
+	b.ACI.OnGetContainerGroup = b.get
+	b.ACI.OnGetContainerGroups = b.list
+	b.ACI.OnUpdateTags = b.updateTags
+	b.ACI.OnDelete = b.delete
+	b.ACI.OnAction = b.action
+	b.ACI.OnGetLogs = b.getLogs
+	b.ACI.OnGetMetrics = b.getMetrics
+	return b
+}
+
+// Close stops the mock servers of the backend.
+func (b *Backend) Close() {
+	b.ACI.Close()
+	b.AAD.Close()
+}
+
+// Authentication is the authentication of a provider using the backend.
+func (b *Backend) Authentication() *azure.Authentication {
+	auth := azure.NewAuthentication(azure.PublicCloud.Name, "e2e-client", "e2e-secret", SubscriptionID, "e2e-tenant", "")
+	auth.ActiveDirectoryEndpoint = b.AAD.GetServerURL()
+	auth.ResourceManagerEndpoint = b.ACI.GetServerURL()
+	return auth
+}
+
+// ContainerGroup returns a copy of a container group of the backend, false if it does not exist.
+func (b *Backend) ContainerGroup(name string) (*aci.ContainerGroup, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	cg, ok := b.groups[name]
+	if !ok {
+		return nil, false
+	}
+	return copyGroup(cg), true
+}
+
+// copyGroup copies a container group of the backend, for it to be encoded once the lock of the backend is released.
+func copyGroup(cg *aci.ContainerGroup) *aci.ContainerGroup {
+	copied := *cg
+	copied.Containers = append([]aci.Container(nil), cg.Containers...)
+	if cg.IPAddress != nil {
+		ip := *cg.IPAddress
+		copied.IPAddress = &ip
+	}
+	copied.Tags = make(map[string]string, len(cg.Tags))
+	for key, value := range cg.Tags {
+		copied.Tags[key] = value
+	}
+	return &copied
+}
+
+// SetLogs sets the logs of a container of a container group.
+func (b *Backend) SetLogs(containerGroup, container, logs string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.logs[containerGroup+"/"+container] = logs
+}
+
+// SetUsage sets the CPU usage, in millicores, and the memory usage, in bytes, reported for each running container.
+func (b *Backend) SetUsage(cpu, memory float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.cpuUsage = cpu
+	b.memoryUsage = memory
+}
+
+// TerminateContainer terminates a container of a container group with an exit code, as if its process exited. ACI
+// restarts it as the restart policy of the container group requires, otherwise the container group succeeds or fails
+// once all its containers are terminated.
+func (b *Backend) TerminateContainer(containerGroup, container string, exitCode int32) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	cg, ok := b.groups[containerGroup]
+	if !ok {
+		return fmt.Errorf("container group %s not found", containerGroup)
+	}
+	found := false
+	for i := range cg.Containers {
+		c := &cg.Containers[i]
+		if c.Name != container {
+			continue
+		}
+		found = true
+		view := &c.InstanceView
+		view.PreviousState = view.CurrentState
+		view.CurrentState = aci.ContainerState{
+			State:        "Terminated",
+			StartTime:    view.PreviousState.StartTime,
+			FinishTime:   api.JSONTime(time.Now().UTC()),
+			ExitCode:     exitCode,
+			DetailStatus: "Completed",
+		}
+		if cg.RestartPolicy == aci.Always || cg.RestartPolicy == aci.OnFailure && exitCode != 0 {
+			view.PreviousState = view.CurrentState
+			view.CurrentState = aci.ContainerState{State: "Running", StartTime: api.JSONTime(time.Now().UTC())}
+			view.RestartCount++
+		}
+	}
+	if !found {
+		return fmt.Errorf("container %s not found in container group %s", container, containerGroup)
+	}
+
+	state := "Succeeded"
+	for _, c := range cg.Containers {
+		switch {
+		case c.InstanceView.CurrentState.State == "Running":
+			return nil
+		case c.InstanceView.CurrentState.ExitCode != 0:
+			state = "Failed"
+		}
+	}
+	cg.InstanceView.State = state
+	return nil
+}
+
+// start runs the containers of a container group.
+func start(cg *aci.ContainerGroup) {
+	now := api.JSONTime(time.Now().UTC())
+	cg.ProvisioningState = "Succeeded"
+	cg.InstanceView = aci.ContainerGroupPropertiesInstanceView{State: "Running"}
+	for i := range cg.Containers {
+		view := &cg.Containers[i].InstanceView
+		if view.CurrentState.State != "" {
+			view.PreviousState = view.CurrentState
+			view.RestartCount++
+		}
+		view.CurrentState = aci.ContainerState{State: "Running", StartTime: now}
+	}
+	if cg.IPAddress != nil {
+		cg.IPAddress.IP = containerGroupIP
+	}
+}
+
+// stop terminates the containers of a container group.
+func stop(cg *aci.ContainerGroup) {
+	now := api.JSONTime(time.Now().UTC())
+	cg.InstanceView.State = "Stopped"
+	for i := range cg.Containers {
+		view := &cg.Containers[i].InstanceView
+		if view.CurrentState.State != "Running" {
+			continue
+		}
+		view.PreviousState = view.CurrentState
+		view.CurrentState = aci.ContainerState{State: "Terminated", StartTime: view.PreviousState.StartTime, FinishTime: now, DetailStatus: "Stopped"}
+	}
+}
+
+// notFound is the ARM error of the missing container groups.
+func notFound(name string) interface{} {
+	return map[string]interface{}{
+		"error": map[string]string{
+			"code":    "ResourceNotFound",
+			"message": fmt.Sprintf("The Resource 'Microsoft.ContainerInstance/containerGroups/%s' under resource group '%s' was not found.", name, ResourceGroup),
+		},
+	}
+}
+
+func (b *Backend) getRPManifest() (int, interface{}) {
+	return http.StatusOK, &aci.ResourceProviderManifest{
+		Metadata: &aci.ResourceProviderMetadata{
+			GPURegionalSKUs: []*aci.GPURegionalSKU{
+				{Location: Region, SKUs: []aci.GPUSKU{aci.K80, aci.P100, aci.V100}},
+			},
+		},
+	}
+}
+
+func (b *Backend) create(subscription, resourceGroup, name string, cg *aci.ContainerGroup) (int, interface{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	status := http.StatusCreated
+	if _, ok := b.groups[name]; ok {
+		status = http.StatusOK
+	}
+	cg.ID = fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.ContainerInstance/containerGroups/%s", subscription, resourceGroup, name)
+	cg.Name = name
+	cg.Type = "Microsoft.ContainerInstance/containerGroups"
+	start(cg)
+	b.groups[name] = cg
+	return status, copyGroup(cg)
+}
+
+func (b *Backend) get(subscription, resourceGroup, name string) (int, interface{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	cg, ok := b.groups[name]
+	if !ok {
+		return http.StatusNotFound, notFound(name)
+	}
+	return http.StatusOK, copyGroup(cg)
+}
+
+func (b *Backend) list(subscription, resourceGroup string) (int, interface{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	list := aci.ContainerGroupListResult{Value: make([]aci.ContainerGroup, 0, len(b.groups))}
+	for _, cg := range b.groups {
+		list.Value = append(list.Value, *copyGroup(cg))
+	}
+	return http.StatusOK, list
+}
+
+func (b *Backend) updateTags(subscription, resourceGroup, name string, tags map[string]string) (int, interface{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	cg, ok := b.groups[name]
+	if !ok {
+		return http.StatusNotFound, notFound(name)
+	}
+	cg.Tags = tags
+	return http.StatusOK, copyGroup(cg)
+}
+
+func (b *Backend) delete(subscription, resourceGroup, name string) (int, interface{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.groups[name]; !ok {
+		return http.StatusNoContent, nil
+	}
+	delete(b.groups, name)
+	for key := range b.logs {
+		if strings.HasPrefix(key, name+"/") {
+			delete(b.logs, key)
+		}
+	}
+	return http.StatusOK, nil
+}
+
+func (b *Backend) action(subscription, resourceGroup, name, action string) (int, interface{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	cg, ok := b.groups[name]
+	if !ok {
+		return http.StatusNotFound, notFound(name)
+	}
+	switch action {
+	case "start", "restart":
+		start(cg)
+	case "stop":
+		stop(cg)
+	default:
+		return http.StatusNotImplemented, nil
+	}
+	return http.StatusNoContent, nil
+}
+
+func (b *Backend) getLogs(subscription, resourceGroup, name, container string) (int, interface{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.groups[name]; !ok {
+		return http.StatusNotFound, notFound(name)
+	}
+	return http.StatusOK, aci.Logs{Content: b.logs[name+"/"+container]}
+}
+
+// getMetrics reports the usage of the running containers, split by container for the CPU and memory metrics.
+func (b *Backend) getMetrics(subscription, resourceGroup, name, metricNames string) (int, interface{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	cg, ok := b.groups[name]
+	if !ok {
+		return http.StatusNotFound, notFound(name)
+	}
+
+	now := time.Now().UTC()
+	var result aci.ContainerGroupMetricsResult
+	for _, metric := range strings.Split(metricNames, ",") {
+		value := aci.MetricValue{Desc: aci.MetricDescriptor{Value: aci.MetricType(metric), LocalizedValue: metric}}
+		switch aci.MetricType(metric) {
+		case aci.MetricTypeCPUUsage, aci.MetricTypeMemoryUsage:
+			usage := b.cpuUsage
+			if aci.MetricType(metric) == aci.MetricTypeMemoryUsage {
+				usage = b.memoryUsage
+			}
+			for _, c := range cg.Containers {
+				if c.InstanceView.CurrentState.State != "Running" {
+					continue
+				}
+				value.Timeseries = append(value.Timeseries, aci.MetricTimeSeries{
+					Data: []aci.TimeSeriesEntry{{Timestamp: now, Average: usage, Maximum: usage, Total: usage, Count: 1}},
+					MetadataValues: []aci.MetricMetadataValue{
+						{Name: aci.ValueDescriptor{Value: "containerName", LocalizedValue: "containerName"}, Value: c.Name},
+					},
+				})
+			}
+		default:
+			value.Timeseries = []aci.MetricTimeSeries{{Data: []aci.TimeSeriesEntry{{Timestamp: now}}}}
+		}
+		result.Value = append(result.Value, value)
+	}
+	return http.StatusOK, result
+}
@@ -0,0 +1,103 @@
+package e2e
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/virtual-kubelet/virtual-kubelet/node/api"
+	"gotest.tools/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func startHarness(t *testing.T) (context.Context, *Harness, func()) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	h, err := Start(ctx, Config{})
+	if err != nil {
+		cancel()
+		t.Fatal(err)
+	}
+	return ctx, h, func() {
+		h.Stop()
+		cancel()
+	}
+}
+
+func testPod(name string, restartPolicy v1.RestartPolicy) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "e2e", UID: types.UID("uid-" + name)},
+		Spec: v1.PodSpec{
+			RestartPolicy: restartPolicy,
+			Containers: []v1.Container{{
+				Name:  "web",
+				Image: "nginx",
+				Ports: []v1.ContainerPort{{ContainerPort: 80, Protocol: v1.ProtocolTCP}},
+			}},
+		},
+	}
+}
+
+func TestPodLifecycle(t *testing.T) {
+	ctx, h, stop := startHarness(t)
+	defer stop()
+
+	_, err := h.CreatePod(ctx, testPod("web", v1.RestartPolicyNever))
+	assert.NilError(t, err)
+	pod, err := h.WaitForPodPhase(ctx, "e2e", "web", v1.PodRunning)
+	assert.NilError(t, err)
+	assert.Equal(t, pod.Status.PodIP, containerGroupIP)
+	assert.Equal(t, len(pod.Status.ContainerStatuses), 1)
+	assert.Assert(t, pod.Status.ContainerStatuses[0].State.Running != nil)
+
+	cgName := ContainerGroupName("e2e", "web")
+	cg, ok := h.Backend.ContainerGroup(cgName)
+	assert.Assert(t, ok)
+	assert.Equal(t, cg.Containers[0].Image, "nginx")
+	assert.Equal(t, cg.Tags["NodeName"], NodeName)
+
+	h.Backend.SetLogs(cgName, "web", "listening on :80\n")
+	logs, err := h.Provider.GetContainerLogs(ctx, "e2e", "web", "web", api.ContainerLogOpts{})
+	assert.NilError(t, err)
+	b, err := ioutil.ReadAll(logs)
+	logs.Close()
+	assert.NilError(t, err)
+	assert.Equal(t, string(b), "listening on :80\n")
+
+	h.Backend.SetUsage(250, 128*1024*1024)
+	summary, err := h.Provider.GetStatsSummary(ctx)
+	assert.NilError(t, err)
+	assert.Equal(t, len(summary.Pods), 1)
+	assert.Equal(t, summary.Pods[0].PodRef.Name, "web")
+	assert.Equal(t, *summary.Pods[0].CPU.UsageNanoCores, uint64(250*1000000))
+	assert.Equal(t, *summary.Pods[0].Memory.WorkingSetBytes, uint64(128*1024*1024))
+
+	assert.NilError(t, h.Backend.TerminateContainer(cgName, "web", 0))
+	pod, err = h.WaitForPodPhase(ctx, "e2e", "web", v1.PodSucceeded)
+	assert.NilError(t, err)
+	assert.Assert(t, pod.Status.ContainerStatuses[0].State.Terminated != nil)
+	assert.Equal(t, pod.Status.ContainerStatuses[0].State.Terminated.ExitCode, int32(0))
+
+	assert.NilError(t, h.DeletePod(ctx, "e2e", "web"))
+}
+
+func TestPodRestart(t *testing.T) {
+	ctx, h, stop := startHarness(t)
+	defer stop()
+
+	_, err := h.CreatePod(ctx, testPod("web", v1.RestartPolicyAlways))
+	assert.NilError(t, err)
+	_, err = h.WaitForPodPhase(ctx, "e2e", "web", v1.PodRunning)
+	assert.NilError(t, err)
+
+	assert.NilError(t, h.Backend.TerminateContainer(ContainerGroupName("e2e", "web"), "web", 137))
+	pod, err := h.WaitForPod(ctx, "e2e", "web", func(pod *v1.Pod) bool {
+		return len(pod.Status.ContainerStatuses) == 1 && pod.Status.ContainerStatuses[0].RestartCount == 1
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, pod.Status.Phase, v1.PodRunning)
+	assert.Assert(t, pod.Status.ContainerStatuses[0].LastTerminationState.Terminated != nil)
+	assert.Equal(t, pod.Status.ContainerStatuses[0].LastTerminationState.Terminated.ExitCode, int32(137))
+}
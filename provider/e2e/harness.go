@@ -0,0 +1,253 @@
+// Package e2e runs the provider against a simulated ACI backend, with the pods driven by the pod controller of the
+// virtual kubelet, so the lifecycle of the pods can be tested end to end without an Azure subscription.
+package e2e
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/virtual-kubelet/azure-aci/provider"
+	"github.com/virtual-kubelet/node-cli/manager"
+	"github.com/virtual-kubelet/virtual-kubelet/node"
+	v1 "k8s.io/api/core/v1"
+	k8serr "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/kubernetes/scheme"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+const (
+	// NodeName is the name of the virtual node of the harness.
+	NodeName = "virtual-kubelet-e2e"
+
+	pollInterval = 100 * time.Millisecond
+)
+
+// Config are the settings of a harness.
+type Config struct {
+	// ProviderConfig is added to the provider config file, in its TOML format.
+	ProviderConfig string
+	// StatusSyncInterval is the interval of the status updates of the pods, 200ms by default.
+	StatusSyncInterval time.Duration
+}
+
+// Harness runs a provider against a simulated backend, with the pods created in a fake API server.
+type Harness struct {
+	Backend    *Backend
+	Provider   *provider.ACIProvider
+	KubeClient *fake.Clientset
+
+	configPath  string
+	pods        corev1listers.PodLister
+	broadcaster record.EventBroadcaster
+	cancel      context.CancelFunc
+	running     bool
+	done        chan error
+}
+
+// Start starts a harness, stopped with Stop.
+func Start(ctx context.Context, config Config) (*Harness, error) {
+	interval := config.StatusSyncInterval
+	if interval == 0 {
+		interval = 200 * time.Millisecond
+	}
+
+	f, err := ioutil.TempFile("", "vk-e2e-*.toml")
+	if err != nil {
+		return nil, err
+	}
+	_, err = fmt.Fprintf(f, "ResourceGroup = %q\nRegion = %q\nOperatingSystem = \"Linux\"\nStatusSyncInterval = %q\n%s\n",
+		ResourceGroup, Region, interval.String(), config.ProviderConfig)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return nil, err
+	}
+
+	h := &Harness{
+		Backend:    NewBackend(),
+		KubeClient: fake.NewSimpleClientset(),
+		configPath: f.Name(),
+		done:       make(chan error, 1),
+	}
+	ctx, h.cancel = context.WithCancel(ctx)
+	if err := h.start(ctx); err != nil {
+		h.Stop()
+		return nil, err
+	}
+	return h, nil
+}
+
+func (h *Harness) start(ctx context.Context) error {
+	factory := informers.NewSharedInformerFactory(h.KubeClient, 0)
+	podInformer := factory.Core().V1().Pods()
+	secretInformer := factory.Core().V1().Secrets()
+	configMapInformer := factory.Core().V1().ConfigMaps()
+	serviceInformer := factory.Core().V1().Services()
+	h.pods = podInformer.Lister()
+
+	rm, err := manager.NewResourceManager(h.pods, secretInformer.Lister(), configMapInformer.Lister(), serviceInformer.Lister())
+	if err != nil {
+		return err
+	}
+
+	h.broadcaster = record.NewBroadcaster()
+	h.broadcaster.StartRecordingToSink(&corev1client.EventSinkImpl{Interface: h.KubeClient.CoreV1().Events(v1.NamespaceAll)})
+	recorder := h.broadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: NodeName})
+
+	h.Provider, err = provider.NewProvider(ctx, provider.Options{
+		ConfigPath:      h.configPath,
+		ResourceManager: rm,
+		NodeName:        NodeName,
+		OperatingSystem: "Linux",
+		InternalIP:      "10.240.0.1",
+		DaemonPort:      10250,
+		ClusterDomain:   "cluster.local",
+		Authentication:  h.Backend.Authentication(),
+		KubeClient:      h.KubeClient,
+		EventRecorder:   recorder,
+	})
+	if err != nil {
+		return fmt.Errorf("error creating the provider: %v", err)
+	}
+
+	pc, err := node.NewPodController(node.PodControllerConfig{
+		PodClient:         h.KubeClient.CoreV1(),
+		PodInformer:       podInformer,
+		EventRecorder:     recorder,
+		Provider:          h.Provider,
+		SecretInformer:    secretInformer,
+		ConfigMapInformer: configMapInformer,
+		ServiceInformer:   serviceInformer,
+	})
+	if err != nil {
+		return fmt.Errorf("error creating the pod controller: %v", err)
+	}
+
+	factory.Start(ctx.Done())
+	for informer, synced := range factory.WaitForCacheSync(ctx.Done()) {
+		if !synced {
+			return fmt.Errorf("informer %v did not sync", informer)
+		}
+	}
+
+	h.running = true
+	go func() {
+		h.done <- pc.Run(ctx, 1)
+	}()
+	select {
+	case <-pc.Ready():
+		return nil
+	case err := <-h.done:
+		return fmt.Errorf("the pod controller exited: %v", err)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Stop stops the pod controller, the provider and the backend of the harness.
+func (h *Harness) Stop() {
+	h.cancel()
+	if h.running {
+		select {
+		case <-h.done:
+		case <-time.After(10 * time.Second):
+		}
+	}
+	if h.broadcaster != nil {
+		h.broadcaster.Shutdown()
+	}
+	h.Backend.Close()
+	os.Remove(h.configPath)
+}
+
+// ContainerGroupName is the name of the container group of a pod.
+func ContainerGroupName(namespace, name string) string {
+	return namespace + "-" + name
+}
+
+// CreatePod creates a pod scheduled on the virtual node. The fake API server does not default the pods, the restart
+// policy defaults to Always as with an API server.
+func (h *Harness) CreatePod(ctx context.Context, pod *v1.Pod) (*v1.Pod, error) {
+	pod = pod.DeepCopy()
+	pod.Spec.NodeName = NodeName
+	if pod.Spec.RestartPolicy == "" {
+		pod.Spec.RestartPolicy = v1.RestartPolicyAlways
+	}
+	if pod.CreationTimestamp.IsZero() {
+		pod.CreationTimestamp = metav1.Now()
+	}
+	return h.KubeClient.CoreV1().Pods(pod.Namespace).Create(ctx, pod, metav1.CreateOptions{})
+}
+
+// DeletePod deletes a pod, and waits for its container group to be deleted.
+func (h *Harness) DeletePod(ctx context.Context, namespace, name string) error {
+	if err := h.KubeClient.CoreV1().Pods(namespace).Delete(ctx, name, metav1.DeleteOptions{}); err != nil {
+		return err
+	}
+	return h.poll(ctx, func() (bool, error) {
+		_, ok := h.Backend.ContainerGroup(ContainerGroupName(namespace, name))
+		return !ok, nil
+	})
+}
+
+// WaitForPod waits for a pod to satisfy condition, and returns it. The pod is read from the informer of the provider,
+// so the provider sees the pod as returned once it is.
+func (h *Harness) WaitForPod(ctx context.Context, namespace, name string, condition func(*v1.Pod) bool) (*v1.Pod, error) {
+	var pod *v1.Pod
+	err := h.poll(ctx, func() (bool, error) {
+		var err error
+		pod, err = h.pods.Pods(namespace).Get(name)
+		if k8serr.IsNotFound(err) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		return condition(pod), nil
+	})
+	if pod != nil {
+		pod = pod.DeepCopy()
+	}
+	return pod, err
+}
+
+// WaitForPodPhase waits for a pod to reach a phase, and returns it.
+func (h *Harness) WaitForPodPhase(ctx context.Context, namespace, name string, phase v1.PodPhase) (*v1.Pod, error) {
+	pod, err := h.WaitForPod(ctx, namespace, name, func(pod *v1.Pod) bool {
+		return pod.Status.Phase == phase
+	})
+	if err != nil && pod != nil {
+		return pod, fmt.Errorf("pod %s/%s is %s, not %s: %v", namespace, name, pod.Status.Phase, phase, err)
+	}
+	return pod, err
+}
+
+// Events returns the events of an object of a namespace.
+func (h *Harness) Events(ctx context.Context, namespace, name string) ([]v1.Event, error) {
+	list, err := h.KubeClient.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	var events []v1.Event
+	for _, event := range list.Items {
+		if event.InvolvedObject.Name == name {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+func (h *Harness) poll(ctx context.Context, condition wait.ConditionFunc) error {
+	return wait.PollImmediateUntil(pollInterval, condition, ctx.Done())
+}
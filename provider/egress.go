@@ -0,0 +1,121 @@
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+
+	client "github.com/virtual-kubelet/azure-aci/client"
+)
+
+// defaultDataPlaneHost is the host pattern of the exec websockets of container groups.
+const defaultDataPlaneHost = "*.azurecontainer.io"
+
+// egressEndpoints are the endpoints the node connects to, for the firewall rules of clusters whose
+// egress is locked down.
+type egressEndpoints struct {
+	ControlPlane controlPlaneEndpoints `json:"controlPlane"`
+	DataPlane    dataPlaneEndpoints    `json:"dataPlane"`
+}
+
+// controlPlaneEndpoints are the Azure Resource Manager and Active Directory endpoints, reached through the proxy if any.
+type controlPlaneEndpoints struct {
+	Proxy     string   `json:"proxy,omitempty"`
+	Endpoints []string `json:"endpoints"`
+}
+
+// dataPlaneEndpoints are the host patterns of the exec websockets, reached directly.
+type dataPlaneEndpoints struct {
+	Endpoints []string `json:"endpoints"`
+}
+
+// setupEgress sets up the egress lockdown mode from the config file or the ACI_CONTROL_PLANE_PROXY and
+// ACI_DATA_PLANE_HOSTS environment variables. The requests to Azure Resource Manager and Active Directory
+// go through the control plane proxy, while the exec websockets connect directly to the container groups,
+// only to the hosts matching the data plane patterns.
+func (p *ACIProvider) setupEgress(azAuth *client.Authentication) error {
+	if v := os.Getenv("ACI_CONTROL_PLANE_PROXY"); v != "" {
+		p.controlPlaneProxy = v
+	}
+	if v := os.Getenv("ACI_DATA_PLANE_HOSTS"); v != "" {
+		p.dataPlaneHosts = nil
+		for _, host := range strings.Split(v, ",") {
+			if host = strings.TrimSpace(host); host != "" {
+				p.dataPlaneHosts = append(p.dataPlaneHosts, host)
+			}
+		}
+	}
+
+	if p.controlPlaneProxy != "" {
+		proxy, err := url.Parse(p.controlPlaneProxy)
+		if err != nil || (proxy.Scheme != "http" && proxy.Scheme != "https") || proxy.Host == "" {
+			return fmt.Errorf("invalid control plane proxy %q, expected an http or https URL", p.controlPlaneProxy)
+		}
+		azAuth.ControlPlaneProxy = proxy
+	}
+	for _, host := range p.dataPlaneHosts {
+		if _, err := path.Match(host, ""); err != nil {
+			return fmt.Errorf("invalid data plane host %q: %v", host, err)
+		}
+	}
+	if p.controlPlaneProxy != "" && len(p.dataPlaneHosts) == 0 {
+		p.dataPlaneHosts = []string{defaultDataPlaneHost}
+	}
+
+	p.egress = egressEndpoints{DataPlane: dataPlaneEndpoints{Endpoints: []string{}}}
+	if azAuth.ControlPlaneProxy != nil {
+		// Don't expose the credentials of the proxy.
+		proxy := *azAuth.ControlPlaneProxy
+		proxy.User = nil
+		p.egress.ControlPlane.Proxy = proxy.String()
+	}
+	p.egress.ControlPlane.Endpoints = append(p.egress.ControlPlane.Endpoints, endpointHost(azAuth.ResourceManagerEndpoint))
+	if !azAuth.UseUserIdentity {
+		p.egress.ControlPlane.Endpoints = append(p.egress.ControlPlane.Endpoints, endpointHost(azAuth.ActiveDirectoryEndpoint))
+	}
+	dataPlaneHosts := p.dataPlaneHosts
+	if len(dataPlaneHosts) == 0 {
+		dataPlaneHosts = []string{defaultDataPlaneHost}
+	}
+	for _, host := range dataPlaneHosts {
+		p.egress.DataPlane.Endpoints = append(p.egress.DataPlane.Endpoints, host+":443")
+	}
+	return nil
+}
+
+// endpointHost returns the host and port of an endpoint URL.
+func endpointHost(endpoint string) string {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return endpoint
+	}
+	if u.Port() != "" {
+		return u.Host
+	}
+	return net.JoinHostPort(u.Hostname(), "443")
+}
+
+// serveEgressEndpoints serves the endpoints the node connects to as JSON.
+func (p *ACIProvider) serveEgressEndpoints(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(p.egress)
+}
+
+// dataPlaneAllowed reports whether the exec websocket may connect to the host.
+func (p *ACIProvider) dataPlaneAllowed(host string) bool {
+	if len(p.dataPlaneHosts) == 0 {
+		return true
+	}
+	host = strings.ToLower(host)
+	for _, pattern := range p.dataPlaneHosts {
+		if ok, _ := path.Match(strings.ToLower(pattern), host); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,182 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"strings"
+
+	client "github.com/virtual-kubelet/azure-aci/client"
+	"github.com/virtual-kubelet/azure-aci/client/aci"
+	"github.com/virtual-kubelet/azure-aci/client/storage"
+	"github.com/virtual-kubelet/virtual-kubelet/errdefs"
+	"github.com/virtual-kubelet/virtual-kubelet/log"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// defaultEmptyDirMaxSize is the largest emptyDir volume stored in the local storage of a container group.
+const defaultEmptyDirMaxSize = "15Gi"
+
+// scratchStorage creates the Azure Files shares backing the emptyDir volumes too large for ACI.
+type scratchStorage interface {
+	CreateFileShare(ctx context.Context, resourceGroup, accountName, shareName string, share storage.FileShare) (*storage.FileShare, error)
+	DeleteFileShare(ctx context.Context, resourceGroup, accountName, shareName string) error
+	ListAccountKeys(ctx context.Context, resourceGroup, accountName string) (*storage.AccountListKeysResult, error)
+}
+
+// setupEmptyDirs reads the largest emptyDir volume of ACI from ACI_EMPTY_DIR_MAX_SIZE or the config
+// file, and the storage account of the shares of larger emptyDir volumes from ACI_SCRATCH_ACCOUNT and
+// ACI_SCRATCH_ACCOUNT_RESOURCE_GROUP, defaulting to the resource group of the container groups.
+func (p *ACIProvider) setupEmptyDirs(azAuth *client.Authentication) error {
+	if v := os.Getenv("ACI_EMPTY_DIR_MAX_SIZE"); v != "" {
+		p.emptyDirMaxSize = v
+	}
+	if p.emptyDirMaxSize == "" {
+		p.emptyDirMaxSize = defaultEmptyDirMaxSize
+	}
+	q, err := resource.ParseQuantity(p.emptyDirMaxSize)
+	if err != nil {
+		return fmt.Errorf("invalid emptyDir max size %q: %v", p.emptyDirMaxSize, err)
+	}
+	p.emptyDirLimit = q
+
+	if v := os.Getenv("ACI_SCRATCH_ACCOUNT"); v != "" {
+		p.scratchAccount = v
+	}
+	if v := os.Getenv("ACI_SCRATCH_ACCOUNT_RESOURCE_GROUP"); v != "" {
+		p.scratchAccountRG = v
+	}
+	if p.scratchAccount == "" {
+		return nil
+	}
+
+	p.scratchStorage, err = storage.NewClient(p.authFor(p.scratchResourceGroup(), azAuth), p.extraUserAgent)
+	if err != nil {
+		return fmt.Errorf("error setting up scratch storage: %v", err)
+	}
+	return nil
+}
+
+// scratchShareName returns the name of the share of an emptyDir volume of the pod, unique per pod
+// and volume and valid as a share name.
+func scratchShareName(pod *v1.Pod, volume string) string {
+	h := fnv.New32a()
+	h.Write([]byte(volume))
+	id := strings.ToLower(string(pod.UID))
+	if id == "" {
+		u := fnv.New64a()
+		u.Write([]byte(pod.Namespace + "/" + pod.Name))
+		id = fmt.Sprintf("%016x", u.Sum64())
+	}
+	return fmt.Sprintf("vk-%s-%08x", id, h.Sum32())
+}
+
+// needsScratchShare reports whether an emptyDir volume is larger than the local storage of ACI allows.
+func (p *ACIProvider) needsScratchShare(v *v1.EmptyDirVolumeSource) bool {
+	return v.SizeLimit != nil && v.SizeLimit.Cmp(p.emptyDirLimit) > 0
+}
+
+func (p *ACIProvider) scratchResourceGroup() string {
+	if p.scratchAccountRG != "" {
+		return p.scratchAccountRG
+	}
+	return p.resourceGroup
+}
+
+// getEmptyDirVolume translates an emptyDir volume to an ACI empty dir, or to an Azure Files share when
+// its size limit is larger than ACI supports. The memory medium is rejected, ACI has no tmpfs volumes.
+// The share is only created, and its key set, by provisionScratchShares once the container group is
+// created, the translation has no side effects.
+func (p *ACIProvider) getEmptyDirVolume(pod *v1.Pod, v v1.Volume) (aci.Volume, error) {
+	if v.EmptyDir.Medium == v1.StorageMediumMemory || strings.HasPrefix(string(v.EmptyDir.Medium), string(v1.StorageMediumHugePages)) {
+		return aci.Volume{}, errdefs.InvalidInputf("pod %s requires emptyDir volume %s with the %s medium, ACI only supports emptyDir volumes on disk", pod.Name, v.Name, v.EmptyDir.Medium)
+	}
+
+	if !p.needsScratchShare(v.EmptyDir) {
+		return aci.Volume{Name: v.Name, EmptyDir: map[string]interface{}{}}, nil
+	}
+	if p.scratchStorage == nil {
+		return aci.Volume{}, errdefs.InvalidInputf("pod %s requires emptyDir volume %s of %s, larger than the %s of ACI, set ACI_SCRATCH_ACCOUNT to store it in Azure Files", pod.Name, v.Name, v.EmptyDir.SizeLimit.String(), p.emptyDirLimit.String())
+	}
+
+	return aci.Volume{
+		Name: v.Name,
+		AzureFile: &aci.AzureFileVolume{
+			ShareName:          scratchShareName(pod, v.Name),
+			StorageAccountName: p.scratchAccount,
+		},
+	}, nil
+}
+
+// provisionScratchShares creates the shares of the emptyDir volumes of the pod stored in Azure Files, with
+// their size limit as quota, and sets the key of the storage account in the volumes of the container group.
+// Creating a share which exists, when the container group is created again, keeps it.
+func (p *ACIProvider) provisionScratchShares(ctx context.Context, pod *v1.Pod, cg *aci.ContainerGroup) error {
+	if p.scratchStorage == nil {
+		return nil
+	}
+
+	var key string
+	rg := p.scratchResourceGroup()
+	for _, v := range pod.Spec.Volumes {
+		if v.EmptyDir == nil || !p.needsScratchShare(v.EmptyDir) {
+			continue
+		}
+
+		const gi = 1 << 30
+		quota := (v.EmptyDir.SizeLimit.Value() + gi - 1) / gi
+		shareName := scratchShareName(pod, v.Name)
+		_, err := p.scratchStorage.CreateFileShare(ctx, rg, p.scratchAccount, shareName, storage.FileShare{
+			Properties: &storage.FileShareProperties{
+				ShareQuota: int32(quota),
+				Metadata: map[string]string{
+					"namespace": pod.Namespace,
+					"pod":       pod.Name,
+					"volume":    v.Name,
+				},
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("unable to create the share of emptyDir volume %s: %v", v.Name, err)
+		}
+
+		if key == "" {
+			keys, err := p.scratchStorage.ListAccountKeys(ctx, rg, p.scratchAccount)
+			if err != nil {
+				return fmt.Errorf("unable to get the key of storage account %s: %v", p.scratchAccount, err)
+			}
+			if len(keys.Keys) == 0 {
+				return fmt.Errorf("storage account %s has no key", p.scratchAccount)
+			}
+			key = keys.Keys[0].Value
+		}
+
+		for i := range cg.Volumes {
+			if f := cg.Volumes[i].AzureFile; f != nil && f.ShareName == shareName && f.StorageAccountName == p.scratchAccount {
+				f.StorageAccountKey = key
+			}
+		}
+	}
+	return nil
+}
+
+// deleteScratchShares deletes the shares of the emptyDir volumes of a deleted pod. Failures are logged
+// only, the pod is gone either way.
+func (p *ACIProvider) deleteScratchShares(ctx context.Context, pod *v1.Pod) {
+	if p.scratchStorage == nil {
+		return
+	}
+
+	for _, v := range pod.Spec.Volumes {
+		if v.EmptyDir == nil || !p.needsScratchShare(v.EmptyDir) {
+			continue
+		}
+		shareName := scratchShareName(pod, v.Name)
+		err := p.scratchStorage.DeleteFileShare(ctx, p.scratchResourceGroup(), p.scratchAccount, shareName)
+		if err != nil && !storage.IsNotFound(err) {
+			log.G(ctx).WithError(err).Warnf("failed to delete share %s of emptyDir volume %s", shareName, v.Name)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/virtual-kubelet/azure-aci/client/aci"
+	"github.com/virtual-kubelet/azure-aci/client/storage"
+	"github.com/virtual-kubelet/virtual-kubelet/errdefs"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type fakeScratchStorage struct {
+	shares map[string]int32
+}
+
+func (s *fakeScratchStorage) CreateFileShare(ctx context.Context, resourceGroup, accountName, shareName string, share storage.FileShare) (*storage.FileShare, error) {
+	s.shares[shareName] = share.Properties.ShareQuota
+	return &share, nil
+}
+
+func (s *fakeScratchStorage) DeleteFileShare(ctx context.Context, resourceGroup, accountName, shareName string) error {
+	delete(s.shares, shareName)
+	return nil
+}
+
+func (s *fakeScratchStorage) ListAccountKeys(ctx context.Context, resourceGroup, accountName string) (*storage.AccountListKeysResult, error) {
+	return &storage.AccountListKeysResult{Keys: []storage.AccountKey{{KeyName: "key1", Value: "secret"}}}, nil
+}
+
+func emptyDirPod(medium v1.StorageMedium, sizeLimit string) *v1.Pod {
+	emptyDir := &v1.EmptyDirVolumeSource{Medium: medium}
+	if sizeLimit != "" {
+		q := resource.MustParse(sizeLimit)
+		emptyDir.SizeLimit = &q
+	}
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "nginx", Namespace: "default", UID: "9c1e4fd4-5d3b-4b59-a0c4-4d1c1f5c2f43"},
+		Spec: v1.PodSpec{
+			Volumes: []v1.Volume{{Name: "scratch", VolumeSource: v1.VolumeSource{EmptyDir: emptyDir}}},
+		},
+	}
+}
+
+func TestEmptyDirVolume(t *testing.T) {
+	p := ACIProvider{}
+	assert.NilError(t, p.setupEmptyDirs(nil))
+
+	pod := emptyDirPod("", "1Gi")
+	volume, err := p.getEmptyDirVolume(pod, pod.Spec.Volumes[0])
+	assert.NilError(t, err)
+	assert.Check(t, volume.EmptyDir != nil && volume.AzureFile == nil, "Small emptyDir should stay an ACI empty dir")
+
+	pod = emptyDirPod(v1.StorageMediumMemory, "")
+	_, err = p.getEmptyDirVolume(pod, pod.Spec.Volumes[0])
+	assert.Check(t, errdefs.IsInvalidInput(err), "Memory medium should be rejected, got %v", err)
+
+	pod = emptyDirPod("", "100Gi")
+	_, err = p.getEmptyDirVolume(pod, pod.Spec.Volumes[0])
+	assert.Check(t, errdefs.IsInvalidInput(err), "Large emptyDir without scratch storage should be rejected, got %v", err)
+}
+
+func TestEmptyDirScratchShare(t *testing.T) {
+	shares := &fakeScratchStorage{shares: make(map[string]int32)}
+	p := ACIProvider{resourceGroup: fakeResourceGroup}
+	assert.NilError(t, p.setupEmptyDirs(nil))
+	p.scratchAccount = "vkscratch"
+	p.scratchStorage = shares
+
+	pod := emptyDirPod("", "20.5Gi")
+	volume, err := p.getEmptyDirVolume(pod, pod.Spec.Volumes[0])
+	assert.NilError(t, err)
+	assert.Assert(t, volume.AzureFile != nil, "Large emptyDir should be an Azure Files share")
+	assert.Check(t, is.Equal("vkscratch", volume.AzureFile.StorageAccountName))
+	assert.Check(t, len(volume.AzureFile.ShareName) <= 63, "Share name %s is too long", volume.AzureFile.ShareName)
+	assert.Check(t, is.Len(shares.shares, 0), "Share should not be created by the translation")
+
+	cg := &aci.ContainerGroup{ContainerGroupProperties: aci.ContainerGroupProperties{Volumes: []aci.Volume{volume}}}
+	assert.NilError(t, p.provisionScratchShares(context.Background(), pod, cg))
+	assert.Check(t, is.Equal("secret", cg.Volumes[0].AzureFile.StorageAccountKey))
+	assert.Check(t, is.Equal(int32(21), shares.shares[volume.AzureFile.ShareName]))
+
+	p.deleteScratchShares(context.Background(), pod)
+	assert.Check(t, is.Len(shares.shares, 0), "Share should be deleted with the pod")
+}
@@ -0,0 +1,64 @@
+package provider
+
+import (
+	"strings"
+
+	"github.com/virtual-kubelet/virtual-kubelet/errdefs"
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	containerWaitingReasonEphemeralNotSupported = "EphemeralContainersNotSupported"
+	ephemeralContainerNotSupportedMessage       = "ACI can not add containers to a running container group, use kubectl debug --copy-to to debug a copy of the pod, or kubectl exec into its containers"
+)
+
+// ephemeralContainerStatuses reports the ephemeral containers of the pod as waiting forever:
+// a container group can not get new containers without being recreated, which would kill the
+// containers being debugged.
+func ephemeralContainerStatuses(pod *v1.Pod) []v1.ContainerStatus {
+	if len(pod.Spec.EphemeralContainers) == 0 {
+		return nil
+	}
+
+	statuses := make([]v1.ContainerStatus, 0, len(pod.Spec.EphemeralContainers))
+	for _, c := range pod.Spec.EphemeralContainers {
+		statuses = append(statuses, v1.ContainerStatus{
+			Name:  c.Name,
+			Image: c.Image,
+			State: v1.ContainerState{
+				Waiting: &v1.ContainerStateWaiting{
+					Reason:  containerWaitingReasonEphemeralNotSupported,
+					Message: ephemeralContainerNotSupportedMessage,
+				},
+			},
+		})
+	}
+
+	return statuses
+}
+
+// rejectEphemeralContainers rejects the update of a pod adding ephemeral containers, the ones not reported in its
+// status yet, with a warning event on the pod: only their detection is supported, they are neither run nor
+// attached to.
+func (p *ACIProvider) rejectEphemeralContainers(pod *v1.Pod) error {
+	var added []string
+	for _, c := range pod.Spec.EphemeralContainers {
+		reported := false
+		for _, cs := range pod.Status.EphemeralContainerStatuses {
+			if cs.Name == c.Name {
+				reported = true
+				break
+			}
+		}
+		if !reported {
+			added = append(added, c.Name)
+		}
+	}
+	if len(added) == 0 {
+		return nil
+	}
+
+	names := strings.Join(added, ", ")
+	p.recordEvent(pod, v1.EventTypeWarning, containerWaitingReasonEphemeralNotSupported, "Rejected ephemeral containers %s: %s", names, ephemeralContainerNotSupportedMessage)
+	return errdefs.InvalidInputf("ephemeral containers %s of pod %s are not supported: %s", names, pod.Name, ephemeralContainerNotSupportedMessage)
+}
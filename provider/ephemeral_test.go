@@ -0,0 +1,59 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/virtual-kubelet/virtual-kubelet/errdefs"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestEphemeralContainerStatuses(t *testing.T) {
+	pod := &v1.Pod{}
+	assert.Check(t, is.Len(ephemeralContainerStatuses(pod), 0), "No ephemeral container status is expected")
+
+	pod.Spec.EphemeralContainers = []v1.EphemeralContainer{
+		{
+			EphemeralContainerCommon: v1.EphemeralContainerCommon{
+				Name:  "debugger",
+				Image: "busybox",
+			},
+			TargetContainerName: "nginx",
+		},
+	}
+
+	statuses := ephemeralContainerStatuses(pod)
+	assert.Assert(t, is.Len(statuses, 1))
+	assert.Check(t, is.Equal("debugger", statuses[0].Name), "Container name is not expected")
+	assert.Check(t, statuses[0].State.Waiting != nil, "Waiting state is expected")
+	assert.Check(t, is.Equal(containerWaitingReasonEphemeralNotSupported, statuses[0].State.Waiting.Reason), "Waiting reason is not expected")
+}
+
+func TestUpdatePodRejectsEphemeralContainers(t *testing.T) {
+	recorder := record.NewFakeRecorder(1)
+	p := ACIProvider{eventRecorder: recorder}
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "ns"}}
+	pod.Spec.EphemeralContainers = []v1.EphemeralContainer{
+		{EphemeralContainerCommon: v1.EphemeralContainerCommon{Name: "debugger", Image: "busybox"}},
+	}
+
+	// The update adding the container is rejected before anything is sent to ACI.
+	err := p.UpdatePod(context.Background(), pod)
+	assert.Check(t, errdefs.IsInvalidInput(err), "Expected an invalid input error, got %v", err)
+	assert.Check(t, is.ErrorContains(err, "debugger"))
+	select {
+	case event := <-recorder.Events:
+		assert.Check(t, is.Contains(event, containerWaitingReasonEphemeralNotSupported))
+		assert.Check(t, is.Contains(event, "debugger"))
+	default:
+		t.Error("Expected an event for the rejected ephemeral container")
+	}
+
+	// Once reported as waiting, the container doesn't reject the later updates.
+	pod.Status.EphemeralContainerStatuses = ephemeralContainerStatuses(pod)
+	assert.NilError(t, p.rejectEphemeralContainers(pod))
+}
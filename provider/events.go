@@ -0,0 +1,104 @@
+package provider
+
+import (
+	"context"
+	"os"
+
+	"github.com/virtual-kubelet/virtual-kubelet/log"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/record"
+)
+
+const eventSourceComponent = "virtual-kubelet"
+
+// newKubeClient creates a client of the API server, using the kubeconfig from KUBECONFIG or the
+// in-cluster configuration. Returns nil if none of them is available, in which case the features
+// talking to the API server, such as pod events, are disabled.
+func newKubeClient(ctx context.Context) kubernetes.Interface {
+	var config *rest.Config
+	var err error
+	if kubeconfig := os.Getenv("KUBECONFIG"); kubeconfig != "" {
+		config, err = clientcmd.BuildConfigFromFlags("", kubeconfig)
+	} else {
+		config, err = rest.InClusterConfig()
+	}
+	if err != nil {
+		log.G(ctx).WithError(err).Warn("Unable to load kubernetes client config, pod events are disabled")
+		return nil
+	}
+
+	kubeClient, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		log.G(ctx).WithError(err).Warn("Unable to create kubernetes client, pod events are disabled")
+		return nil
+	}
+
+	return kubeClient
+}
+
+// newEventRecorder creates a recorder posting pod events to the API server with the kube client.
+// Returns nil without a kube client, in which case events are only logged.
+func newEventRecorder(kubeClient kubernetes.Interface, nodeName string) record.EventRecorder {
+	if kubeClient == nil {
+		return nil
+	}
+
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&corev1.EventSinkImpl{Interface: kubeClient.CoreV1().Events("")})
+	return broadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: eventSourceComponent, Host: nodeName})
+}
+
+// recordContainerEvents records an event for each container entering a new waiting reason
+// worth reporting, comparing the last known status of the pod with the one from the provider.
+func (pt *PodsTracker) recordContainerEvents(ctx context.Context, pod *v1.Pod, status *v1.PodStatus) {
+	for _, cs := range status.ContainerStatuses {
+		if cs.State.Waiting == nil {
+			continue
+		}
+
+		eventType, reason, ok := imagePullEventForReason(cs.State.Waiting.Reason)
+		if !ok || containerWaitingReason(pod.Status.ContainerStatuses, cs.Name) == cs.State.Waiting.Reason {
+			continue
+		}
+
+		log.G(ctx).WithField("pod", pod.Name).WithField("container", cs.Name).Infof("%s: %s", reason, cs.State.Waiting.Message)
+		if pt.recorder != nil {
+			pt.recorder.Event(pod, eventType, reason, cs.State.Waiting.Message)
+		}
+	}
+}
+
+func containerWaitingReason(statuses []v1.ContainerStatus, name string) string {
+	for _, cs := range statuses {
+		if cs.Name == name && cs.State.Waiting != nil {
+			return cs.State.Waiting.Reason
+		}
+	}
+
+	return ""
+}
+
+// recordEvent records an event on the pod, if the recorder is available.
+func (p *ACIProvider) recordEvent(pod *v1.Pod, eventType, reason, messageFmt string, args ...interface{}) {
+	if p.eventRecorder == nil {
+		return
+	}
+
+	p.eventRecorder.Eventf(pod, eventType, reason, messageFmt, args...)
+}
+
+// recordNodeEvent records an event on the node, if the recorder is available.
+func (p *ACIProvider) recordNodeEvent(eventType, reason, messageFmt string, args ...interface{}) {
+	if p.eventRecorder == nil {
+		return
+	}
+
+	node := &v1.ObjectReference{Kind: "Node", Name: p.nodeName, UID: types.UID(p.nodeName)}
+	p.eventRecorder.Eventf(node, eventType, reason, messageFmt, args...)
+}
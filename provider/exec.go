@@ -0,0 +1,98 @@
+package provider
+
+import (
+	"context"
+	"io"
+	"strings"
+
+	"github.com/gorilla/websocket"
+)
+
+// execBufferSize is the size of the stdin chunks sent to the exec websocket.
+const execBufferSize = 32 * 1024
+
+// execCommand returns the command line to launch in the container.
+// ACI always runs exec commands in a terminal, which translates line endings and echoes the input.
+// Without a TTY on the client side (kubectl cp, or piping data through kubectl exec), the terminal
+// is switched to raw mode first so binary streams such as tar archives go through unchanged.
+func execCommand(cmd []string, tty bool) string {
+	if tty {
+		return strings.Join(cmd, " ")
+	}
+
+	quoted := make([]string, 0, len(cmd))
+	for _, arg := range cmd {
+		quoted = append(quoted, shellQuote(arg))
+	}
+
+	return `/bin/sh -c "` + doubleQuoteEscape("stty raw -echo 2>/dev/null; exec "+strings.Join(quoted, " ")) + `"`
+}
+
+// doubleQuoteEscape escapes the characters a POSIX shell interprets within double quotes, so the script given to
+// sh -c in double quotes, with its single quoted arguments, reaches it unchanged.
+func doubleQuoteEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '\\', '"', '$', '`':
+			b.WriteRune('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// shellQuote quotes an argument for a POSIX shell.
+func shellQuote(arg string) string {
+	if arg != "" && !strings.ContainsAny(arg, " \t\n'\"\\$`!*?[]{}()<>|&;#~") {
+		return arg
+	}
+
+	return "'" + strings.Replace(arg, "'", `'\''`, -1) + "'"
+}
+
+// copyToExecWebsocket sends the stdin stream to the exec websocket as binary messages,
+// until the stream or the context ends.
+func copyToExecWebsocket(ctx context.Context, c *websocket.Conn, in io.Reader) {
+	msg := make([]byte, execBufferSize)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		n, err := in.Read(msg)
+		if n > 0 { // Only call WriteMessage if there is data to send
+			if err := c.WriteMessage(websocket.BinaryMessage, msg[:n]); err != nil {
+				return
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// copyFromExecWebsocket copies the messages of the exec websocket, text or binary, to out
+// until the command exits and the websocket is closed.
+func copyFromExecWebsocket(ctx context.Context, c *websocket.Conn, out io.Writer) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		_, r, err := c.NextReader()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure) {
+				return err
+			}
+			return nil
+		}
+		if _, err := io.Copy(out, r); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package provider
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func TestExecCommand(t *testing.T) {
+	assert.Check(t, is.Equal("ls -la /tmp", execCommand([]string{"ls", "-la", "/tmp"}, true)), "TTY command is not expected")
+	assert.Check(t, is.Equal(`/bin/sh -c "stty raw -echo 2>/dev/null; exec tar cf - /tmp/foo"`, execCommand([]string{"tar", "cf", "-", "/tmp/foo"}, false)), "Non TTY command is not expected")
+	assert.Check(t, is.Equal(`/bin/sh -c "stty raw -echo 2>/dev/null; exec tar xf - -C '/tmp/my dir'"`, execCommand([]string{"tar", "xf", "-", "-C", "/tmp/my dir"}, false)), "Quoted command is not expected")
+}
+
+func TestShellQuote(t *testing.T) {
+	assert.Check(t, is.Equal("/tmp/foo", shellQuote("/tmp/foo")))
+	assert.Check(t, is.Equal("''", shellQuote("")))
+	assert.Check(t, is.Equal(`'it'\''s'`, shellQuote("it's")))
+}
+
+func TestExecCommandHostileArgs(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("no shell to run the command with")
+	}
+
+	args := []string{`a"b`, "$HOME", "`id`", "$(id)", "it's", `back\slash`, `"'$x'"`, "two  spaces", "semi;colon", ""}
+	cmd := execCommand(append([]string{"printf", "[%s]\n"}, args...), false)
+
+	// The command line is parsed like a shell would, as the container does.
+	out, err := exec.Command(sh, "-c", cmd).Output()
+	assert.NilError(t, err, cmd)
+	var expected []string
+	for _, arg := range args {
+		expected = append(expected, "["+arg+"]")
+	}
+	assert.Check(t, is.Equal(strings.Join(expected, "\n")+"\n", string(out)), cmd)
+}
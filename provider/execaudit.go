@@ -0,0 +1,174 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/virtual-kubelet/virtual-kubelet/log"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apiserver/pkg/endpoints/request"
+)
+
+const (
+	execSessionStarted   = "Started"
+	execSessionCompleted = "Completed"
+
+	// unknownExecUser is the user of the sessions whose request did not carry an authenticated user.
+	unknownExecUser = "unknown"
+)
+
+// execAuditRecord is a line of the exec audit log.
+type execAuditRecord struct {
+	Stage     string     `json:"stage"`
+	User      string     `json:"user"`
+	Groups    []string   `json:"groups,omitempty"`
+	Namespace string     `json:"namespace"`
+	Pod       string     `json:"pod"`
+	Container string     `json:"container"`
+	Command   []string   `json:"command"`
+	TTY       bool       `json:"tty"`
+	Start     time.Time  `json:"start"`
+	End       *time.Time `json:"end,omitempty"`
+	Error     string     `json:"error,omitempty"`
+}
+
+// execAuditLog writes the exec audit records as JSON lines, each sealed with the cipher if not nil.
+type execAuditLog struct {
+	mu     sync.Mutex
+	w      io.Writer
+	cipher *stateCipher
+}
+
+func (l *execAuditLog) write(record execAuditRecord) error {
+	b, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if l.cipher != nil {
+		if b, err = l.cipher.sealLine(b); err != nil {
+			return err
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	_, err = l.w.Write(append(b, '\n'))
+	return err
+}
+
+// setupExecAudit enables the exec audit log when ACI_EXEC_AUDIT_LOG is set, to stdout or to the path
+// of a file the records are appended to, encrypted with the state key if any, and the exec events when
+// ACI_EXEC_AUDIT_EVENTS is true.
+func (p *ACIProvider) setupExecAudit() error {
+	switch path := os.Getenv("ACI_EXEC_AUDIT_LOG"); path {
+	case "":
+	case "stdout":
+		p.execAudit = &execAuditLog{w: os.Stdout}
+	default:
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+		if err != nil {
+			return fmt.Errorf("error opening exec audit log: %v", err)
+		}
+		p.execAudit = &execAuditLog{w: f, cipher: p.stateCipher}
+	}
+
+	if v := os.Getenv("ACI_EXEC_AUDIT_EVENTS"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid ACI_EXEC_AUDIT_EVENTS %q: %v", v, err)
+		}
+		p.execAuditEvents = b
+	}
+
+	return nil
+}
+
+// execSession is an exec session being audited.
+type execSession struct {
+	p      *ACIProvider
+	ctx    context.Context
+	record execAuditRecord
+}
+
+// startExecSession audits the start of an exec session, with the user authenticated for the request.
+func (p *ACIProvider) startExecSession(ctx context.Context, namespace, name, container string, cmd []string, tty bool) *execSession {
+	record := execAuditRecord{
+		Stage:     execSessionStarted,
+		User:      unknownExecUser,
+		Namespace: namespace,
+		Pod:       name,
+		Container: container,
+		Command:   cmd,
+		TTY:       tty,
+		Start:     time.Now().UTC(),
+	}
+	if u, ok := request.UserFrom(ctx); ok && u.GetName() != "" {
+		record.User = u.GetName()
+		record.Groups = u.GetGroups()
+	}
+
+	s := &execSession{p: p, ctx: ctx, record: record}
+	s.write()
+
+	if p.execAuditEvents {
+		p.recordEvent(p.auditedPod(namespace, name), v1.EventTypeNormal, "ExecSession", "User %s started %q in container %s", record.User, strings.Join(cmd, " "), container)
+	}
+	return s
+}
+
+// end audits the end of the session, which failed with err if not nil.
+func (s *execSession) end(err error) {
+	end := time.Now().UTC()
+	s.record.Stage = execSessionCompleted
+	s.record.End = &end
+	if err != nil {
+		s.record.Error = err.Error()
+	}
+	s.write()
+}
+
+func (s *execSession) write() {
+	logger := log.G(s.ctx).WithFields(log.Fields{
+		"user":      s.record.User,
+		"namespace": s.record.Namespace,
+		"pod":       s.record.Pod,
+		"container": s.record.Container,
+	})
+	logger.Infof("exec session %s", strings.ToLower(s.record.Stage))
+
+	if s.p.execAudit == nil {
+		return
+	}
+	if err := s.p.execAudit.write(s.record); err != nil {
+		logger.WithError(err).Error("failed to write the exec audit log")
+	}
+}
+
+// auditedPod returns the pod of the resource manager by name, for the events to reference its UID.
+func (p *ACIProvider) auditedPod(namespace, name string) *v1.Pod {
+	if pod := p.managedPod(namespace, name); pod != nil {
+		return pod
+	}
+	return &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}}
+}
+
+// managedPod returns the pod of the resource manager by name, nil if it has none.
+func (p *ACIProvider) managedPod(namespace, name string) *v1.Pod {
+	if p.resourceManager != nil {
+		for _, pod := range p.resourceManager.GetPods() {
+			if pod.Namespace == namespace && pod.Name == name {
+				return pod
+			}
+		}
+	}
+	return nil
+}
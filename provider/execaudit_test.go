@@ -0,0 +1,85 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestExecAudit(t *testing.T) {
+	var buf bytes.Buffer
+	p := ACIProvider{execAudit: &execAuditLog{w: &buf}}
+
+	ctx := request.WithUser(context.Background(), &user.DefaultInfo{Name: "alice", Groups: []string{"system:authenticated"}})
+	session := p.startExecSession(ctx, "ns", "pod", "app", []string{"sh", "-c", "id"}, true)
+	session.end(errors.New("connection reset"))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Assert(t, is.Len(lines, 2))
+
+	var started, completed execAuditRecord
+	assert.NilError(t, json.Unmarshal([]byte(lines[0]), &started))
+	assert.NilError(t, json.Unmarshal([]byte(lines[1]), &completed))
+
+	assert.Check(t, is.Equal(execSessionStarted, started.Stage))
+	assert.Check(t, is.Equal("alice", started.User))
+	assert.Check(t, is.DeepEqual([]string{"system:authenticated"}, started.Groups))
+	assert.Check(t, is.DeepEqual([]string{"sh", "-c", "id"}, started.Command))
+	assert.Check(t, started.End == nil, "Started record should not have an end")
+
+	assert.Check(t, is.Equal(execSessionCompleted, completed.Stage))
+	assert.Check(t, completed.End != nil && !completed.End.Before(completed.Start), "Completed record should end after its start")
+	assert.Check(t, is.Equal("connection reset", completed.Error))
+}
+
+func TestExecAuditUnknownUser(t *testing.T) {
+	var buf bytes.Buffer
+	p := ACIProvider{execAudit: &execAuditLog{w: &buf}}
+
+	p.startExecSession(context.Background(), "ns", "pod", "app", []string{"ls"}, false)
+
+	var record execAuditRecord
+	assert.NilError(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Check(t, is.Equal(unknownExecUser, record.User))
+}
+
+func TestExecAuditUserOfTheKubeletAPI(t *testing.T) {
+	kubeClient := fake.NewSimpleClientset()
+	kubeClient.PrependReactor("create", "tokenreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authenticationv1.TokenReview)
+		review.Status.Authenticated = review.Spec.Token == "alice-token"
+		review.Status.User = authenticationv1.UserInfo{Username: "alice", Groups: []string{"system:authenticated"}}
+		return true, review, nil
+	})
+
+	var buf bytes.Buffer
+	p := ACIProvider{nodeName: fakeNodeName, kubeClient: kubeClient, execAudit: &execAuditLog{w: &buf}}
+	handler := p.authenticateRequests(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p.startExecSession(r.Context(), "ns", "pod", "app", []string{"ls"}, false).end(nil)
+	}))
+
+	r := httptest.NewRequest(http.MethodPost, "/exec/ns/pod/app?command=ls", nil)
+	r.Header.Set("Authorization", "Bearer alice-token")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Assert(t, is.Len(lines, 2))
+	var record execAuditRecord
+	assert.NilError(t, json.Unmarshal([]byte(lines[0]), &record))
+	assert.Check(t, is.Equal("alice", record.User))
+	assert.Check(t, is.DeepEqual([]string{"system:authenticated"}, record.Groups))
+}
@@ -0,0 +1,117 @@
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/virtual-kubelet/azure-aci/client/aci"
+	"github.com/virtual-kubelet/virtual-kubelet/errdefs"
+	v1 "k8s.io/api/core/v1"
+)
+
+// extensionsAnnotation is a JSON array of the container group extensions of a pod, in the format of the
+// extensions of the ACI API: [{"name": "...", "properties": {"extensionType": "...", "version": "...", "settings": {}}}].
+const extensionsAnnotation = "virtual-kubelet.io/extensions"
+
+// containerGroupExtension is an extension added to every container group, from Extensions in the config file.
+type containerGroupExtension struct {
+	Name    string
+	Type    string
+	Version string
+	// Settings are the settings of the extension.
+	Settings map[string]string
+	// ProtectedSettings are the settings of the extension ACI does not return, like credentials.
+	ProtectedSettings map[string]string
+}
+
+func (e containerGroupExtension) validate() error {
+	if e.Name == "" || e.Type == "" || e.Version == "" {
+		return fmt.Errorf("the Name, Type and Version of extension %q must be set", e.Name)
+	}
+	return nil
+}
+
+func (e containerGroupExtension) extension() *aci.Extension {
+	return &aci.Extension{
+		Name: e.Name,
+		Properties: &aci.ExtensionProperties{
+			Type:              aci.ExtensionType(e.Type),
+			Version:           aci.ExtensionVersion(e.Version),
+			Settings:          e.Settings,
+			ProtectedSettings: e.ProtectedSettings,
+		},
+	}
+}
+
+// setupExtensions validates the extensions of the config file.
+func (p *ACIProvider) setupExtensions() error {
+	names := make(map[string]bool, len(p.extensions))
+	for _, e := range p.extensions {
+		if err := e.validate(); err != nil {
+			return fmt.Errorf("invalid extension: %v", err)
+		}
+		if names[e.Name] {
+			return fmt.Errorf("invalid extension: %q is defined twice", e.Name)
+		}
+		names[e.Name] = true
+	}
+	return nil
+}
+
+// podExtensions parses the extensions of the extensions annotation of a pod. Annotations are readable by anyone who
+// can read the pod, so they can't carry protected settings.
+func podExtensions(pod *v1.Pod) ([]*aci.Extension, error) {
+	value, ok := pod.Annotations[extensionsAnnotation]
+	if !ok {
+		return nil, nil
+	}
+	var extensions []*aci.Extension
+	if err := json.Unmarshal([]byte(value), &extensions); err != nil {
+		return nil, errdefs.InvalidInputf("pod %s: invalid %s annotation: %v", pod.Name, extensionsAnnotation, err)
+	}
+	for _, e := range extensions {
+		if e == nil || e.Name == "" || e.Properties == nil || e.Properties.Type == "" || e.Properties.Version == "" {
+			return nil, errdefs.InvalidInputf("pod %s: the extensions of the %s annotation need a name, an extensionType and a version", pod.Name, extensionsAnnotation)
+		}
+		if len(e.Properties.ProtectedSettings) > 0 {
+			return nil, errdefs.InvalidInputf("pod %s: extension %s of the %s annotation can't have protected settings, define it in the provider config instead", pod.Name, e.Name, extensionsAnnotation)
+		}
+	}
+	return extensions, nil
+}
+
+// applyExtensions adds the extensions of the config file and of the pod to its container group, after the ones of
+// the provider, like kube-proxy. An extension can't replace another one of the same name.
+func (p *ACIProvider) applyExtensions(pod *v1.Pod, cg *aci.ContainerGroup) error {
+	annotated, err := podExtensions(pod)
+	if err != nil {
+		return err
+	}
+
+	names := make(map[string]bool, len(cg.Extensions))
+	for _, e := range cg.Extensions {
+		if e != nil {
+			names[e.Name] = true
+		}
+	}
+	add := func(e *aci.Extension) error {
+		if names[e.Name] {
+			return errdefs.InvalidInputf("pod %s: extension %s is already added to the container group", pod.Name, e.Name)
+		}
+		names[e.Name] = true
+		cg.Extensions = append(cg.Extensions, e)
+		return nil
+	}
+
+	for _, e := range p.extensions {
+		if err := add(e.extension()); err != nil {
+			return err
+		}
+	}
+	for _, e := range annotated {
+		if err := add(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
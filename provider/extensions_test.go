@@ -0,0 +1,41 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/virtual-kubelet/azure-aci/client/aci"
+	"github.com/virtual-kubelet/virtual-kubelet/errdefs"
+	"gotest.tools/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestApplyExtensions(t *testing.T) {
+	p := ACIProvider{extensions: []containerGroupExtension{{
+		Name:              "log-forwarder",
+		Type:              "log-forwarder",
+		Version:           "1.0",
+		ProtectedSettings: map[string]string{"token": "s3cr3t"},
+	}}}
+	assert.NilError(t, p.setupExtensions())
+
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web", Annotations: map[string]string{
+		extensionsAnnotation: `[{"name": "metrics", "properties": {"extensionType": "realtime-metrics", "version": "1.0"}}]`,
+	}}}
+	cg := &aci.ContainerGroup{}
+	cg.Extensions = []*aci.Extension{{Name: "kube-proxy", Properties: &aci.ExtensionProperties{Type: aci.ExtensionTypeKubeProxy, Version: aci.ExtensionVersion1_0}}}
+	assert.NilError(t, p.applyExtensions(pod, cg))
+	assert.Equal(t, len(cg.Extensions), 3)
+	assert.Equal(t, cg.Extensions[1].Properties.ProtectedSettings["token"], "s3cr3t")
+	assert.Equal(t, cg.Extensions[2].Properties.Type, aci.ExtensionType("realtime-metrics"))
+
+	pod.Annotations[extensionsAnnotation] = `[{"name": "kube-proxy", "properties": {"extensionType": "kube-proxy", "version": "1.0"}}]`
+	cg.Extensions = cg.Extensions[:1]
+	assert.Assert(t, errdefs.IsInvalidInput(p.applyExtensions(pod, cg)), "The kube-proxy of the provider can't be replaced")
+
+	pod.Annotations[extensionsAnnotation] = `[{"name": "metrics", "properties": {"extensionType": "realtime-metrics", "version": "1.0", "protectedSettings": {"key": "v"}}}]`
+	assert.Assert(t, errdefs.IsInvalidInput(p.applyExtensions(pod, cg)), "Annotations can't carry protected settings")
+
+	p.extensions = append(p.extensions, containerGroupExtension{Name: "log-forwarder", Type: "log-forwarder", Version: "1.0"})
+	assert.ErrorContains(t, p.setupExtensions(), "defined twice")
+}
@@ -0,0 +1,71 @@
+package provider
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/virtual-kubelet/azure-aci/client/aci"
+)
+
+// parseFaults parses the faults injected in the requests to ARM, a comma separated list of key=value among seed,
+// latency, latency-jitter, throttle, retry-after, drop-create and partial-list, like
+// "latency=200ms,throttle=0.1,seed=42".
+func parseFaults(v string) (aci.FaultConfig, error) {
+	var cfg aci.FaultConfig
+	for _, field := range strings.Split(v, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		kv := strings.SplitN(field, "=", 2)
+		if len(kv) != 2 {
+			return cfg, fmt.Errorf("invalid fault %q, expected key=value", field)
+		}
+		key, value := strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1])
+
+		var err error
+		switch key {
+		case "seed":
+			cfg.Seed, err = strconv.ParseInt(value, 10, 64)
+		case "latency":
+			cfg.Latency, err = time.ParseDuration(value)
+		case "latency-jitter":
+			cfg.LatencyJitter, err = time.ParseDuration(value)
+		case "retry-after":
+			cfg.RetryAfter, err = time.ParseDuration(value)
+		case "throttle":
+			cfg.ThrottleRate, err = strconv.ParseFloat(value, 64)
+		case "drop-create":
+			cfg.DropCreateRate, err = strconv.ParseFloat(value, 64)
+		case "partial-list":
+			cfg.PartialListRate, err = strconv.ParseFloat(value, 64)
+		default:
+			return cfg, fmt.Errorf("unknown fault %q", key)
+		}
+		if err != nil {
+			return cfg, fmt.Errorf("invalid fault %q: %v", field, err)
+		}
+	}
+	return cfg, cfg.Validate()
+}
+
+// setupFaultInjection injects the faults of Faults in the config file, or ACI_FAULTS, in the requests to ARM, to test
+// how the provider handles a degraded ARM. Only the builds with the faultinjection tag inject faults, the others fail
+// to start with faults set.
+func (p *ACIProvider) setupFaultInjection() error {
+	if v := os.Getenv("ACI_FAULTS"); v != "" {
+		p.faults = v
+	}
+	if p.faults == "" {
+		return nil
+	}
+
+	cfg, err := parseFaults(p.faults)
+	if err != nil {
+		return fmt.Errorf("invalid ACI_FAULTS %q: %v", p.faults, err)
+	}
+	return p.aciClient.EnableFaultInjection(cfg)
+}
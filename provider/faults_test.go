@@ -0,0 +1,30 @@
+package provider
+
+import (
+	"testing"
+	"time"
+
+	"github.com/virtual-kubelet/azure-aci/client/aci"
+	"gotest.tools/assert"
+)
+
+func TestParseFaults(t *testing.T) {
+	cfg, err := parseFaults("latency=200ms, latency-jitter=50ms,throttle=0.1,retry-after=2s,drop-create=0.05,partial-list=0.2,seed=42")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, cfg, aci.FaultConfig{
+		Seed:            42,
+		Latency:         200 * time.Millisecond,
+		LatencyJitter:   50 * time.Millisecond,
+		ThrottleRate:    0.1,
+		RetryAfter:      2 * time.Second,
+		DropCreateRate:  0.05,
+		PartialListRate: 0.2,
+	})
+
+	_, err = parseFaults("throttle")
+	assert.ErrorContains(t, err, "expected key=value")
+	_, err = parseFaults("timeout=1s")
+	assert.ErrorContains(t, err, "unknown fault")
+	_, err = parseFaults("throttle=1.5")
+	assert.ErrorContains(t, err, "invalid throttle rate")
+}
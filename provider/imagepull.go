@@ -0,0 +1,281 @@
+package provider
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/virtual-kubelet/azure-aci/client/aci"
+	"github.com/virtual-kubelet/azure-aci/client/registry"
+	"github.com/virtual-kubelet/virtual-kubelet/log"
+	v1 "k8s.io/api/core/v1"
+)
+
+// Waiting reasons reported while ACI pulls the image of a container, matching the kubelet ones.
+const (
+	containerWaitingReasonCreating  = "ContainerCreating"
+	containerWaitingReasonPullError = "ErrImagePull"
+	containerWaitingReasonBackOff   = "ImagePullBackOff"
+)
+
+// Names of the ACI container events related to image pulls.
+const (
+	aciEventPulling = "Pulling"
+	aciEventPulled  = "Pulled"
+	aciEventFailed  = "Failed"
+	aciEventBackOff = "BackOff"
+)
+
+// imagePullWaitingState returns the waiting state matching the latest image pull event of a container,
+// or nil if the image is not being pulled or was pulled successfully.
+func imagePullWaitingState(events []aci.Event) *v1.ContainerStateWaiting {
+	var latest *aci.Event
+	for i := range events {
+		e := &events[i]
+		if !isImagePullEvent(e) {
+			continue
+		}
+		if latest == nil || !time.Time(e.LastTimestamp).Before(time.Time(latest.LastTimestamp)) {
+			latest = e
+		}
+	}
+
+	if latest == nil {
+		return nil
+	}
+
+	switch latest.Name {
+	case aciEventPulling:
+		return &v1.ContainerStateWaiting{Reason: containerWaitingReasonCreating, Message: latest.Message}
+	case aciEventFailed:
+		return &v1.ContainerStateWaiting{Reason: containerWaitingReasonPullError, Message: latest.Message}
+	case aciEventBackOff:
+		return &v1.ContainerStateWaiting{Reason: containerWaitingReasonBackOff, Message: latest.Message}
+	}
+
+	return nil
+}
+
+// isImagePullEvent reports whether an ACI event relates to pulling the image.
+// Failed and BackOff events are also raised for crashing containers, only those about pulls are kept.
+func isImagePullEvent(e *aci.Event) bool {
+	switch e.Name {
+	case aciEventPulling, aciEventPulled:
+		return true
+	case aciEventFailed, aciEventBackOff:
+		return strings.Contains(strings.ToLower(e.Message), "pull")
+	}
+
+	return false
+}
+
+// imagePullEventForReason returns the type and reason of the pod event to record for a waiting reason.
+func imagePullEventForReason(waitingReason string) (string, string, bool) {
+	switch waitingReason {
+	case containerWaitingReasonCreating:
+		return v1.EventTypeNormal, aciEventPulling, true
+	case containerWaitingReasonPullError:
+		return v1.EventTypeWarning, aciEventFailed, true
+	case containerWaitingReasonBackOff:
+		return v1.EventTypeWarning, aciEventBackOff, true
+	case containerWaitingReasonCrashLoopBackOff:
+		// Like the kubelet, the crash loops are reported as a BackOff too.
+		return v1.EventTypeWarning, aciEventBackOff, true
+	}
+
+	return "", "", false
+}
+
+var imageDigestRegexp = regexp.MustCompile(`sha256:[0-9a-f]{64}`)
+
+// getImageID returns the image reference pinned to the digest the container runs, or an empty string
+// if the digest is unknown. ACI does not report the resolved digest in the instance view, so it is taken
+// from the image itself when pinned, or from the message of the latest pulled event.
+func getImageID(c aci.Container) string {
+	repository := c.Image
+	if i := strings.Index(repository, "@"); i >= 0 {
+		if imageDigestRegexp.MatchString(repository[i+1:]) {
+			return repository
+		}
+		repository = repository[:i]
+	}
+
+	var latest *aci.Event
+	for i := range c.InstanceView.Events {
+		e := &c.InstanceView.Events[i]
+		if e.Name != aciEventPulled || !imageDigestRegexp.MatchString(e.Message) {
+			continue
+		}
+		if latest == nil || !time.Time(e.LastTimestamp).Before(time.Time(latest.LastTimestamp)) {
+			latest = e
+		}
+	}
+
+	if latest == nil {
+		return ""
+	}
+
+	// Strip the tag, but not the port of the registry host.
+	if i := strings.LastIndex(repository, ":"); i > strings.LastIndex(repository, "/") {
+		repository = repository[:i]
+	}
+
+	return repository + "@" + imageDigestRegexp.FindString(latest.Message)
+}
+
+const eventReasonImagePullPolicy = "ImagePullPolicy"
+
+// restartOnImageUpdateAnnotation set to "true" restarts the container group of the pod in place once ACI restarted
+// one of its containers with imagePullPolicy Always and the tag of its image points to another image, so the
+// updated image is pulled, see checkImagePullAlways.
+const restartOnImageUpdateAnnotation = "virtual-kubelet.io/restart-on-image-update"
+
+const (
+	// imagePullAlwaysInterval is how often the container groups of the annotated pods are checked.
+	imagePullAlwaysInterval = time.Minute
+	// imagePullAlwaysBackoff is the minimum time between two restarts of a container group to pull its images.
+	imagePullAlwaysBackoff = 10 * time.Minute
+)
+
+// imageDigestResolver resolves the digest an image tag points to in its registry.
+type imageDigestResolver interface {
+	Digest(ctx context.Context, image, username, password string) (string, error)
+}
+
+// recordImagePullPolicyEvents describes how ACI honors the image pull policy of each container.
+// ACI pulls the images when the container group is created, restarted or started, pulling a tag again if it
+// was updated, but restarts the crashed containers in place with the image they were started with. Always can be
+// honored by restarting the container group, see checkImagePullAlways, while IfNotPresent and Never can only be
+// approximated.
+func (p *ACIProvider) recordImagePullPolicyEvents(ctx context.Context, pod *v1.Pod) {
+	for _, c := range pod.Spec.Containers {
+		switch c.ImagePullPolicy {
+		case v1.PullNever:
+			log.G(ctx).Warnf("container %s of pod %s uses imagePullPolicy Never, image %s is pulled by ACI anyway", c.Name, pod.Name, c.Image)
+			p.recordEvent(pod, v1.EventTypeWarning, eventReasonImagePullPolicy,
+				"Container %s: imagePullPolicy Never is not supported by ACI, image %q is pulled from the registry", c.Name, c.Image)
+		case v1.PullIfNotPresent:
+			p.recordEvent(pod, v1.EventTypeNormal, eventReasonImagePullPolicy,
+				"Container %s: image %q is pulled from the registry unless ACI has it cached", c.Name, c.Image)
+		case v1.PullAlways:
+			if strings.Contains(c.Image, "@") {
+				continue
+			}
+			if pod.Annotations[restartOnImageUpdateAnnotation] == "true" {
+				p.recordEvent(pod, v1.EventTypeNormal, eventReasonImagePullPolicy,
+					"Container %s: tag of image %q is resolved again on every container group creation, restart and start, the container group is restarted when the container restarts and the tag was updated", c.Name, c.Image)
+				continue
+			}
+			p.recordEvent(pod, v1.EventTypeNormal, eventReasonImagePullPolicy,
+				"Container %s: tag of image %q is resolved again on every container group creation, restart and start, not when ACI restarts the container", c.Name, c.Image)
+		}
+	}
+}
+
+// imagePullAlwaysContainers returns the containers of the pod pulling an image tag always.
+func imagePullAlwaysContainers(pod *v1.Pod) map[string]bool {
+	always := make(map[string]bool)
+	for _, c := range pod.Spec.Containers {
+		if c.ImagePullPolicy == v1.PullAlways && !strings.Contains(c.Image, "@") {
+			always[c.Name] = true
+		}
+	}
+	return always
+}
+
+// imagePullAlwaysLoop checks the container groups of the running pods with the restart on image update annotation
+// until the context is done.
+func (p *ACIProvider) imagePullAlwaysLoop(ctx context.Context) {
+	ticker := time.NewTicker(imagePullAlwaysInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for _, pod := range p.resourceManager.GetPods() {
+			p.checkImagePullAlways(ctx, pod)
+		}
+	}
+}
+
+// checkImagePullAlways restarts the container group of a running pod with the restart on image update annotation
+// once ACI restarted in place one of its containers with imagePullPolicy Always, and the tag of its image points to
+// another digest than the one it runs, so ACI pulls the updated image like the kubelet does before starting a
+// container. The other containers of the pod are restarted too, so the container group is restarted at most once
+// per imagePullAlwaysBackoff, and the restart resets the restart counts ACI reports, so it is only restarted again
+// when a container restarts again.
+func (p *ACIProvider) checkImagePullAlways(ctx context.Context, pod *v1.Pod) {
+	if pod.Annotations[restartOnImageUpdateAnnotation] != "true" || pod.Status.Phase != v1.PodRunning || p.imageDigests == nil {
+		return
+	}
+	always := imagePullAlwaysContainers(pod)
+	if len(always) == 0 {
+		return
+	}
+
+	logger := log.G(ctx).WithField("pod", pod.Name).WithField("namespace", pod.Namespace)
+	cg, err := p.getContainerGroup(ctx, pod.Namespace, pod.Name)
+	if err != nil {
+		logger.WithError(err).Debug("failed to get the container group to check the images pulled always")
+		return
+	}
+	// The restart counts are only reset once the containers started again after the last restart.
+	restartedAt := parseRestartBaseline(cg.Tags[restartCountsTag]).at
+	if time.Since(restartedAt) < imagePullAlwaysBackoff {
+		return
+	}
+
+	var creds []aci.ImageRegistryCredential
+	credsRead := false
+	for i := range cg.Containers {
+		c := &cg.Containers[i]
+		if !always[c.Name] || c.InstanceView.RestartCount == 0 ||
+			!time.Time(c.InstanceView.CurrentState.StartTime).After(restartedAt) {
+			continue
+		}
+		running := getImageID(*c)
+		if running == "" {
+			continue
+		}
+
+		if !credsRead {
+			if creds, err = p.getImagePullSecrets(pod); err != nil {
+				logger.WithError(err).Warn("failed to get the image pull secrets to check the images pulled always")
+			}
+			credsRead = true
+		}
+		username, password := imageCredential(creds, c.Image)
+		digest, err := p.imageDigests.Digest(ctx, c.Image, username, password)
+		if err != nil {
+			logger.WithError(err).Warnf("failed to resolve the digest of image %s of container %s", c.Image, c.Name)
+			continue
+		}
+		if strings.HasSuffix(running, "@"+digest) {
+			continue
+		}
+
+		logger.Infof("restarting container group %s to pull the updated image %s of container %s", cg.Name, c.Image, c.Name)
+		if err := p.restartContainerGroup(ctx, cg); err != nil {
+			return
+		}
+		p.recordEvent(pod, v1.EventTypeNormal, eventReasonImagePullPolicy,
+			"Container %s restarted with imagePullPolicy Always and image %q was updated to %s, restarted container group %s to pull it", c.Name, c.Image, digest, cg.Name)
+		return
+	}
+}
+
+// imageCredential returns the username and password of the registry of an image, if any.
+func imageCredential(creds []aci.ImageRegistryCredential, image string) (string, string) {
+	server := registry.ParseReference(image).Registry
+	for _, c := range creds {
+		if registry.NormalizeServer(c.Server) == server {
+			return c.Username, c.Password
+		}
+	}
+	return "", ""
+}
@@ -0,0 +1,277 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/virtual-kubelet/azure-aci/client/aci"
+	"github.com/virtual-kubelet/azure-aci/client/api"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestImagePullWaitingState(t *testing.T) {
+	now := time.Now()
+	event := func(name, message string, age time.Duration) aci.Event {
+		return aci.Event{
+			Name:          name,
+			Message:       message,
+			LastTimestamp: api.JSONTime(now.Add(-age)),
+		}
+	}
+
+	cases := []struct {
+		description string
+		events      []aci.Event
+		reason      string
+	}{
+		{
+			description: "no events",
+		},
+		{
+			description: "pulling",
+			events:      []aci.Event{event("Pulling", "pulling image \"nginx\"", 0)},
+			reason:      containerWaitingReasonCreating,
+		},
+		{
+			description: "pulled",
+			events: []aci.Event{
+				event("Pulling", "pulling image \"nginx\"", time.Minute),
+				event("Pulled", "Successfully pulled image \"nginx\"", 0),
+			},
+		},
+		{
+			description: "pull failure",
+			events: []aci.Event{
+				event("Pulling", "pulling image \"nginx:nope\"", time.Minute),
+				event("Failed", "Failed to pull image \"nginx:nope\": manifest unknown", 0),
+			},
+			reason: containerWaitingReasonPullError,
+		},
+		{
+			description: "pull back off",
+			events: []aci.Event{
+				event("Failed", "Failed to pull image \"nginx:nope\": manifest unknown", time.Minute),
+				event("BackOff", "Back-off pulling image \"nginx:nope\"", 0),
+			},
+			reason: containerWaitingReasonBackOff,
+		},
+		{
+			description: "crash back off",
+			events: []aci.Event{
+				event("Pulled", "Successfully pulled image \"nginx\"", time.Minute),
+				event("BackOff", "Back-off restarting failed container", 0),
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			waiting := imagePullWaitingState(c.events)
+			if c.reason == "" {
+				assert.Check(t, is.Nil(waiting), "No waiting state is expected")
+				return
+			}
+
+			assert.Assert(t, waiting != nil, "A waiting state is expected")
+			assert.Check(t, is.Equal(c.reason, waiting.Reason), "Waiting reason is not expected")
+			assert.Check(t, is.Equal(c.events[len(c.events)-1].Message, waiting.Message), "Waiting message is not expected")
+		})
+	}
+}
+
+func TestGetImageID(t *testing.T) {
+	digest := "sha256:" + strings.Repeat("ab", 32)
+	pulled := func(message string) []aci.Event {
+		return []aci.Event{
+			{
+				Name:          "Pulled",
+				Message:       message,
+				LastTimestamp: api.JSONTime(time.Now()),
+			},
+		}
+	}
+
+	cases := []struct {
+		description string
+		image       string
+		events      []aci.Event
+		imageID     string
+	}{
+		{
+			description: "pinned image",
+			image:       "nginx@" + digest,
+			imageID:     "nginx@" + digest,
+		},
+		{
+			description: "unknown digest",
+			image:       "nginx:1.19",
+			events:      pulled("Successfully pulled image \"nginx:1.19\""),
+		},
+		{
+			description: "digest from pulled event",
+			image:       "myregistry.azurecr.io:443/app:v1",
+			events:      pulled("Successfully pulled image \"myregistry.azurecr.io:443/app:v1\" with digest " + digest),
+			imageID:     "myregistry.azurecr.io:443/app@" + digest,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			container := aci.Container{
+				Name: "app",
+				ContainerProperties: aci.ContainerProperties{
+					Image: c.image,
+					InstanceView: aci.ContainerPropertiesInstanceView{
+						Events: c.events,
+					},
+				},
+			}
+
+			assert.Check(t, is.Equal(c.imageID, getImageID(container)), "Image ID is not expected")
+		})
+	}
+}
+
+func TestImagePullAlwaysContainers(t *testing.T) {
+	pod := &v1.Pod{Spec: v1.PodSpec{Containers: []v1.Container{
+		{Name: "app", Image: "app:latest", ImagePullPolicy: v1.PullAlways},
+		{Name: "pinned", Image: "app@sha256:0123", ImagePullPolicy: v1.PullAlways},
+		{Name: "sidecar", Image: "sidecar:1.0", ImagePullPolicy: v1.PullIfNotPresent},
+		{Name: "agent", Image: "agent", ImagePullPolicy: v1.PullAlways},
+	}}}
+	assert.Check(t, is.Equal("app,agent", imagePullAlwaysContainers(pod)))
+	assert.Check(t, is.Equal("", imagePullAlwaysContainers(&v1.Pod{})))
+}
+
+type fakeImageDigests map[string]string
+
+func (f fakeImageDigests) Digest(ctx context.Context, image, username, password string) (string, error) {
+	return f[image], nil
+}
+
+// imagePullAlwaysContainerGroup returns a container group whose container runs the image app:latest at the digest
+// pulled, restarted restartCount times by ACI.
+func imagePullAlwaysContainerGroup(name, pulled string, restartCount int32) *aci.ContainerGroup {
+	startTime := time.Now().Add(-time.Minute)
+	return &aci.ContainerGroup{
+		Name: "ns-pod",
+		Tags: map[string]string{"UID": "uid", "NodeName": fakeNodeName},
+		ContainerGroupProperties: aci.ContainerGroupProperties{
+			Containers: []aci.Container{{
+				Name: name,
+				ContainerProperties: aci.ContainerProperties{
+					Image: "app:latest",
+					InstanceView: aci.ContainerPropertiesInstanceView{
+						RestartCount: restartCount,
+						CurrentState: aci.ContainerState{
+							State:     "Running",
+							StartTime: api.JSONTime(startTime),
+						},
+						Events: []aci.Event{{
+							Name:          aciEventPulled,
+							Message:       "Successfully pulled image \"app:latest\" with digest " + pulled,
+							LastTimestamp: api.JSONTime(startTime.Add(-time.Minute)),
+						}},
+					},
+				},
+			}},
+		},
+	}
+}
+
+func imagePullAlwaysPod(annotated bool) *v1.Pod {
+	pod := &v1.Pod{}
+	pod.Namespace, pod.Name, pod.UID = "ns", "pod", "uid"
+	if annotated {
+		pod.Annotations = map[string]string{restartOnImageUpdateAnnotation: "true"}
+	}
+	pod.Spec.Containers = []v1.Container{{Name: "app", Image: "app:latest", ImagePullPolicy: v1.PullAlways}}
+	pod.Status.Phase = v1.PodRunning
+	return pod
+}
+
+func TestCheckImagePullAlways(t *testing.T) {
+	_, aciServerMocker, provider, err := prepareMocks()
+	if err != nil {
+		t.Fatal("Unable to prepare the mocks", err)
+	}
+
+	pulled := "sha256:" + strings.Repeat("1", 64)
+	updated := "sha256:" + strings.Repeat("2", 64)
+	provider.imageDigests = fakeImageDigests{"app:latest": updated}
+
+	restarts := 0
+	aciServerMocker.OnAction = func(subscription, resourceGroup, containerGroup, action string) (int, interface{}) {
+		assert.Check(t, is.Equal("restart", action))
+		restarts++
+		return http.StatusNoContent, nil
+	}
+	var tags map[string]string
+	aciServerMocker.OnUpdateTags = func(subscription, resourceGroup, containerGroup string, t map[string]string) (int, interface{}) {
+		tags = t
+		return http.StatusOK, nil
+	}
+	var cg *aci.ContainerGroup
+	aciServerMocker.OnGetContainerGroup = func(subscription, resourceGroup, containerGroup string) (int, interface{}) {
+		return http.StatusOK, cg
+	}
+
+	cg = imagePullAlwaysContainerGroup("app", pulled, 1)
+	provider.checkImagePullAlways(context.Background(), imagePullAlwaysPod(false))
+	assert.Check(t, is.Equal(0, restarts), "the pod didn't opt in")
+
+	cg = imagePullAlwaysContainerGroup("app", pulled, 0)
+	provider.checkImagePullAlways(context.Background(), imagePullAlwaysPod(true))
+	assert.Check(t, is.Equal(0, restarts), "no container restarted")
+
+	cg = imagePullAlwaysContainerGroup("sidecar", pulled, 2)
+	provider.checkImagePullAlways(context.Background(), imagePullAlwaysPod(true))
+	assert.Check(t, is.Equal(0, restarts), "the restarted container doesn't pull its image always")
+
+	cg = imagePullAlwaysContainerGroup("app", updated, 1)
+	provider.checkImagePullAlways(context.Background(), imagePullAlwaysPod(true))
+	assert.Check(t, is.Equal(0, restarts), "the tag still points to the image the container runs")
+
+	pod := imagePullAlwaysPod(true)
+	pod.Status.Phase = v1.PodFailed
+	cg = imagePullAlwaysContainerGroup("app", pulled, 1)
+	provider.checkImagePullAlways(context.Background(), pod)
+	assert.Check(t, is.Equal(0, restarts), "the pod isn't running")
+
+	provider.checkImagePullAlways(context.Background(), imagePullAlwaysPod(true))
+	assert.Check(t, is.Equal(1, restarts), "the container group should be restarted to pull the updated image")
+	assert.Check(t, tags[restartCountsTag] != "", "the restart counts should be kept before the restart")
+
+	// The container group was restarted recently.
+	cg.Tags[restartCountsTag] = "app=1@" + strconv.FormatInt(time.Now().Add(-imagePullAlwaysBackoff/2).Unix(), 10)
+	cg.Containers[0].InstanceView.CurrentState.StartTime = api.JSONTime(time.Now())
+	provider.checkImagePullAlways(context.Background(), imagePullAlwaysPod(true))
+	assert.Check(t, is.Equal(1, restarts), "the container group should not be restarted again so soon")
+}
+
+func TestGetPodStatusDoesNotRestartForImagePullAlways(t *testing.T) {
+	_, aciServerMocker, provider, err := prepareMocks()
+	if err != nil {
+		t.Fatal("Unable to prepare the mocks", err)
+	}
+	provider.imageDigests = fakeImageDigests{"app:latest": "sha256:" + strings.Repeat("2", 64)}
+
+	aciServerMocker.OnAction = func(subscription, resourceGroup, containerGroup, action string) (int, interface{}) {
+		t.Errorf("unexpected %s of the container group on a status read", action)
+		return http.StatusNoContent, nil
+	}
+	aciServerMocker.OnGetContainerGroup = func(subscription, resourceGroup, containerGroup string) (int, interface{}) {
+		return http.StatusOK, imagePullAlwaysContainerGroup("app", "sha256:"+strings.Repeat("1", 64), 1)
+	}
+
+	for i := 0; i < 3; i++ {
+		_, err := provider.GetPodStatus(context.Background(), "ns", "pod")
+		assert.NilError(t, err)
+	}
+}
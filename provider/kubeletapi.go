@@ -0,0 +1,321 @@
+package provider
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/virtual-kubelet/virtual-kubelet/log"
+	"github.com/virtual-kubelet/virtual-kubelet/node/api"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/endpoints/request"
+)
+
+const (
+	// The TTL of the token reviews is the one of the kubelet webhook authenticator.
+	tokenReviewTTL = 2 * time.Minute
+	// tokenReviewCacheSize is the number of token reviews cached, the least recently used being dropped first.
+	tokenReviewCacheSize = 1024
+)
+
+// kubeletAPICert is the serving certificate of the kubelet API, loaded from its files, and loaded again
+// once they are renewed without restarting the virtual kubelet.
+type kubeletAPICert struct {
+	certFile string
+	keyFile  string
+
+	mu   sync.RWMutex
+	cert *tls.Certificate
+}
+
+func (c *kubeletAPICert) load() error {
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return fmt.Errorf("error loading the serving certificate %s: %v", c.certFile, err)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cert = &cert
+	return nil
+}
+
+func (c *kubeletAPICert) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.cert, nil
+}
+
+// reloadKubeletAPICert loads the serving certificate of the kubelet API again from its files, once renewed.
+func (p *ACIProvider) reloadKubeletAPICert() error {
+	if p.kubeletAPICert == nil {
+		return nil
+	}
+	return p.kubeletAPICert.load()
+}
+
+// tokenReview is a cached outcome of a token review.
+type tokenReview struct {
+	user    user.Info
+	expires time.Time
+}
+
+// tokenReviewCache caches the outcome of the token reviews by hash of the token, up to tokenReviewCacheSize
+// reviews. The expired reviews are dropped, so the tokens of clients gone aren't kept.
+type tokenReviewCache struct {
+	mu      sync.Mutex
+	reviews map[string]*list.Element
+	// lru orders the reviews from the most recently used.
+	lru *list.List
+}
+
+// tokenReviewEntry is the element of a review in the lru list.
+type tokenReviewEntry struct {
+	key    string
+	review tokenReview
+}
+
+func (c *tokenReviewCache) get(key string, now time.Time) (tokenReview, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.reviews[key]
+	if !ok {
+		return tokenReview{}, false
+	}
+	r := e.Value.(*tokenReviewEntry).review
+	if now.After(r.expires) {
+		c.removeElement(e)
+		return tokenReview{}, false
+	}
+	c.lru.MoveToFront(e)
+	return r, true
+}
+
+func (c *tokenReviewCache) put(key string, r tokenReview, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.reviews == nil {
+		c.reviews = make(map[string]*list.Element)
+		c.lru = list.New()
+	}
+	if e, ok := c.reviews[key]; ok {
+		c.removeElement(e)
+	}
+	c.reviews[key] = c.lru.PushFront(&tokenReviewEntry{key: key, review: r})
+
+	for e := c.lru.Back(); e != nil; {
+		prev := e.Prev()
+		if now.After(e.Value.(*tokenReviewEntry).review.expires) {
+			c.removeElement(e)
+		}
+		e = prev
+	}
+	for c.lru.Len() > tokenReviewCacheSize {
+		c.removeElement(c.lru.Back())
+	}
+}
+
+func (c *tokenReviewCache) removeElement(e *list.Element) {
+	c.lru.Remove(e)
+	delete(c.reviews, e.Value.(*tokenReviewEntry).key)
+}
+
+// setupKubeletAPI serves the kubelet API of the node on its daemon port when the serving certificate files are
+// set in the options, instead of node-cli, which serves it without authentication and only loads its certificate
+// at start. The clients are authenticated with their client certificate, verified with the CA of
+// ACI_CLIENT_CA_FILE, or their bearer token, with a token review, and the user authenticated is set in the context
+// of the requests. With ACI_CLIENT_CA_FILE or ACI_AUTHORIZATION_WEBHOOK the requests without an authenticated user
+// are rejected, and with ACI_AUTHORIZATION_WEBHOOK the others are authorized with subject access reviews like the
+// kubelet does.
+func (p *ACIProvider) setupKubeletAPI(ctx context.Context, opts Options) error {
+	if opts.ServingCertFile == "" || opts.ServingKeyFile == "" {
+		return nil
+	}
+
+	p.kubeletAPICert = &kubeletAPICert{certFile: opts.ServingCertFile, keyFile: opts.ServingKeyFile}
+	if err := p.kubeletAPICert.load(); err != nil {
+		return err
+	}
+	tlsConfig := &tls.Config{
+		GetCertificate: p.kubeletAPICert.getCertificate,
+		MinVersion:     tls.VersionTLS12,
+	}
+	if caFile := os.Getenv("ACI_CLIENT_CA_FILE"); caFile != "" {
+		pem, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return fmt.Errorf("invalid ACI_CLIENT_CA_FILE %q: %v", caFile, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("invalid ACI_CLIENT_CA_FILE %q: no certificate found", caFile)
+		}
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+		p.requireAuthn = true
+	}
+	if p.authorizeRequests {
+		p.requireAuthn = true
+	}
+
+	l, err := tls.Listen("tcp", fmt.Sprintf(":%d", p.daemonEndpointPort), tlsConfig)
+	if err != nil {
+		return fmt.Errorf("error listening on the kubelet API port %d: %v", p.daemonEndpointPort, err)
+	}
+
+	s := &http.Server{Handler: p.kubeletAPIHandler()}
+	goSubsystem("kubelet_api", func() {
+		if err := s.Serve(l); err != nil && err != http.ErrServerClosed {
+			log.G(ctx).WithError(err).Error("failed to serve the kubelet API")
+		}
+	})
+	return nil
+}
+
+// kubeletAPIHandler returns the handler of the kubelet API: the routes of the pods, logs and exec, of the stats,
+// and of the egress endpoints and pod diagnostics, behind the authentication of the requests.
+func (p *ACIProvider) kubeletAPIHandler() http.Handler {
+	mux := http.NewServeMux()
+	api.AttachPodRoutes(api.PodHandlerConfig{
+		RunInContainer:   p.RunInContainer,
+		GetContainerLogs: p.GetContainerLogs,
+		GetPods:          p.GetPods,
+		GetPodsFromKubernetes: func(context.Context) ([]*v1.Pod, error) {
+			return p.resourceManager.GetPods(), nil
+		},
+	}, mux, true)
+	// The metrics routes have their own catch-all route, like the pod routes.
+	stats := http.NewServeMux()
+	api.AttachPodMetricsRoutes(api.PodMetricsConfig{GetStatsSummary: p.GetStatsSummary}, stats)
+	mux.Handle("/stats/", stats)
+	mux.HandleFunc("/egress", p.serveEgressEndpoints)
+	mux.HandleFunc("/debug/pods/", p.serveDebugPod)
+	return p.authenticateRequests(mux)
+}
+
+// authenticateRequests sets the user authenticated for the requests in their context. The requests without an
+// authenticated user are rejected when the authentication is required, and with the authorization enabled the
+// others need the verb of their method on the subresource of the node of their path.
+func (p *ACIProvider) authenticateRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		u, err := p.authenticate(ctx, r)
+		if err != nil {
+			log.G(ctx).WithError(err).Warn("failed to authenticate a request to the kubelet API")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if u != nil {
+			ctx = request.WithUser(ctx, u)
+		} else if p.requireAuthn {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		if err := p.authorize(ctx, requestVerb(r), requestSubresource(r)); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// authenticate returns the user of the client certificate of the request, verified by the TLS handshake, or of
+// its bearer token, or nil without any. An invalid bearer token is an error.
+func (p *ACIProvider) authenticate(ctx context.Context, r *http.Request) (user.Info, error) {
+	if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+		cert := r.TLS.VerifiedChains[0][0]
+		if cert.Subject.CommonName != "" {
+			return &user.DefaultInfo{Name: cert.Subject.CommonName, Groups: cert.Subject.Organization}, nil
+		}
+	}
+
+	auth := strings.TrimSpace(r.Header.Get("Authorization"))
+	if len(auth) < len("Bearer ") || !strings.EqualFold(auth[:len("Bearer ")], "Bearer ") {
+		return nil, nil
+	}
+	token := strings.TrimSpace(auth[len("Bearer "):])
+	if token == "" || p.kubeClient == nil {
+		return nil, nil
+	}
+
+	sum := sha256.Sum256([]byte(token))
+	key := hex.EncodeToString(sum[:])
+	now := time.Now()
+	if cached, ok := p.tokenReviews.get(key, now); ok {
+		if cached.user == nil {
+			return nil, fmt.Errorf("invalid bearer token")
+		}
+		return cached.user, nil
+	}
+
+	review, err := p.kubeClient.AuthenticationV1().TokenReviews().Create(ctx, &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{Token: token},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error reviewing the bearer token: %v", err)
+	}
+
+	cached := tokenReview{expires: now.Add(tokenReviewTTL)}
+	if review.Status.Authenticated {
+		extra := make(map[string][]string, len(review.Status.User.Extra))
+		for k, v := range review.Status.User.Extra {
+			extra[k] = v
+		}
+		cached.user = &user.DefaultInfo{
+			Name:   review.Status.User.Username,
+			UID:    review.Status.User.UID,
+			Groups: review.Status.User.Groups,
+			Extra:  extra,
+		}
+	}
+	p.tokenReviews.put(key, cached, now)
+	if cached.user == nil {
+		return nil, fmt.Errorf("invalid bearer token: %s", review.Status.Error)
+	}
+	return cached.user, nil
+}
+
+// requestVerb returns the verb of the request to authorize, from its method like the kubelet does.
+func requestVerb(r *http.Request) string {
+	switch r.Method {
+	case http.MethodPost:
+		return "create"
+	case http.MethodPut:
+		return "update"
+	case http.MethodPatch:
+		return "patch"
+	case http.MethodDelete:
+		return "delete"
+	default:
+		return "get"
+	}
+}
+
+// requestSubresource returns the subresource of the node to authorize the request on, from its path like the
+// kubelet does.
+func requestSubresource(r *http.Request) string {
+	path := r.URL.Path
+	for _, prefix := range []string{"stats", "metrics", "logs", "spec"} {
+		if path == "/"+prefix || strings.HasPrefix(path, "/"+prefix+"/") {
+			if prefix == "logs" {
+				return "log"
+			}
+			return prefix
+		}
+	}
+	return "proxy"
+}
@@ -0,0 +1,210 @@
+package provider
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/virtual-kubelet/azure-aci/client/aci"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestKubeletAPIAuthorizesTheAuthenticatedUser(t *testing.T) {
+	_, aciServerMocker, provider, err := prepareMocks()
+	if err != nil {
+		t.Fatal("Unable to prepare the mocks", err)
+	}
+	aciServerMocker.OnGetContainerGroup = func(subscription, resourceGroup, containerGroup string) (int, interface{}) {
+		return http.StatusOK, aci.ContainerGroup{
+			Name: containerGroup,
+			Tags: map[string]string{"NodeName": fakeNodeName},
+		}
+	}
+	aciServerMocker.OnGetLogs = func(subscription, resourceGroup, containerGroup, containerName string) (int, interface{}) {
+		return http.StatusOK, aci.Logs{Content: "hello"}
+	}
+
+	tokenReviews := 0
+	kubeClient := fake.NewSimpleClientset()
+	kubeClient.PrependReactor("create", "tokenreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		tokenReviews++
+		review := action.(k8stesting.CreateAction).GetObject().(*authenticationv1.TokenReview)
+		switch review.Spec.Token {
+		case "alice-token":
+			review.Status.Authenticated = true
+			review.Status.User.Username = "alice"
+		case "bob-token":
+			review.Status.Authenticated = true
+			review.Status.User.Username = "bob"
+		default:
+			review.Status.Error = "unknown token"
+		}
+		return true, review, nil
+	})
+	kubeClient.PrependReactor("create", "subjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview)
+		attrs := review.Spec.ResourceAttributes
+		review.Status.Allowed = review.Spec.User == "alice" && attrs.Verb == "get" && attrs.Resource == "nodes" &&
+			attrs.Subresource == "proxy" && attrs.Name == fakeNodeName
+		return true, review, nil
+	})
+	provider.kubeClient = kubeClient
+	provider.authorizeRequests = true
+	provider.requireAuthn = true
+
+	handler := provider.kubeletAPIHandler()
+	get := func(token string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/containerLogs/ns/pod/container", nil)
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	w := get("alice-token")
+	assert.Check(t, is.Equal(http.StatusOK, w.Code))
+	body, _ := ioutil.ReadAll(w.Body)
+	assert.Check(t, is.Equal("hello", string(body)))
+
+	w = get("alice-token")
+	assert.Check(t, is.Equal(http.StatusOK, w.Code))
+	assert.Check(t, is.Equal(1, tokenReviews), "the token review should be cached")
+
+	assert.Check(t, is.Equal(http.StatusForbidden, get("bob-token").Code))
+	assert.Check(t, is.Equal(http.StatusUnauthorized, get("mallory-token").Code))
+	assert.Check(t, is.Equal(http.StatusUnauthorized, get("").Code))
+}
+
+func TestTokenReviewCache(t *testing.T) {
+	var c tokenReviewCache
+	now := time.Now()
+	c.put("expired", tokenReview{expires: now.Add(time.Second)}, now)
+	c.put("valid", tokenReview{expires: now.Add(tokenReviewTTL)}, now)
+
+	// The expired reviews are dropped, when read or when a review is cached.
+	now = now.Add(time.Minute)
+	_, ok := c.get("expired", now)
+	assert.Check(t, !ok, "the review should be expired")
+	assert.Check(t, is.Len(c.reviews, 1))
+	c.put("expiring", tokenReview{expires: now.Add(time.Second)}, now)
+	c.put("other", tokenReview{expires: now.Add(tokenReviewTTL)}, now.Add(2*time.Second))
+	assert.Check(t, is.Len(c.reviews, 2))
+	_, ok = c.get("expiring", now)
+	assert.Check(t, !ok, "the expired review should be dropped")
+
+	// The least recently used reviews are dropped once full.
+	for i := 0; i < tokenReviewCacheSize; i++ {
+		if i == 1 {
+			_, ok = c.get("valid", now)
+			assert.Check(t, ok)
+		}
+		c.put(fmt.Sprintf("token-%d", i), tokenReview{expires: now.Add(tokenReviewTTL)}, now)
+	}
+	assert.Check(t, is.Len(c.reviews, tokenReviewCacheSize))
+	assert.Check(t, is.Equal(tokenReviewCacheSize, c.lru.Len()))
+	_, ok = c.get("valid", now)
+	assert.Check(t, ok, "the recently used review should be kept")
+	_, ok = c.get("other", now)
+	assert.Check(t, !ok, "the least recently used review should be dropped")
+}
+
+func TestKubeletAPIWithoutAuthentication(t *testing.T) {
+	p := ACIProvider{nodeName: fakeNodeName}
+	handler := p.authenticateRequests(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/stats/summary", nil))
+	assert.Check(t, is.Equal(http.StatusNoContent, w.Code))
+
+	p.requireAuthn = true
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/stats/summary", nil))
+	assert.Check(t, is.Equal(http.StatusUnauthorized, w.Code))
+}
+
+func TestRequestAttributes(t *testing.T) {
+	for _, c := range []struct {
+		method, path, verb, subresource string
+	}{
+		{http.MethodGet, "/containerLogs/ns/pod/c", "get", "proxy"},
+		{http.MethodPost, "/exec/ns/pod/c", "create", "proxy"},
+		{http.MethodGet, "/stats/summary", "get", "stats"},
+		{http.MethodGet, "/logs/", "get", "log"},
+		{http.MethodGet, "/statsz", "get", "proxy"},
+	} {
+		r := httptest.NewRequest(c.method, c.path, nil)
+		assert.Check(t, is.Equal(c.verb, requestVerb(r)), c.path)
+		assert.Check(t, is.Equal(c.subresource, requestSubresource(r)), c.path)
+	}
+}
+
+func writeTestServingCert(t *testing.T, certFile, keyFile, name string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NilError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NilError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	assert.NilError(t, err)
+	assert.NilError(t, ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644))
+	assert.NilError(t, ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+}
+
+func TestReloadKubeletAPICert(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kubeletapi")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+
+	served := func(p *ACIProvider) string {
+		cert, err := p.kubeletAPICert.getCertificate(nil)
+		assert.NilError(t, err)
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		assert.NilError(t, err)
+		return leaf.Subject.CommonName
+	}
+
+	writeTestServingCert(t, certFile, keyFile, "first")
+	p := &ACIProvider{kubeletAPICert: &kubeletAPICert{certFile: certFile, keyFile: keyFile}}
+	assert.NilError(t, p.reloadKubeletAPICert())
+	assert.Check(t, is.Equal("first", served(p)))
+
+	// The renewed certificate is served without restarting.
+	writeTestServingCert(t, certFile, keyFile, "renewed")
+	assert.NilError(t, p.reloadKubeletAPICert())
+	assert.Check(t, is.Equal("renewed", served(p)))
+
+	// A broken renewal keeps serving the previous certificate.
+	assert.NilError(t, ioutil.WriteFile(keyFile, []byte("garbage"), 0600))
+	assert.Check(t, p.reloadKubeletAPICert() != nil, "expected an error for an invalid key")
+	assert.Check(t, is.Equal("renewed", served(p)))
+
+	assert.NilError(t, (&ACIProvider{}).reloadKubeletAPICert())
+}
@@ -0,0 +1,46 @@
+package provider
+
+import (
+	"strings"
+	"time"
+
+	"github.com/virtual-kubelet/azure-aci/client/aci"
+)
+
+// Names of the ACI container events raised by the probes and the restarts of the containers.
+const (
+	aciEventUnhealthy = "Unhealthy"
+	aciEventKilling   = "Killing"
+)
+
+// livenessFailure returns the time of the last liveness probe failure of a running container, or of ACI killing
+// it, since it started. ACI restarts such a container, so it is not ready anymore until it starts again, whatever
+// its running state says in the meantime.
+func livenessFailure(c aci.Container) (time.Time, bool) {
+	if c.InstanceView.CurrentState.State != "Running" {
+		return time.Time{}, false
+	}
+	started := time.Time(c.InstanceView.CurrentState.StartTime)
+
+	var failedAt time.Time
+	for _, e := range c.InstanceView.Events {
+		if !isLivenessFailureEvent(e) {
+			continue
+		}
+		at := time.Time(e.LastTimestamp)
+		if at.After(started) && at.After(failedAt) {
+			failedAt = at
+		}
+	}
+	return failedAt, !failedAt.IsZero()
+}
+
+func isLivenessFailureEvent(e aci.Event) bool {
+	switch e.Name {
+	case aciEventKilling:
+		return true
+	case aciEventUnhealthy:
+		return strings.Contains(strings.ToLower(e.Message), "liveness")
+	}
+	return false
+}
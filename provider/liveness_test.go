@@ -0,0 +1,38 @@
+package provider
+
+import (
+	"testing"
+	"time"
+
+	"github.com/virtual-kubelet/azure-aci/client/aci"
+	"github.com/virtual-kubelet/azure-aci/client/api"
+	"gotest.tools/assert"
+)
+
+func TestLivenessFailure(t *testing.T) {
+	started := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+	c := aci.Container{Name: "web"}
+	c.InstanceView.CurrentState = aci.ContainerState{State: "Running", StartTime: api.JSONTime(started)}
+	c.InstanceView.Events = []aci.Event{
+		{Name: aciEventUnhealthy, Message: "Readiness probe failed: connection refused", LastTimestamp: api.JSONTime(started.Add(time.Minute))},
+		{Name: aciEventUnhealthy, Message: "Liveness probe failed: connection refused", LastTimestamp: api.JSONTime(started.Add(-time.Minute))},
+	}
+	_, ok := livenessFailure(c)
+	assert.Check(t, !ok, "A liveness failure before the start of the container should be ignored")
+
+	failed := started.Add(2 * time.Minute)
+	c.InstanceView.Events = append(c.InstanceView.Events, aci.Event{Name: aciEventUnhealthy, Message: "Liveness probe failed: timeout", LastTimestamp: api.JSONTime(failed)})
+	at, ok := livenessFailure(c)
+	assert.Assert(t, ok)
+	assert.Equal(t, at, failed)
+
+	// Once restarted, the container is ready again.
+	c.InstanceView.CurrentState.StartTime = api.JSONTime(failed.Add(10 * time.Second))
+	_, ok = livenessFailure(c)
+	assert.Check(t, !ok)
+
+	c.InstanceView.CurrentState.State = "Waiting"
+	c.InstanceView.Events = append(c.InstanceView.Events, aci.Event{Name: aciEventKilling, LastTimestamp: api.JSONTime(failed.Add(time.Minute))})
+	_, ok = livenessFailure(c)
+	assert.Check(t, !ok, "Only running containers are checked")
+}
@@ -0,0 +1,108 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/virtual-kubelet/azure-aci/client/blob"
+	"github.com/virtual-kubelet/virtual-kubelet/log"
+	"github.com/virtual-kubelet/virtual-kubelet/trace"
+	v1 "k8s.io/api/core/v1"
+)
+
+const defaultLogArchiveInterval = 5 * time.Minute
+
+// logSink stores snapshots of container logs outside of the container group,
+// so they survive its deletion.
+type logSink interface {
+	WriteLogs(ctx context.Context, namespace, podName, podUID, containerName string, logs []byte) error
+}
+
+// blobLogSink writes container logs to blobs named <namespace>/<pod>/<pod uid>/<container>.log.
+type blobLogSink struct {
+	client *blob.Client
+}
+
+func (s *blobLogSink) WriteLogs(ctx context.Context, namespace, podName, podUID, containerName string, logs []byte) error {
+	name := fmt.Sprintf("%s/%s/%s/%s.log", namespace, podName, podUID, containerName)
+	return s.client.PutBlockBlob(ctx, name, "text/plain; charset=utf-8", logs)
+}
+
+// setupLogArchive enables the archival of container logs when ACI_LOG_ARCHIVE_CONTAINER_URL is set
+// to the URL of a storage container, with a SAS token allowing to write blobs.
+func (p *ACIProvider) setupLogArchive() error {
+	containerURL := os.Getenv("ACI_LOG_ARCHIVE_CONTAINER_URL")
+	if containerURL == "" {
+		return nil
+	}
+
+	client, err := blob.NewClient(containerURL)
+	if err != nil {
+		return fmt.Errorf("error setting up log archive: %v", err)
+	}
+	p.logSink = &blobLogSink{client: client}
+
+	p.logArchiveInterval = defaultLogArchiveInterval
+	if interval := os.Getenv("ACI_LOG_ARCHIVE_INTERVAL"); interval != "" {
+		d, err := time.ParseDuration(interval)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("invalid ACI_LOG_ARCHIVE_INTERVAL %q, expected a positive duration", interval)
+		}
+		p.logArchiveInterval = d
+	}
+
+	return nil
+}
+
+// archiveLogsLoop periodically snapshots the logs of the pods running on the node.
+func (p *ACIProvider) archiveLogsLoop(ctx context.Context) {
+	ticker := time.NewTicker(p.logArchiveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, pod := range p.resourceManager.GetPods() {
+				if pod.Spec.NodeName != p.nodeName || pod.Status.Phase != v1.PodRunning {
+					continue
+				}
+				p.archivePodLogs(ctx, pod.Namespace, pod.Name)
+			}
+		}
+	}
+}
+
+// archivePodLogs snapshots the logs of all the containers of a pod. Failures are logged only,
+// archival never blocks the pod lifecycle.
+func (p *ACIProvider) archivePodLogs(ctx context.Context, namespace, name string) {
+	if p.logSink == nil {
+		return
+	}
+
+	ctx, span := trace.StartSpan(ctx, "aci.archivePodLogs")
+	defer span.End()
+	ctx = addAzureAttributes(ctx, span, p)
+	logger := log.G(ctx).WithField("namespace", namespace).WithField("pod", name)
+
+	cg, err := p.getContainerGroup(ctx, namespace, name)
+	if err != nil {
+		logger.WithError(err).Warn("failed to get container group to archive logs")
+		return
+	}
+
+	for _, c := range cg.Containers {
+		logs, err := p.aciClient.GetContainerLogs(ctx, p.resourceGroup, cg.Name, c.Name, 0)
+		if err != nil {
+			logger.WithError(err).Warnf("failed to get logs of container %s to archive", c.Name)
+			continue
+		}
+
+		if err := p.logSink.WriteLogs(ctx, namespace, name, cg.Tags["UID"], c.Name, []byte(logs.Content)); err != nil {
+			logger.WithError(err).Warnf("failed to archive logs of container %s", c.Name)
+		}
+	}
+}
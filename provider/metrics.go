@@ -2,11 +2,17 @@ package provider
 
 import (
 	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/pkg/errors"
 	"github.com/virtual-kubelet/azure-aci/client/aci"
+	"github.com/virtual-kubelet/azure-aci/client/api"
 	"github.com/virtual-kubelet/virtual-kubelet/log"
 	"github.com/virtual-kubelet/virtual-kubelet/trace"
 	"golang.org/x/sync/errgroup"
@@ -15,12 +21,99 @@ import (
 	stats "k8s.io/kubernetes/pkg/kubelet/apis/stats/v1alpha1"
 )
 
+const (
+	defaultMetricsWindow = time.Minute
+
+	// skipMetricsAnnotation excludes a pod from the stats summaries when "true".
+	skipMetricsAnnotation = "virtual-kubelet.io/skip-metrics"
+
+	eventReasonMetricsUnavailable = "MetricsUnavailable"
+)
+
+// metricsWindows are the lookback windows of the metrics, among the granularities of Azure Monitor.
+var metricsWindows = []time.Duration{time.Minute, 5 * time.Minute, 15 * time.Minute, 30 * time.Minute, time.Hour}
+
+// setupMetrics reads the aggregation and the lookback window of the metrics of the stats summaries from
+// MetricsAggregation and MetricsWindow in the config file, or ACI_METRICS_AGGREGATION and ACI_METRICS_WINDOW.
+// The metrics are aggregated over the whole window, so a longer window than the default 1 minute Average
+// bridges the gaps of the sparse metrics of ACI. The pods of the namespaces of ExcludeFromMetrics, or the
+// ACI_METRICS_EXCLUDED_NAMESPACES comma separated list, are left out of the summaries. With OnlyCPUAndMemory or ACI_STATS_ONLY_CPU_AND_MEMORY, the
+// network metrics are not fetched, as the virtual kubelet doesn't pass the only_cpu_and_memory query parameter
+// of the stats endpoint to the provider. With MetricsFallback or ACI_METRICS_FALLBACK, zero stats are reported
+// when Azure Monitor is disabled in the subscription instead of failing the summaries.
+func (p *ACIProvider) setupMetrics() error {
+	if v := os.Getenv("ACI_METRICS_EXCLUDED_NAMESPACES"); v != "" {
+		p.noMetricsNamespaces = splitNamespaces(v)
+	}
+	for _, pattern := range p.noMetricsNamespaces {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid namespace pattern %q: %v", pattern, err)
+		}
+	}
+	if v := os.Getenv("ACI_METRICS_FALLBACK"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid ACI_METRICS_FALLBACK %q: %v", v, err)
+		}
+		p.metricsFallback = b
+	}
+	if v := os.Getenv("ACI_STATS_ONLY_CPU_AND_MEMORY"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid ACI_STATS_ONLY_CPU_AND_MEMORY %q: %v", v, err)
+		}
+		p.onlyCPUAndMemory = b
+	}
+	if v := os.Getenv("ACI_METRICS_AGGREGATION"); v != "" {
+		p.metricsAggregation = v
+	}
+	if v := os.Getenv("ACI_METRICS_WINDOW"); v != "" {
+		p.metricsWindowConfig = v
+	}
+
+	p.aggregation = aci.AggregationTypeAverage
+	switch strings.ToLower(p.metricsAggregation) {
+	case "", "average":
+	case "maximum":
+		p.aggregation = aci.AggregationTypeMaximum
+	case "total":
+		p.aggregation = aci.AggregationTypeTotal
+	default:
+		return fmt.Errorf("invalid metrics aggregation %q, expected one of Average, Maximum or Total", p.metricsAggregation)
+	}
+
+	p.metricsWindow = defaultMetricsWindow
+	if p.metricsWindowConfig == "" {
+		return nil
+	}
+	d, err := time.ParseDuration(p.metricsWindowConfig)
+	if err != nil {
+		return fmt.Errorf("invalid metrics window %q: %v", p.metricsWindowConfig, err)
+	}
+	for _, w := range metricsWindows {
+		if d == w {
+			p.metricsWindow = d
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid metrics window %q, expected one of 1m, 5m, 15m, 30m or 1h", p.metricsWindowConfig)
+}
+
+// metricsSource fetches the Azure Monitor metrics of the container groups.
+type metricsSource interface {
+	GetContainerGroupMetrics(ctx context.Context, resourceGroup, containerGroup string, options aci.MetricsRequest) (*aci.ContainerGroupMetricsResult, error)
+}
+
 // GetStatsSummary returns the stats summary for pods running on ACI
 func (p *ACIProvider) GetStatsSummary(ctx context.Context) (summary *stats.Summary, err error) {
 	ctx, span := trace.StartSpan(ctx, "GetSummaryStats")
 	defer span.End()
 	ctx = addAzureAttributes(ctx, span, p)
 
+	if err := p.authorize(ctx, "get", "stats"); err != nil {
+		return nil, err
+	}
+
 	p.metricsSync.Lock()
 	defer p.metricsSync.Unlock()
 
@@ -58,15 +151,16 @@ func (p *ACIProvider) GetStatsSummary(ctx context.Context) (summary *stats.Summa
 	chResult := make(chan stats.PodStats, len(pods))
 
 	end := time.Now()
-	start := end.Add(-1 * time.Minute)
+	start := end.Add(-p.metricsWindow)
 
 	sema := make(chan struct{}, 10)
 	for _, pod := range pods {
-		if pod.Status.Phase != v1.PodRunning {
+		if pod.Status.Phase != v1.PodRunning || !p.collectsMetrics(pod) {
 			continue
 		}
 		pod := pod
 		errGroup.Go(func() error {
+			defer trackGoroutine("metrics")()
 			ctx, span := trace.StartSpan(ctx, "getPodMetrics")
 			defer span.End()
 			logger := log.G(ctx).WithFields(log.Fields{
@@ -87,33 +181,32 @@ func (p *ACIProvider) GetStatsSummary(ctx context.Context) (summary *stats.Summa
 			logger.Debug("Acquired semaphore")
 
 			cgName := containerGroupName(pod.Namespace, pod.Name)
-			// cpu/mem and net stats are split because net stats do not support container level detail
-			systemStats, err := p.aciClient.GetContainerGroupMetrics(ctx, p.resourceGroup, cgName, aci.MetricsRequest{
-				Dimension:    "containerName eq '*'",
-				Start:        start,
-				End:          end,
-				Aggregations: []aci.AggregationType{aci.AggregationTypeAverage},
-				Types:        []aci.MetricType{aci.MetricTypeCPUUsage, aci.MetricTypeMemoryUsage},
-			})
-			if err != nil {
-				span.SetStatus(err)
-				return errors.Wrapf(err, "error fetching cpu/mem stats for container group %s", cgName)
+			systemStats, netStats, err := p.getContainerGroupMetrics(ctx, cgName, p.isWindowsPod(pod), start, end)
+			if err != nil && p.metricsFallback && isMetricsUnavailable(err) {
+				logger.WithError(err).Debug("Azure Monitor is unavailable, reporting zero stats")
+				p.metricsUnavailable.Do(func() {
+					p.recordNodeEvent(v1.EventTypeWarning, eventReasonMetricsUnavailable, "Azure Monitor metrics are unavailable, zero stats are reported for the pods: %v", err)
+				})
+				chResult <- zeroPodStats(pod, end)
+				return nil
 			}
-			logger.Debug("Got system stats")
-
-			netStats, err := p.aciClient.GetContainerGroupMetrics(ctx, p.resourceGroup, cgName, aci.MetricsRequest{
-				Start:        start,
-				End:          end,
-				Aggregations: []aci.AggregationType{aci.AggregationTypeAverage},
-				Types:        []aci.MetricType{aci.MetricTyperNetworkBytesRecievedPerSecond, aci.MetricTyperNetworkBytesTransmittedPerSecond},
-			})
 			if err != nil {
 				span.SetStatus(err)
-				return errors.Wrapf(err, "error fetching network stats for container group %s", cgName)
+				return err
+			}
+			logger.Debug("Got system and network stats")
+
+			stat := collectMetrics(pod, systemStats, netStats, p.aggregation)
+			if p.volumeStats != nil && hasAzureFileVolumes(pod) {
+				cg, err := p.getContainerGroup(ctx, pod.Namespace, pod.Name)
+				if err != nil {
+					logger.WithError(err).Warn("Unable to get the container group for the volume stats")
+				} else {
+					stat.VolumeStats = p.getPodVolumeStats(ctx, pod, cg)
+				}
 			}
-			logger.Debug("Got network stats")
 
-			chResult <- collectMetrics(pod, systemStats, netStats)
+			chResult <- stat
 			return nil
 		})
 	}
@@ -134,11 +227,222 @@ func (p *ACIProvider) GetStatsSummary(ctx context.Context) (summary *stats.Summa
 	for stat := range chResult {
 		s.Pods = append(s.Pods, stat)
 	}
+	p.accumulateCPUUsage(s.Pods, end)
+	s.Pods = p.withTerminatedPodStats(s.Pods, end)
+	p.metricsCaches.set(len(p.lastPodStats), len(p.cpuUsage))
 
 	return &s, nil
 }
 
-func collectMetrics(pod *v1.Pod, system, net *aci.ContainerGroupMetricsResult) stats.PodStats {
+// getContainerGroupMetrics fetches the cpu/mem and the network metrics of a container group between start and end,
+// aggregated over the whole period. They are split because the network metrics do not support container level detail.
+// The network metrics are empty when only the cpu/mem stats are reported, and for Windows container groups which
+// don't emit them nor split their cpu/mem metrics by container.
+func (p *ACIProvider) getContainerGroupMetrics(ctx context.Context, cgName string, windows bool, start, end time.Time) (system, net *aci.ContainerGroupMetricsResult, err error) {
+	dimension := "containerName eq '*'"
+	if windows {
+		dimension = ""
+	}
+	system, err = p.metricsSource.GetContainerGroupMetrics(ctx, p.resourceGroup, cgName, aci.MetricsRequest{
+		Dimension:    dimension,
+		Start:        start,
+		End:          end,
+		Aggregations: []aci.AggregationType{p.aggregation},
+		Interval:     end.Sub(start),
+		Types:        []aci.MetricType{aci.MetricTypeCPUUsage, aci.MetricTypeMemoryUsage},
+	})
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "error fetching cpu/mem stats for container group %s", cgName)
+	}
+	if p.onlyCPUAndMemory || windows {
+		return system, &aci.ContainerGroupMetricsResult{}, nil
+	}
+
+	net, err = p.metricsSource.GetContainerGroupMetrics(ctx, p.resourceGroup, cgName, aci.MetricsRequest{
+		Start:        start,
+		End:          end,
+		Aggregations: []aci.AggregationType{p.aggregation},
+		Interval:     end.Sub(start),
+		Types:        []aci.MetricType{aci.MetricTyperNetworkBytesRecievedPerSecond, aci.MetricTyperNetworkBytesTransmittedPerSecond},
+	})
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "error fetching network stats for container group %s", cgName)
+	}
+	return system, net, nil
+}
+
+// collectsMetrics reports whether the stats of the pod are collected: batch jobs which don't need them can
+// opt out with the skip metrics annotation, so they don't use the Azure Monitor quota.
+func (p *ACIProvider) collectsMetrics(pod *v1.Pod) bool {
+	if skip, _ := strconv.ParseBool(pod.Annotations[skipMetricsAnnotation]); skip {
+		return false
+	}
+	return !matchNamespace(p.noMetricsNamespaces, pod.Namespace)
+}
+
+// isMetricsUnavailable reports whether Azure Monitor refused the metrics because the microsoft.insights
+// resource provider is disabled or not registered in the subscription.
+func isMetricsUnavailable(err error) bool {
+	e, ok := errors.Cause(err).(*api.Error)
+	return ok && (e.StatusCode == http.StatusForbidden || e.StatusCode == http.StatusNotFound)
+}
+
+// zeroPodStats returns structurally valid stats of a pod whose metrics are unavailable, with a zero usage, so the
+// consumers of the summaries, like the HPA, don't take the node for broken.
+func zeroPodStats(pod *v1.Pod, now time.Time) stats.PodStats {
+	sampleAt := metav1.NewTime(now)
+	zeroCPU := func() *stats.CPUStats {
+		var nanoCores, coreNanoSeconds uint64
+		return &stats.CPUStats{Time: sampleAt, UsageNanoCores: &nanoCores, UsageCoreNanoSeconds: &coreNanoSeconds}
+	}
+	zeroMemory := func() *stats.MemoryStats {
+		var usage, workingSet uint64
+		return &stats.MemoryStats{Time: sampleAt, UsageBytes: &usage, WorkingSetBytes: &workingSet}
+	}
+
+	stat := stats.PodStats{
+		PodRef: stats.PodReference{
+			Name:      pod.Name,
+			Namespace: pod.Namespace,
+			UID:       string(pod.UID),
+		},
+		StartTime: pod.CreationTimestamp,
+		CPU:       zeroCPU(),
+		Memory:    zeroMemory(),
+	}
+	for _, c := range pod.Spec.Containers {
+		stat.Containers = append(stat.Containers, stats.ContainerStats{
+			Name:      c.Name,
+			StartTime: pod.CreationTimestamp,
+			CPU:       zeroCPU(),
+			Memory:    zeroMemory(),
+		})
+	}
+	return stat
+}
+
+// terminatedPodStatsTTL is how long the last stats of a pod stay in the summary once it stopped running.
+const terminatedPodStatsTTL = 5 * time.Minute
+
+// lastPodStats are the stats of a pod from the last summary it was running in.
+type lastPodStats struct {
+	stats    stats.PodStats
+	sampleAt time.Time
+}
+
+// withTerminatedPodStats remembers the stats of the running pods, and adds the last known stats of the
+// pods which stopped running since less than terminatedPodStatsTTL. Short lived pods, such as jobs, would
+// otherwise never or only partially show up in the summaries.
+// It must be called with the metrics mutex held.
+func (p *ACIProvider) withTerminatedPodStats(running []stats.PodStats, now time.Time) []stats.PodStats {
+	if p.lastPodStats == nil {
+		p.lastPodStats = make(map[string]lastPodStats)
+	}
+
+	collected := make(map[string]bool, len(running))
+	for _, stat := range running {
+		collected[stat.PodRef.UID] = true
+		p.lastPodStats[stat.PodRef.UID] = lastPodStats{stats: stat, sampleAt: now}
+	}
+
+	for uid, last := range p.lastPodStats {
+		if collected[uid] {
+			continue
+		}
+		if now.Sub(last.sampleAt) > terminatedPodStatsTTL {
+			delete(p.lastPodStats, uid)
+			continue
+		}
+		running = append(running, last.stats)
+	}
+
+	return running
+}
+
+// cpuUsage is the cumulative CPU usage of a container, from its first sample.
+type cpuUsage struct {
+	coreNanoSeconds uint64
+	sampleAt        time.Time
+	seenAt          time.Time
+}
+
+// accumulateCPUUsage replaces the CPU usage of the containers and pods, which Azure Monitor only reports as a
+// rate, with a counter accumulated across the summaries, so it increases monotonically like the one of a real
+// kubelet and rate() calculations hold. The first sample of a container counts for the 1 minute interval, the
+// next ones for the time since the previous sample. Containers missing from the summaries for longer than
+// terminatedPodStatsTTL are forgotten.
+// It must be called with the metrics mutex held.
+func (p *ACIProvider) accumulateCPUUsage(pods []stats.PodStats, now time.Time) {
+	if p.cpuUsage == nil {
+		p.cpuUsage = make(map[string]*cpuUsage)
+	}
+
+	for i := range pods {
+		pod := &pods[i]
+		var podUsage uint64
+		for j := range pod.Containers {
+			cs := &pod.Containers[j]
+			if cs.CPU == nil || cs.CPU.UsageNanoCores == nil {
+				continue
+			}
+			key := pod.PodRef.UID + "/" + cs.Name
+			sampleAt := cs.CPU.Time.Time
+			u, ok := p.cpuUsage[key]
+			switch {
+			case !ok:
+				u = &cpuUsage{sampleAt: sampleAt}
+				if cs.CPU.UsageCoreNanoSeconds != nil {
+					u.coreNanoSeconds = *cs.CPU.UsageCoreNanoSeconds
+				}
+				p.cpuUsage[key] = u
+			case sampleAt.After(u.sampleAt):
+				u.coreNanoSeconds += uint64(float64(*cs.CPU.UsageNanoCores) * sampleAt.Sub(u.sampleAt).Seconds())
+				u.sampleAt = sampleAt
+			}
+			u.seenAt = now
+
+			usage := u.coreNanoSeconds
+			cs.CPU.UsageCoreNanoSeconds = &usage
+			podUsage += usage
+		}
+		if pod.CPU != nil {
+			pod.CPU.UsageCoreNanoSeconds = &podUsage
+		}
+	}
+
+	for key, u := range p.cpuUsage {
+		if now.Sub(u.seenAt) > terminatedPodStatsTTL {
+			delete(p.cpuUsage, key)
+		}
+	}
+}
+
+// singleContainerName returns the name of the container of a pod with a single container.
+func singleContainerName(pod *v1.Pod) string {
+	if len(pod.Spec.Containers) == 1 {
+		return pod.Spec.Containers[0].Name
+	}
+	if len(pod.Spec.Containers) == 0 && len(pod.Status.ContainerStatuses) == 1 {
+		return pod.Status.ContainerStatuses[0].Name
+	}
+	return ""
+}
+
+// metricValue returns the value of the aggregation in a metric entry.
+func metricValue(data aci.TimeSeriesEntry, aggregation aci.AggregationType) float64 {
+	switch aggregation {
+	case aci.AggregationTypeMaximum:
+		return data.Maximum
+	case aci.AggregationTypeTotal:
+		return data.Total
+	case aci.AggregationTypeCount:
+		return data.Count
+	default:
+		return data.Average
+	}
+}
+
+func collectMetrics(pod *v1.Pod, system, net *aci.ContainerGroupMetricsResult, aggregation aci.AggregationType) stats.PodStats {
 	var stat stats.PodStats
 	containerStats := make(map[string]*stats.ContainerStats, len(pod.Status.ContainerStatuses))
 	stat.StartTime = pod.CreationTimestamp
@@ -161,7 +465,15 @@ func collectMetrics(pod *v1.Pod, system, net *aci.ContainerGroupMetricsResult) s
 				}
 			}
 			if cs == nil {
-				continue
+				// The metrics of Windows container groups are not split by container: they belong to the
+				// only container of the pod, or only count in the stats of the pod.
+				name := singleContainerName(pod)
+				if name == "" {
+					cs = &stats.ContainerStats{}
+				} else if cs = containerStats[name]; cs == nil {
+					cs = &stats.ContainerStats{Name: name, StartTime: stat.StartTime}
+					containerStats[name] = cs
+				}
 			}
 
 			if stat.Containers == nil {
@@ -175,8 +487,8 @@ func collectMetrics(pod *v1.Pod, system, net *aci.ContainerGroupMetricsResult) s
 					cs.CPU = &stats.CPUStats{}
 				}
 
-				// average is the average number of millicores over a 1 minute interval (which is the interval we are pulling the stats for)
-				nanoCores := uint64(data.Average * 1000000)
+				// the value is the number of millicores over a 1 minute interval by default (which is the interval we are pulling the stats for)
+				nanoCores := uint64(metricValue(data, aggregation) * 1000000)
 				usageNanoSeconds := nanoCores * 60
 				cs.CPU.Time = metav1.NewTime(data.Timestamp)
 				cs.CPU.UsageCoreNanoSeconds = &usageNanoSeconds
@@ -198,7 +510,7 @@ func collectMetrics(pod *v1.Pod, system, net *aci.ContainerGroupMetricsResult) s
 					cs.Memory = &stats.MemoryStats{}
 				}
 				cs.Memory.Time = metav1.NewTime(data.Timestamp)
-				bytes := uint64(data.Average)
+				bytes := uint64(metricValue(data, aggregation))
 				cs.Memory.UsageBytes = &bytes
 				cs.Memory.WorkingSetBytes = &bytes
 
@@ -228,7 +540,7 @@ func collectMetrics(pod *v1.Pod, system, net *aci.ContainerGroupMetricsResult) s
 		}
 		data := entry.Data[len(entry.Data)-1] // get only the last entry
 
-		bytes := uint64(data.Average)
+		bytes := uint64(metricValue(data, aggregation))
 		switch m.Desc.Value {
 		case aci.MetricTyperNetworkBytesRecievedPerSecond:
 			stat.Network.RxBytes = &bytes
@@ -239,6 +551,7 @@ func collectMetrics(pod *v1.Pod, system, net *aci.ContainerGroupMetricsResult) s
 		stat.Network.InterfaceStats.Name = "eth0"
 	}
 
+	setAvailableMemory(pod, &stat, containerStats)
 	for _, cs := range containerStats {
 		stat.Containers = append(stat.Containers, *cs)
 	}
@@ -251,3 +564,41 @@ func collectMetrics(pod *v1.Pod, system, net *aci.ContainerGroupMetricsResult) s
 
 	return stat
 }
+
+// setAvailableMemory sets the memory available to the containers and the pod, their memory limit minus their
+// usage, as the kubelet does for the eviction and autoscaling calculations. The limit of a container without
+// one is its request, which is the memory ACI allocates it. ACI only reports the working set of the containers,
+// not their RSS or page faults, so these stay unset.
+func setAvailableMemory(pod *v1.Pod, stat *stats.PodStats, containerStats map[string]*stats.ContainerStats) {
+	var podLimit, podUsage uint64
+	podLimited := stat.Memory != nil
+	for _, c := range pod.Spec.Containers {
+		cs := containerStats[c.Name]
+		limit := c.Resources.Limits.Memory().Value()
+		if limit == 0 {
+			limit = c.Resources.Requests.Memory().Value()
+		}
+		if limit <= 0 {
+			podLimited = false
+			continue
+		}
+		podLimit += uint64(limit)
+		if cs == nil || cs.Memory == nil || cs.Memory.WorkingSetBytes == nil {
+			continue
+		}
+		podUsage += *cs.Memory.WorkingSetBytes
+		available := availableBytes(uint64(limit), *cs.Memory.WorkingSetBytes)
+		cs.Memory.AvailableBytes = &available
+	}
+	if podLimited && len(pod.Spec.Containers) > 0 {
+		available := availableBytes(podLimit, podUsage)
+		stat.Memory.AvailableBytes = &available
+	}
+}
+
+func availableBytes(limit, usage uint64) uint64 {
+	if usage > limit {
+		return 0
+	}
+	return limit - usage
+}
@@ -1,14 +1,20 @@
 package provider
 
 import (
+	"context"
+	"net/http"
+	"os"
 	"path"
 	"reflect"
 	"strconv"
 	"testing"
 	"time"
 
+	"github.com/pkg/errors"
 	"github.com/virtual-kubelet/azure-aci/client/aci"
+	"github.com/virtual-kubelet/azure-aci/client/api"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	stats "k8s.io/kubernetes/pkg/kubelet/apis/stats/v1alpha1"
@@ -28,7 +34,7 @@ func TestCollectMetrics(t *testing.T) {
 			expected := podStatFromTestCase(t, pod, test)
 
 			system, net := fakeACIMetrics(pod, test)
-			actual := collectMetrics(pod, system, net)
+			actual := collectMetrics(pod, system, net, aci.AggregationTypeAverage)
 
 			if len(actual.Containers) != len(expected.Containers) {
 				t.Fatalf("got unexpected results\nexpected:\n%+v\nactual:\n%+v", expected, actual)
@@ -185,3 +191,220 @@ func podStatFromTestCase(t *testing.T, pod *v1.Pod, test metricTestCase) stats.P
 	}
 	return expected
 }
+
+func TestWithTerminatedPodStats(t *testing.T) {
+	var p ACIProvider
+	now := time.Now()
+	job := stats.PodStats{PodRef: stats.PodReference{Name: "job", Namespace: "ns", UID: "job-uid"}}
+	web := stats.PodStats{PodRef: stats.PodReference{Name: "web", Namespace: "ns", UID: "web-uid"}}
+
+	if summary := p.withTerminatedPodStats([]stats.PodStats{job, web}, now); len(summary) != 2 {
+		t.Fatalf("expected 2 pod stats, got %d", len(summary))
+	}
+
+	// The job completed, its last stats are still reported.
+	if summary := p.withTerminatedPodStats([]stats.PodStats{web}, now.Add(time.Minute)); len(summary) != 2 {
+		t.Fatalf("expected the stats of the terminated pod, got %d pod stats", len(summary))
+	}
+
+	// Until the TTL expires.
+	summary := p.withTerminatedPodStats([]stats.PodStats{web}, now.Add(terminatedPodStatsTTL+time.Second))
+	if len(summary) != 1 || summary[0].PodRef.UID != "web-uid" {
+		t.Fatalf("expected only the stats of the running pod after the TTL, got %v", summary)
+	}
+}
+
+type fakeMetricsSource struct {
+	requests []aci.MetricsRequest
+}
+
+func (f *fakeMetricsSource) GetContainerGroupMetrics(ctx context.Context, resourceGroup, containerGroup string, options aci.MetricsRequest) (*aci.ContainerGroupMetricsResult, error) {
+	f.requests = append(f.requests, options)
+	return &aci.ContainerGroupMetricsResult{}, nil
+}
+
+func TestGetContainerGroupMetrics(t *testing.T) {
+	source := &fakeMetricsSource{}
+	p := ACIProvider{metricsSource: source, resourceGroup: "rg", aggregation: aci.AggregationTypeAverage}
+	end := time.Now()
+	system, net, err := p.getContainerGroupMetrics(context.Background(), "ns-pod", false, end.Add(-time.Minute), end)
+	if err != nil || system == nil || net == nil {
+		t.Fatalf("expected the system and network metrics, got %v, %v, %v", system, net, err)
+	}
+	if len(source.requests) != 2 {
+		t.Fatalf("expected 2 metrics requests, got %d", len(source.requests))
+	}
+	if source.requests[0].Dimension == "" || source.requests[1].Dimension != "" {
+		t.Fatalf("expected only the cpu/mem metrics to be split by container, got %+v", source.requests)
+	}
+	if source.requests[0].Interval != time.Minute {
+		t.Fatalf("expected the metrics to be aggregated over the window, got an interval of %s", source.requests[0].Interval)
+	}
+
+	source.requests = nil
+	p.onlyCPUAndMemory = true
+	system, net, err = p.getContainerGroupMetrics(context.Background(), "ns-pod", false, end.Add(-time.Minute), end)
+	if err != nil || system == nil || net == nil || len(net.Value) != 0 {
+		t.Fatalf("expected the system metrics and empty network metrics, got %v, %v, %v", system, net, err)
+	}
+	if len(source.requests) != 1 {
+		t.Fatalf("expected only the cpu/mem metrics to be fetched, got %d requests", len(source.requests))
+	}
+}
+
+func TestSetupMetrics(t *testing.T) {
+	var p ACIProvider
+	if err := p.setupMetrics(); err != nil || p.aggregation != aci.AggregationTypeAverage || p.metricsWindow != time.Minute {
+		t.Fatalf("expected the 1 minute average by default, got %q, %s, %v", p.aggregation, p.metricsWindow, err)
+	}
+
+	p = ACIProvider{metricsAggregation: "Maximum", metricsWindowConfig: "5m"}
+	if err := p.setupMetrics(); err != nil || p.aggregation != aci.AggregationTypeMaximum || p.metricsWindow != 5*time.Minute {
+		t.Fatalf("expected the 5 minutes maximum, got %q, %s, %v", p.aggregation, p.metricsWindow, err)
+	}
+
+	os.Setenv("ACI_METRICS_WINDOW", "2m")
+	defer os.Unsetenv("ACI_METRICS_WINDOW")
+	if err := p.setupMetrics(); err == nil {
+		t.Fatal("expected an error for a window Azure Monitor does not support")
+	}
+
+	p = ACIProvider{metricsAggregation: "median"}
+	os.Unsetenv("ACI_METRICS_WINDOW")
+	if err := p.setupMetrics(); err == nil {
+		t.Fatal("expected an error for an unknown aggregation")
+	}
+}
+
+func TestCollectMetricsAvailableMemory(t *testing.T) {
+	pod := fakePod(t, 2, time.Now())
+	pod.Spec.Containers = []v1.Container{
+		{Name: "c0", Resources: v1.ResourceRequirements{Limits: v1.ResourceList{v1.ResourceMemory: resource.MustParse("1000")}}},
+		{Name: "c1", Resources: v1.ResourceRequirements{Requests: v1.ResourceList{v1.ResourceMemory: resource.MustParse("500")}}},
+	}
+	system, net := fakeACIMetrics(pod, metricTestCase{stats: [][2]float64{{100, 400}, {100, 600}}, collected: time.Now()})
+	stat := collectMetrics(pod, system, net, aci.AggregationTypeAverage)
+
+	available := map[string]uint64{}
+	for _, cs := range stat.Containers {
+		if cs.Memory == nil || cs.Memory.AvailableBytes == nil {
+			t.Fatalf("expected the available memory of container %s", cs.Name)
+		}
+		available[cs.Name] = *cs.Memory.AvailableBytes
+	}
+	if available["c0"] != 600 || available["c1"] != 0 {
+		t.Fatalf("expected the limits or requests minus the usage, got %v", available)
+	}
+	if stat.Memory.AvailableBytes == nil || *stat.Memory.AvailableBytes != 500 {
+		t.Fatalf("expected 500 bytes available in the pod, got %v", stat.Memory.AvailableBytes)
+	}
+}
+
+func TestAccumulateCPUUsage(t *testing.T) {
+	var p ACIProvider
+	now := time.Now()
+	podStats := func(sampleAt time.Time, nanoCores uint64) []stats.PodStats {
+		usage := nanoCores * 60
+		podUsage := usage
+		return []stats.PodStats{{
+			PodRef:     stats.PodReference{Name: "web", Namespace: "ns", UID: "web-uid"},
+			CPU:        &stats.CPUStats{UsageNanoCores: &nanoCores, UsageCoreNanoSeconds: &podUsage},
+			Containers: []stats.ContainerStats{{Name: "c0", CPU: &stats.CPUStats{Time: metav1.NewTime(sampleAt), UsageNanoCores: &nanoCores, UsageCoreNanoSeconds: &usage}}},
+		}}
+	}
+
+	first := podStats(now, 1000)
+	p.accumulateCPUUsage(first, now)
+	if v := *first[0].Containers[0].CPU.UsageCoreNanoSeconds; v != 60000 {
+		t.Fatalf("expected the first sample to count for a minute, got %d", v)
+	}
+
+	// The usage keeps increasing with the time since the last sample, even when the rate drops.
+	second := podStats(now.Add(2*time.Minute), 500)
+	p.accumulateCPUUsage(second, now.Add(2*time.Minute))
+	if v := *second[0].Containers[0].CPU.UsageCoreNanoSeconds; v != 120000 {
+		t.Fatalf("expected 120000 cumulated core nanoseconds, got %d", v)
+	}
+	if v := *second[0].CPU.UsageCoreNanoSeconds; v != 120000 {
+		t.Fatalf("expected the pod usage to sum its containers, got %d", v)
+	}
+
+	// A sample seen again doesn't count twice.
+	again := podStats(now.Add(2*time.Minute), 500)
+	p.accumulateCPUUsage(again, now.Add(3*time.Minute))
+	if v := *again[0].Containers[0].CPU.UsageCoreNanoSeconds; v != 120000 {
+		t.Fatalf("expected the usage to stay at 120000, got %d", v)
+	}
+
+	p.accumulateCPUUsage(nil, now.Add(3*time.Minute+terminatedPodStatsTTL+time.Second))
+	if len(p.cpuUsage) != 0 {
+		t.Fatalf("expected the usage of the gone container to be forgotten, got %v", p.cpuUsage)
+	}
+}
+
+func TestZeroStatsFallback(t *testing.T) {
+	if !isMetricsUnavailable(errors.Wrap(&api.Error{StatusCode: http.StatusForbidden}, "error fetching cpu/mem stats")) {
+		t.Fatal("expected a forbidden error to mean Azure Monitor is unavailable")
+	}
+	if isMetricsUnavailable(&api.Error{StatusCode: http.StatusTooManyRequests}) {
+		t.Fatal("expected a throttled request not to mean Azure Monitor is unavailable")
+	}
+
+	pod := fakePod(t, 0, time.Now())
+	pod.Spec.Containers = []v1.Container{{Name: "c0"}, {Name: "c1"}}
+	stat := zeroPodStats(pod, time.Now())
+	if stat.PodRef.UID != string(pod.UID) || len(stat.Containers) != 2 {
+		t.Fatalf("expected the stats of the pod and its 2 containers, got %+v", stat)
+	}
+	for _, cs := range stat.Containers {
+		if cs.CPU == nil || *cs.CPU.UsageNanoCores != 0 || cs.Memory == nil || *cs.Memory.WorkingSetBytes != 0 {
+			t.Fatalf("expected zero stats for container %s, got %+v", cs.Name, cs)
+		}
+	}
+}
+
+func TestWindowsPodMetrics(t *testing.T) {
+	source := &fakeMetricsSource{}
+	p := ACIProvider{metricsSource: source, aggregation: aci.AggregationTypeAverage, operatingSystem: "Windows"}
+	pod := fakePod(t, 2, time.Now())
+	if !p.isWindowsPod(pod) {
+		t.Fatal("expected the pods of a Windows provider to be Windows pods")
+	}
+	end := time.Now()
+	if _, _, err := p.getContainerGroupMetrics(context.Background(), "ns-pod", true, end.Add(-time.Minute), end); err != nil {
+		t.Fatal(err)
+	}
+	if len(source.requests) != 1 || source.requests[0].Dimension != "" {
+		t.Fatalf("expected only the cpu/mem metrics of the container group, got %+v", source.requests)
+	}
+
+	// The metrics of the container group are not split by container.
+	cpu := aci.MetricValue{Desc: aci.MetricDescriptor{Value: aci.MetricTypeCPUUsage}, Timeseries: []aci.MetricTimeSeries{{Data: []aci.TimeSeriesEntry{{Timestamp: end, Average: 200}}}}}
+	system := &aci.ContainerGroupMetricsResult{Value: []aci.MetricValue{cpu}}
+	stat := collectMetrics(pod, system, &aci.ContainerGroupMetricsResult{}, aci.AggregationTypeAverage)
+	if len(stat.Containers) != 0 || stat.CPU == nil || *stat.CPU.UsageNanoCores != 200000000 {
+		t.Fatalf("expected only the cpu of the pod, got %+v", stat)
+	}
+
+	single := fakePod(t, 1, time.Now())
+	stat = collectMetrics(single, system, &aci.ContainerGroupMetricsResult{}, aci.AggregationTypeAverage)
+	if len(stat.Containers) != 1 || stat.Containers[0].Name != "c0" || stat.Containers[0].CPU == nil {
+		t.Fatalf("expected the cpu of the only container, got %+v", stat)
+	}
+}
+
+func TestCollectsMetrics(t *testing.T) {
+	p := ACIProvider{noMetricsNamespaces: []string{"batch-*"}}
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}}
+	if !p.collectsMetrics(pod) {
+		t.Fatal("expected the stats of the pod to be collected")
+	}
+	pod.Annotations = map[string]string{skipMetricsAnnotation: "true"}
+	if p.collectsMetrics(pod) {
+		t.Fatal("expected the annotated pod to be left out")
+	}
+	job := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "job", Namespace: "batch-nightly"}}
+	if p.collectsMetrics(job) {
+		t.Fatal("expected the pods of an excluded namespace to be left out")
+	}
+}
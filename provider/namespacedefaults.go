@@ -0,0 +1,96 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/virtual-kubelet/azure-aci/client/aci"
+	"github.com/virtual-kubelet/virtual-kubelet/errdefs"
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	namespaceDefaultsResource = "acinamespacedefaults"
+	// namespaceDefaultsName is the name of the ACINamespaceDefaults of a namespace, the only one read.
+	namespaceDefaultsName = "default"
+)
+
+// aciNamespaceDefaults is an ACINamespaceDefaults, the default ACI settings of the pods of a namespace.
+type aciNamespaceDefaults struct {
+	Spec aciNamespaceDefaultsSpec `json:"spec"`
+}
+
+// aciNamespaceDefaultsSpec is the spec of an ACINamespaceDefaults. The settings of an ACIPodConfig of a pod, then its
+// annotations, take precedence over the defaults.
+type aciNamespaceDefaultsSpec struct {
+	aciPodConfigSpec `json:",inline"`
+	// Tags are added to the container groups, without replacing the tags of the provider.
+	Tags map[string]string `json:"tags,omitempty"`
+	// LogAnalytics is the workspace the logs of the container groups are sent to, instead of the one of the node.
+	LogAnalytics *namespaceLogAnalytics `json:"logAnalytics,omitempty"`
+}
+
+// namespaceLogAnalytics is the Log Analytics workspace of a namespace, with its key in a secret of the namespace.
+type namespaceLogAnalytics struct {
+	WorkspaceID        string                `json:"workspaceID"`
+	WorkspaceKeySecret *v1.SecretKeySelector `json:"workspaceKeySecret"`
+	LogType            string                `json:"logType,omitempty"`
+	Metadata           map[string]string     `json:"metadata,omitempty"`
+}
+
+func (s aciNamespaceDefaultsSpec) validate() error {
+	if s.PodSelector != nil {
+		return fmt.Errorf("podSelector is not supported, the defaults apply to all the pods of the namespace")
+	}
+	if s.LogAnalytics != nil && (s.LogAnalytics.WorkspaceID == "" || s.LogAnalytics.WorkspaceKeySecret == nil) {
+		return fmt.Errorf("logAnalytics requires a workspaceID and a workspaceKeySecret")
+	}
+	return s.aciPodConfigSpec.validate()
+}
+
+// getNamespaceDefaults returns the ACINamespaceDefaults of the namespace of a pod, nil if it has none.
+func (p *ACIProvider) getNamespaceDefaults(ctx context.Context, pod *v1.Pod) (*aciNamespaceDefaultsSpec, error) {
+	defaults, err := p.podConfigs.GetNamespaceDefaults(ctx, pod.Namespace)
+	if err != nil || defaults == nil {
+		return nil, err
+	}
+	if err := defaults.Spec.validate(); err != nil {
+		return nil, errdefs.InvalidInputf("pod %s: invalid ACINamespaceDefaults of namespace %s: %v", pod.Name, pod.Namespace, err)
+	}
+	return &defaults.Spec, nil
+}
+
+// namespaceDefaultsDiagnostics returns the diagnostics of the Log Analytics workspace of the defaults of a
+// namespace, with the key of the workspace read from its secret.
+func (p *ACIProvider) namespaceDefaultsDiagnostics(pod *v1.Pod, la *namespaceLogAnalytics) (*aci.ContainerGroupDiagnostics, error) {
+	if p.resourceManager == nil {
+		return nil, fmt.Errorf("the Log Analytics workspace key of namespace %s requires a resource manager to read its secret", pod.Namespace)
+	}
+	secret, err := p.resourceManager.GetSecret(la.WorkspaceKeySecret.Name, pod.Namespace)
+	if err != nil {
+		return nil, errdefs.InvalidInputf("pod %s: error reading the Log Analytics workspace key of namespace %s: %v", pod.Name, pod.Namespace, err)
+	}
+	key, ok := secret.Data[la.WorkspaceKeySecret.Key]
+	if !ok {
+		return nil, errdefs.InvalidInputf("pod %s: secret %s has no key %s for the Log Analytics workspace of namespace %s", pod.Name, la.WorkspaceKeySecret.Name, la.WorkspaceKeySecret.Key, pod.Namespace)
+	}
+	diagnostics, err := namespaceDiagnostics{
+		WorkspaceID:  la.WorkspaceID,
+		WorkspaceKey: string(key),
+		LogType:      la.LogType,
+		Metadata:     la.Metadata,
+	}.containerGroupDiagnostics()
+	if err != nil {
+		return nil, errdefs.InvalidInputf("pod %s: invalid logAnalytics of the ACINamespaceDefaults of namespace %s: %v", pod.Name, pod.Namespace, err)
+	}
+	return diagnostics, nil
+}
+
+// applyTags adds the tags of the defaults of the namespace to a container group, the tags of the provider are kept.
+func applyTags(cg *aci.ContainerGroup, tags map[string]string) {
+	for key, value := range tags {
+		if _, ok := cg.Tags[key]; !ok {
+			cg.Tags[key] = value
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package provider
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/virtual-kubelet/virtual-kubelet/errdefs"
+	v1 "k8s.io/api/core/v1"
+)
+
+const eventReasonNamespaceNotAllowed = "NamespaceNotAllowed"
+
+// setupNamespaces validates the namespaces allowed and denied on this node, read from the
+// ACI_ALLOWED_NAMESPACES and ACI_DENIED_NAMESPACES comma separated lists or the config file.
+// The names can be patterns, like kube-*.
+func (p *ACIProvider) setupNamespaces() error {
+	if v := os.Getenv("ACI_ALLOWED_NAMESPACES"); v != "" {
+		p.allowedNamespaces = splitNamespaces(v)
+	}
+	if v := os.Getenv("ACI_DENIED_NAMESPACES"); v != "" {
+		p.deniedNamespaces = splitNamespaces(v)
+	}
+
+	for _, pattern := range append(append([]string{}, p.allowedNamespaces...), p.deniedNamespaces...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid namespace pattern %q: %v", pattern, err)
+		}
+	}
+	return nil
+}
+
+func splitNamespaces(v string) []string {
+	var namespaces []string
+	for _, ns := range strings.Split(v, ",") {
+		if ns = strings.TrimSpace(ns); ns != "" {
+			namespaces = append(namespaces, ns)
+		}
+	}
+	return namespaces
+}
+
+func matchNamespace(patterns []string, namespace string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, namespace); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// namespaceAllowed reports whether pods of the namespace can run on this node. A denied
+// namespace is never allowed, and when namespaces are allowed only those are.
+func (p *ACIProvider) namespaceAllowed(namespace string) bool {
+	if matchNamespace(p.deniedNamespaces, namespace) {
+		return false
+	}
+	return len(p.allowedNamespaces) == 0 || matchNamespace(p.allowedNamespaces, namespace)
+}
+
+// checkPodNamespace rejects the pods of the namespaces not allowed on this node with an event,
+// so a pod tolerating the taint of the node by mistake is never burst to ACI.
+func (p *ACIProvider) checkPodNamespace(pod *v1.Pod) error {
+	if p.namespaceAllowed(pod.Namespace) {
+		return nil
+	}
+
+	p.recordEvent(pod, v1.EventTypeWarning, eventReasonNamespaceNotAllowed, "Pods of namespace %s are not allowed on node %s", pod.Namespace, p.nodeName)
+	return errdefs.InvalidInputf("pod %s can not be created, pods of namespace %s are not allowed on node %s", pod.Name, pod.Namespace, p.nodeName)
+}
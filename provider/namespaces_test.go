@@ -0,0 +1,51 @@
+package provider
+
+import (
+	"os"
+	"testing"
+
+	"github.com/virtual-kubelet/virtual-kubelet/errdefs"
+	"gotest.tools/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestNamespaceAllowed(t *testing.T) {
+	p := ACIProvider{}
+	assert.Check(t, p.namespaceAllowed("default"), "All namespaces should be allowed by default")
+
+	p = ACIProvider{deniedNamespaces: []string{"kube-*", "secrets"}}
+	assert.Check(t, p.namespaceAllowed("default"))
+	assert.Check(t, !p.namespaceAllowed("kube-system"))
+	assert.Check(t, !p.namespaceAllowed("secrets"))
+
+	p = ACIProvider{allowedNamespaces: []string{"burst-*"}, deniedNamespaces: []string{"burst-payments"}}
+	assert.Check(t, p.namespaceAllowed("burst-web"))
+	assert.Check(t, !p.namespaceAllowed("default"), "Namespaces not allowed should be rejected")
+	assert.Check(t, !p.namespaceAllowed("burst-payments"), "Denied namespaces should win over allowed ones")
+}
+
+func TestSetupNamespaces(t *testing.T) {
+	os.Setenv("ACI_ALLOWED_NAMESPACES", "web, batch,")
+	defer os.Unsetenv("ACI_ALLOWED_NAMESPACES")
+	p := ACIProvider{}
+	assert.NilError(t, p.setupNamespaces())
+	assert.DeepEqual(t, []string{"web", "batch"}, p.allowedNamespaces)
+
+	p = ACIProvider{deniedNamespaces: []string{"kube-["}}
+	assert.Check(t, p.setupNamespaces() != nil, "Invalid pattern should be rejected")
+}
+
+func TestCheckPodNamespace(t *testing.T) {
+	recorder := record.NewFakeRecorder(1)
+	p := ACIProvider{nodeName: fakeNodeName, deniedNamespaces: []string{"secrets"}, eventRecorder: recorder}
+
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "nginx", Namespace: "default"}}
+	assert.NilError(t, p.checkPodNamespace(pod))
+
+	pod.Namespace = "secrets"
+	err := p.checkPodNamespace(pod)
+	assert.Check(t, errdefs.IsInvalidInput(err), "Expected an invalid input error, got %v", err)
+	assert.Equal(t, "Warning NamespaceNotAllowed Pods of namespace secrets are not allowed on node "+fakeNodeName, <-recorder.Events)
+}
@@ -0,0 +1,315 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	aznetwork "github.com/Azure/azure-sdk-for-go/services/network/mgmt/2018-08-01/network"
+	"github.com/virtual-kubelet/azure-aci/client/api"
+	"github.com/virtual-kubelet/virtual-kubelet/log"
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	eventReasonSubnetNotDelegated     = "SubnetNotDelegated"
+	eventReasonSecurityRuleBlocks     = "SecurityRuleBlocksTraffic"
+	eventReasonEgressThroughAppliance = "EgressThroughAppliance"
+	eventReasonSubnetFull             = "SubnetFull"
+	eventReasonNetworkCheckFailed     = "NetworkCheckFailed"
+	eventReasonNetworkCheckPassed     = "NetworkCheckPassed"
+
+	// networkCheckMinInterval is how often the network is checked again at most, when the creation of
+	// container groups fails on the network.
+	networkCheckMinInterval = 5 * time.Minute
+)
+
+// networkInspector reads the delegated subnet and the security group and route table it references.
+type networkInspector interface {
+	GetSubnet(resourceGroup, vnet, name string) (*aznetwork.Subnet, error)
+	GetSecurityGroup(id string) (*aznetwork.SecurityGroup, error)
+	GetRouteTable(id string) (*aznetwork.RouteTable, error)
+}
+
+// networkProblem is a problem of the virtual network which makes container groups fail or misbehave.
+type networkProblem struct {
+	reason  string
+	message string
+}
+
+// networkCheck throttles the checks of the network on demand.
+type networkCheck struct {
+	mu        sync.Mutex
+	checkedAt time.Time
+}
+
+// checkNetwork validates the delegated subnet, its security group, route table and free IP addresses, and
+// reports the problems found as warning events on the node, instead of every pod creation failing on them.
+func (p *ACIProvider) checkNetwork(ctx context.Context, inspector networkInspector) []networkProblem {
+	p.networkCheck.mu.Lock()
+	p.networkCheck.checkedAt = time.Now()
+	p.networkCheck.mu.Unlock()
+
+	subnet, err := inspector.GetSubnet(p.vnetResourceGroup, p.vnetName, p.subnetName)
+	if err != nil {
+		log.G(ctx).WithError(err).Warn("Unable to get the subnet to check the network")
+		p.recordNodeEvent(v1.EventTypeWarning, eventReasonNetworkCheckFailed, "Unable to get subnet %s of virtual network %s to check it: %v", p.subnetName, p.vnetName, err)
+		return nil
+	}
+	problems := subnetProblems(subnet)
+
+	if props := subnet.SubnetPropertiesFormat; props != nil {
+		if props.NetworkSecurityGroup != nil && props.NetworkSecurityGroup.ID != nil {
+			nsg, err := inspector.GetSecurityGroup(*props.NetworkSecurityGroup.ID)
+			if err != nil {
+				log.G(ctx).WithError(err).Warn("Unable to get the network security group of the subnet")
+			} else {
+				problems = append(problems, securityGroupProblems(nsg)...)
+			}
+		}
+		if props.RouteTable != nil && props.RouteTable.ID != nil {
+			rt, err := inspector.GetRouteTable(*props.RouteTable.ID)
+			if err != nil {
+				log.G(ctx).WithError(err).Warn("Unable to get the route table of the subnet")
+			} else {
+				problems = append(problems, routeTableProblems(rt)...)
+			}
+		}
+	}
+
+	for _, problem := range problems {
+		log.G(ctx).WithField("reason", problem.reason).Warn(problem.message)
+		p.recordNodeEvent(v1.EventTypeWarning, problem.reason, "%s", problem.message)
+	}
+	if len(problems) == 0 {
+		p.recordNodeEvent(v1.EventTypeNormal, eventReasonNetworkCheckPassed, "Subnet %s of virtual network %s is ready for container groups", p.subnetName, p.vnetName)
+	}
+	return problems
+}
+
+// checkNetworkOnFailure checks the network again when the creation of a container group failed with an
+// error of the network, at most every networkCheckMinInterval.
+func (p *ACIProvider) checkNetworkOnFailure(ctx context.Context, err error) {
+	if p.networkClient == nil || p.subnetName == "" || !isNetworkError(err) {
+		return
+	}
+
+	p.networkCheck.mu.Lock()
+	due := time.Since(p.networkCheck.checkedAt) >= networkCheckMinInterval
+	p.networkCheck.mu.Unlock()
+	if due {
+		p.checkNetwork(ctx, p.networkClient)
+	}
+}
+
+// isNetworkError reports whether ARM rejected a container group because of its subnet or network profile.
+func isNetworkError(err error) bool {
+	e, ok := err.(*api.Error)
+	if !ok || e.StatusCode != http.StatusBadRequest {
+		return false
+	}
+	s := strings.ToLower(e.Code + " " + e.Message)
+	return strings.Contains(s, "subnet") || strings.Contains(s, "networkprofile") || strings.Contains(s, "network profile")
+}
+
+// subnetProblems checks that the subnet is delegated to ACI and has IP addresses left.
+func subnetProblems(subnet *aznetwork.Subnet) []networkProblem {
+	props := subnet.SubnetPropertiesFormat
+	if props == nil {
+		return nil
+	}
+	name := ""
+	if subnet.Name != nil {
+		name = *subnet.Name
+	}
+
+	var problems []networkProblem
+	delegated := false
+	if props.Delegations != nil {
+		for _, d := range *props.Delegations {
+			if d.ServiceDelegationPropertiesFormat != nil && d.ServiceDelegationPropertiesFormat.ServiceName != nil &&
+				*d.ServiceDelegationPropertiesFormat.ServiceName == subnetDelegationService {
+				delegated = true
+			}
+		}
+	}
+	if !delegated {
+		problems = append(problems, networkProblem{
+			reason:  eventReasonSubnetNotDelegated,
+			message: fmt.Sprintf("Subnet %s is not delegated to %s, container groups can't be deployed in it", name, subnetDelegationService),
+		})
+	}
+
+	if props.AddressPrefix != nil {
+		used := 0
+		if props.IPConfigurations != nil {
+			used = len(*props.IPConfigurations)
+		}
+		if subnetUsableIPs(*props.AddressPrefix)-used <= 0 {
+			problems = append(problems, networkProblem{
+				reason:  eventReasonSubnetFull,
+				message: fmt.Sprintf("Subnet %s (%s) has no IP address left for container groups", name, *props.AddressPrefix),
+			})
+		}
+	}
+	return problems
+}
+
+// trafficCheck is traffic the container groups need, which the security group of the subnet must allow.
+type trafficCheck struct {
+	direction   aznetwork.SecurityRuleDirection
+	port        int
+	peers       []string
+	description string
+}
+
+var requiredTraffic = []trafficCheck{
+	{aznetwork.SecurityRuleDirectionOutbound, 443, []string{"*", "Internet", "0.0.0.0/0", "AzureCloud"}, "outbound HTTPS, so container groups can pull images and reach Azure"},
+	{aznetwork.SecurityRuleDirectionInbound, 0, []string{"*", "VirtualNetwork"}, "inbound traffic from the virtual network, so the cluster can reach the pods"},
+}
+
+// securityGroupProblems checks that the first rule of the security group, by priority, matching each
+// required traffic allows it.
+func securityGroupProblems(nsg *aznetwork.SecurityGroup) []networkProblem {
+	props := nsg.SecurityGroupPropertiesFormat
+	if props == nil || props.SecurityRules == nil {
+		return nil
+	}
+	rules := append([]aznetwork.SecurityRule{}, *props.SecurityRules...)
+	sort.SliceStable(rules, func(i, j int) bool { return rulePriority(rules[i]) < rulePriority(rules[j]) })
+
+	name := ""
+	if nsg.Name != nil {
+		name = *nsg.Name
+	}
+
+	var problems []networkProblem
+	for _, traffic := range requiredTraffic {
+		for _, rule := range rules {
+			if !ruleMatches(rule, traffic) {
+				continue
+			}
+			if rule.SecurityRulePropertiesFormat.Access == aznetwork.SecurityRuleAccessDeny {
+				ruleName := ""
+				if rule.Name != nil {
+					ruleName = *rule.Name
+				}
+				problems = append(problems, networkProblem{
+					reason:  eventReasonSecurityRuleBlocks,
+					message: fmt.Sprintf("Rule %s of network security group %s denies %s", ruleName, name, traffic.description),
+				})
+			}
+			break
+		}
+	}
+	return problems
+}
+
+func rulePriority(rule aznetwork.SecurityRule) int32 {
+	if rule.SecurityRulePropertiesFormat == nil || rule.SecurityRulePropertiesFormat.Priority == nil {
+		return 0
+	}
+	return *rule.SecurityRulePropertiesFormat.Priority
+}
+
+// ruleMatches reports whether a security rule applies to all the required traffic. The port 0 stands
+// for all the ports, only matched by rules for all the ports.
+func ruleMatches(rule aznetwork.SecurityRule, traffic trafficCheck) bool {
+	props := rule.SecurityRulePropertiesFormat
+	if props == nil || props.Direction != traffic.direction {
+		return false
+	}
+	if props.Protocol != aznetwork.SecurityRuleProtocolAsterisk && props.Protocol != aznetwork.SecurityRuleProtocolTCP {
+		return false
+	}
+
+	peer, peers := props.DestinationAddressPrefix, props.DestinationAddressPrefixes
+	if traffic.direction == aznetwork.SecurityRuleDirectionInbound {
+		peer, peers = props.SourceAddressPrefix, props.SourceAddressPrefixes
+	}
+	if !matchesAny(peer, peers, func(prefix string) bool {
+		for _, p := range traffic.peers {
+			if strings.EqualFold(prefix, p) {
+				return true
+			}
+		}
+		return false
+	}) {
+		return false
+	}
+
+	return matchesAny(props.DestinationPortRange, props.DestinationPortRanges, func(ports string) bool {
+		return portRangeIncludes(ports, traffic.port)
+	})
+}
+
+func matchesAny(value *string, values *[]string, match func(string) bool) bool {
+	if value != nil && match(*value) {
+		return true
+	}
+	if values != nil {
+		for _, v := range *values {
+			if match(v) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// portRangeIncludes reports whether a port range of a security rule, like *, 443 or 400-500, includes the port.
+func portRangeIncludes(ports string, port int) bool {
+	if ports == "*" {
+		return true
+	}
+	if port == 0 {
+		return false
+	}
+	bounds := strings.SplitN(ports, "-", 2)
+	low, err := strconv.Atoi(strings.TrimSpace(bounds[0]))
+	if err != nil {
+		return false
+	}
+	high := low
+	if len(bounds) == 2 {
+		if high, err = strconv.Atoi(strings.TrimSpace(bounds[1])); err != nil {
+			return false
+		}
+	}
+	return low <= port && port <= high
+}
+
+// routeTableProblems reports the default routes sending the egress of the subnet through a virtual appliance,
+// which must allow the traffic of the container groups.
+func routeTableProblems(rt *aznetwork.RouteTable) []networkProblem {
+	props := rt.RouteTablePropertiesFormat
+	if props == nil || props.Routes == nil {
+		return nil
+	}
+	name := ""
+	if rt.Name != nil {
+		name = *rt.Name
+	}
+
+	var problems []networkProblem
+	for _, route := range *props.Routes {
+		r := route.RoutePropertiesFormat
+		if r == nil || r.AddressPrefix == nil || *r.AddressPrefix != "0.0.0.0/0" || r.NextHopType != aznetwork.RouteNextHopTypeVirtualAppliance {
+			continue
+		}
+		hop := ""
+		if r.NextHopIPAddress != nil {
+			hop = *r.NextHopIPAddress
+		}
+		problems = append(problems, networkProblem{
+			reason:  eventReasonEgressThroughAppliance,
+			message: fmt.Sprintf("Route table %s sends the egress of the subnet through the virtual appliance %s, which must allow container groups to pull images and reach Azure", name, hop),
+		})
+	}
+	return problems
+}
@@ -0,0 +1,109 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	aznetwork "github.com/Azure/azure-sdk-for-go/services/network/mgmt/2018-08-01/network"
+	"github.com/virtual-kubelet/azure-aci/client/api"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+type fakeNetworkInspector struct {
+	subnet *aznetwork.Subnet
+	nsg    *aznetwork.SecurityGroup
+	rt     *aznetwork.RouteTable
+}
+
+func (f *fakeNetworkInspector) GetSubnet(resourceGroup, vnet, name string) (*aznetwork.Subnet, error) {
+	return f.subnet, nil
+}
+
+func (f *fakeNetworkInspector) GetSecurityGroup(id string) (*aznetwork.SecurityGroup, error) {
+	return f.nsg, nil
+}
+
+func (f *fakeNetworkInspector) GetRouteTable(id string) (*aznetwork.RouteTable, error) {
+	return f.rt, nil
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+func securityRule(name string, priority int32, direction aznetwork.SecurityRuleDirection, access aznetwork.SecurityRuleAccess, peer, ports string) aznetwork.SecurityRule {
+	props := &aznetwork.SecurityRulePropertiesFormat{
+		Protocol:             aznetwork.SecurityRuleProtocolAsterisk,
+		Access:               access,
+		Direction:            direction,
+		Priority:             int32Ptr(priority),
+		DestinationPortRange: stringPtr(ports),
+	}
+	if direction == aznetwork.SecurityRuleDirectionInbound {
+		props.SourceAddressPrefix = stringPtr(peer)
+	} else {
+		props.DestinationAddressPrefix = stringPtr(peer)
+	}
+	return aznetwork.SecurityRule{Name: stringPtr(name), SecurityRulePropertiesFormat: props}
+}
+
+func TestCheckNetwork(t *testing.T) {
+	delegations := []aznetwork.Delegation{{ServiceDelegationPropertiesFormat: &aznetwork.ServiceDelegationPropertiesFormat{ServiceName: stringPtr(subnetDelegationService)}}}
+	inspector := &fakeNetworkInspector{
+		subnet: &aznetwork.Subnet{
+			Name: stringPtr("aci"),
+			SubnetPropertiesFormat: &aznetwork.SubnetPropertiesFormat{
+				AddressPrefix:        stringPtr("10.1.0.0/24"),
+				Delegations:          &delegations,
+				NetworkSecurityGroup: &aznetwork.SecurityGroup{ID: stringPtr("nsg-id")},
+				RouteTable:           &aznetwork.RouteTable{ID: stringPtr("rt-id")},
+			},
+		},
+		nsg: &aznetwork.SecurityGroup{
+			Name: stringPtr("nsg"),
+			SecurityGroupPropertiesFormat: &aznetwork.SecurityGroupPropertiesFormat{SecurityRules: &[]aznetwork.SecurityRule{
+				securityRule("deny-internet", 200, aznetwork.SecurityRuleDirectionOutbound, aznetwork.SecurityRuleAccessDeny, "Internet", "*"),
+				securityRule("allow-https", 100, aznetwork.SecurityRuleDirectionOutbound, aznetwork.SecurityRuleAccessAllow, "Internet", "443"),
+				securityRule("deny-vnet", 300, aznetwork.SecurityRuleDirectionInbound, aznetwork.SecurityRuleAccessDeny, "VirtualNetwork", "*"),
+			}},
+		},
+		rt: &aznetwork.RouteTable{
+			Name: stringPtr("rt"),
+			RouteTablePropertiesFormat: &aznetwork.RouteTablePropertiesFormat{Routes: &[]aznetwork.Route{{
+				RoutePropertiesFormat: &aznetwork.RoutePropertiesFormat{
+					AddressPrefix:    stringPtr("0.0.0.0/0"),
+					NextHopType:      aznetwork.RouteNextHopTypeVirtualAppliance,
+					NextHopIPAddress: stringPtr("10.0.0.4"),
+				},
+			}}},
+		},
+	}
+
+	p := ACIProvider{vnetName: "vnet", subnetName: "aci"}
+	problems := p.checkNetwork(context.Background(), inspector)
+	assert.Assert(t, is.Len(problems, 2))
+	assert.Equal(t, problems[0].reason, eventReasonSecurityRuleBlocks)
+	assert.Check(t, is.Contains(problems[0].message, "deny-vnet"), "The allowed HTTPS egress should not be reported")
+	assert.Equal(t, problems[1].reason, eventReasonEgressThroughAppliance)
+
+	inspector.subnet.SubnetPropertiesFormat = &aznetwork.SubnetPropertiesFormat{AddressPrefix: stringPtr("10.1.0.0/29"), IPConfigurations: &[]aznetwork.IPConfiguration{{}, {}, {}}}
+	problems = p.checkNetwork(context.Background(), inspector)
+	assert.Assert(t, is.Len(problems, 2))
+	assert.Equal(t, problems[0].reason, eventReasonSubnetNotDelegated)
+	assert.Equal(t, problems[1].reason, eventReasonSubnetFull)
+}
+
+func TestPortRangeIncludes(t *testing.T) {
+	assert.Check(t, portRangeIncludes("*", 0))
+	assert.Check(t, portRangeIncludes("443", 443))
+	assert.Check(t, portRangeIncludes("400-500", 443))
+	assert.Check(t, !portRangeIncludes("80", 443))
+	assert.Check(t, !portRangeIncludes("1-65535", 0), "All the ports are only matched by *")
+}
+
+func TestIsNetworkError(t *testing.T) {
+	assert.Check(t, isNetworkError(&api.Error{StatusCode: 400, Code: "SubnetNotDelegated"}))
+	assert.Check(t, !isNetworkError(&api.Error{StatusCode: 400, Code: "InvalidImage"}))
+	assert.Check(t, !isNetworkError(&api.Error{StatusCode: 500, Message: "subnet"}))
+}
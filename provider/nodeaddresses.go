@@ -0,0 +1,63 @@
+package provider
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// setupNodeAddresses validates the addresses reported for the node, from the config file or the
+// ACI_NODE_INTERNAL_IP, ACI_NODE_EXTERNAL_ADDRESS and ACI_NODE_HOSTNAME environment variables.
+// The internal IP defaults to the IP of the virtual kubelet pod.
+func (p *ACIProvider) setupNodeAddresses() error {
+	if ip := os.Getenv("ACI_NODE_INTERNAL_IP"); ip != "" {
+		p.internalIP = ip
+	}
+	if p.internalIP != "" && net.ParseIP(p.internalIP) == nil {
+		return fmt.Errorf("invalid node internal IP %q", p.internalIP)
+	}
+
+	if address := os.Getenv("ACI_NODE_EXTERNAL_ADDRESS"); address != "" {
+		p.externalAddress = address
+	}
+	if p.externalAddress != "" && net.ParseIP(p.externalAddress) == nil {
+		if errs := validation.IsDNS1123Subdomain(p.externalAddress); len(errs) > 0 {
+			return fmt.Errorf("invalid node external address %q, expected an IP or a DNS name: %s", p.externalAddress, strings.Join(errs, ", "))
+		}
+	}
+
+	if hostname := os.Getenv("ACI_NODE_HOSTNAME"); hostname != "" {
+		p.hostname = hostname
+	}
+	if p.hostname != "" {
+		if errs := validation.IsDNS1123Subdomain(p.hostname); len(errs) > 0 {
+			return fmt.Errorf("invalid node hostname %q: %s", p.hostname, strings.Join(errs, ", "))
+		}
+	}
+
+	return nil
+}
+
+// nodeAddresses returns a list of addresses for the node status
+// within Kubernetes.
+func (p *ACIProvider) nodeAddresses() []v1.NodeAddress {
+	var addresses []v1.NodeAddress
+	if p.internalIP != "" {
+		addresses = append(addresses, v1.NodeAddress{Type: v1.NodeInternalIP, Address: p.internalIP})
+	}
+	if p.externalAddress != "" {
+		addressType := v1.NodeExternalDNS
+		if net.ParseIP(p.externalAddress) != nil {
+			addressType = v1.NodeExternalIP
+		}
+		addresses = append(addresses, v1.NodeAddress{Type: addressType, Address: p.externalAddress})
+	}
+	if p.hostname != "" {
+		addresses = append(addresses, v1.NodeAddress{Type: v1.NodeHostName, Address: p.hostname})
+	}
+	return addresses
+}
@@ -0,0 +1,35 @@
+package provider
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestNodeAddresses(t *testing.T) {
+	p := ACIProvider{internalIP: "10.240.0.4"}
+	assert.NilError(t, p.setupNodeAddresses())
+	assert.Check(t, is.DeepEqual([]v1.NodeAddress{{Type: v1.NodeInternalIP, Address: "10.240.0.4"}}, p.nodeAddresses()))
+
+	p = ACIProvider{internalIP: "10.240.0.4", externalAddress: "20.1.2.3", hostname: "virtual-node-aci"}
+	assert.NilError(t, p.setupNodeAddresses())
+	assert.Check(t, is.DeepEqual([]v1.NodeAddress{
+		{Type: v1.NodeInternalIP, Address: "10.240.0.4"},
+		{Type: v1.NodeExternalIP, Address: "20.1.2.3"},
+		{Type: v1.NodeHostName, Address: "virtual-node-aci"},
+	}, p.nodeAddresses()))
+
+	p = ACIProvider{externalAddress: "vk.example.com"}
+	assert.NilError(t, p.setupNodeAddresses())
+	assert.Check(t, is.DeepEqual([]v1.NodeAddress{{Type: v1.NodeExternalDNS, Address: "vk.example.com"}}, p.nodeAddresses()))
+}
+
+func TestNodeAddressesInvalid(t *testing.T) {
+	p := ACIProvider{internalIP: "not-an-ip"}
+	assert.Check(t, p.setupNodeAddresses() != nil, "Invalid internal IP should be rejected")
+
+	p = ACIProvider{hostname: "Not_A_Hostname"}
+	assert.Check(t, p.setupNodeAddresses() != nil, "Invalid hostname should be rejected")
+}
@@ -0,0 +1,145 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/virtual-kubelet/virtual-kubelet/log"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	regionLabel     = "topology.kubernetes.io/region"
+	zoneLabel       = "topology.kubernetes.io/zone"
+	betaRegionLabel = "failure-domain.beta.kubernetes.io/region"
+	betaZoneLabel   = "failure-domain.beta.kubernetes.io/zone"
+
+	nodeConfigReconcileInterval = time.Minute
+)
+
+// nodeTaint is a taint of the node in the provider config file.
+type nodeTaint struct {
+	Key    string
+	Value  string
+	Effect string
+}
+
+func (t nodeTaint) taint() (v1.Taint, error) {
+	if errs := validation.IsQualifiedName(t.Key); len(errs) > 0 {
+		return v1.Taint{}, fmt.Errorf("invalid taint key %q: %s", t.Key, strings.Join(errs, ", "))
+	}
+	if errs := validation.IsValidLabelValue(t.Value); len(errs) > 0 {
+		return v1.Taint{}, fmt.Errorf("invalid taint value %q: %s", t.Value, strings.Join(errs, ", "))
+	}
+
+	switch effect := v1.TaintEffect(t.Effect); effect {
+	case v1.TaintEffectNoSchedule, v1.TaintEffectPreferNoSchedule, v1.TaintEffectNoExecute:
+		return v1.Taint{Key: t.Key, Value: t.Value, Effect: effect}, nil
+	default:
+		return v1.Taint{}, fmt.Errorf("invalid effect %q of taint %q, expected NoSchedule, PreferNoSchedule or NoExecute", t.Effect, t.Key)
+	}
+}
+
+func validateNodeLabels(labels map[string]string) error {
+	for k, v := range labels {
+		if errs := validation.IsQualifiedName(k); len(errs) > 0 {
+			return fmt.Errorf("invalid node label key %q: %s", k, strings.Join(errs, ", "))
+		}
+		if errs := validation.IsValidLabelValue(v); len(errs) > 0 {
+			return fmt.Errorf("invalid value %q of node label %q: %s", v, k, strings.Join(errs, ", "))
+		}
+	}
+	return nil
+}
+
+// configuredNodeLabels returns the node labels from the provider config, along with the topology labels.
+func (p *ACIProvider) configuredNodeLabels() map[string]string {
+	labels := make(map[string]string, len(p.nodeLabels)+4)
+	for k, v := range p.nodeLabels {
+		labels[k] = v
+	}
+
+	if p.region != "" {
+		labels[regionLabel] = p.region
+		labels[betaRegionLabel] = p.region
+	}
+	if p.zone != "" {
+		labels[zoneLabel] = p.zone
+		labels[betaZoneLabel] = p.zone
+	}
+
+	return labels
+}
+
+// applyNodeConfig adds the labels and taints from the provider config to the node, and reports whether it changed.
+func (p *ACIProvider) applyNodeConfig(node *v1.Node) bool {
+	changed := false
+	if node.ObjectMeta.Labels == nil {
+		node.ObjectMeta.Labels = make(map[string]string)
+	}
+	for k, v := range p.configuredNodeLabels() {
+		if current, ok := node.ObjectMeta.Labels[k]; !ok || current != v {
+			node.ObjectMeta.Labels[k] = v
+			changed = true
+		}
+	}
+
+	for _, taint := range p.nodeTaints {
+		found := false
+		for i := range node.Spec.Taints {
+			if node.Spec.Taints[i].Key != taint.Key || node.Spec.Taints[i].Effect != taint.Effect {
+				continue
+			}
+			found = true
+			if node.Spec.Taints[i].Value != taint.Value {
+				node.Spec.Taints[i].Value = taint.Value
+				changed = true
+			}
+		}
+		if !found {
+			node.Spec.Taints = append(node.Spec.Taints, taint)
+			changed = true
+		}
+	}
+
+	return changed
+}
+
+// reconcileNodeConfigLoop adds back the labels and taints from the provider config to the node, if something
+// stripped them, until the context is done.
+func (p *ACIProvider) reconcileNodeConfigLoop(ctx context.Context) {
+	if p.kubeClient == nil || (len(p.configuredNodeLabels()) == 0 && len(p.nodeTaints) == 0) {
+		return
+	}
+
+	ticker := time.NewTicker(nodeConfigReconcileInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		node, err := p.kubeClient.CoreV1().Nodes().Get(ctx, p.nodeName, metav1.GetOptions{})
+		if err != nil {
+			log.G(ctx).WithError(err).Warn("failed to get the node to reconcile its labels and taints")
+			continue
+		}
+
+		updated := node.DeepCopy()
+		if !p.applyNodeConfig(updated) || reflect.DeepEqual(node, updated) {
+			continue
+		}
+		log.G(ctx).Info("adding back the labels and taints of the node from the provider config")
+		if _, err := p.kubeClient.CoreV1().Nodes().Update(ctx, updated, metav1.UpdateOptions{}); err != nil {
+			log.G(ctx).WithError(err).Warn("failed to reconcile the labels and taints of the node")
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/virtual-kubelet/azure-aci/client/aci"
+)
+
+// setupListByNodeTag enables listing the container groups of the node by their NodeName tag, from the
+// config file or the ACI_LIST_BY_NODE_TAG environment variable.
+func (p *ACIProvider) setupListByNodeTag() error {
+	if v := os.Getenv("ACI_LIST_BY_NODE_TAG"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid ACI_LIST_BY_NODE_TAG %q: %v", v, err)
+		}
+		p.listByNodeTag = b
+	}
+	return nil
+}
+
+// visitNodeContainerGroups calls visit for each container group of the node. By default all the container
+// groups of the resource group are listed and the ones of other nodes skipped. Listing by node tag only
+// lists the container groups of the node, without their properties, and then gets each of them, which sends
+// fewer and smaller responses when the resource group is shared with many other container groups.
+func (p *ACIProvider) visitNodeContainerGroups(ctx context.Context, visit func(*aci.ContainerGroup) error) error {
+	if !p.listByNodeTag {
+		return p.visitResourceGroupNodeContainerGroups(ctx, visit)
+	}
+
+	var names []string
+	err := p.aciClient.VisitContainerGroupsByTag(ctx, p.resourceGroup, "NodeName", p.nodeName, func(cg *aci.ContainerGroup) error {
+		names = append(names, cg.Name)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		cg, status, err := p.aciClient.GetContainerGroup(ctx, p.resourceGroup, name)
+		if err != nil {
+			if status != nil && *status == http.StatusNotFound {
+				// Deleted since it was listed.
+				continue
+			}
+			return err
+		}
+		if cg.Tags["NodeName"] != p.nodeName {
+			continue
+		}
+		if err := visit(cg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// visitNodeContainerGroupTags calls visit for each container group of the node, for the callers which only need
+// their name and tags. Listing by node tag then uses the container groups of the list as they are, without getting
+// each of them.
+func (p *ACIProvider) visitNodeContainerGroupTags(ctx context.Context, visit func(*aci.ContainerGroup) error) error {
+	if !p.listByNodeTag {
+		return p.visitResourceGroupNodeContainerGroups(ctx, visit)
+	}
+
+	return p.aciClient.VisitContainerGroupsByTag(ctx, p.resourceGroup, "NodeName", p.nodeName, func(cg *aci.ContainerGroup) error {
+		if cg.Tags["NodeName"] != p.nodeName {
+			return nil
+		}
+		return visit(cg)
+	})
+}
+
+// visitResourceGroupNodeContainerGroups lists all the container groups of the resource group and calls visit for
+// the ones of the node.
+func (p *ACIProvider) visitResourceGroupNodeContainerGroups(ctx context.Context, visit func(*aci.ContainerGroup) error) error {
+	return p.aciClient.VisitContainerGroups(ctx, p.resourceGroup, func(cg *aci.ContainerGroup) error {
+		if cg.Tags["NodeName"] != p.nodeName {
+			return nil
+		}
+		return visit(cg)
+	})
+}
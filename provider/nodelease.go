@@ -0,0 +1,70 @@
+package provider
+
+import (
+	"context"
+	"time"
+
+	"github.com/virtual-kubelet/virtual-kubelet/log"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// nodeLeaseDurationFactor is the duration of the node lease in renew intervals by default, like the kubelet
+// renewing its 40 seconds lease every 10 seconds.
+const nodeLeaseDurationFactor = 4
+
+// renewNodeLeaseLoop renews the lease of the node in the kube-node-lease namespace every lease renew interval,
+// whatever the readiness of the node, until the context is done.
+func (p *ACIProvider) renewNodeLeaseLoop(ctx context.Context) {
+	ticker := time.NewTicker(p.leaseRenewInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := p.renewNodeLease(ctx, time.Now()); err != nil {
+			log.G(ctx).WithError(err).Warn("failed to renew the node lease")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// renewNodeLease renews the lease of the node at now, creating it owned by the node if missing.
+func (p *ACIProvider) renewNodeLease(ctx context.Context, now time.Time) error {
+	leases := p.kubeClient.CoordinationV1().Leases(v1.NamespaceNodeLease)
+	holder := p.nodeName
+	duration := int32(p.nodeLeaseDuration / time.Second)
+	renewTime := metav1.NewMicroTime(now)
+
+	lease, err := leases.Get(ctx, p.nodeName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		lease = &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{Name: p.nodeName, Namespace: v1.NamespaceNodeLease},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       &holder,
+				LeaseDurationSeconds: &duration,
+				RenewTime:            &renewTime,
+			},
+		}
+		// The lease is garbage collected with the node when it is owned by it.
+		if node, err := p.kubeClient.CoreV1().Nodes().Get(ctx, p.nodeName, metav1.GetOptions{}); err == nil {
+			lease.OwnerReferences = []metav1.OwnerReference{{APIVersion: "v1", Kind: "Node", Name: node.Name, UID: node.UID}}
+		}
+		_, err = leases.Create(ctx, lease, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	lease.Spec.HolderIdentity = &holder
+	lease.Spec.LeaseDurationSeconds = &duration
+	lease.Spec.RenewTime = &renewTime
+	_, err = leases.Update(ctx, lease, metav1.UpdateOptions{})
+	return err
+}
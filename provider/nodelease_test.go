@@ -0,0 +1,49 @@
+package provider
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestRenewNodeLease(t *testing.T) {
+	kubeClient := fake.NewSimpleClientset(&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: fakeNodeName, UID: types.UID("node-uid")}})
+	p := ACIProvider{nodeName: fakeNodeName, kubeClient: kubeClient, nodeLeaseDuration: 40 * time.Second}
+
+	created := time.Now().Add(-time.Minute).Truncate(time.Second)
+	assert.NilError(t, p.renewNodeLease(context.Background(), created))
+	lease, err := kubeClient.CoordinationV1().Leases(v1.NamespaceNodeLease).Get(context.Background(), fakeNodeName, metav1.GetOptions{})
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(fakeNodeName, *lease.Spec.HolderIdentity))
+	assert.Check(t, is.Equal(int32(40), *lease.Spec.LeaseDurationSeconds))
+	assert.Check(t, lease.Spec.RenewTime.Time.Equal(created))
+	assert.Assert(t, is.Len(lease.OwnerReferences, 1))
+	assert.Check(t, is.Equal(types.UID("node-uid"), lease.OwnerReferences[0].UID))
+
+	renewed := created.Add(10 * time.Second)
+	assert.NilError(t, p.renewNodeLease(context.Background(), renewed))
+	lease, err = kubeClient.CoordinationV1().Leases(v1.NamespaceNodeLease).Get(context.Background(), fakeNodeName, metav1.GetOptions{})
+	assert.NilError(t, err)
+	assert.Check(t, lease.Spec.RenewTime.Time.Equal(renewed))
+}
+
+func TestSetupNodeLease(t *testing.T) {
+	p := ACIProvider{leaseRenew: "5s"}
+	assert.NilError(t, p.setupReadiness())
+	assert.Check(t, is.Equal(5*time.Second, p.leaseRenewInterval))
+	assert.Check(t, is.Equal(20*time.Second, p.nodeLeaseDuration), "The lease should last 4 renew intervals by default")
+
+	p = ACIProvider{leaseRenew: "10s", leaseDuration: "10s"}
+	assert.ErrorContains(t, p.setupReadiness(), "longer than the lease renew interval")
+
+	p = ACIProvider{}
+	assert.NilError(t, p.setupReadiness())
+	assert.Check(t, is.Equal(time.Duration(0), p.leaseRenewInterval), "The lease should be left to the node controller by default")
+}
@@ -0,0 +1,57 @@
+package provider
+
+import (
+	"context"
+
+	client "github.com/virtual-kubelet/azure-aci/client"
+	"github.com/virtual-kubelet/azure-aci/client/aci"
+	"github.com/virtual-kubelet/node-cli/manager"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
+)
+
+// Options are the parameters of NewProvider.
+type Options struct {
+	// ConfigPath is the provider config file, optional.
+	ConfigPath string
+	// ResourceManager reads the secrets, config maps and services of the pods.
+	ResourceManager *manager.ResourceManager
+	// NodeName is the name of the virtual node.
+	NodeName string
+	// OperatingSystem is the operating system of the container groups, Linux or Windows.
+	OperatingSystem string
+	// InternalIP is the address of the node, unless the config file sets one.
+	InternalIP string
+	// DaemonPort is the port of the kubelet API of the node.
+	DaemonPort int32
+	// ClusterDomain is the DNS domain of the cluster.
+	ClusterDomain string
+	// ServingCertFile and ServingKeyFile are the serving certificate of the kubelet API and its key. When both are
+	// set the provider serves the kubelet API on DaemonPort, with the authentication of its clients.
+	ServingCertFile string
+	ServingKeyFile  string
+
+	// Authentication is the Azure authentication of the provider. When nil it is read from the file of
+	// AZURE_AUTH_LOCATION or ACS_CREDENTIAL_LOCATION and the AZURE_* environment variables. It is used as is
+	// otherwise, UseUserIdentity included.
+	Authentication *client.Authentication
+	// KubeClient is the client of the API server. When nil one is created from KUBECONFIG or the in-cluster
+	// configuration, if available.
+	KubeClient kubernetes.Interface
+	// EventRecorder records the events of the pods. When nil one recording to KubeClient is created.
+	EventRecorder record.EventRecorder
+}
+
+// ContainerGroupFromPod translates a pod to the container group the provider creates for it, without creating it.
+// The secrets, config maps and volumes of the pod are resolved as on creation, so the container group holds their
+// values.
+func (p *ACIProvider) ContainerGroupFromPod(ctx context.Context, pod *v1.Pod) (*aci.ContainerGroup, error) {
+	return p.getContainerGroupFromPod(ctx, pod)
+}
+
+// PodFromContainerGroup translates a container group created by the provider back to its pod, with the status of
+// the container group.
+func PodFromContainerGroup(cg *aci.ContainerGroup) (*v1.Pod, error) {
+	return containerGroupToPod(cg)
+}
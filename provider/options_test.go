@@ -0,0 +1,57 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	azure "github.com/virtual-kubelet/azure-aci/client"
+	"github.com/virtual-kubelet/azure-aci/client/aci"
+	"github.com/virtual-kubelet/node-cli/manager"
+	"gotest.tools/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestNewProvider(t *testing.T) {
+	aadServerMocker, aciServerMocker, _, err := prepareMocks()
+	assert.NilError(t, err)
+
+	auth := azure.NewAuthentication(azure.PublicCloud.Name, fakeClientID, fakeClientSecret, fakeSubscription, fakeTenantID, fakeUserIdentity)
+	auth.ActiveDirectoryEndpoint = aadServerMocker.GetServerURL()
+	auth.ResourceManagerEndpoint = aciServerMocker.GetServerURL()
+	rm, err := manager.NewResourceManager(nil, nil, nil, nil)
+	assert.NilError(t, err)
+	kubeClient := fake.NewSimpleClientset()
+
+	// The authentication file of AZURE_AUTH_LOCATION is removed once the mocks are prepared, it must not be read.
+	p, err := NewProvider(context.Background(), Options{
+		ResourceManager: rm,
+		NodeName:        fakeNodeName,
+		OperatingSystem: "Linux",
+		InternalIP:      "0.0.0.0",
+		DaemonPort:      10250,
+		ClusterDomain:   "cluster.local",
+		Authentication:  auth,
+		KubeClient:      kubeClient,
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, p.kubeClient, kubeClient)
+	assert.Assert(t, p.eventRecorder != nil)
+
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "ns"},
+		Spec:       v1.PodSpec{Containers: []v1.Container{{Name: "nginx", Image: "nginx"}}},
+	}
+	aciServerMocker.OnCreate = func(subscription, resourceGroup, containerGroup string, cg *aci.ContainerGroup) (int, interface{}) {
+		t.Error("The container group should not be created")
+		return http.StatusOK, cg
+	}
+	cg, err := p.ContainerGroupFromPod(context.Background(), pod)
+	assert.NilError(t, err)
+	assert.Equal(t, cg.Location, fakeRegion)
+	assert.Equal(t, len(cg.Containers), 1)
+	assert.Equal(t, cg.Containers[0].Image, "nginx")
+	assert.Equal(t, cg.Tags["PodName"], "web")
+}
@@ -0,0 +1,186 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/virtual-kubelet/virtual-kubelet/errdefs"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// Resources ACI allocates to a container when the pod spec doesn't specify them.
+var (
+	defaultContainerCPU    = resource.MustParse("1")
+	defaultContainerMemory = resource.MustParse("1.5G")
+)
+
+// setupPodOverhead parses the per container group overhead ACI reserves on top of the containers resources.
+func (p *ACIProvider) setupPodOverhead() error {
+	if cpu := os.Getenv("ACI_POD_OVERHEAD_CPU"); cpu != "" {
+		p.podOverheadCPU = cpu
+	}
+
+	if memory := os.Getenv("ACI_POD_OVERHEAD_MEMORY"); memory != "" {
+		p.podOverheadMemory = memory
+	}
+
+	overhead := v1.ResourceList{}
+	if p.podOverheadCPU != "" {
+		q, err := resource.ParseQuantity(p.podOverheadCPU)
+		if err != nil {
+			return fmt.Errorf("error parsing pod overhead CPU %q: %v", p.podOverheadCPU, err)
+		}
+		overhead[v1.ResourceCPU] = q
+	}
+	if p.podOverheadMemory != "" {
+		q, err := resource.ParseQuantity(p.podOverheadMemory)
+		if err != nil {
+			return fmt.Errorf("error parsing pod overhead memory %q: %v", p.podOverheadMemory, err)
+		}
+		overhead[v1.ResourceMemory] = q
+	}
+
+	if len(overhead) != 0 {
+		p.podOverhead = overhead
+	}
+
+	return nil
+}
+
+// getPodOverhead returns the overhead accounted for the container group of a pod.
+// The overhead set through the pod RuntimeClass takes precedence over the provider default.
+func (p *ACIProvider) getPodOverhead(pod *v1.Pod) v1.ResourceList {
+	if len(pod.Spec.Overhead) != 0 {
+		return pod.Spec.Overhead
+	}
+
+	return p.podOverhead
+}
+
+// getPodResources returns the CPU and memory a pod consumes from the node capacity, including its overhead.
+func (p *ACIProvider) getPodResources(pod *v1.Pod) v1.ResourceList {
+	cpu := resource.Quantity{}
+	memory := resource.Quantity{}
+
+	for _, c := range pod.Spec.Containers {
+		cpu.Add(getContainerResource(c, v1.ResourceCPU, defaultContainerCPU))
+		memory.Add(getContainerResource(c, v1.ResourceMemory, defaultContainerMemory))
+	}
+
+	overhead := p.getPodOverhead(pod)
+	if q, ok := overhead[v1.ResourceCPU]; ok {
+		cpu.Add(q)
+	}
+	if q, ok := overhead[v1.ResourceMemory]; ok {
+		memory.Add(q)
+	}
+
+	return v1.ResourceList{
+		v1.ResourceCPU:    cpu,
+		v1.ResourceMemory: memory,
+	}
+}
+
+// allocatable returns the resources of the node pods can be scheduled with: its capacity, less the provider
+// overhead of the pods of the node. The scheduler only accounts the overhead set through a RuntimeClass, so
+// without this it would keep scheduling pods which don't fit once the provider overhead is added.
+func (p *ACIProvider) allocatable() v1.ResourceList {
+	allocatable := p.capacity()
+	if len(p.podOverhead) == 0 || p.resourceManager == nil {
+		return allocatable
+	}
+
+	for _, pod := range p.resourceManager.GetPods() {
+		if pod.Spec.NodeName != p.nodeName ||
+			len(pod.Spec.Overhead) != 0 ||
+			pod.Status.Phase == v1.PodSucceeded ||
+			pod.Status.Phase == v1.PodFailed {
+			continue
+		}
+		for _, name := range []v1.ResourceName{v1.ResourceCPU, v1.ResourceMemory} {
+			q, ok := p.podOverhead[name]
+			if !ok {
+				continue
+			}
+			left := allocatable[name]
+			left.Sub(q)
+			if left.Sign() < 0 {
+				left = resource.Quantity{}
+			}
+			allocatable[name] = left
+		}
+	}
+
+	return allocatable
+}
+
+// getContainerResource returns the request of a container, defaulting to its limit like Kubernetes does.
+func getContainerResource(c v1.Container, name v1.ResourceName, defaultValue resource.Quantity) resource.Quantity {
+	if q, ok := c.Resources.Requests[name]; ok {
+		return q
+	}
+	if q, ok := c.Resources.Limits[name]; ok {
+		return q
+	}
+
+	return defaultValue
+}
+
+// checkPodFitsCapacity validates the pod and its overhead fit in the remaining node capacity.
+// The scheduler is not aware of the overhead unless it is set through a RuntimeClass,
+// so the check only applies when an overhead is accounted. With Spot preemption, the Spot pods of
+// a lower priority are preempted to make room for the pod.
+func (p *ACIProvider) checkPodFitsCapacity(ctx context.Context, pod *v1.Pod) error {
+	if len(p.getPodOverhead(pod)) == 0 {
+		return nil
+	}
+
+	pods := p.resourceManager.GetPods()
+	err := p.podFitsCapacity(pod, pods, nil)
+	if err == nil {
+		return nil
+	}
+	fits, preemptErr := p.preemptSpotPods(ctx, pod, pods, func(evicted map[string]bool) bool {
+		return p.podFitsCapacity(pod, pods, evicted) == nil
+	})
+	if preemptErr != nil {
+		return preemptErr
+	}
+	if fits {
+		return nil
+	}
+	return err
+}
+
+// podFitsCapacity validates the pod fits in the capacity of the node left by pods, except the ones excluded.
+func (p *ACIProvider) podFitsCapacity(pod *v1.Pod, pods []*v1.Pod, excluded map[string]bool) error {
+	used := p.getPodResources(pod)
+	for _, other := range pods {
+		if other.Spec.NodeName != p.nodeName ||
+			(other.Namespace == pod.Namespace && other.Name == pod.Name) ||
+			other.Status.Phase == v1.PodSucceeded ||
+			other.Status.Phase == v1.PodFailed ||
+			excluded[podKey(other)] {
+			continue
+		}
+
+		for name, q := range p.getPodResources(other) {
+			total := used[name]
+			total.Add(q)
+			used[name] = total
+		}
+	}
+
+	capacity := p.capacity()
+	for _, name := range []v1.ResourceName{v1.ResourceCPU, v1.ResourceMemory} {
+		q := used[name]
+		c := capacity[name]
+		if q.Cmp(c) > 0 {
+			return errdefs.InvalidInputf("pod %s would bring the %s usage of node %s to %s including the ACI overhead, which exceeds the capacity %s", pod.Name, name, p.nodeName, q.String(), c.String())
+		}
+	}
+
+	return nil
+}
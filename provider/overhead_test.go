@@ -0,0 +1,53 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/virtual-kubelet/node-cli/manager"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestAllocatable(t *testing.T) {
+	pod := func(name, node string, phase v1.PodPhase, overhead v1.ResourceList) *v1.Pod {
+		return &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns"},
+			Spec:       v1.PodSpec{NodeName: node, Overhead: overhead},
+			Status:     v1.PodStatus{Phase: phase},
+		}
+	}
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for _, p := range []*v1.Pod{
+		pod("running", fakeNodeName, v1.PodRunning, nil),
+		pod("pending", fakeNodeName, v1.PodPending, nil),
+		pod("succeeded", fakeNodeName, v1.PodSucceeded, nil),
+		pod("elsewhere", "other", v1.PodRunning, nil),
+		// The scheduler already accounts the overhead of the RuntimeClass.
+		pod("runtimeclass", fakeNodeName, v1.PodRunning, v1.ResourceList{v1.ResourceCPU: resource.MustParse("1")}),
+	} {
+		assert.NilError(t, indexer.Add(p))
+	}
+	rm, err := manager.NewResourceManager(corev1listers.NewPodLister(indexer), nil, nil, nil)
+	assert.NilError(t, err)
+
+	p := ACIProvider{nodeName: fakeNodeName, cpu: "4", memory: "4Gi", pods: "10", resourceManager: rm}
+	allocatable := p.allocatable()
+	assert.Check(t, is.Equal("4", allocatable.Cpu().String()), "without overhead the capacity is allocatable")
+
+	p.podOverhead = v1.ResourceList{
+		v1.ResourceCPU:    resource.MustParse("250m"),
+		v1.ResourceMemory: resource.MustParse("3Gi"),
+	}
+	allocatable = p.allocatable()
+	assert.Check(t, is.Equal("3500m", allocatable.Cpu().String()))
+	assert.Check(t, is.Equal("0", allocatable.Memory().String()), "the allocatable memory can't be negative")
+	assert.Check(t, is.Equal("10", allocatable.Pods().String()))
+
+	capacity := p.capacity()
+	assert.Check(t, is.Equal("4", capacity.Cpu().String()), "the capacity doesn't change")
+}
@@ -0,0 +1,140 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	client "github.com/virtual-kubelet/azure-aci/client"
+	"github.com/virtual-kubelet/azure-aci/client/authorization"
+	"github.com/virtual-kubelet/virtual-kubelet/log"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// permissionCheckReport reports the missing permissions with a condition of the node.
+	permissionCheckReport = "Report"
+	// permissionCheckEnforce fails the start of the virtual kubelet on missing permissions.
+	permissionCheckEnforce = "Enforce"
+
+	// permissionsMissingCondition is true on the node while the identity of the virtual kubelet misses permissions.
+	permissionsMissingCondition v1.NodeConditionType = "ARMPermissionsMissing"
+)
+
+// containerGroupActions are the actions on the resource group needed to run the pods.
+var containerGroupActions = []string{
+	"Microsoft.ContainerInstance/containerGroups/read",
+	"Microsoft.ContainerInstance/containerGroups/write",
+	"Microsoft.ContainerInstance/containerGroups/delete",
+	"Microsoft.Insights/metrics/read",
+}
+
+// subnetJoinAction is the action on the subnet needed to run the pods in a virtual network.
+const subnetJoinAction = "Microsoft.Network/virtualNetworks/subnets/join/action"
+
+// permissionSource reports the permissions of the identity of the virtual kubelet.
+type permissionSource interface {
+	ListResourceGroupPermissions(ctx context.Context, resourceGroup string) ([]authorization.Permission, error)
+	ListSubnetPermissions(ctx context.Context, resourceGroup, virtualNetworkName, subnetName string) ([]authorization.Permission, error)
+	ListResourceGroupDenyAssignments(ctx context.Context, resourceGroup string) ([]authorization.DenyAssignment, error)
+}
+
+// setupPermissionCheck checks the permissions of the identity of the virtual kubelet at startup, from
+// PermissionCheck in the config file or the ACI_PERMISSION_CHECK environment variable: Report reports the
+// missing permissions with a condition of the node, Enforce fails the start.
+func (p *ACIProvider) setupPermissionCheck(ctx context.Context, azAuth *client.Authentication) error {
+	if v := os.Getenv("ACI_PERMISSION_CHECK"); v != "" {
+		p.permissionCheck = v
+	}
+	switch p.permissionCheck {
+	case "":
+		return nil
+	case permissionCheckReport, permissionCheckEnforce:
+	default:
+		return fmt.Errorf("invalid ACI_PERMISSION_CHECK %q, expected %s or %s", p.permissionCheck, permissionCheckReport, permissionCheckEnforce)
+	}
+
+	permissions, err := authorization.NewClient(azAuth, p.extraUserAgent)
+	if err != nil {
+		return fmt.Errorf("error setting up the permission check: %v", err)
+	}
+	// The subnet is joined with the identity of the resource group of the virtual network.
+	subnetPermissions := permissions
+	if vnetAuth := p.authFor(p.vnetResourceGroup, azAuth); vnetAuth != azAuth {
+		if subnetPermissions, err = authorization.NewClient(vnetAuth, p.extraUserAgent); err != nil {
+			return fmt.Errorf("error setting up the permission check: %v", err)
+		}
+	}
+	return p.checkPermissions(ctx, permissions, subnetPermissions)
+}
+
+// checkPermissions checks the identity of the virtual kubelet is allowed the actions needed to run the pods, and is
+// not denied them by a deny assignment, instead of failing every pod with 403 responses later. A failure to list the
+// permissions is only logged.
+func (p *ACIProvider) checkPermissions(ctx context.Context, permissions, subnetPermissions permissionSource) error {
+	p.missingPermissions = nil
+
+	allowed, err := permissions.ListResourceGroupPermissions(ctx, p.resourceGroup)
+	if err != nil {
+		log.G(ctx).WithError(err).Warn("failed to list the permissions on the resource group")
+		return nil
+	}
+	denials, err := permissions.ListResourceGroupDenyAssignments(ctx, p.resourceGroup)
+	if err != nil {
+		log.G(ctx).WithError(err).Warn("failed to list the deny assignments of the resource group")
+	}
+	for _, action := range containerGroupActions {
+		scope := "resource group " + p.resourceGroup
+		if !authorization.Allowed(allowed, action) {
+			p.missingPermissions = append(p.missingPermissions, action+" on "+scope)
+			continue
+		}
+		for _, d := range denials {
+			if d.Denies(action) {
+				p.missingPermissions = append(p.missingPermissions, fmt.Sprintf("%s on %s, denied by %s", action, scope, d.Properties.DenyAssignmentName))
+				break
+			}
+		}
+	}
+
+	if p.subnetName != "" {
+		allowed, err := subnetPermissions.ListSubnetPermissions(ctx, p.vnetResourceGroup, p.vnetName, p.subnetName)
+		if err != nil {
+			log.G(ctx).WithError(err).Warn("failed to list the permissions on the subnet")
+		} else if !authorization.Allowed(allowed, subnetJoinAction) {
+			p.missingPermissions = append(p.missingPermissions, fmt.Sprintf("%s on subnet %s of virtual network %s", subnetJoinAction, p.subnetName, p.vnetName))
+		}
+	}
+
+	if len(p.missingPermissions) == 0 {
+		return nil
+	}
+	missing := strings.Join(p.missingPermissions, "; ")
+	if p.permissionCheck == permissionCheckEnforce {
+		return fmt.Errorf("the identity of the virtual kubelet misses permissions: %s", missing)
+	}
+	log.G(ctx).Warnf("the identity of the virtual kubelet misses permissions: %s", missing)
+	p.recordNodeEvent(v1.EventTypeWarning, "PermissionsMissing", "The identity of the virtual kubelet misses permissions: %s", missing)
+	return nil
+}
+
+// permissionsCondition returns the condition of the node reporting the permissions the identity of the virtual
+// kubelet misses.
+func (p *ACIProvider) permissionsCondition() v1.NodeCondition {
+	condition := v1.NodeCondition{
+		Type:               permissionsMissingCondition,
+		Status:             v1.ConditionFalse,
+		LastHeartbeatTime:  metav1.Now(),
+		LastTransitionTime: metav1.Now(),
+		Reason:             "PermissionsGranted",
+		Message:            "the identity of the virtual kubelet has the permissions to run the pods",
+	}
+	if len(p.missingPermissions) > 0 {
+		condition.Status = v1.ConditionTrue
+		condition.Reason = "PermissionsMissing"
+		condition.Message = "the identity of the virtual kubelet misses permissions: " + strings.Join(p.missingPermissions, "; ")
+	}
+	return condition
+}
@@ -0,0 +1,63 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/virtual-kubelet/azure-aci/client/authorization"
+	"gotest.tools/assert"
+	v1 "k8s.io/api/core/v1"
+)
+
+type fakePermissions struct {
+	resourceGroup []authorization.Permission
+	subnet        []authorization.Permission
+	denials       []authorization.DenyAssignment
+}
+
+func (f *fakePermissions) ListResourceGroupPermissions(ctx context.Context, resourceGroup string) ([]authorization.Permission, error) {
+	return f.resourceGroup, nil
+}
+
+func (f *fakePermissions) ListSubnetPermissions(ctx context.Context, resourceGroup, virtualNetworkName, subnetName string) ([]authorization.Permission, error) {
+	return f.subnet, nil
+}
+
+func (f *fakePermissions) ListResourceGroupDenyAssignments(ctx context.Context, resourceGroup string) ([]authorization.DenyAssignment, error) {
+	return f.denials, nil
+}
+
+func TestCheckPermissions(t *testing.T) {
+	permissions := &fakePermissions{
+		resourceGroup: []authorization.Permission{{Actions: []string{"Microsoft.ContainerInstance/*", "*/read"}}},
+		subnet:        []authorization.Permission{{Actions: []string{"*/read"}}},
+		denials: []authorization.DenyAssignment{{Properties: authorization.DenyAssignmentProperties{
+			DenyAssignmentName: "managed-app",
+			Permissions:        []authorization.Permission{{Actions: []string{"*/delete"}}},
+			Principals:         []authorization.Principal{{ID: "00000000-0000-0000-0000-000000000000", Type: "SystemDefined"}},
+		}}},
+	}
+	p := ACIProvider{
+		resourceGroup:     "rg",
+		vnetResourceGroup: "vnet-rg",
+		vnetName:          "vnet",
+		subnetName:        "aci",
+		permissionCheck:   permissionCheckReport,
+	}
+	ctx := context.Background()
+
+	assert.NilError(t, p.checkPermissions(ctx, permissions, permissions))
+	assert.DeepEqual(t, p.missingPermissions, []string{
+		"Microsoft.ContainerInstance/containerGroups/delete on resource group rg, denied by managed-app",
+		"Microsoft.Network/virtualNetworks/subnets/join/action on subnet aci of virtual network vnet",
+	})
+	assert.Equal(t, p.permissionsCondition().Status, v1.ConditionTrue)
+
+	p.permissionCheck = permissionCheckEnforce
+	assert.ErrorContains(t, p.checkPermissions(ctx, permissions, permissions), "misses permissions")
+
+	permissions.denials = nil
+	permissions.subnet = []authorization.Permission{{Actions: []string{"Microsoft.Network/virtualNetworks/subnets/join/action"}}}
+	assert.NilError(t, p.checkPermissions(ctx, permissions, permissions))
+	assert.Equal(t, p.permissionsCondition().Status, v1.ConditionFalse)
+}
@@ -0,0 +1,262 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/virtual-kubelet/azure-aci/client/aci"
+	"github.com/virtual-kubelet/virtual-kubelet/errdefs"
+	v1 "k8s.io/api/core/v1"
+	k8serr "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const (
+	podConfigGroup    = "aci.virtual-kubelet.io"
+	podConfigVersion  = "v1alpha1"
+	podConfigResource = "acipodconfigs"
+
+	// podConfigAnnotation is the name of the ACIPodConfig of the namespace of a pod which applies to it, instead of
+	// the one selecting the pod by its labels.
+	podConfigAnnotation = "virtual-kubelet.io/pod-config"
+)
+
+// aciPodConfig is an ACIPodConfig, the ACI settings of the pods of a namespace referencing it or selected by it.
+type aciPodConfig struct {
+	Metadata struct {
+		Name string `json:"name"`
+	} `json:"metadata"`
+	Spec aciPodConfigSpec `json:"spec"`
+}
+
+// aciPodConfigSpec is the spec of an ACIPodConfig. The annotations of a pod take precedence over the settings they
+// are equivalent to.
+type aciPodConfigSpec struct {
+	// PodSelector selects the pods of the namespace the config applies to.
+	PodSelector *metav1.LabelSelector `json:"podSelector,omitempty"`
+	// SKU is the container group SKU, one of Standard, Dedicated or Confidential, unless the RuntimeClass profile
+	// of the pod has one.
+	SKU string `json:"sku,omitempty"`
+	// GPUType is the equivalent of the virtual-kubelet.io/gpu-type annotation.
+	GPUType string `json:"gpuType,omitempty"`
+	// PublicIP is the equivalent of the virtual-kubelet.io/public-ip annotation.
+	PublicIP *bool `json:"publicIP,omitempty"`
+	// DNSNameLabelScope is the equivalent of the virtual-kubelet.io/dns-name-label-scope annotation.
+	DNSNameLabelScope string `json:"dnsNameLabelScope,omitempty"`
+	// ContainerGroupProfile is the equivalent of the virtual-kubelet.io/container-group-profile annotation.
+	ContainerGroupProfile string `json:"containerGroupProfile,omitempty"`
+	// StandbyPool is the equivalent of the virtual-kubelet.io/standby-pool annotation.
+	StandbyPool string `json:"standbyPool,omitempty"`
+	// CcePolicy is the equivalent of the virtual-kubelet.io/cce-policy annotation.
+	CcePolicy string `json:"ccePolicy,omitempty"`
+	// Extensions is the equivalent of the virtual-kubelet.io/extensions annotation.
+	Extensions []*aci.Extension `json:"extensions,omitempty"`
+}
+
+// annotations returns the pod annotations the settings of the spec are equivalent to.
+func (s aciPodConfigSpec) annotations() (map[string]string, error) {
+	annotations := map[string]string{}
+	set := func(key, value string) {
+		if value != "" {
+			annotations[key] = value
+		}
+	}
+	set(gpuTypeAnnotation, s.GPUType)
+	if s.PublicIP != nil {
+		set(publicIPAnnotation, strconv.FormatBool(*s.PublicIP))
+	}
+	set(dnsNameLabelScopeAnnotation, s.DNSNameLabelScope)
+	set(containerGroupProfileAnnotation, s.ContainerGroupProfile)
+	set(standbyPoolAnnotation, s.StandbyPool)
+	set(ccePolicyAnnotation, s.CcePolicy)
+	if len(s.Extensions) > 0 {
+		b, err := json.Marshal(s.Extensions)
+		if err != nil {
+			return nil, err
+		}
+		set(extensionsAnnotation, string(b))
+	}
+	return annotations, nil
+}
+
+// over returns the spec with the settings it does not set taken from defaults.
+func (s aciPodConfigSpec) over(defaults aciPodConfigSpec) aciPodConfigSpec {
+	merged := s
+	setDefault := func(value *string, defaultValue string) {
+		if *value == "" {
+			*value = defaultValue
+		}
+	}
+	setDefault(&merged.SKU, defaults.SKU)
+	setDefault(&merged.GPUType, defaults.GPUType)
+	setDefault(&merged.DNSNameLabelScope, defaults.DNSNameLabelScope)
+	setDefault(&merged.ContainerGroupProfile, defaults.ContainerGroupProfile)
+	setDefault(&merged.StandbyPool, defaults.StandbyPool)
+	setDefault(&merged.CcePolicy, defaults.CcePolicy)
+	if merged.PublicIP == nil {
+		merged.PublicIP = defaults.PublicIP
+	}
+	if len(merged.Extensions) == 0 {
+		merged.Extensions = defaults.Extensions
+	}
+	return merged
+}
+
+func (s aciPodConfigSpec) validate() error {
+	if s.SKU != "" && (runtimeClassProfile{SKU: s.SKU}).containerGroupSku() == "" {
+		return fmt.Errorf("%q is not a valid container group SKU", s.SKU)
+	}
+	if s.PodSelector != nil {
+		if _, err := metav1.LabelSelectorAsSelector(s.PodSelector); err != nil {
+			return fmt.Errorf("invalid podSelector: %v", err)
+		}
+	}
+	return nil
+}
+
+// podConfigSource reads the ACIPodConfigs and the ACINamespaceDefaults of a namespace.
+type podConfigSource interface {
+	GetPodConfig(ctx context.Context, namespace, name string) (*aciPodConfig, error)
+	ListPodConfigs(ctx context.Context, namespace string) ([]aciPodConfig, error)
+	// GetNamespaceDefaults returns nil when the namespace has no defaults.
+	GetNamespaceDefaults(ctx context.Context, namespace string) (*aciNamespaceDefaults, error)
+}
+
+// setupPodConfigs enables the ACIPodConfigs and the ACINamespaceDefaults, from PodConfigs in the config file or the ACI_POD_CONFIGS environment
+// variable. They are watched from the API server, and read from the informers when the container groups are created.
+func (p *ACIProvider) setupPodConfigs(ctx context.Context) error {
+	if v := os.Getenv("ACI_POD_CONFIGS"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid ACI_POD_CONFIGS %q: %v", v, err)
+		}
+		p.podConfigsEnabled = b
+	}
+	if !p.podConfigsEnabled {
+		return nil
+	}
+	if p.kubeClient == nil {
+		return fmt.Errorf("the ACIPodConfigs require a kubernetes client to read them")
+	}
+	configs := newInformerPodConfigs(p.kubeClient.Discovery().RESTClient())
+	configs.run(ctx)
+	p.podConfigs = configs
+	return nil
+}
+
+// getPodConfig returns the ACIPodConfig applying to a pod: the one of its annotation, or else the only one selecting
+// it. A pod referencing a missing config, or selected by several ones, is rejected.
+func (p *ACIProvider) getPodConfig(ctx context.Context, pod *v1.Pod) (*aciPodConfig, error) {
+	if p.podConfigs == nil {
+		return nil, nil
+	}
+
+	if name := pod.Annotations[podConfigAnnotation]; name != "" {
+		config, err := p.podConfigs.GetPodConfig(ctx, pod.Namespace, name)
+		if k8serr.IsNotFound(err) {
+			return nil, errdefs.InvalidInputf("pod %s: ACIPodConfig %s of the %s annotation does not exist", pod.Name, name, podConfigAnnotation)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return config, nil
+	}
+
+	configs, err := p.podConfigs.ListPodConfigs(ctx, pod.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	var selected []aciPodConfig
+	for _, config := range configs {
+		if config.Spec.PodSelector == nil {
+			continue
+		}
+		selector, err := metav1.LabelSelectorAsSelector(config.Spec.PodSelector)
+		if err != nil {
+			return nil, errdefs.InvalidInputf("ACIPodConfig %s: invalid podSelector: %v", config.Metadata.Name, err)
+		}
+		if selector.Matches(labels.Set(pod.Labels)) {
+			selected = append(selected, config)
+		}
+	}
+	switch len(selected) {
+	case 0:
+		return nil, nil
+	case 1:
+		return &selected[0], nil
+	default:
+		names := make([]string, 0, len(selected))
+		for _, config := range selected {
+			names = append(names, config.Metadata.Name)
+		}
+		sort.Strings(names)
+		return nil, errdefs.InvalidInputf("pod %s is selected by several ACIPodConfigs: %s, set the %s annotation to choose one", pod.Name, strings.Join(names, ", "), podConfigAnnotation)
+	}
+}
+
+// podSettings are the settings of the ACIPodConfig and the ACINamespaceDefaults of a pod which have no equivalent
+// annotation.
+type podSettings struct {
+	SKU         string
+	Tags        map[string]string
+	Diagnostics *aci.ContainerGroupDiagnostics
+}
+
+// applyPodConfig returns a copy of the pod with the annotations of its ACIPodConfig and of the ACINamespaceDefaults
+// of its namespace, and their other settings. The pod is returned unchanged without a config nor defaults.
+func (p *ACIProvider) applyPodConfig(ctx context.Context, pod *v1.Pod) (*v1.Pod, *podSettings, error) {
+	if p.podConfigs == nil {
+		return pod, nil, nil
+	}
+	defaults, err := p.getNamespaceDefaults(ctx, pod)
+	if err != nil {
+		return nil, nil, err
+	}
+	config, err := p.getPodConfig(ctx, pod)
+	if err != nil {
+		return nil, nil, err
+	}
+	if defaults == nil && config == nil {
+		return pod, nil, nil
+	}
+
+	var spec aciPodConfigSpec
+	settings := &podSettings{}
+	if defaults != nil {
+		spec = defaults.aciPodConfigSpec
+		settings.Tags = defaults.Tags
+		if defaults.LogAnalytics != nil {
+			if settings.Diagnostics, err = p.namespaceDefaultsDiagnostics(pod, defaults.LogAnalytics); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	if config != nil {
+		if err := config.Spec.validate(); err != nil {
+			return nil, nil, errdefs.InvalidInputf("pod %s: invalid ACIPodConfig %s: %v", pod.Name, config.Metadata.Name, err)
+		}
+		spec = config.Spec.over(spec)
+	}
+	settings.SKU = spec.SKU
+
+	annotations, err := spec.annotations()
+	if err != nil {
+		return nil, nil, err
+	}
+	pod = pod.DeepCopy()
+	if pod.Annotations == nil {
+		pod.Annotations = make(map[string]string, len(annotations))
+	}
+	for key, value := range annotations {
+		if _, ok := pod.Annotations[key]; !ok {
+			pod.Annotations[key] = value
+		}
+	}
+	return pod, settings, nil
+}
@@ -0,0 +1,122 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/virtual-kubelet/azure-aci/client/aci"
+	"github.com/virtual-kubelet/virtual-kubelet/errdefs"
+	"gotest.tools/assert"
+	v1 "k8s.io/api/core/v1"
+	k8serr "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+type fakePodConfigs struct {
+	configs  map[string]aciPodConfig
+	defaults *aciNamespaceDefaults
+}
+
+func (f fakePodConfigs) GetPodConfig(ctx context.Context, namespace, name string) (*aciPodConfig, error) {
+	config, ok := f.configs[name]
+	if !ok {
+		return nil, k8serr.NewNotFound(schema.GroupResource{Group: podConfigGroup, Resource: podConfigResource}, name)
+	}
+	return &config, nil
+}
+
+func (f fakePodConfigs) ListPodConfigs(ctx context.Context, namespace string) ([]aciPodConfig, error) {
+	configs := make([]aciPodConfig, 0, len(f.configs))
+	for _, config := range f.configs {
+		configs = append(configs, config)
+	}
+	return configs, nil
+}
+
+func (f fakePodConfigs) GetNamespaceDefaults(ctx context.Context, namespace string) (*aciNamespaceDefaults, error) {
+	return f.defaults, nil
+}
+
+func testPodConfig(name string, spec aciPodConfigSpec) aciPodConfig {
+	config := aciPodConfig{Spec: spec}
+	config.Metadata.Name = name
+	return config
+}
+
+func TestApplyPodConfig(t *testing.T) {
+	public := true
+	configs := map[string]aciPodConfig{
+		"gpu": testPodConfig("gpu", aciPodConfigSpec{
+			PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "train"}},
+			SKU:         "dedicated",
+			GPUType:     "V100",
+			PublicIP:    &public,
+		}),
+		"confidential": testPodConfig("confidential", aciPodConfigSpec{SKU: "Confidential"}),
+	}
+	p := ACIProvider{podConfigs: fakePodConfigs{configs: configs}}
+	ctx := context.Background()
+
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web", Labels: map[string]string{"app": "web"}}}
+	applied, settings, err := p.applyPodConfig(ctx, pod)
+	assert.NilError(t, err)
+	assert.Assert(t, settings == nil)
+	assert.Equal(t, applied, pod)
+
+	pod.Labels["app"] = "train"
+	pod.Annotations = map[string]string{publicIPAnnotation: "false"}
+	applied, settings, err = p.applyPodConfig(ctx, pod)
+	assert.NilError(t, err)
+	assert.Equal(t, settings.SKU, "dedicated")
+	assert.Equal(t, applied.Annotations[gpuTypeAnnotation], "V100")
+	assert.Equal(t, applied.Annotations[publicIPAnnotation], "false", "The annotations of the pod take precedence")
+	assert.Equal(t, len(pod.Annotations), 1, "The pod should not be changed")
+
+	pod.Annotations[podConfigAnnotation] = "confidential"
+	_, settings, err = p.applyPodConfig(ctx, pod)
+	assert.NilError(t, err)
+	assert.Equal(t, settings.SKU, "Confidential")
+
+	pod.Annotations[podConfigAnnotation] = "missing"
+	_, _, err = p.applyPodConfig(ctx, pod)
+	assert.Assert(t, errdefs.IsInvalidInput(err))
+
+	delete(pod.Annotations, podConfigAnnotation)
+	configs["train"] = testPodConfig("train", aciPodConfigSpec{PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "train"}}})
+	_, _, err = p.applyPodConfig(ctx, pod)
+	assert.ErrorContains(t, err, "several ACIPodConfigs: gpu, train")
+
+	configs["train"] = testPodConfig("train", aciPodConfigSpec{SKU: "Premium"})
+	pod.Annotations[podConfigAnnotation] = "train"
+	_, _, err = p.applyPodConfig(ctx, pod)
+	assert.Assert(t, errdefs.IsInvalidInput(err))
+}
+
+func TestNamespaceDefaults(t *testing.T) {
+	public := false
+	defaults := &aciNamespaceDefaults{Spec: aciNamespaceDefaultsSpec{
+		aciPodConfigSpec: aciPodConfigSpec{SKU: "Dedicated", GPUType: "K80", PublicIP: &public},
+		Tags:             map[string]string{"cost-center": "1234", "PodName": "overridden"},
+	}}
+	configs := map[string]aciPodConfig{
+		"gpu": testPodConfig("gpu", aciPodConfigSpec{GPUType: "V100"}),
+	}
+	p := ACIProvider{podConfigs: fakePodConfigs{configs: configs, defaults: defaults}}
+	ctx := context.Background()
+
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web", Annotations: map[string]string{podConfigAnnotation: "gpu"}}}
+	applied, settings, err := p.applyPodConfig(ctx, pod)
+	assert.NilError(t, err)
+	assert.Equal(t, settings.SKU, "Dedicated")
+	assert.Equal(t, applied.Annotations[gpuTypeAnnotation], "V100", "The pod config takes precedence over the defaults")
+	assert.Equal(t, applied.Annotations[publicIPAnnotation], "false")
+
+	cg := &aci.ContainerGroup{Tags: map[string]string{"PodName": "web"}}
+	applyTags(cg, settings.Tags)
+	assert.DeepEqual(t, cg.Tags, map[string]string{"PodName": "web", "cost-center": "1234"})
+
+	defaults.Spec.LogAnalytics = &namespaceLogAnalytics{WorkspaceID: "workspace"}
+	_, _, err = p.applyPodConfig(ctx, pod)
+	assert.Assert(t, errdefs.IsInvalidInput(err))
+}
@@ -0,0 +1,195 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	k8serr "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+)
+
+// podConfigsCRDRecheck is how often the resources of a missing CRD are listed again, so the CRD can be installed
+// after the virtual kubelet started.
+const podConfigsCRDRecheck = time.Minute
+
+// informerPodConfigs reads the ACIPodConfigs and the ACINamespaceDefaults from informers watching them in all the
+// namespaces, instead of requests to the API server for every container group created. The resources of a missing
+// CRD are no resources.
+type informerPodConfigs struct {
+	podConfigs        cache.SharedIndexInformer
+	namespaceDefaults cache.SharedIndexInformer
+}
+
+// newInformerPodConfigs creates the informers of the ACIPodConfigs and the ACINamespaceDefaults with the REST client
+// of the API server. They are started with run.
+func newInformerPodConfigs(client rest.Interface) *informerPodConfigs {
+	informer := func(resource string) cache.SharedIndexInformer {
+		return cache.NewSharedIndexInformer(customResourceListWatch(client, resource), &unstructured.Unstructured{}, 0,
+			cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	}
+	return &informerPodConfigs{
+		podConfigs:        informer(podConfigResource),
+		namespaceDefaults: informer(namespaceDefaultsResource),
+	}
+}
+
+// customResourceListWatch lists and watches a resource of the ACIPodConfig group in all the namespaces, as
+// unstructured objects. A missing CRD is listed as empty, and watched until it is listed again.
+func customResourceListWatch(client rest.Interface, resource string) *cache.ListWatch {
+	path := []string{"/apis", podConfigGroup, podConfigVersion, resource}
+	return &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			req := client.Get().AbsPath(path...)
+			if options.ResourceVersion != "" {
+				req = req.Param("resourceVersion", options.ResourceVersion)
+			}
+			raw, err := req.DoRaw(context.Background())
+			if k8serr.IsNotFound(err) {
+				return &unstructured.UnstructuredList{}, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			list := &unstructured.UnstructuredList{}
+			if err := list.UnmarshalJSON(raw); err != nil {
+				return nil, fmt.Errorf("invalid %s list: %v", resource, err)
+			}
+			return list, nil
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			req := client.Get().AbsPath(path...).Param("watch", "true")
+			if options.ResourceVersion != "" {
+				req = req.Param("resourceVersion", options.ResourceVersion)
+			}
+			if options.TimeoutSeconds != nil {
+				req = req.Param("timeoutSeconds", strconv.FormatInt(*options.TimeoutSeconds, 10))
+			}
+			stream, err := req.Stream(context.Background())
+			if k8serr.IsNotFound(err) {
+				w := watch.NewFake()
+				time.AfterFunc(podConfigsCRDRecheck, w.Stop)
+				return w, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			return watch.NewStreamWatcher(&unstructuredWatchDecoder{body: stream, decoder: json.NewDecoder(stream)},
+				k8serr.NewClientErrorReporter(http.StatusInternalServerError, "GET", "ClientWatchDecoding")), nil
+		},
+	}
+}
+
+// unstructuredWatchDecoder decodes the events of a watch of custom resources, as unstructured objects.
+type unstructuredWatchDecoder struct {
+	body    io.ReadCloser
+	decoder *json.Decoder
+}
+
+func (d *unstructuredWatchDecoder) Decode() (watch.EventType, runtime.Object, error) {
+	var event struct {
+		Type   watch.EventType `json:"type"`
+		Object json.RawMessage `json:"object"`
+	}
+	if err := d.decoder.Decode(&event); err != nil {
+		return "", nil, err
+	}
+	obj := &unstructured.Unstructured{}
+	if err := obj.UnmarshalJSON(event.Object); err != nil {
+		return "", nil, fmt.Errorf("invalid %s watch event: %v", event.Type, err)
+	}
+	return event.Type, obj, nil
+}
+
+func (d *unstructuredWatchDecoder) Close() {
+	d.body.Close()
+}
+
+// run runs the informers until the context is done.
+func (i *informerPodConfigs) run(ctx context.Context) {
+	goSubsystem("pod_configs", func() { i.podConfigs.Run(ctx.Done()) })
+	goSubsystem("namespace_defaults", func() { i.namespaceDefaults.Run(ctx.Done()) })
+}
+
+// waitForSync waits for the informers to list the resources, or the context to be done.
+func (i *informerPodConfigs) waitForSync(ctx context.Context) error {
+	if !cache.WaitForCacheSync(ctx.Done(), i.podConfigs.HasSynced, i.namespaceDefaults.HasSynced) {
+		return fmt.Errorf("the ACIPodConfigs and ACINamespaceDefaults are not synced yet")
+	}
+	return nil
+}
+
+// fromUnstructured decodes an object of an informer into out.
+func fromUnstructured(obj interface{}, out interface{}) error {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return fmt.Errorf("unexpected object %T", obj)
+	}
+	b, err := json.Marshal(u.Object)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, out)
+}
+
+func (i *informerPodConfigs) GetPodConfig(ctx context.Context, namespace, name string) (*aciPodConfig, error) {
+	if err := i.waitForSync(ctx); err != nil {
+		return nil, err
+	}
+	obj, ok, err := i.podConfigs.GetIndexer().GetByKey(namespace + "/" + name)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, k8serr.NewNotFound(schema.GroupResource{Group: podConfigGroup, Resource: podConfigResource}, name)
+	}
+	var config aciPodConfig
+	if err := fromUnstructured(obj, &config); err != nil {
+		return nil, fmt.Errorf("invalid ACIPodConfig %s: %v", name, err)
+	}
+	return &config, nil
+}
+
+func (i *informerPodConfigs) ListPodConfigs(ctx context.Context, namespace string) ([]aciPodConfig, error) {
+	if err := i.waitForSync(ctx); err != nil {
+		return nil, err
+	}
+	objs, err := i.podConfigs.GetIndexer().ByIndex(cache.NamespaceIndex, namespace)
+	if err != nil {
+		return nil, err
+	}
+	configs := make([]aciPodConfig, 0, len(objs))
+	for _, obj := range objs {
+		var config aciPodConfig
+		if err := fromUnstructured(obj, &config); err != nil {
+			return nil, fmt.Errorf("invalid ACIPodConfig of namespace %s: %v", namespace, err)
+		}
+		configs = append(configs, config)
+	}
+	return configs, nil
+}
+
+func (i *informerPodConfigs) GetNamespaceDefaults(ctx context.Context, namespace string) (*aciNamespaceDefaults, error) {
+	if err := i.waitForSync(ctx); err != nil {
+		return nil, err
+	}
+	obj, ok, err := i.namespaceDefaults.GetIndexer().GetByKey(namespace + "/" + namespaceDefaultsName)
+	if err != nil || !ok {
+		return nil, err
+	}
+	var defaults aciNamespaceDefaults
+	if err := fromUnstructured(obj, &defaults); err != nil {
+		return nil, fmt.Errorf("invalid ACINamespaceDefaults of namespace %s: %v", namespace, err)
+	}
+	return &defaults, nil
+}
@@ -0,0 +1,80 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	k8serr "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+func TestInformerPodConfigs(t *testing.T) {
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/apis/aci.virtual-kubelet.io/v1alpha1/acipodconfigs" && r.URL.Query().Get("watch") == "":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"apiVersion": "aci.virtual-kubelet.io/v1alpha1", "kind": "ACIPodConfigList", "metadata": {"resourceVersion": "1"},
+				"items": [{"metadata": {"name": "gpu", "namespace": "team", "resourceVersion": "1"}, "spec": {"sku": "Dedicated"}}]}`))
+		case r.URL.Path == "/apis/aci.virtual-kubelet.io/v1alpha1/acipodconfigs":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"type": "ADDED", "object": {"apiVersion": "aci.virtual-kubelet.io/v1alpha1", "kind": "ACIPodConfig",
+				"metadata": {"name": "confidential", "namespace": "team", "resourceVersion": "2"}, "spec": {"sku": "Confidential"}}}` + "\n"))
+			w.(http.Flusher).Flush()
+			select {
+			case <-r.Context().Done():
+			case <-done:
+			}
+		default:
+			// The ACINamespaceDefaults CRD is not installed.
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"kind": "Status", "apiVersion": "v1", "status": "Failure", "reason": "NotFound", "code": 404}`))
+		}
+	}))
+	defer server.Close()
+	defer close(done)
+
+	kubeClient, err := kubernetes.NewForConfig(&rest.Config{Host: server.URL})
+	assert.NilError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	configs := newInformerPodConfigs(kubeClient.Discovery().RESTClient())
+	configs.run(ctx)
+
+	config, err := configs.GetPodConfig(ctx, "team", "gpu")
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal("Dedicated", config.Spec.SKU))
+	assert.Check(t, is.Equal("gpu", config.Metadata.Name))
+
+	_, err = configs.GetPodConfig(ctx, "team", "missing")
+	assert.Check(t, k8serr.IsNotFound(err), "Expected a not found error, got %v", err)
+
+	// The configs created since the list are watched.
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		listed, err := configs.ListPodConfigs(ctx, "team")
+		assert.NilError(t, err)
+		if len(listed) == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the watched config to be listed, got %v", listed)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	listed, err := configs.ListPodConfigs(ctx, "other")
+	assert.NilError(t, err)
+	assert.Check(t, is.Len(listed, 0))
+
+	// A missing CRD is no defaults.
+	defaults, err := configs.GetNamespaceDefaults(ctx, "team")
+	assert.NilError(t, err)
+	assert.Check(t, defaults == nil, "Expected no defaults, got %v", defaults)
+}
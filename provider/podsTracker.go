@@ -2,14 +2,18 @@ package provider
 
 import (
 	"context"
+	"math/rand"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/virtual-kubelet/node-cli/manager"
 	errdef "github.com/virtual-kubelet/virtual-kubelet/errdefs"
 	"github.com/virtual-kubelet/virtual-kubelet/log"
 	"github.com/virtual-kubelet/virtual-kubelet/trace"
 	v1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
 )
 
 const (
@@ -19,9 +23,31 @@ const (
 	containerExitCodeNotFound     int32 = -137
 
 	statusUpdatesInterval = 5 * time.Second
+	statusUpdatesJitter   = 0.2
 	cleanupInterval       = 5 * time.Minute
+
+	// statusUpdatesTick is the resolution of the per pod status updates schedule.
+	statusUpdatesTick = time.Second
+	// maxStatusUpdatesBackoff caps the multiplier of the interval for pods whose status settled.
+	maxStatusUpdatesBackoff = 12
+)
+
+const (
+	statusUpdateChanged   = "changed"
+	statusUpdateUnchanged = "unchanged"
 )
 
+// podStatusUpdates counts the statuses fetched from ACI, whether they changed and were written to the API server.
+var podStatusUpdates = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "aci",
+	Name:      "pod_status_updates_total",
+	Help:      "Pod statuses fetched from ACI, by whether they changed and were written to the API server.",
+}, []string{"result"})
+
+func init() {
+	prometheus.MustRegister(podStatusUpdates)
+}
+
 type PodIdentifier struct {
 	namespace string
 	name      string
@@ -31,12 +57,26 @@ type PodsTrackerHandler interface {
 	ListActivePods(ctx context.Context) ([]PodIdentifier, error)
 	FetchPodStatus(ctx context.Context, ns, name string) (*v1.PodStatus, error)
 	CleanupPod(ctx context.Context, ns, name string) error
+	RepairPod(ctx context.Context, pod *v1.Pod) bool
 }
 
 type PodsTracker struct {
 	rm       *manager.ResourceManager
 	updateCb func(*v1.Pod)
 	handler  PodsTrackerHandler
+	recorder record.EventRecorder
+
+	// updatesInterval is the base interval between two status updates of a pod, randomized by
+	// updatesJitter so the updates of the pods do not all hit ARM at the same time.
+	updatesInterval time.Duration
+	updatesJitter   float64
+	schedules       map[string]*podUpdateSchedule
+}
+
+// podUpdateSchedule is when the status of a pod is updated next.
+type podUpdateSchedule struct {
+	next    time.Time
+	backoff int
 }
 
 // StartTracking starts the background tracking for created pods.
@@ -44,7 +84,17 @@ func (pt *PodsTracker) StartTracking(ctx context.Context) {
 	ctx, span := trace.StartSpan(ctx, "PodsTracker.StartTracking")
 	defer span.End()
 
-	statusUpdatesTimer := time.NewTimer(statusUpdatesInterval)
+	if pt.updatesInterval <= 0 {
+		pt.updatesInterval = statusUpdatesInterval
+	}
+	pt.schedules = make(map[string]*podUpdateSchedule)
+
+	tick := statusUpdatesTick
+	if pt.updatesInterval < tick {
+		tick = pt.updatesInterval
+	}
+
+	statusUpdatesTimer := time.NewTimer(tick)
 	cleanupTimer := time.NewTimer(cleanupInterval)
 	defer statusUpdatesTimer.Stop()
 	defer cleanupTimer.Stop()
@@ -58,7 +108,7 @@ func (pt *PodsTracker) StartTracking(ctx context.Context) {
 			return
 		case <-statusUpdatesTimer.C:
 			pt.updatePodsLoop(ctx)
-			statusUpdatesTimer.Reset(statusUpdatesInterval)
+			statusUpdatesTimer.Reset(tick)
 		case <-cleanupTimer.C:
 			pt.cleanupDanglingPods(ctx)
 			cleanupTimer.Reset(cleanupInterval)
@@ -89,14 +139,76 @@ func (pt *PodsTracker) updatePodsLoop(ctx context.Context) {
 	ctx, span := trace.StartSpan(ctx, "PodsTracker.updatePods")
 	defer span.End()
 
+	now := time.Now()
 	k8sPods := pt.rm.GetPods()
+	seen := make(map[string]bool, len(k8sPods))
 	for _, pod := range k8sPods {
+		key := pod.Namespace + "/" + pod.Name
+		seen[key] = true
+
+		schedule, ok := pt.schedules[key]
+		if !ok {
+			schedule = &podUpdateSchedule{backoff: 1}
+			pt.schedules[key] = schedule
+		}
+		if now.Before(schedule.next) {
+			continue
+		}
+
 		updatedPod := pod.DeepCopy()
-		ok := pt.processPodUpdates(ctx, updatedPod)
-		if ok {
+		updated := pt.processPodUpdates(ctx, updatedPod)
+		if updated {
 			pt.updateCb(updatedPod)
 		}
+
+		// Back off pods whose status settled, the status of their container group is not expected to change.
+		if isPodSettled(updatedPod) {
+			if schedule.backoff *= 2; schedule.backoff > maxStatusUpdatesBackoff {
+				schedule.backoff = maxStatusUpdatesBackoff
+			}
+		} else {
+			schedule.backoff = 1
+		}
+		schedule.next = now.Add(pt.nextUpdateDelay(schedule.backoff))
 	}
+
+	for key := range pt.schedules {
+		if !seen[key] {
+			delete(pt.schedules, key)
+		}
+	}
+}
+
+// nextUpdateDelay returns the delay before the next status update of a pod, randomized by the jitter.
+func (pt *PodsTracker) nextUpdateDelay(backoff int) time.Duration {
+	delay := float64(pt.updatesInterval) * float64(backoff)
+	if pt.updatesJitter > 0 {
+		delay += delay * pt.updatesJitter * (2*rand.Float64() - 1)
+	}
+
+	return time.Duration(delay)
+}
+
+// isPodSettled reports whether the pod is suspended, or all its containers terminated and ACI
+// does not restart them.
+func isPodSettled(pod *v1.Pod) bool {
+	if pod.Status.Reason == podStatusReasonSuspended {
+		return true
+	}
+	if len(pod.Status.ContainerStatuses) == 0 || pod.Spec.RestartPolicy == v1.RestartPolicyAlways || pod.Spec.RestartPolicy == "" {
+		return false
+	}
+
+	for _, cs := range pod.Status.ContainerStatuses {
+		if cs.State.Terminated == nil {
+			return false
+		}
+		if pod.Spec.RestartPolicy == v1.RestartPolicyOnFailure && cs.State.Terminated.ExitCode != 0 {
+			return false
+		}
+	}
+
+	return true
 }
 
 func (pt *PodsTracker) cleanupDanglingPods(ctx context.Context) {
@@ -137,7 +249,21 @@ func (pt *PodsTracker) processPodUpdates(ctx context.Context, pod *v1.Pod) bool
 
 	podStatusFromProvider, err := pt.handler.FetchPodStatus(ctx, pod.Namespace, pod.Name)
 	if err == nil && podStatusFromProvider != nil {
+		// Keep the pod as is while its container group is re-created, instead of failing it.
+		if podStatusFromProvider.Reason == podStatusReasonProvisioningFailed && pt.handler.RepairPod(ctx, pod) {
+			return false
+		}
+		pt.recordContainerEvents(ctx, pod, podStatusFromProvider)
+		pt.recordDisruptionEvents(ctx, pod, podStatusFromProvider)
+		previous := pod.Status.DeepCopy()
 		podStatusFromProvider.DeepCopyInto(&pod.Status)
+		pod.Status.EphemeralContainerStatuses = ephemeralContainerStatuses(pod)
+		// Only write the status to the API server when it changed.
+		if apiequality.Semantic.DeepEqual(previous, &pod.Status) {
+			podStatusUpdates.WithLabelValues(statusUpdateUnchanged).Inc()
+			return false
+		}
+		podStatusUpdates.WithLabelValues(statusUpdateChanged).Inc()
 		return true
 	}
 
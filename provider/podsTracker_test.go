@@ -0,0 +1,102 @@
+package provider
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestNextUpdateDelay(t *testing.T) {
+	pt := &PodsTracker{
+		updatesInterval: 10 * time.Second,
+		updatesJitter:   0.2,
+	}
+
+	for i := 0; i < 100; i++ {
+		delay := pt.nextUpdateDelay(2)
+		assert.Check(t, delay >= 16*time.Second && delay <= 24*time.Second, "Delay %v is out of the jitter bounds", delay)
+	}
+}
+
+func TestIsPodSettled(t *testing.T) {
+	terminated := func(exitCode int32) v1.ContainerStatus {
+		return v1.ContainerStatus{
+			State: v1.ContainerState{
+				Terminated: &v1.ContainerStateTerminated{ExitCode: exitCode},
+			},
+		}
+	}
+
+	pod := &v1.Pod{}
+	pod.Spec.RestartPolicy = v1.RestartPolicyNever
+	pod.Status.ContainerStatuses = []v1.ContainerStatus{terminated(1)}
+	assert.Check(t, isPodSettled(pod), "Terminated pod never restarted should be settled")
+
+	pod.Spec.RestartPolicy = v1.RestartPolicyOnFailure
+	assert.Check(t, !isPodSettled(pod), "Failed pod restarted on failure should not be settled")
+
+	pod.Spec.RestartPolicy = v1.RestartPolicyAlways
+	pod.Status.ContainerStatuses = []v1.ContainerStatus{terminated(0)}
+	assert.Check(t, !isPodSettled(pod), "Pod always restarted should not be settled")
+
+	pod.Status.Reason = podStatusReasonSuspended
+	assert.Check(t, isPodSettled(pod), "Suspended pod should be settled")
+}
+
+type repairingHandler struct {
+	status  *v1.PodStatus
+	repairs int
+}
+
+func (h *repairingHandler) ListActivePods(ctx context.Context) ([]PodIdentifier, error) {
+	return nil, nil
+}
+
+func (h *repairingHandler) FetchPodStatus(ctx context.Context, ns, name string) (*v1.PodStatus, error) {
+	return h.status.DeepCopy(), nil
+}
+
+func (h *repairingHandler) CleanupPod(ctx context.Context, ns, name string) error {
+	return nil
+}
+
+func (h *repairingHandler) RepairPod(ctx context.Context, pod *v1.Pod) bool {
+	if h.repairs == 0 {
+		return false
+	}
+	h.repairs--
+	return true
+}
+
+func TestProcessPodUpdatesRepairsFailedProvisioning(t *testing.T) {
+	handler := &repairingHandler{
+		status:  &v1.PodStatus{Phase: v1.PodFailed, Reason: podStatusReasonProvisioningFailed},
+		repairs: 1,
+	}
+	pt := &PodsTracker{handler: handler}
+
+	pod := &v1.Pod{}
+	pod.Status.Phase = v1.PodPending
+	assert.Check(t, !pt.processPodUpdates(context.Background(), pod), "Pod being repaired should not be updated")
+	assert.Check(t, pod.Status.Phase == v1.PodPending, "Pod being repaired should stay pending")
+
+	assert.Check(t, pt.processPodUpdates(context.Background(), pod), "Pod whose repairs are exhausted should be updated")
+	assert.Check(t, pod.Status.Phase == v1.PodFailed, "Pod whose repairs are exhausted should be failed")
+}
+
+func TestProcessPodUpdatesSkipsUnchangedStatus(t *testing.T) {
+	handler := &repairingHandler{status: &v1.PodStatus{Phase: v1.PodRunning, PodIP: "10.0.0.4"}}
+	pt := &PodsTracker{handler: handler}
+
+	pod := &v1.Pod{}
+	pod.Status.Phase = v1.PodPending
+	assert.Check(t, pt.processPodUpdates(context.Background(), pod), "Changed status should be updated")
+	assert.Check(t, !pt.processPodUpdates(context.Background(), pod), "Unchanged status should not be updated")
+
+	handler.status.PodIP = "10.0.0.5"
+	assert.Check(t, pt.processPodUpdates(context.Background(), pod), "Changed status should be updated")
+	assert.Check(t, pod.Status.PodIP == "10.0.0.5")
+}
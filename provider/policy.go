@@ -0,0 +1,138 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	client "github.com/virtual-kubelet/azure-aci/client"
+	"github.com/virtual-kubelet/azure-aci/client/aci"
+	"github.com/virtual-kubelet/azure-aci/client/policyinsights"
+	"github.com/virtual-kubelet/virtual-kubelet/errdefs"
+	"github.com/virtual-kubelet/virtual-kubelet/log"
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	eventReasonPolicyDenied = "PolicyDenied"
+
+	containerGroupResourceType = "Microsoft.ContainerInstance/containerGroups"
+)
+
+// policySource evaluates the policies assigned to the resource group against a container group.
+type policySource interface {
+	CheckPolicyRestrictions(ctx context.Context, resourceGroup string, request policyinsights.CheckRestrictionsRequest) (*policyinsights.CheckRestrictionsResult, error)
+}
+
+// setupPolicyCheck enables checking the container groups against the Azure Policies of the resource group before
+// creating them, from PolicyCheck in the config file or the ACI_POLICY_CHECK environment variable.
+func (p *ACIProvider) setupPolicyCheck(azAuth *client.Authentication) error {
+	if v := os.Getenv("ACI_POLICY_CHECK"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid ACI_POLICY_CHECK %q: %v", v, err)
+		}
+		p.policyCheck = b
+	}
+	if !p.policyCheck {
+		return nil
+	}
+
+	var err error
+	p.policies, err = policyinsights.NewClient(azAuth, p.extraUserAgent)
+	if err != nil {
+		return fmt.Errorf("error setting up the policy check: %v", err)
+	}
+	return nil
+}
+
+// checkPolicyRestrictions rejects a pod with an event when an Azure Policy would deny the creation of its container
+// group, instead of failing the pod once ARM denies it. The policies are evaluated by Azure Policy Insights against
+// the container group as it would be created, without its secrets. A failure to evaluate them lets the pod through.
+func (p *ACIProvider) checkPolicyRestrictions(ctx context.Context, pod *v1.Pod, cg *aci.ContainerGroup) error {
+	if p.policies == nil {
+		return nil
+	}
+
+	cgName := containerGroupName(pod.Namespace, pod.Name)
+	content, err := policyResourceContent(cgName, cg)
+	if err != nil {
+		log.G(ctx).WithError(err).Warnf("failed to check container group %v against the policies", cgName)
+		return nil
+	}
+	request := policyinsights.CheckRestrictionsRequest{ResourceDetails: policyinsights.CheckRestrictionsResourceDetails{ResourceContent: content}}
+	if p.aciClient != nil {
+		// Without a supported api version, the creation fails anyway.
+		request.ResourceDetails.APIVersion, _ = p.aciClient.CreateAPIVersion(*cg)
+	}
+
+	result, err := p.policies.CheckPolicyRestrictions(ctx, p.resourceGroup, request)
+	if err != nil {
+		log.G(ctx).WithError(err).Warnf("failed to check container group %v against the policies", cgName)
+		return nil
+	}
+
+	denials := result.Denials()
+	if len(denials) == 0 {
+		return nil
+	}
+	policies := make([]string, 0, len(denials))
+	for _, d := range denials {
+		policy := d.PolicyInfo.PolicyName()
+		if assignment := d.PolicyInfo.AssignmentName(); assignment != "" {
+			policy += " (assignment " + assignment + ")"
+		}
+		policies = append(policies, policy)
+	}
+
+	p.recordEvent(pod, v1.EventTypeWarning, eventReasonPolicyDenied, "Container group %s is denied by policy %s", cgName, strings.Join(policies, ", "))
+	return errdefs.InvalidInputf("pod %s can not be created, its container group is denied by policy %s", pod.Name, strings.Join(policies, ", "))
+}
+
+// policyResourceContent returns the content of the creation of the container group, with the values of its
+// secrets left empty.
+func policyResourceContent(cgName string, cg *aci.ContainerGroup) (map[string]interface{}, error) {
+	resource := *cg
+	resource.Name = cgName
+	resource.Type = containerGroupResourceType
+
+	b, err := json.Marshal(resource)
+	if err != nil {
+		return nil, err
+	}
+	var content map[string]interface{}
+	if err := json.Unmarshal(b, &content); err != nil {
+		return nil, err
+	}
+	redactSecrets(content)
+	return content, nil
+}
+
+// redactSecrets empties the secure environment variables, the passwords, the storage account keys and the
+// values of the secret volumes of a container group.
+func redactSecrets(v interface{}) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			switch key {
+			case "secureValue", "password", "storageAccountKey":
+				v[key] = ""
+			case "secret":
+				if secret, ok := value.(map[string]interface{}); ok {
+					for name := range secret {
+						secret[name] = ""
+					}
+				}
+			default:
+				redactSecrets(value)
+			}
+		}
+	case []interface{}:
+		for _, value := range v {
+			redactSecrets(value)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/virtual-kubelet/azure-aci/client/aci"
+	"github.com/virtual-kubelet/azure-aci/client/policyinsights"
+	"github.com/virtual-kubelet/virtual-kubelet/errdefs"
+	"gotest.tools/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+type fakePolicies struct {
+	request policyinsights.CheckRestrictionsRequest
+	result  policyinsights.CheckRestrictionsResult
+}
+
+func (f *fakePolicies) CheckPolicyRestrictions(ctx context.Context, resourceGroup string, request policyinsights.CheckRestrictionsRequest) (*policyinsights.CheckRestrictionsResult, error) {
+	f.request = request
+	return &f.result, nil
+}
+
+func TestCheckPolicyRestrictions(t *testing.T) {
+	policies := &fakePolicies{}
+	recorder := record.NewFakeRecorder(1)
+	p := ACIProvider{policies: policies, eventRecorder: recorder}
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "ns"}}
+	cg := &aci.ContainerGroup{Location: "eastus"}
+	cg.Containers = []aci.Container{{Name: "web", ContainerProperties: aci.ContainerProperties{
+		EnvironmentVariables: []aci.EnvironmentVariable{{Name: "TOKEN", SecureValue: "s3cr3t"}},
+	}}}
+	cg.ImageRegistryCredentials = []aci.ImageRegistryCredential{{Server: "registry.example.com", Username: "user", Password: "p4ss"}}
+
+	assert.NilError(t, p.checkPolicyRestrictions(context.Background(), pod, cg))
+	content := policies.request.ResourceDetails.ResourceContent.(map[string]interface{})
+	assert.Equal(t, content["name"], "ns-web")
+	assert.Equal(t, content["type"], containerGroupResourceType)
+	properties := content["properties"].(map[string]interface{})
+	credential := properties["imageRegistryCredentials"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, credential["server"], "registry.example.com")
+	assert.Equal(t, credential["password"], "", "Secrets should not be sent to the policy check")
+	container := properties["containers"].([]interface{})[0].(map[string]interface{})
+	env := container["properties"].(map[string]interface{})["environmentVariables"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, env["secureValue"], "")
+	assert.Equal(t, cg.ImageRegistryCredentials[0].Password, "p4ss", "The container group should not be changed")
+
+	policies.result.ContentEvaluationResult.PolicyEvaluations = []policyinsights.PolicyEvaluationResult{{
+		PolicyInfo: policyinsights.PolicyReference{
+			PolicyDefinitionID: "/providers/Microsoft.Authorization/policyDefinitions/no-public-ip",
+			PolicyAssignmentID: "/subscriptions/sub/providers/Microsoft.Authorization/policyAssignments/network",
+		},
+		EvaluationResult: "NonCompliant",
+		EffectDetails:    &policyinsights.EffectDetails{PolicyEffect: "Deny"},
+	}}
+	err := p.checkPolicyRestrictions(context.Background(), pod, cg)
+	assert.Assert(t, errdefs.IsInvalidInput(err))
+	assert.Equal(t, <-recorder.Events, "Warning PolicyDenied Container group ns-web is denied by policy no-public-ip (assignment network)")
+}
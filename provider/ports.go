@@ -0,0 +1,135 @@
+package provider
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/virtual-kubelet/azure-aci/client/aci"
+	"github.com/virtual-kubelet/virtual-kubelet/errdefs"
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	// publicIPAnnotation requests a public IP for the ports of a pod with the public-on-annotation policy.
+	publicIPAnnotation = "virtual-kubelet.io/public-ip"
+
+	// dnsNameLabelScopeAnnotation is the scope in which ACI reuses the DNS name label of a pod.
+	dnsNameLabelScopeAnnotation = "virtual-kubelet.io/dns-name-label-scope"
+
+	portExposurePublic             = "public"
+	portExposurePublicOnAnnotation = "public-on-annotation"
+	portExposurePrivate            = "private"
+)
+
+// dnsNameLabelScopes are the scopes of the DNS name labels of ACI.
+var dnsNameLabelScopes = []string{"Unsecure", "TenantReuse", "SubscriptionReuse", "ResourceGroupReuse", "Noreuse"}
+
+// setupPortExposure reads when the ports of the pods outside of a virtual network are exposed on a public IP
+// from ACI_PORT_EXPOSURE or the config file: public, the default, exposes them unless the public IP annotation
+// of the pod is false, public-on-annotation only for the pods with the public IP annotation, and private never
+// does. ACI_DNS_NAME_LABEL_SCOPE or the config file sets the default scope of the DNS name labels.
+func (p *ACIProvider) setupPortExposure() error {
+	if v := os.Getenv("ACI_PORT_EXPOSURE"); v != "" {
+		p.portExposure = v
+	}
+	switch p.portExposure {
+	case "":
+		p.portExposure = portExposurePublic
+	case portExposurePublic, portExposurePublicOnAnnotation, portExposurePrivate:
+	default:
+		return fmt.Errorf("invalid port exposure %q, must be %s, %s or %s", p.portExposure, portExposurePublic, portExposurePublicOnAnnotation, portExposurePrivate)
+	}
+
+	if v := os.Getenv("ACI_DNS_NAME_LABEL_SCOPE"); v != "" {
+		p.dnsLabelScope = v
+	}
+	if _, err := p.dnsNameLabelScope(&v1.Pod{}); err != nil {
+		return fmt.Errorf("invalid default DNS name label scope %q, must be one of %s", p.dnsLabelScope, strings.Join(dnsNameLabelScopes, ", "))
+	}
+	return nil
+}
+
+// getContainerGroupPorts returns the ports of the containers to open on the IP of the container group.
+// ACI exposes a container port on the same port of the container group IP, so a hostPort can only
+// repeat its containerPort, and ACI has no SCTP.
+func getContainerGroupPorts(pod *v1.Pod) ([]aci.Port, error) {
+	var ports []aci.Port
+	seen := make(map[aci.Port]bool)
+	for _, c := range pod.Spec.Containers {
+		for _, port := range c.Ports {
+			var protocol aci.ContainerGroupNetworkProtocol
+			switch port.Protocol {
+			case v1.ProtocolTCP, "":
+				protocol = aci.TCP
+			case v1.ProtocolUDP:
+				protocol = aci.UDP
+			default:
+				return nil, errdefs.InvalidInputf("container %s of pod %s: port %d uses protocol %s, ACI only supports TCP and UDP", c.Name, pod.Name, port.ContainerPort, port.Protocol)
+			}
+			if port.HostPort != 0 && port.HostPort != port.ContainerPort {
+				return nil, errdefs.InvalidInputf("container %s of pod %s: hostPort %d differs from containerPort %d, ACI exposes the containerPort on the IP of the pod", c.Name, pod.Name, port.HostPort, port.ContainerPort)
+			}
+
+			groupPort := aci.Port{Port: port.ContainerPort, Protocol: protocol}
+			if seen[groupPort] {
+				continue
+			}
+			seen[groupPort] = true
+			ports = append(ports, groupPort)
+		}
+	}
+	return ports, nil
+}
+
+// exposePublicly reports whether the ports of a pod outside of a virtual network are exposed on a
+// public IP, according to the port exposure policy and the public IP annotation of the pod, which
+// opts in or out of the policy. A DNS name label requires a public IP.
+func (p *ACIProvider) exposePublicly(pod *v1.Pod) (bool, error) {
+	requested, set := false, false
+	if v := pod.Annotations[publicIPAnnotation]; v != "" {
+		var err error
+		if requested, err = strconv.ParseBool(v); err != nil {
+			return false, errdefs.InvalidInputf("invalid %s annotation %q of pod %s: %v", publicIPAnnotation, v, pod.Name, err)
+		}
+		set = true
+	}
+	if pod.Annotations[virtualKubeletDNSNameLabel] != "" {
+		if set && !requested {
+			return false, errdefs.InvalidInputf("pod %s has a DNS name label, which requires a public IP, but its %s annotation is false", pod.Name, publicIPAnnotation)
+		}
+		requested, set = true, true
+	}
+
+	switch p.portExposure {
+	case portExposurePrivate:
+		if requested {
+			return false, errdefs.InvalidInputf("pod %s requests a public IP, which the port exposure policy of node %s does not allow", pod.Name, p.nodeName)
+		}
+		return false, nil
+	case portExposurePublicOnAnnotation:
+		return requested, nil
+	default:
+		return requested || !set, nil
+	}
+}
+
+// dnsNameLabelScope returns the scope in which the DNS name label of a pod is reused, from its annotation
+// or else the default of the node. ACI hashes the label with the scope, so the FQDN of the pod stays the
+// same when its container group is recreated in the scope, and can't be taken by other tenants.
+func (p *ACIProvider) dnsNameLabelScope(pod *v1.Pod) (string, error) {
+	scope := p.dnsLabelScope
+	if v := pod.Annotations[dnsNameLabelScopeAnnotation]; v != "" {
+		scope = v
+	}
+	if scope == "" {
+		return "", nil
+	}
+	for _, valid := range dnsNameLabelScopes {
+		if strings.EqualFold(scope, valid) {
+			return valid, nil
+		}
+	}
+	return "", errdefs.InvalidInputf("invalid DNS name label scope %q of pod %s, must be one of %s", scope, pod.Name, strings.Join(dnsNameLabelScopes, ", "))
+}
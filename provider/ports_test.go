@@ -0,0 +1,96 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/virtual-kubelet/azure-aci/client/aci"
+	"github.com/virtual-kubelet/virtual-kubelet/errdefs"
+	"gotest.tools/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func portsPod(annotations map[string]string, ports ...v1.ContainerPort) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "ns", Annotations: annotations},
+		Spec:       v1.PodSpec{Containers: []v1.Container{{Name: "app", Ports: ports}}},
+	}
+}
+
+func TestGetContainerGroupPorts(t *testing.T) {
+	ports, err := getContainerGroupPorts(portsPod(nil,
+		v1.ContainerPort{ContainerPort: 80},
+		v1.ContainerPort{ContainerPort: 53, Protocol: v1.ProtocolUDP},
+		v1.ContainerPort{ContainerPort: 53, Protocol: v1.ProtocolTCP, HostPort: 53},
+		v1.ContainerPort{ContainerPort: 80, Protocol: v1.ProtocolTCP},
+	))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, ports, []aci.Port{{Port: 80, Protocol: aci.TCP}, {Port: 53, Protocol: aci.UDP}, {Port: 53, Protocol: aci.TCP}})
+
+	_, err = getContainerGroupPorts(portsPod(nil, v1.ContainerPort{ContainerPort: 9000, Protocol: v1.ProtocolSCTP}))
+	assert.Check(t, errdefs.IsInvalidInput(err), "Expected an invalid input error, got %v", err)
+	assert.ErrorContains(t, err, "SCTP")
+
+	_, err = getContainerGroupPorts(portsPod(nil, v1.ContainerPort{ContainerPort: 80, HostPort: 8080}))
+	assert.Check(t, errdefs.IsInvalidInput(err), "Expected an invalid input error, got %v", err)
+}
+
+func TestExposePublicly(t *testing.T) {
+	p := ACIProvider{}
+	assert.NilError(t, p.setupPortExposure())
+	public, err := p.exposePublicly(portsPod(nil))
+	assert.NilError(t, err)
+	assert.Check(t, public, "Ports should be public by default")
+	public, err = p.exposePublicly(portsPod(map[string]string{publicIPAnnotation: "false"}))
+	assert.NilError(t, err)
+	assert.Check(t, !public, "A pod should opt out of the public IP")
+	_, err = p.exposePublicly(portsPod(map[string]string{publicIPAnnotation: "false", virtualKubeletDNSNameLabel: "app"}))
+	assert.Check(t, errdefs.IsInvalidInput(err), "Expected an invalid input error, got %v", err)
+
+	p.portExposure = portExposurePublicOnAnnotation
+	public, err = p.exposePublicly(portsPod(nil))
+	assert.NilError(t, err)
+	assert.Check(t, !public)
+	public, err = p.exposePublicly(portsPod(map[string]string{publicIPAnnotation: "true"}))
+	assert.NilError(t, err)
+	assert.Check(t, public)
+	public, err = p.exposePublicly(portsPod(map[string]string{virtualKubeletDNSNameLabel: "app"}))
+	assert.NilError(t, err)
+	assert.Check(t, public, "A DNS name label should request a public IP")
+	_, err = p.exposePublicly(portsPod(map[string]string{publicIPAnnotation: "maybe"}))
+	assert.Check(t, errdefs.IsInvalidInput(err), "Expected an invalid input error, got %v", err)
+
+	p.portExposure = portExposurePrivate
+	public, err = p.exposePublicly(portsPod(nil))
+	assert.NilError(t, err)
+	assert.Check(t, !public)
+	_, err = p.exposePublicly(portsPod(map[string]string{publicIPAnnotation: "true"}))
+	assert.Check(t, errdefs.IsInvalidInput(err), "Expected an invalid input error, got %v", err)
+	public, err = p.exposePublicly(portsPod(map[string]string{publicIPAnnotation: "false"}))
+	assert.NilError(t, err)
+	assert.Check(t, !public)
+
+	p.portExposure = "everywhere"
+	assert.ErrorContains(t, p.setupPortExposure(), "invalid port exposure")
+}
+
+func TestDNSNameLabelScope(t *testing.T) {
+	p := ACIProvider{}
+	scope, err := p.dnsNameLabelScope(portsPod(nil))
+	assert.NilError(t, err)
+	assert.Equal(t, scope, "")
+
+	p.dnsLabelScope = "TenantReuse"
+	scope, err = p.dnsNameLabelScope(portsPod(nil))
+	assert.NilError(t, err)
+	assert.Equal(t, scope, "TenantReuse")
+	scope, err = p.dnsNameLabelScope(portsPod(map[string]string{dnsNameLabelScopeAnnotation: "resourcegroupreuse"}))
+	assert.NilError(t, err)
+	assert.Equal(t, scope, "ResourceGroupReuse")
+
+	_, err = p.dnsNameLabelScope(portsPod(map[string]string{dnsNameLabelScopeAnnotation: "Global"}))
+	assert.Check(t, errdefs.IsInvalidInput(err), "Expected an invalid input error, got %v", err)
+
+	p.dnsLabelScope = "Global"
+	assert.ErrorContains(t, p.setupPortExposure(), "invalid default DNS name label scope")
+}
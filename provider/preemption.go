@@ -0,0 +1,221 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/virtual-kubelet/azure-aci/client/aci"
+	"github.com/virtual-kubelet/virtual-kubelet/errdefs"
+	"github.com/virtual-kubelet/virtual-kubelet/log"
+	v1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// spotAnnotation set to "true" runs the container group of a pod as a Spot container group.
+	spotAnnotation = "virtual-kubelet.io/spot"
+
+	eventReasonPreempting = "Preempting"
+	eventReasonPreempted  = "Preempted"
+
+	// preemptionDeleteMargin is how long the container groups of the pods preempted may take to be deleted, after
+	// their grace period.
+	preemptionDeleteMargin = time.Minute
+)
+
+// isSpotPod reports whether the container group of the pod is a Spot container group.
+func isSpotPod(pod *v1.Pod) bool {
+	return pod.Annotations[spotAnnotation] == "true"
+}
+
+// applySpotPriority runs the container group of a pod with the spot annotation as a Spot container group.
+func applySpotPriority(pod *v1.Pod, cg *aci.ContainerGroup) {
+	if isSpotPod(pod) {
+		cg.Priority = aci.ContainerGroupPrioritySpot
+	}
+}
+
+// setupSpotPreemption enables the preemption of the Spot pods by the pods of a higher priority which don't fit
+// in the capacity of the node or the budget of their namespace, from SpotPreemption in the config file or
+// ACI_SPOT_PREEMPTION.
+func (p *ACIProvider) setupSpotPreemption() error {
+	if v := os.Getenv("ACI_SPOT_PREEMPTION"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid ACI_SPOT_PREEMPTION %q: %v", v, err)
+		}
+		p.spotPreemption = b
+	}
+	return nil
+}
+
+func podPriority(pod *v1.Pod) int32 {
+	if pod.Spec.Priority != nil {
+		return *pod.Spec.Priority
+	}
+	return 0
+}
+
+func podKey(pod *v1.Pod) string {
+	return pod.Namespace + "/" + pod.Name
+}
+
+// pendingPreemption are the pods preempted for a pod, whose container groups still hold their resources.
+type pendingPreemption struct {
+	victims  []*v1.Pod
+	deadline time.Time
+}
+
+// preemptions are the pending preemptions by pod, until the container groups of their pods preempted are deleted.
+type preemptions struct {
+	mu   sync.Mutex
+	pods map[string]pendingPreemption
+}
+
+func (pr *preemptions) get(podNS, podName string) (pendingPreemption, bool) {
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+	pending, ok := pr.pods[podNS+"/"+podName]
+	return pending, ok
+}
+
+func (pr *preemptions) set(podNS, podName string, pending pendingPreemption) {
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+	if pr.pods == nil {
+		pr.pods = make(map[string]pendingPreemption)
+	}
+	pr.pods[podNS+"/"+podName] = pending
+}
+
+func (pr *preemptions) remove(podNS, podName string) {
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+	delete(pr.pods, podNS+"/"+podName)
+}
+
+// preemptSpotPods evicts Spot pods among candidates of a lower priority than pod, until fits reports the pod fits
+// without the pods evicted, the lowest priority and then the youngest pods first, like the preemption of the
+// scheduler. The pods are evicted through the eviction API, which refuses the evictions their PodDisruptionBudget
+// doesn't allow, those pods are kept. Nothing is evicted when the pod wouldn't fit even without all the candidates.
+// Until the container groups of the pods evicted are deleted they still hold their resources in ACI, so once the
+// evictions are issued it returns an error for the creation of the pod to be retried, without blocking it. On the
+// retries, it reports the pod fits once they are deleted, or gives up after their longest grace period and
+// preemptionDeleteMargin.
+func (p *ACIProvider) preemptSpotPods(ctx context.Context, pod *v1.Pod, candidates []*v1.Pod, fits func(evicted map[string]bool) bool) (bool, error) {
+	if !p.spotPreemption || (pod.Spec.PreemptionPolicy != nil && *pod.Spec.PreemptionPolicy == v1.PreemptNever) {
+		return false, nil
+	}
+
+	if pending, ok := p.preemptions.get(pod.Namespace, pod.Name); ok {
+		running := p.runningContainerGroups(ctx, pending.victims)
+		if len(running) > 0 && time.Now().Before(pending.deadline) {
+			return false, preemptionPendingError(running)
+		}
+		p.preemptions.remove(pod.Namespace, pod.Name)
+		if len(running) > 0 {
+			log.G(ctx).WithField("pods", len(running)).Warn("the container groups of the preempted Spot pods are not deleted yet")
+			return false, nil
+		}
+		evicted := make(map[string]bool, len(pending.victims))
+		for _, victim := range pending.victims {
+			evicted[podKey(victim)] = true
+		}
+		if fits(evicted) {
+			return true, nil
+		}
+	}
+
+	var victims []*v1.Pod
+	all := make(map[string]bool)
+	for _, other := range candidates {
+		if !isSpotPod(other) || !placedPod(other, pod) || podPriority(other) >= podPriority(pod) {
+			continue
+		}
+		victims = append(victims, other)
+		all[podKey(other)] = true
+	}
+	if len(victims) == 0 || !fits(all) {
+		return false, nil
+	}
+	sort.SliceStable(victims, func(i, j int) bool {
+		if podPriority(victims[i]) != podPriority(victims[j]) {
+			return podPriority(victims[i]) < podPriority(victims[j])
+		}
+		return victims[j].CreationTimestamp.Before(&victims[i].CreationTimestamp)
+	})
+
+	evicted := make(map[string]bool)
+	var names []string
+	var preempted []*v1.Pod
+	for _, victim := range victims {
+		if fits(evicted) {
+			break
+		}
+		eviction := &policyv1beta1.Eviction{ObjectMeta: metav1.ObjectMeta{Namespace: victim.Namespace, Name: victim.Name}}
+		if err := p.kubeClient.CoreV1().Pods(victim.Namespace).Evict(ctx, eviction); err != nil {
+			logger := log.G(ctx).WithError(err).WithField("victim", podKey(victim))
+			if apierrors.IsTooManyRequests(err) {
+				logger.Info("the PodDisruptionBudget of the Spot pod doesn't allow its preemption")
+			} else {
+				logger.Warn("failed to preempt a Spot pod")
+			}
+			continue
+		}
+		evicted[podKey(victim)] = true
+		names = append(names, podKey(victim))
+		preempted = append(preempted, victim)
+		p.recordEvent(victim, v1.EventTypeWarning, eventReasonPreempted, "Preempted by pod %s of priority %d", podKey(pod), podPriority(pod))
+	}
+	if len(names) > 0 {
+		p.recordEvent(pod, v1.EventTypeNormal, eventReasonPreempting, "Preempted the Spot pods %s", strings.Join(names, ", "))
+	}
+	if !fits(evicted) {
+		return false, nil
+	}
+	p.preemptions.set(pod.Namespace, pod.Name, pendingPreemption{victims: preempted, deadline: time.Now().Add(preemptionDeadline(preempted))})
+	return false, preemptionPendingError(preempted)
+}
+
+// preemptionDeadline returns how long the container groups of the pods preempted may take to be deleted, their
+// longest grace period and preemptionDeleteMargin.
+func preemptionDeadline(pods []*v1.Pod) time.Duration {
+	grace := int64(v1.DefaultTerminationGracePeriodSeconds)
+	for _, pod := range pods {
+		if pod.Spec.TerminationGracePeriodSeconds != nil && *pod.Spec.TerminationGracePeriodSeconds > grace {
+			grace = *pod.Spec.TerminationGracePeriodSeconds
+		}
+	}
+	return time.Duration(grace)*time.Second + preemptionDeleteMargin
+}
+
+// runningContainerGroups returns the pods whose container groups are not deleted yet.
+func (p *ACIProvider) runningContainerGroups(ctx context.Context, pods []*v1.Pod) []*v1.Pod {
+	var running []*v1.Pod
+	for _, pod := range pods {
+		cg, err := p.getContainerGroup(ctx, pod.Namespace, pod.Name)
+		if errdefs.IsNotFound(err) || (err == nil && cg.Tags["UID"] != string(pod.UID)) {
+			continue
+		}
+		running = append(running, pod)
+	}
+	return running
+}
+
+// preemptionPendingError is the error retrying the creation of a pod until the container groups of the pods
+// preempted for it are deleted.
+func preemptionPendingError(pods []*v1.Pod) error {
+	names := make([]string, 0, len(pods))
+	for _, pod := range pods {
+		names = append(names, podKey(pod))
+	}
+	return fmt.Errorf("waiting for the container groups of the preempted Spot pods %s to be deleted", strings.Join(names, ", "))
+}
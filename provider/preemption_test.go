@@ -0,0 +1,165 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/virtual-kubelet/azure-aci/client/aci"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	v1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func preemptionPod(name string, priority int32, spot bool, age time.Duration) *v1.Pod {
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         "team",
+			CreationTimestamp: metav1.NewTime(time.Now().Add(-age)),
+		},
+		Spec: v1.PodSpec{Priority: &priority},
+	}
+	if spot {
+		pod.Annotations = map[string]string{spotAnnotation: "true"}
+	}
+	return pod
+}
+
+func TestPreemptSpotPods(t *testing.T) {
+	var evictions []string
+	kubeClient := fake.NewSimpleClientset()
+	kubeClient.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "eviction" {
+			return false, nil, nil
+		}
+		eviction := action.(k8stesting.CreateAction).GetObject().(*policyv1beta1.Eviction)
+		if eviction.Name == "protected" {
+			return true, nil, apierrors.NewTooManyRequests("Cannot evict pod as it would violate the pod's disruption budget.", 0)
+		}
+		evictions = append(evictions, eviction.Name)
+		return true, nil, nil
+	})
+	_, aciServerMocker, p, err := prepareMocks()
+	if err != nil {
+		t.Fatal("Unable to prepare the mocks", err)
+	}
+	// The container groups of the pods evicted are gone.
+	aciServerMocker.OnGetContainerGroup = func(subscription, resourceGroup, containerGroup string) (int, interface{}) {
+		return http.StatusNotFound, nil
+	}
+	p.kubeClient = kubeClient
+	p.spotPreemption = true
+
+	candidates := []*v1.Pod{
+		preemptionPod("regular", 0, false, time.Hour),
+		preemptionPod("higher", 200, true, time.Hour),
+		preemptionPod("protected", 0, true, time.Minute),
+		preemptionPod("old", 0, true, time.Hour),
+		preemptionPod("young", 0, true, 2*time.Minute),
+		preemptionPod("lowest", -10, true, time.Hour),
+	}
+	pod := preemptionPod("api", 100, false, 0)
+
+	// The pod fits once two Spot pods are gone.
+	fitsWithout := func(n int) func(map[string]bool) bool {
+		return func(evicted map[string]bool) bool { return len(evicted) >= n }
+	}
+	// The creation is retried once the pods are evicted, and the pod fits once their container groups are deleted.
+	fits, err := p.preemptSpotPods(context.Background(), pod, candidates, fitsWithout(2))
+	assert.Check(t, !fits)
+	assert.Check(t, is.ErrorContains(err, "team/lowest, team/young"))
+	assert.Check(t, is.DeepEqual([]string{"lowest", "young"}, evictions))
+	fits, err = p.preemptSpotPods(context.Background(), pod, candidates, fitsWithout(2))
+	assert.NilError(t, err)
+	assert.Check(t, fits)
+	assert.Check(t, is.DeepEqual([]string{"lowest", "young"}, evictions), "The retry should not evict more pods")
+
+	evictions = nil
+	fits, err = p.preemptSpotPods(context.Background(), pod, candidates, fitsWithout(5))
+	assert.Check(t, !fits && err == nil)
+	assert.Check(t, is.Len(evictions, 0), "Nothing should be evicted when the pod doesn't fit without all the Spot pods")
+
+	_, err = p.preemptSpotPods(context.Background(), pod, candidates, fitsWithout(3))
+	assert.Check(t, err != nil)
+	assert.Check(t, is.DeepEqual([]string{"lowest", "young", "old"}, evictions), "The pod protected by its budget should be kept")
+	p.preemptions.remove("team", "api")
+
+	evictions = nil
+	never := v1.PreemptNever
+	pod.Spec.PreemptionPolicy = &never
+	fits, err = p.preemptSpotPods(context.Background(), pod, candidates, fitsWithout(1))
+	assert.Check(t, !fits && err == nil)
+	assert.Check(t, is.Len(evictions, 0), "A pod which never preempts should not evict any pod")
+
+	pod.Spec.PreemptionPolicy = nil
+	p.spotPreemption = false
+	fits, err = p.preemptSpotPods(context.Background(), pod, candidates, fitsWithout(1))
+	assert.Check(t, !fits && err == nil)
+	assert.Check(t, is.Len(evictions, 0), "Preemption should be disabled by default")
+}
+
+func TestPreemptSpotPodsRetriesUntilTheContainerGroupsAreDeleted(t *testing.T) {
+	evictions := 0
+	kubeClient := fake.NewSimpleClientset()
+	kubeClient.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "eviction" {
+			return false, nil, nil
+		}
+		evictions++
+		return true, nil, nil
+	})
+	_, aciServerMocker, p, err := prepareMocks()
+	if err != nil {
+		t.Fatal("Unable to prepare the mocks", err)
+	}
+	var mu sync.Mutex
+	deleted := false
+	aciServerMocker.OnGetContainerGroup = func(subscription, resourceGroup, containerGroup string) (int, interface{}) {
+		mu.Lock()
+		defer mu.Unlock()
+		if deleted {
+			return http.StatusNotFound, nil
+		}
+		return http.StatusOK, aci.ContainerGroup{Name: containerGroup, Tags: map[string]string{"NodeName": fakeNodeName}}
+	}
+	p.kubeClient = kubeClient
+	p.spotPreemption = true
+
+	pod := preemptionPod("api", 100, false, 0)
+	candidates := []*v1.Pod{preemptionPod("spot", 0, true, time.Hour)}
+	fits := func(evicted map[string]bool) bool { return len(evicted) == 1 }
+
+	// The creation doesn't wait for the container group of the pod evicted to be deleted.
+	for i := 0; i < 2; i++ {
+		ok, err := p.preemptSpotPods(context.Background(), pod, candidates, fits)
+		assert.Check(t, !ok)
+		assert.Check(t, is.ErrorContains(err, "team/spot"))
+	}
+	assert.Check(t, is.Equal(1, evictions), "The pod should only be evicted once")
+
+	mu.Lock()
+	deleted = true
+	mu.Unlock()
+	ok, err := p.preemptSpotPods(context.Background(), pod, candidates, fits)
+	assert.NilError(t, err)
+	assert.Check(t, ok, "The pod should fit once the container group is deleted")
+
+	// The pod doesn't fit when the container group is still not deleted after the grace period.
+	mu.Lock()
+	deleted = false
+	mu.Unlock()
+	p.preemptions.set("team", "api", pendingPreemption{victims: candidates, deadline: time.Now().Add(-time.Second)})
+	ok, err = p.preemptSpotPods(context.Background(), pod, []*v1.Pod{}, fits)
+	assert.Check(t, !ok && err == nil)
+	_, pending := p.preemptions.get("team", "api")
+	assert.Check(t, !pending)
+}
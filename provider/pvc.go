@@ -0,0 +1,129 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/virtual-kubelet/azure-aci/client/aci"
+	"github.com/virtual-kubelet/virtual-kubelet/errdefs"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const azureFileCSIDriver = "file.csi.azure.com"
+
+// azureFileShare is an Azure Files share with the secret holding the key of its storage account.
+type azureFileShare struct {
+	account         string
+	share           string
+	secretName      string
+	secretNamespace string
+	readOnly        bool
+}
+
+// azureFileShareFromPV returns the share of a persistent volume of the azurefile-csi driver or the in-tree
+// azureFile plugin. The CSI volume handle is {resourceGroup}#{account}#{share}#..., overridden by the
+// storageAccount and shareName attributes.
+func azureFileShareFromPV(pv *v1.PersistentVolume) (*azureFileShare, bool) {
+	if f := pv.Spec.AzureFile; f != nil {
+		share := &azureFileShare{share: f.ShareName, secretName: f.SecretName, readOnly: f.ReadOnly}
+		if f.SecretNamespace != nil {
+			share.secretNamespace = *f.SecretNamespace
+		}
+		return share, true
+	}
+
+	csi := pv.Spec.CSI
+	if csi == nil || csi.Driver != azureFileCSIDriver {
+		return nil, false
+	}
+
+	share := &azureFileShare{readOnly: csi.ReadOnly}
+	parts := strings.Split(csi.VolumeHandle, "#")
+	if len(parts) > 2 {
+		share.account, share.share = parts[1], parts[2]
+	}
+	if v := csi.VolumeAttributes["storageAccount"]; v != "" {
+		share.account = v
+	}
+	if v := csi.VolumeAttributes["shareName"]; v != "" {
+		share.share = v
+	}
+	if ref := csi.NodeStageSecretRef; ref != nil {
+		share.secretName, share.secretNamespace = ref.Name, ref.Namespace
+	} else {
+		// The default secret of the driver for the storage account.
+		share.secretName = fmt.Sprintf("azure-storage-account-%s-secret", share.account)
+		share.secretNamespace = csi.VolumeAttributes["secretNamespace"]
+		if share.secretNamespace == "" {
+			share.secretNamespace = metav1.NamespaceDefault
+		}
+	}
+	return share, true
+}
+
+// isReadOnlyPV reports whether the access modes of a persistent volume only allow read-only mounts.
+func isReadOnlyPV(pv *v1.PersistentVolume) bool {
+	if len(pv.Spec.AccessModes) == 0 {
+		return false
+	}
+	for _, mode := range pv.Spec.AccessModes {
+		if mode != v1.ReadOnlyMany {
+			return false
+		}
+	}
+	return true
+}
+
+// getPVCVolume translates a persistent volume claim bound to an Azure Files persistent volume into an
+// ACI Azure Files volume. The volume is read-only if the claim, the persistent volume or its access
+// modes say so.
+func (p *ACIProvider) getPVCVolume(ctx context.Context, pod *v1.Pod, v v1.Volume) (aci.Volume, error) {
+	if p.kubeClient == nil {
+		return aci.Volume{}, fmt.Errorf("Pod %s requires persistent volume claim %s, which requires a kubernetes client", pod.Name, v.PersistentVolumeClaim.ClaimName)
+	}
+
+	claimName := v.PersistentVolumeClaim.ClaimName
+	pvc, err := p.kubeClient.CoreV1().PersistentVolumeClaims(pod.Namespace).Get(ctx, claimName, metav1.GetOptions{})
+	if err != nil {
+		return aci.Volume{}, fmt.Errorf("unable to get persistent volume claim %s of Pod %s: %v", claimName, pod.Name, err)
+	}
+	if pvc.Status.Phase != v1.ClaimBound || pvc.Spec.VolumeName == "" {
+		return aci.Volume{}, fmt.Errorf("persistent volume claim %s of Pod %s is not bound yet", claimName, pod.Name)
+	}
+
+	pv, err := p.kubeClient.CoreV1().PersistentVolumes().Get(ctx, pvc.Spec.VolumeName, metav1.GetOptions{})
+	if err != nil {
+		return aci.Volume{}, fmt.Errorf("unable to get persistent volume %s of claim %s: %v", pvc.Spec.VolumeName, claimName, err)
+	}
+	share, ok := azureFileShareFromPV(pv)
+	if !ok {
+		return aci.Volume{}, errdefs.InvalidInputf("persistent volume %s of claim %s is not an Azure Files volume, which is the only kind ACI can mount", pv.Name, claimName)
+	}
+	if share.share == "" {
+		return aci.Volume{}, fmt.Errorf("persistent volume %s has no share name", pv.Name)
+	}
+	if share.secretNamespace == "" {
+		share.secretNamespace = pod.Namespace
+	}
+
+	secret, err := p.kubeClient.CoreV1().Secrets(share.secretNamespace).Get(ctx, share.secretName, metav1.GetOptions{})
+	if err != nil {
+		return aci.Volume{}, fmt.Errorf("unable to get secret %s/%s of persistent volume %s: %v", share.secretNamespace, share.secretName, pv.Name, err)
+	}
+	account := string(secret.Data["azurestorageaccountname"])
+	if account == "" {
+		account = share.account
+	}
+
+	return aci.Volume{
+		Name: v.Name,
+		AzureFile: &aci.AzureFileVolume{
+			ShareName:          share.share,
+			ReadOnly:           v.PersistentVolumeClaim.ReadOnly || share.readOnly || isReadOnlyPV(pv),
+			StorageAccountName: account,
+			StorageAccountKey:  string(secret.Data["azurestorageaccountkey"]),
+		},
+	}, nil
+}
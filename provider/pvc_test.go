@@ -0,0 +1,123 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/virtual-kubelet/virtual-kubelet/errdefs"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func pvcVolume(name string, readOnly bool) v1.Volume {
+	return v1.Volume{
+		Name: name,
+		VolumeSource: v1.VolumeSource{
+			PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{ClaimName: name, ReadOnly: readOnly},
+		},
+	}
+}
+
+func boundClaim(name, volumeName string) *v1.PersistentVolumeClaim {
+	return &v1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns"},
+		Spec:       v1.PersistentVolumeClaimSpec{VolumeName: volumeName},
+		Status:     v1.PersistentVolumeClaimStatus{Phase: v1.ClaimBound},
+	}
+}
+
+func TestGetPVCVolume(t *testing.T) {
+	kubeClient := fake.NewSimpleClientset(
+		boundClaim("data", "pv-data"),
+		boundClaim("shared", "pv-shared"),
+		boundClaim("disk", "pv-disk"),
+		&v1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: "pending", Namespace: "ns"}},
+		&v1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: "pv-data"},
+			Spec: v1.PersistentVolumeSpec{
+				AccessModes: []v1.PersistentVolumeAccessMode{v1.ReadWriteMany},
+				PersistentVolumeSource: v1.PersistentVolumeSource{CSI: &v1.CSIPersistentVolumeSource{
+					Driver:             azureFileCSIDriver,
+					VolumeHandle:       "rg#account1#share1#",
+					NodeStageSecretRef: &v1.SecretReference{Name: "creds", Namespace: "ns"},
+				}},
+			},
+		},
+		&v1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: "pv-shared"},
+			Spec: v1.PersistentVolumeSpec{
+				AccessModes: []v1.PersistentVolumeAccessMode{v1.ReadOnlyMany},
+				PersistentVolumeSource: v1.PersistentVolumeSource{CSI: &v1.CSIPersistentVolumeSource{
+					Driver:           azureFileCSIDriver,
+					VolumeHandle:     "rg#account1#share1#",
+					VolumeAttributes: map[string]string{"shareName": "share2"},
+				}},
+			},
+		},
+		&v1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: "pv-disk"},
+			Spec: v1.PersistentVolumeSpec{
+				PersistentVolumeSource: v1.PersistentVolumeSource{CSI: &v1.CSIPersistentVolumeSource{Driver: "disk.csi.azure.com"}},
+			},
+		},
+		&v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "creds", Namespace: "ns"},
+			Data:       map[string][]byte{"azurestorageaccountname": []byte("account1"), "azurestorageaccountkey": []byte("key1")},
+		},
+		&v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "azure-storage-account-account1-secret", Namespace: "default"},
+			Data:       map[string][]byte{"azurestorageaccountkey": []byte("key2")},
+		},
+	)
+	p := ACIProvider{kubeClient: kubeClient}
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "ns"}}
+	ctx := context.Background()
+
+	volume, err := p.getPVCVolume(ctx, pod, pvcVolume("data", false))
+	assert.NilError(t, err)
+	assert.Equal(t, volume.Name, "data")
+	assert.Check(t, volume.AzureFile != nil)
+	assert.Check(t, is.Equal(volume.AzureFile.ShareName, "share1"))
+	assert.Check(t, is.Equal(volume.AzureFile.StorageAccountName, "account1"))
+	assert.Check(t, is.Equal(volume.AzureFile.StorageAccountKey, "key1"))
+	assert.Check(t, !volume.AzureFile.ReadOnly)
+
+	volume, err = p.getPVCVolume(ctx, pod, pvcVolume("data", true))
+	assert.NilError(t, err)
+	assert.Check(t, volume.AzureFile.ReadOnly, "A read-only claim volume should be mounted read-only")
+
+	volume, err = p.getPVCVolume(ctx, pod, pvcVolume("shared", false))
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(volume.AzureFile.ShareName, "share2"))
+	assert.Check(t, is.Equal(volume.AzureFile.StorageAccountName, "account1"))
+	assert.Check(t, is.Equal(volume.AzureFile.StorageAccountKey, "key2"))
+	assert.Check(t, volume.AzureFile.ReadOnly, "A ReadOnlyMany persistent volume should be mounted read-only")
+
+	_, err = p.getPVCVolume(ctx, pod, pvcVolume("disk", false))
+	assert.Check(t, errdefs.IsInvalidInput(err))
+
+	_, err = p.getPVCVolume(ctx, pod, pvcVolume("pending", false))
+	assert.ErrorContains(t, err, "not bound")
+
+	_, err = p.getPVCVolume(ctx, pod, pvcVolume("missing", false))
+	assert.ErrorContains(t, err, "unable to get persistent volume claim")
+}
+
+func TestAzureFileShareFromInTreePV(t *testing.T) {
+	namespace := "secrets"
+	share, ok := azureFileShareFromPV(&v1.PersistentVolume{
+		Spec: v1.PersistentVolumeSpec{
+			PersistentVolumeSource: v1.PersistentVolumeSource{AzureFile: &v1.AzureFilePersistentVolumeSource{
+				SecretName:      "creds",
+				ShareName:       "share",
+				ReadOnly:        true,
+				SecretNamespace: &namespace,
+			}},
+		},
+	})
+	assert.Assert(t, ok)
+	assert.Check(t, *share == azureFileShare{share: "share", secretName: "creds", secretNamespace: "secrets", readOnly: true})
+}
@@ -0,0 +1,195 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/virtual-kubelet/azure-aci/client/aci"
+	"github.com/virtual-kubelet/virtual-kubelet/log"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	podStatusReasonQuotaExceeded = "QuotaExceeded"
+
+	quotaRetryInitialBackoff = time.Minute
+	quotaRetryMaxBackoff     = 30 * time.Minute
+	quotaRetryTick           = 15 * time.Second
+)
+
+// quotaWait is a pod whose container group creation exceeded a quota.
+type quotaWait struct {
+	pod       *v1.Pod
+	cg        *aci.ContainerGroup
+	dimension string
+}
+
+// quotaQueue keeps the pods waiting for quota, and when their creation is retried next.
+// The retries back off on an extended schedule while the quota is reached.
+type quotaQueue struct {
+	mu      sync.Mutex
+	pods    map[string]quotaWait
+	backoff time.Duration
+	next    time.Time
+}
+
+func quotaKey(namespace, name string) string {
+	return namespace + "/" + name
+}
+
+// add queues a pod waiting for quota, and backs off the retries.
+func (q *quotaQueue) add(w quotaWait, now time.Time) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.pods == nil {
+		q.pods = make(map[string]quotaWait)
+	}
+	q.pods[quotaKey(w.pod.Namespace, w.pod.Name)] = w
+	q.extend(now)
+}
+
+// extend backs off the retries, doubling the backoff up to quotaRetryMaxBackoff.
+// It must be called with the mutex held.
+func (q *quotaQueue) extend(now time.Time) {
+	if q.backoff == 0 {
+		q.backoff = quotaRetryInitialBackoff
+	} else if q.backoff *= 2; q.backoff > quotaRetryMaxBackoff {
+		q.backoff = quotaRetryMaxBackoff
+	}
+	q.next = now.Add(q.backoff)
+}
+
+// backOff backs off the retries after a retry exceeded the quota again.
+func (q *quotaQueue) backOff(now time.Time) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.extend(now)
+}
+
+// due returns the pods waiting for quota if they are due for a retry at now.
+func (q *quotaQueue) due(now time.Time) []quotaWait {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.pods) == 0 || now.Before(q.next) {
+		return nil
+	}
+
+	waits := make([]quotaWait, 0, len(q.pods))
+	for _, w := range q.pods {
+		waits = append(waits, w)
+	}
+	return waits
+}
+
+// remove stops waiting for quota for a pod, once created or deleted. The backoff is reset
+// once no pod waits anymore.
+func (q *quotaQueue) remove(namespace, name string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	delete(q.pods, quotaKey(namespace, name))
+	if len(q.pods) == 0 {
+		q.backoff = 0
+	}
+}
+
+// waitForQuota queues the creation of a pod which exceeded a quota, reporting it with a
+// QuotaExceeded reason and condition on the pod and an event naming the quota dimension.
+func (p *ACIProvider) waitForQuota(ctx context.Context, pod *v1.Pod, cg *aci.ContainerGroup, err error) {
+	dimension := aci.QuotaDimension(err)
+	message := fmt.Sprintf("Creating the container group exceeds the %s quota, retrying once there is headroom: %v", dimension, err)
+
+	log.G(ctx).WithField("pod", pod.Name).WithField("dimension", dimension).Warn(message)
+	p.recordEvent(pod, v1.EventTypeWarning, podStatusReasonQuotaExceeded, "%s", message)
+	p.quotaWaits.add(quotaWait{pod: pod, cg: cg, dimension: dimension}, time.Now())
+
+	if p.tracker == nil {
+		return
+	}
+	updateErr := p.tracker.UpdatePodStatus(pod.Namespace, pod.Name, func(status *v1.PodStatus) {
+		status.Phase = v1.PodPending
+		status.Reason = podStatusReasonQuotaExceeded
+		status.Message = message
+		status.Conditions = []v1.PodCondition{{
+			Type:               v1.PodScheduled,
+			Status:             v1.ConditionFalse,
+			LastTransitionTime: metav1.Now(),
+			Reason:             podStatusReasonQuotaExceeded,
+			Message:            message,
+		}}
+	}, false)
+	if updateErr != nil {
+		log.G(ctx).WithError(updateErr).Errorf("failed to update the status of pod %v waiting for quota", pod.Name)
+	}
+}
+
+// retryQuotaLoop retries the creation of the pods waiting for quota until the context is done.
+func (p *ACIProvider) retryQuotaLoop(ctx context.Context) {
+	ticker := time.NewTicker(quotaRetryTick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.retryQuota(ctx, time.Now())
+		}
+	}
+}
+
+// retryQuota retries the creation of the pods waiting for quota if they are due, and the usages
+// of their quota dimensions show headroom.
+func (p *ACIProvider) retryQuota(ctx context.Context, now time.Time) {
+	waits := p.quotaWaits.due(now)
+	if len(waits) == 0 {
+		return
+	}
+
+	usages, err := p.aciClient.ListUsages(ctx, p.region)
+	if err != nil {
+		log.G(ctx).WithError(err).Warn("failed to list the ACI usages, retrying the pods waiting for quota")
+	}
+
+	for _, w := range waits {
+		if usages != nil && !hasQuotaHeadroom(usages.Value, w.dimension) {
+			log.G(ctx).Infof("no headroom in the %s quota yet, backing off", w.dimension)
+			p.quotaWaits.backOff(now)
+			return
+		}
+
+		// The pod keeps its budget while it waits for quota, whatever the outcome of the retry.
+		if _, err := p.reserveNamespaceBudget(ctx, w.pod, w.cg); err != nil {
+			log.G(ctx).WithError(err).Errorf("failed to reserve the budget of pod %v waiting for quota", w.pod.Name)
+			continue
+		}
+		err := p.createContainerGroup(ctx, w.pod, w.cg)
+		if aci.IsQuotaExceeded(err) {
+			p.quotaWaits.backOff(now)
+			return
+		}
+		if err != nil {
+			log.G(ctx).WithError(err).Errorf("failed to create container group of pod %v waiting for quota", w.pod.Name)
+			continue
+		}
+		p.quotaWaits.remove(w.pod.Namespace, w.pod.Name)
+	}
+}
+
+// hasQuotaHeadroom reports whether the usage of the quota dimension is below its limit.
+// A dimension without usage is assumed to have headroom.
+func hasQuotaHeadroom(usages []aci.Usage, dimension string) bool {
+	for _, u := range usages {
+		if strings.EqualFold(u.Name.Value, dimension) {
+			return u.CurrentValue < u.Limit
+		}
+	}
+	return true
+}
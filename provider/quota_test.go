@@ -0,0 +1,75 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/virtual-kubelet/azure-aci/client/aci"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCreatePodWaitsForQuota(t *testing.T) {
+	_, aciServerMocker, provider, err := prepareMocks()
+	if err != nil {
+		t.Fatal("Unable to prepare the mocks", err)
+	}
+
+	podName := "pod-" + uuid.New().String()
+	podNamespace := "ns-" + uuid.New().String()
+
+	quotaReached := true
+	aciServerMocker.OnCreate = func(subscription, resourceGroup, containerGroup string, cg *aci.ContainerGroup) (int, interface{}) {
+		if quotaReached {
+			return http.StatusConflict, map[string]interface{}{
+				"error": map[string]string{
+					"code":    "ContainerGroupQuotaReached",
+					"message": "Resource type 'Microsoft.ContainerInstance/containerGroups' has reached its quota limit 'ContainerGroups' of '100' in region 'westus'.",
+				},
+			}
+		}
+		return http.StatusOK, cg
+	}
+
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      podName,
+			Namespace: podNamespace,
+		},
+		Spec: v1.PodSpec{
+			Containers: []v1.Container{
+				v1.Container{
+					Name: "nginx",
+				},
+			},
+		},
+	}
+
+	if err := provider.CreatePod(context.Background(), pod); err != nil {
+		t.Fatal("Pods exceeding the quota should wait for it", err)
+	}
+	waits := provider.quotaWaits.due(time.Now().Add(time.Hour))
+	assert.Assert(t, is.Len(waits, 1))
+	assert.Check(t, is.Equal("ContainerGroups", waits[0].dimension))
+	assert.Check(t, is.Len(provider.quotaWaits.due(time.Now()), 0), "Retries should back off")
+
+	provider.retryQuota(context.Background(), time.Now().Add(time.Hour))
+	assert.Check(t, is.Len(provider.quotaWaits.due(time.Now().Add(24*time.Hour)), 1), "Pods should keep waiting while the quota is reached")
+	assert.Check(t, is.Equal(2*quotaRetryInitialBackoff, provider.quotaWaits.backoff))
+
+	quotaReached = false
+	provider.retryQuota(context.Background(), time.Now().Add(2*time.Hour))
+	assert.Check(t, is.Len(provider.quotaWaits.due(time.Now().Add(24*time.Hour)), 0), "Pods should be created once there is headroom")
+}
+
+func TestHasQuotaHeadroom(t *testing.T) {
+	usages := []aci.Usage{{CurrentValue: 100, Limit: 100, Name: aci.UsageName{Value: "ContainerGroups"}}}
+
+	assert.Check(t, !hasQuotaHeadroom(usages, "containerGroups"), "Reached quota should have no headroom")
+	assert.Check(t, hasQuotaHeadroom(usages, "StandardCores"), "Unknown quota should have headroom")
+}
@@ -0,0 +1,172 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/virtual-kubelet/azure-aci/client/aci"
+	"github.com/virtual-kubelet/virtual-kubelet/log"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	defaultARMUnhealthyAfter  = 3 * time.Minute
+	defaultARMHealthyAfter    = time.Minute
+	defaultNodeStatusInterval = 10 * time.Second
+
+	// armProbeInterval is how long ARM can go without requests before a ping probes it.
+	armProbeInterval = time.Minute
+)
+
+// armReadiness is whether the node is ready from the outcome of the requests to ARM. The node turns
+// not ready once requests failed and none succeeded for unhealthyAfter, and ready again once requests
+// succeeded and none failed for healthyAfter, so a flapping ARM does not flap the node.
+type armReadiness struct {
+	mu             sync.Mutex
+	unhealthyAfter time.Duration
+	healthyAfter   time.Duration
+	started        time.Time
+	notReady       bool
+	since          time.Time
+	lastError      string
+}
+
+// update updates the readiness from the health of ARM at now, and returns whether the node is ready.
+func (r *armReadiness) update(h aci.ARMHealth, now time.Time) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.unhealthyAfter <= 0 {
+		return true
+	}
+	if r.started.IsZero() {
+		r.started = now
+	}
+
+	if !r.notReady {
+		lastSuccess := h.LastSuccess
+		if lastSuccess.IsZero() {
+			lastSuccess = r.started
+		}
+		if h.LastFailure.After(lastSuccess) && now.Sub(lastSuccess) >= r.unhealthyAfter {
+			r.notReady = true
+			r.since = now
+			r.lastError = h.LastError
+		}
+	} else {
+		if h.LastSuccess.After(h.LastFailure) && now.Sub(h.LastFailure) >= r.healthyAfter {
+			r.notReady = false
+			r.since = now
+		} else if h.LastError != "" {
+			r.lastError = h.LastError
+		}
+	}
+
+	return !r.notReady
+}
+
+func (r *armReadiness) condition(now time.Time) v1.NodeCondition {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	transition := r.since
+	if transition.IsZero() {
+		transition = now
+	}
+	condition := v1.NodeCondition{
+		Type:               v1.NodeReady,
+		Status:             v1.ConditionTrue,
+		LastHeartbeatTime:  metav1.NewTime(now),
+		LastTransitionTime: metav1.NewTime(transition),
+		Reason:             "KubeletReady",
+		Message:            "kubelet is ready.",
+	}
+	if r.notReady {
+		condition.Status = v1.ConditionFalse
+		condition.Reason = "ARMUnreachable"
+		condition.Message = fmt.Sprintf("requests to Azure Resource Manager fail since %s: %s", r.since.Format(time.RFC3339), r.lastError)
+	}
+	return condition
+}
+
+// setupReadiness validates the durations after which the node turns not ready and ready again, the
+// node status interval and the renewal of the node lease, from the config file or the ACI_ARM_UNHEALTHY_AFTER,
+// ACI_ARM_HEALTHY_AFTER, ACI_NODE_STATUS_INTERVAL, ACI_NODE_HEARTBEAT_INTERVAL, ACI_NODE_LEASE_RENEW_INTERVAL
+// and ACI_NODE_LEASE_DURATION environment variables.
+// An unhealthy duration of 0 keeps the node ready whatever the outcome of the requests to ARM.
+func (p *ACIProvider) setupReadiness() error {
+	p.readiness.unhealthyAfter = defaultARMUnhealthyAfter
+	p.readiness.healthyAfter = defaultARMHealthyAfter
+	p.nodeStatusInterval = defaultNodeStatusInterval
+
+	durations := []struct {
+		env      string
+		config   string
+		value    *time.Duration
+		positive bool
+	}{
+		{"ACI_ARM_UNHEALTHY_AFTER", p.armUnhealthyAfter, &p.readiness.unhealthyAfter, false},
+		{"ACI_ARM_HEALTHY_AFTER", p.armHealthyAfter, &p.readiness.healthyAfter, false},
+		{"ACI_NODE_STATUS_INTERVAL", p.statusInterval, &p.nodeStatusInterval, true},
+		{"ACI_NODE_HEARTBEAT_INTERVAL", p.heartbeat, &p.heartbeatInterval, false},
+		{"ACI_NODE_LEASE_RENEW_INTERVAL", p.leaseRenew, &p.leaseRenewInterval, false},
+		{"ACI_NODE_LEASE_DURATION", p.leaseDuration, &p.nodeLeaseDuration, false},
+	}
+	for _, d := range durations {
+		v := d.config
+		if env := os.Getenv(d.env); env != "" {
+			v = env
+		}
+		if v == "" {
+			continue
+		}
+		parsed, err := time.ParseDuration(v)
+		if err != nil || parsed < 0 || (d.positive && parsed == 0) {
+			return fmt.Errorf("invalid %s %q, expected a positive duration", d.env, v)
+		}
+		*d.value = parsed
+	}
+
+	if p.nodeLeaseDuration == 0 {
+		p.nodeLeaseDuration = nodeLeaseDurationFactor * p.leaseRenewInterval
+	}
+	if p.leaseRenewInterval > 0 && p.nodeLeaseDuration <= p.leaseRenewInterval {
+		return fmt.Errorf("invalid ACI_NODE_LEASE_DURATION %s, expected a duration longer than the lease renew interval %s", p.nodeLeaseDuration, p.leaseRenewInterval)
+	}
+
+	return nil
+}
+
+// readyCondition returns the Ready condition of the node, from the outcome of the last requests to ARM.
+func (p *ACIProvider) readyCondition() v1.NodeCondition {
+	now := time.Now()
+	if p.aciClient != nil {
+		p.readiness.update(p.aciClient.ARMHealth(), now)
+	}
+	return p.readiness.condition(now)
+}
+
+// probeARM sends a request to ARM when none was sent for armProbeInterval, so the readiness of an idle
+// node follows whether its credential and ARM work.
+func (p *ACIProvider) probeARM(ctx context.Context) {
+	if p.aciClient == nil || p.readiness.unhealthyAfter <= 0 {
+		return
+	}
+
+	h := p.aciClient.ARMHealth()
+	last := h.LastSuccess
+	if h.LastFailure.After(last) {
+		last = h.LastFailure
+	}
+	if time.Since(last) < armProbeInterval {
+		return
+	}
+
+	if _, err := p.aciClient.GetResourceProviderMetadata(ctx); err != nil {
+		log.G(ctx).WithError(err).Debug("failed to probe Azure Resource Manager")
+	}
+}
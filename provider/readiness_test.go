@@ -0,0 +1,39 @@
+package provider
+
+import (
+	"testing"
+	"time"
+
+	"github.com/virtual-kubelet/azure-aci/client/aci"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestARMReadiness(t *testing.T) {
+	r := armReadiness{unhealthyAfter: 3 * time.Minute, healthyAfter: time.Minute}
+	start := time.Now()
+
+	assert.Check(t, r.update(aci.ARMHealth{}, start), "Node should be ready before any request")
+
+	failing := aci.ARMHealth{LastSuccess: start, LastFailure: start.Add(time.Minute), LastError: "token refresh failed"}
+	assert.Check(t, r.update(failing, start.Add(2*time.Minute)), "Node should stay ready before the unhealthy duration")
+	assert.Check(t, !r.update(failing, start.Add(3*time.Minute)), "Node should not be ready once requests failed for the unhealthy duration")
+
+	condition := r.condition(start.Add(3 * time.Minute))
+	assert.Check(t, is.Equal(v1.ConditionFalse, condition.Status))
+	assert.Check(t, is.Contains(condition.Message, "token refresh failed"))
+
+	recovering := aci.ARMHealth{LastSuccess: start.Add(3*time.Minute + 30*time.Second), LastFailure: start.Add(3 * time.Minute)}
+	assert.Check(t, !r.update(recovering, start.Add(3*time.Minute+30*time.Second)), "Node should stay not ready before the healthy duration")
+	assert.Check(t, r.update(recovering, start.Add(4*time.Minute)), "Node should be ready once requests succeeded for the healthy duration")
+	assert.Check(t, is.Equal(v1.ConditionTrue, r.condition(start.Add(4*time.Minute)).Status))
+}
+
+func TestARMReadinessDisabled(t *testing.T) {
+	var r armReadiness
+	now := time.Now()
+
+	failing := aci.ARMHealth{LastFailure: now, LastError: "forbidden"}
+	assert.Check(t, r.update(failing, now.Add(time.Hour)), "Node should always be ready without an unhealthy duration")
+}
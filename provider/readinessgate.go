@@ -0,0 +1,43 @@
+package provider
+
+import (
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// podConditionRoutable is the condition of the pods whose container group has a routable IP and
+	// whose containers are ready. Pods list it in their readinessGates, so the controllers adding pods
+	// to the backends of an external load balancer only add them once they can take traffic.
+	podConditionRoutable v1.PodConditionType = "virtual-kubelet.io/aci-routable"
+
+	podConditionReasonNoIP     = "NoIPAddress"
+	podConditionReasonNotReady = "ContainersNotReady"
+)
+
+// routableCondition returns the routable condition of a container group in the ACI state, with the IP
+// of the container group. It is only published along with the ready condition, once the container
+// group runs.
+func routableCondition(state, ip string, creationTime, lastUpdateTime metav1.Time, allReady bool) (v1.PodCondition, bool) {
+	if state != "Running" && state != "Succeeded" {
+		return v1.PodCondition{}, false
+	}
+
+	condition := v1.PodCondition{
+		Type:               podConditionRoutable,
+		Status:             v1.ConditionFalse,
+		LastTransitionTime: creationTime,
+	}
+	switch {
+	case ip == "":
+		condition.Reason = podConditionReasonNoIP
+		condition.Message = "The container group has no IP address"
+	case state != "Running" || !allReady:
+		condition.Reason = podConditionReasonNotReady
+		condition.Message = "The containers of the container group are not ready"
+	default:
+		condition.Status = v1.ConditionTrue
+		condition.LastTransitionTime = lastUpdateTime
+	}
+	return condition, true
+}
@@ -0,0 +1,35 @@
+package provider
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRoutableCondition(t *testing.T) {
+	created := metav1.NewTime(time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC))
+	started := metav1.NewTime(created.Add(time.Minute))
+
+	_, ok := routableCondition("Creating", "", created, started, false)
+	assert.Check(t, !ok, "The condition should only be published once the container group runs")
+
+	condition, ok := routableCondition("Running", "", created, started, true)
+	assert.Assert(t, ok)
+	assert.Equal(t, condition.Status, v1.ConditionFalse)
+	assert.Equal(t, condition.Reason, podConditionReasonNoIP)
+
+	condition, _ = routableCondition("Running", "10.0.0.4", created, started, false)
+	assert.Equal(t, condition.Status, v1.ConditionFalse)
+	assert.Equal(t, condition.Reason, podConditionReasonNotReady)
+
+	condition, _ = routableCondition("Running", "10.0.0.4", created, started, true)
+	assert.Equal(t, condition.Type, podConditionRoutable)
+	assert.Equal(t, condition.Status, v1.ConditionTrue)
+	assert.Equal(t, condition.LastTransitionTime, started)
+
+	condition, _ = routableCondition("Succeeded", "10.0.0.4", created, started, true)
+	assert.Equal(t, condition.Status, v1.ConditionFalse, "A completed container group should not take traffic")
+}
@@ -0,0 +1,161 @@
+package provider
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/virtual-kubelet/azure-aci/client/aci"
+	"github.com/virtual-kubelet/virtual-kubelet/log"
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	// recordARMAnnotation set to "true" records the requests to ARM for the container group of a pod and their
+	// responses, sanitized, in a bundle of the recording directory.
+	recordARMAnnotation = "virtual-kubelet.io/record-arm"
+
+	// maxRecordingSize is the size a bundle stops growing at.
+	maxRecordingSize = 16 << 20
+
+	eventReasonRecording = "RecordingARM"
+)
+
+// armRecordings are the pods whose requests to ARM are recorded, by container group name. The lines of the
+// bundles are sealed with the cipher if not nil.
+type armRecordings struct {
+	dir    string
+	cipher *stateCipher
+
+	mu   sync.Mutex
+	pods map[string]*armRecording
+}
+
+// armRecording is the bundle of the exchanges with ARM of a pod, one JSON exchange per line.
+type armRecording struct {
+	pod  string
+	path string
+	full bool
+}
+
+// recordedExchange is an exchange with ARM in a bundle.
+type recordedExchange struct {
+	Pod string `json:"pod"`
+	aci.Exchange
+}
+
+// setupRecording records the requests to ARM of the pods with the record-arm annotation in RecordingDir in the
+// config file, or ACI_RECORDING_DIR, so the exact exchanges with ARM can be attached to a bug report without
+// sharing credentials. The bundles are encrypted with the state key if any. Without a directory, the annotation
+// is ignored.
+func (p *ACIProvider) setupRecording() error {
+	if v := os.Getenv("ACI_RECORDING_DIR"); v != "" {
+		p.recordingDir = v
+	}
+	if p.recordingDir == "" {
+		return nil
+	}
+
+	if err := os.MkdirAll(p.recordingDir, 0700); err != nil {
+		return fmt.Errorf("invalid ACI_RECORDING_DIR %q: %v", p.recordingDir, err)
+	}
+	p.recordings = &armRecordings{dir: p.recordingDir, cipher: p.stateCipher, pods: make(map[string]*armRecording)}
+	p.aciClient.EnableRecording(p.recordings.recorder)
+	return nil
+}
+
+// trackRecording starts or stops recording the requests to ARM for the pod, as its annotation asks.
+func (p *ACIProvider) trackRecording(pod *v1.Pod) {
+	if path := p.recordings.track(pod); path != "" {
+		p.recordEvent(pod, v1.EventTypeNormal, eventReasonRecording, "Recording the requests to ARM for the pod in %s", path)
+	}
+}
+
+// track starts or stops recording the requests for the pod with its annotation. It returns the path of the
+// bundle when the recording starts.
+func (r *armRecordings) track(pod *v1.Pod) string {
+	if r == nil {
+		return ""
+	}
+	name := containerGroupName(pod.Namespace, pod.Name)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if pod.Annotations[recordARMAnnotation] != "true" {
+		delete(r.pods, name)
+		return ""
+	}
+	if _, ok := r.pods[name]; ok {
+		return ""
+	}
+	rec := &armRecording{
+		pod:  pod.Namespace + "/" + pod.Name,
+		path: filepath.Join(r.dir, pod.Namespace+"_"+pod.Name+".jsonl"),
+	}
+	r.pods[name] = rec
+	return rec.path
+}
+
+// remove stops recording the requests for a pod, once its container group is deleted.
+func (r *armRecordings) remove(podNS, podName string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.pods, containerGroupName(podNS, podName))
+}
+
+// recorder returns the func appending the exchanges of a container group to the bundle of its pod, if recorded.
+func (r *armRecordings) recorder(containerGroupName string) func(aci.Exchange) {
+	r.mu.Lock()
+	rec, ok := r.pods[containerGroupName]
+	r.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	return func(e aci.Exchange) {
+		if err := r.append(rec, e); err != nil {
+			log.L.WithError(err).WithField("pod", rec.pod).Warn("failed to record a request to ARM")
+		}
+	}
+}
+
+func (r *armRecordings) append(rec *armRecording, e aci.Exchange) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(recordedExchange{Pod: rec.pod, Exchange: e}); err != nil {
+		return err
+	}
+	line := buf.Bytes()
+	if r.cipher != nil {
+		sealed, err := r.cipher.sealLine(bytes.TrimSuffix(line, []byte("\n")))
+		if err != nil {
+			return err
+		}
+		line = append(sealed, '\n')
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if rec.full {
+		return nil
+	}
+	f, err := os.OpenFile(rec.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if fi, err := f.Stat(); err != nil {
+		return err
+	} else if fi.Size()+int64(len(line)) > maxRecordingSize {
+		rec.full = true
+		return fmt.Errorf("the recording %s reached %d bytes, the next requests are not recorded", rec.path, maxRecordingSize)
+	}
+	_, err = f.Write(line)
+	return err
+}
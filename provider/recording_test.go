@@ -0,0 +1,92 @@
+package provider
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/virtual-kubelet/azure-aci/client/aci"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestARMRecordings(t *testing.T) {
+	dir, err := ioutil.TempDir("", "recordings")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+
+	r := &armRecordings{dir: dir, pods: make(map[string]*armRecording)}
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Namespace:   "ns",
+		Name:        "pod",
+		Annotations: map[string]string{recordARMAnnotation: "true"},
+	}}
+	path := r.track(pod)
+	assert.Check(t, is.Equal(filepath.Join(dir, "ns_pod.jsonl"), path))
+	assert.Check(t, is.Equal("", r.track(pod)), "expected a pod already recorded to keep its recording")
+	assert.Check(t, r.recorder("ns-other") == nil)
+
+	observe := r.recorder("ns-pod")
+	assert.Assert(t, observe != nil)
+	observe(aci.Exchange{Method: "PUT", URL: "https://management.azure.com/containerGroups/ns-pod", StatusCode: 201})
+	observe(aci.Exchange{Method: "GET", URL: "https://management.azure.com/containerGroups/ns-pod", StatusCode: 200})
+
+	b, err := ioutil.ReadFile(path)
+	assert.NilError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	assert.Assert(t, is.Len(lines, 2))
+	var e recordedExchange
+	assert.NilError(t, json.Unmarshal([]byte(lines[0]), &e))
+	assert.Check(t, is.Equal("ns/pod", e.Pod))
+	assert.Check(t, is.Equal("PUT", e.Method))
+	assert.Check(t, is.Equal(201, e.StatusCode))
+
+	delete(pod.Annotations, recordARMAnnotation)
+	r.track(pod)
+	assert.Check(t, r.recorder("ns-pod") == nil, "expected the recording to stop without the annotation")
+
+	pod.Annotations[recordARMAnnotation] = "true"
+	r.track(pod)
+	r.remove("ns", "pod")
+	assert.Check(t, r.recorder("ns-pod") == nil, "expected the recording to stop once the container group is deleted")
+
+	var disabled *armRecordings
+	assert.Check(t, is.Equal("", disabled.track(pod)))
+}
+
+func TestARMRecordingsEncrypted(t *testing.T) {
+	dir, err := ioutil.TempDir("", "recordings")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+
+	c, err := newStateCipher(bytes.Repeat([]byte{7}, stateKeySize))
+	assert.NilError(t, err)
+	r := &armRecordings{dir: dir, cipher: c, pods: make(map[string]*armRecording)}
+	path := r.track(&v1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Namespace:   "ns",
+		Name:        "pod",
+		Annotations: map[string]string{recordARMAnnotation: "true"},
+	}})
+	observe := r.recorder("ns-pod")
+	assert.Assert(t, observe != nil)
+	observe(aci.Exchange{Method: "PUT", URL: "https://management.azure.com/containerGroups/ns-pod", StatusCode: 201})
+	observe(aci.Exchange{Method: "GET", URL: "https://management.azure.com/containerGroups/ns-pod", StatusCode: 200})
+
+	b, err := ioutil.ReadFile(path)
+	assert.NilError(t, err)
+	assert.Check(t, !bytes.Contains(b, []byte("containerGroups")), "The bundle should be encrypted")
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	assert.Assert(t, is.Len(lines, 2))
+	line, err := c.openLine([]byte(lines[1]))
+	assert.NilError(t, err)
+	var e recordedExchange
+	assert.NilError(t, json.Unmarshal(line, &e))
+	assert.Check(t, is.Equal("ns/pod", e.Pod))
+	assert.Check(t, is.Equal("GET", e.Method))
+}
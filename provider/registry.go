@@ -0,0 +1,77 @@
+package provider
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+const defaultRegistry = "docker.io"
+
+// splitImageRegistry splits an image reference into its registry and the remaining repository path,
+// following the docker normalization rules: images without a registry come from docker.io and
+// official images live under library/.
+func splitImageRegistry(image string) (string, string) {
+	i := strings.Index(image, "/")
+	if i < 0 {
+		return defaultRegistry, "library/" + image
+	}
+
+	host := image[:i]
+	if !strings.ContainsAny(host, ".:") && host != "localhost" {
+		return defaultRegistry, image
+	}
+	if host == "index.docker.io" || host == "registry-1.docker.io" {
+		host = defaultRegistry
+	}
+
+	return host, image[i+1:]
+}
+
+// mirrorImage returns the image pulled from the configured mirror of its registry, if any.
+func (p *ACIProvider) mirrorImage(image string) string {
+	if len(p.registryMirrors) == 0 {
+		return image
+	}
+
+	registry, path := splitImageRegistry(image)
+	mirror, ok := p.registryMirrors[registry]
+	if !ok {
+		return image
+	}
+
+	return strings.TrimSuffix(mirror, "/") + "/" + path
+}
+
+// setupRegistryMirrors reads the registry mirrors from ACI_REGISTRY_MIRRORS, a comma
+// separated list of registry=mirror pairs. It overrides the mirrors from the config file.
+func (p *ACIProvider) setupRegistryMirrors() error {
+	mirrors := os.Getenv("ACI_REGISTRY_MIRRORS")
+	if mirrors == "" {
+		return validateRegistryMirrors(p.registryMirrors)
+	}
+
+	p.registryMirrors = make(map[string]string)
+	for _, pair := range strings.Split(mirrors, ",") {
+		kv := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+		if len(kv) != 2 {
+			return fmt.Errorf("invalid registry mirror %q in ACI_REGISTRY_MIRRORS, expected registry=mirror", pair)
+		}
+		p.registryMirrors[kv[0]] = kv[1]
+	}
+
+	return validateRegistryMirrors(p.registryMirrors)
+}
+
+func validateRegistryMirrors(mirrors map[string]string) error {
+	for registry, mirror := range mirrors {
+		if registry == "" || mirror == "" {
+			return fmt.Errorf("invalid registry mirror %q=%q, both the registry and the mirror are required", registry, mirror)
+		}
+		if strings.Contains(registry, "://") || strings.Contains(mirror, "://") {
+			return fmt.Errorf("invalid registry mirror %q=%q, registries must not include a scheme", registry, mirror)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,32 @@
+package provider
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func TestMirrorImage(t *testing.T) {
+	p := ACIProvider{
+		registryMirrors: map[string]string{
+			"docker.io": "mirror.azurecr.io/dockerhub/",
+			"quay.io":   "quay-mirror.internal:5000",
+		},
+	}
+
+	cases := map[string]string{
+		"nginx":                                 "mirror.azurecr.io/dockerhub/library/nginx",
+		"nginx:1.19":                            "mirror.azurecr.io/dockerhub/library/nginx:1.19",
+		"bitnami/redis":                         "mirror.azurecr.io/dockerhub/bitnami/redis",
+		"docker.io/library/busybox":             "mirror.azurecr.io/dockerhub/library/busybox",
+		"quay.io/coreos/etcd:v3.4":              "quay-mirror.internal:5000/coreos/etcd:v3.4",
+		"myregistry.azurecr.io/app:v1":          "myregistry.azurecr.io/app:v1",
+		"localhost/app":                         "localhost/app",
+		"registry.local:5000/team/app@sha256:0": "registry.local:5000/team/app@sha256:0",
+	}
+
+	for image, wanted := range cases {
+		assert.Check(t, is.Equal(wanted, p.mirrorImage(image)), "Mirrored image of %s is not expected", image)
+	}
+}
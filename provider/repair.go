@@ -0,0 +1,104 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/virtual-kubelet/virtual-kubelet/log"
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	podStatusReasonProvisioningFailed = "ProvisioningFailed"
+	eventReasonRepairing              = "Repairing"
+
+	defaultMaxRepairs = 3
+)
+
+// repairCounter counts the repairs of the container group of each pod.
+type repairCounter struct {
+	mu      sync.Mutex
+	repairs map[string]int
+}
+
+// next counts a repair of the pod, and returns its number.
+func (rc *repairCounter) next(namespace, name string) int {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	if rc.repairs == nil {
+		rc.repairs = make(map[string]int)
+	}
+	key := namespace + "/" + name
+	rc.repairs[key]++
+	return rc.repairs[key]
+}
+
+// remove forgets the repairs of the pod, when it is deleted.
+func (rc *repairCounter) remove(namespace, name string) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	delete(rc.repairs, namespace+"/"+name)
+}
+
+// setupRepairs validates the number of repairs of a container group, from the ACI_MAX_REPAIRS environment variable.
+// A maximum of 0 disables the repairs.
+func (p *ACIProvider) setupRepairs() error {
+	p.maxRepairs = defaultMaxRepairs
+	if v := os.Getenv("ACI_MAX_REPAIRS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid ACI_MAX_REPAIRS %q, expected a positive number of repairs", v)
+		}
+		p.maxRepairs = n
+	}
+
+	return nil
+}
+
+// RepairPod re-creates the container group of a pod whose provisioning failed, at most maxRepairs times.
+// It returns false once the repairs are exhausted, in which case the pod is failed. The cause of the failure
+// from the Activity Log is recorded on the pod first.
+func (p *ACIProvider) RepairPod(ctx context.Context, pod *v1.Pod) bool {
+	if pod.DeletionTimestamp != nil {
+		return false
+	}
+	p.recordProvisioningFailure(ctx, pod)
+
+	repair := p.repairs.next(pod.Namespace, pod.Name)
+	if repair > p.maxRepairs {
+		return false
+	}
+
+	desired, err := p.getContainerGroupFromPod(ctx, pod)
+	if err != nil {
+		log.G(ctx).WithError(err).Errorf("failed to translate pod %v to repair its container group", pod.Name)
+		return false
+	}
+
+	// The pod keeps its budget while its container group is re-created.
+	cancelBudget, err := p.reserveNamespaceBudget(ctx, pod, desired)
+	if err != nil {
+		log.G(ctx).WithError(err).Errorf("failed to reserve the budget of pod %v to repair its container group", pod.Name)
+		return true
+	}
+
+	cgName := containerGroupName(pod.Namespace, pod.Name)
+	log.G(ctx).Warnf("provisioning of container group %v failed, re-creating it (repair %d of %d)", cgName, repair, p.maxRepairs)
+	p.recordEvent(pod, v1.EventTypeWarning, eventReasonRepairing, "Provisioning of container group %s failed, re-creating it (repair %d of %d)", cgName, repair, p.maxRepairs)
+
+	if err := p.aciClient.DeleteContainerGroup(ctx, p.resourceGroup, cgName); err != nil {
+		log.G(ctx).WithError(err).Errorf("failed to delete container group %v for repair", cgName)
+		cancelBudget()
+		return true
+	}
+	if err := p.createContainerGroup(ctx, pod, desired); err != nil {
+		log.G(ctx).WithError(err).Errorf("failed to re-create container group %v for repair", cgName)
+	}
+
+	return true
+}
@@ -0,0 +1,201 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	client "github.com/virtual-kubelet/azure-aci/client"
+	"github.com/virtual-kubelet/azure-aci/client/resourcehealth"
+	"github.com/virtual-kubelet/virtual-kubelet/log"
+	"github.com/virtual-kubelet/virtual-kubelet/trace"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// aciServiceDegradedCondition is true on the node while Azure Resource Health reports an active ACI service issue
+// in the region of the node.
+const aciServiceDegradedCondition v1.NodeConditionType = "ACIServiceDegraded"
+
+// resourceHealthSource reports the health of the ACI service and of the container groups of the node.
+type resourceHealthSource interface {
+	ListActiveServiceIssues(ctx context.Context, service, region string) ([]resourcehealth.Event, error)
+	GetContainerGroupAvailabilityStatus(ctx context.Context, resourceGroup, containerGroupName string) (*resourcehealth.AvailabilityStatus, error)
+}
+
+// serviceHealth is the last known active ACI service issues of the region of the node.
+type serviceHealth struct {
+	mu     sync.Mutex
+	issues []string
+	since  time.Time
+}
+
+// update replaces the active issues, and returns whether they changed.
+func (h *serviceHealth) update(issues []string, now time.Time) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if strings.Join(issues, "\n") == strings.Join(h.issues, "\n") {
+		return false
+	}
+	if (len(issues) == 0) != (len(h.issues) == 0) {
+		h.since = now
+	}
+	h.issues = issues
+	return true
+}
+
+func (h *serviceHealth) key() string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return strings.Join(h.issues, ",")
+}
+
+func (h *serviceHealth) condition(now time.Time) v1.NodeCondition {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	transition := h.since
+	if transition.IsZero() {
+		transition = now
+	}
+	condition := v1.NodeCondition{
+		Type:               aciServiceDegradedCondition,
+		Status:             v1.ConditionFalse,
+		LastHeartbeatTime:  metav1.NewTime(now),
+		LastTransitionTime: metav1.NewTime(transition),
+		Reason:             "ACIServiceHealthy",
+		Message:            "Azure Resource Health reports no ACI service issue in the region",
+	}
+	if len(h.issues) > 0 {
+		condition.Status = v1.ConditionTrue
+		condition.Reason = "ACIServiceIssue"
+		condition.Message = fmt.Sprintf("Azure Resource Health reports ACI service issues in the region: %s", strings.Join(h.issues, "; "))
+	}
+	return condition
+}
+
+// setupResourceHealth enables polling Azure Resource Health every ACI_RESOURCE_HEALTH_INTERVAL.
+func (p *ACIProvider) setupResourceHealth(azAuth *client.Authentication) error {
+	interval := os.Getenv("ACI_RESOURCE_HEALTH_INTERVAL")
+	if interval == "" {
+		return nil
+	}
+
+	d, err := time.ParseDuration(interval)
+	if err != nil || d <= 0 {
+		return fmt.Errorf("invalid ACI_RESOURCE_HEALTH_INTERVAL %q, expected a positive duration", interval)
+	}
+	p.resourceHealthPoll = d
+
+	p.resourceHealth, err = resourcehealth.NewClient(azAuth, p.extraUserAgent)
+	if err != nil {
+		return fmt.Errorf("error setting up resource health: %v", err)
+	}
+	return nil
+}
+
+// resourceHealthLoop periodically checks the health of the ACI service and of the container groups of the
+// pods which are not ready, until the context is done.
+func (p *ACIProvider) resourceHealthLoop(ctx context.Context) {
+	ticker := time.NewTicker(p.resourceHealthPoll)
+	defer ticker.Stop()
+
+	unavailable := make(map[string]string)
+	for {
+		p.checkServiceHealth(ctx)
+		unavailable = p.checkPodsAvailability(ctx, unavailable)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkServiceHealth updates the active ACI service issues of the region, and records an event on the node when
+// they change.
+func (p *ACIProvider) checkServiceHealth(ctx context.Context) {
+	ctx, span := trace.StartSpan(ctx, "aci.checkServiceHealth")
+	defer span.End()
+	ctx = addAzureAttributes(ctx, span, p)
+
+	events, err := p.resourceHealth.ListActiveServiceIssues(ctx, resourcehealth.ContainerInstancesService, p.region)
+	if err != nil {
+		log.G(ctx).WithError(err).Warn("failed to list the ACI service issues from Azure Resource Health")
+		return
+	}
+
+	issues := make([]string, 0, len(events))
+	for _, e := range events {
+		issues = append(issues, e.Properties.Title)
+	}
+	if !p.serviceHealth.update(issues, time.Now()) {
+		return
+	}
+
+	if len(issues) == 0 {
+		log.G(ctx).Info("the ACI service issues of the region are resolved")
+		p.recordNodeEvent(v1.EventTypeNormal, "ACIServiceRestored", "Azure Resource Health reports no ACI service issue in %s", p.region)
+		return
+	}
+	for _, e := range events {
+		log.G(ctx).Warnf("ACI service issue in %s: %s", p.region, e.Properties.Title)
+		p.recordNodeEvent(v1.EventTypeWarning, "ACIServiceIssue", "%s: %s", e.Properties.Title, e.Properties.Summary)
+	}
+}
+
+// serviceHealthCondition returns the condition of the node reporting the active ACI service issues of the region.
+func (p *ACIProvider) serviceHealthCondition() v1.NodeCondition {
+	return p.serviceHealth.condition(time.Now())
+}
+
+// checkPodsAvailability records an event on the pods which are not ready when Azure Resource Health reports their
+// container group unavailable or degraded, and again when it is available again, so a platform outage is not
+// mistaken for a slow pod. It returns the states reported by container group, to compare them with on the next check.
+func (p *ACIProvider) checkPodsAvailability(ctx context.Context, reported map[string]string) map[string]string {
+	ctx, span := trace.StartSpan(ctx, "aci.checkPodsAvailability")
+	defer span.End()
+	ctx = addAzureAttributes(ctx, span, p)
+
+	unavailable := make(map[string]string)
+	for _, pod := range p.resourceManager.GetPods() {
+		if pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed {
+			continue
+		}
+		name := containerGroupName(pod.Namespace, pod.Name)
+		if podReady(pod) && reported[name] == "" {
+			continue
+		}
+
+		status, err := p.resourceHealth.GetContainerGroupAvailabilityStatus(ctx, p.resourceGroup, name)
+		if err != nil {
+			log.G(ctx).WithError(err).WithField("pod", pod.Name).Debug("failed to get the availability of the container group")
+			if previous, ok := reported[name]; ok {
+				unavailable[name] = previous
+			}
+			continue
+		}
+
+		props := status.Properties
+		switch props.AvailabilityState {
+		case resourcehealth.AvailabilityStateUnavailable, resourcehealth.AvailabilityStateDegraded:
+			state := string(props.AvailabilityState) + ": " + props.Summary
+			unavailable[name] = state
+			if reported[name] == state {
+				continue
+			}
+			log.G(ctx).WithField("pod", pod.Name).Warnf("container group is %s", state)
+			p.recordEvent(pod, v1.EventTypeWarning, "Resource"+string(props.AvailabilityState), "Azure Resource Health reports the container group %s: %s", strings.ToLower(string(props.AvailabilityState)), props.Summary)
+		case resourcehealth.AvailabilityStateAvailable:
+			if reported[name] != "" {
+				p.recordEvent(pod, v1.EventTypeNormal, "ResourceAvailable", "Azure Resource Health reports the container group available")
+			}
+		}
+	}
+	return unavailable
+}
@@ -0,0 +1,99 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/virtual-kubelet/azure-aci/client/resourcehealth"
+	"github.com/virtual-kubelet/node-cli/manager"
+	"gotest.tools/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+)
+
+type fakeResourceHealth struct {
+	issues []resourcehealth.Event
+	states map[string]resourcehealth.AvailabilityState
+	gets   int
+}
+
+func (f *fakeResourceHealth) ListActiveServiceIssues(ctx context.Context, service, region string) ([]resourcehealth.Event, error) {
+	return f.issues, nil
+}
+
+func (f *fakeResourceHealth) GetContainerGroupAvailabilityStatus(ctx context.Context, resourceGroup, containerGroupName string) (*resourcehealth.AvailabilityStatus, error) {
+	f.gets++
+	state, ok := f.states[containerGroupName]
+	if !ok {
+		state = resourcehealth.AvailabilityStateAvailable
+	}
+	return &resourcehealth.AvailabilityStatus{Properties: resourcehealth.AvailabilityStatusProperties{
+		AvailabilityState: state,
+		Summary:           "The host of the container group is down",
+	}}, nil
+}
+
+func TestCheckServiceHealth(t *testing.T) {
+	health := &fakeResourceHealth{}
+	recorder := record.NewFakeRecorder(2)
+	p := ACIProvider{region: "eastus", resourceHealth: health, eventRecorder: recorder}
+	ctx := context.Background()
+
+	p.checkServiceHealth(ctx)
+	assert.Equal(t, p.serviceHealthCondition().Status, v1.ConditionFalse)
+	assert.Equal(t, len(recorder.Events), 0)
+
+	health.issues = []resourcehealth.Event{{Properties: resourcehealth.EventProperties{Title: "Container Instances - East US", Summary: "Container group creations fail"}}}
+	p.checkServiceHealth(ctx)
+	condition := p.serviceHealthCondition()
+	assert.Equal(t, condition.Status, v1.ConditionTrue)
+	assert.Equal(t, condition.Reason, "ACIServiceIssue")
+	assert.Equal(t, <-recorder.Events, "Warning ACIServiceIssue Container Instances - East US: Container group creations fail")
+
+	p.checkServiceHealth(ctx)
+	assert.Equal(t, len(recorder.Events), 0, "An issue should only be reported once")
+
+	health.issues = nil
+	p.checkServiceHealth(ctx)
+	assert.Equal(t, p.serviceHealthCondition().Status, v1.ConditionFalse)
+	assert.Equal(t, <-recorder.Events, "Normal ACIServiceRestored Azure Resource Health reports no ACI service issue in eastus")
+}
+
+func TestCheckPodsAvailability(t *testing.T) {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	rm, err := manager.NewResourceManager(corev1listers.NewPodLister(indexer), nil, nil, nil)
+	assert.NilError(t, err)
+	pending := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "ns"},
+		Status:     v1.PodStatus{Phase: v1.PodPending},
+	}
+	ready := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "ns"},
+		Status:     v1.PodStatus{Phase: v1.PodRunning, Conditions: []v1.PodCondition{{Type: v1.PodReady, Status: v1.ConditionTrue}}},
+	}
+	assert.NilError(t, indexer.Add(pending))
+	assert.NilError(t, indexer.Add(ready))
+
+	health := &fakeResourceHealth{states: map[string]resourcehealth.AvailabilityState{
+		"ns-web": resourcehealth.AvailabilityStateUnavailable,
+		"ns-api": resourcehealth.AvailabilityStateUnavailable,
+	}}
+	recorder := record.NewFakeRecorder(2)
+	p := ACIProvider{resourceManager: rm, resourceHealth: health, eventRecorder: recorder}
+	ctx := context.Background()
+
+	reported := p.checkPodsAvailability(ctx, nil)
+	assert.Equal(t, health.gets, 1, "Only the pods which are not ready should be checked")
+	assert.Equal(t, <-recorder.Events, "Warning ResourceUnavailable Azure Resource Health reports the container group unavailable: The host of the container group is down")
+
+	reported = p.checkPodsAvailability(ctx, reported)
+	assert.Equal(t, len(recorder.Events), 0, "An unavailable container group should only be reported once")
+
+	delete(health.states, "ns-web")
+	reported = p.checkPodsAvailability(ctx, reported)
+	assert.Equal(t, <-recorder.Events, "Normal ResourceAvailable Azure Resource Health reports the container group available")
+	assert.Equal(t, len(reported), 0)
+}
@@ -0,0 +1,85 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/virtual-kubelet/azure-aci/client/aci"
+	"github.com/virtual-kubelet/virtual-kubelet/log"
+	v1 "k8s.io/api/core/v1"
+)
+
+// restartRequestedAtAnnotation requests a restart of the containers of the pod, in place, without
+// losing the IP or FQDN of the container group. The value is the RFC3339 time of the request,
+// the restart is only triggered if the containers were started before that time.
+const restartRequestedAtAnnotation = "virtual-kubelet.io/restart-requested-at"
+
+const eventReasonInvalidRestartRequest = "InvalidRestartRequest"
+
+// restartRequestClockSkew is how far in the future a restart request can be, for the clock skew of its client.
+const restartRequestClockSkew = time.Minute
+
+// reconcileRestart restarts the container group when a restart was requested after the containers started.
+// It reports whether the container group was restarted.
+func (p *ACIProvider) reconcileRestart(ctx context.Context, pod *v1.Pod, cg *aci.ContainerGroup) (bool, error) {
+	requestedAt, ok := pod.Annotations[restartRequestedAtAnnotation]
+	if !ok {
+		return false, nil
+	}
+
+	// An invalid request is ignored, so it doesn't block the other updates of the pod. So is a request in the
+	// future, beyond the clock skew, which would restart the containers again on every update until then.
+	t, err := time.Parse(time.RFC3339, requestedAt)
+	if err == nil && t.After(time.Now().Add(restartRequestClockSkew)) {
+		err = fmt.Errorf("the time is in the future")
+	}
+	if err != nil {
+		log.G(ctx).WithError(err).Warnf("ignoring invalid %s annotation %q on pod %s", restartRequestedAtAnnotation, requestedAt, pod.Name)
+		p.recordEvent(pod, v1.EventTypeWarning, eventReasonInvalidRestartRequest, "Ignoring invalid %s annotation %q: %v", restartRequestedAtAnnotation, requestedAt, err)
+		return false, nil
+	}
+
+	if !t.After(lastContainerStartTime(cg)) {
+		return false, nil
+	}
+
+	log.G(ctx).Infof("start restarting pod %v requested at %v", pod.Name, requestedAt)
+	return true, p.restartContainerGroup(ctx, cg)
+}
+
+// restartContainerGroup restarts the containers of the container group in place, keeping their restart counts
+// in its tags first.
+func (p *ACIProvider) restartContainerGroup(ctx context.Context, cg *aci.ContainerGroup) error {
+	// The restart resets the restart counts of the containers, keep them in the tags first.
+	tagged := false
+	if err := p.aciClient.UpdateContainerGroupTags(ctx, p.resourceGroup, cg.Name, withRestartBaseline(cg.Tags, cg, time.Now())); err != nil {
+		log.G(ctx).WithError(err).Warnf("failed to keep the restart counts of container group %v", cg.Name)
+	} else {
+		tagged = true
+	}
+
+	if err := p.aciClient.RestartContainerGroup(ctx, p.resourceGroup, cg.Name); err != nil {
+		log.G(ctx).WithError(err).Errorf("failed to restart container group %v", cg.Name)
+		if tagged {
+			if err := p.aciClient.UpdateContainerGroupTags(ctx, p.resourceGroup, cg.Name, cg.Tags); err != nil {
+				log.G(ctx).WithError(err).Warnf("failed to restore the restart counts of container group %v", cg.Name)
+			}
+		}
+		return err
+	}
+
+	return nil
+}
+
+// lastContainerStartTime returns the most recent start time of the containers of the container group.
+func lastContainerStartTime(cg *aci.ContainerGroup) time.Time {
+	var last time.Time
+	for _, c := range cg.Containers {
+		if start := time.Time(c.InstanceView.CurrentState.StartTime); start.After(last) {
+			last = start
+		}
+	}
+
+	return last
+}
@@ -0,0 +1,198 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/virtual-kubelet/azure-aci/client/aci"
+	"github.com/virtual-kubelet/azure-aci/client/api"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestUpdatePodWithRestartRequest(t *testing.T) {
+	_, aciServerMocker, provider, err := prepareMocks()
+
+	if err != nil {
+		t.Fatal("Unable to prepare the mocks", err)
+	}
+
+	podName := "pod-" + uuid.New().String()
+	podNamespace := "ns-" + uuid.New().String()
+	startTime := time.Now().Add(-time.Hour).UTC()
+
+	aciServerMocker.OnGetContainerGroup = func(subscription, resourceGroup, containerGroup string) (int, interface{}) {
+		return http.StatusOK, aci.ContainerGroup{
+			Name: containerGroup,
+			Tags: map[string]string{
+				"NodeName": fakeNodeName,
+			},
+			ContainerGroupProperties: aci.ContainerGroupProperties{
+				ProvisioningState: "Succeeded",
+				InstanceView: aci.ContainerGroupPropertiesInstanceView{
+					State: "Running",
+				},
+				Containers: []aci.Container{
+					aci.Container{
+						Name: "nginx",
+						ContainerProperties: aci.ContainerProperties{
+							Image: "nginx",
+							Resources: aci.ResourceRequirements{
+								Requests: &aci.ComputeResources{
+									CPU:        1,
+									MemoryInGB: 1.5,
+								},
+							},
+							InstanceView: aci.ContainerPropertiesInstanceView{
+								CurrentState: aci.ContainerState{
+									State:     "Running",
+									StartTime: api.JSONTime(startTime),
+								},
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	restarts := 0
+	aciServerMocker.OnAction = func(subscription, resourceGroup, containerGroup, action string) (int, interface{}) {
+		assert.Check(t, is.Equal(podNamespace+"-"+podName, containerGroup), "Container group name is not expected")
+		assert.Check(t, is.Equal("restart", action), "Action is not expected")
+		restarts++
+		return http.StatusNoContent, nil
+	}
+
+	var tags map[string]string
+	aciServerMocker.OnUpdateTags = func(subscription, resourceGroup, containerGroup string, t map[string]string) (int, interface{}) {
+		tags = t
+		return http.StatusOK, nil
+	}
+
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      podName,
+			Namespace: podNamespace,
+			Annotations: map[string]string{
+				restartRequestedAtAnnotation: startTime.Add(-time.Minute).Format(time.RFC3339),
+			},
+		},
+		Spec: v1.PodSpec{
+			Containers: []v1.Container{
+				v1.Container{
+					Name:  "nginx",
+					Image: "nginx",
+				},
+			},
+		},
+	}
+
+	// The restart was requested before the containers started, nothing to do.
+	if err := provider.UpdatePod(context.Background(), pod); err != nil {
+		t.Fatal("Failed to update pod", err)
+	}
+	assert.Check(t, is.Equal(0, restarts), "No restart is expected")
+
+	pod.Annotations[restartRequestedAtAnnotation] = time.Now().UTC().Format(time.RFC3339)
+	if err := provider.UpdatePod(context.Background(), pod); err != nil {
+		t.Fatal("Failed to update pod", err)
+	}
+	assert.Check(t, is.Equal(1, restarts), "1 restart is expected")
+	assert.Check(t, is.Equal(fakeNodeName, tags["NodeName"]), "The tags of the container group should be kept")
+	assert.Check(t, tags[restartCountsTag] != "", "The restart counts should be kept before the restart")
+}
+
+func restartTestContainerGroup(name string, startTime time.Time) aci.ContainerGroup {
+	return aci.ContainerGroup{
+		Name: name,
+		Tags: map[string]string{
+			"NodeName": fakeNodeName,
+		},
+		ContainerGroupProperties: aci.ContainerGroupProperties{
+			ProvisioningState: "Succeeded",
+			InstanceView: aci.ContainerGroupPropertiesInstanceView{
+				State: "Running",
+			},
+			Containers: []aci.Container{
+				aci.Container{
+					Name: "nginx",
+					ContainerProperties: aci.ContainerProperties{
+						Image: "nginx",
+						Resources: aci.ResourceRequirements{
+							Requests: &aci.ComputeResources{
+								CPU:        1,
+								MemoryInGB: 1.5,
+							},
+						},
+						InstanceView: aci.ContainerPropertiesInstanceView{
+							CurrentState: aci.ContainerState{
+								State:     "Running",
+								StartTime: api.JSONTime(startTime),
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestUpdatePodWithInvalidRestartRequest(t *testing.T) {
+	_, aciServerMocker, provider, err := prepareMocks()
+	if err != nil {
+		t.Fatal("Unable to prepare the mocks", err)
+	}
+
+	aciServerMocker.OnGetContainerGroup = func(subscription, resourceGroup, containerGroup string) (int, interface{}) {
+		return http.StatusOK, restartTestContainerGroup(containerGroup, time.Now().Add(-time.Hour))
+	}
+	aciServerMocker.OnAction = func(subscription, resourceGroup, containerGroup, action string) (int, interface{}) {
+		t.Errorf("Unexpected %s of container group %s", action, containerGroup)
+		return http.StatusNoContent, nil
+	}
+	puts := 0
+	aciServerMocker.OnCreate = func(subscription, resourceGroup, containerGroup string, cg *aci.ContainerGroup) (int, interface{}) {
+		puts++
+		return http.StatusOK, cg
+	}
+
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pod",
+			Namespace: "ns",
+			Annotations: map[string]string{
+				restartRequestedAtAnnotation: "now",
+			},
+		},
+		Spec: v1.PodSpec{
+			Containers: []v1.Container{
+				v1.Container{
+					Name:  "nginx",
+					Image: "nginx",
+					Resources: v1.ResourceRequirements{
+						Requests: v1.ResourceList{
+							"cpu":    resource.MustParse("2"),
+							"memory": resource.MustParse("1.5G"),
+						},
+					},
+				},
+			},
+		},
+	}
+
+	// The invalid request is ignored and doesn't block the resize.
+	assert.NilError(t, provider.UpdatePod(context.Background(), pod))
+	assert.Check(t, is.Equal(1, puts), "The container group should be resized")
+
+	// So is a request in the future, which would restart the containers on every update until then.
+	pod.Annotations[restartRequestedAtAnnotation] = time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	assert.NilError(t, provider.UpdatePod(context.Background(), pod))
+	assert.Check(t, is.Equal(2, puts), "The container group should be resized")
+}
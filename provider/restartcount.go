@@ -0,0 +1,102 @@
+package provider
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/virtual-kubelet/azure-aci/client/aci"
+)
+
+// restartCountsTag keeps the restart counts of the containers across the updates and restarts of the container
+// group, which reset the restart counts ACI reports.
+const restartCountsTag = "RestartCounts"
+
+// restartBaseline are the restart counts of the containers when their container group was last updated or
+// restarted, at the given time. The counts ACI reports for the containers started since then add up to them,
+// while the containers started before are still counted in the baseline.
+type restartBaseline struct {
+	counts map[string]int32
+	at     time.Time
+}
+
+// parseRestartBaseline parses the restart counts tag of a container group, formatted as c1=3,c2=1@<unix time>.
+// An invalid tag is ignored.
+func parseRestartBaseline(v string) restartBaseline {
+	var b restartBaseline
+	i := strings.LastIndex(v, "@")
+	if i < 0 {
+		return b
+	}
+	at, err := strconv.ParseInt(v[i+1:], 10, 64)
+	if err != nil {
+		return b
+	}
+	b.at = time.Unix(at, 0)
+	b.counts = make(map[string]int32)
+	for _, kv := range strings.Split(v[:i], ",") {
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		if n, err := strconv.ParseInt(parts[1], 10, 32); err == nil {
+			b.counts[parts[0]] = int32(n)
+		}
+	}
+	return b
+}
+
+func (b restartBaseline) String() string {
+	names := make([]string, 0, len(b.counts))
+	for name, n := range b.counts {
+		if n > 0 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	counts := make([]string, 0, len(names))
+	for _, name := range names {
+		counts = append(counts, fmt.Sprintf("%s=%d", name, b.counts[name]))
+	}
+	return strings.Join(counts, ",") + "@" + strconv.FormatInt(b.at.Unix(), 10)
+}
+
+// restartCount returns the restart count of a container, monotonic across the updates of its container group.
+func (b restartBaseline) restartCount(c aci.Container) int32 {
+	if b.at.IsZero() {
+		return c.InstanceView.RestartCount
+	}
+	if !time.Time(c.InstanceView.CurrentState.StartTime).After(b.at) {
+		return b.counts[c.Name]
+	}
+	return b.counts[c.Name] + c.InstanceView.RestartCount
+}
+
+// containerGroupRestartBaseline returns the baseline of the restart counts of the containers of a container
+// group which is updated or restarted at the given time.
+func containerGroupRestartBaseline(cg *aci.ContainerGroup, at time.Time) restartBaseline {
+	current := parseRestartBaseline(cg.Tags[restartCountsTag])
+	b := restartBaseline{counts: make(map[string]int32, len(cg.Containers)), at: at}
+	for _, c := range cg.Containers {
+		b.counts[c.Name] = current.restartCount(c)
+	}
+	return b
+}
+
+// withRestartBaseline returns the tags of the container group with the restart counts baseline of its current
+// containers. The baseline is left out when it doesn't fit in a tag.
+func withRestartBaseline(tags map[string]string, current *aci.ContainerGroup, at time.Time) map[string]string {
+	v := containerGroupRestartBaseline(current, at).String()
+	if len(v) > maxTagValueLength {
+		return tags
+	}
+	updated := make(map[string]string, len(tags)+1)
+	for k, tag := range tags {
+		updated[k] = tag
+	}
+	updated[restartCountsTag] = v
+	return updated
+}
@@ -0,0 +1,47 @@
+package provider
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/virtual-kubelet/azure-aci/client/aci"
+	"github.com/virtual-kubelet/azure-aci/client/api"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func TestRestartBaseline(t *testing.T) {
+	started := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+	container := func(name string, restarts int32, startedAt time.Time) aci.Container {
+		c := aci.Container{Name: name}
+		c.InstanceView.RestartCount = restarts
+		c.InstanceView.CurrentState = aci.ContainerState{State: "Running", StartTime: api.JSONTime(startedAt)}
+		return c
+	}
+	cg := &aci.ContainerGroup{ContainerGroupProperties: aci.ContainerGroupProperties{
+		Containers: []aci.Container{container("web", 3, started), container("sidecar", 0, started)},
+	}}
+
+	var none restartBaseline
+	assert.Check(t, is.Equal(none.restartCount(cg.Containers[0]), int32(3)), "Without a baseline ACI's count is reported")
+
+	// The container group is restarted.
+	restartedAt := started.Add(time.Hour)
+	cg.Tags = withRestartBaseline(nil, cg, restartedAt)
+	assert.Check(t, is.Equal(cg.Tags[restartCountsTag], "web=3@"+strconv.FormatInt(restartedAt.Unix(), 10)))
+
+	b := parseRestartBaseline(cg.Tags[restartCountsTag])
+	assert.Check(t, is.Equal(b.restartCount(cg.Containers[0]), int32(3)), "Before the restart the count is in the baseline")
+
+	cg.Containers[0] = container("web", 1, restartedAt.Add(time.Minute))
+	assert.Check(t, is.Equal(b.restartCount(cg.Containers[0]), int32(4)), "The new restarts add up to the baseline")
+	assert.Check(t, is.Equal(b.restartCount(container("sidecar", 0, restartedAt.Add(time.Minute))), int32(0)))
+
+	// A second restart keeps accumulating.
+	cg.Tags = withRestartBaseline(cg.Tags, cg, restartedAt.Add(time.Hour))
+	b = parseRestartBaseline(cg.Tags[restartCountsTag])
+	assert.Check(t, is.Equal(b.counts["web"], int32(4)))
+
+	assert.Check(t, parseRestartBaseline("web=3").at.IsZero(), "An invalid tag should be ignored")
+}
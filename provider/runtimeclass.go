@@ -0,0 +1,128 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/virtual-kubelet/azure-aci/client/aci"
+	"github.com/virtual-kubelet/node-cli/provider"
+	"github.com/virtual-kubelet/virtual-kubelet/errdefs"
+	v1 "k8s.io/api/core/v1"
+	k8serr "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/informers"
+)
+
+// runtimeClassProfile is the ACI deployment profile a RuntimeClass handler maps to.
+type runtimeClassProfile struct {
+	// SKU is the container group SKU, one of Standard, Dedicated or Confidential.
+	SKU string
+	// OperatingSystem overrides the operating system of the container group.
+	OperatingSystem string
+	// GPUSKU is the GPU SKU used by containers requesting GPU resources.
+	GPUSKU string
+}
+
+var validContainerGroupSkus = []aci.ContainerGroupSku{
+	aci.ContainerGroupSkuStandard,
+	aci.ContainerGroupSkuDedicated,
+	aci.ContainerGroupSkuConfidential,
+}
+
+func (r runtimeClassProfile) validate() error {
+	if r.SKU != "" && r.containerGroupSku() == "" {
+		return fmt.Errorf("%q is not a valid container group SKU", r.SKU)
+	}
+
+	if r.OperatingSystem != "" && !provider.ValidOperatingSystems[r.OperatingSystem] {
+		return fmt.Errorf("%q is not a valid operating system, try one of the following instead: %s", r.OperatingSystem, strings.Join(provider.ValidOperatingSystems.Names(), " | "))
+	}
+
+	return nil
+}
+
+func (r runtimeClassProfile) containerGroupSku() aci.ContainerGroupSku {
+	for _, sku := range validContainerGroupSkus {
+		if strings.EqualFold(r.SKU, string(sku)) {
+			return sku
+		}
+	}
+
+	return ""
+}
+
+// setupRuntimeClasses watches the RuntimeClasses when profiles are configured, so the RuntimeClass of a pod is
+// resolved to its handler, which the profiles are configured for.
+func (p *ACIProvider) setupRuntimeClasses(ctx context.Context) error {
+	if len(p.runtimeClassProfiles) == 0 {
+		return nil
+	}
+	if p.kubeClient == nil {
+		return fmt.Errorf("the runtime class handler profiles require a kubernetes client to read the RuntimeClasses")
+	}
+
+	informer := informers.NewSharedInformerFactory(p.kubeClient, 0).Node().V1beta1().RuntimeClasses()
+	p.runtimeClasses = informer.Lister()
+	p.runtimeClassesSynced = informer.Informer().HasSynced
+	goSubsystem("runtime_classes", func() { informer.Informer().Run(ctx.Done()) })
+	return nil
+}
+
+// getRuntimeClassProfile returns the profile of the handler of the pod RuntimeClass, so the RuntimeClasses sharing
+// a handler share its profile. Pods without a RuntimeClass, or running on a provider without profiles, get a nil
+// profile.
+func (p *ACIProvider) getRuntimeClassProfile(pod *v1.Pod) (*runtimeClassProfile, error) {
+	if pod.Spec.RuntimeClassName == nil || len(p.runtimeClassProfiles) == 0 {
+		return nil, nil
+	}
+	if p.runtimeClasses == nil {
+		return nil, fmt.Errorf("the RuntimeClasses are not watched")
+	}
+	if p.runtimeClassesSynced != nil && !p.runtimeClassesSynced() {
+		return nil, fmt.Errorf("the RuntimeClasses are not synced yet")
+	}
+
+	runtimeClass, err := p.runtimeClasses.Get(*pod.Spec.RuntimeClassName)
+	if k8serr.IsNotFound(err) {
+		return nil, errdefs.InvalidInputf("runtime class %s of pod %s does not exist", *pod.Spec.RuntimeClassName, pod.Name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	profile, ok := p.runtimeClassProfiles[runtimeClass.Handler]
+	if !ok {
+		return nil, errdefs.InvalidInputf("handler %s of runtime class %s of pod %s is not supported by the ACI provider", runtimeClass.Handler, runtimeClass.Name, pod.Name)
+	}
+
+	return &profile, nil
+}
+
+// isWindowsPod reports whether the container group of the pod runs Windows, from the operating system of its
+// RuntimeClass profile or of the provider.
+func (p *ACIProvider) isWindowsPod(pod *v1.Pod) bool {
+	operatingSystem := p.operatingSystem
+	if profile, err := p.getRuntimeClassProfile(pod); err == nil && profile != nil && profile.OperatingSystem != "" {
+		operatingSystem = profile.OperatingSystem
+	}
+	return strings.EqualFold(operatingSystem, string(aci.Windows))
+}
+
+// applyRuntimeClassProfile sets the SKU and operating system of the container group from the profile of the pod
+// RuntimeClass, keeping the ones of the provider for the fields the profile leaves unset.
+func (p *ACIProvider) applyRuntimeClassProfile(pod *v1.Pod, containerGroup *aci.ContainerGroup) error {
+	profile, err := p.getRuntimeClassProfile(pod)
+	if err != nil || profile == nil {
+		return err
+	}
+
+	if sku := profile.containerGroupSku(); sku != "" {
+		containerGroup.ContainerGroupProperties.Sku = sku
+	}
+
+	if profile.OperatingSystem != "" {
+		containerGroup.ContainerGroupProperties.OsType = aci.OperatingSystemTypes(profile.OperatingSystem)
+	}
+
+	return nil
+}
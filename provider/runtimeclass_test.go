@@ -0,0 +1,90 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/virtual-kubelet/azure-aci/client/aci"
+	"github.com/virtual-kubelet/virtual-kubelet/errdefs"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	v1 "k8s.io/api/core/v1"
+	nodev1beta1 "k8s.io/api/node/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	nodelisters "k8s.io/client-go/listers/node/v1beta1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestApplyRuntimeClassProfile(t *testing.T) {
+	runtimeClasses := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for name, handler := range map[string]string{
+		"confidential":      "confidential",
+		"confidential-fast": "confidential",
+		"windows":           "windows",
+		"gvisor":            "runsc",
+	} {
+		assert.NilError(t, runtimeClasses.Add(&nodev1beta1.RuntimeClass{ObjectMeta: metav1.ObjectMeta{Name: name}, Handler: handler}))
+	}
+	p := ACIProvider{
+		operatingSystem: "Linux",
+		runtimeClassProfiles: map[string]runtimeClassProfile{
+			"confidential": {SKU: "confidential"},
+			"windows":      {OperatingSystem: "Windows"},
+		},
+		runtimeClasses: nodelisters.NewRuntimeClassLister(runtimeClasses),
+	}
+	pod := func(runtimeClass string) *v1.Pod {
+		pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "ns"}}
+		if runtimeClass != "" {
+			pod.Spec.RuntimeClassName = &runtimeClass
+		}
+		return pod
+	}
+	containerGroup := func() *aci.ContainerGroup {
+		cg := &aci.ContainerGroup{}
+		cg.Sku = aci.ContainerGroupSkuStandard
+		cg.OsType = aci.Linux
+		return cg
+	}
+
+	// Pods without a RuntimeClass keep the container group of the provider.
+	cg := containerGroup()
+	assert.NilError(t, p.applyRuntimeClassProfile(pod(""), cg))
+	assert.Check(t, is.Equal(aci.ContainerGroupSkuStandard, cg.Sku))
+	assert.Check(t, is.Equal(aci.Linux, cg.OsType))
+
+	cg = containerGroup()
+	assert.NilError(t, p.applyRuntimeClassProfile(pod("confidential"), cg))
+	assert.Check(t, is.Equal(aci.ContainerGroupSkuConfidential, cg.Sku))
+	assert.Check(t, is.Equal(aci.Linux, cg.OsType), "the profile doesn't set the operating system")
+
+	// The profile is the one of the handler, shared by its RuntimeClasses.
+	cg = containerGroup()
+	assert.NilError(t, p.applyRuntimeClassProfile(pod("confidential-fast"), cg))
+	assert.Check(t, is.Equal(aci.ContainerGroupSkuConfidential, cg.Sku))
+
+	cg = containerGroup()
+	assert.NilError(t, p.applyRuntimeClassProfile(pod("windows"), cg))
+	assert.Check(t, is.Equal(aci.ContainerGroupSkuStandard, cg.Sku), "the profile doesn't set the SKU")
+	assert.Check(t, is.Equal(aci.Windows, cg.OsType))
+	assert.Check(t, p.isWindowsPod(pod("windows")))
+	assert.Check(t, !p.isWindowsPod(pod("confidential")))
+
+	err := p.applyRuntimeClassProfile(pod("gvisor"), containerGroup())
+	assert.Check(t, errdefs.IsInvalidInput(err), "Expected an invalid input error, got %v", err)
+	assert.Check(t, is.ErrorContains(err, "handler runsc"))
+
+	err = p.applyRuntimeClassProfile(pod("missing"), containerGroup())
+	assert.Check(t, errdefs.IsInvalidInput(err), "Expected an invalid input error, got %v", err)
+
+	// The RuntimeClasses are resolved once synced.
+	p.runtimeClassesSynced = func() bool { return false }
+	err = p.applyRuntimeClassProfile(pod("confidential"), containerGroup())
+	assert.Check(t, is.ErrorContains(err, "not synced yet"))
+	p.runtimeClassesSynced = nil
+
+	// Without profiles the RuntimeClass is left to the scheduler.
+	p.runtimeClassProfiles = nil
+	cg = containerGroup()
+	assert.NilError(t, p.applyRuntimeClassProfile(pod("gvisor"), cg))
+	assert.Check(t, is.Equal(aci.ContainerGroupSkuStandard, cg.Sku))
+}
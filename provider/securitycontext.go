@@ -0,0 +1,98 @@
+package provider
+
+import (
+	"github.com/virtual-kubelet/azure-aci/client/aci"
+	"github.com/virtual-kubelet/virtual-kubelet/errdefs"
+	v1 "k8s.io/api/core/v1"
+)
+
+// getContainerSecurityContext translates the security context of a container, inheriting the
+// user and group of the pod security context. Fields ACI has no equivalent for are rejected
+// rather than silently dropped.
+func getContainerSecurityContext(pod *v1.Pod, container *v1.Container) (*aci.SecurityContextDefinition, error) {
+	var runAsUser, runAsGroup *int64
+	var runAsNonRoot *bool
+	if psc := pod.Spec.SecurityContext; psc != nil {
+		runAsUser, runAsGroup, runAsNonRoot = psc.RunAsUser, psc.RunAsGroup, psc.RunAsNonRoot
+		if psc.SELinuxOptions != nil {
+			return nil, errdefs.InvalidInputf("pod %s: seLinuxOptions are not supported by ACI", pod.Name)
+		}
+	}
+
+	sc := container.SecurityContext
+	if sc != nil {
+		if sc.RunAsUser != nil {
+			runAsUser = sc.RunAsUser
+		}
+		if sc.RunAsGroup != nil {
+			runAsGroup = sc.RunAsGroup
+		}
+		if sc.RunAsNonRoot != nil {
+			runAsNonRoot = sc.RunAsNonRoot
+		}
+
+		if sc.ReadOnlyRootFilesystem != nil && *sc.ReadOnlyRootFilesystem {
+			return nil, errdefs.InvalidInputf("container %s: readOnlyRootFilesystem is not supported by ACI", container.Name)
+		}
+		if sc.SELinuxOptions != nil {
+			return nil, errdefs.InvalidInputf("container %s: seLinuxOptions are not supported by ACI", container.Name)
+		}
+		if sc.ProcMount != nil && *sc.ProcMount != v1.DefaultProcMount {
+			return nil, errdefs.InvalidInputf("container %s: procMount %s is not supported by ACI", container.Name, *sc.ProcMount)
+		}
+	}
+
+	// ACI does not inspect the image, so only an explicit non root user can satisfy runAsNonRoot.
+	if runAsNonRoot != nil && *runAsNonRoot && (runAsUser == nil || *runAsUser == 0) {
+		return nil, errdefs.InvalidInputf("container %s: runAsNonRoot requires a non root runAsUser on ACI", container.Name)
+	}
+
+	if runAsUser == nil && runAsGroup == nil && (sc == nil || (sc.Privileged == nil && sc.AllowPrivilegeEscalation == nil && sc.Capabilities == nil)) {
+		return nil, nil
+	}
+
+	securityContext := &aci.SecurityContextDefinition{
+		RunAsUser:  runAsUser,
+		RunAsGroup: runAsGroup,
+	}
+	if sc != nil {
+		securityContext.Privileged = sc.Privileged
+		securityContext.AllowPrivilegeEscalation = sc.AllowPrivilegeEscalation
+		if sc.Capabilities != nil {
+			securityContext.Capabilities = &aci.SecurityContextCapabilitiesDefinition{
+				Add:  capabilityNames(sc.Capabilities.Add),
+				Drop: capabilityNames(sc.Capabilities.Drop),
+			}
+		}
+	}
+
+	return securityContext, nil
+}
+
+func capabilityNames(capabilities []v1.Capability) []string {
+	if len(capabilities) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(capabilities))
+	for _, c := range capabilities {
+		names = append(names, string(c))
+	}
+
+	return names
+}
+
+// validateSecurityContexts rejects security contexts on Windows container groups, ACI only supports them on Linux.
+func validateSecurityContexts(pod *v1.Pod, cg *aci.ContainerGroup) error {
+	if cg.OsType != aci.Windows {
+		return nil
+	}
+
+	for _, c := range cg.Containers {
+		if c.SecurityContext != nil {
+			return errdefs.InvalidInputf("container %s of pod %s: securityContext is not supported by ACI on Windows", c.Name, pod.Name)
+		}
+	}
+
+	return nil
+}
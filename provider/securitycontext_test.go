@@ -0,0 +1,56 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/virtual-kubelet/virtual-kubelet/errdefs"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestGetContainerSecurityContext(t *testing.T) {
+	user := int64(1000)
+	group := int64(3000)
+	yes := true
+
+	pod := &v1.Pod{
+		Spec: v1.PodSpec{
+			SecurityContext: &v1.PodSecurityContext{
+				RunAsUser:  &user,
+				RunAsGroup: &group,
+			},
+		},
+	}
+	container := &v1.Container{
+		Name: "nginx",
+		SecurityContext: &v1.SecurityContext{
+			Capabilities: &v1.Capabilities{
+				Add:  []v1.Capability{"NET_ADMIN"},
+				Drop: []v1.Capability{"ALL"},
+			},
+		},
+	}
+
+	sc, err := getContainerSecurityContext(pod, container)
+	assert.NilError(t, err)
+	assert.Assert(t, sc != nil, "A security context is expected")
+	assert.Check(t, is.Equal(user, *sc.RunAsUser), "User is not expected")
+	assert.Check(t, is.Equal(group, *sc.RunAsGroup), "Group is not expected")
+	assert.Check(t, is.DeepEqual([]string{"NET_ADMIN"}, sc.Capabilities.Add), "Added capabilities are not expected")
+	assert.Check(t, is.DeepEqual([]string{"ALL"}, sc.Capabilities.Drop), "Dropped capabilities are not expected")
+
+	sc, err = getContainerSecurityContext(&v1.Pod{}, &v1.Container{Name: "nginx"})
+	assert.NilError(t, err)
+	assert.Check(t, is.Nil(sc), "No security context is expected")
+
+	container.SecurityContext.ReadOnlyRootFilesystem = &yes
+	_, err = getContainerSecurityContext(pod, container)
+	assert.Check(t, errdefs.IsInvalidInput(err), "readOnlyRootFilesystem should be rejected")
+
+	_, err = getContainerSecurityContext(&v1.Pod{}, &v1.Container{
+		Name:            "nginx",
+		SecurityContext: &v1.SecurityContext{RunAsNonRoot: &yes},
+	})
+	assert.Check(t, errdefs.IsInvalidInput(err), "runAsNonRoot without user should be rejected")
+}
@@ -0,0 +1,135 @@
+package provider
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/virtual-kubelet/azure-aci/client/aci"
+	"github.com/virtual-kubelet/virtual-kubelet/errdefs"
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	seccompProfileUnconfined    = "unconfined"
+	appArmorAnnotationKeyPrefix = "container.apparmor.security.beta.kubernetes.io/"
+	// localhostProfilePrefix is the prefix of the seccomp and AppArmor profiles loaded from the node.
+	localhostProfilePrefix = "localhost/"
+
+	// ccePolicyAnnotation is the base64 encoded confidential computing enforcement policy of the container group
+	// of a pod of the Confidential SKU, as generated by az confcom.
+	ccePolicyAnnotation = "virtual-kubelet.io/cce-policy"
+
+	eventReasonConfidentialPolicyMissing = "ConfidentialPolicyMissing"
+)
+
+// setupSeccompProfiles reads the directory of the localhost seccomp profiles from SeccompProfileDir in the config
+// file or the ACI_SECCOMP_PROFILE_DIR environment variable, the equivalent of the seccomp directory of a kubelet.
+func (p *ACIProvider) setupSeccompProfiles() {
+	if v := os.Getenv("ACI_SECCOMP_PROFILE_DIR"); v != "" {
+		p.seccompProfileDir = v
+	}
+}
+
+// seccompProfileName returns the seccomp profile of a container from the seccomp annotations of the pod, the one
+// of the container over the one of the pod.
+func seccompProfileName(pod *v1.Pod, container string) string {
+	if profile, ok := pod.Annotations[v1.SeccompContainerAnnotationKeyPrefix+container]; ok {
+		return profile
+	}
+	return pod.Annotations[v1.SeccompPodAnnotationKey]
+}
+
+// getSeccompProfile translates the seccomp profile of a container to the base64 encoded profile of its security
+// context. The default profile of the runtime is the one ACI applies, so it is left empty. ACI can't run a container
+// unconfined, and a localhost profile is read from the seccomp profile directory.
+func (p *ACIProvider) getSeccompProfile(pod *v1.Pod, container string) (string, error) {
+	profile := seccompProfileName(pod, container)
+	switch {
+	case profile == "", profile == v1.SeccompProfileRuntimeDefault, profile == v1.DeprecatedSeccompProfileDockerDefault:
+		return "", nil
+	case profile == seccompProfileUnconfined:
+		return "", errdefs.InvalidInputf("container %s: seccomp profile %s is not supported by ACI, containers always run with a seccomp profile", container, profile)
+	case strings.HasPrefix(profile, localhostProfilePrefix):
+	default:
+		return "", errdefs.InvalidInputf("container %s: invalid seccomp profile %q", container, profile)
+	}
+
+	if p.seccompProfileDir == "" {
+		return "", errdefs.InvalidInputf("container %s: seccomp profile %s requires ACI_SECCOMP_PROFILE_DIR to be set on the virtual kubelet", container, profile)
+	}
+	name := filepath.Clean(strings.TrimPrefix(profile, localhostProfilePrefix))
+	if filepath.IsAbs(name) || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+		return "", errdefs.InvalidInputf("container %s: seccomp profile %s is outside of the seccomp profile directory", container, profile)
+	}
+	b, err := ioutil.ReadFile(filepath.Join(p.seccompProfileDir, name))
+	if err != nil {
+		return "", errdefs.InvalidInputf("container %s: error reading seccomp profile %s: %v", container, profile, err)
+	}
+	if !json.Valid(b) {
+		return "", errdefs.InvalidInputf("container %s: seccomp profile %s is not valid JSON", container, profile)
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+// applySeccompProfile sets the seccomp profile of a container on its security context.
+func (p *ACIProvider) applySeccompProfile(pod *v1.Pod, c *aci.Container) error {
+	profile, err := p.getSeccompProfile(pod, c.Name)
+	if err != nil || profile == "" {
+		return err
+	}
+	if c.SecurityContext == nil {
+		c.SecurityContext = &aci.SecurityContextDefinition{}
+	}
+	c.SecurityContext.SeccompProfile = profile
+	return nil
+}
+
+// unsupportedAppArmorFields returns the AppArmor annotations of the pod, ACI does not apply AppArmor profiles.
+// Localhost profiles are rejected by validateAppArmorProfiles instead of being ignored.
+func unsupportedAppArmorFields(pod *v1.Pod) []string {
+	var fields []string
+	for _, c := range pod.Spec.Containers {
+		key := appArmorAnnotationKeyPrefix + c.Name
+		if profile, ok := pod.Annotations[key]; ok && !strings.HasPrefix(profile, localhostProfilePrefix) {
+			fields = append(fields, "metadata.annotations["+key+"]")
+		}
+	}
+	return fields
+}
+
+// validateAppArmorProfiles rejects the containers with a localhost AppArmor profile, which ACI can't load.
+func validateAppArmorProfiles(pod *v1.Pod) error {
+	for _, c := range pod.Spec.Containers {
+		if profile := pod.Annotations[appArmorAnnotationKeyPrefix+c.Name]; strings.HasPrefix(profile, localhostProfilePrefix) {
+			return errdefs.InvalidInputf("container %s: AppArmor profile %s is not supported by ACI", c.Name, profile)
+		}
+	}
+	return nil
+}
+
+// applyConfidentialPolicy sets the confidential computing enforcement policy of the pod on its container group of
+// the Confidential SKU. Without a policy ACI enforces none, so the pod gets an event telling so.
+func (p *ACIProvider) applyConfidentialPolicy(pod *v1.Pod, cg *aci.ContainerGroup) error {
+	policy := pod.Annotations[ccePolicyAnnotation]
+	if cg.Sku != aci.ContainerGroupSkuConfidential {
+		if policy != "" {
+			return errdefs.InvalidInputf("pod %s: the %s annotation requires the Confidential SKU", pod.Name, ccePolicyAnnotation)
+		}
+		return nil
+	}
+
+	if policy == "" {
+		p.recordEvent(pod, v1.EventTypeWarning, eventReasonConfidentialPolicyMissing,
+			"Container group runs with the allow all confidential computing enforcement policy, set the %s annotation to the policy generated by az confcom to enforce one", ccePolicyAnnotation)
+		return nil
+	}
+	if _, err := base64.StdEncoding.DecodeString(policy); err != nil {
+		return errdefs.InvalidInputf("pod %s: the %s annotation is not base64 encoded: %v", pod.Name, ccePolicyAnnotation, err)
+	}
+	cg.ConfidentialComputeProperties = &aci.ConfidentialComputeProperties{CcePolicy: policy}
+	return nil
+}
@@ -0,0 +1,84 @@
+package provider
+
+import (
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/virtual-kubelet/azure-aci/client/aci"
+	"github.com/virtual-kubelet/virtual-kubelet/errdefs"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestSeccompProfile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "seccomp")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+	profile := []byte(`{"defaultAction":"SCMP_ACT_ERRNO"}`)
+	assert.NilError(t, ioutil.WriteFile(filepath.Join(dir, "audit.json"), profile, 0600))
+
+	p := ACIProvider{}
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web", Annotations: map[string]string{
+		v1.SeccompPodAnnotationKey:                     v1.SeccompProfileRuntimeDefault,
+		v1.SeccompContainerAnnotationKeyPrefix + "app": "localhost/audit.json",
+	}}}
+
+	c := aci.Container{Name: "sidecar"}
+	assert.NilError(t, p.applySeccompProfile(pod, &c))
+	assert.Assert(t, c.SecurityContext == nil, "The default profile is the one of ACI")
+
+	c = aci.Container{Name: "app"}
+	err = p.applySeccompProfile(pod, &c)
+	assert.Assert(t, errdefs.IsInvalidInput(err))
+	assert.Assert(t, is.ErrorContains(err, "ACI_SECCOMP_PROFILE_DIR"))
+
+	p.seccompProfileDir = dir
+	assert.NilError(t, p.applySeccompProfile(pod, &c))
+	assert.Equal(t, c.SecurityContext.SeccompProfile, base64.StdEncoding.EncodeToString(profile))
+
+	pod.Annotations[v1.SeccompContainerAnnotationKeyPrefix+"app"] = "localhost/../etc/passwd"
+	assert.Assert(t, is.ErrorContains(p.applySeccompProfile(pod, &c), "outside of the seccomp profile directory"))
+	pod.Annotations[v1.SeccompContainerAnnotationKeyPrefix+"app"] = seccompProfileUnconfined
+	assert.Assert(t, is.ErrorContains(p.applySeccompProfile(pod, &c), "not supported by ACI"))
+}
+
+func TestAppArmorProfiles(t *testing.T) {
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Annotations: map[string]string{
+			appArmorAnnotationKeyPrefix + "app": "runtime/default",
+		}},
+		Spec: v1.PodSpec{Containers: []v1.Container{{Name: "app"}, {Name: "sidecar"}}},
+	}
+	assert.DeepEqual(t, unsupportedAppArmorFields(pod), []string{"metadata.annotations[container.apparmor.security.beta.kubernetes.io/app]"})
+	assert.NilError(t, validateAppArmorProfiles(pod))
+
+	pod.Annotations[appArmorAnnotationKeyPrefix+"sidecar"] = "localhost/k8s-deny-write"
+	assert.Assert(t, errdefs.IsInvalidInput(validateAppArmorProfiles(pod)))
+	assert.Equal(t, len(unsupportedAppArmorFields(pod)), 1)
+}
+
+func TestApplyConfidentialPolicy(t *testing.T) {
+	recorder := record.NewFakeRecorder(1)
+	p := ACIProvider{eventRecorder: recorder}
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web", Annotations: map[string]string{}}}
+
+	cg := &aci.ContainerGroup{}
+	cg.Sku = aci.ContainerGroupSkuConfidential
+	assert.NilError(t, p.applyConfidentialPolicy(pod, cg))
+	assert.Assert(t, cg.ConfidentialComputeProperties == nil)
+	assert.Equal(t, len(recorder.Events), 1)
+
+	policy := base64.StdEncoding.EncodeToString([]byte("package policy"))
+	pod.Annotations[ccePolicyAnnotation] = policy
+	assert.NilError(t, p.applyConfidentialPolicy(pod, cg))
+	assert.Equal(t, cg.ConfidentialComputeProperties.CcePolicy, policy)
+
+	cg = &aci.ContainerGroup{}
+	assert.Assert(t, errdefs.IsInvalidInput(p.applyConfidentialPolicy(pod, cg)))
+}
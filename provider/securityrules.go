@@ -0,0 +1,270 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	aznetwork "github.com/Azure/azure-sdk-for-go/services/network/mgmt/2018-08-01/network"
+	"github.com/virtual-kubelet/virtual-kubelet/log"
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	// allowFromAnnotation lists the source address prefixes or service tags, like Internet, allowed to
+	// reach the ports of the pod by a rule of the network security group of the subnet, comma separated.
+	allowFromAnnotation = "virtual-kubelet.io/allow-from"
+
+	// The priorities of the security rules managed by the node.
+	minSecurityRulePriority = 2000
+	maxSecurityRulePriority = 2999
+
+	securityRulesSyncInterval = 30 * time.Second
+)
+
+// securityRuleManager manages the rules of the network security group of the delegated subnet.
+type securityRuleManager interface {
+	GetSubnet(resourceGroup, vnet, name string) (*aznetwork.Subnet, error)
+	GetSecurityGroup(id string) (*aznetwork.SecurityGroup, error)
+	CreateOrUpdateSecurityRule(securityGroupID string, rule *aznetwork.SecurityRule) error
+	DeleteSecurityRule(securityGroupID, name string) error
+}
+
+// setupSecurityRules enables the management of the security rules opening the ports of the annotated pods
+// with ACI_MANAGE_NSG or the config file. The rules are added to the network security group of the subnet,
+// or the one set by ACI_NSG_ID.
+func (p *ACIProvider) setupSecurityRules() error {
+	if v := os.Getenv("ACI_MANAGE_NSG"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid ACI_MANAGE_NSG %q: %v", v, err)
+		}
+		p.manageNSG = b
+	}
+	if v := os.Getenv("ACI_NSG_ID"); v != "" {
+		p.nsgID = v
+	}
+	if !p.manageNSG {
+		return nil
+	}
+	if p.networkClient == nil || p.subnetName == "" {
+		return fmt.Errorf("managing the network security group requires the pods to run in a subnet")
+	}
+	p.securityRules = p.networkClient
+	return p.resolveSecurityGroup()
+}
+
+// resolveSecurityGroup finds the network security group of the subnet when none was set.
+func (p *ACIProvider) resolveSecurityGroup() error {
+	if p.nsgID != "" {
+		return nil
+	}
+	subnet, err := p.securityRules.GetSubnet(p.vnetResourceGroup, p.vnetName, p.subnetName)
+	if err != nil {
+		return fmt.Errorf("error getting the network security group of subnet %s: %v", p.subnetName, err)
+	}
+	if subnet.SubnetPropertiesFormat == nil || subnet.SubnetPropertiesFormat.NetworkSecurityGroup == nil || subnet.SubnetPropertiesFormat.NetworkSecurityGroup.ID == nil {
+		return fmt.Errorf("subnet %s has no network security group, set one or ACI_NSG_ID", p.subnetName)
+	}
+	p.nsgID = *subnet.SubnetPropertiesFormat.NetworkSecurityGroup.ID
+	return nil
+}
+
+func fnvHex(s string) string {
+	h := fnv.New32a()
+	h.Write([]byte(s))
+	return fmt.Sprintf("%08x", h.Sum32())
+}
+
+// securityRulePrefix is the prefix of the names of the security rules owned by the node.
+func (p *ACIProvider) securityRulePrefix() string {
+	return "vk-" + fnvHex(p.nodeName) + "-"
+}
+
+// securityRuleName is the name of the security rule of the pod.
+func (p *ACIProvider) securityRuleName(pod *v1.Pod) string {
+	return p.securityRulePrefix() + fnvHex(pod.Namespace+"/"+pod.Name)
+}
+
+// podSecurityRule returns the security rule allowing the sources of the annotation to reach the ports of
+// the pod, without its priority, or false if the pod doesn't need one.
+func (p *ACIProvider) podSecurityRule(pod *v1.Pod) (aznetwork.SecurityRule, bool) {
+	var sources []string
+	for _, s := range strings.Split(pod.Annotations[allowFromAnnotation], ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			sources = append(sources, s)
+		}
+	}
+	ports, err := getContainerGroupPorts(pod)
+	if len(sources) == 0 || err != nil || len(ports) == 0 || !podReady(pod) {
+		return aznetwork.SecurityRule{}, false
+	}
+
+	protocol := aznetwork.SecurityRuleProtocol("")
+	var portRanges []string
+	for _, port := range ports {
+		portProtocol := aznetwork.SecurityRuleProtocolTCP
+		if port.Protocol == "UDP" {
+			portProtocol = aznetwork.SecurityRuleProtocolUDP
+		}
+		if protocol == "" {
+			protocol = portProtocol
+		} else if protocol != portProtocol {
+			protocol = aznetwork.SecurityRuleProtocolAsterisk
+		}
+		portRanges = appendUnique(portRanges, strconv.Itoa(int(port.Port)))
+	}
+	sort.Strings(portRanges)
+	sort.Strings(sources)
+
+	name := p.securityRuleName(pod)
+	description := fmt.Sprintf("Managed by virtual-kubelet node %s for pod %s/%s", p.nodeName, pod.Namespace, pod.Name)
+	destination := pod.Status.PodIP
+	wildcard := "*"
+	props := &aznetwork.SecurityRulePropertiesFormat{
+		Description:              &description,
+		Protocol:                 protocol,
+		SourcePortRange:          &wildcard,
+		DestinationAddressPrefix: &destination,
+		DestinationPortRanges:    &portRanges,
+		Access:                   aznetwork.SecurityRuleAccessAllow,
+		Direction:                aznetwork.SecurityRuleDirectionInbound,
+	}
+	if len(sources) == 1 {
+		props.SourceAddressPrefix = &sources[0]
+	} else {
+		props.SourceAddressPrefixes = &sources
+	}
+	return aznetwork.SecurityRule{Name: &name, SecurityRulePropertiesFormat: props}, true
+}
+
+func appendUnique(values []string, v string) []string {
+	for _, existing := range values {
+		if existing == v {
+			return values
+		}
+	}
+	return append(values, v)
+}
+
+// securityRulesLoop keeps the security rules of the node in sync with the ready pods until the context is done.
+func (p *ACIProvider) securityRulesLoop(ctx context.Context) {
+	ticker := time.NewTicker(securityRulesSyncInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := p.syncSecurityRules(ctx); err != nil {
+			log.G(ctx).WithError(err).Warn("Unable to sync the network security group rules")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// syncSecurityRules creates or updates the rules of the ready annotated pods, and deletes the rules of the node
+// whose pod is not ready anymore or was deleted. The rules of the node are found by their name, so the rules
+// left by a previous run are cleaned up too.
+func (p *ACIProvider) syncSecurityRules(ctx context.Context) error {
+	nsgID := p.nsgID
+	nsg, err := p.securityRules.GetSecurityGroup(nsgID)
+	if err != nil {
+		return err
+	}
+
+	owned := make(map[string]aznetwork.SecurityRule)
+	used := make(map[int32]bool)
+	if nsg.SecurityGroupPropertiesFormat != nil && nsg.SecurityGroupPropertiesFormat.SecurityRules != nil {
+		for _, rule := range *nsg.SecurityGroupPropertiesFormat.SecurityRules {
+			used[rulePriority(rule)] = true
+			if rule.Name != nil && strings.HasPrefix(*rule.Name, p.securityRulePrefix()) {
+				owned[*rule.Name] = rule
+			}
+		}
+	}
+
+	desired := make(map[string]aznetwork.SecurityRule)
+	for _, pod := range p.resourceManager.GetPods() {
+		if rule, ok := p.podSecurityRule(pod); ok {
+			desired[*rule.Name] = rule
+		}
+	}
+
+	for name := range owned {
+		if _, ok := desired[name]; ok {
+			continue
+		}
+		if err := p.securityRules.DeleteSecurityRule(nsgID, name); err != nil {
+			log.G(ctx).WithError(err).WithField("rule", name).Warn("Unable to delete the security rule")
+		}
+	}
+
+	names := make([]string, 0, len(desired))
+	for name := range desired {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		rule := desired[name]
+		if existing, ok := owned[name]; ok {
+			priority := rulePriority(existing)
+			rule.SecurityRulePropertiesFormat.Priority = &priority
+			if sameSecurityRule(existing, rule) {
+				continue
+			}
+		} else {
+			priority, ok := freeSecurityRulePriority(used)
+			if !ok {
+				log.G(ctx).WithField("rule", name).Warn("No priority left for the security rule")
+				continue
+			}
+			used[priority] = true
+			rule.SecurityRulePropertiesFormat.Priority = &priority
+		}
+		if err := p.securityRules.CreateOrUpdateSecurityRule(nsgID, &rule); err != nil {
+			log.G(ctx).WithError(err).WithField("rule", name).Warn("Unable to create the security rule")
+		}
+	}
+	return nil
+}
+
+func freeSecurityRulePriority(used map[int32]bool) (int32, bool) {
+	for priority := int32(minSecurityRulePriority); priority <= maxSecurityRulePriority; priority++ {
+		if !used[priority] {
+			return priority, true
+		}
+	}
+	return 0, false
+}
+
+// sameSecurityRule reports whether an existing rule already has the properties of the desired one.
+func sameSecurityRule(existing, desired aznetwork.SecurityRule) bool {
+	e, d := existing.SecurityRulePropertiesFormat, desired.SecurityRulePropertiesFormat
+	if e == nil {
+		return false
+	}
+	return e.Protocol == d.Protocol &&
+		reflect.DeepEqual(e.SourceAddressPrefix, d.SourceAddressPrefix) &&
+		reflect.DeepEqual(e.SourceAddressPrefixes, d.SourceAddressPrefixes) &&
+		reflect.DeepEqual(e.DestinationAddressPrefix, d.DestinationAddressPrefix) &&
+		reflect.DeepEqual(e.DestinationPortRanges, d.DestinationPortRanges)
+}
+
+// deletePodSecurityRule deletes the security rule of a deleted pod, before its container group is deleted.
+func (p *ACIProvider) deletePodSecurityRule(ctx context.Context, pod *v1.Pod) {
+	if p.securityRules == nil || pod.Annotations[allowFromAnnotation] == "" {
+		return
+	}
+	if err := p.securityRules.DeleteSecurityRule(p.nsgID, p.securityRuleName(pod)); err != nil {
+		log.G(ctx).WithError(err).Warn("Unable to delete the security rule of the pod")
+	}
+}
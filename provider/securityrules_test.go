@@ -0,0 +1,128 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	aznetwork "github.com/Azure/azure-sdk-for-go/services/network/mgmt/2018-08-01/network"
+	"github.com/virtual-kubelet/node-cli/manager"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	v1 "k8s.io/api/core/v1"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+const testSecurityGroup = "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/networkSecurityGroups/nsg"
+
+type fakeSecurityRules struct {
+	rules   map[string]aznetwork.SecurityRule
+	updates int
+}
+
+func (f *fakeSecurityRules) GetSubnet(resourceGroup, vnet, name string) (*aznetwork.Subnet, error) {
+	id := testSecurityGroup
+	return &aznetwork.Subnet{SubnetPropertiesFormat: &aznetwork.SubnetPropertiesFormat{
+		NetworkSecurityGroup: &aznetwork.SecurityGroup{ID: &id},
+	}}, nil
+}
+
+func (f *fakeSecurityRules) GetSecurityGroup(id string) (*aznetwork.SecurityGroup, error) {
+	var rules []aznetwork.SecurityRule
+	for _, rule := range f.rules {
+		rules = append(rules, rule)
+	}
+	return &aznetwork.SecurityGroup{ID: &id, SecurityGroupPropertiesFormat: &aznetwork.SecurityGroupPropertiesFormat{SecurityRules: &rules}}, nil
+}
+
+func (f *fakeSecurityRules) CreateOrUpdateSecurityRule(securityGroupID string, rule *aznetwork.SecurityRule) error {
+	f.updates++
+	f.rules[*rule.Name] = *rule
+	return nil
+}
+
+func (f *fakeSecurityRules) DeleteSecurityRule(securityGroupID, name string) error {
+	delete(f.rules, name)
+	return nil
+}
+
+func securityRulePod(name, ip string, ready bool, allowFrom string) *v1.Pod {
+	pod := backendPoolPod(name, ip, ready)
+	pod.Annotations = map[string]string{allowFromAnnotation: allowFrom}
+	pod.Spec.Containers = []v1.Container{{
+		Name: "web",
+		Ports: []v1.ContainerPort{
+			{ContainerPort: 80, Protocol: v1.ProtocolTCP},
+			{ContainerPort: 53, Protocol: v1.ProtocolUDP},
+		},
+	}}
+	return pod
+}
+
+func TestSyncSecurityRules(t *testing.T) {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	rm, err := manager.NewResourceManager(corev1listers.NewPodLister(indexer), nil, nil, nil)
+	assert.NilError(t, err)
+
+	taken := int32(minSecurityRulePriority)
+	other := "allow-ssh"
+	rules := &fakeSecurityRules{rules: map[string]aznetwork.SecurityRule{
+		other: {Name: &other, SecurityRulePropertiesFormat: &aznetwork.SecurityRulePropertiesFormat{Priority: &taken}},
+	}}
+	p := ACIProvider{
+		resourceManager: rm,
+		nodeName:        "vk",
+		subnetName:      "aci",
+		manageNSG:       true,
+		securityRules:   rules,
+	}
+	assert.NilError(t, p.resolveSecurityGroup())
+	assert.Equal(t, p.nsgID, testSecurityGroup)
+
+	// A rule left by a previous run for a pod that is gone.
+	stale := p.securityRulePrefix() + "deadbeef"
+	rules.rules[stale] = aznetwork.SecurityRule{Name: &stale, SecurityRulePropertiesFormat: &aznetwork.SecurityRulePropertiesFormat{}}
+
+	web := securityRulePod("web", "10.1.0.4", true, "Internet")
+	assert.NilError(t, indexer.Add(web))
+	assert.NilError(t, indexer.Add(securityRulePod("api", "10.1.0.5", true, "10.0.0.0/8, 192.168.0.0/16")))
+	assert.NilError(t, indexer.Add(securityRulePod("starting", "", false, "Internet")))
+	assert.NilError(t, indexer.Add(securityRulePod("private", "10.1.0.6", true, "")))
+
+	ctx := context.Background()
+	assert.NilError(t, p.syncSecurityRules(ctx))
+	assert.Check(t, is.Len(rules.rules, 3))
+	_, ok := rules.rules[stale]
+	assert.Check(t, !ok, "expected the stale rule to be deleted")
+
+	rule := rules.rules[p.securityRuleName(web)]
+	props := rule.SecurityRulePropertiesFormat
+	assert.Check(t, is.Equal(props.Protocol, aznetwork.SecurityRuleProtocolAsterisk))
+	assert.Check(t, is.Equal(*props.SourceAddressPrefix, "Internet"))
+	assert.Check(t, is.Equal(*props.DestinationAddressPrefix, "10.1.0.4"))
+	assert.Check(t, is.DeepEqual(*props.DestinationPortRanges, []string{"53", "80"}))
+	assert.Check(t, is.Equal(props.Access, aznetwork.SecurityRuleAccessAllow))
+	assert.Check(t, *props.Priority != taken, "expected a free priority")
+
+	api := rules.rules[p.securityRuleName(securityRulePod("api", "", false, ""))]
+	assert.Check(t, is.DeepEqual(*api.SecurityRulePropertiesFormat.SourceAddressPrefixes, []string{"10.0.0.0/8", "192.168.0.0/16"}))
+	assert.Check(t, *api.SecurityRulePropertiesFormat.Priority != *props.Priority)
+
+	// Unchanged rules are not updated again, and keep their priority.
+	updates := rules.updates
+	assert.NilError(t, p.syncSecurityRules(ctx))
+	assert.Check(t, is.Equal(rules.updates, updates))
+
+	web.Status.PodIP = "10.1.0.9"
+	assert.NilError(t, indexer.Update(web))
+	assert.NilError(t, p.syncSecurityRules(ctx))
+	updated := rules.rules[p.securityRuleName(web)]
+	assert.Check(t, is.Equal(*updated.SecurityRulePropertiesFormat.DestinationAddressPrefix, "10.1.0.9"))
+	assert.Check(t, is.Equal(*updated.SecurityRulePropertiesFormat.Priority, *props.Priority))
+
+	p.deletePodSecurityRule(ctx, web)
+	_, ok = rules.rules[p.securityRuleName(web)]
+	assert.Check(t, !ok, "expected the rule of the deleted pod to be deleted")
+	_, ok = rules.rules[other]
+	assert.Check(t, ok, "expected the rule not owned by the node to be kept")
+}
@@ -0,0 +1,232 @@
+package provider
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/virtual-kubelet/virtual-kubelet/log"
+	certificates "k8s.io/api/certificates/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	defaultServingCertSigner  = "kubernetes.io/kubelet-serving"
+	defaultServingCertTimeout = 5 * time.Minute
+
+	servingCertPollInterval = 5 * time.Second
+	// servingCertRenewAt is the fraction of the lifetime of the serving certificate after which it is renewed.
+	servingCertRenewAt = 0.8
+)
+
+// servingCert requests the serving certificate of the kubelet API endpoints from the cluster CA,
+// with a certificate signing request for the node.
+type servingCert struct {
+	signer      string
+	timeout     time.Duration
+	trustDomain string
+	certFile    string
+	keyFile     string
+	// reload loads the renewed serving certificate in the kubelet API, which serves it from then on.
+	reload func() error
+}
+
+// setupServingCert requests the serving certificate from the cluster CA when ACI_SERVING_CERT_CSR is true,
+// and writes it to the serving certificate files of the options before the kubelet API endpoints
+// are served. ACI_SERVING_CERT_SIGNER sets the signer of the request, ACI_SERVING_CERT_TIMEOUT how
+// long to wait for its approval and ACI_SERVING_CERT_TRUST_DOMAIN adds a SPIFFE ID to the certificate.
+func (p *ACIProvider) setupServingCert(ctx context.Context, opts Options) error {
+	if v := os.Getenv("ACI_SERVING_CERT_CSR"); v == "" {
+		return nil
+	} else if enabled, err := strconv.ParseBool(v); err != nil {
+		return fmt.Errorf("invalid ACI_SERVING_CERT_CSR %q: %v", v, err)
+	} else if !enabled {
+		return nil
+	}
+
+	if p.kubeClient == nil {
+		return fmt.Errorf("requesting the serving certificate requires a kubernetes client")
+	}
+
+	sc := &servingCert{
+		signer:      defaultServingCertSigner,
+		timeout:     defaultServingCertTimeout,
+		trustDomain: os.Getenv("ACI_SERVING_CERT_TRUST_DOMAIN"),
+		certFile:    opts.ServingCertFile,
+		keyFile:     opts.ServingKeyFile,
+		reload:      p.reloadKubeletAPICert,
+	}
+	if sc.certFile == "" || sc.keyFile == "" {
+		return fmt.Errorf("requesting the serving certificate requires APISERVER_CERT_LOCATION and APISERVER_KEY_LOCATION")
+	}
+	if signer := os.Getenv("ACI_SERVING_CERT_SIGNER"); signer != "" {
+		sc.signer = signer
+	}
+	if v := os.Getenv("ACI_SERVING_CERT_TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("invalid ACI_SERVING_CERT_TIMEOUT %q, expected a positive duration", v)
+		}
+		sc.timeout = d
+	}
+
+	cert, err := p.requestServingCert(ctx, sc)
+	if err != nil {
+		return fmt.Errorf("error requesting the serving certificate: %v", err)
+	}
+
+	goSubsystem("serving_cert", func() { p.renewServingCertLoop(context.Background(), sc, cert) })
+	return nil
+}
+
+// servingCertRequest returns the certificate signing request of the node for key, for its addresses.
+func (p *ACIProvider) servingCertRequest(sc *servingCert, key *ecdsa.PrivateKey) ([]byte, error) {
+	template := &x509.CertificateRequest{
+		Subject: pkix.Name{
+			CommonName:   "system:node:" + p.nodeName,
+			Organization: []string{"system:nodes"},
+		},
+	}
+	for _, address := range []string{p.internalIP, p.externalAddress} {
+		if ip := net.ParseIP(address); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else if address != "" {
+			template.DNSNames = append(template.DNSNames, address)
+		}
+	}
+	if p.hostname != "" {
+		template.DNSNames = append(template.DNSNames, p.hostname)
+	}
+	if sc.trustDomain != "" {
+		template.URIs = append(template.URIs, &url.URL{Scheme: "spiffe", Host: sc.trustDomain, Path: "/node/" + p.nodeName})
+	}
+
+	der, err := x509.CreateCertificateRequest(rand.Reader, template, key)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der}), nil
+}
+
+// requestServingCert requests a serving certificate, waits for it to be issued and writes it with its key.
+func (p *ACIProvider) requestServingCert(ctx context.Context, sc *servingCert) (*x509.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	request, err := p.servingCertRequest(sc, key)
+	if err != nil {
+		return nil, err
+	}
+
+	csrs := p.kubeClient.CertificatesV1beta1().CertificateSigningRequests()
+	csr, err := csrs.Create(ctx, &certificates.CertificateSigningRequest{
+		ObjectMeta: metav1.ObjectMeta{GenerateName: "csr-" + p.nodeName + "-"},
+		Spec: certificates.CertificateSigningRequestSpec{
+			Request:    request,
+			SignerName: &sc.signer,
+			Usages: []certificates.KeyUsage{
+				certificates.UsageDigitalSignature,
+				certificates.UsageKeyEncipherment,
+				certificates.UsageServerAuth,
+			},
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return nil, err
+	}
+	log.G(ctx).Infof("requested serving certificate with certificate signing request %s, waiting for its approval", csr.Name)
+
+	ctx, cancel := context.WithTimeout(ctx, sc.timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(servingCertPollInterval)
+	defer ticker.Stop()
+
+	for {
+		csr, err = csrs.Get(ctx, csr.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range csr.Status.Conditions {
+			if c.Type == certificates.CertificateDenied {
+				return nil, fmt.Errorf("certificate signing request %s was denied: %s", csr.Name, c.Message)
+			}
+		}
+		if len(csr.Status.Certificate) > 0 {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("certificate signing request %s was not issued: %v", csr.Name, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+
+	block, _ := pem.Decode(csr.Status.Certificate)
+	if block == nil {
+		return nil, fmt.Errorf("certificate signing request %s issued an invalid certificate", csr.Name)
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(sc.keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(sc.certFile, csr.Status.Certificate, 0644); err != nil {
+		return nil, err
+	}
+
+	return cert, nil
+}
+
+// servingCertRenewal returns when the certificate should be renewed.
+func servingCertRenewal(cert *x509.Certificate) time.Time {
+	lifetime := cert.NotAfter.Sub(cert.NotBefore)
+	return cert.NotBefore.Add(time.Duration(float64(lifetime) * servingCertRenewAt))
+}
+
+// renewServingCertLoop renews the serving certificate once most of its lifetime elapsed, and then
+// reloads it in the kubelet API. Failed renewals are retried until the certificate expires.
+func (p *ACIProvider) renewServingCertLoop(ctx context.Context, sc *servingCert, cert *x509.Certificate) {
+	renewAt := servingCertRenewal(cert)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Until(renewAt)):
+		}
+
+		renewed, err := p.requestServingCert(ctx, sc)
+		if err == nil {
+			err = sc.reload()
+		}
+		if err != nil {
+			log.G(ctx).WithError(err).Errorf("failed to renew the serving certificate expiring at %s", cert.NotAfter)
+			renewAt = time.Now().Add(time.Minute)
+			continue
+		}
+
+		log.G(ctx).Infof("renewed the serving certificate, now expiring at %s", renewed.NotAfter)
+		cert = renewed
+		renewAt = servingCertRenewal(cert)
+	}
+}
@@ -0,0 +1,41 @@
+package provider
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func TestServingCertRequest(t *testing.T) {
+	p := ACIProvider{nodeName: "virtual-node-aci", internalIP: "10.240.0.4", externalAddress: "vk.example.com", hostname: "virtual-node-aci"}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NilError(t, err)
+
+	request, err := p.servingCertRequest(&servingCert{trustDomain: "cluster.local"}, key)
+	assert.NilError(t, err)
+	block, _ := pem.Decode(request)
+	assert.Assert(t, block != nil)
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	assert.NilError(t, err)
+
+	assert.Check(t, is.Equal("system:node:virtual-node-aci", csr.Subject.CommonName))
+	assert.Check(t, is.DeepEqual([]string{"system:nodes"}, csr.Subject.Organization))
+	assert.Assert(t, is.Len(csr.IPAddresses, 1))
+	assert.Check(t, is.Equal("10.240.0.4", csr.IPAddresses[0].String()))
+	assert.Check(t, is.DeepEqual([]string{"vk.example.com", "virtual-node-aci"}, csr.DNSNames))
+	assert.Assert(t, is.Len(csr.URIs, 1))
+	assert.Check(t, is.Equal("spiffe://cluster.local/node/virtual-node-aci", csr.URIs[0].String()))
+}
+
+func TestServingCertRenewal(t *testing.T) {
+	notBefore := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	cert := &x509.Certificate{NotBefore: notBefore, NotAfter: notBefore.Add(10 * 24 * time.Hour)}
+	assert.Check(t, is.Equal(notBefore.Add(8*24*time.Hour), servingCertRenewal(cert)))
+}
@@ -0,0 +1,137 @@
+package provider
+
+import (
+	"context"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/virtual-kubelet/virtual-kubelet/log"
+)
+
+// subsystemGoroutines is the number of goroutines running per subsystem of the provider. The loops are one each,
+// the per pod goroutines, such as the metrics collection and the deletions, must come back to zero once idle.
+var subsystemGoroutines = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: "aci",
+	Name:      "subsystem_goroutines",
+	Help:      "Number of goroutines running per subsystem of the provider.",
+}, []string{"subsystem"})
+
+var (
+	cacheEntriesDesc = prometheus.NewDesc("aci_cache_entries",
+		"Number of entries in the caches of the provider.", []string{"cache"}, nil)
+	queueDepthDesc = prometheus.NewDesc("aci_queue_depth",
+		"Number of pods or requests waiting in the queues of the provider.", []string{"queue"}, nil)
+	armRequestsInFlightDesc = prometheus.NewDesc("aci_arm_requests_in_flight",
+		"Number of requests sent to ARM and not answered yet.", nil, nil)
+)
+
+func init() {
+	prometheus.MustRegister(subsystemGoroutines)
+}
+
+// trackGoroutine counts a goroutine of subsystem until the returned func is called, like
+// defer trackGoroutine("metrics")().
+func trackGoroutine(subsystem string) func() {
+	g := subsystemGoroutines.WithLabelValues(subsystem)
+	g.Inc()
+	return g.Dec
+}
+
+// goSubsystem runs f in a goroutine counted in subsystem.
+func goSubsystem(subsystem string, f func()) {
+	done := trackGoroutine(subsystem)
+	go func() {
+		defer done()
+		f()
+	}()
+}
+
+// metricsCacheSizes are the sizes of the caches of the metrics collection, recorded by GetStatsSummary which
+// holds the metrics mutex for the whole collection, so the scrapes don't wait for it.
+type metricsCacheSizes struct {
+	mu           sync.Mutex
+	lastPodStats int
+	cpuUsage     int
+}
+
+func (s *metricsCacheSizes) set(lastPodStats, cpuUsage int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastPodStats, s.cpuUsage = lastPodStats, cpuUsage
+}
+
+func (s *metricsCacheSizes) get() (lastPodStats, cpuUsage int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastPodStats, s.cpuUsage
+}
+
+// soakCollector collects the sizes of the caches and queues of the provider, and the requests to ARM in flight, at
+// scrape time, so long-running deployments can tell a leak from a load.
+type soakCollector struct {
+	p *ACIProvider
+}
+
+func (c soakCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- cacheEntriesDesc
+	ch <- queueDepthDesc
+	ch <- armRequestsInFlightDesc
+}
+
+func (c soakCollector) Collect(ch chan<- prometheus.Metric) {
+	p := c.p
+	cache := func(name string, n int) {
+		ch <- prometheus.MustNewConstMetric(cacheEntriesDesc, prometheus.GaugeValue, float64(n), name)
+	}
+	queue := func(name string, n int) {
+		ch <- prometheus.MustNewConstMetric(queueDepthDesc, prometheus.GaugeValue, float64(n), name)
+	}
+
+	p.terminalStatuses.mu.RLock()
+	cache("terminal_statuses", len(p.terminalStatuses.statuses))
+	p.terminalStatuses.mu.RUnlock()
+
+	p.instanceViews.mu.Lock()
+	cache("instance_views", len(p.instanceViews.views))
+	p.instanceViews.mu.Unlock()
+
+	p.accessReviews.mu.Lock()
+	cache("access_reviews", len(p.accessReviews.decisions))
+	p.accessReviews.mu.Unlock()
+
+	p.createLatencies.mu.Lock()
+	cache("create_latencies", len(p.createLatencies.pods))
+	p.createLatencies.mu.Unlock()
+
+	cache("arm_errors", p.armErrors.len())
+	cache("zone_placements", p.zonePlacements.len())
+
+	lastPodStats, cpuUsage := p.metricsCaches.get()
+	cache("last_pod_stats", lastPodStats)
+	cache("cpu_usage", cpuUsage)
+
+	p.quotaWaits.mu.Lock()
+	queue("quota", len(p.quotaWaits.pods))
+	p.quotaWaits.mu.Unlock()
+
+	if p.deletions != nil {
+		p.deletions.mu.Lock()
+		queue("deletions", len(p.deletions.pending))
+		p.deletions.mu.Unlock()
+	}
+
+	if p.aciClient != nil {
+		s := p.aciClient.Stats()
+		cache("etags", s.ETagCacheEntries)
+		queue("arm_concurrency", s.Waiting)
+		ch <- prometheus.MustNewConstMetric(armRequestsInFlightDesc, prometheus.GaugeValue, float64(s.InFlight))
+	}
+}
+
+// registerSoakCollector registers the collector of the sizes of the provider with Prometheus. Only one provider
+// of a process is collected, the next ones are logged.
+func (p *ACIProvider) registerSoakCollector(ctx context.Context) {
+	if err := prometheus.Register(soakCollector{p: p}); err != nil {
+		log.G(ctx).WithError(err).Warn("failed to register the collector of the cache sizes and queue depths")
+	}
+}
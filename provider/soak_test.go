@@ -0,0 +1,59 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestTrackGoroutine(t *testing.T) {
+	g := subsystemGoroutines.WithLabelValues("test")
+	done := trackGoroutine("test")
+	assert.Check(t, is.Equal(float64(1), testutil.ToFloat64(g)))
+	done()
+	assert.Check(t, is.Equal(float64(0), testutil.ToFloat64(g)))
+
+	ch := make(chan struct{})
+	stopped := make(chan struct{})
+	goSubsystem("test", func() {
+		defer close(stopped)
+		<-ch
+	})
+	assert.Check(t, is.Equal(float64(1), testutil.ToFloat64(g)))
+	close(ch)
+	<-stopped
+}
+
+func TestSoakCollector(t *testing.T) {
+	p := &ACIProvider{}
+	p.terminalStatuses.statuses = map[string]*v1.PodStatus{"ns/a": {}, "ns/b": {}}
+	p.quotaWaits.pods = map[string]quotaWait{"ns/c": {}}
+	p.metricsCaches.set(3, 4)
+
+	reg := prometheus.NewPedanticRegistry()
+	assert.NilError(t, reg.Register(soakCollector{p: p}))
+	families, err := reg.Gather()
+	assert.NilError(t, err)
+
+	values := make(map[string]float64)
+	for _, f := range families {
+		for _, m := range f.GetMetric() {
+			name := f.GetName()
+			for _, l := range m.GetLabel() {
+				name += "/" + l.GetValue()
+			}
+			values[name] = m.GetGauge().GetValue()
+		}
+	}
+	assert.Check(t, is.Equal(float64(2), values["aci_cache_entries/terminal_statuses"]))
+	assert.Check(t, is.Equal(float64(0), values["aci_cache_entries/instance_views"]))
+	assert.Check(t, is.Equal(float64(3), values["aci_cache_entries/last_pod_stats"]))
+	assert.Check(t, is.Equal(float64(4), values["aci_cache_entries/cpu_usage"]))
+	assert.Check(t, is.Equal(float64(1), values["aci_queue_depth/quota"]))
+	_, ok := values["aci_queue_depth/deletions"]
+	assert.Check(t, !ok, "expected no deletions queue without background deletions")
+}
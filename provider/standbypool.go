@@ -0,0 +1,49 @@
+package provider
+
+import (
+	"strconv"
+
+	"github.com/virtual-kubelet/azure-aci/client/aci"
+	"github.com/virtual-kubelet/virtual-kubelet/errdefs"
+	v1 "k8s.io/api/core/v1"
+)
+
+// Annotations drawing the container group of a pod from a warm standby pool.
+// The profile describes the workload template the pool is warmed up for.
+const (
+	containerGroupProfileAnnotation         = "virtual-kubelet.io/container-group-profile"
+	containerGroupProfileRevisionAnnotation = "virtual-kubelet.io/container-group-profile-revision"
+	standbyPoolAnnotation                   = "virtual-kubelet.io/standby-pool"
+)
+
+func (p *ACIProvider) amendStandbyPoolProfile(containerGroup *aci.ContainerGroup, pod *v1.Pod) error {
+	profileID := pod.Annotations[containerGroupProfileAnnotation]
+	poolID := pod.Annotations[standbyPoolAnnotation]
+	if profileID == "" && poolID == "" {
+		return nil
+	}
+
+	if profileID == "" {
+		return errdefs.InvalidInputf("pod %s uses standby pool %s without the %s annotation", pod.Name, poolID, containerGroupProfileAnnotation)
+	}
+
+	profile := &aci.ContainerGroupProfileReference{ID: profileID}
+	if revision := pod.Annotations[containerGroupProfileRevisionAnnotation]; revision != "" {
+		r, err := strconv.ParseInt(revision, 10, 64)
+		if err != nil {
+			return errdefs.InvalidInputf("invalid container group profile revision %q for pod %s: %v", revision, pod.Name, err)
+		}
+		profile.Revision = &r
+	}
+	containerGroup.ContainerGroupProperties.ContainerGroupProfile = profile
+
+	if poolID != "" {
+		// Let ACI fall back to a regular create when no warm container group can be reused.
+		containerGroup.ContainerGroupProperties.StandbyPoolProfile = &aci.StandbyPoolProfileDefinition{
+			ID:                                     poolID,
+			FailContainerGroupCreateOnReuseFailure: false,
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,171 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"strings"
+
+	client "github.com/virtual-kubelet/azure-aci/client"
+	"github.com/virtual-kubelet/azure-aci/client/keyvault"
+)
+
+// stateKeySize is the size of the AES-256 key the state files are encrypted with.
+const stateKeySize = 32
+
+// keyUnwrapper unwraps the state key with a key of a Key Vault.
+type keyUnwrapper interface {
+	UnwrapKey(ctx context.Context, vaultName, name, version string, wrapped []byte) ([]byte, error)
+}
+
+// stateCipher encrypts the files the virtual kubelet writes on its disk with AES-256-GCM.
+type stateCipher struct {
+	aead cipher.AEAD
+}
+
+func newStateCipher(key []byte) (*stateCipher, error) {
+	if len(key) != stateKeySize {
+		return nil, fmt.Errorf("the key is %d bytes, expected %d", len(key), stateKeySize)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &stateCipher{aead: aead}, nil
+}
+
+// seal encrypts plaintext, prefixed with its random nonce.
+func (c *stateCipher) seal(plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return c.aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// open decrypts a ciphertext sealed by seal.
+func (c *stateCipher) open(ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) < c.aead.NonceSize() {
+		return nil, fmt.Errorf("the ciphertext is too short")
+	}
+	nonce, sealed := ciphertext[:c.aead.NonceSize()], ciphertext[c.aead.NonceSize():]
+	return c.aead.Open(nil, nonce, sealed, nil)
+}
+
+// sealLine encrypts a line of a file, base64 encoded so the file stays line oriented.
+func (c *stateCipher) sealLine(line []byte) ([]byte, error) {
+	sealed, err := c.seal(line)
+	if err != nil {
+		return nil, err
+	}
+	b := make([]byte, base64.StdEncoding.EncodedLen(len(sealed)))
+	base64.StdEncoding.Encode(b, sealed)
+	return b, nil
+}
+
+// openLine decrypts a line sealed by sealLine.
+func (c *stateCipher) openLine(line []byte) ([]byte, error) {
+	sealed, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(line)))
+	if err != nil {
+		return nil, err
+	}
+	return c.open(sealed)
+}
+
+// setupStateEncryption loads the key the files the virtual kubelet writes on its disk are encrypted with, from
+// StateKeyFile in the config file or the ACI_STATE_KEY_FILE environment variable. The file holds the 32 bytes of the
+// key, raw or base64 encoded. With StateKeyVaultKey or ACI_STATE_KEY_VAULT_KEY, the identifier of a key of a Key
+// Vault, the file holds the key wrapped with it instead, unwrapped at startup with the identity of the node.
+func (p *ACIProvider) setupStateEncryption(ctx context.Context, azAuth *client.Authentication) error {
+	if v := os.Getenv("ACI_STATE_KEY_FILE"); v != "" {
+		p.stateKeyFile = v
+	}
+	if v := os.Getenv("ACI_STATE_KEY_VAULT_KEY"); v != "" {
+		p.stateKeyVaultKey = v
+	}
+	if p.stateKeyFile == "" {
+		if p.stateKeyVaultKey != "" {
+			return fmt.Errorf("ACI_STATE_KEY_VAULT_KEY requires ACI_STATE_KEY_FILE")
+		}
+		return nil
+	}
+
+	var vaults keyUnwrapper
+	if p.stateKeyVaultKey != "" {
+		var err error
+		if vaults, err = keyvault.NewClient(azAuth, p.extraUserAgent); err != nil {
+			return fmt.Errorf("error setting up the state encryption: %v", err)
+		}
+	}
+
+	var err error
+	p.stateCipher, err = loadStateCipher(ctx, p.stateKeyFile, p.stateKeyVaultKey, vaults)
+	return err
+}
+
+// loadStateCipher reads the state key from a file, unwrapping it with the key of a Key Vault if keyID is set.
+func loadStateCipher(ctx context.Context, path, keyID string, vaults keyUnwrapper) (*stateCipher, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading the state key: %v", err)
+	}
+	key := decodeKeyFile(b)
+
+	if keyID != "" {
+		vault, name, version, err := parseKeyVaultKeyID(keyID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ACI_STATE_KEY_VAULT_KEY %q: %v", keyID, err)
+		}
+		if key, err = vaults.UnwrapKey(ctx, vault, name, version, key); err != nil {
+			return nil, fmt.Errorf("error unwrapping the state key with %s: %v", keyID, err)
+		}
+	}
+
+	c, err := newStateCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid state key %s: %v", path, err)
+	}
+	return c, nil
+}
+
+// decodeKeyFile returns the content of a key file, base64 decoded if it is base64 encoded.
+func decodeKeyFile(b []byte) []byte {
+	if decoded, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(b))); err == nil {
+		return decoded
+	}
+	return b
+}
+
+// parseKeyVaultKeyID parses the identifier of a key of a Key Vault,
+// https://<vault>.vault.azure.net/keys/<name>[/<version>], in any cloud.
+func parseKeyVaultKeyID(id string) (vault, name, version string, err error) {
+	u, err := url.Parse(id)
+	if err != nil {
+		return "", "", "", err
+	}
+	if u.Scheme != "https" || u.Host == "" {
+		return "", "", "", fmt.Errorf("expected https://<vault>.vault.azure.net/keys/<name>[/<version>]")
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) < 2 || len(parts) > 3 || parts[0] != "keys" || parts[1] == "" {
+		return "", "", "", fmt.Errorf("expected https://<vault>.vault.azure.net/keys/<name>[/<version>]")
+	}
+	vault = strings.SplitN(u.Hostname(), ".", 2)[0]
+	name = parts[1]
+	if len(parts) == 3 {
+		version = parts[2]
+	}
+	return vault, name, version, nil
+}
@@ -0,0 +1,62 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+type fakeUnwrapper struct {
+	vault, name, version string
+	key                  []byte
+}
+
+func (f *fakeUnwrapper) UnwrapKey(ctx context.Context, vaultName, name, version string, wrapped []byte) ([]byte, error) {
+	f.vault, f.name, f.version = vaultName, name, version
+	return f.key, nil
+}
+
+func TestStateEncryption(t *testing.T) {
+	dir, err := ioutil.TempDir("", "state-key")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+
+	key := bytes.Repeat([]byte{7}, stateKeySize)
+	keyFile := filepath.Join(dir, "key")
+	assert.NilError(t, ioutil.WriteFile(keyFile, []byte(base64.StdEncoding.EncodeToString(key)+"\n"), 0600))
+
+	c, err := loadStateCipher(context.Background(), keyFile, "", nil)
+	assert.NilError(t, err)
+
+	var out bytes.Buffer
+	l := &execAuditLog{w: &out, cipher: c}
+	assert.NilError(t, l.write(execAuditRecord{Stage: execSessionStarted, User: "alice", Command: []string{"sh"}}))
+	assert.Assert(t, !bytes.Contains(out.Bytes(), []byte("alice")), "The audit log should be encrypted")
+
+	line, err := c.openLine(out.Bytes())
+	assert.NilError(t, err)
+	var record execAuditRecord
+	assert.NilError(t, json.Unmarshal(line, &record))
+	assert.Equal(t, record.User, "alice")
+
+	assert.NilError(t, ioutil.WriteFile(keyFile, []byte("wrapped"), 0600))
+	vaults := &fakeUnwrapper{key: key}
+	_, err = loadStateCipher(context.Background(), keyFile, "https://vk-state.vault.azure.net/keys/state/0123", vaults)
+	assert.NilError(t, err)
+	assert.Equal(t, vaults.vault, "vk-state")
+	assert.Equal(t, vaults.name, "state")
+	assert.Equal(t, vaults.version, "0123")
+
+	_, err = loadStateCipher(context.Background(), keyFile, "", nil)
+	assert.Assert(t, is.ErrorContains(err, "expected 32"))
+	_, err = loadStateCipher(context.Background(), keyFile, "https://vk-state.vault.azure.net/secrets/state", vaults)
+	assert.Assert(t, is.ErrorContains(err, "invalid ACI_STATE_KEY_VAULT_KEY"))
+}
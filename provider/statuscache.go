@@ -0,0 +1,114 @@
+package provider
+
+import (
+	"encoding/json"
+	"hash/fnv"
+	"sync"
+
+	"github.com/virtual-kubelet/azure-aci/client/aci"
+	v1 "k8s.io/api/core/v1"
+)
+
+// terminalStatusCache keeps the last status of pods whose container group reached a terminal state.
+// The status of a completed container group never changes, so it is served from the cache until the
+// pod is deleted instead of being fetched from ARM again.
+type terminalStatusCache struct {
+	mu       sync.RWMutex
+	statuses map[string]*v1.PodStatus
+}
+
+func terminalStatusCacheKey(namespace, name string) string {
+	return namespace + "/" + name
+}
+
+// get returns a copy of the cached status of the pod, or nil if the pod is not terminated.
+func (c *terminalStatusCache) get(namespace, name string) *v1.PodStatus {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if status, ok := c.statuses[terminalStatusCacheKey(namespace, name)]; ok {
+		return status.DeepCopy()
+	}
+	return nil
+}
+
+// put caches the status of the pod if it is terminal.
+func (c *terminalStatusCache) put(namespace, name string, status *v1.PodStatus) {
+	if status.Phase != v1.PodSucceeded && status.Phase != v1.PodFailed {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.statuses == nil {
+		c.statuses = make(map[string]*v1.PodStatus)
+	}
+	c.statuses[terminalStatusCacheKey(namespace, name)] = status.DeepCopy()
+}
+
+// remove forgets the status of the pod, when it is deleted or created again.
+func (c *terminalStatusCache) remove(namespace, name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.statuses, terminalStatusCacheKey(namespace, name))
+}
+
+// instanceViewCache keeps the status of the pods derived from the last instance view of their container group.
+// The status is only rebuilt when the container group changed since, most polls finding it as it was.
+type instanceViewCache struct {
+	mu    sync.Mutex
+	views map[string]cachedInstanceView
+}
+
+type cachedInstanceView struct {
+	fingerprint uint64
+	status      *v1.PodStatus
+}
+
+// containerGroupFingerprint hashes the container group, its properties and instance views, to detect its changes.
+func containerGroupFingerprint(cg *aci.ContainerGroup) (uint64, bool) {
+	b, err := json.Marshal(cg)
+	if err != nil {
+		return 0, false
+	}
+	h := fnv.New64a()
+	h.Write(b)
+	return h.Sum64(), true
+}
+
+// status returns a copy of the status of the pod derived from the container group, built again only when
+// the container group changed since the last call.
+func (c *instanceViewCache) status(namespace, name string, cg *aci.ContainerGroup, build func(*aci.ContainerGroup) *v1.PodStatus) *v1.PodStatus {
+	fingerprint, ok := containerGroupFingerprint(cg)
+	if !ok {
+		return build(cg)
+	}
+	key := terminalStatusCacheKey(namespace, name)
+
+	c.mu.Lock()
+	if view, found := c.views[key]; found && view.fingerprint == fingerprint {
+		c.mu.Unlock()
+		return view.status.DeepCopy()
+	}
+	c.mu.Unlock()
+
+	status := build(cg)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.views == nil {
+		c.views = make(map[string]cachedInstanceView)
+	}
+	c.views[key] = cachedInstanceView{fingerprint: fingerprint, status: status.DeepCopy()}
+	return status
+}
+
+// remove forgets the instance view of the pod, when it is deleted or created again.
+func (c *instanceViewCache) remove(namespace, name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.views, terminalStatusCacheKey(namespace, name))
+}
@@ -0,0 +1,82 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/virtual-kubelet/azure-aci/client/aci"
+	"github.com/virtual-kubelet/virtual-kubelet/errdefs"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestTerminalStatusCache(t *testing.T) {
+	var c terminalStatusCache
+
+	c.put("ns", "running", &v1.PodStatus{Phase: v1.PodRunning})
+	assert.Check(t, is.Nil(c.get("ns", "running")), "Running pods should not be cached")
+
+	c.put("ns", "job", &v1.PodStatus{Phase: v1.PodSucceeded})
+	status := c.get("ns", "job")
+	assert.Assert(t, status != nil, "Succeeded pods should be cached")
+	assert.Check(t, is.Equal(v1.PodSucceeded, status.Phase))
+
+	status.Phase = v1.PodFailed
+	assert.Check(t, is.Equal(v1.PodSucceeded, c.get("ns", "job").Phase), "Cached status should not be modified by callers")
+
+	c.remove("ns", "job")
+	assert.Check(t, is.Nil(c.get("ns", "job")), "Removed pods should not be cached")
+}
+
+func TestInstanceViewCache(t *testing.T) {
+	var c instanceViewCache
+	builds := 0
+	build := func(cg *aci.ContainerGroup) *v1.PodStatus {
+		builds++
+		return &v1.PodStatus{Phase: v1.PodRunning, Message: cg.ContainerGroupProperties.ProvisioningState}
+	}
+
+	cg := &aci.ContainerGroup{Name: "ns-pod", ContainerGroupProperties: aci.ContainerGroupProperties{ProvisioningState: "Creating"}}
+	assert.Check(t, is.Equal(c.status("ns", "pod", cg, build).Message, "Creating"))
+	status := c.status("ns", "pod", cg, build)
+	assert.Check(t, is.Equal(builds, 1), "Unchanged container groups should not be built again")
+	status.Message = "modified"
+	assert.Check(t, is.Equal(c.status("ns", "pod", cg, build).Message, "Creating"), "Cached status should not be modified by callers")
+
+	cg.ContainerGroupProperties.ProvisioningState = "Succeeded"
+	assert.Check(t, is.Equal(c.status("ns", "pod", cg, build).Message, "Succeeded"))
+	assert.Check(t, is.Equal(builds, 2))
+
+	c.remove("ns", "pod")
+	c.status("ns", "pod", cg, build)
+	assert.Check(t, is.Equal(builds, 3), "Removed pods should be built again")
+}
+
+func TestTerminalStatusRemovedWithContainerGroup(t *testing.T) {
+	_, aciServerMocker, provider, err := prepareMocks()
+	if err != nil {
+		t.Fatal("Unable to prepare the mocks", err)
+	}
+
+	gets := 0
+	aciServerMocker.OnGetContainerGroup = func(subscription, resourceGroup, containerGroup string) (int, interface{}) {
+		gets++
+		return http.StatusNotFound, nil
+	}
+
+	provider.terminalStatuses.put("ns", "job", &v1.PodStatus{Phase: v1.PodSucceeded})
+	status, err := provider.GetPodStatus(context.Background(), "ns", "job")
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(v1.PodSucceeded, status.Phase))
+	assert.Check(t, is.Equal(0, gets), "The terminal status should be served from the cache")
+
+	_, err = provider.getContainerGroup(context.Background(), "ns", "job")
+	assert.Check(t, errdefs.IsNotFound(err), "Expected a not found error, got %v", err)
+	assert.Check(t, is.Nil(provider.terminalStatuses.get("ns", "job")), "The terminal status of a deleted container group should be removed")
+
+	_, err = provider.GetPodStatus(context.Background(), "ns", "job")
+	assert.Check(t, errdefs.IsNotFound(err), "Expected a not found error, got %v", err)
+	assert.Check(t, is.Equal(2, gets))
+}
@@ -0,0 +1,44 @@
+package provider
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// setupStatusSync validates the interval and jitter of the pod status sync, from the config
+// file or the ACI_STATUS_SYNC_INTERVAL and ACI_STATUS_SYNC_JITTER environment variables.
+// The jitter is the fraction of the interval each update is randomly moved by.
+func (p *ACIProvider) setupStatusSync() error {
+	if interval := os.Getenv("ACI_STATUS_SYNC_INTERVAL"); interval != "" {
+		p.statusSyncInterval = interval
+	}
+
+	p.updatesInterval = statusUpdatesInterval
+	if p.statusSyncInterval != "" {
+		d, err := time.ParseDuration(p.statusSyncInterval)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("invalid status sync interval %q, expected a positive duration", p.statusSyncInterval)
+		}
+		p.updatesInterval = d
+	}
+
+	if jitter := os.Getenv("ACI_STATUS_SYNC_JITTER"); jitter != "" {
+		j, err := strconv.ParseFloat(jitter, 64)
+		if err != nil {
+			return fmt.Errorf("invalid ACI_STATUS_SYNC_JITTER %q: %v", jitter, err)
+		}
+		p.statusSyncJitter = &j
+	}
+
+	p.updatesJitter = statusUpdatesJitter
+	if p.statusSyncJitter != nil {
+		if *p.statusSyncJitter < 0 || *p.statusSyncJitter >= 1 {
+			return fmt.Errorf("invalid status sync jitter %v, expected a fraction of the interval between 0 and 1", *p.statusSyncJitter)
+		}
+		p.updatesJitter = *p.statusSyncJitter
+	}
+
+	return nil
+}
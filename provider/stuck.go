@@ -0,0 +1,210 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/virtual-kubelet/azure-aci/client/aci"
+	"github.com/virtual-kubelet/virtual-kubelet/log"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// stuckPodPolicyFail marks the pods stuck provisioning as failed.
+	stuckPodPolicyFail = "Fail"
+	// stuckPodPolicyWait only reports the pods stuck provisioning, and keeps waiting for them.
+	stuckPodPolicyWait = "Wait"
+
+	podStatusReasonProvisioningTimeout = "ProvisioningTimeout"
+
+	// stuckPodEvents is the number of the last ACI events reported for a pod stuck provisioning.
+	stuckPodEvents = 3
+)
+
+// provisioningTracker keeps when the container groups of the pods were created, and whether a pod
+// was already reported stuck provisioning.
+type provisioningTracker struct {
+	mu       sync.Mutex
+	since    map[string]time.Time
+	reported map[string]bool
+}
+
+func provisioningKey(namespace, name string) string {
+	return namespace + "/" + name
+}
+
+// created tracks the container group of a pod created at t.
+func (pt *provisioningTracker) created(namespace, name string, t time.Time) {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+
+	if pt.since == nil {
+		pt.since = make(map[string]time.Time)
+		pt.reported = make(map[string]bool)
+	}
+	key := provisioningKey(namespace, name)
+	pt.since[key] = t
+	delete(pt.reported, key)
+}
+
+// createdAt returns when the container group of the pod was created, or fallback if not tracked.
+func (pt *provisioningTracker) createdAt(namespace, name string, fallback time.Time) time.Time {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+
+	if t, ok := pt.since[provisioningKey(namespace, name)]; ok {
+		return t
+	}
+	return fallback
+}
+
+// report returns whether the pod stuck provisioning was not reported yet, and marks it reported.
+func (pt *provisioningTracker) report(namespace, name string) bool {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+
+	if pt.reported == nil {
+		pt.reported = make(map[string]bool)
+	}
+	key := provisioningKey(namespace, name)
+	if pt.reported[key] {
+		return false
+	}
+	pt.reported[key] = true
+	return true
+}
+
+// remove stops tracking the pod, once its container group provisioned or is deleted.
+func (pt *provisioningTracker) remove(namespace, name string) {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+
+	key := provisioningKey(namespace, name)
+	delete(pt.since, key)
+	delete(pt.reported, key)
+}
+
+// setupProvisioningTimeout validates the provisioning timeout and stuck pod policy, from the config file or the
+// ACI_PROVISION_TIMEOUT, ACI_STUCK_POD_POLICY and ACI_DELETE_STUCK_GROUPS environment variables.
+func (p *ACIProvider) setupProvisioningTimeout() error {
+	if timeout := os.Getenv("ACI_PROVISION_TIMEOUT"); timeout != "" {
+		p.provisionTimeout = timeout
+	}
+	if p.provisionTimeout != "" {
+		d, err := time.ParseDuration(p.provisionTimeout)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("invalid provisioning timeout %q, expected a positive duration", p.provisionTimeout)
+		}
+		p.stuckPodTimeout = d
+	}
+
+	if policy := os.Getenv("ACI_STUCK_POD_POLICY"); policy != "" {
+		p.stuckPodPolicy = policy
+	}
+	switch p.stuckPodPolicy {
+	case "":
+		p.stuckPodPolicy = stuckPodPolicyFail
+	case stuckPodPolicyFail, stuckPodPolicyWait:
+	default:
+		return fmt.Errorf("invalid stuck pod policy %q, expected %s or %s", p.stuckPodPolicy, stuckPodPolicyFail, stuckPodPolicyWait)
+	}
+
+	if deleteStuck := os.Getenv("ACI_DELETE_STUCK_GROUPS"); deleteStuck != "" {
+		b, err := strconv.ParseBool(deleteStuck)
+		if err != nil {
+			return fmt.Errorf("invalid ACI_DELETE_STUCK_GROUPS %q: %v", deleteStuck, err)
+		}
+		p.deleteStuckGroups = b
+	}
+	if p.deleteStuckGroups && p.stuckPodPolicy != stuckPodPolicyFail {
+		return fmt.Errorf("deleting stuck container groups requires the %s stuck pod policy", stuckPodPolicyFail)
+	}
+
+	return nil
+}
+
+func isProvisioningState(state string) bool {
+	switch state {
+	case "Accepted", "Pending", "Creating", "Repairing":
+		return true
+	}
+	return false
+}
+
+// checkProvisioningTimeout reports the pods whose container group is provisioning for longer than the
+// provisioning timeout, and depending on the stuck pod policy marks them failed and deletes their container group.
+func (p *ACIProvider) checkProvisioningTimeout(ctx context.Context, namespace, name string, cg *aci.ContainerGroup, status *v1.PodStatus) {
+	if p.stuckPodTimeout <= 0 {
+		return
+	}
+
+	state, creationTime := aciResourceMetaFromContainerGroup(cg)
+	if !isProvisioningState(state) {
+		p.provisioning.remove(namespace, name)
+		return
+	}
+
+	createdAt := p.provisioning.createdAt(namespace, name, creationTime.Time)
+	if createdAt.IsZero() {
+		return
+	}
+	elapsed := time.Since(createdAt)
+	if elapsed < p.stuckPodTimeout {
+		return
+	}
+
+	message := fmt.Sprintf("Container group %s is %s for %s", cg.Name, state, elapsed.Round(time.Second))
+	if events := lastACIEvents(cg, stuckPodEvents); len(events) > 0 {
+		message += ", last ACI events: " + strings.Join(events, "; ")
+	}
+
+	if p.provisioning.report(namespace, name) {
+		log.G(ctx).WithField("pod", name).WithField("namespace", namespace).Warn(message)
+		pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, UID: types.UID(cg.Tags["UID"])}}
+		p.recordEvent(pod, v1.EventTypeWarning, podStatusReasonProvisioningTimeout, "%s", message)
+
+		if p.deleteStuckGroups {
+			if err := p.aciClient.DeleteContainerGroup(ctx, p.resourceGroup, cg.Name); err != nil {
+				log.G(ctx).WithError(err).Errorf("failed to delete stuck container group %v", cg.Name)
+			}
+		}
+	}
+
+	if p.stuckPodPolicy == stuckPodPolicyFail {
+		status.Phase = v1.PodFailed
+		status.Reason = podStatusReasonProvisioningTimeout
+		status.Message = message
+	}
+}
+
+// lastACIEvents returns the messages of the last n events of the container group and its containers.
+func lastACIEvents(cg *aci.ContainerGroup, n int) []string {
+	events := append([]aci.Event{}, cg.InstanceView.Events...)
+	for _, c := range cg.Containers {
+		events = append(events, c.InstanceView.Events...)
+	}
+
+	latest := make([]aci.Event, 0, n)
+	for _, e := range events {
+		latest = append(latest, e)
+		for i := len(latest) - 1; i > 0 && time.Time(latest[i].LastTimestamp).After(time.Time(latest[i-1].LastTimestamp)); i-- {
+			latest[i], latest[i-1] = latest[i-1], latest[i]
+		}
+		if len(latest) > n {
+			latest = latest[:n]
+		}
+	}
+
+	messages := make([]string, 0, len(latest))
+	for _, e := range latest {
+		messages = append(messages, fmt.Sprintf("%s: %s", e.Name, e.Message))
+	}
+	return messages
+}
@@ -0,0 +1,50 @@
+package provider
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/virtual-kubelet/azure-aci/client/aci"
+	"github.com/virtual-kubelet/azure-aci/client/api"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestCheckProvisioningTimeout(t *testing.T) {
+	p := ACIProvider{stuckPodTimeout: time.Minute, stuckPodPolicy: stuckPodPolicyFail}
+	cg := &aci.ContainerGroup{
+		Name: "ns-pod",
+		ContainerGroupProperties: aci.ContainerGroupProperties{
+			ProvisioningState: "Creating",
+			Containers: []aci.Container{{
+				Name: "nginx",
+				ContainerProperties: aci.ContainerProperties{
+					InstanceView: aci.ContainerPropertiesInstanceView{
+						Events: []aci.Event{
+							{Name: "Pulling", Message: "pulling image nginx", LastTimestamp: api.JSONTime(time.Now().Add(-5 * time.Minute))},
+						},
+					},
+				},
+			}},
+		},
+	}
+
+	p.provisioning.created("ns", "pod", time.Now())
+	status := &v1.PodStatus{Phase: v1.PodPending}
+	p.checkProvisioningTimeout(context.Background(), "ns", "pod", cg, status)
+	assert.Check(t, is.Equal(v1.PodPending, status.Phase), "Pods provisioning within the timeout should be pending")
+
+	p.provisioning.created("ns", "pod", time.Now().Add(-10*time.Minute))
+	p.checkProvisioningTimeout(context.Background(), "ns", "pod", cg, status)
+	assert.Check(t, is.Equal(v1.PodFailed, status.Phase), "Pods stuck provisioning should be failed")
+	assert.Check(t, is.Equal(podStatusReasonProvisioningTimeout, status.Reason))
+	assert.Check(t, strings.Contains(status.Message, "pulling image nginx"), "The last ACI events should be reported, got %q", status.Message)
+
+	p.stuckPodPolicy = stuckPodPolicyWait
+	status = &v1.PodStatus{Phase: v1.PodPending}
+	p.checkProvisioningTimeout(context.Background(), "ns", "pod", cg, status)
+	assert.Check(t, is.Equal(v1.PodPending, status.Phase), "Pods stuck provisioning should keep waiting with the Wait policy")
+}
@@ -0,0 +1,120 @@
+package provider
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/virtual-kubelet/virtual-kubelet/log"
+)
+
+const (
+	subnetCapacityInterval = 5 * time.Minute
+
+	// azureReservedIPs is the number of IP addresses Azure reserves in every subnet.
+	azureReservedIPs = 5
+)
+
+// subnetCapacity is the number of pods the delegated subnet has IP addresses for, once known.
+type subnetCapacity struct {
+	mu    sync.Mutex
+	pods  int
+	known bool
+}
+
+func (sc *subnetCapacity) get() (int, bool) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	return sc.pods, sc.known
+}
+
+func (sc *subnetCapacity) set(pods int) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	sc.pods = pods
+	sc.known = true
+}
+
+// subnetUsableIPs returns the number of IP addresses of the subnet available to container groups.
+func subnetUsableIPs(cidr string) int {
+	_, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return 0
+	}
+
+	ones, bits := ipNet.Mask.Size()
+	if bits-ones >= 31 {
+		return int(^uint(0) >> 1)
+	}
+	usable := 1<<uint(bits-ones) - azureReservedIPs
+	if usable < 0 {
+		return 0
+	}
+	return usable
+}
+
+// podsCapacity returns the pods capacity of the node, lowered to the pods the delegated subnet has IP
+// addresses for.
+func (p *ACIProvider) podsCapacity() string {
+	pods, known := p.subnetPods.get()
+	if !known {
+		return p.pods
+	}
+
+	if configured, err := strconv.Atoi(p.pods); err == nil && configured < pods {
+		return p.pods
+	}
+	return strconv.Itoa(pods)
+}
+
+// updateSubnetCapacity computes the pods the delegated subnet has IP addresses for: the pods of the node,
+// which already have an IP address, and the IP addresses left in the subnet.
+func (p *ACIProvider) updateSubnetCapacity(ctx context.Context) {
+	subnet, err := p.networkClient.GetSubnet(p.vnetResourceGroup, p.vnetName, p.subnetName)
+	if err != nil {
+		log.G(ctx).WithError(err).Warn("failed to get the subnet to compute the pods capacity")
+		return
+	}
+	if subnet.SubnetPropertiesFormat == nil || subnet.SubnetPropertiesFormat.AddressPrefix == nil {
+		return
+	}
+
+	used := 0
+	if subnet.SubnetPropertiesFormat.IPConfigurations != nil {
+		used = len(*subnet.SubnetPropertiesFormat.IPConfigurations)
+	}
+	free := subnetUsableIPs(*subnet.SubnetPropertiesFormat.AddressPrefix) - used
+	if free < 0 {
+		free = 0
+	}
+
+	pods := free + len(p.resourceManager.GetPods())
+	log.G(ctx).Debugf("subnet %s has %d IP addresses left, pods capacity is %d", p.subnetName, free, pods)
+	p.subnetPods.set(pods)
+}
+
+// subnetCapacityLoop keeps the pods capacity of the node in line with the IP addresses left in the
+// delegated subnet, until the context is done.
+func (p *ACIProvider) subnetCapacityLoop(ctx context.Context) {
+	if p.networkClient == nil || p.subnetName == "" {
+		return
+	}
+
+	p.updateSubnetCapacity(ctx)
+
+	ticker := time.NewTicker(subnetCapacityInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.updateSubnetCapacity(ctx)
+		}
+	}
+}
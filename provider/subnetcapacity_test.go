@@ -0,0 +1,26 @@
+package provider
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func TestSubnetUsableIPs(t *testing.T) {
+	assert.Check(t, is.Equal(251, subnetUsableIPs("10.0.0.0/24")))
+	assert.Check(t, is.Equal(11, subnetUsableIPs("10.0.0.0/28")))
+	assert.Check(t, is.Equal(0, subnetUsableIPs("10.0.0.0/30")))
+	assert.Check(t, is.Equal(0, subnetUsableIPs("not a cidr")))
+}
+
+func TestPodsCapacity(t *testing.T) {
+	p := ACIProvider{pods: "20"}
+	assert.Check(t, is.Equal("20", p.podsCapacity()), "Pods capacity should be configured without subnet")
+
+	p.subnetPods.set(5)
+	assert.Check(t, is.Equal("5", p.podsCapacity()), "Pods capacity should be lowered to the IP addresses left")
+
+	p.subnetPods.set(50)
+	assert.Check(t, is.Equal("20", p.podsCapacity()), "Pods capacity should not exceed the configured pods")
+}
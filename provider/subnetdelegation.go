@@ -0,0 +1,59 @@
+package provider
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	aznetwork "github.com/Azure/azure-sdk-for-go/services/network/mgmt/2018-08-01/network"
+	"github.com/virtual-kubelet/azure-aci/client/network"
+)
+
+// setupSubnetManagement reads whether the provider creates the subnet and delegates it to ACI when it is
+// not, from ACI_MANAGE_SUBNET or the config file, true by default. Without it, the subnet must be
+// delegated beforehand.
+func (p *ACIProvider) setupSubnetManagement() error {
+	p.manageSubnet = true
+	if p.manageSubnetConfig != nil {
+		p.manageSubnet = *p.manageSubnetConfig
+	}
+	if v := os.Getenv("ACI_MANAGE_SUBNET"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid ACI_MANAGE_SUBNET %q: %v", v, err)
+		}
+		p.manageSubnet = b
+	}
+	return nil
+}
+
+// subnetDelegatedToACI reports whether an existing subnet is delegated to ACI, and fails if it is
+// delegated to another service, as a subnet can only be delegated to one.
+func subnetDelegatedToACI(subnet *aznetwork.Subnet) (bool, error) {
+	if subnet.SubnetPropertiesFormat == nil || subnet.SubnetPropertiesFormat.Delegations == nil {
+		return false, nil
+	}
+	for _, d := range *subnet.SubnetPropertiesFormat.Delegations {
+		if d.ServiceDelegationPropertiesFormat == nil || d.ServiceDelegationPropertiesFormat.ServiceName == nil {
+			continue
+		}
+		if *d.ServiceDelegationPropertiesFormat.ServiceName == subnetDelegationService {
+			return true, nil
+		}
+		name := ""
+		if subnet.Name != nil {
+			name = *subnet.Name
+		}
+		return false, fmt.Errorf("unable to delegate subnet '%s' to Azure Container Instance as it is delegated to '%s'", name, *d.ServiceDelegationPropertiesFormat.ServiceName)
+	}
+	return false, nil
+}
+
+// subnetWriteError describes the failure to write the subnet, with the permission missing when the
+// identity of the virtual kubelet is not allowed to.
+func subnetWriteError(action, subnetName string, err error) error {
+	if network.IsForbidden(err) {
+		return fmt.Errorf("error %s subnet '%s', the identity of the virtual kubelet needs the Microsoft.Network/virtualNetworks/subnets/write permission, or the subnet must be delegated to %s beforehand: %v", action, subnetName, subnetDelegationService, err)
+	}
+	return fmt.Errorf("error %s subnet: %v", action, err)
+}
@@ -0,0 +1,48 @@
+package provider
+
+import (
+	"os"
+	"testing"
+
+	aznetwork "github.com/Azure/azure-sdk-for-go/services/network/mgmt/2018-08-01/network"
+	"github.com/virtual-kubelet/azure-aci/client/network"
+	"gotest.tools/assert"
+)
+
+func TestSubnetDelegatedToACI(t *testing.T) {
+	nsg := &aznetwork.SecurityGroup{ID: stringPtr("nsg-id")}
+	subnet := &aznetwork.Subnet{
+		Name:                   stringPtr("aci"),
+		SubnetPropertiesFormat: &aznetwork.SubnetPropertiesFormat{AddressPrefix: stringPtr("10.1.0.0/24"), NetworkSecurityGroup: nsg},
+	}
+	delegated, err := subnetDelegatedToACI(subnet)
+	assert.NilError(t, err)
+	assert.Check(t, !delegated)
+
+	network.AddContainerInstanceDelegation(subnet)
+	delegated, err = subnetDelegatedToACI(subnet)
+	assert.NilError(t, err)
+	assert.Check(t, delegated)
+	assert.Equal(t, subnet.SubnetPropertiesFormat.NetworkSecurityGroup, nsg, "The repaired subnet should keep its properties")
+
+	other := "Microsoft.Web/serverFarms"
+	subnet.SubnetPropertiesFormat.Delegations = &[]aznetwork.Delegation{{ServiceDelegationPropertiesFormat: &aznetwork.ServiceDelegationPropertiesFormat{ServiceName: &other}}}
+	_, err = subnetDelegatedToACI(subnet)
+	assert.ErrorContains(t, err, "delegated to 'Microsoft.Web/serverFarms'")
+}
+
+func TestSetupSubnetManagement(t *testing.T) {
+	var p ACIProvider
+	assert.NilError(t, p.setupSubnetManagement())
+	assert.Check(t, p.manageSubnet, "The subnet should be managed by default")
+
+	disabled := false
+	p.manageSubnetConfig = &disabled
+	assert.NilError(t, p.setupSubnetManagement())
+	assert.Check(t, !p.manageSubnet)
+
+	os.Setenv("ACI_MANAGE_SUBNET", "true")
+	defer os.Unsetenv("ACI_MANAGE_SUBNET")
+	assert.NilError(t, p.setupSubnetManagement())
+	assert.Check(t, p.manageSubnet, "The environment should override the config file")
+}
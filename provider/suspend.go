@@ -0,0 +1,55 @@
+package provider
+
+import (
+	"context"
+	"strings"
+
+	"github.com/virtual-kubelet/azure-aci/client/aci"
+	"github.com/virtual-kubelet/virtual-kubelet/log"
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	// suspendAnnotation set to "true" stops the container group of the pod, removing it resumes the pod.
+	// Stopping preserves the container group definition and its cached images, so resuming is faster than recreating.
+	suspendAnnotation = "virtual-kubelet.io/suspend"
+
+	aciStateStopped          = "Stopped"
+	podStatusReasonSuspended = "Suspended"
+)
+
+func isPodSuspended(pod *v1.Pod) bool {
+	return strings.EqualFold(pod.Annotations[suspendAnnotation], "true")
+}
+
+// reconcileSuspend stops or starts the container group to match the suspend annotation of the pod.
+// It reports whether an action was taken on the container group.
+func (p *ACIProvider) reconcileSuspend(ctx context.Context, pod *v1.Pod, cg *aci.ContainerGroup) (bool, error) {
+	stopped := cg.ContainerGroupProperties.InstanceView.State == aciStateStopped
+	suspended := isPodSuspended(pod)
+
+	switch {
+	case suspended && !stopped:
+		log.G(ctx).Infof("start suspending pod %v", pod.Name)
+		if err := p.aciClient.StopContainerGroup(ctx, p.resourceGroup, cg.Name); err != nil {
+			log.G(ctx).WithError(err).Errorf("failed to stop container group %v", cg.Name)
+			return true, err
+		}
+		return true, nil
+	case !suspended && stopped && cg.Tags[crashLoopStoppedTag] != "":
+		// The container group was stopped because its containers crash loop, the pod failed.
+		return true, nil
+	case !suspended && stopped:
+		log.G(ctx).Infof("start resuming pod %v", pod.Name)
+		if err := p.aciClient.StartContainerGroup(ctx, p.resourceGroup, cg.Name); err != nil {
+			log.G(ctx).WithError(err).Errorf("failed to start container group %v", cg.Name)
+			return true, err
+		}
+		return true, nil
+	case suspended && stopped:
+		// Nothing can be changed on a stopped container group until it is resumed.
+		return true, nil
+	}
+
+	return false, nil
+}
@@ -0,0 +1,107 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/virtual-kubelet/azure-aci/client/aci"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestUpdatePodWithSuspend(t *testing.T) {
+	_, aciServerMocker, provider, err := prepareMocks()
+	if err != nil {
+		t.Fatal("Unable to prepare the mocks", err)
+	}
+
+	state := "Running"
+	tags := map[string]string{"NodeName": fakeNodeName}
+	aciServerMocker.OnGetContainerGroup = func(subscription, resourceGroup, containerGroup string) (int, interface{}) {
+		return http.StatusOK, aci.ContainerGroup{
+			Name: containerGroup,
+			Tags: tags,
+			ContainerGroupProperties: aci.ContainerGroupProperties{
+				ProvisioningState: "Succeeded",
+				InstanceView: aci.ContainerGroupPropertiesInstanceView{
+					State: state,
+				},
+				Containers: []aci.Container{
+					aci.Container{
+						Name: "nginx",
+						ContainerProperties: aci.ContainerProperties{
+							Image: "nginx",
+							Resources: aci.ResourceRequirements{
+								Requests: &aci.ComputeResources{
+									CPU:        1,
+									MemoryInGB: 1.5,
+								},
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+	var actions []string
+	aciServerMocker.OnAction = func(subscription, resourceGroup, containerGroup, action string) (int, interface{}) {
+		assert.Check(t, is.Equal("ns-pod", containerGroup), "Container group name is not expected")
+		actions = append(actions, action)
+		return http.StatusNoContent, nil
+	}
+	aciServerMocker.OnCreate = func(subscription, resourceGroup, containerGroup string, cg *aci.ContainerGroup) (int, interface{}) {
+		t.Error("A suspended container group should not be resized")
+		return http.StatusOK, cg
+	}
+
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "pod",
+			Namespace:   "ns",
+			Annotations: map[string]string{suspendAnnotation: "true"},
+		},
+		Spec: v1.PodSpec{
+			Containers: []v1.Container{
+				v1.Container{
+					Name:  "nginx",
+					Image: "nginx",
+					Resources: v1.ResourceRequirements{
+						Requests: v1.ResourceList{
+							"cpu":    resource.MustParse("2"),
+							"memory": resource.MustParse("1.5G"),
+						},
+					},
+				},
+			},
+		},
+	}
+
+	// Suspending a running pod stops its container group.
+	assert.NilError(t, provider.UpdatePod(context.Background(), pod))
+	assert.Check(t, is.DeepEqual([]string{"stop"}, actions))
+
+	// Nothing is changed on a suspended pod, not even its resources.
+	state = aciStateStopped
+	actions = nil
+	assert.NilError(t, provider.UpdatePod(context.Background(), pod))
+	assert.Check(t, is.Len(actions, 0))
+
+	status, err := provider.GetPodStatus(context.Background(), "ns", "pod")
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(podStatusReasonSuspended, status.Reason))
+
+	// Removing the annotation resumes the pod.
+	delete(pod.Annotations, suspendAnnotation)
+	assert.NilError(t, provider.UpdatePod(context.Background(), pod))
+	assert.Check(t, is.DeepEqual([]string{"start"}, actions))
+
+	// A container group stopped for crash looping is not resumed.
+	tags[crashLoopStoppedTag] = "Container nginx restarted 10 times"
+	actions = nil
+	assert.NilError(t, provider.UpdatePod(context.Background(), pod))
+	assert.Check(t, is.Len(actions, 0))
+}
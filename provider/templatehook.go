@@ -0,0 +1,184 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/virtual-kubelet/azure-aci/client/aci"
+	"github.com/virtual-kubelet/azure-aci/client/api"
+	"github.com/virtual-kubelet/virtual-kubelet/errdefs"
+	"github.com/virtual-kubelet/virtual-kubelet/log"
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	// templateHookFail fails the creation of the pods when the template hook can't be called.
+	templateHookFail = "Fail"
+	// templateHookIgnore creates the container groups as translated when the template hook can't be called.
+	templateHookIgnore = "Ignore"
+
+	defaultTemplateHookTimeout = 10 * time.Second
+)
+
+// templateHookRequest is the body of the calls to the template hook.
+type templateHookRequest struct {
+	Pod            *v1.Pod             `json:"pod"`
+	ContainerGroup *aci.ContainerGroup `json:"containerGroup"`
+}
+
+// templateHookResponse is the body of the responses of the template hook.
+type templateHookResponse struct {
+	// Patch is a JSON merge patch of the container group, in the format of the ACI API.
+	Patch json.RawMessage `json:"patch,omitempty"`
+	// Error rejects the pod with its message.
+	Error string `json:"error,omitempty"`
+}
+
+// templateHook calls a webhook with the container groups translated from the pods before they are sent to ARM.
+type templateHook struct {
+	url           string
+	client        *http.Client
+	failurePolicy string
+}
+
+// setupTemplateHook enables the template hook from HookURL, HookCAFile, HookTimeout and HookFailurePolicy in the
+// config file or the ACI_TEMPLATE_HOOK_URL, ACI_TEMPLATE_HOOK_CA_FILE, ACI_TEMPLATE_HOOK_TIMEOUT and
+// ACI_TEMPLATE_HOOK_FAILURE_POLICY environment variables. The hook is only called over HTTPS.
+func (p *ACIProvider) setupTemplateHook() error {
+	if v := os.Getenv("ACI_TEMPLATE_HOOK_URL"); v != "" {
+		p.hookURL = v
+	}
+	if v := os.Getenv("ACI_TEMPLATE_HOOK_CA_FILE"); v != "" {
+		p.hookCAFile = v
+	}
+	if v := os.Getenv("ACI_TEMPLATE_HOOK_TIMEOUT"); v != "" {
+		p.hookTimeout = v
+	}
+	if v := os.Getenv("ACI_TEMPLATE_HOOK_FAILURE_POLICY"); v != "" {
+		p.hookFailurePolicy = v
+	}
+	if p.hookURL == "" {
+		return nil
+	}
+
+	u, err := url.Parse(p.hookURL)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("invalid ACI_TEMPLATE_HOOK_URL %q: expected an https URL", p.hookURL)
+	}
+
+	timeout := defaultTemplateHookTimeout
+	if p.hookTimeout != "" {
+		if timeout, err = time.ParseDuration(p.hookTimeout); err != nil {
+			return fmt.Errorf("invalid ACI_TEMPLATE_HOOK_TIMEOUT %q: %v", p.hookTimeout, err)
+		}
+	}
+
+	switch p.hookFailurePolicy {
+	case "":
+		p.hookFailurePolicy = templateHookFail
+	case templateHookFail, templateHookIgnore:
+	default:
+		return fmt.Errorf("invalid ACI_TEMPLATE_HOOK_FAILURE_POLICY %q, expected %s or %s", p.hookFailurePolicy, templateHookFail, templateHookIgnore)
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if p.hookCAFile != "" {
+		pem, err := ioutil.ReadFile(p.hookCAFile)
+		if err != nil {
+			return fmt.Errorf("error reading the template hook CA: %v", err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("invalid ACI_TEMPLATE_HOOK_CA_FILE %q: no certificate found", p.hookCAFile)
+		}
+	}
+
+	p.templateHook = &templateHook{
+		url: p.hookURL,
+		client: &http.Client{
+			Timeout:   timeout,
+			Transport: &http.Transport{TLSClientConfig: tlsConfig, Proxy: http.ProxyFromEnvironment},
+		},
+		failurePolicy: p.hookFailurePolicy,
+	}
+	return nil
+}
+
+// call sends the pod and its container group to the hook, and returns its response.
+func (h *templateHook) call(ctx context.Context, pod *v1.Pod, cg *aci.ContainerGroup) (*templateHookResponse, error) {
+	b, err := json.Marshal(templateHookRequest{Pod: pod, ContainerGroup: cg})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest("POST", h.url, bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("template hook returned %s", resp.Status)
+	}
+
+	var response templateHookResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("invalid template hook response: %v", err)
+	}
+	return &response, nil
+}
+
+// applyTemplateHook lets the template hook mutate the container group of a pod, to set the fields the provider does
+// not model. The patch of the hook is applied to the container group, and kept to be applied on its creation for the
+// properties the client does not model. A hook which can't be called fails the pod, or is ignored with the Ignore
+// failure policy.
+func (p *ACIProvider) applyTemplateHook(ctx context.Context, pod *v1.Pod, cg *aci.ContainerGroup) error {
+	if p.templateHook == nil {
+		return nil
+	}
+
+	response, err := p.templateHook.call(ctx, pod, cg)
+	if err != nil {
+		if p.templateHook.failurePolicy == templateHookIgnore {
+			log.G(ctx).WithError(err).Warnf("failed to call the template hook for pod %s/%s, ignoring it", pod.Namespace, pod.Name)
+			return nil
+		}
+		return fmt.Errorf("error calling the template hook for pod %s/%s: %v", pod.Namespace, pod.Name, err)
+	}
+	if response.Error != "" {
+		return errdefs.InvalidInputf("pod %s is rejected by the template hook: %s", pod.Name, response.Error)
+	}
+	if len(response.Patch) == 0 || string(response.Patch) == "null" {
+		return nil
+	}
+
+	b, err := json.Marshal(cg)
+	if err != nil {
+		return err
+	}
+	patched, err := api.MergePatch(b, response.Patch)
+	if err != nil {
+		return errdefs.InvalidInputf("pod %s: invalid patch of the template hook: %v", pod.Name, err)
+	}
+	var mutated aci.ContainerGroup
+	if err := json.Unmarshal(patched, &mutated); err != nil {
+		return errdefs.InvalidInputf("pod %s: invalid patch of the template hook: %v", pod.Name, err)
+	}
+	mutated.MergePatch = response.Patch
+	*cg = mutated
+	return nil
+}
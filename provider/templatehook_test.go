@@ -0,0 +1,59 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/virtual-kubelet/azure-aci/client/aci"
+	"github.com/virtual-kubelet/virtual-kubelet/errdefs"
+	"gotest.tools/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestApplyTemplateHook(t *testing.T) {
+	var response string
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request templateHookRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.Pod.Name != "web" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(response))
+	}))
+	defer server.Close()
+
+	p := ACIProvider{templateHook: &templateHook{url: server.URL, client: server.Client(), failurePolicy: templateHookFail}}
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "ns"}}
+	ctx := context.Background()
+
+	cg := &aci.ContainerGroup{Location: "eastus", Tags: map[string]string{"PodName": "web"}}
+	response = `{"patch": {"tags": {"team": "a"}, "properties": {"priority": "Spot"}}}`
+	assert.NilError(t, p.applyTemplateHook(ctx, pod, cg))
+	assert.Equal(t, cg.Location, "eastus")
+	assert.DeepEqual(t, cg.Tags, map[string]string{"PodName": "web", "team": "a"})
+	assert.Equal(t, string(cg.MergePatch), `{"tags": {"team": "a"}, "properties": {"priority": "Spot"}}`, "The patch should be kept for the unmodeled properties")
+
+	response = `{"error": "no GPU quota for the team"}`
+	assert.Assert(t, errdefs.IsInvalidInput(p.applyTemplateHook(ctx, pod, cg)))
+
+	pod.Name = "other"
+	assert.ErrorContains(t, p.applyTemplateHook(ctx, pod, cg), "400 Bad Request")
+	p.templateHook.failurePolicy = templateHookIgnore
+	assert.NilError(t, p.applyTemplateHook(ctx, pod, cg))
+}
+
+func TestSetupTemplateHook(t *testing.T) {
+	p := ACIProvider{hookURL: "http://hook.example.com"}
+	assert.ErrorContains(t, p.setupTemplateHook(), "expected an https URL")
+
+	p = ACIProvider{hookURL: "https://hook.example.com", hookFailurePolicy: "Retry"}
+	assert.ErrorContains(t, p.setupTemplateHook(), "invalid ACI_TEMPLATE_HOOK_FAILURE_POLICY")
+
+	p = ACIProvider{hookURL: "https://hook.example.com"}
+	assert.NilError(t, p.setupTemplateHook())
+	assert.Equal(t, p.templateHook.failurePolicy, templateHookFail)
+}
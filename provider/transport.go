@@ -0,0 +1,97 @@
+package provider
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// setupTransport validates the connection pool and retry options of the ACI client, from the config file or the
+// ACI_HTTP_KEEPALIVES, ACI_HTTP_MAX_IDLE_CONNS, ACI_HTTP_IDLE_TIMEOUT, ACI_HTTP2, ACI_HTTP_RETRY_WAIT_MIN,
+// ACI_HTTP_RETRY_WAIT_MAX, ACI_HTTP_RETRY_MAX and ACI_HTTP_COMPRESSION environment variables.
+func (p *ACIProvider) setupTransport() error {
+	if keepAlives := os.Getenv("ACI_HTTP_KEEPALIVES"); keepAlives != "" {
+		b, err := strconv.ParseBool(keepAlives)
+		if err != nil {
+			return fmt.Errorf("invalid ACI_HTTP_KEEPALIVES %q: %v", keepAlives, err)
+		}
+		p.transportOptions.KeepAlives = b
+	}
+
+	if maxIdleConns := os.Getenv("ACI_HTTP_MAX_IDLE_CONNS"); maxIdleConns != "" {
+		n, err := strconv.Atoi(maxIdleConns)
+		if err != nil {
+			return fmt.Errorf("invalid ACI_HTTP_MAX_IDLE_CONNS %q: %v", maxIdleConns, err)
+		}
+		p.transportOptions.MaxIdleConnsPerHost = n
+	}
+	if p.transportOptions.MaxIdleConnsPerHost < 0 {
+		return fmt.Errorf("invalid max idle connections per host %d, expected a positive number", p.transportOptions.MaxIdleConnsPerHost)
+	}
+
+	if idleTimeout := os.Getenv("ACI_HTTP_IDLE_TIMEOUT"); idleTimeout != "" {
+		p.httpIdleTimeout = idleTimeout
+	}
+	if p.httpIdleTimeout != "" {
+		d, err := time.ParseDuration(p.httpIdleTimeout)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("invalid idle connection timeout %q, expected a positive duration", p.httpIdleTimeout)
+		}
+		p.transportOptions.IdleConnTimeout = d
+	}
+
+	if http2 := os.Getenv("ACI_HTTP2"); http2 != "" {
+		b, err := strconv.ParseBool(http2)
+		if err != nil {
+			return fmt.Errorf("invalid ACI_HTTP2 %q: %v", http2, err)
+		}
+		p.transportOptions.HTTP2 = b
+	}
+
+	if retryWaitMin := os.Getenv("ACI_HTTP_RETRY_WAIT_MIN"); retryWaitMin != "" {
+		p.httpRetryWaitMin = retryWaitMin
+	}
+	if p.httpRetryWaitMin != "" {
+		d, err := time.ParseDuration(p.httpRetryWaitMin)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("invalid minimum retry wait %q, expected a positive duration", p.httpRetryWaitMin)
+		}
+		p.transportOptions.RetryWaitMin = d
+	}
+
+	if retryWaitMax := os.Getenv("ACI_HTTP_RETRY_WAIT_MAX"); retryWaitMax != "" {
+		p.httpRetryWaitMax = retryWaitMax
+	}
+	if p.httpRetryWaitMax != "" {
+		d, err := time.ParseDuration(p.httpRetryWaitMax)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("invalid maximum retry wait %q, expected a positive duration", p.httpRetryWaitMax)
+		}
+		p.transportOptions.RetryWaitMax = d
+	}
+	if p.transportOptions.RetryWaitMin > 0 && p.transportOptions.RetryWaitMax > 0 && p.transportOptions.RetryWaitMax < p.transportOptions.RetryWaitMin {
+		return fmt.Errorf("invalid maximum retry wait %s, expected at least the minimum retry wait %s", p.transportOptions.RetryWaitMax, p.transportOptions.RetryWaitMin)
+	}
+
+	if retryMax := os.Getenv("ACI_HTTP_RETRY_MAX"); retryMax != "" {
+		n, err := strconv.Atoi(retryMax)
+		if err != nil {
+			return fmt.Errorf("invalid ACI_HTTP_RETRY_MAX %q: %v", retryMax, err)
+		}
+		p.transportOptions.RetryMax = &n
+	}
+	if p.transportOptions.RetryMax != nil && *p.transportOptions.RetryMax < 0 {
+		return fmt.Errorf("invalid max retries %d, expected a positive number", *p.transportOptions.RetryMax)
+	}
+
+	if compression := os.Getenv("ACI_HTTP_COMPRESSION"); compression != "" {
+		b, err := strconv.ParseBool(compression)
+		if err != nil {
+			return fmt.Errorf("invalid ACI_HTTP_COMPRESSION %q: %v", compression, err)
+		}
+		p.transportOptions.DisableCompression = !b
+	}
+
+	return nil
+}
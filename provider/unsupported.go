@@ -0,0 +1,159 @@
+package provider
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/virtual-kubelet/virtual-kubelet/errdefs"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// podConditionUnsupportedFields is the condition of the pods with fields ignored by ACI.
+	podConditionUnsupportedFields v1.PodConditionType = "UnsupportedFields"
+
+	eventReasonUnsupportedFields = "UnsupportedFields"
+
+	// unsupportedFieldsTag is the container group tag listing the fields of the pod ignored by ACI,
+	// so its status keeps the condition across restarts of the virtual kubelet.
+	unsupportedFieldsTag = "UnsupportedFields"
+	// maxTagValueLength is the longest value of an Azure resource tag.
+	maxTagValueLength = 256
+
+	unsupportedFieldsWarn   = "warn"
+	unsupportedFieldsReject = "reject"
+)
+
+// setupUnsupportedFields reads how pods with fields ACI can't honor are handled from
+// ACI_UNSUPPORTED_FIELDS or the config file: warn, the default, creates them with an event
+// and a condition listing the fields, reject refuses them.
+func (p *ACIProvider) setupUnsupportedFields() error {
+	if v := os.Getenv("ACI_UNSUPPORTED_FIELDS"); v != "" {
+		p.unsupportedFields = v
+	}
+	switch p.unsupportedFields {
+	case "":
+		p.unsupportedFields = unsupportedFieldsWarn
+	case unsupportedFieldsWarn, unsupportedFieldsReject:
+	default:
+		return fmt.Errorf("invalid unsupported fields policy %q, must be %s or %s", p.unsupportedFields, unsupportedFieldsWarn, unsupportedFieldsReject)
+	}
+	return nil
+}
+
+// unsupportedPodFields returns the paths of the fields set on the pod which ACI has no equivalent for,
+// and which the translation to a container group would otherwise silently drop.
+func unsupportedPodFields(pod *v1.Pod) []string {
+	var fields []string
+	spec := pod.Spec
+	if spec.HostNetwork {
+		fields = append(fields, "spec.hostNetwork")
+	}
+	if spec.HostPID {
+		fields = append(fields, "spec.hostPID")
+	}
+	if spec.HostIPC {
+		fields = append(fields, "spec.hostIPC")
+	}
+	if spec.ShareProcessNamespace != nil && *spec.ShareProcessNamespace {
+		fields = append(fields, "spec.shareProcessNamespace")
+	}
+	if psc := spec.SecurityContext; psc != nil {
+		// Only the user and group of the pod security context are inherited by the containers.
+		if psc.FSGroup != nil {
+			fields = append(fields, "spec.securityContext.fsGroup")
+		}
+		if len(psc.SupplementalGroups) > 0 {
+			fields = append(fields, "spec.securityContext.supplementalGroups")
+		}
+		if len(psc.Sysctls) > 0 {
+			fields = append(fields, "spec.securityContext.sysctls")
+		}
+		if psc.WindowsOptions != nil {
+			fields = append(fields, "spec.securityContext.windowsOptions")
+		}
+	}
+	if len(spec.TopologySpreadConstraints) > 0 {
+		fields = append(fields, "spec.topologySpreadConstraints")
+	}
+	if len(spec.HostAliases) > 0 {
+		fields = append(fields, "spec.hostAliases")
+	}
+	if spec.ActiveDeadlineSeconds != nil {
+		fields = append(fields, "spec.activeDeadlineSeconds")
+	}
+
+	fields = append(fields, unsupportedAppArmorFields(pod)...)
+
+	for _, c := range spec.Containers {
+		prefix := fmt.Sprintf("spec.containers[%s].", c.Name)
+		if c.Lifecycle != nil {
+			fields = append(fields, prefix+"lifecycle")
+		}
+		if c.StartupProbe != nil {
+			fields = append(fields, prefix+"startupProbe")
+		}
+		if c.Stdin {
+			fields = append(fields, prefix+"stdin")
+		}
+		if c.TTY {
+			fields = append(fields, prefix+"tty")
+		}
+		if len(c.VolumeDevices) > 0 {
+			fields = append(fields, prefix+"volumeDevices")
+		}
+		for _, m := range c.VolumeMounts {
+			if m.SubPath != "" || m.SubPathExpr != "" {
+				fields = append(fields, fmt.Sprintf("%svolumeMounts[%s].subPath", prefix, m.Name))
+			}
+			if m.MountPropagation != nil && *m.MountPropagation != v1.MountPropagationNone {
+				fields = append(fields, fmt.Sprintf("%svolumeMounts[%s].mountPropagation", prefix, m.Name))
+			}
+		}
+	}
+	return fields
+}
+
+// unsupportedFieldsTagValue joins the fields into a tag value, truncated to the longest tag value.
+func unsupportedFieldsTagValue(fields []string) string {
+	v := strings.Join(fields, ",")
+	if len(v) > maxTagValueLength {
+		v = v[:maxTagValueLength-3] + "..."
+	}
+	return v
+}
+
+// checkUnsupportedFields reports the fields of the pod ignored by ACI with a single event, and rejects
+// the pod instead with the reject policy.
+func (p *ACIProvider) checkUnsupportedFields(pod *v1.Pod) error {
+	fields := unsupportedPodFields(pod)
+	if len(fields) == 0 {
+		return nil
+	}
+
+	list := strings.Join(fields, ", ")
+	if p.unsupportedFields == unsupportedFieldsReject {
+		p.recordEvent(pod, v1.EventTypeWarning, eventReasonUnsupportedFields, "Pod is rejected, ACI does not support the fields: %s", list)
+		return errdefs.InvalidInputf("pod %s can not be created, ACI does not support the fields: %s", pod.Name, list)
+	}
+	p.recordEvent(pod, v1.EventTypeWarning, eventReasonUnsupportedFields, "ACI ignores the unsupported fields: %s", list)
+	return nil
+}
+
+// unsupportedFieldsCondition returns the condition listing the fields ignored by ACI from the tag of the
+// container group, if any.
+func unsupportedFieldsCondition(tags map[string]string, creationTime metav1.Time) (v1.PodCondition, bool) {
+	fields := tags[unsupportedFieldsTag]
+	if fields == "" {
+		return v1.PodCondition{}, false
+	}
+	return v1.PodCondition{
+		Type:               podConditionUnsupportedFields,
+		Status:             v1.ConditionTrue,
+		LastTransitionTime: creationTime,
+		Reason:             eventReasonUnsupportedFields,
+		Message:            "ACI ignores the unsupported fields: " + strings.Replace(fields, ",", ", ", -1),
+	}, true
+}
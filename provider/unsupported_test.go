@@ -0,0 +1,89 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/virtual-kubelet/virtual-kubelet/errdefs"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func unsupportedFieldsPod() *v1.Pod {
+	share := true
+	fsGroup := int64(2000)
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "ns"},
+		Spec: v1.PodSpec{
+			HostPID:               true,
+			ShareProcessNamespace: &share,
+			SecurityContext: &v1.PodSecurityContext{
+				FSGroup:            &fsGroup,
+				SupplementalGroups: []int64{3000},
+				Sysctls:            []v1.Sysctl{{Name: "net.core.somaxconn", Value: "1024"}},
+				WindowsOptions:     &v1.WindowsSecurityContextOptions{},
+			},
+			TopologySpreadConstraints: []v1.TopologySpreadConstraint{
+				{MaxSkew: 1, TopologyKey: "topology.kubernetes.io/zone", WhenUnsatisfiable: v1.DoNotSchedule},
+			},
+			Containers: []v1.Container{{
+				Name:         "app",
+				Lifecycle:    &v1.Lifecycle{PreStop: &v1.Handler{Exec: &v1.ExecAction{Command: []string{"sleep", "5"}}}},
+				VolumeMounts: []v1.VolumeMount{{Name: "data", MountPath: "/data", SubPath: "app"}},
+			}},
+		},
+	}
+}
+
+func TestUnsupportedPodFields(t *testing.T) {
+	fields := unsupportedPodFields(unsupportedFieldsPod())
+	assert.DeepEqual(t, fields, []string{
+		"spec.hostPID",
+		"spec.shareProcessNamespace",
+		"spec.securityContext.fsGroup",
+		"spec.securityContext.supplementalGroups",
+		"spec.securityContext.sysctls",
+		"spec.securityContext.windowsOptions",
+		"spec.topologySpreadConstraints",
+		"spec.containers[app].lifecycle",
+		"spec.containers[app].volumeMounts[data].subPath",
+	})
+
+	assert.Check(t, is.Len(unsupportedPodFields(&v1.Pod{Spec: v1.PodSpec{Containers: []v1.Container{{Name: "app"}}}}), 0))
+}
+
+func TestCheckUnsupportedFields(t *testing.T) {
+	p := ACIProvider{}
+	assert.NilError(t, p.setupUnsupportedFields())
+	assert.Equal(t, p.unsupportedFields, unsupportedFieldsWarn)
+	assert.NilError(t, p.checkUnsupportedFields(unsupportedFieldsPod()))
+
+	p.unsupportedFields = unsupportedFieldsReject
+	err := p.checkUnsupportedFields(unsupportedFieldsPod())
+	assert.Check(t, errdefs.IsInvalidInput(err), "Expected an invalid input error, got %v", err)
+	assert.ErrorContains(t, err, "spec.hostPID, spec.shareProcessNamespace")
+
+	p.unsupportedFields = "ignore"
+	assert.ErrorContains(t, p.setupUnsupportedFields(), "invalid unsupported fields policy")
+}
+
+func TestUnsupportedFieldsCondition(t *testing.T) {
+	fields := unsupportedPodFields(unsupportedFieldsPod())
+	tags := map[string]string{unsupportedFieldsTag: unsupportedFieldsTagValue(fields)}
+
+	condition, ok := unsupportedFieldsCondition(tags, metav1.Now())
+	assert.Assert(t, ok)
+	assert.Equal(t, condition.Type, podConditionUnsupportedFields)
+	assert.Equal(t, condition.Status, v1.ConditionTrue)
+	assert.Check(t, is.Contains(condition.Message, "spec.hostPID, spec.shareProcessNamespace"))
+
+	_, ok = unsupportedFieldsCondition(map[string]string{}, metav1.Now())
+	assert.Check(t, !ok)
+
+	long := make([]string, 50)
+	for i := range long {
+		long[i] = "spec.containers[container].lifecycle"
+	}
+	assert.Check(t, is.Len(unsupportedFieldsTagValue(long), maxTagValueLength))
+}
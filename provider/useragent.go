@@ -0,0 +1,52 @@
+package provider
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// Version and GitCommit identify the build of the provider in the user agent of its requests to Azure.
+// They are set by the virtual kubelet command.
+var (
+	Version   = "N/A"
+	GitCommit = "N/A"
+)
+
+// setupUserAgent builds the extra user agent of the requests to Azure: ACI_EXTRA_USER_AGENT, the version
+// and commit of the provider, the cluster ID when opted in with UserAgentClusterID in the config file or
+// ACI_USER_AGENT_CLUSTER_ID, and the token of the operator from UserAgentSuffix or ACI_USER_AGENT_SUFFIX.
+func (p *ACIProvider) setupUserAgent() error {
+	if clusterID := os.Getenv("ACI_USER_AGENT_CLUSTER_ID"); clusterID != "" {
+		p.userAgentClusterID = clusterID
+	}
+	if suffix := os.Getenv("ACI_USER_AGENT_SUFFIX"); suffix != "" {
+		p.userAgentSuffix = suffix
+	}
+
+	tokens := []string{
+		os.Getenv("ACI_EXTRA_USER_AGENT"),
+		fmt.Sprintf("vk-azure-aci/%s", Version),
+		fmt.Sprintf("(commit %s)", GitCommit),
+	}
+	if p.userAgentClusterID != "" {
+		tokens = append(tokens, fmt.Sprintf("(cluster %s)", p.userAgentClusterID))
+	}
+	tokens = append(tokens, p.userAgentSuffix)
+
+	for _, token := range tokens {
+		if i := strings.IndexFunc(token, unicode.IsControl); i >= 0 {
+			return fmt.Errorf("invalid user agent %q, it must not contain control characters", token)
+		}
+	}
+
+	var userAgent []string
+	for _, token := range tokens {
+		if token = strings.TrimSpace(token); token != "" {
+			userAgent = append(userAgent, token)
+		}
+	}
+	p.extraUserAgent = strings.Join(userAgent, " ")
+	return nil
+}
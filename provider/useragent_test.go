@@ -0,0 +1,21 @@
+package provider
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func TestUserAgent(t *testing.T) {
+	p := ACIProvider{}
+	assert.NilError(t, p.setupUserAgent())
+	assert.Check(t, is.Equal("vk-azure-aci/N/A (commit N/A)", p.extraUserAgent))
+
+	p = ACIProvider{userAgentClusterID: "aks-prod-1", userAgentSuffix: "contoso-batch/1.2"}
+	assert.NilError(t, p.setupUserAgent())
+	assert.Check(t, is.Equal("vk-azure-aci/N/A (commit N/A) (cluster aks-prod-1) contoso-batch/1.2", p.extraUserAgent))
+
+	p = ACIProvider{userAgentSuffix: "contoso\r\nX-Injected: 1"}
+	assert.Check(t, p.setupUserAgent() != nil, "User agent with control characters should be rejected")
+}
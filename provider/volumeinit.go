@@ -0,0 +1,211 @@
+package provider
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/virtual-kubelet/azure-aci/client/aci"
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	// defaultVolumeInitImage is the image of the init containers copying files into volumes.
+	defaultVolumeInitImage = "busybox:1.32"
+	// defaultFileMode is the mode of the files of secret and configMap volumes.
+	defaultFileMode int32 = 0644
+
+	volumeInitSourcePath = "/mnt/source"
+	volumeInitTargetPath = "/mnt/target"
+	maxACINameLength     = 63
+)
+
+// setupVolumeInit reads the images of the init containers populating volumes, from ACI_VOLUME_INIT_IMAGE
+// and ACI_GIT_REPO_IMAGE or the config file. gitRepo volumes are cloned by ACI unless a git image is set.
+func (p *ACIProvider) setupVolumeInit() {
+	if image := os.Getenv("ACI_VOLUME_INIT_IMAGE"); image != "" {
+		p.volumeInitImage = image
+	}
+	if p.volumeInitImage == "" {
+		p.volumeInitImage = defaultVolumeInitImage
+	}
+	if image := os.Getenv("ACI_GIT_REPO_IMAGE"); image != "" {
+		p.gitRepoImage = image
+	}
+}
+
+// volumeFile is a file of a secret or configMap volume: the key of its content, its path in the volume
+// and its mode.
+type volumeFile struct {
+	key  string
+	path string
+	mode int32
+}
+
+// projectVolumeFiles returns the files of a secret or configMap volume with the keys of its data. Only the
+// items are projected if any, at their path, and the files have the default mode unless an item sets one.
+func projectVolumeFiles(keys []string, items []v1.KeyToPath, defaultMode *int32) []volumeFile {
+	mode := defaultFileMode
+	if defaultMode != nil {
+		mode = *defaultMode
+	}
+
+	var files []volumeFile
+	if len(items) == 0 {
+		sorted := append([]string{}, keys...)
+		sort.Strings(sorted)
+		for _, k := range sorted {
+			files = append(files, volumeFile{key: k, path: k, mode: mode})
+		}
+		return files
+	}
+
+	for _, item := range items {
+		f := volumeFile{key: item.Key, path: item.Path, mode: mode}
+		if item.Mode != nil {
+			f.mode = *item.Mode
+		}
+		files = append(files, f)
+	}
+	return files
+}
+
+// needsFileModes reports whether the files of a volume have another mode than the one of ACI secret volumes.
+func needsFileModes(items []v1.KeyToPath, defaultMode *int32) bool {
+	if defaultMode != nil && *defaultMode != defaultFileMode {
+		return true
+	}
+	for _, item := range items {
+		if item.Mode != nil && *item.Mode != defaultFileMode {
+			return true
+		}
+	}
+	return false
+}
+
+// aciName returns a name valid for ACI volumes and containers from a prefix and a pod volume name.
+func aciName(prefix, name string) string {
+	n := prefix + name
+	if len(n) > maxACINameLength {
+		n = strings.TrimRight(n[:maxACINameLength], "-")
+	}
+	return n
+}
+
+// copyFilesVolumes translates a secret or configMap volume whose files can't be represented by an ACI
+// secret volume. The data is stored in an ACI secret volume, and copied by an init container into an
+// empty dir volume with the name of the pod volume, at the paths and with the modes of the files.
+func (p *ACIProvider) copyFilesVolumes(name string, data map[string]string, files []volumeFile) ([]aci.Volume, aci.InitContainerDefinition) {
+	source := aciName("vk-source-", name)
+
+	script := []string{"set -e"}
+	for _, f := range files {
+		target := path.Join(volumeInitTargetPath, f.path)
+		script = append(script,
+			fmt.Sprintf("mkdir -p %s", shellQuote(path.Dir(target))),
+			fmt.Sprintf("cp %s %s", shellQuote(path.Join(volumeInitSourcePath, f.key)), shellQuote(target)),
+			fmt.Sprintf("chmod %o %s", f.mode, shellQuote(target)),
+		)
+	}
+
+	volumes := []aci.Volume{
+		{Name: source, Secret: data},
+		{Name: name, EmptyDir: map[string]interface{}{}},
+	}
+	initContainer := aci.InitContainerDefinition{
+		Name: aciName("vk-copy-", name),
+		Properties: aci.InitContainerPropertiesDefinition{
+			Image:   p.volumeInitImage,
+			Command: []string{"/bin/sh", "-c", strings.Join(script, "\n")},
+			VolumeMounts: []aci.VolumeMount{
+				{Name: source, MountPath: volumeInitSourcePath, ReadOnly: true},
+				{Name: name, MountPath: volumeInitTargetPath},
+			},
+		},
+	}
+	return volumes, initContainer
+}
+
+// gitRepoInitContainer returns the init container cloning the repository of a gitRepo volume into an
+// empty dir volume, like the kubelet does: into the directory of the volume, or a sub-directory named
+// after the repository if none is set.
+func (p *ACIProvider) gitRepoInitContainer(name string, g *v1.GitRepoVolumeSource) aci.InitContainerDefinition {
+	clone := "git clone -- " + shellQuote(g.Repository)
+	dir := g.Directory
+	if dir != "" {
+		clone += " " + shellQuote(dir)
+	} else {
+		dir = strings.TrimSuffix(path.Base(g.Repository), ".git")
+	}
+
+	script := []string{"set -e", "cd " + volumeInitTargetPath, clone}
+	if g.Revision != "" {
+		script = append(script, "cd "+shellQuote(dir), "git checkout "+shellQuote(g.Revision)+" --", "git reset --hard")
+	}
+
+	return aci.InitContainerDefinition{
+		Name: aciName("vk-clone-", name),
+		Properties: aci.InitContainerPropertiesDefinition{
+			Image:   p.gitRepoImage,
+			Command: []string{"/bin/sh", "-c", strings.Join(script, "\n")},
+			VolumeMounts: []aci.VolumeMount{
+				{Name: name, MountPath: volumeInitTargetPath},
+			},
+		},
+	}
+}
+
+// getProjectedVolumes translates a secret or configMap volume with the data of its source. The items are
+// projected at their path, and a missing key fails the pod unless the volume is optional. The data is
+// stored in an ACI secret volume, copied by an init container into an empty dir if the files are in
+// sub-directories or have another mode. A volume without files is an empty dir.
+func (p *ACIProvider) getProjectedVolumes(pod *v1.Pod, name, source string, data map[string][]byte, items []v1.KeyToPath, defaultMode *int32, optional bool) ([]aci.Volume, *aci.InitContainerDefinition, error) {
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+
+	present := make([]v1.KeyToPath, 0, len(items))
+	for _, item := range items {
+		if _, ok := data[item.Key]; !ok {
+			if optional {
+				continue
+			}
+			return nil, nil, fmt.Errorf("%s is missing key %s required by volume %s of Pod %s", source, item.Key, name, pod.Name)
+		}
+		present = append(present, item)
+	}
+
+	var files []volumeFile
+	if len(items) == 0 || len(present) > 0 {
+		files = projectVolumeFiles(keys, present, defaultMode)
+	}
+	if len(files) == 0 {
+		return []aci.Volume{{Name: name, EmptyDir: map[string]interface{}{}}}, nil, nil
+	}
+
+	copyFiles := needsFileModes(present, defaultMode)
+	for _, f := range files {
+		if strings.Contains(f.path, "/") {
+			copyFiles = true
+		}
+	}
+
+	paths := make(map[string]string, len(files))
+	if copyFiles {
+		// The files are copied from the keys, which are valid file names.
+		for _, f := range files {
+			paths[f.key] = base64.StdEncoding.EncodeToString(data[f.key])
+		}
+		volumes, initContainer := p.copyFilesVolumes(name, paths, files)
+		return volumes, &initContainer, nil
+	}
+
+	for _, f := range files {
+		paths[f.path] = base64.StdEncoding.EncodeToString(data[f.key])
+	}
+	return []aci.Volume{{Name: name, Secret: paths}}, nil, nil
+}
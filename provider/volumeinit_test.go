@@ -0,0 +1,98 @@
+package provider
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	v1 "k8s.io/api/core/v1"
+)
+
+func int32Ptr(i int32) *int32 {
+	return &i
+}
+
+func TestProjectVolumeFiles(t *testing.T) {
+	files := projectVolumeFiles([]string{"b", "a"}, nil, int32Ptr(0400))
+	assert.Assert(t, is.Len(files, 2))
+	assert.Check(t, files[0] == volumeFile{key: "a", path: "a", mode: 0400}, "Unexpected file %+v", files[0])
+	assert.Check(t, files[1] == volumeFile{key: "b", path: "b", mode: 0400}, "Unexpected file %+v", files[1])
+
+	files = projectVolumeFiles([]string{"a", "run"}, []v1.KeyToPath{{Key: "run", Path: "bin/run.sh", Mode: int32Ptr(0755)}}, nil)
+	assert.Assert(t, is.Len(files, 1), "Only the items should be projected")
+	assert.Check(t, files[0] == volumeFile{key: "run", path: "bin/run.sh", mode: 0755}, "Unexpected file %+v", files[0])
+}
+
+func TestNeedsFileModes(t *testing.T) {
+	assert.Check(t, !needsFileModes(nil, nil))
+	assert.Check(t, !needsFileModes([]v1.KeyToPath{{Key: "a", Path: "a"}}, int32Ptr(0644)))
+	assert.Check(t, needsFileModes(nil, int32Ptr(0755)))
+	assert.Check(t, needsFileModes([]v1.KeyToPath{{Key: "a", Path: "a", Mode: int32Ptr(0700)}}, nil))
+}
+
+func TestCopyFilesVolumes(t *testing.T) {
+	p := ACIProvider{}
+	p.setupVolumeInit()
+
+	volumes, init := p.copyFilesVolumes("scripts", map[string]string{"run": "ZWNobw=="}, []volumeFile{{key: "run", path: "bin/run.sh", mode: 0755}})
+	assert.Assert(t, is.Len(volumes, 2))
+	assert.Check(t, is.Equal("vk-source-scripts", volumes[0].Name))
+	assert.Check(t, is.Equal("ZWNobw==", volumes[0].Secret["run"]))
+	assert.Check(t, is.Equal("scripts", volumes[1].Name))
+	assert.Check(t, volumes[1].EmptyDir != nil, "Pod volume should be an empty dir")
+
+	assert.Check(t, is.Equal(defaultVolumeInitImage, init.Properties.Image))
+	script := init.Properties.Command[2]
+	assert.Check(t, is.Contains(script, "cp /mnt/source/run /mnt/target/bin/run.sh"))
+	assert.Check(t, is.Contains(script, "chmod 755 /mnt/target/bin/run.sh"))
+}
+
+func TestGitRepoInitContainer(t *testing.T) {
+	p := ACIProvider{gitRepoImage: "alpine/git"}
+
+	init := p.gitRepoInitContainer("source", &v1.GitRepoVolumeSource{Repository: "https://github.com/virtual-kubelet/azure-aci.git", Revision: "v1.0.0"})
+	script := init.Properties.Command[2]
+	assert.Check(t, is.Equal("alpine/git", init.Properties.Image))
+	assert.Check(t, is.Contains(script, "git clone -- https://github.com/virtual-kubelet/azure-aci.git\n"))
+	assert.Check(t, is.Contains(script, "cd azure-aci\ngit checkout v1.0.0 --"))
+
+	init = p.gitRepoInitContainer("source", &v1.GitRepoVolumeSource{Repository: "https://example.com/repo", Directory: "."})
+	assert.Check(t, strings.HasSuffix(init.Properties.Command[2], "git clone -- https://example.com/repo ."))
+}
+
+func TestProjectedVolumes(t *testing.T) {
+	p := ACIProvider{}
+	p.setupVolumeInit()
+	pod := &v1.Pod{}
+	pod.Name = "nginx"
+	cert := []byte{0x30, 0x82, 0x01, 0x0a, 0x00, 0xff}
+	data := map[string][]byte{"tls.crt": cert, "tls.key": []byte("key")}
+
+	volumes, init, err := p.getProjectedVolumes(pod, "certs", "Secret certs", data, nil, nil, false)
+	assert.NilError(t, err)
+	assert.Check(t, init == nil, "Flat files should not need an init container")
+	assert.Assert(t, is.Len(volumes, 1))
+	assert.Check(t, is.Equal(base64.StdEncoding.EncodeToString(cert), volumes[0].Secret["tls.crt"]), "Binary data should be kept")
+
+	items := []v1.KeyToPath{{Key: "tls.crt", Path: "server.crt"}}
+	volumes, init, err = p.getProjectedVolumes(pod, "certs", "Secret certs", data, items, nil, false)
+	assert.NilError(t, err)
+	assert.Check(t, init == nil)
+	assert.Check(t, is.DeepEqual(map[string]string{"server.crt": base64.StdEncoding.EncodeToString(cert)}, volumes[0].Secret), "Only the items should be projected at their path")
+
+	items = []v1.KeyToPath{{Key: "tls.crt", Path: "ssl/server.crt"}}
+	volumes, init, err = p.getProjectedVolumes(pod, "certs", "Secret certs", data, items, nil, false)
+	assert.NilError(t, err)
+	assert.Assert(t, init != nil, "Files in sub-directories should be copied by an init container")
+	assert.Check(t, is.Contains(init.Properties.Command[2], "/mnt/target/ssl/server.crt"))
+	assert.Check(t, is.Len(volumes[0].Secret, 1))
+
+	items = []v1.KeyToPath{{Key: "ca.crt", Path: "ca.crt"}}
+	_, _, err = p.getProjectedVolumes(pod, "certs", "Secret certs", data, items, nil, false)
+	assert.Check(t, err != nil, "Missing key should fail a required volume")
+	volumes, _, err = p.getProjectedVolumes(pod, "certs", "Secret certs", data, items, nil, true)
+	assert.NilError(t, err)
+	assert.Check(t, volumes[0].EmptyDir != nil, "Optional volume without files should be an empty dir")
+}
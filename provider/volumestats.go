@@ -0,0 +1,160 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	client "github.com/virtual-kubelet/azure-aci/client"
+	"github.com/virtual-kubelet/azure-aci/client/aci"
+	"github.com/virtual-kubelet/azure-aci/client/storage"
+	"github.com/virtual-kubelet/virtual-kubelet/log"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	stats "k8s.io/kubernetes/pkg/kubelet/apis/stats/v1alpha1"
+)
+
+// storageAccountsRefresh is how often the storage accounts of the subscription are listed again to find
+// the resource group of an account missing from the last list.
+const storageAccountsRefresh = 10 * time.Minute
+
+// fileShareStats reads the usage of the Azure Files shares mounted by the container groups.
+type fileShareStats interface {
+	GetFileShare(ctx context.Context, resourceGroup, accountName, shareName string) (*storage.FileShare, error)
+	ListAccounts(ctx context.Context) ([]storage.Account, error)
+}
+
+// storageAccountGroups caches the resource group of the storage accounts of the subscription, as the
+// Azure Files volumes of a container group only have the name of their account.
+type storageAccountGroups struct {
+	mu       sync.Mutex
+	groups   map[string]string
+	listedAt time.Time
+}
+
+// setupVolumeStats enables the stats of the Azure Files volumes in the stats summary with
+// ACI_VOLUME_STATS or the config file. They are opt-in as they need to read the storage accounts of
+// the subscription, and cost a request per volume on each summary.
+func (p *ACIProvider) setupVolumeStats(azAuth *client.Authentication) error {
+	if v := os.Getenv("ACI_VOLUME_STATS"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid ACI_VOLUME_STATS %q: %v", v, err)
+		}
+		p.volumeStatsEnabled = b
+	}
+	if !p.volumeStatsEnabled {
+		return nil
+	}
+
+	shares, err := storage.NewClient(azAuth, p.extraUserAgent)
+	if err != nil {
+		return fmt.Errorf("error setting up volume stats: %v", err)
+	}
+	p.volumeStats = shares
+	return nil
+}
+
+// hasAzureFileVolumes reports whether the pod has volumes which may be mounted from Azure Files.
+func hasAzureFileVolumes(pod *v1.Pod) bool {
+	for _, v := range pod.Spec.Volumes {
+		if v.AzureFile != nil || v.PersistentVolumeClaim != nil || v.EmptyDir != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// storageAccountResourceGroup returns the resource group of a storage account of the subscription.
+func (p *ACIProvider) storageAccountResourceGroup(ctx context.Context, account string) (string, error) {
+	if p.scratchAccount != "" && account == p.scratchAccount {
+		return p.scratchResourceGroup(), nil
+	}
+
+	c := &p.accountGroups
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if rg, ok := c.groups[account]; ok {
+		return rg, nil
+	}
+	if c.groups == nil || time.Since(c.listedAt) > storageAccountsRefresh {
+		accounts, err := p.volumeStats.ListAccounts(ctx)
+		if err != nil {
+			return "", fmt.Errorf("unable to list the storage accounts: %v", err)
+		}
+		c.groups = make(map[string]string, len(accounts))
+		for _, a := range accounts {
+			c.groups[a.Name] = a.ResourceGroup()
+		}
+		c.listedAt = time.Now()
+	}
+	if rg, ok := c.groups[account]; ok {
+		return rg, nil
+	}
+	return "", fmt.Errorf("storage account %s is not in the subscription", account)
+}
+
+// getPodVolumeStats returns the stats of the Azure Files volumes of the container group of the pod, with
+// the quota of the share as capacity and its usage as used bytes. A volume whose share can't be read is
+// left out of the stats instead of failing the whole summary.
+func (p *ACIProvider) getPodVolumeStats(ctx context.Context, pod *v1.Pod, cg *aci.ContainerGroup) []stats.VolumeStats {
+	claims := make(map[string]string)
+	for _, v := range pod.Spec.Volumes {
+		if v.PersistentVolumeClaim != nil {
+			claims[v.Name] = v.PersistentVolumeClaim.ClaimName
+		}
+	}
+
+	var volumeStats []stats.VolumeStats
+	for _, v := range cg.Volumes {
+		if v.AzureFile == nil {
+			continue
+		}
+		logger := log.G(ctx).WithField("volume", v.Name)
+
+		rg, err := p.storageAccountResourceGroup(ctx, v.AzureFile.StorageAccountName)
+		if err != nil {
+			logger.WithError(err).Warn("Unable to get the stats of the volume")
+			continue
+		}
+		share, err := p.volumeStats.GetFileShare(ctx, rg, v.AzureFile.StorageAccountName, v.AzureFile.ShareName)
+		if err != nil {
+			logger.WithError(err).Warn("Unable to get the stats of the volume")
+			continue
+		}
+
+		vs := stats.VolumeStats{
+			Name: v.Name,
+			FsStats: stats.FsStats{
+				Time: metav1.Now(),
+			},
+		}
+		if claim, ok := claims[v.Name]; ok {
+			vs.PVCRef = &stats.PVCReference{Name: claim, Namespace: pod.Namespace}
+		}
+		if props := share.Properties; props != nil {
+			var capacity, used uint64
+			if props.ShareQuota > 0 {
+				capacity = uint64(props.ShareQuota) << 30
+				vs.CapacityBytes = &capacity
+			}
+			if props.ShareUsageBytes != nil {
+				used = uint64(*props.ShareUsageBytes)
+				vs.UsedBytes = &used
+			}
+			if vs.CapacityBytes != nil && vs.UsedBytes != nil {
+				available := uint64(0)
+				if capacity > used {
+					available = capacity - used
+				}
+				vs.AvailableBytes = &available
+			}
+		}
+		volumeStats = append(volumeStats, vs)
+	}
+	return volumeStats
+}
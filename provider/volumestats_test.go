@@ -0,0 +1,84 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/virtual-kubelet/azure-aci/client/aci"
+	"github.com/virtual-kubelet/azure-aci/client/storage"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	stats "k8s.io/kubernetes/pkg/kubelet/apis/stats/v1alpha1"
+)
+
+type fakeFileShareStats struct {
+	accounts []storage.Account
+	shares   map[string]storage.FileShareProperties
+	lists    int
+}
+
+func (s *fakeFileShareStats) GetFileShare(ctx context.Context, resourceGroup, accountName, shareName string) (*storage.FileShare, error) {
+	props, ok := s.shares[resourceGroup+"/"+accountName+"/"+shareName]
+	if !ok {
+		return nil, fmt.Errorf("share %s not found", shareName)
+	}
+	return &storage.FileShare{Name: shareName, Properties: &props}, nil
+}
+
+func (s *fakeFileShareStats) ListAccounts(ctx context.Context) ([]storage.Account, error) {
+	s.lists++
+	return s.accounts, nil
+}
+
+func TestGetPodVolumeStats(t *testing.T) {
+	used := int64(3 << 30)
+	shares := &fakeFileShareStats{
+		accounts: []storage.Account{{ID: "/subscriptions/sub/resourceGroups/data-rg/providers/Microsoft.Storage/storageAccounts/data", Name: "data"}},
+		shares: map[string]storage.FileShareProperties{
+			"data-rg/data/share1":    {ShareQuota: 5, ShareUsageBytes: &used},
+			"scratch-rg/scratch/tmp": {ShareQuota: 20},
+		},
+	}
+	p := ACIProvider{volumeStats: shares, scratchAccount: "scratch", scratchAccountRG: "scratch-rg"}
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "ns"},
+		Spec: v1.PodSpec{Volumes: []v1.Volume{
+			{Name: "claim", VolumeSource: v1.VolumeSource{PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{ClaimName: "data"}}},
+		}},
+	}
+	assert.Check(t, hasAzureFileVolumes(pod))
+
+	cg := &aci.ContainerGroup{ContainerGroupProperties: aci.ContainerGroupProperties{Volumes: []aci.Volume{
+		{Name: "claim", AzureFile: &aci.AzureFileVolume{StorageAccountName: "data", ShareName: "share1"}},
+		{Name: "tmp", AzureFile: &aci.AzureFileVolume{StorageAccountName: "scratch", ShareName: "tmp"}},
+		{Name: "missing", AzureFile: &aci.AzureFileVolume{StorageAccountName: "data", ShareName: "missing"}},
+		{Name: "config", Secret: map[string]string{"key": "dmFsdWU="}},
+	}}}
+
+	volumeStats := p.getPodVolumeStats(context.Background(), pod, cg)
+	assert.Assert(t, is.Len(volumeStats, 2), "Only the readable Azure Files volumes should have stats")
+
+	claim := volumeStats[0]
+	assert.Equal(t, claim.Name, "claim")
+	assert.Assert(t, claim.PVCRef != nil)
+	assert.Check(t, *claim.PVCRef == stats.PVCReference{Name: "data", Namespace: "ns"})
+	assert.Check(t, is.Equal(*claim.CapacityBytes, uint64(5<<30)))
+	assert.Check(t, is.Equal(*claim.UsedBytes, uint64(3<<30)))
+	assert.Check(t, is.Equal(*claim.AvailableBytes, uint64(2<<30)))
+
+	tmp := volumeStats[1]
+	assert.Equal(t, tmp.Name, "tmp")
+	assert.Check(t, tmp.PVCRef == nil)
+	assert.Check(t, is.Equal(*tmp.CapacityBytes, uint64(20<<30)))
+	assert.Check(t, tmp.UsedBytes == nil, "A share without usage stats should have no used bytes")
+
+	p.getPodVolumeStats(context.Background(), pod, cg)
+	assert.Equal(t, shares.lists, 1, "The resource groups of the storage accounts should be cached")
+
+	_, err := p.storageAccountResourceGroup(context.Background(), "other")
+	assert.ErrorContains(t, err, "not in the subscription")
+	assert.Equal(t, shares.lists, 1, "The storage accounts should not be listed again before the refresh")
+}
@@ -0,0 +1,258 @@
+package provider
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/virtual-kubelet/azure-aci/client/aci"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const (
+	eventReasonPlacedInZone    = "PlacedInZone"
+	eventReasonNoZoneAvailable = "NoZoneAvailable"
+)
+
+// setupZones reads the availability zones of the region the container groups are placed in, from Zones in the
+// config file or the ACI_ZONES comma separated list, like "1,2,3". Without zones, ACI places the container groups.
+func (p *ACIProvider) setupZones() error {
+	if v := os.Getenv("ACI_ZONES"); v != "" {
+		p.zones = splitNamespaces(v)
+	}
+
+	seen := make(map[string]bool, len(p.zones))
+	for _, zone := range p.zones {
+		if zone == "" || seen[zone] {
+			return fmt.Errorf("invalid zones %q, expected distinct zones like 1,2,3", p.zones)
+		}
+		seen[zone] = true
+	}
+	return nil
+}
+
+// zonePlacements are the zones the container groups of the pods are placed in, by pod.
+type zonePlacements struct {
+	mu    sync.Mutex
+	zones map[string]string
+}
+
+func zonePlacementKey(namespace, name string) string {
+	return namespace + "/" + name
+}
+
+// observe records the zone of an existing container group, such as the ones created before a restart.
+func (z *zonePlacements) observe(namespace, name string, zones []string) {
+	if len(zones) == 0 {
+		return
+	}
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	if z.zones == nil {
+		z.zones = make(map[string]string)
+	}
+	z.zones[zonePlacementKey(namespace, name)] = zones[0]
+}
+
+func (z *zonePlacements) remove(namespace, name string) {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	delete(z.zones, zonePlacementKey(namespace, name))
+}
+
+func (z *zonePlacements) len() int {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	return len(z.zones)
+}
+
+// place chooses the zone of the container group of pod among zones, given the pods of the node, and records it.
+// The zones with a pod the pod is anti-affine to, or with a pod anti-affine to the pod, by the required pod
+// anti-affinity on the zone label of either, are excluded. The pods are then spread across the zones left by their
+// topologySpreadConstraints on the zone label, the zone with the fewest pods matching the constraints wins, then the
+// zone with the lowest weight of the pods matching the preferred anti-affinity of the pod, and then the zone with
+// the fewest pods of the node, so the pods without constraints are spread too. Placing a pod in the zone with the
+// fewest matching pods keeps the skew the lowest possible, so the constraints are satisfied whenever they can be.
+func (z *zonePlacements) place(zones []string, pod *v1.Pod, pods []*v1.Pod) (string, error) {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	if z.zones == nil {
+		z.zones = make(map[string]string)
+	}
+
+	var selectors []labels.Selector
+	for _, c := range pod.Spec.TopologySpreadConstraints {
+		if !isZoneTopologyKey(c.TopologyKey) || c.LabelSelector == nil {
+			continue
+		}
+		selector, err := metav1.LabelSelectorAsSelector(c.LabelSelector)
+		if err != nil {
+			return "", fmt.Errorf("invalid label selector of the topology spread constraint on %s: %v", c.TopologyKey, err)
+		}
+		selectors = append(selectors, selector)
+	}
+	required, preferred, err := zoneAntiAffinityTerms(pod)
+	if err != nil {
+		return "", err
+	}
+
+	excluded := make(map[string]bool, len(zones))
+	matching := make(map[string]int, len(zones))
+	penalty := make(map[string]int32, len(zones))
+	total := make(map[string]int, len(zones))
+	for _, other := range pods {
+		if !placedPod(other, pod) {
+			continue
+		}
+		zone, ok := z.zones[zonePlacementKey(other.Namespace, other.Name)]
+		if !ok {
+			continue
+		}
+		total[zone]++
+
+		for _, term := range required {
+			if term.matches(pod, other) {
+				excluded[zone] = true
+			}
+		}
+		if !excluded[zone] {
+			// The anti-affinity of the pods already placed holds against the new pods too.
+			otherRequired, _, err := zoneAntiAffinityTerms(other)
+			if err == nil {
+				for _, term := range otherRequired {
+					if term.matches(other, pod) {
+						excluded[zone] = true
+					}
+				}
+			}
+		}
+		for _, term := range preferred {
+			if term.matches(pod, other) {
+				penalty[zone] += term.weight
+			}
+		}
+
+		if other.Namespace != pod.Namespace {
+			continue
+		}
+		for _, selector := range selectors {
+			if selector.Matches(labels.Set(other.Labels)) {
+				matching[zone]++
+			}
+		}
+	}
+
+	best := ""
+	for _, zone := range zones {
+		if excluded[zone] {
+			continue
+		}
+		if best == "" || matching[zone] < matching[best] ||
+			(matching[zone] == matching[best] && (penalty[zone] < penalty[best] ||
+				(penalty[zone] == penalty[best] && total[zone] < total[best]))) {
+			best = zone
+		}
+	}
+	if best == "" {
+		return "", fmt.Errorf("no zone among %s satisfies the pod anti-affinity of pod %s/%s", strings.Join(zones, ", "), pod.Namespace, pod.Name)
+	}
+	z.zones[zonePlacementKey(pod.Namespace, pod.Name)] = best
+	return best, nil
+}
+
+// zoneAntiAffinityTerm is a term of the pod anti-affinity of a pod on the zone label.
+type zoneAntiAffinityTerm struct {
+	namespaces map[string]bool
+	selector   labels.Selector
+	weight     int32
+}
+
+// matches reports whether the term of the anti-affinity of owner matches other.
+func (t zoneAntiAffinityTerm) matches(owner, other *v1.Pod) bool {
+	if len(t.namespaces) == 0 {
+		if other.Namespace != owner.Namespace {
+			return false
+		}
+	} else if !t.namespaces[other.Namespace] {
+		return false
+	}
+	return t.selector.Matches(labels.Set(other.Labels))
+}
+
+// zoneAntiAffinityTerms returns the required and preferred terms of the pod anti-affinity of the pod on the zone
+// label. The terms on the hostname label always hold, every container group is a host of its own.
+func zoneAntiAffinityTerms(pod *v1.Pod) (required, preferred []zoneAntiAffinityTerm, err error) {
+	if pod.Spec.Affinity == nil || pod.Spec.Affinity.PodAntiAffinity == nil {
+		return nil, nil, nil
+	}
+	term := func(t v1.PodAffinityTerm, weight int32) (*zoneAntiAffinityTerm, error) {
+		if !isZoneTopologyKey(t.TopologyKey) || t.LabelSelector == nil {
+			return nil, nil
+		}
+		selector, err := metav1.LabelSelectorAsSelector(t.LabelSelector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid label selector of the pod anti-affinity on %s: %v", t.TopologyKey, err)
+		}
+		namespaces := make(map[string]bool, len(t.Namespaces))
+		for _, ns := range t.Namespaces {
+			namespaces[ns] = true
+		}
+		return &zoneAntiAffinityTerm{namespaces: namespaces, selector: selector, weight: weight}, nil
+	}
+
+	anti := pod.Spec.Affinity.PodAntiAffinity
+	for _, t := range anti.RequiredDuringSchedulingIgnoredDuringExecution {
+		zt, err := term(t, 0)
+		if err != nil {
+			return nil, nil, err
+		}
+		if zt != nil {
+			required = append(required, *zt)
+		}
+	}
+	for _, t := range anti.PreferredDuringSchedulingIgnoredDuringExecution {
+		zt, err := term(t.PodAffinityTerm, t.Weight)
+		if err != nil {
+			return nil, nil, err
+		}
+		if zt != nil {
+			preferred = append(preferred, *zt)
+		}
+	}
+	return required, preferred, nil
+}
+
+// placedPod reports whether other is a pod of the node taking its place, in a zone or in the capacity of the
+// node, other than pod.
+func placedPod(other, pod *v1.Pod) bool {
+	if other.Namespace == pod.Namespace && other.Name == pod.Name {
+		return false
+	}
+	if other.DeletionTimestamp != nil {
+		return false
+	}
+	return other.Status.Phase != v1.PodSucceeded && other.Status.Phase != v1.PodFailed
+}
+
+func isZoneTopologyKey(key string) bool {
+	return key == zoneLabel || key == betaZoneLabel
+}
+
+// placeInZone places the container group of the pod in one of the zones of the node, if any.
+func (p *ACIProvider) placeInZone(pod *v1.Pod, cg *aci.ContainerGroup) error {
+	if len(p.zones) == 0 {
+		return nil
+	}
+
+	zone, err := p.zonePlacements.place(p.zones, pod, p.resourceManager.GetPods())
+	if err != nil {
+		p.recordEvent(pod, v1.EventTypeWarning, eventReasonNoZoneAvailable, "The container group can't be placed in a zone: %v", err)
+		return err
+	}
+	cg.Zones = []string{zone}
+	p.recordEvent(pod, v1.EventTypeNormal, eventReasonPlacedInZone, "Placed the container group in zone %s of %s", zone, p.region)
+	return nil
+}
@@ -0,0 +1,109 @@
+package provider
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func zoneTestPod(name string, labels map[string]string, spread bool) *v1.Pod {
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: name, Labels: labels},
+		Status:     v1.PodStatus{Phase: v1.PodRunning},
+	}
+	if spread {
+		pod.Spec.TopologySpreadConstraints = []v1.TopologySpreadConstraint{{
+			MaxSkew:           1,
+			TopologyKey:       zoneLabel,
+			WhenUnsatisfiable: v1.DoNotSchedule,
+			LabelSelector:     &metav1.LabelSelector{MatchLabels: labels},
+		}}
+	}
+	return pod
+}
+
+func TestZonePlacements(t *testing.T) {
+	zones := []string{"1", "2", "3"}
+	var z zonePlacements
+	var pods []*v1.Pod
+	place := func(pod *v1.Pod) string {
+		pods = append(pods, pod)
+		zone, err := z.place(zones, pod, pods)
+		assert.NilError(t, err)
+		return zone
+	}
+
+	// The pods of another app fill zone 1, the pods without constraints go to the least used zones.
+	z.observe("ns", "other-0", []string{"1"})
+	z.observe("ns", "other-1", []string{"1"})
+	pods = append(pods, zoneTestPod("other-0", map[string]string{"app": "other"}, false), zoneTestPod("other-1", map[string]string{"app": "other"}, false))
+	assert.Check(t, is.Equal("2", place(zoneTestPod("single", nil, false))))
+
+	// The replicas spread across the zones, zone 1 included despite the other pods.
+	web := map[string]string{"app": "web"}
+	var placed []string
+	for _, name := range []string{"web-0", "web-1", "web-2", "web-3"} {
+		placed = append(placed, place(zoneTestPod(name, web, true)))
+	}
+	assert.Check(t, is.DeepEqual([]string{"3", "2", "1", "2"}, placed))
+
+	// A deleted replica frees its zone.
+	z.remove("ns", "web-0")
+	pods[3].DeletionTimestamp = &metav1.Time{}
+	assert.Check(t, is.Equal("3", place(zoneTestPod("web-4", web, true))))
+	assert.Check(t, is.Equal(7, z.len()))
+}
+
+func TestZonePlacementsAntiAffinity(t *testing.T) {
+	zones := []string{"1", "2"}
+	db := map[string]string{"app": "db"}
+	antiAffine := func(name string, required bool) *v1.Pod {
+		pod := zoneTestPod(name, db, false)
+		term := v1.PodAffinityTerm{LabelSelector: &metav1.LabelSelector{MatchLabels: db}, TopologyKey: zoneLabel}
+		anti := &v1.PodAntiAffinity{}
+		if required {
+			anti.RequiredDuringSchedulingIgnoredDuringExecution = []v1.PodAffinityTerm{term}
+		} else {
+			anti.PreferredDuringSchedulingIgnoredDuringExecution = []v1.WeightedPodAffinityTerm{{Weight: 10, PodAffinityTerm: term}}
+		}
+		pod.Spec.Affinity = &v1.Affinity{PodAntiAffinity: anti}
+		return pod
+	}
+
+	var z zonePlacements
+	z.observe("ns", "busy-0", []string{"2"})
+	z.observe("ns", "busy-1", []string{"2"})
+	pods := []*v1.Pod{zoneTestPod("busy-0", nil, false), zoneTestPod("busy-1", nil, false)}
+
+	// The second replica goes to zone 2 despite its pods, the third one has no zone left.
+	for _, want := range []string{"1", "2"} {
+		pod := antiAffine("db-"+want, true)
+		pods = append(pods, pod)
+		zone, err := z.place(zones, pod, pods)
+		assert.NilError(t, err)
+		assert.Check(t, is.Equal(want, zone))
+	}
+	pod := antiAffine("db-3", true)
+	pods = append(pods, pod)
+	_, err := z.place(zones, pod, pods)
+	assert.ErrorContains(t, err, "no zone among 1, 2")
+
+	// A pod without anti-affinity of its own can't join the anti-affine pods either.
+	pods[len(pods)-1] = zoneTestPod("db-4", db, false)
+	_, err = z.place(zones, pods[len(pods)-1], pods)
+	assert.ErrorContains(t, err, "no zone")
+
+	// A preferred anti-affinity is only a preference, over the fewest pods of the node.
+	var preferred zonePlacements
+	preferred.observe("ns", "db-0", []string{"1"})
+	pods = []*v1.Pod{zoneTestPod("db-0", db, false), zoneTestPod("busy-0", nil, false), zoneTestPod("busy-1", nil, false)}
+	preferred.observe("ns", "busy-0", []string{"2"})
+	preferred.observe("ns", "busy-1", []string{"2"})
+	pod = antiAffine("db-1", false)
+	zone, err := preferred.place(zones, pod, append(pods, pod))
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal("2", zone))
+}